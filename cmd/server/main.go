@@ -1,37 +1,204 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"io/fs"
 	"log"
-	"net/http"
-	"tiktaktoes/internal/api"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"tiktaktoes/internal/audit"
 	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/clientip"
 	"tiktaktoes/internal/game"
-	"tiktaktoes/internal/htmx"
+	"tiktaktoes/internal/journal"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/security"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/storage"
+	"tiktaktoes/internal/telemetry"
+	"tiktaktoes/internal/text"
 	"tiktaktoes/internal/ws"
+	"time"
 )
 
 func main() {
-	// Initialize shared services
-	gameService := game.NewService()
-	hub := broadcast.NewHub()
+	addr := flag.String("addr", ":8080", "address to listen on")
+	static := flag.String("static", "web", "directory of static files to serve")
+	store := flag.String("store", "memory", "game storage: memory or bolt:path/to/db")
+	snapshotPath := flag.String("snapshot", "", "file to save in-progress games to on shutdown and restore from on startup (disabled when empty)")
+	snapshotInterval := flag.Duration("snapshot-interval", time.Minute, "how often to write the snapshot while running (0 writes only on shutdown)")
+	journalDir := flag.String("journal", "", "directory for the write-ahead move journal (disabled when empty)")
+	journalSync := flag.String("journal-sync", "always", "when to fsync the journal: always, interval or never")
+	journalMaxSize := flag.Int64("journal-max-size", 16<<20, "journal segment size in bytes before rotating")
+	auditPath := flag.String("audit-log", "", "file to record destructive and admin actions in as JSON lines (disabled when empty)")
+	auditMaxSize := flag.Int64("audit-max-size", 8<<20, "audit log size in bytes before rotating")
+	auditMaxFiles := flag.Int("audit-max-files", 5, "number of rotated audit log files to keep")
+	auditMaxAge := flag.Duration("audit-max-age", 0, "audit log age before rotating, counted from its first entry (0 rotates by size alone)")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "OTLP/HTTP endpoint URL for traces, e.g. http://localhost:4318 (disabled when empty)")
+	adminKey := flag.String("admin-key", os.Getenv("TIKTAKTOES_ADMIN_KEY"), "bearer token for the /api/admin endpoints (disabled when empty; defaults to $TIKTAKTOES_ADMIN_KEY)")
+	seatKey := flag.String("seat-key", os.Getenv("TIKTAKTOES_SEAT_KEY"), "key signing the seat tokens players are handed when they join (random on every start when empty; defaults to $TIKTAKTOES_SEAT_KEY)")
+	requireAPIKey := flag.Bool("require-api-key", false, "require an API key, created through /api/admin/keys, on the REST API (needs -admin-key)")
+	metrics := flag.Bool("metrics", true, "serve Prometheus metrics at /metrics")
+	pathPrefix := flag.String("path-prefix", "", "serve everything under this path, e.g. /ttt, when a reverse proxy forwards a sub-path without stripping it")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain file for HTTPS (requires -tls-key)")
+	tlsKey := flag.String("tls-key", "", "PEM private key file for HTTPS (requires -tls-cert)")
+	acmeDomain := flag.String("acme-domain", "", "comma-separated domains to get Let's Encrypt certificates for (serve on -addr :443)")
+	acmeCache := flag.String("acme-cache", "acme-cache", "directory to keep ACME certificates and keys in")
+	redirectAddr := flag.String("redirect-addr", "", "plain HTTP address that redirects to HTTPS (defaults to :80 with -acme-domain)")
+	trustedProxies := flag.String("trusted-proxies", "", "comma-separated CIDRs or addresses of reverse proxies whose forwarding headers are trusted, see -trusted-header")
+	trustedHeader := flag.String("trusted-header", "xff", "forwarding header the -trusted-proxies set, the only one believed: xff for X-Forwarded-For, forwarded for Forwarded")
+	listenAddr := flag.String("listen", "", "unix:/path/to.sock or a TCP address, overriding -addr (a systemd socket takes precedence over both)")
+	socketMode := flag.String("socket-mode", "", "octal permissions for the -listen unix socket, e.g. 0660 (umask when empty)")
+	compress := flag.Bool("compress", true, "compress responses with gzip or zstd when the client accepts it")
+	wsIdleTimeout := flag.Duration("ws-idle-timeout", ws.DefaultIdleTimeout, "disconnect WebSocket clients that neither send anything nor answer pings for this long")
+	wsOrigins := flag.String("ws-allowed-origins", security.AnyOrigin, "comma-separated origins whose pages may open WebSockets besides this server's own, or * for any")
+	wsReadBuffer := flag.Int("ws-read-buffer", 0, "WebSocket read buffer size in bytes (0 for the 4 KiB default)")
+	wsWriteBuffer := flag.Int("ws-write-buffer", 0, "WebSocket write buffer size in bytes (0 for the 4 KiB default)")
+	wsCompress := flag.Bool("ws-compress", false, "negotiate permessage-deflate compression on WebSockets")
+	maxOpenGames := flag.Int("max-open-games-per-ip", 0, "how many unfinished games one client address may have at a time (0 for no limit)")
+	maxGames := flag.Int("max-games", 0, "most unfinished games the server keeps, expiring the longest-waiting ones to make room (0 for no limit)")
+	claimAfter := flag.Duration("claim-after", game.DefaultClaimAfter, "how long an opponent must be gone before the win can be claimed")
+	vacateAfter := flag.Duration("vacate-after", game.DefaultVacateAfter, "how long a player must be gone before their slot can be vacated")
+	readyTimeout := flag.Duration("ready-timeout", game.DefaultReadyTimeout, "how long players of a game with a ready check have to ready up before the unready are vacated")
+	readyCountdown := flag.Duration("ready-countdown", 3*time.Second, "countdown before a game with a ready check starts once both players are ready (0 starts it at once)")
+	undoWindow := flag.Duration("undo-window", game.DefaultUndoWindow, "how long a reset or cancelled game can be put back as it was")
+	thinkTime := flag.Duration("think-time", game.DefaultThinkTime, "least time the computer takes over a move")
+	duplicateTabs := flag.String("duplicate-tabs", "supersede", "when a player's side gets a second connection, as from another tab: supersede hands it to the new one, reject turns the new one away unless it asks to take over")
+	embedAncestors := flag.String("embed-ancestors", "*", "comma-separated sites allowed to frame game embed pages, as CSP sources such as https://example.com (* for any, empty for this server alone)")
+	blockedWords := flag.String("blocked-words", "", "file of words, one per line, that game and player names may not contain (none when empty)")
+	follow := flag.String("follow", "", "URL of a primary server to mirror as a read-only warm standby until promoted with POST /api/admin/promote (needs the primary's -admin-key)")
+	flag.Parse()
 
-	// Initialize handlers
-	apiHandler := api.NewHandler(gameService, hub)
-	wsHandler := ws.NewHandler(gameService, hub)
-	htmxHandler := htmx.NewHandler(gameService, hub)
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewTextHandler(os.Stderr, nil))))
 
-	// Setup routes
-	mux := http.NewServeMux()
-	apiHandler.RegisterRoutes(mux)
-	wsHandler.RegisterRoutes(mux)
-	htmxHandler.RegisterRoutes(mux)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Serve static files
-	mux.Handle("/", http.FileServer(http.Dir("web")))
+	proxies, err := clientip.ParsePrefixes(*trustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+	forwardedHeader, err := clientip.ParseHeader(*trustedHeader)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Apply CORS middleware
-	server := api.CORSMiddleware(mux)
+	var mode uint64
+	if *socketMode != "" {
+		if mode, err = strconv.ParseUint(*socketMode, 8, 32); err != nil {
+			log.Fatalf("invalid -socket-mode %q", *socketMode)
+		}
+	}
 
-	log.Println("Server starting on http://localhost:8080/")
-	log.Fatal(http.ListenAndServe(":8080", server))
+	syncPolicy, err := journal.ParseSyncPolicy(*journalSync)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	duplicates, err := broadcast.ParseDuplicatePolicy(*duplicateTabs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var blocked []string
+	if *blockedWords != "" {
+		if blocked, err = text.LoadWords(*blockedWords); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	repo, err := storage.Open(*store)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *otlpEndpoint != "" {
+		shutdownTracing, err := telemetry.Setup(ctx, *otlpEndpoint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer shutdownTracing(context.Background())
+	}
+
+	srv, err := server.New(server.Config{
+		Addr:       *addr,
+		Listen:     *listenAddr,
+		SocketMode: fs.FileMode(mode),
+		StaticDir:  *static,
+		Tracing:    *otlpEndpoint != "",
+		GameOptions: []game.Option{
+			game.WithRepository(repo),
+			game.WithQuota(*maxOpenGames),
+			game.WithMaxGames(*maxGames),
+			game.WithClaimAfter(*claimAfter),
+			game.WithVacateAfter(*vacateAfter),
+			game.WithReadyTimeout(*readyTimeout),
+			game.WithCountdown(*readyCountdown),
+			game.WithUndoWindow(*undoWindow),
+			game.WithThinkTime(*thinkTime),
+			game.WithTextPolicy(text.NewDefault(blocked)),
+		},
+		SnapshotPath:         *snapshotPath,
+		SnapshotInterval:     *snapshotInterval,
+		AdminKey:             *adminKey,
+		SeatKey:              *seatKey,
+		RequireAPIKey:        *requireAPIKey,
+		APIKeys:              storage.Keys(repo),
+		Featured:             storage.Featured(repo),
+		Metrics:              *metrics,
+		Compress:             *compress,
+		PathPrefix:           *pathPrefix,
+		TrustedProxies:       proxies,
+		TrustedHeader:        forwardedHeader,
+		WSIdleTimeout:        *wsIdleTimeout,
+		DuplicateConnections: duplicates,
+		EmbedAncestors:       splitList(*embedAncestors),
+		Follow:               *follow,
+		WSUpgrader: security.UpgraderConfig{
+			AllowedOrigins:    splitList(*wsOrigins),
+			ReadBufferSize:    *wsReadBuffer,
+			WriteBufferSize:   *wsWriteBuffer,
+			EnableCompression: *wsCompress,
+		},
+		TLS: server.TLSConfig{
+			CertFile:     *tlsCert,
+			KeyFile:      *tlsKey,
+			ACMEDomains:  splitList(*acmeDomain),
+			ACMECacheDir: *acmeCache,
+			RedirectAddr: *redirectAddr,
+		},
+		Audit: audit.Options{
+			Path:     *auditPath,
+			MaxSize:  *auditMaxSize,
+			MaxFiles: *auditMaxFiles,
+			MaxAge:   *auditMaxAge,
+		},
+		Journal: journal.Options{
+			Dir:            *journalDir,
+			Sync:           syncPolicy,
+			MaxSegmentSize: *journalMaxSize,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := srv.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
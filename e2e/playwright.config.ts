@@ -18,10 +18,60 @@ export default defineConfig({
       use: { browserName: "chromium" },
     },
   ],
-  webServer: {
-    command: "cd .. && go run ./cmd/server",
-    url: "http://localhost:8080",
-    reuseExistingServer: !process.env.CI,
-    timeout: 30_000,
-  },
+  webServer: [
+    {
+      // A short -claim-after lets tests/claim.spec.ts claim wins quickly,
+      // tests/names.spec.ts checks names against e2e/blocked-words.txt
+      // and tests/status.spec.ts reads /statusz with the admin key
+      command:
+        "cd .. && go run ./cmd/server -claim-after 2s -blocked-words e2e/blocked-words.txt -admin-key e2e-admin",
+      url: "http://localhost:8080",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+    {
+      // Mounted under a path prefix, as behind a reverse proxy; see
+      // tests/path-prefix.spec.ts
+      command: "cd .. && go run ./cmd/server -addr :8081 -path-prefix /ttt",
+      url: "http://localhost:8081/ttt/",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+    {
+      // Limits game creation; see tests/quota.spec.ts
+      command:
+        "cd .. && go run ./cmd/server -addr :8082 -max-open-games-per-ip 2 -max-games 3 -trusted-proxies 127.0.0.1/32",
+      url: "http://localhost:8082",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+    {
+      // Turns a player's second tab away; see tests/takeover.spec.ts
+      command: "cd .. && go run ./cmd/server -addr :8083 -duplicate-tabs reject",
+      url: "http://localhost:8083",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+    {
+      // Requires API keys on the REST API; see tests/api-keys.spec.ts
+      command:
+        "cd .. && go run ./cmd/server -addr :8084 -admin-key e2e-admin -require-api-key",
+      url: "http://localhost:8084",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+    {
+      // A primary and its warm standby; see tests/replication.spec.ts
+      command: "cd .. && go run ./cmd/server -addr :8085 -admin-key e2e-admin",
+      url: "http://localhost:8085",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+    {
+      command: "cd .. && go run ./cmd/server -addr :8086 -admin-key e2e-admin -follow http://localhost:8085",
+      url: "http://localhost:8086",
+      reuseExistingServer: !process.env.CI,
+      timeout: 30_000,
+    },
+  ],
 });
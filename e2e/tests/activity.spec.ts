@@ -0,0 +1,95 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+const ADMIN = { Authorization: "Bearer e2e-admin" };
+
+/** Joins the game as player, returning the seat token handed out. */
+async function join(request: APIRequestContext, id: string, player: string) {
+  const res = await request.post(`/api/game/${id}/join`, { data: { player } });
+  return res.headers()["x-seat-token"];
+}
+
+/** Starts a game with both sides joined over the API, returning X's seat token too. */
+async function startedGame(request: APIRequestContext) {
+  const { id } = await (await request.post("/api/game", { data: {} })).json();
+  const token = await join(request, id, "X");
+  await join(request, id, "O");
+  return { id: id as string, token };
+}
+
+type Entry = { transport: string; player?: string; action: string; result: string; move?: { position: number } };
+
+async function activity(request: APIRequestContext, id: string, token: string, player = "X") {
+  const res = await request.get(`/api/game/${id}/activity?player=${player}`, { headers: { "X-Seat-Token": token } });
+  expect(res.ok()).toBeTruthy();
+  return (await res.json()).entries as Entry[];
+}
+
+test.describe("Activity", () => {
+  test("should record moves and rejections from the API", async ({ request }) => {
+    const { id, token } = await startedGame(request);
+    await request.post(`/api/game/${id}`, { data: { player: "O", position: 4 } });
+    await request.post(`/api/game/${id}`, { data: { player: "X", position: 4 } });
+
+    const entries = await activity(request, id, token);
+    expect(entries.slice(-2)).toMatchObject([
+      { transport: "api", player: "O", action: "move", result: "not_your_turn", move: { position: 4 } },
+      { transport: "api", player: "X", action: "move", result: "ok", move: { position: 4 } },
+    ]);
+    expect(JSON.stringify(entries)).not.toContain("127.0.0.1");
+  });
+
+  test("should record moves sent over a WebSocket", async ({ page, request, baseURL }) => {
+    const { id, token } = await startedGame(request);
+    await page.goto("/");
+    await page.evaluate(
+      (url) =>
+        new Promise<void>((resolve) => {
+          const ws = new WebSocket(url);
+          ws.onopen = () => {
+            ws.send(JSON.stringify({ player: "O", position: 0 }));
+            ws.send(JSON.stringify({ player: "X", position: 0 }));
+            setTimeout(resolve, 300);
+          };
+        }),
+      `${baseURL!.replace(/^http/, "ws")}/ws/${id}?player=X`
+    );
+
+    const entries = (await activity(request, id, token)).filter((e) => e.transport === "ws");
+    expect(entries).toMatchObject([
+      { action: "move", player: "O", result: "not_your_turn" },
+      { action: "move", player: "X", result: "ok" },
+    ]);
+  });
+
+  test("should record moves made on the page", async ({ browser, request }) => {
+    const context = await browser.newContext();
+    const created = await context.request.post("/htmx/game/new?player=X");
+    const id = /data-game-id="([^"]+)"/.exec(await created.text())![1];
+    const token = await join(request, id, "O");
+    await context.request.post(`/htmx/move/${id}/4?player=X`);
+    await context.request.post(`/htmx/move/${id}/5?player=X`);
+
+    const entries = (await activity(request, id, token, "O")).filter((e) => e.transport === "htmx");
+    expect(entries).toMatchObject([
+      { action: "move", player: "X", result: "ok", move: { position: 4 } },
+      { action: "move", player: "X", result: "not_your_turn", move: { position: 5 } },
+    ]);
+  });
+
+  test("should only show joined players and admins", async ({ request }) => {
+    const { id } = await (await request.post("/api/game", { data: {} })).json();
+    const token = await join(request, id, "X");
+    const seat = { "X-Seat-Token": token };
+
+    expect((await request.get(`/api/game/${id}/activity`, { headers: seat })).status()).toBe(403);
+    expect((await request.get(`/api/game/${id}/activity?player=O`, { headers: seat })).status()).toBe(403);
+    expect((await request.get(`/api/game/${id}/activity?player=X`)).status()).toBe(403);
+    expect((await request.get(`/api/game/${id}/activity?player=X`, { headers: seat })).status()).toBe(200);
+    expect((await request.get(`/api/game/${id}/activity?player=X`, { headers: ADMIN })).status()).toBe(200);
+    expect((await request.get(`/api/admin/game/${id}/activity`)).status()).toBe(401);
+
+    const res = await request.get(`/api/admin/game/${id}/activity`, { headers: ADMIN });
+    expect(res.ok()).toBeTruthy();
+    expect((await res.json()).entries[0]).toMatchObject({ action: "join", player: "X", result: "ok" });
+  });
+});
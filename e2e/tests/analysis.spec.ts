@@ -0,0 +1,52 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+async function analysedGame(request: APIRequestContext): Promise<string> {
+  const { id } = await (
+    await request.post("/api/game", { data: { analysisLive: true } })
+  ).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  return id;
+}
+
+test.describe("Live analysis", () => {
+  test("should show spectators the evaluation after each move", async ({
+    page,
+    request,
+  }) => {
+    const id = await analysedGame(request);
+    await page.goto(`/?game=${id}`);
+    await expect(page.locator(".eval-label")).toContainText("eval: even");
+
+    // X 0, O 4, X 8, O 2 leaves X a fork at 6
+    const moves: [number, string][] = [[0, "X"], [4, "O"], [8, "X"], [2, "O"]];
+    for (const [position, player] of moves) {
+      await request.post(`/api/game/${id}`, { data: { position, player } });
+    }
+    await expect(page.locator(".eval-label")).toContainText("eval: X wins in 2", {
+      timeout: 5000,
+    });
+    await expect(page.locator(".eval-bar")).toHaveAttribute("data-advantage", "X");
+  });
+
+  test("should keep the evaluation from players", async ({ page, request }) => {
+    const { id } = await (
+      await request.post("/api/game", { data: { analysisLive: true } })
+    ).json();
+    // Join X from the page's browser, whose seat cookie the board needs
+    await page.request.post(`/htmx/join/${id}?player=X`);
+    await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+    await page.goto(`/?game=${id}&player=X`);
+    await expect(page.locator(".board")).toBeVisible();
+    await request.post(`/api/game/${id}`, { data: { position: 0, player: "X" } });
+    await expect(page.locator(".cell").nth(0)).toHaveText("X", { timeout: 5000 });
+    await expect(page.locator(".eval")).toHaveCount(0);
+  });
+
+  test("should leave games without analysis alone", async ({ page, request }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    await page.goto(`/?game=${id}`);
+    await expect(page.locator(".board")).toBeVisible();
+    await expect(page.locator(".eval")).toHaveCount(0);
+  });
+});
@@ -0,0 +1,89 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+// The fifth web server in playwright.config.ts requires API keys; the
+// main one doesn't.
+const BASE = "http://localhost:8084";
+const ADMIN = { Authorization: "Bearer e2e-admin" };
+
+async function createKey(request: APIRequestContext, data: object) {
+  const res = await request.post(`${BASE}/api/admin/keys`, { data, headers: ADMIN });
+  expect(res.status()).toBe(201);
+  return (await res.json()) as { id: string; token: string; scopes: string[] };
+}
+
+function bearer(token: string) {
+  return { Authorization: `Bearer ${token}` };
+}
+
+test.describe("API keys", () => {
+  test("should turn away requests without a key", async ({ request }) => {
+    const res = await request.post(`${BASE}/api/game`, { data: {} });
+    expect(res.status()).toBe(401);
+    expect(res.headers()["www-authenticate"]).toContain("Bearer");
+  });
+
+  test("should let a read-only key read but not move", async ({ request }) => {
+    const player = await createKey(request, { scopes: ["games:create", "games:play"] });
+    const reader = await createKey(request, { name: "watcher", scopes: ["games:read"] });
+
+    const created = await request.post(`${BASE}/api/game`, { data: {}, headers: bearer(player.token) });
+    expect(created.status()).toBe(200);
+    const { id } = await created.json();
+
+    const read = await request.get(`${BASE}/api/game/${id}`, { headers: bearer(reader.token) });
+    expect(read.status()).toBe(200);
+    const join = await request.post(`${BASE}/api/game/${id}/join`, {
+      data: { player: "X" },
+      headers: bearer(reader.token),
+    });
+    expect(join.status()).toBe(403);
+    const create = await request.post(`${BASE}/api/game`, { data: {}, headers: bearer(reader.token) });
+    expect(create.status()).toBe(403);
+  });
+
+  test("should stop accepting a key as soon as it is revoked", async ({ request }) => {
+    const key = await createKey(request, { scopes: ["games:read"] });
+    expect((await request.get(`${BASE}/api/puzzle/today`, { headers: bearer(key.token) })).status()).toBe(200);
+
+    const revoked = await request.delete(`${BASE}/api/admin/keys/${key.id}`, { headers: ADMIN });
+    expect(revoked.status()).toBe(204);
+    expect((await request.get(`${BASE}/api/puzzle/today`, { headers: bearer(key.token) })).status()).toBe(401);
+
+    const list = await (await request.get(`${BASE}/api/admin/keys`, { headers: ADMIN })).json();
+    expect(list.map((k: { id: string }) => k.id)).not.toContain(key.id);
+  });
+
+  test("should limit each key to its own rate", async ({ request }) => {
+    const slow = await createKey(request, { scopes: ["games:read"], rate: 0.1, burst: 2 });
+    const other = await createKey(request, { scopes: ["games:read"], rate: 0.1, burst: 2 });
+    for (let i = 0; i < 2; i++) {
+      expect((await request.get(`${BASE}/api/puzzle/today`, { headers: bearer(slow.token) })).status()).toBe(200);
+    }
+    const limited = await request.get(`${BASE}/api/puzzle/today`, { headers: bearer(slow.token) });
+    expect(limited.status()).toBe(429);
+    expect(limited.headers()["retry-after"]).toBe("10");
+    expect((await request.get(`${BASE}/api/puzzle/today`, { headers: bearer(other.token) })).status()).toBe(200);
+  });
+
+  test("should accept a key with the admin scope on admin routes", async ({ request }) => {
+    const admin = await createKey(request, { scopes: ["admin:*"] });
+    expect((await request.get(`${BASE}/api/admin/hub`, { headers: bearer(admin.token) })).status()).toBe(200);
+    const reader = await createKey(request, { scopes: ["games:read"] });
+    expect((await request.get(`${BASE}/api/admin/hub`, { headers: bearer(reader.token) })).status()).toBe(403);
+  });
+
+  test("should leave the browser's pages and discovery open", async ({ request }) => {
+    expect((await request.get(`${BASE}/`)).status()).toBe(200);
+    expect((await request.get(`${BASE}/htmx/lobby`)).status()).toBe(200);
+    const version = await (await request.get(`${BASE}/api/version`)).json();
+    expect(version.features).toContain("api-keys");
+  });
+
+  test("should be inert without -require-api-key", async ({ request }) => {
+    expect((await request.post("/api/game", { data: {} })).status()).toBe(200);
+    const keys = await request.get("/api/admin/keys", { headers: ADMIN });
+    expect(keys.status()).not.toBe(200);
+    const version = await (await request.get("/api/version")).json();
+    expect(version.features).not.toContain("api-keys");
+  });
+});
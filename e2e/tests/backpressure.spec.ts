@@ -0,0 +1,87 @@
+import { test, expect } from "@playwright/test";
+import { connect, Socket } from "net";
+import { randomBytes } from "crypto";
+
+// open makes a WebSocket handshake by hand, so the test can send frames
+// a browser never would, such as half of one
+async function open(baseURL: string, path: string): Promise<{ socket: Socket; received: () => Buffer }> {
+  const url = new URL(baseURL);
+  const socket = connect(Number(url.port), url.hostname);
+  let data = Buffer.alloc(0);
+  socket.on("data", (chunk) => (data = Buffer.concat([data, chunk])));
+  await new Promise((resolve) => socket.once("connect", resolve));
+  socket.write(
+    `GET ${path} HTTP/1.1\r\nHost: ${url.host}\r\nOrigin: ${url.origin}\r\nUpgrade: websocket\r\n` +
+      `Connection: Upgrade\r\nSec-WebSocket-Key: ${randomBytes(16).toString("base64")}\r\nSec-WebSocket-Version: 13\r\n\r\n`,
+  );
+  await expect.poll(() => data.indexOf("\r\n\r\n")).toBeGreaterThan(0);
+  expect(data.toString().split("\r\n")[0]).toContain("101");
+  const start = data.indexOf("\r\n\r\n") + 4;
+  return { socket, received: () => data.subarray(start) };
+}
+
+// frame returns a client's text frame of payload, masked as clients' are
+function frame(payload: string): Buffer {
+  const body = Buffer.from(payload);
+  const mask = randomBytes(4);
+  const masked = body.map((b, i) => b ^ mask[i % 4]);
+  return Buffer.concat([Buffer.from([0x81, 0x80 | body.length]), mask, masked]);
+}
+
+// closeCode returns the code of the close frame among the server's
+// frames, or undefined before one arrives
+function closeCode(frames: Buffer): number | undefined {
+  let at = 0;
+  while (at + 2 <= frames.length) {
+    let length = frames[at + 1] & 0x7f;
+    let offset = at + 2;
+    if (length === 126) {
+      length = frames.readUInt16BE(at + 2);
+      offset += 2;
+    }
+    if ((frames[at] & 0x0f) === 0x8 && offset + 2 <= frames.length) return frames.readUInt16BE(offset);
+    at = offset + length;
+  }
+  return undefined;
+}
+
+test.describe("WebSocket backpressure", () => {
+  test("should disconnect a client that stalls halfway through a message", async ({ request, baseURL }) => {
+    test.setTimeout(20_000);
+    const { id } = await (await request.post("/api/game")).json();
+    const { socket, received } = await open(baseURL!, `/ws/${id}?player=X`);
+
+    // The header and a few bytes of a move, then nothing
+    socket.write(frame(JSON.stringify({ position: 4, player: "X" })).subarray(0, 10));
+    const started = Date.now();
+
+    // 1008 is the policy violation close code
+    await expect.poll(() => closeCode(received()), { timeout: 10_000 }).toBe(1008);
+    expect(Date.now() - started).toBeGreaterThanOrEqual(4_000);
+    socket.destroy();
+
+    // The half sent move was never made
+    const game = await (await request.get(`/api/game/${id}`)).json();
+    expect(game.board[4]).toBe("");
+  });
+
+  test("should handle a burst of messages in the order they were sent", async ({ request, baseURL }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    const { socket, received } = await open(baseURL!, `/ws/${id}?player=X`);
+
+    // As many as the rate limit allows at once, in one write
+    const moves = [0, 3, 1, 4, 2];
+    socket.write(
+      Buffer.concat([
+        ...moves.map((position, i) => frame(JSON.stringify({ position, player: i % 2 === 0 ? "X" : "O" }))),
+        ...Array.from({ length: 5 }, () => frame(JSON.stringify({ type: "get" }))),
+      ]),
+    );
+
+    await expect
+      .poll(async () => (await (await request.get(`/api/game/${id}`)).json()).winner, { timeout: 5_000 })
+      .toBe("X");
+    expect(closeCode(received())).toBeUndefined();
+    socket.destroy();
+  });
+});
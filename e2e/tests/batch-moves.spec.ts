@@ -0,0 +1,80 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Batch moves", () => {
+  test("should make a batch of moves in one go", async ({ request }) => {
+    const { id } = await (await request.post("/api/game", { data: { mode: "hotseat", exhibition: true } })).json();
+    const res = await request.post(`/api/game/${id}/moves`, {
+      data: {
+        moves: [
+          { player: "X", position: 0 },
+          { player: "O", position: 4 },
+          { player: "X", position: 1 },
+          { player: "O", position: 8 },
+          { player: "X", cell: "c1" },
+        ],
+      },
+    });
+    expect(res.status()).toBe(200);
+    const state = await res.json();
+    expect(state.winner).toBe("X");
+    expect(state.history).toHaveLength(5);
+    const batches = new Set(state.history.map((m: { batch: string }) => m.batch));
+    expect(batches.size).toBe(1);
+    expect([...batches][0]).toBeTruthy();
+  });
+
+  test("should leave the game as it was when a move fails", async ({ request }) => {
+    const { id } = await (await request.post("/api/game", { data: { mode: "hotseat", exhibition: true } })).json();
+    const res = await request.post(`/api/game/${id}/moves`, {
+      data: {
+        moves: [
+          { player: "X", position: 0 },
+          { player: "O", position: 4 },
+          { player: "X", position: 4 },
+        ],
+      },
+    });
+    expect(res.status()).toBe(409);
+    expect(await res.json()).toMatchObject({ code: "position_taken", moveIndex: 2 });
+
+    const state = await (await request.get(`/api/game/${id}`)).json();
+    expect(state.history).toEqual([]);
+  });
+
+  test("should send watchers a single update", async ({ page, request, baseURL }) => {
+    const { id } = await (await request.post("/api/game", { data: { mode: "hotseat", exhibition: true } })).json();
+    await page.goto("/");
+    await page.evaluate((url) => {
+      const w = window as any;
+      w.states = [];
+      const ws = new WebSocket(url);
+      ws.onmessage = (e) => {
+        const frame = JSON.parse(e.data);
+        if (frame.board) {
+          w.states.push(frame);
+        }
+      };
+      return new Promise((resolve) => (ws.onopen = resolve));
+    }, `${baseURL!.replace(/^http/, "ws")}/ws/${id}`);
+    await page.waitForTimeout(300);
+    const before = await page.evaluate(() => (window as any).states.length);
+
+    await request.post(`/api/game/${id}/moves`, {
+      data: { moves: [{ player: "X", position: 0 }, { player: "O", position: 4 }, { player: "X", position: 8 }] },
+    });
+    await page.waitForTimeout(500);
+    const updates = await page.evaluate((n) => (window as any).states.slice(n), before);
+    expect(updates).toHaveLength(1);
+    expect(updates[0].history).toHaveLength(3);
+  });
+
+  test("should only take batches in exhibition games", async ({ request }) => {
+    const { id } = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    const res = await request.post(`/api/game/${id}/moves`, { data: { moves: [{ player: "X", position: 0 }] } });
+    expect(res.status()).toBe(409);
+    expect(await res.json()).toMatchObject({ code: "no_batch" });
+
+    const empty = await request.post(`/api/game/${id}/moves`, { data: { moves: [] } });
+    expect(empty.status()).toBe(400);
+  });
+});
@@ -0,0 +1,75 @@
+import { test, expect, BrowserContext } from "@playwright/test";
+
+// The web server runs with -claim-after 2s, see playwright.config.ts
+
+// The seat token the browser's seats cookie holds for the game.
+async function seatOf(context: BrowserContext, id: string): Promise<string> {
+  const cookie = (await context.cookies()).find((c) => c.name === "seats")!;
+  const entry = cookie.value.split("-").find((e) => e.startsWith(`${id}.`))!;
+  return entry.slice(id.length + 1);
+}
+
+test.describe("Claiming a win", () => {
+  test("should let a player claim the win once the opponent has left", async ({ browser }) => {
+    const xContext = await browser.newContext();
+    const oContext = await browser.newContext();
+    const created = await xContext.request.post("/htmx/game/new?player=X");
+    const id = /data-game-id="([^"]+)"/.exec(await created.text())![1];
+    await oContext.request.post(`/htmx/join/${id}?player=O`);
+
+    const pageX = await xContext.newPage();
+    await pageX.goto(`/?game=${id}&player=X`);
+    const pageO = await oContext.newPage();
+    await pageO.goto(`/?game=${id}&player=O`);
+    await expect(pageO.locator(".board")).toBeVisible();
+    const asX = { headers: { "X-Seat-Token": await seatOf(xContext, id) }, data: { player: "X" } };
+
+    // Nothing to claim before the first move or while O is around
+    let res = await xContext.request.post(`/api/game/${id}/claim`, asX);
+    expect(res.status()).toBe(409);
+    await pageX.locator(".cell").nth(4).click();
+    await expect(pageO.locator(".cell").nth(4)).toHaveText("X", { timeout: 5000 });
+    res = await xContext.request.post(`/api/game/${id}/claim`, asX);
+    expect((await res.json()).error).toContain("still connected");
+
+    await pageO.close();
+    await expect(pageX.locator(".claim")).toContainText("your opponent left", { timeout: 5000 });
+    res = await xContext.request.post(`/api/game/${id}/claim`, asX);
+    expect(res.status()).toBe(409);
+    expect(res.headers()["retry-after"]).toBeDefined();
+    // Only the player's own seat token claims for them
+    res = await oContext.request.post(`/api/game/${id}/claim`, { data: { player: "X" } });
+    expect(res.status()).toBe(403);
+    expect((await res.json()).code).toBe("seat_token");
+
+    await pageX.locator("button", { hasText: "[claim win]" }).click({ timeout: 5000 });
+    await expect(pageX.locator("#status")).toContainText("the opponent left");
+    const game = await (await xContext.request.get(`/api/game/${id}`)).json();
+    expect(game).toMatchObject({ isOver: true, winner: "X", winClaimed: true });
+
+    await xContext.close();
+    await oContext.close();
+  });
+
+  test("should withdraw the claim when the opponent comes back", async ({ browser }) => {
+    const xContext = await browser.newContext();
+    const oContext = await browser.newContext();
+    const created = await xContext.request.post("/htmx/game/new?player=X");
+    const id = /data-game-id="([^"]+)"/.exec(await created.text())![1];
+    await oContext.request.post(`/htmx/join/${id}?player=O`);
+    await xContext.request.post(`/htmx/move/${id}/0?player=X`);
+
+    const pageX = await xContext.newPage();
+    await pageX.goto(`/?game=${id}&player=X`);
+    await expect(pageX.locator(".claim")).toContainText("your opponent left", { timeout: 5000 });
+
+    const pageO = await oContext.newPage();
+    await pageO.goto(`/?game=${id}&player=O`);
+    await expect(pageX.locator(".claim")).toHaveCount(0, { timeout: 5000 });
+    const res = await xContext.request.get(`/api/game/${id}/claim?player=X`);
+    expect(res.status()).toBe(409);
+
+    await xContext.close();
+    await oContext.close();
+  });
+});
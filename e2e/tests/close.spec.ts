@@ -0,0 +1,66 @@
+import { test, expect, Page } from "@playwright/test";
+
+/** Opens a WebSocket in the page and resolves with its close code and reason. */
+function closed(page: Page, url: string, onOpen = "") {
+  return page.evaluate(
+    ([url, onOpen]) =>
+      new Promise<{ code: number; reason: string }>((resolve) => {
+        const ws = new WebSocket(url);
+        ws.onclose = (e) => resolve({ code: e.code, reason: e.reason });
+        ws.onmessage = () => {
+          if (onOpen === "close") ws.close(1000, "bye");
+          if (onOpen === "ready") (window as any).wsReady = true;
+        };
+      }),
+    [url, onOpen] as const
+  );
+}
+
+test.describe("WebSocket close codes", () => {
+  test("should send 4001 when the game is deleted", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+    await page.goto("/");
+
+    const result = closed(page, `${baseURL!.replace(/^http/, "ws")}/ws/${id}`, "ready");
+    await page.waitForFunction(() => (window as any).wsReady);
+    await request.post(`/htmx/cancel/${id}`);
+    expect(await result).toEqual({ code: 4001, reason: "game deleted" });
+  });
+
+  test("should send 1008 to a flooding client", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    await page.goto("/");
+    const result = page.evaluate(
+      (url) =>
+        new Promise<number>((resolve) => {
+          const ws = new WebSocket(url);
+          ws.onclose = (e) => resolve(e.code);
+          ws.onopen = () => {
+            for (let i = 0; i < 100; i++) ws.send(JSON.stringify({ position: 9 }));
+          };
+        }),
+      `${baseURL!.replace(/^http/, "ws")}/ws/${id}`
+    );
+    expect(await result).toBe(1008);
+  });
+
+  test("should answer a normal close with 1000", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    await page.goto("/");
+    const result = await closed(page, `${baseURL!.replace(/^http/, "ws")}/ws/${id}`, "close");
+    expect(result.code).toBe(1000);
+  });
+});
@@ -0,0 +1,81 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+/** Starts a game with both sides joined and a commentator appointed by X. */
+async function commentedGame(request: APIRequestContext) {
+  const { id } = await (await request.post("/api/game", { data: {} })).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  const res = await request.post(`/api/game/${id}/commentator`, { data: { player: "X" } });
+  expect(res.status()).toBe(201);
+  const { token } = await res.json();
+  return { id: id as string, token: token as string };
+}
+
+test.describe("Commentary", () => {
+  test("should show spectators commentary as it is written", async ({ page, request }) => {
+    const { id, token } = await commentedGame(request);
+    await page.goto(`/?game=${id}`);
+    await expect(page.locator(".commentary")).toBeVisible();
+
+    await request.post(`/api/game/${id}`, { data: { position: 4, player: "X" } });
+    const res = await request.post(`/api/game/${id}/commentary`, {
+      data: { token, text: "X takes the centre" },
+    });
+    expect(res.status()).toBe(201);
+    expect(await res.json()).toMatchObject({ move: 1, text: "X takes the centre" });
+    const line = page.locator(".commentary-line");
+    await expect(line).toContainText("move 1: X takes the centre");
+    await expect(line).toHaveAttribute("data-move", "1");
+  });
+
+  test("should show a late spectator the commentary so far", async ({ page, request }) => {
+    const { id, token } = await commentedGame(request);
+    await request.post(`/api/game/${id}/commentary`, { data: { token, text: "Here we go" } });
+    await page.goto(`/?game=${id}`);
+    await expect(page.locator(".commentary-line")).toHaveText("move 0: Here we go");
+
+    const log = await (await request.get(`/api/game/${id}/commentary`)).json();
+    expect(log).toMatchObject({ gameId: id, commentator: true });
+    expect(log.lines.map((l: { text: string }) => l.text)).toEqual(["Here we go"]);
+  });
+
+  test("should leave commentary off players' boards unless they ask", async ({ page, request }) => {
+    const { id } = await (await request.post("/api/game", { data: {} })).json();
+    // Join X from the page's browser, whose seat cookie the board needs
+    await page.request.post(`/htmx/join/${id}?player=X`);
+    await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+    await page.goto(`/?game=${id}&player=X`);
+    await expect(page.locator(".board")).toBeVisible();
+    await expect(page.locator(".commentary")).toHaveCount(0);
+
+    const { token } = await (
+      await request.post(`/api/game/${id}/commentator`, { data: { player: "O" } })
+    ).json();
+    await page.goto(`/?game=${id}&player=X&commentary=1`);
+    await expect(page.locator(".commentary")).toBeVisible();
+    await request.post(`/api/game/${id}/commentary`, { data: { token, text: "Good luck" } });
+    await expect(page.locator(".commentary-line")).toContainText("Good luck");
+  });
+
+  test("should turn the commentator away once revoked or replaced", async ({ request }) => {
+    const { id, token } = await commentedGame(request);
+    const next = await (
+      await request.post(`/api/game/${id}/commentator`, { data: { player: "O" } })
+    ).json();
+    const stale = await request.post(`/api/game/${id}/commentary`, { data: { token, text: "hi" } });
+    expect(stale.status()).toBe(403);
+
+    const revoked = await request.delete(`/api/game/${id}/commentator`, { data: { player: "X" } });
+    expect(revoked.status()).toBe(204);
+    const after = await request.post(`/api/game/${id}/commentary`, {
+      data: { token: next.token, text: "hi" },
+    });
+    expect(after.status()).toBe(403);
+  });
+
+  test("should only let a joined player appoint the commentator", async ({ request }) => {
+    const { id } = await (await request.post("/api/game", { data: {} })).json();
+    const res = await request.post(`/api/game/${id}/commentator`, { data: { player: "X" } });
+    expect(res.status()).toBe(403);
+  });
+});
@@ -0,0 +1,50 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Playing the computer", () => {
+  test("should show the computer thinking, then its reply", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[vs-computer]" }).click();
+    await expect(page.locator("#status")).toContainText("your_turn");
+
+    await page.locator(".cell").nth(0).click();
+    await expect(page.locator(".cell").nth(0)).toHaveText("X");
+    await expect(page.locator("#status")).toContainText("opponent is thinking");
+
+    // Taking the centre is the only move that doesn't lose
+    await expect(page.locator(".cell").nth(4)).toHaveText("O", { timeout: 5_000 });
+    await expect(page.locator("#status")).toContainText("your_turn");
+  });
+
+  test("should answer a move without holding up its response", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "ai" } })).json();
+    expect(game.playerOJoined).toBe(true);
+
+    const state = await (await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } })).json();
+    expect(state.thinking).toBe(true);
+    expect(state.currentTurn).toBe("O");
+
+    await expect
+      .poll(async () => (await (await request.get(`/api/game/${game.id}`)).json()).currentTurn, { timeout: 5_000 })
+      .toBe("X");
+  });
+
+  test("should drop its move when the game is reset meanwhile", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "ai" } })).json();
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+    await request.put(`/api/game/${game.id}`);
+
+    await new Promise((resolve) => setTimeout(resolve, 1_500));
+    const state = await (await request.get(`/api/game/${game.id}`)).json();
+    expect(state.board).toEqual(Array(9).fill(""));
+    expect(state.currentTurn).toBe("X");
+  });
+
+  test("should not let anyone play the computer's side", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "ai" } })).json();
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+
+    const res = await request.post(`/api/game/${game.id}`, { data: { player: "O", position: 1 } });
+    expect(res.status()).toBe(409);
+    expect((await res.json()).error).toContain("computer plays that side");
+  });
+});
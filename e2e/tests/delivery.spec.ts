@@ -0,0 +1,48 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+const admin = { Authorization: "Bearer e2e-admin" };
+
+/** Reads a sample's value from the Prometheus text at /metrics. */
+async function sample(request: APIRequestContext, name: string) {
+  const text = await (await request.get("/metrics")).text();
+  const line = text.split("\n").find((l) => l.startsWith(name + " "));
+  return line ? Number(line.split(" ")[1]) : NaN;
+}
+
+test.describe("Delivery lag", () => {
+  test("should time each update written to WebSocket and SSE subscribers", async ({ page, request, baseURL }) => {
+    const count = (t: string) => sample(request, `tiktaktoes_hub_delivery_lag_seconds_count{transport="${t}"}`);
+    const [ws, sse] = [await count("ws"), await count("sse")];
+
+    const { id } = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    await page.goto("/");
+    await page.evaluate(
+      ([ws, sse]) =>
+        Promise.all([
+          new Promise((resolve) => (new WebSocket(ws).onopen = resolve)),
+          new Promise((resolve) => (new EventSource(sse).onopen = resolve)),
+        ]),
+      [`${baseURL!.replace(/^http/, "ws")}/ws/${id}`, `/htmx/sse/${id}`]
+    );
+    await request.post(`/api/game/${id}`, { data: { position: 4, player: "X" } });
+    await request.post(`/api/game/${id}`, { data: { position: 0, player: "O" } });
+
+    await expect.poll(() => count("ws")).toBeGreaterThanOrEqual(ws + 2);
+    await expect.poll(() => count("sse")).toBeGreaterThanOrEqual(sse + 2);
+    const inf = await sample(request, `tiktaktoes_hub_delivery_lag_seconds_bucket{transport="ws",le="+Inf"}`);
+    expect(inf).toBe(await count("ws"));
+  });
+
+  test("should show the same figures in the hub stats", async ({ request }) => {
+    const stats = await (await request.get("/api/admin/hub", { headers: admin })).json();
+    for (const transport of ["ws", "sse"]) {
+      const lag = stats.delivery[transport];
+      expect(typeof lag.count).toBe("number");
+      expect(typeof lag.sumSeconds).toBe("number");
+      expect(typeof lag.dropped).toBe("number");
+      expect(lag.buckets.map((b: any) => b.le)).toEqual([0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5]);
+      const counts = lag.buckets.map((b: any) => b.count);
+      expect(counts).toEqual([...counts].sort((a, b) => a - b));
+    }
+  });
+});
@@ -0,0 +1,127 @@
+import { test, expect, Page, APIRequestContext } from "@playwright/test";
+
+/** Opens a WebSocket in the page under name, keeping its state and delta frames. */
+async function connect(page: Page, name: string, url: string, ack: boolean) {
+  await page.evaluate(
+    ([name, url, ack]) =>
+      new Promise<void>((resolve) => {
+        const w = window as any;
+        w.frames_ = w.frames_ || {};
+        const frames: any[] = (w.frames_[name] = []);
+        const ws = new WebSocket(url as string);
+        ws.onmessage = (e) => {
+          const msg = JSON.parse(e.data);
+          if (msg.type === "state" || msg.type === "delta" || msg.board) {
+            frames.push(msg);
+          }
+          if (ack && (msg.type === "state" || msg.type === "delta")) {
+            const version = msg.type === "state" ? msg.version : msg.toVersion;
+            ws.send(JSON.stringify({ type: "ack", version }));
+          }
+          resolve();
+        };
+      }),
+    [name, url, ack] as const
+  );
+}
+
+/** Waits for count frames on the named socket and returns them. */
+async function frames(page: Page, name: string, count: number) {
+  await page.waitForFunction(
+    ([name, count]) => (window as any).frames_[name].length >= count,
+    [name, count] as const
+  );
+  return page.evaluate((name) => (window as any).frames_[name], name);
+}
+
+async function newGame(request: APIRequestContext): Promise<string> {
+  const { id } = await (await request.post("/api/game")).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  return id;
+}
+
+/** Plays X 0, O 3, X 1, O 4, X 2: a win for X across the top row. */
+async function playWin(request: APIRequestContext, id: string) {
+  const moves: [number, string][] = [[0, "X"], [3, "O"], [1, "X"], [4, "O"], [2, "X"]];
+  for (const [position, player] of moves) {
+    await request.post(`/api/game/${id}`, { data: { position, player } });
+  }
+}
+
+test.describe("WebSocket deltas", () => {
+  test("should rebuild the board from deltas across a game", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const id = await newGame(request);
+    await page.goto("/");
+    await connect(page, "bot", `${baseURL!.replace(/^http/, "ws")}/ws/${id}?delta=1`, true);
+    await playWin(request, id);
+
+    const [first, ...deltas] = await frames(page, "bot", 6);
+    expect(first.type).toBe("state");
+    const board = [...first.game.board];
+    let version = first.version;
+    for (const d of deltas) {
+      expect(d.type).toBe("delta");
+      expect(d.fromVersion).toBe(version);
+      for (const c of d.changes) board[c.pos] = c.player;
+      version = d.toVersion;
+    }
+
+    const game = await (await request.get(`/api/game/${id}`)).json();
+    expect(board).toEqual(game.board);
+    expect(deltas.at(-1).status).toBe("won");
+    expect(deltas.at(-1).winner).toBe("X");
+  });
+
+  test("should fall back to the full state once the acked version is too old", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const id = await newGame(request);
+    await page.goto("/");
+    await connect(page, "lazy", `${baseURL!.replace(/^http/, "ws")}/ws/${id}?delta=1`, false);
+
+    // Each round is five moves and a reset, which keeps both players
+    // seated; three of them push the state the client last acked out of
+    // the window
+    for (let round = 0; round < 3; round++) {
+      await playWin(request, id);
+      await request.put(`/api/game/${id}`);
+    }
+    await request.post(`/api/game/${id}`, { data: { position: 8, player: "X" } });
+
+    const all = await frames(page, "lazy", 20);
+    expect(all[1]).toMatchObject({ type: "delta", fromVersion: 1 });
+    expect(all.at(-1)).toMatchObject({ type: "state", version: 20 });
+    expect(all.at(-1).game.board[8]).toBe("X");
+  });
+
+  test("should send full and delta subscribers of one game their own frames", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const id = await newGame(request);
+    const wsURL = `${baseURL!.replace(/^http/, "ws")}/ws/${id}`;
+    await page.goto("/");
+    await connect(page, "full", wsURL, false);
+    await connect(page, "delta", `${wsURL}?delta=1`, true);
+    await request.post(`/api/game/${id}`, { data: { position: 4, player: "X" } });
+
+    const full = await frames(page, "full", 2);
+    expect(full[1].type).toBeUndefined();
+    expect(full[1].board[4]).toBe("X");
+    const delta = await frames(page, "delta", 2);
+    expect(delta[1]).toMatchObject({
+      type: "delta",
+      changes: [{ pos: 4, player: "X" }],
+      currentTurn: "O",
+      status: "playing",
+    });
+  });
+});
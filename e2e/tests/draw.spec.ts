@@ -0,0 +1,84 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+/** Creates a game both sides have joined, plays X's first move, and returns it with each side's seat token. */
+async function startedGame(request: APIRequestContext) {
+  const { id } = await (await request.post("/api/game")).json();
+  const x = (await request.post(`/api/game/${id}/join`, { data: { player: "X" } })).headers()["x-seat-token"];
+  const o = (await request.post(`/api/game/${id}/join`, { data: { player: "O" } })).headers()["x-seat-token"];
+  await request.post(`/api/game/${id}`, { data: { position: 0, player: "X" } });
+  return { id: id as string, x, o };
+}
+
+/** A draw request's options for player, with their seat token. */
+function as(player: string, token: string, data: object = {}) {
+  return { headers: { "X-Seat-Token": token }, data: { player, ...data } };
+}
+
+test.describe("Draw offers", () => {
+  test("should end the game when the opponent accepts", async ({ request }) => {
+    const { id, x, o } = await startedGame(request);
+
+    const offered = await request.post(`/api/game/${id}/draw`, as("O", o));
+    expect((await offered.json()).drawOffer).toBe("O");
+
+    const own = await request.post(`/api/game/${id}/draw/reply`, as("O", o, { accept: true }));
+    expect(own.status()).toBe(409);
+    // only X's seat token answers for X
+    const forged = await request.post(`/api/game/${id}/draw/reply`, as("X", o, { accept: true }));
+    expect(forged.status()).toBe(403);
+    expect((await forged.json()).code).toBe("seat_token");
+
+    const accepted = await (await request.post(`/api/game/${id}/draw/reply`, as("X", x, { accept: true }))).json();
+    expect(accepted.isDraw).toBe(true);
+    expect(accepted.isOver).toBe(true);
+    expect(accepted.drawAgreed).toBe(true);
+    expect(accepted.legalMoves).toEqual([]);
+  });
+
+  test("should allow one offer a turn and expire it on a move", async ({ request }) => {
+    const { id, x, o } = await startedGame(request);
+
+    expect((await request.post(`/api/game/${id}/draw`, as("X", x))).status()).toBe(409);
+    await request.post(`/api/game/${id}/draw`, as("O", o));
+    await request.post(`/api/game/${id}/draw/reply`, as("X", x, { accept: false }));
+    expect((await request.post(`/api/game/${id}/draw`, as("O", o))).status()).toBe(409);
+
+    await request.post(`/api/game/${id}/draw`, as("O", o));
+    const moved = await (
+      await request.post(`/api/game/${id}`, { data: { position: 4, player: "O" } })
+    ).json();
+    expect(moved.drawOffer).toBeUndefined();
+  });
+
+  test("should offer over WebSocket", async ({ page, request, baseURL }) => {
+    const { id, o } = await startedGame(request);
+    await page.goto("/");
+    const state = await page.evaluate(
+      (url) =>
+        new Promise<any>((resolve) => {
+          const ws = new WebSocket(url);
+          ws.onmessage = (e) => {
+            const msg = JSON.parse(e.data);
+            if (msg.drawOffer) resolve(msg);
+            else ws.send(JSON.stringify({ type: "offer-draw", player: "O" }));
+          };
+        }),
+      `${baseURL!.replace(/^http/, "ws")}/ws/${id}?player=O&seat=${encodeURIComponent(o)}`
+    );
+    expect(state.drawOffer).toBe("O");
+  });
+
+  test("should let the opponent accept in the page", async ({ page, request }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    // Join X from the page's browser, whose seat cookie the board needs
+    await page.request.post(`/htmx/join/${id}?player=X`);
+    const o = (await request.post(`/api/game/${id}/join`, { data: { player: "O" } })).headers()["x-seat-token"];
+    await request.post(`/api/game/${id}`, { data: { position: 0, player: "X" } });
+    await request.post(`/api/game/${id}/draw`, as("O", o));
+
+    await page.goto(`/?game=${id}&player=X`);
+    await expect(page.locator("#drawOffer")).toContainText("O offers a draw");
+    await page.locator("button", { hasText: "[accept draw]" }).click();
+    await expect(page.locator("#status")).toContainText("result: draw by agreement");
+  });
+});
@@ -0,0 +1,66 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+/** Creates a hot-seat game and plays the moves in turn, X first. */
+async function play(request: APIRequestContext, moves: number[], settings: object = { earlyDraw: true }) {
+  const { id } = await (await request.post("/api/game", { data: { mode: "hotseat", ...settings } })).json();
+  for (const [i, position] of moves.entries()) {
+    const res = await request.post(`/api/game/${id}`, { data: { position, player: i % 2 ? "O" : "X" } });
+    expect(res.ok()).toBe(true);
+  }
+  return id as string;
+}
+
+async function state(request: APIRequestContext, id: string) {
+  return (await request.get(`/api/game/${id}`)).json();
+}
+
+test.describe("Early draws", () => {
+  // X: 0 1 5 6, O: 2 3 4, O to move with 7 and 8 left, neither of which
+  // completes a line for anyone
+  const dead = [0, 4, 1, 2, 6, 3, 5];
+
+  test("should end a dead position as a draw", async ({ request }) => {
+    const id = await play(request, dead);
+    await expect.poll(async () => (await state(request, id)).isOver).toBe(true);
+    const g = await state(request, id);
+    expect(g.isDraw).toBe(true);
+    expect(g.winner).toBe("");
+    expect(g.drawReason).toBe("dead_position");
+    expect(g.board.filter((c: string) => c === "")).toHaveLength(2);
+    expect((await request.post(`/api/game/${id}`, { data: { position: 7, player: "O" } })).status()).toBe(409);
+  });
+
+  test("should end a dead position reached with one cell left", async ({ request }) => {
+    // X: 1 3 4 8, O: 0 2 5 7, X to move with only 6, which makes no line
+    const id = await play(request, [4, 0, 8, 2, 1, 7, 3, 5]);
+    await expect.poll(async () => (await state(request, id)).drawReason).toBe("dead_position");
+  });
+
+  test("should play on while a line can still be made", async ({ request }) => {
+    // One move before the dead position, O still wins on 5 if X lets it
+    const id = await play(request, dead.slice(0, -1));
+    // X: 1 3 4 8, O: 0 2 7, O to move: taking 6 would let X win on 5
+    const other = await play(request, [4, 0, 8, 2, 1, 7, 3]);
+    await new Promise((resolve) => setTimeout(resolve, 500));
+    for (const g of [await state(request, id), await state(request, other)]) {
+      expect(g.isOver).toBe(false);
+      expect(g.drawReason).toBeUndefined();
+    }
+  });
+
+  test("should be off unless asked for", async ({ request }) => {
+    const id = await play(request, dead, {});
+    await new Promise((resolve) => setTimeout(resolve, 500));
+    const g = await state(request, id);
+    expect(g.isOver).toBe(false);
+    expect(g.earlyDraw).toBeUndefined();
+  });
+
+  test("should tell players why the game ended", async ({ page, request }) => {
+    const id = await play(request, dead.slice(0, -1));
+    await page.goto(`/?game=${id}`);
+    await expect(page.locator(".board")).toBeVisible();
+    await request.post(`/api/game/${id}`, { data: { position: 5, player: "X" } });
+    await expect(page.locator("#status")).toContainText("neither side can win anymore");
+  });
+});
@@ -0,0 +1,79 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+/** Creates an online game with both sides seated and returns its ID. */
+async function seated(request: APIRequestContext) {
+  const { id } = await (await request.post("/api/game")).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  return id as string;
+}
+
+test.describe("Embedded games", () => {
+  test("should be served as a standalone page any site may frame", async ({ request }) => {
+    const id = await seated(request);
+    const res = await request.get(`/embed/${id}?player=spectator`);
+    expect(res.status()).toBe(200);
+    expect(res.headers()["content-security-policy"]).toBe("frame-ancestors *");
+    const html = await res.text();
+    expect(html).toMatch(/^<!doctype html>/i);
+    expect(html).toContain(`sse-connect="/htmx/sse/${id}?embed=1"`);
+    expect(html).not.toContain("hx-post");
+    expect(html).not.toContain("player-select");
+
+    expect((await request.get("/embed/nope")).status()).toBe(404);
+  });
+
+  test("should show the board read-only and follow the game live", async ({ page, request }) => {
+    const id = await seated(request);
+    // Even a browser seated as X only watches from the embed
+    await request.post(`/api/game/${id}`, { data: { player: "X", position: 4 } });
+    await page.goto(`/embed/${id}?player=X`);
+    await expect(page.locator(".board .cell")).toHaveCount(9);
+    await expect(page.locator(".cell[hx-post]")).toHaveCount(0);
+    await expect(page.locator(".cell").nth(4)).toHaveText("X");
+    await expect(page.locator(".embed-link")).toHaveAttribute("href", new RegExp(`/\\?game=${id}$`));
+
+    await request.post(`/api/game/${id}`, { data: { player: "O", position: 0 } });
+    await expect(page.locator(".cell").nth(0)).toHaveText("O");
+    await expect(page.locator(".cell[hx-post]")).toHaveCount(0);
+  });
+});
+
+test.describe("oEmbed", () => {
+  test("should describe a share link as a framed embed page", async ({ request, baseURL }) => {
+    const id = await seated(request);
+    for (const url of [`${baseURL}/?game=${id}`, `${baseURL}/embed/${id}`]) {
+      const res = await request.get(`/api/oembed?url=${encodeURIComponent(url)}`);
+      expect(res.status()).toBe(200);
+      const body = await res.json();
+      expect(body).toMatchObject({
+        version: "1.0",
+        type: "rich",
+        provider_name: "Tic Tac Toe",
+        provider_url: `${baseURL}/`,
+        width: 260,
+        height: 330,
+      });
+      expect(body.html).toContain(`<iframe src="${baseURL}/embed/${id}" width="260" height="330"`);
+    }
+  });
+
+  test("should fit within maxwidth and maxheight", async ({ request, baseURL }) => {
+    const id = await seated(request);
+    const url = encodeURIComponent(`${baseURL}/?game=${id}`);
+    const body = await (await request.get(`/api/oembed?url=${url}&maxwidth=200&maxheight=400`)).json();
+    expect(body.width).toBe(200);
+    expect(body.height).toBe(330);
+    expect(body.html).toContain(`width="200" height="330"`);
+  });
+
+  test("should refuse links it can't embed", async ({ request, baseURL }) => {
+    const id = await seated(request);
+    expect((await request.get(`/api/oembed?url=${encodeURIComponent(`https://elsewhere.example/embed/${id}`)}`)).status()).toBe(404);
+    expect((await request.get(`/api/oembed?url=${encodeURIComponent(`${baseURL}/?game=nope`)}`)).status()).toBe(404);
+    expect((await request.get("/api/oembed")).status()).toBe(404);
+    const url = encodeURIComponent(`${baseURL}/embed/${id}`);
+    expect((await request.get(`/api/oembed?url=${url}&format=xml`)).status()).toBe(501);
+    expect((await request.get(`/api/oembed?url=${url}&maxwidth=wide`)).status()).toBe(400);
+  });
+});
@@ -0,0 +1,57 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+/** Creates a game with both sides joined. */
+async function startedGame(request: APIRequestContext) {
+  const { id } = await (await request.post("/api/game", { data: {} })).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  return id as string;
+}
+
+test.describe("Error codes", () => {
+  test("should answer a move out of turn alike over the API and the page's endpoints", async ({ request }) => {
+    const id = await startedGame(request);
+
+    const api = await request.post(`/api/game/${id}`, { data: { player: "O", position: 0 } });
+    expect(api.status()).toBe(409);
+    const body = await api.json();
+    expect(body).toMatchObject({ code: "not_your_turn" });
+    expect(body.temporary).toBeUndefined();
+
+    const htmx = await request.post(`/htmx/move/${id}/0?player=O`, { headers: { Accept: "application/json" } });
+    expect(htmx.status()).toBe(409);
+    expect(await htmx.json()).toMatchObject({ code: "not_your_turn" });
+  });
+
+  test("should tell bad input from a conflict", async ({ request }) => {
+    const id = await startedGame(request);
+    const bad = await request.post(`/api/game/${id}`, { data: { player: "X", position: 12 } });
+    expect(bad.status()).toBe(400);
+    expect(await bad.json()).toMatchObject({ code: "invalid_move" });
+
+    await request.post(`/api/game/${id}`, { data: { player: "X", position: 4 } });
+    const taken = await request.post(`/api/game/${id}`, { data: { player: "O", position: 4 } });
+    expect(taken.status()).toBe(409);
+    expect(await taken.json()).toMatchObject({ code: "position_taken" });
+  });
+
+  test("should give WebSocket error frames the same code", async ({ page, request, baseURL }) => {
+    const id = await startedGame(request);
+    await page.goto("/");
+    const frame = await page.evaluate(
+      ({ url, id }) =>
+        new Promise<any>((resolve) => {
+          const ws = new WebSocket(url);
+          ws.onopen = () => ws.send(JSON.stringify({ type: "move", gameId: id, player: "O", position: 0 }));
+          ws.onmessage = (e) => {
+            const frame = JSON.parse(e.data);
+            if (frame.error) {
+              resolve(frame);
+            }
+          };
+        }),
+      { url: `${baseURL!.replace(/^http/, "ws")}/ws/${id}?player=O`, id }
+    );
+    expect(frame.code).toBe("not_your_turn");
+  });
+});
@@ -0,0 +1,71 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Error events", () => {
+  test("should only tell the player whose move was rejected", async ({
+    browser,
+  }) => {
+    const context = await browser.newContext();
+    const pageX = await context.newPage();
+    await pageX.goto("/");
+    await pageX.locator("button", { hasText: "[new]" }).click();
+    const gameId = await pageX
+      .locator("[data-game-id][data-player]")
+      .getAttribute("data-game-id");
+
+    const pageO = await context.newPage();
+    await pageO.goto(`/?game=${gameId}&player=O`);
+    await pageO.locator("button", { hasText: "[join]" }).click();
+    await expect(pageX.locator("#status")).toContainText("your_turn", {
+      timeout: 5000,
+    });
+
+    // O moves out of turn
+    await pageO.locator(".cell").nth(4).click();
+    await expect(pageO.locator(".toast")).toContainText("not your turn", {
+      timeout: 5000,
+    });
+    await expect(pageO.locator(".toast")).toHaveAttribute(
+      "data-code",
+      "not_your_turn"
+    );
+    await expect(pageX.locator(".toast")).toHaveCount(0);
+
+    await context.close();
+  });
+
+  test("should send WebSocket clients an error frame", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const created = await request.post("/api/game");
+    const { id } = await created.json();
+    await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+    const joined = await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+    const seat = encodeURIComponent(joined.headers()["x-seat-token"]);
+
+    await page.goto("/");
+    const wsURL = `${baseURL!.replace(/^http/, "ws")}/ws/${id}?player=O&seat=${seat}`;
+    const frame = page.evaluate(
+      ([url, moveURL]) =>
+        new Promise<any>((resolve) => {
+          const ws = new WebSocket(url);
+          ws.onmessage = (e) => {
+            const msg = JSON.parse(e.data);
+            if (msg.type === "game-error") resolve(msg);
+            // O moves out of turn over HTTP, on this socket's behalf
+            if (msg.type === "welcome")
+              fetch(moveURL, {
+                method: "POST",
+                headers: { "X-Conn-ID": msg.data.connId },
+                body: JSON.stringify({ position: 4, player: "O" }),
+              });
+          };
+        }),
+      [wsURL, `${baseURL}/api/game/${id}`]
+    );
+    const msg = await frame;
+    expect(msg.data.code).toBe("not_your_turn");
+    expect(msg.data.gameId).toBe(id);
+  });
+});
@@ -0,0 +1,37 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Event sequence numbers", () => {
+  test("should fill a gap with the events after the last one seen", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    for (const [player, position] of [["X", 0], ["O", 4], ["X", 8]] as const) {
+      await request.post(`/api/game/${game.id}`, { data: { player, position } });
+    }
+
+    // A client that saw event 1 and then event 3 knows it missed event 2
+    const res = await request.get(`/api/game/${game.id}/events?fromSeq=1`);
+    expect(res.ok()).toBeTruthy();
+    const body = await res.json();
+    expect(body.seq).toBe(3);
+    expect(body.complete).toBe(true);
+    expect(body.events.map((e: { seq: number }) => e.seq)).toEqual([2, 3]);
+    expect(body.events[0].type).toBe("game-update");
+    expect(body.events[0].game.board[4]).toBe("O");
+    expect(body.events[1].game.board[8]).toBe("X");
+  });
+
+  test("should say when the events asked for aren't kept", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+
+    const body = await (await request.get(`/api/game/${game.id}/events?fromSeq=50`)).json();
+    expect(body.complete).toBe(false);
+    expect(body.events).toEqual([]);
+  });
+
+  test("should reject a malformed fromSeq", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: {} })).json();
+
+    const res = await request.get(`/api/game/${game.id}/events?fromSeq=-1`);
+    expect(res.status()).toBe(400);
+  });
+});
@@ -0,0 +1,68 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+const ADMIN = { Authorization: "Bearer e2e-admin" };
+
+/** Starts a game and plays positions in turn, X first. */
+async function play(request: APIRequestContext, positions: number[]) {
+  const game = await (await request.post("/api/game", { data: {} })).json();
+  await request.post(`/api/game/${game.id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${game.id}/join`, { data: { player: "O" } });
+  for (const [i, position] of positions.entries()) {
+    await request.post(`/api/game/${game.id}`, { data: { position, player: i % 2 ? "O" : "X" } });
+  }
+  return game.id as string;
+}
+
+test.describe("Featured games", () => {
+  test("should feature a win in three moves and not a draw", async ({ request }) => {
+    const quick = await play(request, [0, 3, 1, 4, 2]);
+    const drawn = await play(request, [0, 4, 8, 1, 7, 6, 2, 5, 3]);
+    await expect(async () => {
+      const feed = await (await request.get("/api/featured")).json();
+      const ids = feed.map((e: { gameId: string }) => e.gameId);
+      expect(ids).toContain(quick);
+      expect(ids).not.toContain(drawn);
+      expect(feed.find((e: { gameId: string }) => e.gameId === quick).reason).toBe("fastest-win");
+    }).toPass();
+  });
+
+  test("should feature a comeback", async ({ request }) => {
+    // O has a forced win at one point and lets it slip
+    const id = await play(request, [0, 1, 2, 3, 5, 7, 6, 8, 4]);
+    await expect(async () => {
+      const feed = await (await request.get("/api/featured")).json();
+      expect(feed.find((e: { gameId: string }) => e.gameId === id)?.reason).toBe("comeback");
+    }).toPass();
+  });
+
+  test("should add featured games to the front page live", async ({ page, request }) => {
+    await page.goto("/");
+    await expect(page.locator("#featured")).toBeAttached();
+    const id = await play(request, [0, 3, 1, 4, 2]);
+    await expect(page.locator(`#featured-${id}`)).toContainText("won in three moves");
+    await expect(page.locator(`#featured-${id} a`)).toHaveAttribute("href", `/?game=${id}`);
+  });
+
+  test("should keep pinned games at the top", async ({ page, request }) => {
+    const id = await play(request, [0, 3, 1, 4, 2]);
+    await play(request, [0, 3, 1, 4, 2]);
+    await page.goto("/");
+
+    const pinned = await request.put(`/api/admin/featured/${id}/pin`, { headers: ADMIN });
+    expect(pinned.status()).toBe(200);
+    expect((await pinned.json())[0]).toMatchObject({ gameId: id, pinned: true });
+    await expect(page.locator("#featured li").first()).toHaveAttribute("id", `featured-${id}`);
+    await expect(page.locator(`#featured-${id}`)).toContainText("[pinned]");
+
+    const unpinned = await request.delete(`/api/admin/featured/${id}/pin`, { headers: ADMIN });
+    expect(unpinned.status()).toBe(200);
+    await expect(page.locator(`#featured-${id}`)).not.toContainText("[pinned]");
+  });
+
+  test("should refuse to pin a game that isn't featured", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: {} })).json();
+    const res = await request.put(`/api/admin/featured/${game.id}/pin`, { headers: ADMIN });
+    expect(res.status()).toBe(404);
+    expect((await request.put(`/api/admin/featured/${game.id}/pin`)).status()).toBe(401);
+  });
+});
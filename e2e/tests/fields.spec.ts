@@ -0,0 +1,21 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Field selection", () => {
+  test("should return only the fields asked for", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+
+    const res = await request.get(`/api/game/${game.id}?fields=board,currentTurn`);
+    expect(res.ok()).toBeTruthy();
+    expect(await res.json()).toEqual({ board: Array(9).fill(""), currentTurn: "X" });
+  });
+
+  test("should reject unknown fields, listing the valid ones", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: {} })).json();
+
+    const res = await request.get(`/api/game/${game.id}?fields=board,status`);
+    expect(res.status()).toBe(400);
+    const body = await res.json();
+    expect(body.error).toContain('"status"');
+    expect(body.error).toContain("currentTurn");
+  });
+});
@@ -0,0 +1,40 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("WebSocket flood protection", () => {
+  test("should disconnect a flooding client and keep serving a polite one", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    const wsURL = `${baseURL!.replace(/^http/, "ws")}/ws/${id}`;
+    await page.goto("/");
+
+    const result = await page.evaluate(
+      (url) =>
+        new Promise<{ closeCode: number; politeBoard: string[] }>((resolve) => {
+          const polite = new WebSocket(url);
+          polite.onopen = () => {
+            const flood = new WebSocket(url);
+            flood.onopen = () => {
+              for (let i = 0; i < 200; i++) {
+                flood.send(JSON.stringify({ position: 9, player: "X" }));
+              }
+            };
+            flood.onclose = (e) => {
+              polite.onmessage = (m) => {
+                const msg = JSON.parse(m.data);
+                if (msg.board) resolve({ closeCode: e.code, politeBoard: msg.board });
+              };
+              polite.send(JSON.stringify({ position: 4, player: "X" }));
+            };
+          };
+        }),
+      wsURL
+    );
+
+    // 1008 is the policy violation close code
+    expect(result.closeCode).toBe(1008);
+    expect(result.politeBoard[4]).toBe("X");
+  });
+});
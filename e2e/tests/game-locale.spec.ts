@@ -0,0 +1,57 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Game locale", () => {
+  test("should share a game in its own language while each player sees theirs", async ({ browser }) => {
+    const en = await browser.newContext({ locale: "en" });
+    const es = await browser.newContext({ locale: "es" });
+
+    // An English X creates the game and shares it in English
+    const pageX = await en.newPage();
+    await pageX.goto("/");
+    await pageX.locator("button", { hasText: "[new]" }).click();
+    const gameId = await pageX.locator("[data-game-id][data-player]").getAttribute("data-game-id");
+    await pageX.locator("select[name=locale]").selectOption("en");
+    await expect(pageX.locator("select[name=locale]")).toHaveValue("en");
+
+    // A Spanish O joins and sees the game in Spanish
+    const pageO = await es.newPage();
+    await pageO.goto(`/?game=${gameId}&player=O`);
+    await pageO.locator("button", { hasText: "[unirse]" }).click();
+    await expect(pageO.locator("#status")).toContainText("esperando: X");
+    await expect(pageX.locator("#status")).toContainText("your_turn", { timeout: 5000 });
+
+    // The game's updates reach each in their own language
+    await pageX.locator('[data-cell="a1"]').click();
+    await expect(pageO.locator("#status")).toContainText("tu_turno", { timeout: 5000 });
+    await expect(pageX.locator("#status")).toContainText("waiting: O");
+
+    // What is shared is in the game's language, whoever asks
+    const embed = await es.newPage();
+    await embed.goto(`/embed/${gameId}`);
+    await expect(embed).toHaveTitle(/^Tic Tac Toe: /);
+    await expect(embed.locator("html")).toHaveAttribute("lang", "en");
+    await expect(embed.locator("#status")).toContainText("waiting: O");
+
+    const res = await es.request.get(`/api/oembed?url=${encodeURIComponent(`/?game=${gameId}`)}`, {
+      headers: { "Accept-Language": "es" },
+    });
+    expect((await res.json()).title).toMatch(/^Tic Tac Toe: /);
+
+    await en.close();
+    await es.close();
+  });
+
+  test("should read the game locale back and turn away unknown ones", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { locale: "es" } })).json();
+    expect(game.locale).toBe("es");
+    const settings = await (await request.get(`/api/game/${game.id}/settings`)).json();
+    expect(settings.locale).toBe("es");
+
+    const res = await request.post("/api/game", { data: { locale: "tlh" } });
+    expect(res.status()).toBe(400);
+    expect((await res.json()).code).toBe("invalid_locale");
+
+    const embed = await request.get(`/embed/${game.id}`, { headers: { "Accept-Language": "en" } });
+    expect(await embed.text()).toContain("Tres en raya");
+  });
+});
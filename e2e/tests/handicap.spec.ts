@@ -0,0 +1,60 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Handicap", () => {
+  test("should start with the weaker side's mark as move zero", async ({ request }) => {
+    const res = await request.post("/api/game", {
+      data: { handicap: { style: "mark", player: "O", position: 4 } },
+    });
+    expect(res.ok()).toBeTruthy();
+    const game = await res.json();
+    expect(game.board[4]).toBe("O");
+    expect(game.currentTurn).toBe("X");
+    expect(game.history).toHaveLength(1);
+    expect(game.history[0]).toMatchObject({ position: 4, cell: "b2", player: "O" });
+    expect(game.legalMoves).not.toContain(4);
+
+    const random = await (
+      await request.post("/api/game", { data: { handicap: { style: "mark", player: "X" } } })
+    ).json();
+    expect(random.board.filter((cell: string) => cell === "X")).toHaveLength(1);
+    expect(random.board[random.handicap.position]).toBe("X");
+  });
+
+  test("should let the weaker side open with two moves", async ({ request }) => {
+    const game = await (
+      await request.post("/api/game", {
+        data: { mode: "hotseat", handicap: { style: "double", player: "O" } },
+      })
+    ).json();
+    expect(game.currentTurn).toBe("O");
+
+    let state = await (await request.post(`/api/game/${game.id}`, { data: { player: "O", position: 0 } })).json();
+    expect(state.currentTurn).toBe("O");
+    state = await (await request.post(`/api/game/${game.id}`, { data: { player: "O", position: 1 } })).json();
+    expect(state.currentTurn).toBe("X");
+    state = await (await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 4 } })).json();
+    expect(state.currentTurn).toBe("O");
+    state = await (await request.post(`/api/game/${game.id}`, { data: { player: "O", position: 2 } })).json();
+    expect(state.winner).toBe("O");
+  });
+
+  test("should reject a handicap off the board", async ({ request }) => {
+    const res = await request.post("/api/game", {
+      data: { handicap: { style: "mark", player: "O", position: 9 } },
+    });
+    expect(res.status()).toBe(400);
+  });
+
+  test("should show the handicap and keep it on reset", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("#handicap").selectOption("mark:O");
+    await page.locator("button", { hasText: "[hot-seat]" }).click();
+    await expect(page.locator(".handicap")).toContainText("O started with a mark on");
+    await expect(page.locator(".cell", { hasText: "O" })).toHaveCount(1);
+    await expect(page.locator("#status")).toContainText("pass the device: X's turn");
+
+    await page.locator("button", { hasText: "[reset]" }).click();
+    await expect(page.locator(".cell", { hasText: "O" })).toHaveCount(1);
+    await expect(page.locator(".handicap")).toBeVisible();
+  });
+});
@@ -0,0 +1,52 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Hot-seat", () => {
+  test("should alternate marks on one device", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[hot-seat]" }).click();
+    await expect(page.locator("#status")).toContainText("pass the device: X's turn");
+    await expect(page.locator(".cell[hx-post]").first()).toHaveAttribute(
+      "hx-post",
+      /\/move\/[^/]+\/0\?player=X$/
+    );
+
+    await page.locator(".cell").nth(4).click();
+    await expect(page.locator("#status")).toContainText("pass the device: O's turn");
+    await expect(page.locator(".cell").nth(4)).toHaveText("X");
+    await expect(page.locator(".cell[hx-post]").first()).toHaveAttribute(
+      "hx-post",
+      /\/move\/[^/]+\/0\?player=O$/
+    );
+
+    await page.locator(".cell").nth(0).click();
+    await expect(page.locator(".cell").nth(0)).toHaveText("O");
+    await expect(page.locator("#status")).toContainText("pass the device: X's turn");
+    await expect(page.locator(".cell[hx-post]").first()).toHaveAttribute(
+      "hx-post",
+      /\/move\/[^/]+\/1\?player=X$/
+    );
+  });
+
+  test("should play to a win and keep the mode on reset", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[hot-seat]" }).click();
+    for (const cell of [0, 3, 1, 4, 2]) {
+      await page.locator(".cell").nth(cell).click();
+      await expect(page.locator(".cell").nth(cell)).not.toHaveAttribute("hx-post");
+    }
+    await expect(page.locator("#status")).toContainText("winner: X");
+    await expect(page.locator(".cell.win")).toHaveCount(3);
+    await expect(page.locator(".cell.win").nth(2)).toHaveAttribute("data-cell", "c1");
+    await expect(page.locator(".cell").nth(4)).toHaveAttribute("data-row", "1");
+    await expect(page.locator("#board")).toHaveAttribute("style", /repeat\(3, /);
+
+    await page.locator("button", { hasText: "[reset]" }).click();
+    await expect(page.locator("#status")).toContainText("pass the device: X's turn");
+  });
+
+  test("should leave online games waiting for an opponent", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[new]" }).click();
+    await expect(page.locator("#status")).toContainText("waiting for an opponent");
+  });
+});
@@ -0,0 +1,140 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+const JSON_ACCEPT = { Accept: "application/json" };
+const HTMX = { Accept: "application/json", "HX-Request": "true" };
+
+/** Creates a game through the htmx endpoint, as X, asking for JSON. */
+async function newGame(request: APIRequestContext) {
+  const res = await request.post("/htmx/game/new?player=X", { headers: JSON_ACCEPT });
+  expect(res.status()).toBe(200);
+  return (await res.json()).id as string;
+}
+
+/** Creates a game with both sides joined. */
+async function startedGame(request: APIRequestContext) {
+  const id = await newGame(request);
+  await request.post(`/htmx/join/${id}?player=O`, { headers: JSON_ACCEPT });
+  return id;
+}
+
+test.describe("htmx endpoints answering JSON", () => {
+  test("new game answers JSON or HTML", async ({ request }) => {
+    const json = await request.post("/htmx/game/new?player=X", { headers: JSON_ACCEPT });
+    expect(json.headers()["content-type"]).toContain("application/json");
+    expect(await json.json()).toMatchObject({ playerXJoined: true, playerOJoined: false });
+
+    const html = await request.post("/htmx/game/new?player=X", { headers: HTMX });
+    expect(html.headers()["content-type"]).toContain("text/html");
+  });
+
+  test("viewing a game answers JSON or HTML", async ({ request }) => {
+    const id = await newGame(request);
+    const json = await request.get(`/htmx/game/${id}`, { headers: JSON_ACCEPT });
+    expect(json.headers()["vary"]).toContain("Accept");
+    expect(await json.json()).toMatchObject({ id });
+
+    const html = await request.get(`/htmx/game/${id}`);
+    expect(html.headers()["content-type"]).toContain("text/html");
+
+    const missing = await request.get("/htmx/game/doesnotexist", { headers: JSON_ACCEPT });
+    expect(missing.status()).toBe(404);
+    expect(await missing.json()).toMatchObject({ code: "game_not_found" });
+  });
+
+  test("joining answers JSON or HTML", async ({ request }) => {
+    const id = await newGame(request);
+    const json = await request.post(`/htmx/join/${id}?player=O`, { headers: JSON_ACCEPT });
+    expect(await json.json()).toMatchObject({ id, playerOJoined: true });
+
+    const taken = await request.post(`/htmx/join/${id}?player=O`, { headers: JSON_ACCEPT });
+    expect(taken.status()).toBe(409);
+    expect((await taken.json()).requestId).toBeTruthy();
+
+    const html = await request.post(`/htmx/join/${id}?player=O`, { headers: HTMX });
+    expect(html.status()).toBe(409);
+    expect(html.headers()["content-type"]).toContain("text/html");
+  });
+
+  test("moves answer JSON or HTML", async ({ request }) => {
+    const id = await startedGame(request);
+    const moved = await request.post(`/htmx/move/${id}/4?player=X`, { headers: JSON_ACCEPT });
+    expect(moved.status()).toBe(200);
+    expect((await moved.json()).board[4]).toBe("X");
+
+    const early = await request.post(`/htmx/move/${id}/0?player=X`, { headers: JSON_ACCEPT });
+    expect(early.status()).toBe(409);
+    expect(await early.json()).toMatchObject({ code: "not_your_turn" });
+
+    const bad = await request.post(`/htmx/move/${id}/x?player=O`, { headers: JSON_ACCEPT });
+    expect(bad.status()).toBe(400);
+    expect(await bad.json()).toMatchObject({ code: "invalid_move" });
+
+    const html = await request.post(`/htmx/move/${id}/0?player=O`, { headers: HTMX });
+    expect(html.headers()["content-type"]).toContain("text/html");
+  });
+
+  test("a missing side answers the error envelope", async ({ request }) => {
+    const id = await startedGame(request);
+    const res = await request.post(`/htmx/move/${id}/4`, { headers: JSON_ACCEPT });
+    expect(res.status()).toBe(400);
+    expect(await res.json()).toMatchObject({ code: "invalid_player" });
+
+    const html = await request.post(`/htmx/move/${id}/4`, { headers: HTMX });
+    expect(html.status()).toBe(400);
+    expect(html.headers()["content-type"]).toContain("text/html");
+  });
+
+  test("draws, resets and undo answer JSON", async ({ request }) => {
+    const id = await startedGame(request);
+    await request.post(`/htmx/move/${id}/4?player=X`, { headers: JSON_ACCEPT });
+
+    const offer = await request.post(`/htmx/draw/${id}?player=O`, { headers: JSON_ACCEPT });
+    expect(await offer.json()).toMatchObject({ drawOffer: "O" });
+    const reply = await request.post(`/htmx/draw/${id}/reply?player=X&accept=false`, { headers: JSON_ACCEPT });
+    expect(reply.status()).toBe(200);
+
+    const reset = await request.post(`/htmx/reset/${id}?player=X`, { headers: JSON_ACCEPT });
+    expect((await reset.json()).history).toEqual([]);
+    const undo = await request.post(`/htmx/undo/${id}?player=X`, { headers: JSON_ACCEPT });
+    expect((await undo.json()).board[4]).toBe("X");
+
+    const nothing = await request.post(`/htmx/undo/${id}?player=X`, { headers: JSON_ACCEPT });
+    expect(nothing.status()).toBe(409);
+    expect(await nothing.json()).toMatchObject({ code: "nothing_to_undo" });
+  });
+
+  test("settings answer JSON or HTML", async ({ request }) => {
+    const created = await request.post("/htmx/game/new?player=X", { headers: JSON_ACCEPT });
+    const id = (await created.json()).id as string;
+    const seats = /seats=[^;]*/.exec(created.headers()["set-cookie"])![0];
+    const json = await request.patch(`/htmx/settings/${id}?player=X`, {
+      headers: { ...JSON_ACCEPT, Cookie: seats },
+      form: { readyCheck: "true" },
+    });
+    expect(await json.json()).toMatchObject({ readyCheck: true });
+
+    for (const cookie of ["seats=", `seats=${id}.X`]) {
+      const stranger = await request.patch(`/htmx/settings/${id}?player=X`, {
+        headers: { ...JSON_ACCEPT, Cookie: cookie },
+      });
+      expect(stranger.status()).toBe(403);
+      expect(await stranger.json()).toMatchObject({ code: "not_creator" });
+    }
+
+    const html = await request.patch(`/htmx/settings/${id}?player=X`, {
+      headers: { ...HTMX, Cookie: seats },
+      form: { readyCheck: "false" },
+    });
+    expect(html.headers()["content-type"]).toContain("text/html");
+  });
+
+  test("cancelling answers 204 or HTML", async ({ request }) => {
+    const id = await newGame(request);
+    const json = await request.post(`/htmx/cancel/${id}?player=X`, { headers: JSON_ACCEPT });
+    expect(json.status()).toBe(204);
+
+    const other = await newGame(request);
+    const html = await request.post(`/htmx/cancel/${other}?player=X`, { headers: HTMX });
+    expect(html.headers()["content-type"]).toContain("text/html");
+  });
+});
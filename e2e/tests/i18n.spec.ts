@@ -0,0 +1,60 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Localization", () => {
+  test("should render fragments in English by default", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[new]" }).click();
+    await expect(page.locator("#status")).toContainText("waiting for an opponent");
+  });
+
+  test("should follow Accept-Language", async ({ browser }) => {
+    const context = await browser.newContext({ locale: "es-MX" });
+    const page = await context.newPage();
+    await page.goto("/");
+    await page.locator("button", { hasText: "[new]" }).click();
+    await expect(page.locator("#status")).toContainText("esperando a un rival");
+    await context.close();
+  });
+
+  test("should remember ?lang= over Accept-Language", async ({ browser }) => {
+    const context = await browser.newContext({ locale: "en-US" });
+    const page = await context.newPage();
+    await page.goto("/?lang=es");
+    await page.goto("/");
+    await page.locator("button", { hasText: "[new]" }).click();
+    await expect(page.locator("#status")).toContainText("esperando a un rival");
+    await context.close();
+  });
+
+  test("should translate each viewer's own updates", async ({ browser }) => {
+    const es = await browser.newContext({ locale: "es" });
+    const en = await browser.newContext({ locale: "en" });
+    const pageX = await es.newPage();
+    await pageX.goto("/");
+    await pageX.locator("button", { hasText: "[new]" }).click();
+    const gameId = await pageX
+      .locator("[data-game-id][data-player]")
+      .getAttribute("data-game-id");
+
+    const pageO = await en.newPage();
+    await pageO.goto(`/?game=${gameId}&player=O`);
+    await pageO.locator("button", { hasText: "[join]" }).click();
+    await expect(pageO.locator("#status")).toContainText("waiting: X");
+    await expect(pageX.locator("#status")).toContainText("tu_turno", {
+      timeout: 5000,
+    });
+
+    await es.close();
+    await en.close();
+  });
+
+  test("should translate error fragments", async ({ browser }) => {
+    const context = await browser.newContext({ locale: "es" });
+    const page = await context.newPage();
+    await page.goto("/");
+    await page.fill("#joinId", "zzzzzzzz");
+    await page.locator("button", { hasText: "[join]" }).click();
+    await expect(page.locator("#status")).toContainText("partida no encontrada");
+    await context.close();
+  });
+});
@@ -0,0 +1,106 @@
+import { test, expect, Page } from "@playwright/test";
+
+/** Creates a game as X through the UI and returns its ID. */
+async function createGame(page: Page): Promise<string> {
+  await page.goto("/");
+  await page.locator("button", { hasText: "[new]" }).click();
+  const id = await page
+    .locator("[data-game-id][data-player]")
+    .getAttribute("data-game-id");
+  expect(id).toBeTruthy();
+  return id!;
+}
+
+test.describe("Joining", () => {
+  test("should only view the game when opening a link", async ({
+    browser,
+  }) => {
+    const context = await browser.newContext();
+    const pageX = await context.newPage();
+    const gameId = await createGame(pageX);
+
+    // Opening the invite link twice doesn't use up the slot
+    const pageO = await context.newPage();
+    await pageO.goto(`/?game=${gameId}&player=O`);
+    await expect(pageO.locator("#status")).toContainText(`join session ${gameId} as O`);
+    await pageO.reload();
+    await expect(pageO.locator("#status")).toContainText(`join session ${gameId} as O`);
+    await expect(pageX.locator("#status")).toContainText("waiting for an opponent");
+
+    await pageO.locator("button", { hasText: "[join]" }).click();
+    await expect(pageO.locator("#status")).toContainText("waiting: X");
+    await expect(pageX.locator("#status")).toContainText("your_turn", {
+      timeout: 5000,
+    });
+
+    // Reloading after joining shows the board again rather than an error
+    await pageO.reload();
+    await expect(pageO.locator("#status")).toContainText("waiting: X");
+
+    await context.close();
+  });
+
+  test("should accept codes with stray whitespace and capitals", async ({
+    browser,
+  }) => {
+    const context = await browser.newContext();
+    const gameId = await createGame(await context.newPage());
+
+    const page = await context.newPage();
+    await page.goto("/");
+    await page.locator("#selectO").click();
+    await page.locator("#joinId").fill(`  ${gameId.toUpperCase()} `);
+    await page.locator("button", { hasText: "[join]" }).click();
+    await expect(page.locator("#status")).toContainText("waiting: X");
+
+    await context.close();
+  });
+
+  test("should explain an unknown code", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("#joinId").fill("nosuchgame");
+    await page.locator("button", { hasText: "[join]" }).click();
+    await expect(page.locator("#status")).toContainText(
+      "game not found — check the code"
+    );
+  });
+
+  test("should offer the other side when one is taken", async ({
+    browser,
+  }) => {
+    const context = await browser.newContext();
+    const gameId = await createGame(await context.newPage());
+
+    const page = await context.newPage();
+    await page.goto("/");
+    await page.locator("#joinId").fill(gameId);
+    await page.locator("button", { hasText: "[join]" }).click();
+    await expect(page.locator("#status")).toContainText(
+      "that side is taken, join as O?"
+    );
+    await page.locator("button", { hasText: "[join as O]" }).click();
+    await expect(page.locator("#status")).toContainText("waiting: X");
+
+    await context.close();
+  });
+
+  test("should offer to watch a full game", async ({ browser, request }) => {
+    const context = await browser.newContext();
+    const gameId = await createGame(await context.newPage());
+    await request.post(`/api/game/${gameId}/join`, { data: { player: "O" } });
+
+    const page = await context.newPage();
+    await page.goto("/");
+    await page.locator("#selectO").click();
+    await page.locator("#joinId").fill(gameId);
+    await page.locator("button", { hasText: "[join]" }).click();
+    await expect(page.locator("#status")).toContainText("game is full");
+
+    await page.locator("button", { hasText: "[watch]" }).click();
+    await expect(page.locator("#status")).toContainText("waiting: X");
+    await expect(page.locator(".cell[hx-post]")).toHaveCount(0);
+    await expect(page.locator(".board")).toBeVisible();
+
+    await context.close();
+  });
+});
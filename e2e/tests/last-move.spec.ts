@@ -0,0 +1,35 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Last move", () => {
+  test("should carry the latest move and clear it on reset", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    expect(game.lastMove).toBeUndefined();
+
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+    const state = await (await request.post(`/api/game/${game.id}`, { data: { player: "O", position: 4 } })).json();
+    expect(state.lastMove).toMatchObject({ position: 4, cell: "b2", player: "O" });
+
+    const reset = await (await request.put(`/api/game/${game.id}`)).json();
+    expect(reset.lastMove).toBeUndefined();
+  });
+
+  test("should follow the computer's reply", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "ai" } })).json();
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+
+    await expect
+      .poll(async () => (await (await request.get(`/api/game/${game.id}`)).json()).lastMove?.player, { timeout: 5_000 })
+      .toBe("O");
+  });
+
+  test("should mark the cell that just changed", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[hot-seat]" }).click();
+
+    await page.locator(".cell").nth(4).click();
+    await expect(page.locator(".cell").nth(4)).toHaveClass(/last-move/);
+    await page.locator(".cell").nth(0).click();
+    await expect(page.locator(".cell").nth(0)).toHaveClass(/last-move/);
+    await expect(page.locator(".cell.last-move")).toHaveCount(1);
+  });
+});
@@ -0,0 +1,23 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Lobby", () => {
+  test("should show an open game to every visitor until it fills", async ({ browser, request }) => {
+    const first = await (await browser.newContext()).newPage();
+    const second = await (await browser.newContext()).newPage();
+    for (const page of [first, second]) {
+      await page.goto("/");
+      await expect(page.locator("#lobby")).toBeAttached();
+    }
+
+    const game = await (await request.post("/api/game", { data: {} })).json();
+    await request.post(`/api/game/${game.id}/join`, { data: { player: "X" } });
+    for (const page of [first, second]) {
+      await expect(page.locator(`#lobby-${game.id}`)).toContainText("[join as O]");
+    }
+
+    await request.post(`/api/game/${game.id}/join`, { data: { player: "O" } });
+    for (const page of [first, second]) {
+      await expect(page.locator(`#lobby-${game.id}`)).toHaveCount(0);
+    }
+  });
+});
@@ -0,0 +1,45 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Name scrubbing", () => {
+  test("should drop invisible and direction-changing characters", async ({ request }) => {
+    const res = await request.post("/api/tournaments", {
+      data: { participants: ["‮evil‬", "b​o‍b", "  cat \t  dog "] },
+    });
+    expect(res.status()).toBe(201);
+    expect((await res.json()).participants).toEqual(["evil", "bob", "cat dog"]);
+  });
+
+  test("should reject a name that is nothing but invisible characters", async ({ request }) => {
+    const res = await request.post("/api/tournaments", { data: { participants: ["​‮", "bob"] } });
+    expect(res.status()).toBe(400);
+    expect(await res.json()).toMatchObject({ code: "text_rejected", field: "name" });
+  });
+
+  test("should treat look-alike names as the same", async ({ request }) => {
+    // The second "ann" starts with a Cyrillic а
+    for (const pair of [["ann", "аnn"], ["José", "José"], ["bob", "ｂｏｂ"]]) {
+      const res = await request.post("/api/tournaments", { data: { participants: pair } });
+      expect(res.status()).toBe(400);
+      expect((await res.json()).error).toContain("unique");
+    }
+  });
+
+  test("should turn away blocked words however they are spelled", async ({ request }) => {
+    for (const name of ["badword", "B4DW0RD", "b.a.d.w.o.r.d", "my badword"]) {
+      const res = await request.post("/api/tournaments", { data: { participants: [name, "bob"] } });
+      expect(res.status()).toBe(400);
+      expect(await res.json()).toMatchObject({ code: "text_rejected", field: "name" });
+    }
+    const res = await request.post("/api/tournaments", { data: { participants: ["badwords", "bob"] } });
+    expect(res.status()).toBe(201);
+  });
+
+  test("should clean game names and reject blocked ones", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { slug: "zero​width" } })).json();
+    expect(game.slug).toBe("zerowidth");
+
+    const res = await request.post("/api/game", { data: { slug: "badword-club" } });
+    expect(res.status()).toBe(400);
+    expect(await res.json()).toMatchObject({ code: "text_rejected", field: "slug" });
+  });
+});
@@ -0,0 +1,112 @@
+import { test, expect, Page } from "@playwright/test";
+
+// The second web server in playwright.config.ts serves the app under /ttt
+const BASE = "http://localhost:8081/ttt/";
+const PREFIX = "/ttt/";
+
+/**
+ * Fails the test if the page requests anything on the server outside the
+ * prefix, i.e. a root-relative URL leaked into the page or a fragment.
+ */
+function watchRequests(page: Page): string[] {
+  const leaked: string[] = [];
+  page.on("request", (req) => {
+    const url = new URL(req.url());
+    if (url.host === "localhost:8081" && !url.pathname.startsWith(PREFIX)) {
+      leaked.push(url.pathname);
+    }
+  });
+  return leaked;
+}
+
+/** Checks every link attribute currently in the page carries the prefix. */
+async function expectPrefixedLinks(page: Page) {
+  const links = await page
+    .locator("[hx-get], [hx-post], [sse-connect], a[href]")
+    .evaluateAll((els) =>
+      els.map(
+        (el) =>
+          el.getAttribute("hx-get") ??
+          el.getAttribute("hx-post") ??
+          el.getAttribute("sse-connect") ??
+          el.getAttribute("href") ??
+          ""
+      )
+    );
+  for (const link of links) {
+    if (link.startsWith("/")) {
+      expect(link, "root-relative link outside the prefix").toMatch(/^\/ttt\//);
+    } else if (link.startsWith("http")) {
+      expect(new URL(link).pathname).toMatch(/^\/ttt\//);
+    }
+  }
+}
+
+async function clickCell(page: Page, position: number) {
+  await page.locator(`.cell[hx-post*="/${position}?"]`).click();
+}
+
+test.describe("Path prefix", () => {
+  test("should redirect the bare prefix to the app", async ({ page }) => {
+    await page.goto("http://localhost:8081/ttt");
+    await expect(page).toHaveURL(BASE);
+    await expect(page).toHaveTitle("Tic Tac Toe");
+  });
+
+  test("should not serve anything outside the prefix", async ({ request }) => {
+    const res = await request.get("http://localhost:8081/api/game/nonexistent");
+    expect(res.status()).toBe(404);
+  });
+
+  test("should play a full HTMX game under the prefix", async ({ browser }) => {
+    const contextX = await browser.newContext();
+    const pageX = await contextX.newPage();
+    const leakedX = watchRequests(pageX);
+    await pageX.goto(BASE);
+    await pageX.locator("button", { hasText: "[new]" }).click();
+    await expect(pageX.locator("#gameId")).not.toBeEmpty();
+    await expectPrefixedLinks(pageX);
+
+    const gameId = await pageX.locator("[data-game-id]").getAttribute("data-game-id");
+    expect(gameId).toBeTruthy();
+
+    const contextO = await browser.newContext();
+    const pageO = await contextO.newPage();
+    const leakedO = watchRequests(pageO);
+    await pageO.goto(`${BASE}?game=${gameId}&player=O`);
+    await pageO.locator("button", { hasText: "[join]" }).click();
+    await expect(pageO.locator("#status")).toContainText("waiting");
+    await expect(pageX.locator("#status")).toContainText("your_turn", {
+      timeout: 5000,
+    });
+
+    // X takes the top row while O plays 3 and 4
+    const moves: [Page, number][] = [
+      [pageX, 0],
+      [pageO, 3],
+      [pageX, 1],
+      [pageO, 4],
+      [pageX, 2],
+    ];
+    for (const [page, position] of moves) {
+      await expect(page.locator("#status")).toContainText("your_turn", {
+        timeout: 5000,
+      });
+      await expectPrefixedLinks(page);
+      await clickCell(page, position);
+    }
+
+    await expect(pageX.locator("#status")).toContainText("winner");
+    await expect(pageO.locator("#status")).toContainText("winner", {
+      timeout: 5000,
+    });
+    await expectPrefixedLinks(pageX);
+    await expectPrefixedLinks(pageO);
+
+    expect(leakedX).toEqual([]);
+    expect(leakedO).toEqual([]);
+
+    await contextX.close();
+    await contextO.close();
+  });
+});
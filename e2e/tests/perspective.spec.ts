@@ -0,0 +1,56 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Rendered perspective", () => {
+  test("should show a claimed side only to the browser that joined it", async ({
+    browser,
+  }) => {
+    const xContext = await browser.newContext();
+    const oContext = await browser.newContext();
+    const created = await xContext.request.post("/htmx/game/new?player=X");
+    const id = /data-game-id="([^"]+)"/.exec(await created.text())![1];
+    await oContext.request.post(`/htmx/join/${id}?player=O`);
+
+    // O's browser opening X's link sees the board as a spectator does
+    const page = await oContext.newPage();
+    await page.goto(`/?game=${id}&player=X`);
+    await expect(page.locator(".board")).toBeVisible();
+    await expect(page.locator("[data-game-id]")).toHaveAttribute("data-player", "");
+    await expect(page.locator(`[hx-post*="player=X"]`)).toHaveCount(0);
+
+    // and so does its event stream
+    await page.evaluate(
+      ([id]) => {
+        const source = new EventSource(`/htmx/sse/${id}?player=X`);
+        (window as any).updates = [];
+        source.addEventListener("game-update", (e) => (window as any).updates.push(e.data));
+      },
+      [id]
+    );
+    await expect
+      .poll(() => page.evaluate(() => (window as any).updates.length), { timeout: 5000 })
+      .toBe(1);
+    await xContext.request.post(`/htmx/move/${id}/4?player=X`);
+    await expect
+      .poll(() => page.evaluate(() => (window as any).updates.length), { timeout: 5000 })
+      .toBeGreaterThan(1);
+    const frames: string[] = await page.evaluate(() => (window as any).updates);
+    for (const frame of frames) {
+      expect(frame).not.toContain("player=X");
+    }
+
+    // while X's own browser keeps its board
+    const own = await xContext.newPage();
+    await own.goto(`/?game=${id}&player=X`);
+    await expect(own.locator(`[hx-post*="/htmx/move/${id}/0?player=X"]`)).toHaveCount(1);
+
+    await xContext.close();
+    await oContext.close();
+  });
+
+  test("should still offer an unjoined side to whoever has the link", async ({ page, request }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+    await page.goto(`/?game=${id}&player=O`);
+    await expect(page.locator("#status")).toContainText(`join session ${id} as O`);
+  });
+});
@@ -0,0 +1,46 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+async function startedGame(request: APIRequestContext): Promise<string> {
+  const { id } = await (await request.post("/api/game")).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  return id;
+}
+
+test.describe("Player parameter", () => {
+  for (const player of ["", "banana", "spectator", "x"]) {
+    test(`should reject actions for player=${player || "(missing)"}`, async ({ request }) => {
+      const id = await startedGame(request);
+      const query = player ? `?player=${player}` : "";
+      for (const path of [
+        `/htmx/move/${id}/0${query}`,
+        `/htmx/reset/${id}${query}`,
+        `/htmx/vacate/${id}${query}`,
+        `/htmx/draw/${id}${query}`,
+        `/htmx/draw/${id}/reply${query}`,
+        `/htmx/join/${id}${query}`,
+      ]) {
+        const res = await request.post(path);
+        expect(res.status(), path).toBe(400);
+        expect(await res.text()).toContain("invalid player");
+      }
+      const game = await (await request.get(`/api/game/${id}`)).json();
+      expect(game.history).toEqual([]);
+    });
+
+    test(`should show spectators the board for player=${player || "(missing)"}`, async ({
+      request,
+    }) => {
+      const id = await startedGame(request);
+      const query = player ? `?player=${player}` : "";
+      const html = await (await request.get(`/htmx/game/${id}${query}`)).text();
+      expect(html).toContain('data-player=""');
+      expect(html).not.toContain("/htmx/move/");
+    });
+  }
+
+  test("should still create games as X by default", async ({ request }) => {
+    const html = await (await request.post("/htmx/game/new")).text();
+    expect(html).toContain('data-player="X"');
+  });
+});
@@ -0,0 +1,130 @@
+import { test, expect, Page, APIRequestContext } from "@playwright/test";
+
+/** Opens a WebSocket in the page under name, keeping every frame it gets. */
+async function connect(page: Page, name: string, url: string) {
+  await page.evaluate(
+    ([name, url]) =>
+      new Promise<void>((resolve) => {
+        const w = window as any;
+        w.sockets_ = w.sockets_ || {};
+        const frames: any[] = [];
+        const ws = new WebSocket(url);
+        w.sockets_[name] = { ws, frames };
+        ws.onmessage = (e) => {
+          frames.push(JSON.parse(e.data));
+          resolve();
+        };
+      }),
+    [name, url] as const
+  );
+}
+
+function send(page: Page, name: string, msg: object) {
+  return page.evaluate(
+    ([name, msg]) => (window as any).sockets_[name].ws.send(JSON.stringify(msg)),
+    [name, msg] as const
+  );
+}
+
+/** Waits for a frame on the named socket that matches and returns every frame so far. */
+async function until(page: Page, name: string, type: string) {
+  await page.waitForFunction(
+    ([name, type]) => (window as any).sockets_[name].frames.some((f: any) => f.type === type || f.code === type),
+    [name, type] as const
+  );
+  return page.evaluate((name) => (window as any).sockets_[name].frames, name);
+}
+
+async function newGame(request: APIRequestContext): Promise<string> {
+  const { id } = await (await request.post("/api/game")).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  return id;
+}
+
+test.describe("WebSocket pulls", () => {
+  test("should answer a spectator's get and history", async ({ page, request, baseURL }) => {
+    const id = await newGame(request);
+    await request.post(`/api/game/${id}`, { data: { position: 4, player: "X" } });
+    await request.post(`/api/game/${id}`, { data: { position: 0, player: "O" } });
+    await page.goto("/");
+    await connect(page, "watcher", `${baseURL!.replace(/^http/, "ws")}/ws/${id}?envelope=1`);
+    await until(page, "watcher", "welcome");
+
+    await send(page, "watcher", { type: "history", from: 1 });
+    let frames = await until(page, "watcher", "history");
+    const history = frames.find((f: any) => f.type === "history");
+    expect(history.gameId).toBe(id);
+    expect(history.from).toBe(1);
+    expect(history.total).toBe(2);
+    expect(history.moves.map((m: any) => m.position)).toEqual([0]);
+
+    await send(page, "watcher", { type: "get" });
+    await page.waitForFunction(
+      (name) => (window as any).sockets_[name].frames.filter((f: any) => f.type === "game-update").length >= 2,
+      "watcher"
+    );
+    frames = await page.evaluate((name) => (window as any).sockets_[name].frames, "watcher");
+    const pulled = frames.filter((f: any) => f.type === "game-update").at(-1);
+    expect(pulled.data.board[4]).toBe("X");
+    expect(pulled.data.board[0]).toBe("O");
+
+    await send(page, "watcher", { type: "history", from: -1 });
+    frames = await until(page, "watcher", "invalid_from");
+    expect(frames.find((f: any) => f.code === "invalid_from")).toBeTruthy();
+  });
+
+  test("should never answer with an older state than one already sent", async ({
+    page,
+    request,
+    baseURL,
+  }) => {
+    const id = await newGame(request);
+    await page.goto("/");
+    await connect(page, "bot", `${baseURL!.replace(/^http/, "ws")}/ws/${id}?delta=1`);
+    await until(page, "bot", "welcome");
+
+    // Ask while the moves are being made, then once more after the last
+    const moves: [number, string][] = [[0, "X"], [3, "O"], [1, "X"], [4, "O"]];
+    for (const [position, player] of moves) {
+      await Promise.all([
+        request.post(`/api/game/${id}`, { data: { position, player } }),
+        send(page, "bot", { type: "get" }),
+      ]);
+    }
+    await send(page, "bot", { type: "get" });
+    await page.waitForFunction(
+      (name) => {
+        const states = (window as any).sockets_[name].frames.filter((f: any) => f.type === "state");
+        return states.length >= 6 && states.at(-1).game.history.length === 4;
+      },
+      "bot"
+    );
+
+    const frames = await page.evaluate((name) => (window as any).sockets_[name].frames, "bot");
+    // Versions only grow with the game, so each state must be at least
+    // as new as anything before it
+    let version = 0;
+    for (const f of frames) {
+      if (f.type === "delta") version = Math.max(version, f.toVersion);
+      if (f.type === "state") {
+        expect(f.version).toBeGreaterThanOrEqual(version);
+        version = f.version;
+      }
+    }
+  });
+
+  test("should slow down a client that asks too often", async ({ page, request, baseURL }) => {
+    const id = await newGame(request);
+    await page.goto("/");
+    await connect(page, "poller", `${baseURL!.replace(/^http/, "ws")}/ws/${id}`);
+    await until(page, "poller", "welcome");
+
+    for (let i = 0; i < 7; i++) {
+      await send(page, "poller", { type: "get" });
+    }
+    const frames = await until(page, "poller", "pull_limited");
+    const limited = frames.find((f: any) => f.code === "pull_limited");
+    expect(limited.temporary).toBe(true);
+  });
+});
@@ -0,0 +1,88 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+// The third web server in playwright.config.ts allows two open games per
+// address and three in all, and trusts X-Forwarded-For from localhost so
+// each test can play several clients.
+const BASE = "http://localhost:8082";
+
+let client = 0;
+
+function create(request: APIRequestContext, ip: string) {
+  return request.post(`${BASE}/api/game`, {
+    data: {},
+    headers: { "X-Forwarded-For": ip },
+  });
+}
+
+async function start(request: APIRequestContext, id: string) {
+  await request.post(`${BASE}/api/game/${id}/join`, { data: { player: "X" } });
+  await request.post(`${BASE}/api/game/${id}/join`, { data: { player: "O" } });
+}
+
+/** Plays a started game to a win for X across the top row. */
+async function finish(request: APIRequestContext, id: string) {
+  for (const [position, player] of [
+    [0, "X"],
+    [3, "O"],
+    [1, "X"],
+    [4, "O"],
+    [2, "X"],
+  ]) {
+    await request.post(`${BASE}/api/game/${id}`, { data: { position, player } });
+  }
+}
+
+test.describe("Creation quotas", () => {
+  test("should refuse a third open game from one address", async ({ request }) => {
+    const ip = `10.1.0.${++client}`;
+    expect((await create(request, ip)).status()).toBe(200);
+    expect((await create(request, ip)).status()).toBe(200);
+
+    const res = await create(request, ip);
+    expect(res.status()).toBe(429);
+    expect((await res.json()).error).toContain("you have 2 open games");
+
+    const page = await request.post(`${BASE}/htmx/game/new`, {
+      headers: { "X-Forwarded-For": ip, "Accept-Language": "es" },
+    });
+    expect(page.status()).toBe(429);
+    expect(await page.text()).toContain("tienes 2 partidas abiertas");
+  });
+
+  test("should count only unfinished games", async ({ request }) => {
+    const ip = `10.1.0.${++client}`;
+    const { id } = await (await create(request, ip)).json();
+    expect((await create(request, ip)).status()).toBe(200);
+
+    await start(request, id);
+    await finish(request, id);
+    expect((await create(request, ip)).status()).toBe(200);
+  });
+
+  test("should expire the oldest unstarted game to make room", async ({ request }) => {
+    const ids: string[] = [];
+    for (let i = 0; i < 3; i++) {
+      const { id } = await (await create(request, `10.2.0.${++client}`)).json();
+      ids.push(id);
+    }
+    expect((await create(request, `10.2.0.${++client}`)).status()).toBe(200);
+    expect((await request.get(`${BASE}/api/game/${ids[0]}`)).status()).toBe(404);
+  });
+
+  test("should answer 503 when every game is under way", async ({ request }) => {
+    const ids: string[] = [];
+    for (let i = 0; i < 3; i++) {
+      const { id } = await (await create(request, `10.3.0.${++client}`)).json();
+      await start(request, id);
+      ids.push(id);
+    }
+    const res = await create(request, `10.3.0.${++client}`);
+    expect(res.status()).toBe(503);
+    expect((await res.json()).error).toContain("too many games");
+
+    // Leave room for the next run against a reused server
+    for (const id of ids) {
+      await finish(request, id);
+    }
+  });
+});
@@ -0,0 +1,36 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Ready check", () => {
+  test("should hold the first move until both players are ready", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { readyCheck: true } })).json();
+    const x = (await request.post(`/api/game/${game.id}/join`, { data: { player: "X" } })).headers()["x-seat-token"];
+    const o = (await request.post(`/api/game/${game.id}/join`, { data: { player: "O" } })).headers()["x-seat-token"];
+
+    let res = await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+    expect(res.status()).toBe(409);
+    expect((await res.json()).error).toContain("both players are ready");
+
+    // each side readies with its own seat token
+    res = await request.post(`/api/game/${game.id}/ready`, { headers: { "X-Seat-Token": x }, data: { player: "O" } });
+    expect(res.status()).toBe(403);
+    expect((await res.json()).code).toBe("seat_token");
+    await request.post(`/api/game/${game.id}/ready`, { headers: { "X-Seat-Token": x }, data: { player: "X" } });
+    res = await request.post(`/api/game/${game.id}/ready`, { headers: { "X-Seat-Token": o }, data: { player: "O" } });
+    const state = await res.json();
+    expect(state.playerXReady).toBe(true);
+    expect(state.playerOReady).toBe(true);
+
+    await expect
+      .poll(async () => (await (await request.get(`/api/game/${game.id}`)).json()).legalMoves.length, {
+        timeout: 10_000,
+      })
+      .toBe(9);
+    res = await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+    expect(res.ok()).toBeTruthy();
+  });
+
+  test("should not be offered for hot-seat games", async ({ request }) => {
+    const res = await request.post("/api/game", { data: { mode: "hotseat", readyCheck: true } });
+    expect(res.status()).toBe(400);
+  });
+});
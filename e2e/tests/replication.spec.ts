@@ -0,0 +1,50 @@
+import { test, expect } from "@playwright/test";
+
+const PRIMARY = "http://localhost:8085";
+const STANDBY = "http://localhost:8086";
+const ADMIN = { Authorization: "Bearer e2e-admin" };
+
+test.describe("Warm standby", () => {
+  test("should follow the primary until promoted, then take over", async ({ request }) => {
+    const { id } = await (await request.post(`${PRIMARY}/api/game`, { data: {} })).json();
+    await request.post(`${PRIMARY}/api/game/${id}/join`, { data: { player: "X" } });
+    await request.post(`${PRIMARY}/api/game/${id}/join`, { data: { player: "O" } });
+    await request.post(`${PRIMARY}/api/game/${id}`, { data: { player: "X", position: 4 } });
+
+    // The standby mirrors the game
+    await expect
+      .poll(async () => (await (await request.get(`${STANDBY}/api/game/${id}`)).json()).board?.[4], { timeout: 10_000 })
+      .toBe("X");
+
+    // but turns changes away
+    const refused = await request.post(`${STANDBY}/api/game/${id}`, { data: { player: "O", position: 0 } });
+    expect(refused.status()).toBe(503);
+    expect(await refused.json()).toMatchObject({ code: "read_only", temporary: true });
+
+    // The primary can't be promoted, as it follows nobody
+    const notFollowing = await request.post(`${PRIMARY}/api/admin/promote`, { headers: ADMIN });
+    expect(notFollowing.status()).toBe(409);
+    expect(await notFollowing.json()).toMatchObject({ code: "not_following" });
+
+    const promoted = await request.post(`${STANDBY}/api/admin/promote`, { headers: ADMIN });
+    expect(promoted.status()).toBe(200);
+    expect((await promoted.json()).applied).toBeGreaterThan(0);
+
+    const moved = await request.post(`${STANDBY}/api/game/${id}`, { data: { player: "O", position: 0 } });
+    expect(moved.status()).toBe(200);
+    expect((await moved.json()).board).toEqual(["O", "", "", "", "X", "", "", "", ""]);
+  });
+
+  test("should serve a game's latest entry to standbys", async ({ request }) => {
+    const { id } = await (await request.post(`${PRIMARY}/api/game`, { data: {} })).json();
+    await request.post(`${PRIMARY}/api/game/${id}/join`, { data: { player: "X" } });
+
+    const res = await request.get(`${PRIMARY}/api/admin/replication/${id}`, { headers: ADMIN });
+    expect(res.status()).toBe(200);
+    expect(await res.json()).toMatchObject({ gameId: id, seq: 2, event: "join" });
+
+    expect((await request.get(`${PRIMARY}/api/admin/replication/${id}`)).status()).toBe(401);
+    const version = await (await request.get(`${PRIMARY}/api/version`)).json();
+    expect(version.features).toContain("replication");
+  });
+});
@@ -0,0 +1,54 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+async function playing(request: APIRequestContext) {
+  const { id } = await (await request.post("/api/game")).json();
+  await request.post(`/api/game/${id}/join`, { data: { player: "X", symbol: "★" } });
+  await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+  return id as string;
+}
+
+test.describe("Reset", () => {
+  test("should let both players go on moving after a mid-game reset", async ({ request }) => {
+    const id = await playing(request);
+    await request.post(`/api/game/${id}`, { data: { player: "X", position: 4 } });
+
+    const reset = await (await request.put(`/api/game/${id}`)).json();
+    expect(reset.board[4]).toBe("");
+    expect(reset.playerXJoined).toBe(true);
+    expect(reset.playerOJoined).toBe(true);
+    expect(reset.xSymbol).toBe("★");
+
+    const x = await request.post(`/api/game/${id}`, { data: { player: "X", position: 0 } });
+    expect(x.ok()).toBeTruthy();
+    const o = await request.post(`/api/game/${id}`, { data: { player: "O", position: 1 } });
+    expect(o.ok()).toBeTruthy();
+    expect((await o.json()).board.slice(0, 2)).toEqual(["X", "O"]);
+  });
+
+  test("should keep an outsider from joining after a reset", async ({ request }) => {
+    const id = await playing(request);
+    await request.put(`/api/game/${id}`);
+
+    for (const player of ["X", "O"]) {
+      const res = await request.post(`/api/game/${id}/join`, { data: { player } });
+      expect(res.ok()).toBeFalsy();
+    }
+  });
+
+  test("should let operators wipe a game completely", async ({ request }) => {
+    const id = await playing(request);
+    await request.post(`/api/game/${id}`, { data: { player: "X", position: 4 } });
+
+    expect((await request.post(`/api/admin/game/${id}/reset`)).status()).toBe(401);
+    const res = await request.post(`/api/admin/game/${id}/reset`, {
+      headers: { Authorization: "Bearer e2e-admin" },
+    });
+    expect(res.ok()).toBeTruthy();
+    const wiped = await res.json();
+    expect(wiped.board[4]).toBe("");
+    expect(wiped.playerXJoined).toBe(false);
+    expect(wiped.playerOJoined).toBe(false);
+    expect(wiped.xSymbol).toBe("");
+    expect((await request.post(`/api/game/${id}/join`, { data: { player: "O" } })).ok()).toBeTruthy();
+  });
+});
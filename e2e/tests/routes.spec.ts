@@ -0,0 +1,62 @@
+import { test, expect } from "@playwright/test";
+
+const missing = "zzzz9999";
+
+// Every route with the status it answers for a game or tournament that
+// doesn't exist, and methods routes don't take with the Allow header
+// they answer with.
+const routes: [method: string, path: string, status: number][] = [
+  ["GET", `/api/game/${missing}`, 404],
+  ["POST", `/api/game/${missing}`, 400],
+  ["PUT", `/api/game/${missing}`, 404],
+  ["POST", `/api/game/${missing}/join`, 404],
+  ["POST", `/api/game/${missing}/vacate`, 404],
+  ["POST", `/api/game/${missing}/draw`, 404],
+  ["POST", `/api/game/${missing}/draw/reply`, 404],
+  ["GET", `/api/tournaments/${missing}`, 404],
+  ["GET", `/htmx/game/${missing}`, 404],
+  ["POST", `/htmx/join/${missing}`, 404],
+  ["POST", `/htmx/reset/${missing}`, 404],
+  ["POST", `/htmx/draw/${missing}`, 404],
+  ["POST", `/htmx/draw/${missing}/reply`, 404],
+  ["GET", `/htmx/tournament/${missing}`, 404],
+  ["GET", `/htmx/tournament/${missing}/sse`, 404],
+  ["PUT", "/api/nothing", 404],
+];
+
+const wrongMethods: [method: string, path: string, allow: string][] = [
+  ["DELETE", `/api/game/${missing}`, "GET, HEAD, POST, PUT"],
+  ["PATCH", "/api/tournaments", "GET, HEAD, POST"],
+  ["GET", `/api/game/${missing}/draw`, "POST"],
+  ["POST", `/htmx/sse/${missing}`, "GET, HEAD"],
+  ["POST", `/ws/${missing}`, "GET, HEAD"],
+  ["DELETE", "/app.js", "GET, HEAD"],
+];
+
+test.describe("Routes", () => {
+  for (const [method, path, status] of routes) {
+    test(`${method} ${path} should answer ${status}`, async ({ request }) => {
+      const res = await request.fetch(path, { method, data: { player: "X" } });
+      expect(res.status()).toBe(status);
+    });
+  }
+
+  for (const [method, path, allow] of wrongMethods) {
+    test(`${method} ${path} should answer 405`, async ({ request }) => {
+      const res = await request.fetch(path, { method });
+      expect(res.status()).toBe(405);
+      expect(res.headers()["allow"]).toBe(allow);
+      if (path.startsWith("/api/")) {
+        const body = await res.json();
+        expect(body.error).toBe("Method not allowed");
+        expect(body.requestId).toBeTruthy();
+      }
+    });
+  }
+
+  test("OPTIONS should be answered by the CORS middleware", async ({ request }) => {
+    const res = await request.fetch(`/api/game/${missing}`, { method: "OPTIONS" });
+    expect(res.status()).toBe(200);
+    expect(res.headers()["access-control-allow-methods"]).toContain("PUT");
+  });
+});
@@ -0,0 +1,87 @@
+import { test, expect } from "@playwright/test";
+
+const SKEW = 90_000;
+
+test.describe("Server time", () => {
+  test("should answer the time without a key or caching", async ({ request }) => {
+    const before = Date.now();
+    const res = await request.get("/api/time");
+    const after = Date.now();
+    expect(res.status()).toBe(200);
+    expect(res.headers()["cache-control"]).toBe("no-store");
+    const serverTime = Date.parse((await res.json()).serverTime);
+    expect(serverTime).toBeGreaterThanOrEqual(before - 1000);
+    expect(serverTime).toBeLessThanOrEqual(after + 1000);
+
+    const version = await (await request.get("/api/version")).json();
+    expect(version.features).toContain("server-time");
+  });
+
+  test("should let a page work out its clock's offset", async ({ page }) => {
+    // Fake a server whose clock is SKEW ahead of the browser's
+    await page.route("**/api/time", async (route) => {
+      const res = await route.fetch();
+      const { serverTime } = await res.json();
+      await route.fulfill({ response: res, json: { serverTime: new Date(Date.parse(serverTime) + SKEW).toISOString() } });
+    });
+    await page.goto("/");
+    const offset = await page.evaluate(async () => {
+      let best = { rtt: Infinity, offset: 0 };
+      for (let i = 0; i < 5; i++) {
+        const sent = Date.now();
+        const { serverTime } = await (await fetch("/api/time")).json();
+        const received = Date.now();
+        const rtt = received - sent;
+        if (rtt < best.rtt) {
+          best = { rtt, offset: Date.parse(serverTime) + rtt / 2 - received };
+        }
+      }
+      return best.offset;
+    });
+    expect(Math.abs(offset - SKEW)).toBeLessThan(1000);
+  });
+
+  test("should stamp clock events and the welcome frame", async ({ page, request, baseURL }) => {
+    const game = await (await request.post("/api/game", { data: { readyCheck: true } })).json();
+    const x = (await request.post(`/api/game/${game.id}/join`, { data: { player: "X" } })).headers()["x-seat-token"];
+    const o = (await request.post(`/api/game/${game.id}/join`, { data: { player: "O" } })).headers()["x-seat-token"];
+
+    await page.goto("/");
+    const frames = page.evaluate(
+      (url) =>
+        new Promise<any[]>((resolve) => {
+          const frames: any[] = [];
+          const ws = new WebSocket(url);
+          ws.onmessage = (e) => {
+            const frame = JSON.parse(e.data);
+            frames.push(frame);
+            if (frame.type === "countdown") {
+              resolve(frames);
+            }
+          };
+        }),
+      `${baseURL!.replace(/^http/, "ws")}/ws/${game.id}`
+    );
+    await page.waitForTimeout(300);
+    await request.post(`/api/game/${game.id}/ready`, { headers: { "X-Seat-Token": x }, data: { player: "X" } });
+    await request.post(`/api/game/${game.id}/ready`, { headers: { "X-Seat-Token": o }, data: { player: "O" } });
+
+    const received = await frames;
+    const welcome = received.find((f) => f.type === "welcome");
+    const countdown = received.find((f) => f.type === "countdown");
+    expect(Date.parse(welcome.data.serverTime)).not.toBeNaN();
+    expect(Date.parse(countdown.data.serverTime)).not.toBeNaN();
+  });
+
+  test("should say the server's time along with when a win can be claimed", async ({ request }) => {
+    const { id } = await (await request.post("/api/game", { data: {} })).json();
+    await request.post(`/api/game/${id}/join`, { data: { player: "X" } });
+    await request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+    await request.post(`/api/game/${id}`, { data: { player: "X", position: 4 } });
+
+    const res = await request.get(`/api/game/${id}/claim?player=X`);
+    expect(res.status()).toBe(200);
+    const claim = await res.json();
+    expect(Date.parse(claim.claimableAt)).toBeGreaterThan(Date.parse(claim.serverTime));
+  });
+});
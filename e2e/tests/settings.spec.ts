@@ -0,0 +1,55 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Game settings", () => {
+  test("should be changed by the creator until the opponent joins", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: {} })).json();
+    const joined = await request.post(`/api/game/${game.id}/join`, { data: { player: "X" } });
+    const headers = { "X-Seat-Token": joined.headers()["x-seat-token"] };
+
+    let res = await request.patch(`/api/game/${game.id}/settings`, {
+      data: { player: "X", analysisLive: true },
+    });
+    expect(res.status()).toBe(403);
+    expect((await res.json()).code).toBe("seat_token");
+
+    res = await request.patch(`/api/game/${game.id}/settings`, {
+      headers,
+      data: { player: "O", analysisLive: true },
+    });
+    expect(res.status()).toBe(403);
+    expect((await res.json()).code).toBe("seat_token");
+
+    res = await request.patch(`/api/game/${game.id}/settings`, {
+      headers,
+      data: { player: "X", analysisLive: true, handicap: { style: "mark", player: "O", position: 4 } },
+    });
+    expect(res.ok()).toBeTruthy();
+    const settings = await (await request.get(`/api/game/${game.id}/settings`)).json();
+    expect(settings).toEqual({ analysisLive: true, handicap: { style: "mark", player: "O", position: 4 } });
+    const state = await (await request.get(`/api/game/${game.id}`)).json();
+    expect(state.board[4]).toBe("O");
+
+    await request.post(`/api/game/${game.id}/join`, { data: { player: "O" } });
+    res = await request.patch(`/api/game/${game.id}/settings`, {
+      headers,
+      data: { player: "X", analysisLive: false },
+    });
+    expect(res.status()).toBe(409);
+    expect((await res.json()).code).toBe("settings_locked");
+  });
+
+  test("should be changed from the waiting room", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[new]" }).click();
+    await expect(page.locator("#status")).toContainText("waiting for an opponent");
+    const gameId = await page.locator("[data-game-id]").first().getAttribute("data-game-id");
+
+    const settingsResponse = page.waitForResponse((res) => res.url().includes("/htmx/settings/"));
+    await page.locator(".settings select").selectOption("double:O");
+    expect((await settingsResponse).ok()).toBeTruthy();
+    await expect(page.locator(".settings select")).toHaveValue("double:O");
+
+    const settings = await (await page.request.get(`/api/game/${gameId}/settings`)).json();
+    expect(settings.handicap).toMatchObject({ style: "double", player: "O" });
+  });
+});
@@ -0,0 +1,29 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Game names", () => {
+  test("should find a game by its name until it is over", async ({ request }) => {
+    const slug = `lunch-${Date.now().toString(36)}`;
+    const game = await (await request.post("/api/game", { data: { slug } })).json();
+    expect(game.slug).toBe(slug);
+
+    const found = await (await request.get(`/api/game/${slug}`)).json();
+    expect(found.id).toBe(game.id);
+
+    const res = await request.post("/api/game", { data: { slug } });
+    expect(res.status()).toBe(409);
+    const body = await res.json();
+    expect(body.code).toBe("slug_taken");
+    expect(body.suggestion).toBe(`${slug}-2`);
+  });
+
+  test("should reject names that aren't lowercase letters, digits or dashes", async ({ request }) => {
+    const res = await request.post("/api/game", { data: { slug: "Friday Lunch" } });
+    expect(res.status()).toBe(400);
+    expect((await res.json()).code).toBe("invalid_slug");
+  });
+
+  test("should make up a name on request", async ({ request }) => {
+    const game = await (await request.post("/api/game", { data: { friendlyId: true } })).json();
+    expect(game.slug).toMatch(/^[a-z]+-[a-z]+(-\d+)?$/);
+  });
+});
@@ -0,0 +1,52 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Start position", () => {
+  test("should start from the given board with the side behind to move", async ({ request }) => {
+    const res = await request.post("/api/game", {
+      data: { mode: "hotseat", startPosition: "X.O/.X./..." },
+    });
+    expect(res.ok()).toBeTruthy();
+    const game = await res.json();
+    expect(game.boardCompact).toBe("X.O/.X./...");
+    expect(game.currentTurn).toBe("O");
+    expect(game.startTurn).toBe("O");
+    expect(game.history).toHaveLength(3);
+    expect(game.history.every((m: { setup?: boolean }) => m.setup)).toBeTruthy();
+    expect(game.lastMove).toBeUndefined();
+
+    const moved = await (
+      await request.post(`/api/game/${game.id}`, { data: { player: "O", position: 8 } })
+    ).json();
+    expect(moved.currentTurn).toBe("X");
+    expect(moved.history[3].setup).toBeUndefined();
+    expect(moved.lastMove).toMatchObject({ position: 8, player: "O" });
+
+    const reset = await (await request.put(`/api/game/${game.id}`)).json();
+    expect(reset.boardCompact).toBe("X.O/.X./...");
+    expect(reset.currentTurn).toBe("O");
+  });
+
+  test("should let either side move first from an even position", async ({ request }) => {
+    const game = await (
+      await request.post("/api/game", { data: { startPosition: "XO.......", startTurn: "O" } })
+    ).json();
+    expect(game.currentTurn).toBe("O");
+    expect(game.startPosition).toBe("XO./.../...");
+  });
+
+  for (const [name, data] of [
+    ["marks more than one apart", { startPosition: "XX./X../..." }],
+    ["a line on the board", { startPosition: "XXX/O.O/..." }],
+    ["a full board", { startPosition: "XOX/XOO/OXX" }],
+    ["the side ahead to move", { startPosition: "X../.../...", startTurn: "X" }],
+    ["a dead position with early draw", { startPosition: "XOX/XOO/OX.", earlyDraw: true }],
+    ["a handicap as well", { startPosition: "X../.../...", handicap: { style: "mark", player: "O" } }],
+    ["a board that doesn't parse", { startPosition: "X.O" }],
+  ] as const) {
+    test(`should reject ${name}`, async ({ request }) => {
+      const res = await request.post("/api/game", { data });
+      expect(res.status()).toBe(400);
+      expect((await res.json()).error).toContain("invalid start position");
+    });
+  }
+});
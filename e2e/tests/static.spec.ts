@@ -0,0 +1,49 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Static assets", () => {
+  test("should reference fingerprinted assets from index.html", async ({
+    request,
+  }) => {
+    const res = await request.get("/");
+    expect(res.ok()).toBeTruthy();
+    expect(res.headers()["cache-control"]).toBe("no-cache");
+    const html = await res.text();
+    expect(html).toMatch(/href="app\.[0-9a-f]{8}\.css"/);
+    expect(html).toMatch(/src="app\.[0-9a-f]{8}\.js"/);
+    expect(html).not.toContain("{{");
+  });
+
+  test("should serve fingerprinted assets as immutable", async ({
+    request,
+  }) => {
+    const html = await (await request.get("/")).text();
+    const css = html.match(/app\.[0-9a-f]{8}\.css/)![0];
+    const res = await request.get(`/${css}`);
+    expect(res.ok()).toBeTruthy();
+    expect(res.headers()["cache-control"]).toContain("immutable");
+    expect(res.headers()["content-type"]).toContain("text/css");
+  });
+
+  test("should revalidate assets requested by their plain name", async ({
+    request,
+  }) => {
+    const res = await request.get("/app.css");
+    expect(res.ok()).toBeTruthy();
+    expect(res.headers()["cache-control"]).toBe("no-cache");
+  });
+
+  test("should 404 unknown files instead of listing directories", async ({
+    request,
+  }) => {
+    expect((await request.get("/missing.css")).status()).toBe(404);
+    expect((await request.get("/app.00000000.css")).status()).toBe(404);
+  });
+
+  test("should fall back to index.html for unknown deep links", async ({
+    page,
+  }) => {
+    const res = await page.goto("/some/deep/link");
+    expect(res?.status()).toBe(200);
+    await expect(page).toHaveTitle("Tic Tac Toe");
+  });
+});
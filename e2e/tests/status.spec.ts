@@ -0,0 +1,50 @@
+import { test, expect } from "@playwright/test";
+
+const admin = { Authorization: "Bearer e2e-admin" };
+
+test.describe("Status page", () => {
+  test("should require the admin key", async ({ request }) => {
+    expect((await request.get("/statusz")).status()).toBe(401);
+    expect((await request.get("/htmx/statusz")).status()).toBe(401);
+  });
+
+  test("should report the server's state as JSON", async ({ request }) => {
+    const res = await request.get("/statusz", { headers: admin });
+    expect(res.ok()).toBeTruthy();
+    const status = await res.json();
+    expect(typeof status.uptime).toBe("number");
+    expect(status.startedAt).toBeTruthy();
+    expect(status.goVersion).toMatch(/^go/);
+    expect(typeof status.build).toBe("object");
+    expect(Object.keys(status.games.games).sort()).toEqual(["finished", "playing", "waiting"]);
+    expect(status.games.storage.ok).toBe(true);
+    expect(typeof status.games.expiry.expired).toBe("number");
+    expect(Object.keys(status.hub.subscribers).sort()).toEqual(["firehose", "sse", "ws"]);
+    for (const counter of ["broadcasts", "events", "dropped", "games"]) {
+      expect(typeof status.hub[counter]).toBe("number");
+    }
+  });
+
+  test("should count a game played to the end", async ({ request }) => {
+    const before = await (await request.get("/statusz", { headers: admin })).json();
+
+    const game = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    for (const [player, position] of [["X", 0], ["O", 3], ["X", 1], ["O", 4], ["X", 2]] as const) {
+      await request.post(`/api/game/${game.id}`, { data: { player, position } });
+    }
+
+    const after = await (await request.get("/statusz", { headers: admin })).json();
+    expect(after.games.games.finished).toBe(before.games.games.finished + 1);
+    expect(after.hub.broadcasts).toBeGreaterThanOrEqual(before.hub.broadcasts + 5);
+    expect(after.uptime).toBeGreaterThan(before.uptime);
+  });
+
+  test("should render the same as a table", async ({ request }) => {
+    const res = await request.get("/htmx/statusz", { headers: admin });
+    expect(res.ok()).toBeTruthy();
+    const html = await res.text();
+    expect(html).toContain('<table id="statusz"');
+    expect(html).toContain("<th>games finished</th>");
+    expect(html).toContain("<th>subscribers ws</th>");
+  });
+});
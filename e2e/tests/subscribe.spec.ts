@@ -0,0 +1,88 @@
+import { test, expect, Page, APIRequestContext } from "@playwright/test";
+
+/** Opens a WebSocket in the page that keeps every frame it gets. */
+async function connect(page: Page, url: string) {
+  await page.evaluate(
+    (url) =>
+      new Promise<void>((resolve) => {
+        const w = window as any;
+        w.frames_ = [];
+        w.ws_ = new WebSocket(url);
+        w.ws_.onmessage = (e: MessageEvent) => w.frames_.push(JSON.parse(e.data));
+        w.ws_.onopen = () => resolve();
+      }),
+    url
+  );
+}
+
+function send(page: Page, msg: object) {
+  return page.evaluate((msg) => (window as any).ws_.send(JSON.stringify(msg)), msg);
+}
+
+/** Waits until a frame matches and returns every frame so far. */
+async function until(page: Page, type: string, gameId: string) {
+  await page.waitForFunction(
+    ([type, gameId]) => (window as any).frames_.some((f: any) => f.type === type && f.gameId === gameId),
+    [type, gameId]
+  );
+  return page.evaluate(() => (window as any).frames_ as any[]);
+}
+
+async function hotseat(request: APIRequestContext) {
+  return (await (await request.post("/api/game", { data: { mode: "hotseat" } })).json()).id as string;
+}
+
+test.describe("Subscribing to several games over one WebSocket", () => {
+  test("should tag each game's updates with its ID", async ({ page, request, baseURL }) => {
+    const [a, b, c] = [await hotseat(request), await hotseat(request), await hotseat(request)];
+    await page.goto("/");
+    await connect(page, `${baseURL!.replace(/^http/, "ws")}/ws/${a}?envelope=1`);
+    await send(page, { type: "subscribe", gameId: b });
+    await send(page, { type: "subscribe", gameId: c });
+    await until(page, "welcome", c);
+
+    await request.post(`/api/game/${b}`, { data: { player: "X", position: 1 } });
+    await request.post(`/api/game/${a}`, { data: { player: "X", position: 0 } });
+    await request.post(`/api/game/${c}`, { data: { player: "X", position: 2 } });
+    await until(page, "game-update", c);
+    const frames = await page.evaluate(() => (window as any).frames_.filter((f: any) => f.seq) as any[]);
+    expect(frames.map((f) => f.gameId)).toEqual([b, a, c]);
+    for (const f of frames) {
+      expect(f.data.id).toBe(f.gameId);
+    }
+    expect(frames[0].data.board[1]).toBe("X");
+    expect(frames[2].data.board[2]).toBe("X");
+  });
+
+  test("should stop sending a game's updates once unsubscribed", async ({ page, request, baseURL }) => {
+    const [a, b] = [await hotseat(request), await hotseat(request)];
+    await page.goto("/");
+    await connect(page, `${baseURL!.replace(/^http/, "ws")}/ws/${a}?envelope=1`);
+    await send(page, { type: "subscribe", gameId: b });
+    await until(page, "welcome", b);
+    await send(page, { type: "unsubscribe", gameId: b });
+    await until(page, "unsubscribed", b);
+
+    await request.post(`/api/game/${b}`, { data: { player: "X", position: 1 } });
+    await request.post(`/api/game/${a}`, { data: { player: "X", position: 0 } });
+    const frames = await until(page, "game-update", a);
+    expect(frames.filter((f) => f.type === "game-update" && f.gameId === b && f.seq)).toEqual([]);
+  });
+
+  test("should play a subscribed game named by gameId", async ({ page, request, baseURL }) => {
+    const [a, b] = [await hotseat(request), await hotseat(request)];
+    await page.goto("/");
+    await connect(page, `${baseURL!.replace(/^http/, "ws")}/ws/${a}`);
+    await send(page, { gameId: b, player: "X", position: 4 });
+    await page.waitForFunction(() => (window as any).frames_.some((f: any) => f.code));
+    const rejected = await page.evaluate(() => (window as any).frames_.find((f: any) => f.code));
+    expect(rejected).toMatchObject({ gameId: b, code: "not_subscribed" });
+
+    await send(page, { type: "subscribe", gameId: b });
+    await until(page, "welcome", b);
+    await send(page, { gameId: b, player: "X", position: 4 });
+    await expect
+      .poll(async () => (await (await request.get(`/api/game/${b}`)).json()).board[4])
+      .toBe("X");
+  });
+});
@@ -0,0 +1,137 @@
+import { test, expect, Page, APIRequestContext } from "@playwright/test";
+
+/** Opens a WebSocket in the page, named name, that keeps every frame it gets and its close code. */
+async function connect(page: Page, name: string, url: string) {
+  await page.evaluate(
+    ([name, url]) =>
+      new Promise<void>((resolve) => {
+        const w = window as any;
+        const ws = new WebSocket(url);
+        w[name] = { ws, frames: [], code: 0 };
+        ws.onmessage = (e: MessageEvent) => w[name].frames.push(JSON.parse(e.data));
+        ws.onclose = (e: CloseEvent) => {
+          w[name].code = e.code;
+          resolve();
+        };
+        ws.onopen = () => resolve();
+      }),
+    [name, url]
+  );
+}
+
+function send(page: Page, name: string, msg: object) {
+  return page.evaluate(([name, msg]) => (window as any)[name].ws.send(JSON.stringify(msg)), [name, msg] as const);
+}
+
+function frames(page: Page, name: string): Promise<any[]> {
+  return page.evaluate((name) => (window as any)[name].frames, name);
+}
+
+/** Creates an online game with both sides joined and returns its ID and X's seat token, as a query parameter. */
+async function withOpponent(request: APIRequestContext, base = "") {
+  const { id } = await (await request.post(`${base}/api/game`)).json();
+  await request.post(`${base}/api/game/${id}/join`, { data: { player: "O" } });
+  const joined = await request.post(`${base}/api/game/${id}/join`, { data: { player: "X" } });
+  const seat = `seat=${encodeURIComponent(joined.headers()["x-seat-token"])}`;
+  return { id: id as string, seat };
+}
+
+test.describe("A second connection for the same player", () => {
+  test("should supersede the first, which only watches from then on", async ({ page, request, baseURL }) => {
+    const { id, seat } = await withOpponent(request);
+    const ws = `${baseURL!.replace(/^http/, "ws")}/ws/${id}`;
+    await page.goto("/");
+    await connect(page, "first", `${ws}?player=X&${seat}`);
+    await connect(page, "second", `${ws}?player=X&${seat}`);
+    await connect(page, "spectator", ws);
+
+    await expect.poll(async () => (await frames(page, "first")).some((f) => f.type === "superseded")).toBe(true);
+    const notice = (await frames(page, "first")).find((f) => f.type === "superseded");
+    expect(notice.data).toEqual({ gameId: id, player: "X" });
+    expect((await frames(page, "second")).some((f) => f.type === "superseded")).toBe(false);
+
+    // the superseded tab can't play, the one that took over can
+    await send(page, "first", { position: 0, player: "X" });
+    await expect.poll(async () => (await frames(page, "first")).find((f) => f.code)?.code).toBe("superseded");
+    await send(page, "second", { position: 4, player: "X" });
+    for (const name of ["first", "second", "spectator"]) {
+      await expect.poll(async () => (await frames(page, name)).some((f) => f.board?.[4] === "X")).toBe(true);
+    }
+
+    // turn notifications for X reach the controlling connection alone
+    await request.post(`/api/game/${id}`, { data: { player: "O", position: 0 } });
+    await expect
+      .poll(async () => (await frames(page, "second")).filter((f) => f.type === "turn-notification").length)
+      .toBe(1);
+    expect((await frames(page, "first")).some((f) => f.type === "turn-notification")).toBe(false);
+    expect((await frames(page, "spectator")).some((f) => f.type === "turn-notification")).toBe(false);
+  });
+
+  test("should only watch without the seat token", async ({ page, request, baseURL }) => {
+    const { id, seat } = await withOpponent(request);
+    const ws = `${baseURL!.replace(/^http/, "ws")}/ws/${id}`;
+    await page.goto("/");
+    await connect(page, "first", `${ws}?player=X&${seat}`);
+    await connect(page, "intruder", `${ws}?player=X&takeover=1`);
+
+    // it can't act for X either
+    await send(page, "intruder", { type: "offer-draw", player: "X" });
+    await expect.poll(async () => (await frames(page, "intruder")).find((f) => f.code)?.code).toBe("seat_token");
+
+    await send(page, "first", { position: 4, player: "X" });
+    await expect.poll(async () => (await frames(page, "first")).some((f) => f.board?.[4] === "X")).toBe(true);
+    expect((await frames(page, "first")).some((f) => f.type === "superseded")).toBe(false);
+  });
+
+  test("should hand the board back and forth between tabs", async ({ context }) => {
+    const created = await context.request.post("/htmx/game/new?player=X");
+    const id = /data-game-id="([^"]+)"/.exec(await created.text())![1];
+    await context.request.post(`/api/game/${id}/join`, { data: { player: "O" } });
+
+    const first = await context.newPage();
+    await first.goto(`/?game=${id}&player=X`);
+    await expect(first.locator(`[hx-post*="/htmx/move/${id}/0?player=X"]`)).toHaveCount(1);
+
+    const second = await context.newPage();
+    await second.goto(`/?game=${id}&player=X`);
+    await expect(first.locator(".superseded-notice")).toContainText("in another tab");
+    await expect(first.locator(`[hx-post*="/htmx/move/${id}/0?player=X"]`)).toHaveCount(0);
+    await expect(second.locator(`[hx-post*="/htmx/move/${id}/0?player=X"]`)).toHaveCount(1);
+
+    await first.locator(".superseded-notice button").click();
+    await expect(second.locator(".superseded-notice")).toBeVisible();
+    await expect(first.locator(`[hx-post*="/htmx/move/${id}/0?player=X"]`)).toHaveCount(1);
+    await expect(second.locator(`[hx-post*="/htmx/move/${id}/0?player=X"]`)).toHaveCount(0);
+  });
+});
+
+test.describe("A second connection under -duplicate-tabs reject", () => {
+  // This server turns second connections away, see playwright.config.ts
+  const BASE = "http://localhost:8083";
+
+  test("should be closed unless it asks to take over", async ({ page, request }) => {
+    const { id, seat } = await withOpponent(request, BASE);
+    const ws = `${BASE.replace(/^http/, "ws")}/ws/${id}?player=X&${seat}`;
+    await page.goto(BASE);
+    await connect(page, "first", ws);
+    await connect(page, "second", ws);
+    await expect.poll(() => page.evaluate(() => (window as any).second.code)).toBe(4004);
+    expect((await frames(page, "first")).some((f) => f.type === "superseded")).toBe(false);
+
+    await connect(page, "third", `${ws}&takeover=1`);
+    await expect.poll(async () => (await frames(page, "first")).some((f) => f.type === "superseded")).toBe(true);
+    expect(await page.evaluate(() => (window as any).third.code)).toBe(0);
+  });
+
+  test("should offer the tab turned away to take over", async ({ context }) => {
+    const { id } = await (await context.request.post(`${BASE}/api/game`)).json();
+    await context.request.post(`${BASE}/api/game/${id}/join`, { data: { player: "O" } });
+    await context.request.post(`${BASE}/htmx/join/${id}?player=X`);
+    const first = await context.newPage();
+    await first.goto(`${BASE}/?game=${id}&player=X`);
+    const second = await context.newPage();
+    await second.goto(`${BASE}/?game=${id}&player=X`);
+    await expect(second.locator(".superseded-notice")).toContainText("already playing X");
+    await expect(first.locator(".superseded-notice")).toHaveCount(0);
+  });
+});
@@ -458,4 +458,63 @@ test.describe("REST API", () => {
     expect(final.isOver).toBe(true);
     expect(final.isDraw).toBe(true);
   });
+
+  test("should list legal moves", async ({ request }) => {
+    const created = await (await request.post("/api/game")).json();
+    expect(created.legalMoves).toEqual([0, 1, 2, 3, 4, 5, 6, 7, 8]);
+
+    const moved = await (
+      await request.post(`/api/game/${created.id}`, {
+        data: { position: 4, player: "X" },
+      })
+    ).json();
+    expect(moved.legalMoves).toEqual([0, 1, 2, 3, 5, 6, 7, 8]);
+  });
+
+  test("should list no legal moves while waiting for an opponent", async ({
+    request,
+  }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    const joined = await (
+      await request.post(`/api/game/${id}/join`, { data: { player: "X" } })
+    ).json();
+    expect(joined.legalMoves).toEqual([]);
+
+    const moveRes = await request.post(`/api/game/${id}`, {
+      data: { position: 0, player: "X" },
+    });
+    expect(moveRes.status()).toBe(409);
+    expect((await moveRes.json()).error).toContain("waiting for an opponent");
+  });
+
+  test("should list the last cell of a forced board", async ({ request }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    // X O X
+    // X O O
+    // O X .
+    const moves = [
+      [0, "X"], [1, "O"], [2, "X"], [4, "O"], [3, "X"], [5, "O"], [7, "X"], [6, "O"],
+    ];
+    let state;
+    for (const [position, player] of moves) {
+      state = await (
+        await request.post(`/api/game/${id}`, { data: { position, player } })
+      ).json();
+    }
+    expect(state.legalMoves).toEqual([8]);
+  });
+
+  test("should list no legal moves once the game is over", async ({
+    request,
+  }) => {
+    const { id } = await (await request.post("/api/game")).json();
+    let state;
+    for (const [position, player] of [[0, "X"], [3, "O"], [1, "X"], [4, "O"], [2, "X"]]) {
+      state = await (
+        await request.post(`/api/game/${id}`, { data: { position, player } })
+      ).json();
+    }
+    expect(state.isOver).toBe(true);
+    expect(state.legalMoves).toEqual([]);
+  });
 });
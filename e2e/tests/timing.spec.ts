@@ -0,0 +1,45 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+async function play(request: APIRequestContext, id: string, position: number, player: string) {
+  const resp = await request.post(`/api/game/${id}`, { data: { position, player } });
+  expect(resp.ok()).toBeTruthy();
+  return resp.json();
+}
+
+test.describe("Move timing", () => {
+  test("should sum up how long each side thought once the game is over", async ({
+    page,
+    request,
+  }) => {
+    const { id } = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+
+    // O takes its time over the game's fourth move
+    await play(request, id, 0, "X");
+    await play(request, id, 3, "O");
+    let game = await play(request, id, 1, "X");
+    expect(game.timing).toBeUndefined();
+    await page.waitForTimeout(1200);
+    await play(request, id, 4, "O");
+    game = await play(request, id, 2, "X");
+    expect(game.isOver).toBe(true);
+
+    const thinks: Record<string, number[]> = { X: [], O: [] };
+    for (const m of game.history) thinks[m.player].push(m.thinkNs ?? 0);
+    expect(game.timing.map((t: any) => t.player)).toEqual(["X", "O"]);
+    for (const t of game.timing) {
+      const mine = thinks[t.player];
+      const sum = mine.reduce((a, b) => a + b, 0);
+      expect(t.moves).toBe(mine.length);
+      expect(t.totalNs).toBe(sum);
+      expect(t.averageNs).toBe(Math.trunc(sum / t.moves));
+      expect(t.longestNs).toBe(Math.max(...mine));
+    }
+    const o = game.timing[1];
+    expect(o.longestNs).toBeGreaterThanOrEqual(1.2e9);
+    expect(o.longestMove).toBe(4);
+
+    await page.goto(`/?game=${id}`);
+    await expect(page.locator("#timing")).toContainText("longest think: O");
+    await expect(page.locator("#timing")).toContainText("on move 4");
+  });
+});
@@ -0,0 +1,162 @@
+import { test, expect, APIRequestContext } from "@playwright/test";
+
+// Moves that end a game with the given side winning after that many of
+// its own moves, or drawn
+const games: Record<string, number[]> = {
+  X3: [0, 3, 1, 4, 2],
+  X4: [0, 1, 3, 6, 4, 5, 8],
+  O3: [0, 3, 1, 4, 8, 5],
+  O4: [0, 3, 1, 7, 8, 4, 6, 5],
+  draw: [0, 1, 2, 4, 3, 5, 7, 6, 8],
+};
+
+// A result: the winner and how many moves they take, or a draw
+type Result = [string, number] | "draw";
+
+// run creates a tournament and plays every match it starts, as decide
+// says, until it has a champion
+async function run(
+  request: APIRequestContext,
+  participants: string[],
+  format: string,
+  decide: (players: string[]) => Result,
+) {
+  const res = await request.post("/api/tournaments", { data: { participants, format } });
+  expect(res.status()).toBe(201);
+  const { id } = await res.json();
+
+  const played = new Set<string>();
+  for (let i = 0; i < 100; i++) {
+    const t = await (await request.get(`/api/tournaments/${id}`)).json();
+    if (t.champion) return t;
+    for (const bracket of t.brackets) {
+      for (const round of bracket.rounds) {
+        for (const m of round) {
+          // A drawn elimination game is reset under the same ID
+          const key = `${m.gameId}/${m.replays ?? 0}`;
+          if (!m.gameId || m.winner || m.draw || played.has(key)) continue;
+          played.add(key);
+          const result = decide(m.players);
+          const moves = result === "draw" ? games.draw : games[(m.players[0] === result[0] ? "X" : "O") + result[1]];
+          for (const [n, position] of moves.entries()) {
+            const player = n % 2 === 0 ? "X" : "O";
+            const resp = await request.post(`/api/game/${m.gameId}`, { data: { position, player } });
+            expect(resp.ok()).toBeTruthy();
+          }
+        }
+      }
+    }
+    await new Promise((r) => setTimeout(r, 300));
+  }
+  throw new Error("tournament never finished");
+}
+
+// results looks a match's result up by its players, in either order
+function results(table: Record<string, Result>) {
+  return (players: string[]) => table[players.join("")] ?? table[[...players].reverse().join("")];
+}
+
+test.describe("Tournament formats", () => {
+  test("should default to single elimination", async ({ request }) => {
+    const t = await (await request.post("/api/tournaments", { data: { participants: ["a", "b", "c"] } })).json();
+    expect(t.format).toBe("single-elimination");
+    expect(t.brackets.map((b: any) => b.name)).toEqual(["main"]);
+    expect(t.brackets[0].rounds[0][0]).toMatchObject({ players: ["a", ""], winner: "a", bye: true });
+    expect(t.standings).toBeUndefined();
+  });
+
+  test("should turn away an unknown format", async ({ request }) => {
+    const res = await request.post("/api/tournaments", { data: { participants: ["a", "b"], format: "swiss" } });
+    expect(res.status()).toBe(400);
+    expect((await res.json()).code).toBe("invalid_tournament_format");
+  });
+
+  test("should play everyone against everyone in a round robin", async ({ request }) => {
+    const t = await run(request, ["a", "b", "c", "d", "e"], "round-robin", () => "draw");
+    const pairs = t.brackets[0].rounds.flat().map((m: any) => [...m.players].sort().join(""));
+    expect(pairs).toHaveLength(10);
+    expect(new Set(pairs).size).toBe(10);
+    for (const st of t.standings) {
+      expect(st).toMatchObject({ played: 4, drawn: 4, points: 4 });
+    }
+    // Level on everything, so by seed
+    expect(t.standings.map((s: any) => s.participant)).toEqual(["a", "b", "c", "d", "e"]);
+    expect(t.champion).toBe("a");
+  });
+
+  test("should break a tie on points head to head before moves", async ({ request }) => {
+    // b and c both end on 3 points; c beat b, though b won in fewer moves
+    const t = await run(
+      request,
+      ["a", "b", "c", "d"],
+      "round-robin",
+      results({ ad: ["a", 3], ac: ["a", 4], ab: ["b", 3], bc: ["c", 4], bd: "draw", cd: "draw" }),
+    );
+    expect(t.standings.map((s: any) => [s.participant, s.points])).toEqual([
+      ["a", 4],
+      ["c", 3],
+      ["b", 3],
+      ["d", 2],
+    ]);
+    expect(t.standings[1].winMoves).toBe(4);
+    expect(t.standings[2].winMoves).toBe(3);
+    expect(t.champion).toBe("a");
+  });
+
+  test("should break a tie head to head can't by fewest moves, then seed", async ({ request }) => {
+    // Each beats one of the others, so all are level head to head too
+    const t = await run(
+      request,
+      ["a", "b", "c"],
+      "round-robin",
+      results({ ab: ["a", 4], bc: ["b", 3], ca: ["c", 4] }),
+    );
+    expect(t.standings.map((s: any) => [s.participant, s.winMoves])).toEqual([
+      ["b", 3],
+      ["a", 4],
+      ["c", 4],
+    ]);
+    expect(t.champion).toBe("b");
+  });
+
+  test("should drop losers into the losers bracket", async ({ request }) => {
+    // The better seed wins every match
+    const t = await run(request, ["a", "b", "c", "d", "e", "f", "g", "h"], "double-elimination", (p) => [
+      [...p].sort()[0],
+      3,
+    ]);
+    expect(t.brackets.map((b: any) => b.name)).toEqual(["winners", "losers", "final"]);
+    const [, losers, final] = t.brackets;
+    expect(losers.rounds.map((r: any[]) => r.length)).toEqual([2, 2, 1, 1]);
+    expect(losers.rounds[0].map((m: any) => m.players)).toEqual([
+      ["h", "g"],
+      ["f", "e"],
+    ]);
+    // The winners bracket's winner plays X in the grand final
+    expect(final.rounds).toHaveLength(1);
+    expect(final.rounds[0][0]).toMatchObject({ players: ["a", "b"], winner: "a" });
+    expect(t.champion).toBe("a");
+  });
+
+  test("should play the grand final again when the losers bracket wins it", async ({ request }) => {
+    let match = 0;
+    const t = await run(request, ["a", "b"], "double-elimination", () => {
+      match++;
+      return match === 1 ? ["b", 3] : ["a", 3];
+    });
+    const final = t.brackets[2];
+    expect(final.rounds).toHaveLength(2);
+    expect(final.rounds[0][0]).toMatchObject({ players: ["b", "a"], winner: "a" });
+    expect(final.rounds[1][0]).toMatchObject({ players: ["b", "a"], winner: "a" });
+    expect(t.champion).toBe("a");
+  });
+
+  test("should show standings for a round robin", async ({ page, request }) => {
+    const { id } = await (
+      await request.post("/api/tournaments", { data: { participants: ["ann", "bob", "cat"], format: "round-robin" } })
+    ).json();
+    await page.goto(`/?tournament=${id}`);
+    await expect(page.locator(".bracket[data-bracket=schedule] .round")).toHaveCount(3);
+    await expect(page.locator(".standings tbody tr")).toHaveCount(3);
+  });
+});
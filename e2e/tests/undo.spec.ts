@@ -0,0 +1,41 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Undo reset", () => {
+  test("should put a reset game back until a move is made", async ({ request }) => {
+    const created = await request.post("/api/game", { data: { mode: "hotseat" } });
+    const game = await created.json();
+    const asX = { headers: { "X-Seat-Token": created.headers()["x-seat-token"] }, data: { player: "X" } };
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 4 } });
+    await request.put(`/api/game/${game.id}`, asX);
+
+    let res = await request.post(`/api/game/${game.id}/undo-reset`, { ...asX, data: { player: "O" } });
+    expect(res.status()).toBe(403);
+    expect((await res.json()).code).toBe("not_resetter");
+
+    res = await request.post(`/api/game/${game.id}/undo-reset`, asX);
+    expect(res.ok()).toBeTruthy();
+    expect((await res.json()).board[4]).toBe("X");
+
+    res = await request.post(`/api/game/${game.id}/undo-reset`, asX);
+    expect(res.status()).toBe(409);
+    expect((await res.json()).code).toBe("nothing_to_undo");
+
+    await request.put(`/api/game/${game.id}`, asX);
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 0 } });
+    res = await request.post(`/api/game/${game.id}/undo-reset`, asX);
+    expect(res.status()).toBe(409);
+    expect((await res.json()).code).toBe("undo_stale");
+  });
+
+  test("should offer to undo a reset in the page", async ({ page }) => {
+    await page.goto("/");
+    await page.locator("button", { hasText: "[hot-seat]" }).click();
+    await page.locator(".cell").nth(4).click();
+    await expect(page.locator(".cell").nth(4)).toHaveClass(/x/);
+
+    await page.locator("button", { hasText: "[reset]" }).click();
+    await expect(page.locator(".cell").nth(4)).not.toHaveClass(/x/);
+    await page.locator(".undo button").click();
+    await expect(page.locator(".cell").nth(4)).toHaveClass(/x/);
+  });
+});
@@ -0,0 +1,59 @@
+import { test, expect } from "@playwright/test";
+
+test.describe("Version handshake", () => {
+  test("should describe the build and what it offers", async ({ request }) => {
+    const res = await request.get("/api/version");
+    expect(res.status()).toBe(200);
+    const v = await res.json();
+    expect(typeof v.version).toBe("string");
+    expect(v.version).not.toBe("");
+    expect(v.apiVersion).toBe(1);
+    expect(v.features).toEqual([...v.features].sort());
+    for (const feature of ["ai", "delta", "embed", "events", "subscribe", "takeover"]) {
+      expect(v.features).toContain(feature);
+    }
+    expect(res.headers()["x-server-version"]).toBe(v.version);
+  });
+
+  test("should list only the features the server is configured with", async ({ request }) => {
+    // The main server has an admin key, see playwright.config.ts, the
+    // quota one doesn't
+    const main = await (await request.get("/api/version")).json();
+    expect(main.features).toContain("admin");
+    const quota = await (await request.get("http://localhost:8082/api/version")).json();
+    expect(quota.features).not.toContain("admin");
+    expect(quota.features).toContain("ai");
+  });
+
+  test("should stamp every API response with the version", async ({ request }) => {
+    const { version } = await (await request.get("/api/version")).json();
+    const created = await request.post("/api/game");
+    expect(created.headers()["x-server-version"]).toBe(version);
+    const missing = await request.get("/api/game/zzzzzzzz");
+    expect(missing.status()).toBe(404);
+    expect(missing.headers()["x-server-version"]).toBe(version);
+    expect((await request.get("/")).headers()["x-server-version"]).toBeUndefined();
+  });
+
+  test("should greet WebSocket clients with the version", async ({ page, request, baseURL }) => {
+    const { version } = await (await request.get("/api/version")).json();
+    const { id } = await (await request.post("/api/game")).json();
+    await page.goto("/");
+    const welcome = await page.evaluate(
+      (url) =>
+        new Promise<any>((resolve) => {
+          const ws = new WebSocket(url);
+          ws.onmessage = (e) => {
+            const frame = JSON.parse(e.data);
+            if (frame.type === "welcome") {
+              ws.close();
+              resolve(frame.data);
+            }
+          };
+        }),
+      `${baseURL!.replace(/^http/, "ws")}/ws/${id}`
+    );
+    expect(welcome.version).toBe(version);
+    expect(welcome.apiVersion).toBe(1);
+  });
+});
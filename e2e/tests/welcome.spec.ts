@@ -0,0 +1,48 @@
+import { test, expect, Page } from "@playwright/test";
+
+/** Connects a WebSocket in the page and resolves with its welcome frame's data. */
+function welcome(page: Page, url: string) {
+  return page.evaluate(
+    (url) =>
+      new Promise<any>((resolve) => {
+        const ws = new WebSocket(url);
+        ws.onmessage = (e) => {
+          const msg = JSON.parse(e.data);
+          if (msg.type === "welcome") {
+            ws.close();
+            resolve(msg.data);
+          }
+        };
+      }),
+    url
+  );
+}
+
+test.describe("WebSocket welcome frame", () => {
+  test("should sum up the state of the world on connecting", async ({ page, request, baseURL }) => {
+    const game = await (await request.post("/api/game", { data: { mode: "hotseat" } })).json();
+    await request.post(`/api/game/${game.id}`, { data: { player: "X", position: 4 } });
+    await page.goto("/");
+
+    const ws = `${baseURL!.replace(/^http/, "ws")}/ws/${game.id}`;
+    const data = await welcome(page, ws);
+    expect(data.instanceId).toBeTruthy();
+    expect(data.seq).toBe(1);
+    expect(data.game.board[4]).toBe("X");
+    expect(data.events).toHaveLength(1);
+    expect(data.events[0]).toMatchObject({ seq: 1, type: "game-update" });
+    expect(data.events[0].lastMove).toMatchObject({ position: 4, player: "X" });
+
+    // The instance is the same until the server restarts
+    const again = await welcome(page, `${ws}?delta=1`);
+    expect(again.instanceId).toBe(data.instanceId);
+  });
+
+  test("should welcome a client of a game that doesn't exist", async ({ page, baseURL }) => {
+    await page.goto("/");
+    const data = await welcome(page, `${baseURL!.replace(/^http/, "ws")}/ws/nosuchgame`);
+    expect(data.game).toBeUndefined();
+    expect(data.seq).toBe(0);
+    expect(data.events).toEqual([]);
+  });
+});
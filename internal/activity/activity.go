@@ -0,0 +1,205 @@
+// Package activity keeps a short log of the actions each game was sent
+// and how the game service answered them. Client addresses aren't kept.
+package activity
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/models"
+)
+
+// MaxEntries bounds how much of a game's activity is kept. Once it has
+// that many entries, each new one drops the oldest.
+const MaxEntries = 100
+
+var ErrNotJoined = errors.New("only a player who has joined the game can see its activity")
+
+// Transport is how an action reached the server.
+type Transport string
+
+// The transports actions arrive over.
+const (
+	TransportAPI  Transport = "api"
+	TransportWS   Transport = "ws"
+	TransportHTMX Transport = "htmx"
+)
+
+// The actions recorded, named like the game service's journal events,
+// see game.EventMoved.
+const (
+	ActionMove      = "move"
+	ActionMoveBatch = "move-batch"
+	ActionJoin      = "join"
+	ActionVacate    = "vacate"
+	ActionReset     = "reset"
+	ActionUndo      = "undo"
+	ActionCancel    = "cancel"
+	ActionDrawOffer = "draw-offer"
+	ActionDrawReply = "draw-reply"
+	ActionClaim     = "claim"
+	ActionReady     = "ready"
+	ActionUnready   = "unready"
+	ActionSettings  = "settings"
+)
+
+// ResultOK is the Result of an action the game service carried out.
+const ResultOK = "ok"
+
+// resultError is the Result of an action turned down with an error that
+// has no code, see game.Code.
+const resultError = "error"
+
+// Entry is one action received for a game.
+type Entry struct {
+	At        time.Time `json:"at"`
+	Transport Transport `json:"transport"`
+	// Player is the side the action claimed to be from, if any.
+	Player models.Player `json:"player,omitempty"`
+	// Action names what was asked, such as "move" or "draw-offer".
+	Action string `json:"action"`
+	// Move is the move as it was received, if the action was one.
+	Move *models.Move `json:"move,omitempty"`
+	// Result is ResultOK or the error code the action was turned down
+	// with.
+	Result string `json:"result"`
+	// RequestID is the request the action came in, or that opened the
+	// WebSocket connection it came over, with ConnID naming that
+	// connection.
+	RequestID string `json:"requestId,omitempty"`
+	ConnID    string `json:"connId,omitempty"`
+}
+
+// Result returns the Result of an action the game service answered
+// with err.
+func Result(err error) string {
+	if err == nil {
+		return ResultOK
+	}
+	if code := game.Code(err); code != "" {
+		return code
+	}
+	return resultError
+}
+
+// Recorder is what the handlers record actions with.
+type Recorder interface {
+	// Record adds entry to the activity of the game with the given ID,
+	// which may be a prefix or name of it. It must not block: an entry
+	// it can't take straight away is dropped.
+	Record(ctx context.Context, gameID string, entry Entry)
+}
+
+// gameLog is the activity kept for one game.
+type gameLog struct {
+	mu      sync.Mutex
+	entries []Entry
+	dropped atomic.Uint64
+}
+
+// Log keeps the activity of games in memory. It is safe for concurrent
+// use.
+type Log struct {
+	games *game.Service
+	clock func() time.Time
+
+	byGame sync.Map // game ID -> *gameLog
+}
+
+// NewLog creates an activity log for the games of games. A game's
+// activity is dropped when the game expires or is cancelled.
+func NewLog(games *game.Service) *Log {
+	l := &Log{games: games, clock: time.Now}
+	games.OnGameExpired(l.forget)
+	games.OnGameCancelled(l.forget)
+	return l
+}
+
+func (l *Log) forget(gs models.GameState) {
+	l.byGame.Delete(gs.ID)
+}
+
+// Record adds entry to the activity of the game with the given ID.
+// Entries for unknown games are ignored, and contended ones dropped.
+func (l *Log) Record(ctx context.Context, gameID string, entry Entry) {
+	g, err := l.games.FindGame(ctx, gameID)
+	if err != nil {
+		return
+	}
+	entry.At = l.clock().UTC()
+	entry.RequestID = logging.RequestID(ctx)
+	entry.ConnID = logging.ConnID(ctx)
+
+	v, _ := l.byGame.LoadOrStore(g.ID, &gameLog{})
+	gl := v.(*gameLog)
+	if !gl.mu.TryLock() {
+		gl.dropped.Add(1)
+		return
+	}
+	defer gl.mu.Unlock()
+	if len(gl.entries) >= MaxEntries {
+		gl.entries = slices.Delete(gl.entries, 0, len(gl.entries)-MaxEntries+1)
+	}
+	gl.entries = append(gl.entries, entry)
+}
+
+// Activity is a game's activity so far.
+type Activity struct {
+	GameID  string  `json:"gameId"`
+	Entries []Entry `json:"entries"`
+	// Dropped is how many entries were left out of it because another
+	// was being added at the same moment.
+	Dropped uint64 `json:"dropped"`
+}
+
+// Get returns the activity of the game with the given ID, oldest first,
+// for an admin.
+func (l *Log) Get(ctx context.Context, gameID string) (Activity, error) {
+	g, err := l.games.FindGame(ctx, gameID)
+	if err != nil {
+		return Activity{}, err
+	}
+	return l.activity(g), nil
+}
+
+// GetFor is like Get for player, who must have joined the game, or it
+// returns ErrNotJoined.
+func (l *Log) GetFor(ctx context.Context, gameID string, player models.Player) (Activity, error) {
+	g, err := l.games.FindGame(ctx, gameID)
+	if err != nil {
+		return Activity{}, err
+	}
+	if !joinedAs(g, player) {
+		return Activity{}, ErrNotJoined
+	}
+	return l.activity(g), nil
+}
+
+func (l *Log) activity(g *models.GameState) Activity {
+	activity := Activity{GameID: g.ID, Entries: []Entry{}}
+	if v, ok := l.byGame.Load(g.ID); ok {
+		gl := v.(*gameLog)
+		gl.mu.Lock()
+		activity.Entries = append(activity.Entries, gl.entries...)
+		gl.mu.Unlock()
+		activity.Dropped = gl.dropped.Load()
+	}
+	return activity
+}
+
+// joinedAs reports whether player's side of the game has been joined.
+func joinedAs(g *models.GameState, player models.Player) bool {
+	switch player {
+	case models.PlayerX:
+		return g.PlayerXJoined
+	case models.PlayerO:
+		return g.PlayerOJoined
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+// Package analysis evaluates games created with live analysis after each
+// of their moves and sends the result to their viewers.
+package analysis
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// Live runs the engine for games with AnalysisLive set. Each move is
+// evaluated in its own goroutine, off the path of the move request, and
+// the result is dropped if the game has moved on by the time it is ready.
+type Live struct {
+	games *game.Service
+	hub   *broadcast.Hub
+
+	wg sync.WaitGroup
+}
+
+// NewLive creates a runner fed by the game service's move hook.
+func NewLive(games *game.Service, hub *broadcast.Hub) *Live {
+	l := &Live{games: games, hub: hub}
+	games.OnMoveMade(l.moved)
+	return l
+}
+
+func (l *Live) moved(gs models.GameState) {
+	if !gs.AnalysisLive {
+		return
+	}
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.analyze(context.Background(), &gs)
+	}()
+}
+
+// analyze evaluates gs and sends the result, unless another move landed
+// in the meantime.
+func (l *Live) analyze(ctx context.Context, gs *models.GameState) {
+	eval := game.Analyze(gs)
+	current, ok := l.games.GetGame(ctx, gs.ID)
+	if !ok || current.Version != gs.Version {
+		slog.DebugContext(ctx, "discarding stale analysis", "game_id", gs.ID, "moves", len(gs.History))
+		return
+	}
+	l.hub.SendTo(ctx, gs.ID, broadcast.ToAnalysisViewers(), broadcast.Event{
+		Name: broadcast.AnalysisEvent,
+		Data: broadcast.AnalysisUpdate{GameID: gs.ID, Moves: len(gs.History), Evaluation: eval},
+	})
+}
+
+// Wait blocks until evaluations already started have finished.
+func (l *Live) Wait() {
+	l.wg.Wait()
+}
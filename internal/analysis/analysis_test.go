@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+func TestAnalyzeDropsStaleStateWithinOneTick(t *testing.T) {
+	ctx := context.Background()
+	games := game.NewService(game.WithClock(gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))))
+	defer games.Close()
+	hub := broadcast.NewHub()
+	l := &Live{games: games, hub: hub}
+
+	g, err := games.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat, AnalysisLive: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan broadcast.Message, 16)
+	hub.RegisterSSE(g.ID, ch, broadcast.NewSubscriber("", "viewer"))
+	defer hub.UnregisterSSE(g.ID, ch)
+
+	// The same move again after a reset, in the same tick: the same
+	// number of moves and the same UpdatedAt
+	stale, err := games.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	current, err := games.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.analyze(ctx, stale)
+	l.analyze(ctx, current)
+	select {
+	case msg := <-ch:
+		if msg.Event == nil || msg.Event.Name != broadcast.AnalysisEvent {
+			t.Fatalf("got %+v, want an analysis event", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no analysis of the current state")
+	}
+	select {
+	case msg := <-ch:
+		t.Errorf("got a second message, %+v; the stale state's analysis was sent", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+// ActivityHandler serves games' activity over the REST API.
+type ActivityHandler struct {
+	log   *activity.Log
+	seats *seat.Signer
+	// admin is set by RegisterAdminRoutes, after which the admin key is
+	// accepted in place of a seat token
+	admin *AdminHandler
+}
+
+// NewActivityHandler creates an activity handler, checking players'
+// seat tokens with seats.
+func NewActivityHandler(log *activity.Log, seats *seat.Signer) *ActivityHandler {
+	return &ActivityHandler{log: log, seats: seats}
+}
+
+// RegisterRoutes sets up the route players read a game's activity from,
+// with ?player= naming the side they joined as and the seat token they
+// were given for it in the X-Seat-Token header.
+func (h *ActivityHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/game/{gameID}/activity", h.handleActivity)
+}
+
+// RegisterAdminRoutes sets up the route reading any game's activity,
+// which requires admin's key, and lets the players' route be read with
+// it too.
+func (h *ActivityHandler) RegisterAdminRoutes(mux *http.ServeMux, admin *AdminHandler) {
+	h.admin = admin
+	mux.Handle("GET /api/admin/game/{gameID}/activity", admin.RequireKey(h.adminActivity(admin)))
+}
+
+// adminActivity returns a handler that reads any game's activity,
+// recording that it was read in admin's audit log.
+func (h *ActivityHandler) adminActivity(admin *AdminHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := httpx.PathGameID(r)
+		if err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+		a, err := h.log.Get(r.Context(), gameID)
+		if err != nil {
+			respondErr(w, r, err)
+			return
+		}
+		admin.record(r.Context(), audit.ActionAdminActivity, a.GameID)
+		respondJSON(w, a)
+	}
+}
+
+// handleActivity answers with the game's activity to the player ?player=
+// names, who must show their seat token, or to admin.
+func (h *ActivityHandler) handleActivity(w http.ResponseWriter, r *http.Request) {
+	if h.admin != nil && r.Header.Get("Authorization") != "" {
+		h.admin.RequireKey(h.adminActivity(h.admin)).ServeHTTP(w, r)
+		return
+	}
+	player := models.Player(r.URL.Query().Get("player"))
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	a, err := h.log.GetFor(r.Context(), gameID, player)
+	if err == nil && !h.seats.Holds(a.GameID, r.Header.Get(seat.TokenHeader), player) {
+		err = seat.ErrNoSeat
+	}
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	respondJSON(w, a)
+}
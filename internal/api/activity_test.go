@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestActivityNeedsTheSeatToken(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	joined := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil)
+	token := joined.Header.Get(seat.TokenHeader)
+	if token == "" {
+		t.Fatal("joining handed out no seat token")
+	}
+	var o models.GameState
+	call(t, "POST", url+"/api/game", "", &o)
+	otherToken := call(t, "POST", url+"/api/game/"+o.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+
+	activity := url + "/api/game/" + g.ID + "/activity?player="
+	tests := []struct {
+		name    string
+		url     string
+		headers []string
+		want    int
+	}{
+		{"seat token", activity + "X", []string{seat.TokenHeader, token}, http.StatusOK},
+		{"by ID prefix", url + "/api/game/" + g.ID[:6] + "/activity?player=X", []string{seat.TokenHeader, token}, http.StatusOK},
+		{"admin key", activity + "X", []string{"Authorization", "Bearer " + adminKey}, http.StatusOK},
+		{"no token", activity + "X", nil, http.StatusForbidden},
+		{"another game's token", activity + "X", []string{seat.TokenHeader, otherToken}, http.StatusForbidden},
+		{"made up token", activity + "X", []string{seat.TokenHeader, "X.00000000000000000000000000000000"}, http.StatusForbidden},
+		{"side not joined", activity + "O", []string{seat.TokenHeader, token}, http.StatusForbidden},
+		{"wrong admin key", activity + "X", []string{"Authorization", "Bearer nope"}, http.StatusUnauthorized},
+		{"unknown game", url + "/api/game/nope/activity?player=X", []string{seat.TokenHeader, token}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if res := call(t, "GET", tt.url, "", nil, tt.headers...); res.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, res.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestCreatingAHotSeatGameHandsOutBothSeats(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	res := call(t, "POST", url+"/api/game", `{"mode": "hotseat"}`, &g)
+	token := res.Header.Get(seat.TokenHeader)
+	for _, player := range []string{"X", "O"} {
+		got := call(t, "GET", url+"/api/game/"+g.ID+"/activity?player="+player, "", nil, seat.TokenHeader, token)
+		if got.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", player, got.StatusCode)
+		}
+	}
+
+	// An online game's creator joins separately
+	res = call(t, "POST", url+"/api/game", "", &g)
+	if token := res.Header.Get(seat.TokenHeader); token != "" {
+		t.Errorf("creating an online game handed out %q", token)
+	}
+}
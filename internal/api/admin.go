@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/sse"
+	"tiktaktoes/internal/status"
+)
+
+// AdminHandler serves operator endpoints. Every route requires the admin
+// key as a bearer token.
+type AdminHandler struct {
+	games  *game.Service
+	hub    *broadcast.Hub
+	key    string
+	audit  *audit.Log
+	status *status.Collector
+	// keys with apikey.ScopeAdmin are accepted along with the admin key,
+	// once there are any, see NewKeyHandler
+	keys *apikey.Keyring
+}
+
+// NewAdminHandler creates a new admin handler. key must not be empty.
+// With an audit log, admin actions and rejected keys are recorded in it
+// and it can be read at /api/admin/audit.
+func NewAdminHandler(games *game.Service, hub *broadcast.Hub, key string, auditLog *audit.Log) *AdminHandler {
+	return &AdminHandler{games: games, hub: hub, key: key, audit: auditLog, status: status.NewCollector(games, hub)}
+}
+
+// RegisterRoutes sets up the admin routes.
+func (h *AdminHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /statusz", h.RequireKey(h.handleStatus))
+	mux.Handle("GET /api/admin/hub", h.RequireKey(h.handleHubStats))
+	mux.Handle("GET /api/admin/hub/{gameID}", h.RequireKey(h.handleHubGame))
+	mux.Handle("POST /api/admin/game/{gameID}/reset", h.RequireKey(h.handleHardReset))
+	mux.Handle("GET /api/admin/events", h.RequireKey(h.handleEvents))
+	mux.Handle("GET /api/admin/export", h.RequireKey(h.handleExport))
+	mux.Handle("POST /api/admin/import", h.RequireKey(h.handleImport))
+	if h.audit != nil {
+		mux.Handle("GET /api/admin/audit", h.RequireKey(h.handleAudit))
+	}
+}
+
+// RequireKey rejects requests that don't carry the admin key, or an API
+// key with the admin scope, in an "Authorization: Bearer" header, for
+// admin routes served elsewhere too. Accepted requests are attributed to
+// the key in the audit log.
+func (h *AdminHandler) RequireKey(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.key)) == 1 {
+			next(w, r.WithContext(audit.WithActor(r.Context(), audit.KeyActor(h.key))))
+			return
+		}
+		if ok && h.keys != nil {
+			key, err := h.keys.Check(token, apikey.ScopeAdmin)
+			if key.ID != "" {
+				annotate(r.Context(), "api_key", key.ID)
+			}
+			if err == nil {
+				next(w, r.WithContext(audit.WithActor(r.Context(), key.Actor())))
+				return
+			}
+			if !errors.Is(err, apikey.ErrUnknownKey) {
+				h.record(audit.WithActor(r.Context(), key.Actor()), audit.ActionAdminDenied, "")
+				respondKeyError(w, r, key, err)
+				return
+			}
+		}
+		h.record(r.Context(), audit.ActionAdminDenied, "")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		respondError(w, r, http.StatusUnauthorized, "Admin key required")
+	})
+}
+
+// record writes an audit entry if there is an audit log.
+func (h *AdminHandler) record(ctx context.Context, action, gameID string) {
+	if h.audit != nil {
+		h.audit.Record(ctx, action, gameID, "")
+	}
+}
+
+// handleStatus reports how the server is doing, see status.Report.
+func (h *AdminHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, h.status.Collect(r.Context()))
+}
+
+func (h *AdminHandler) handleHubStats(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, h.hub.Stats())
+}
+
+// hubGameResponse lists the connections to one game.
+type hubGameResponse struct {
+	GameID      string                      `json:"gameId"`
+	Subscribers []broadcast.SubscriberStats `json:"subscribers"`
+}
+
+func (h *AdminHandler) handleHubGame(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	respondJSON(w, hubGameResponse{
+		GameID:      gameID,
+		Subscribers: h.hub.Subscribers(gameID),
+	})
+}
+
+// handleHardReset wipes a game back to how a new one starts, freeing
+// both seats, see game.Service.HardResetGame.
+func (h *AdminHandler) handleHardReset(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	g, err := h.games.HardResetGame(r.Context(), gameID)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	respondJSON(w, g)
+}
+
+// handleEvents streams every game's events, narrowed by ?type= and
+// ?gameId=. A consumer that falls behind is disconnected.
+func (h *AdminHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	filter := broadcast.FirehoseFilter{GameID: game.NormalizeID(r.URL.Query().Get("gameId"))}
+	for _, v := range r.URL.Query()["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+
+	events, cancel := h.hub.SubscribeAll(filter)
+	defer cancel()
+
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	defer sw.Close()
+	h.record(r.Context(), audit.ActionAdminEvents, filter.GameID)
+	sw.Heartbeat(sse.DefaultHeartbeat)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				sw.Send(sse.Event{Name: "disconnected", Data: []byte(`{"reason":"too slow"}`)})
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "encoding firehose event failed", "error", err)
+				continue
+			}
+			if err := sw.Send(sse.Event{Name: ev.Type, Data: data}); err != nil {
+				return
+			}
+		case <-h.hub.Draining():
+			sw.Send(broadcast.Restart())
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+// handleAudit pages through the audit log, oldest first. ?since= takes
+// an RFC 3339 time, ?after= the next cursor of the previous page and
+// ?limit= the page size.
+func (h *AdminHandler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		since = t
+	}
+	var after uint64
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "after must be a cursor from a previous page")
+			return
+		}
+		after = n
+	}
+	limit := defaultAuditPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditPageSize {
+			respondError(w, r, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditPageSize))
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.audit.List(since, after, limit)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, page)
+}
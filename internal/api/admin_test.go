@@ -0,0 +1,73 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+)
+
+func TestAdminHubStats(t *testing.T) {
+	url := serve(t)
+	auth := []string{"Authorization", "Bearer " + adminKey}
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil)
+
+	var stats broadcast.HubStats
+	if res := call(t, "GET", url+"/api/admin/hub", "", &stats, auth...); res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", res.StatusCode)
+	}
+	if stats.Broadcasts == 0 {
+		t.Error("the join's broadcast isn't counted")
+	}
+	if _, ok := stats.Subscribers[broadcast.TransportWS]; !ok {
+		t.Errorf("no WebSocket count in %v", stats.Subscribers)
+	}
+
+	var game struct {
+		GameID      string                      `json:"gameId"`
+		Subscribers []broadcast.SubscriberStats `json:"subscribers"`
+	}
+	if res := call(t, "GET", url+"/api/admin/hub/"+g.ID, "", &game, auth...); res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", res.StatusCode)
+	}
+	if game.GameID != g.ID || game.Subscribers == nil || len(game.Subscribers) != 0 {
+		t.Errorf("got %+v, want %s with an empty list of subscribers", game, g.ID)
+	}
+
+	for _, path := range []string{"/api/admin/hub", "/api/admin/hub/" + g.ID} {
+		if res := call(t, "GET", url+path, "", nil); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s without the admin key: status = %d", path, res.StatusCode)
+		}
+	}
+}
+
+func TestAdminHardReset(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil)
+	call(t, "POST", url+"/api/game/"+g.ID, `{"player": "X", "position": 4}`, nil)
+
+	// A player's reset keeps both seated
+	if res := call(t, "PUT", url+"/api/game/"+g.ID, "", &g); res.StatusCode != http.StatusOK || !g.PlayerXJoined || !g.PlayerOJoined {
+		t.Fatalf("reset: status %d, joined %v, %v", res.StatusCode, g.PlayerXJoined, g.PlayerOJoined)
+	}
+	if res := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil); res.StatusCode != http.StatusConflict {
+		t.Errorf("joining after a reset: status = %d, want %d", res.StatusCode, http.StatusConflict)
+	}
+
+	path := url + "/api/admin/game/" + g.ID + "/reset"
+	if res := call(t, "POST", path, "", nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("hard reset without the admin key: status = %d", res.StatusCode)
+	}
+	if res := call(t, "POST", path, "", &g, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusOK || g.PlayerXJoined || g.PlayerOJoined {
+		t.Fatalf("hard reset: status %d, joined %v, %v", res.StatusCode, g.PlayerXJoined, g.PlayerOJoined)
+	}
+	if res := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil); res.StatusCode != http.StatusOK {
+		t.Errorf("joining after a hard reset: status = %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+)
+
+// CommentaryHandler serves games' commentators and commentary over the
+// REST API.
+type CommentaryHandler struct {
+	commentary *commentary.Service
+}
+
+// NewCommentaryHandler creates a commentary handler.
+func NewCommentaryHandler(commentary *commentary.Service) *CommentaryHandler {
+	return &CommentaryHandler{commentary: commentary}
+}
+
+// RegisterRoutes sets up the commentary routes.
+func (h *CommentaryHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/game/{gameID}/commentator", h.handleAppoint)
+	mux.HandleFunc("DELETE /api/game/{gameID}/commentator", h.handleRevoke)
+	mux.HandleFunc("GET /api/game/{gameID}/commentary", h.handleList)
+	mux.HandleFunc("POST /api/game/{gameID}/commentary", h.handleComment)
+}
+
+// commentatorRequest names the player appointing or revoking the game's
+// commentator.
+type commentatorRequest struct {
+	Player models.Player `json:"player"`
+}
+
+// commentatorResponse hands the commentator's token out, once.
+type commentatorResponse struct {
+	GameID string `json:"gameId"`
+	Token  string `json:"token"`
+}
+
+// commentRequest is a line of commentary, written with the token the
+// commentator was given.
+type commentRequest struct {
+	Token string `json:"token"`
+	Text  string `json:"text"`
+}
+
+// handleAppoint makes a new commentator for the game, revoking the
+// previous one.
+func (h *CommentaryHandler) handleAppoint(w http.ResponseWriter, r *http.Request) {
+	var req commentatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	gameID, token, err := h.commentary.Appoint(r.Context(), gameID, req.Player)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, commentatorResponse{GameID: gameID, Token: token})
+}
+
+// handleRevoke takes the game's commentator away, so their token is
+// turned away from then on.
+func (h *CommentaryHandler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	var req commentatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if err := h.commentary.Revoke(r.Context(), gameID, req.Player); err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CommentaryHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	log, err := h.commentary.Get(r.Context(), gameID)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	respondJSON(w, log)
+}
+
+func (h *CommentaryHandler) handleComment(w http.ResponseWriter, r *http.Request) {
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	line, err := h.commentary.Comment(r.Context(), gameID, req.Token, req.Text)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, line)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}
+	zstdPool = sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	}}
+)
+
+// CompressMiddleware compresses responses with zstd or gzip, whichever
+// the client prefers in Accept-Encoding. Only textual content types are
+// compressed. Server-sent event streams and WebSocket upgrades pass
+// through untouched, since compressing them would buffer messages.
+func CompressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks zstd or gzip from an Accept-Encoding header,
+// preferring the higher quality value and zstd on a tie, or "" for
+// neither.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "zstd" && name != "gzip" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ || (q == bestQ && name == "zstd") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressible reports whether a response of this content type is worth
+// compressing. Event streams are excluded, see CompressMiddleware.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter decides on the first write or flush whether to
+// compress, based on the headers the handler has set by then.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	decided  bool
+	enc      io.WriteCloser
+}
+
+func (cw *compressWriter) decide(status int) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	h := cw.Header()
+	if h.Get("Content-Type") == "" {
+		// Sniffed by net/http after the first write, too late to decide
+		return
+	}
+	if status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusNotModified || status == http.StatusPartialContent ||
+		h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		return
+	}
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+	// The compressed body no longer matches a strong validator
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", "W/"+etag)
+	}
+	switch cw.encoding {
+	case "zstd":
+		z := zstdPool.Get().(*zstd.Encoder)
+		z.Reset(cw.ResponseWriter)
+		cw.enc = z
+	default:
+		gz := gzipPool.Get().(*gzip.Writer)
+		gz.Reset(cw.ResponseWriter)
+		cw.enc = gz
+	}
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	cw.decide(status)
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush writes out whatever has been compressed so far.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands over the connection, which is only possible before
+// anything has been written.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok || cw.decided {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	return h.Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close finishes the compressed stream and returns the encoder to its
+// pool.
+func (cw *compressWriter) close() {
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		enc.Close()
+		gzipPool.Put(enc)
+	case *zstd.Encoder:
+		enc.Close()
+		enc.Reset(nil)
+		zstdPool.Put(enc)
+	}
+}
@@ -0,0 +1,183 @@
+package api_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/api"
+
+	"github.com/gorilla/websocket"
+	"github.com/klauspost/compress/zstd"
+)
+
+// board is a response body the size of a rendered board, which
+// compresses well.
+var board = strings.Repeat(`<button class="cell" hx-post="/htmx/move">X</button>`, 20)
+
+// decompress returns body decoded as encoding says.
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader = body
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	case "zstd":
+		z, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer z.Close()
+		r = z
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name, accept, contentType, want string
+	}{
+		{"gzip JSON", "gzip", "application/json", "gzip"},
+		{"gzip HTML", "gzip, deflate, br", "text/html; charset=utf-8", "gzip"},
+		{"zstd preferred on a tie", "gzip, zstd", "text/html", "zstd"},
+		{"higher quality wins", "zstd;q=0.5, gzip;q=0.8", "application/json", "gzip"},
+		{"nothing accepted", "", "application/json", ""},
+		{"neither accepted", "br, deflate", "application/json", ""},
+		{"not worth compressing", "gzip", "image/png", ""},
+	} {
+		h := api.CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			w.Header().Set("Content-Length", fmt.Sprint(len(board)))
+			io.WriteString(w, board)
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept-Encoding", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		got := w.Header().Get("Content-Encoding")
+		if got != tt.want {
+			t.Errorf("%s: encoded %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+			t.Errorf("%s: Vary %q, want Accept-Encoding", tt.name, vary)
+		}
+		if got != "" {
+			if w.Header().Get("Content-Length") != "" {
+				t.Errorf("%s: kept the uncompressed Content-Length", tt.name)
+			}
+			if w.Body.Len() >= len(board) {
+				t.Errorf("%s: %d bytes compressed to %d", tt.name, len(board), w.Body.Len())
+			}
+		}
+		if body := decompress(t, got, w.Body); body != board {
+			t.Errorf("%s: body %.80q", tt.name, body)
+		}
+	}
+}
+
+func TestCompressMiddlewareWeakensETags(t *testing.T) {
+	h := api.CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, board)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/static/app.css", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if etag := w.Header().Get("ETag"); etag != `W/"v1"` {
+		t.Errorf("ETag %s, want W/\"v1\"", etag)
+	}
+}
+
+// TestCompressMiddlewareSkipsEventStreams checks an event stream is
+// sent as it is and each event arrives as it is flushed, not once the
+// stream ends.
+func TestCompressMiddlewareSkipsEventStreams(t *testing.T) {
+	next := make(chan struct{})
+	srv := httptest.NewServer(api.CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := range 2 {
+			fmt.Fprintf(w, "event: game-update\ndata: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			<-next
+		}
+	})))
+	defer srv.Close()
+	defer close(next)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	// Set by hand, the transport leaves the body as it comes
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if enc := res.Header.Get("Content-Encoding"); enc != "" {
+		t.Fatalf("event stream encoded %s", enc)
+	}
+	if vary := res.Header.Get("Vary"); vary != "Accept-Encoding" {
+		t.Errorf("Vary %q, want Accept-Encoding", vary)
+	}
+	lines := bufio.NewReader(res.Body)
+	for i := range 2 {
+		for _, want := range []string{"event: game-update\n", fmt.Sprintf("data: %d\n", i), "\n"} {
+			if line, err := lines.ReadString('\n'); line != want {
+				t.Fatalf("read %q, %v, want %q", line, err, want)
+			}
+		}
+		next <- struct{}{}
+	}
+}
+
+// TestCompressMiddlewareUpgradesWebSockets checks a WebSocket upgrade
+// through the middleware works from a browser asking for compressed
+// responses, as every browser does.
+func TestCompressMiddlewareUpgradesWebSockets(t *testing.T) {
+	srv := httptest.NewServer(api.CompressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		typ, msg, err := conn.ReadMessage()
+		if err == nil {
+			conn.WriteMessage(typ, msg)
+		}
+	})))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Accept-Encoding": {"gzip, deflate, br, zstd"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if enc := res.Header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("upgrade answered with Content-Encoding %s", enc)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"position":4}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != `{"position":4}` {
+		t.Errorf("echoed %q, %v", msg, err)
+	}
+}
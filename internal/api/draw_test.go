@@ -0,0 +1,45 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestDrawNeedsTheSeat(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	x := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+	o := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil).Header.Get(seat.TokenHeader)
+	call(t, "POST", url+"/api/game/"+g.ID, `{"player": "X", "position": 4}`, nil)
+	offer, reply := url+"/api/game/"+g.ID+"/draw", url+"/api/game/"+g.ID+"/draw/reply"
+
+	tests := []struct {
+		name  string
+		url   string
+		body  string
+		token string
+	}{
+		{"offering without a token", offer, `{"player": "O"}`, ""},
+		{"offering for O with X's token", offer, `{"player": "O"}`, x},
+		{"offering for nobody", offer, `{"player": ""}`, o},
+		{"answering for X with O's token", reply, `{"player": "X", "accept": true}`, o},
+	}
+	var body struct{ Code string }
+	for _, tt := range tests {
+		if res := call(t, "POST", tt.url, tt.body, &body, seat.TokenHeader, tt.token); res.StatusCode != http.StatusForbidden || body.Code != "seat_token" {
+			t.Errorf("%s: %d %s, want 403 seat_token", tt.name, res.StatusCode, body.Code)
+		}
+	}
+
+	var got models.GameState
+	if res := call(t, "POST", offer, `{"player": "O"}`, &got, seat.TokenHeader, o); res.StatusCode != http.StatusOK || got.DrawOffer != models.PlayerO {
+		t.Fatalf("offering with O's token: %d, offer %q", res.StatusCode, got.DrawOffer)
+	}
+	if res := call(t, "POST", reply, `{"player": "X", "accept": true}`, &got, seat.TokenHeader, x); res.StatusCode != http.StatusOK || !got.DrawAgreed {
+		t.Errorf("accepting with X's token: %d, agreed %v", res.StatusCode, got.DrawAgreed)
+	}
+}
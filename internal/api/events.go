@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/httpx"
+)
+
+// eventsResponse lists the events a client missed, see
+// broadcast.Hub.EventsSince. Complete is false when some are no longer
+// kept, in which case the client should fetch the game instead.
+type eventsResponse struct {
+	Seq      uint64                  `json:"seq"`
+	Complete bool                    `json:"complete"`
+	Events   []broadcast.LoggedEvent `json:"events"`
+}
+
+// handleEvents returns the game's events after ?fromSeq=, the number of
+// the last one the client got, so it can fill a gap after reconnecting.
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	g, err := h.gameService.FindGame(r.Context(), gameID)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	var fromSeq uint64
+	if param := r.URL.Query().Get("fromSeq"); param != "" {
+		fromSeq, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "fromSeq must be a non-negative integer")
+			return
+		}
+	}
+	events, seq, complete := h.hub.EventsSince(g.ID, fromSeq)
+	respondJSON(w, eventsResponse{Seq: seq, Complete: complete, Events: events})
+}
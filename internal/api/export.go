@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/errcode"
+	"tiktaktoes/internal/models"
+)
+
+// maxImportLine bounds one game in an import, far above any real one.
+const maxImportLine = 1 << 20
+
+// handleExport streams every game still in progress as JSON Lines, one
+// game state per line with its move history, for /api/admin/import on
+// another instance. ?fields= cuts each game down as for a single one,
+// though such an export can't be imported.
+func (h *AdminHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	fields, err := requestedFields(r)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	games, _, err := h.games.Snapshot(r.Context())
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.record(r.Context(), audit.ActionAdminExport, "")
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="games.jsonl"`)
+	enc := json.NewEncoder(w)
+	for _, g := range games {
+		line, err := project(g, fields)
+		if err == nil {
+			err = enc.Encode(line)
+		}
+		if err != nil {
+			slog.WarnContext(r.Context(), "export interrupted", "error", err)
+			return
+		}
+	}
+}
+
+// importResult is the outcome of one line of an import. Code is stable,
+// see game.Code, and empty for anything but the service's own errors.
+type importResult struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// importResponse summarizes an import.
+type importResponse struct {
+	Imported int            `json:"imported"`
+	Failed   int            `json:"failed"`
+	Results  []importResult `json:"results"`
+}
+
+// handleImport loads games from JSON Lines as written by handleExport,
+// reporting each line's outcome. ?overwrite=1 replaces games in use.
+func (h *AdminHandler) handleImport(w http.ResponseWriter, r *http.Request) {
+	overwrite := r.URL.Query().Get("overwrite") == "1"
+	resp := importResponse{Results: []importResult{}}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, maxImportLine)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		result := importResult{Line: line}
+		var g models.GameState
+		if err := json.Unmarshal(data, &g); err != nil {
+			result.Error = fmt.Sprintf("invalid game: %v", err)
+		} else {
+			result.ID = g.ID
+			imported, err := h.games.Import(r.Context(), &g, overwrite)
+			if err != nil {
+				result.Error = err.Error()
+				result.Code = errcode.FromError(err).Code
+			} else {
+				h.hub.Broadcast(r.Context(), imported.ID, imported)
+			}
+		}
+		if result.Error == "" {
+			resp.Imported++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		respondError(w, r, http.StatusBadRequest, fmt.Sprintf("reading line %d: %v", len(resp.Results)+1, err))
+		return
+	}
+	slog.InfoContext(r.Context(), "games imported", "imported", resp.Imported, "failed", resp.Failed, "overwrite", overwrite)
+	respondJSON(w, resp)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/httpx"
+)
+
+// FeaturedHandler serves the featured games feed over the REST API.
+type FeaturedHandler struct {
+	feed *featured.Feed
+}
+
+// NewFeaturedHandler creates a new featured games handler.
+func NewFeaturedHandler(feed *featured.Feed) *FeaturedHandler {
+	return &FeaturedHandler{feed: feed}
+}
+
+// RegisterRoutes sets up the featured games route.
+func (h *FeaturedHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/featured", h.handleList)
+}
+
+// RegisterAdminRoutes sets up the routes pinning featured games, which
+// require admin's key.
+func (h *FeaturedHandler) RegisterAdminRoutes(mux *http.ServeMux, admin *AdminHandler) {
+	mux.Handle("PUT /api/admin/featured/{gameID}/pin", admin.RequireKey(h.pinner(admin, true)))
+	mux.Handle("DELETE /api/admin/featured/{gameID}/pin", admin.RequireKey(h.pinner(admin, false)))
+}
+
+// handleList returns the feed, pinned games first, then the rest, each
+// newest first.
+func (h *FeaturedHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, h.feed.List())
+}
+
+// pinner returns a handler that pins or unpins a featured game, keeping
+// it at the top of the feed or letting newer games push it out.
+func (h *FeaturedHandler) pinner(admin *AdminHandler, pinned bool) http.HandlerFunc {
+	action := audit.ActionAdminUnpin
+	if pinned {
+		action = audit.ActionAdminPin
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := httpx.PathGameID(r)
+		if err != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+		if err := h.feed.Pin(r.Context(), gameID, pinned); err != nil {
+			respondErr(w, r, err)
+			return
+		}
+		admin.record(r.Context(), action, gameID)
+		respondJSON(w, h.feed.List())
+	}
+}
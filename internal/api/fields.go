@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"tiktaktoes/internal/models"
+)
+
+// gameFields are the top-level fields of a game's JSON that ?fields= can
+// select: those named by models.GameState's struct tags, including its
+// embedded settings, and boardCompact, which its MarshalJSON adds.
+var gameFields = func() []string {
+	fields := append(jsonFields(reflect.TypeFor[models.GameState]()), "boardCompact")
+	slices.Sort(fields)
+	return fields
+}()
+
+// jsonFields returns the JSON names of t's exported fields, flattening
+// embedded structs the way encoding/json does.
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// requestedFields returns the game fields asked for with ?fields=, a
+// comma-separated list such as "board,currentTurn", or nil for all of
+// them. An unknown name is an error listing the valid ones.
+func requestedFields(r *http.Request) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+	var fields []string
+	for name := range strings.SplitSeq(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(gameFields, name) {
+			return nil, fmt.Errorf("unknown field %q, valid fields are: %s", name, strings.Join(gameFields, ", "))
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// project returns the game cut down to the given fields, or the game
+// itself when fields is nil. Fields the game leaves out of its JSON
+// because they are empty, such as a missing handicap, stay out.
+func project(g *models.GameState, fields []string) (any, error) {
+	if fields == nil {
+		return g, nil
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if v, ok := all[name]; ok {
+			projected[name] = v
+		}
+	}
+	return projected, nil
+}
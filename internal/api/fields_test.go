@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestFields(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil)
+
+	tests := []struct {
+		fields string
+		want   []string
+	}{
+		{"board,currentTurn,version", []string{"board", "currentTurn", "version"}},
+		{"%20board%20,%20boardCompact,,", []string{"board", "boardCompact"}},
+		// A missing handicap is left out, as it is without ?fields=
+		{"id,handicap", []string{"id"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var body map[string]json.RawMessage
+		res := call(t, "GET", url+"/api/game/"+g.ID+"?fields="+tt.fields, "", &body)
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%q: status %d", tt.fields, res.StatusCode)
+			continue
+		}
+		if tt.want == nil {
+			if len(body) < 10 {
+				t.Errorf("%q: %d fields, want the whole game", tt.fields, len(body))
+			}
+			continue
+		}
+		got := make([]string, 0, len(body))
+		for name := range body {
+			got = append(got, name)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: fields %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+
+	var body struct{ Error string }
+	res := call(t, "GET", url+"/api/game/"+g.ID+"?fields=board,banana", "", &body)
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(body.Error, `"banana"`) || !strings.Contains(body.Error, "currentTurn") {
+		t.Errorf("an unknown field: %d %q, want a 400 naming it and the valid ones", res.StatusCode, body.Error)
+	}
+}
+
+func TestExportFields(t *testing.T) {
+	url := serve(t)
+	for range 2 {
+		var g models.GameState
+		call(t, "POST", url+"/api/game", "", &g)
+	}
+	req, err := http.NewRequest("GET", url+"/api/admin/export?fields=id,board", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+adminKey)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	lines := 0
+	for s := bufio.NewScanner(res.Body); s.Scan(); lines++ {
+		var line map[string]json.RawMessage
+		if err := json.Unmarshal(s.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := line["id"]; !ok || len(line) != 2 {
+			t.Errorf("line %d: %s, want the ID and board alone", lines, s.Bytes())
+		}
+	}
+	if lines != 2 {
+		t.Errorf("%d games exported, want 2", lines)
+	}
+
+	if res := call(t, "GET", url+"/api/admin/export?fields=banana", "", nil, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("exporting an unknown field: %d, want 400", res.StatusCode)
+	}
+}
@@ -0,0 +1,52 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+)
+
+// FuzzMoveJSON posts anything at all as a move to a hot-seat game O is
+// to move in, and checks that only a move the rules allow changes it.
+func FuzzMoveJSON(f *testing.F) {
+	games := game.NewService()
+	f.Cleanup(func() { games.Close() })
+	mux := server.NewMux(server.Deps{Games: games, Hub: broadcast.NewHub()})
+
+	f.Fuzz(func(t *testing.T, body string) {
+		ctx := t.Context()
+		g, err := games.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer games.CancelGame(ctx, g.ID, models.Empty)
+		before, err := games.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest("POST", "/api/game/"+g.ID, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		after, _ := games.GetGame(ctx, g.ID)
+
+		// The move as the handler decodes it, see handleMakeMove
+		var move models.Move
+		if err := json.NewDecoder(strings.NewReader(body)).Decode(&move); err != nil {
+			if w.Code != http.StatusBadRequest || after.Version != before.Version {
+				t.Fatalf("%q doesn't decode, but was answered %d and the game is now %+v", body, w.Code, after)
+			}
+			return
+		}
+		gametest.WantMoveOutcome(t, before, after, move, w.Code == http.StatusOK)
+	})
+}
@@ -1,24 +1,43 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"tiktaktoes/internal/activity"
 	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/clientip"
+	"tiktaktoes/internal/errcode"
 	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
 	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/seat"
+	"time"
 )
 
 // Handler handles REST API requests.
 type Handler struct {
 	gameService *game.Service
 	hub         *broadcast.Hub
+	activity    activity.Recorder
+	seats       *seat.Signer
 }
 
-// NewHandler creates a new REST API handler.
-func NewHandler(gameService *game.Service, hub *broadcast.Hub) *Handler {
+// NewHandler creates a new REST API handler. With a recorder, the
+// actions it passes to the game service are recorded in the games'
+// activity. Joining hands out seat tokens signed by seats, see package
+// seat.
+func NewHandler(gameService *game.Service, hub *broadcast.Hub, recorder activity.Recorder, seats *seat.Signer) *Handler {
 	return &Handler{
 		gameService: gameService,
 		hub:         hub,
+		activity:    recorder,
+		seats:       seats,
 	}
 }
 
@@ -27,55 +46,496 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/game", h.handleCreateGame)
 	mux.HandleFunc("GET /api/game/{gameID}", h.handleGetGame)
 	mux.HandleFunc("POST /api/game/{gameID}", h.handleMakeMove)
+	mux.HandleFunc("POST /api/game/{gameID}/moves", h.handleMakeMoves)
 	mux.HandleFunc("PUT /api/game/{gameID}", h.handleResetGame)
+	mux.HandleFunc("POST /api/game/{gameID}/undo-reset", h.handleUndoReset)
+	mux.HandleFunc("POST /api/game/{gameID}/join", h.handleJoinGame)
+	mux.HandleFunc("POST /api/game/{gameID}/vacate", h.handleVacateSlot)
+	mux.HandleFunc("POST /api/game/{gameID}/draw", h.handleOfferDraw)
+	mux.HandleFunc("POST /api/game/{gameID}/draw/reply", h.handleRespondDraw)
+	mux.HandleFunc("GET /api/game/{gameID}/claim", h.handleClaimableAt)
+	mux.HandleFunc("POST /api/game/{gameID}/claim", h.handleClaimWin)
+	mux.HandleFunc("POST /api/game/{gameID}/ready", h.handleReady)
+	mux.HandleFunc("POST /api/game/{gameID}/unready", h.handleUnready)
+	mux.HandleFunc("GET /api/game/{gameID}/events", h.handleEvents)
+	mux.HandleFunc("GET /api/game/{gameID}/settings", h.handleGetSettings)
+	mux.HandleFunc("PATCH /api/game/{gameID}/settings", h.handleUpdateSettings)
+	mux.HandleFunc("GET /api/oembed", h.handleOEmbed)
+}
+
+// createGameRequest is the optional body of a create request.
+type createGameRequest struct {
+	XSymbol string `json:"xSymbol"`
+	OSymbol string `json:"oSymbol"`
+	// Mode is "hotseat" for a game played on one device, see
+	// models.ModeHotseat, or "ai" for one against the computer, see
+	// models.ModeAI.
+	Mode models.Mode `json:"mode"`
+	// AnalysisLive evaluates the game after every move for spectators.
+	AnalysisLive bool `json:"analysisLive"`
+	// Handicap gives one side an edge, see models.Handicap.
+	Handicap *handicapRequest `json:"handicap"`
+	// ReadyCheck has the game wait for both players to say they are
+	// ready before it starts.
+	ReadyCheck bool `json:"readyCheck"`
+	// EarlyDraw ends the game as a draw as soon as neither side can win.
+	EarlyDraw bool `json:"earlyDraw"`
+	// StartPosition starts the game from a board in its compact form,
+	// such as "X.O/.X./...", rather than an empty one, with StartTurn
+	// to move, by default whoever is behind.
+	StartPosition string        `json:"startPosition"`
+	StartTurn     models.Player `json:"startTurn"`
+	// Exhibition lets the game take its moves in batches, see
+	// handleMakeMoves.
+	Exhibition bool `json:"exhibition"`
+	// Locale, such as "es", is the language the game is shared in, see
+	// models.GameSettings.
+	Locale string `json:"locale"`
+	// Slug names the game, such as "friday-lunch", so it can be found by
+	// that too. Without one, FriendlyID makes one up.
+	Slug       string `json:"slug"`
+	FriendlyID bool   `json:"friendlyId"`
+}
+
+// handicapRequest is a handicap to create a game with. A mark without a
+// position goes on a random cell.
+type handicapRequest struct {
+	Style    models.HandicapStyle `json:"style"`
+	Player   models.Player        `json:"player"`
+	Position *int                 `json:"position"`
+}
+
+// handicap returns the handicap asked for, or nil for none.
+func (h *handicapRequest) handicap() *models.Handicap {
+	if h == nil {
+		return nil
+	}
+	handicap := &models.Handicap{Style: h.Style, Player: h.Player, Position: game.RandomPosition}
+	if h.Position != nil {
+		handicap.Position = *h.Position
+	}
+	return handicap
 }
 
 func (h *Handler) handleCreateGame(w http.ResponseWriter, r *http.Request) {
-	g := h.gameService.CreateGame(models.Empty)
+	var req createGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	opts := game.CreateOptions{
+		XSymbol: req.XSymbol,
+		OSymbol: req.OSymbol,
+		GameSettings: models.GameSettings{
+			Mode:          req.Mode,
+			AnalysisLive:  req.AnalysisLive,
+			Handicap:      req.Handicap.handicap(),
+			ReadyCheck:    req.ReadyCheck,
+			EarlyDraw:     req.EarlyDraw,
+			StartPosition: req.StartPosition,
+			StartTurn:     req.StartTurn,
+			Exhibition:    req.Exhibition,
+			Locale:        req.Locale,
+		},
+		Slug:       req.Slug,
+		FriendlyID: req.FriendlyID,
+	}
+	if ip := clientip.From(r.Context()); ip.IsValid() {
+		opts.Owner = ip.String()
+	}
+	g, err := h.gameService.CreateGame(r.Context(), models.Empty, opts)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	// A hot-seat or AI game is joined on both sides as it is created
+	if sides := seat.Joined(g); sides != "" {
+		w.Header().Set(seat.TokenHeader, h.seats.Token(g.ID, sides))
+	}
 	respondJSON(w, g)
 }
 
 func (h *Handler) handleGetGame(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
-	g, exists := h.gameService.GetGame(gameID)
-	if !exists {
-		http.Error(w, "Game not found", http.StatusNotFound)
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
 		return
 	}
-	respondJSON(w, g)
+	g, err := h.gameService.FindGame(r.Context(), gameID)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	fields, err := requestedFields(r)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	body, err := project(g, fields)
+	if err != nil {
+		respondError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, body)
 }
 
 func (h *Handler) handleMakeMove(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
 	var move models.Move
 	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	g, err := h.gameService.MakeMove(r.Context(), gameID, move)
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionMove, Player: move.Player, Move: &move}, err)
+	if err != nil {
+		h.notifyError(r, gameID, err)
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	h.hub.NotifyTurn(r.Context(), g, "")
+	respondJSON(w, g)
+}
+
+// movesRequest is a batch of moves, in the order they are made.
+type movesRequest struct {
+	Moves []models.Move `json:"moves"`
+}
+
+// handleMakeMoves makes a batch of moves in an exhibition game, all or
+// none, see game.Service.MakeMoves, so replaying or importing a game
+// takes one request and watchers get one update with the final state.
+// When a move fails the batch, the error envelope's moveIndex says which.
+func (h *Handler) handleMakeMoves(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	var req movesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	g, err := h.gameService.MakeMoves(r.Context(), gameID, req.Moves)
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionMoveBatch}, err)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	h.hub.NotifyTurn(r.Context(), g, "")
+	respondJSON(w, g)
+}
+
+// joinGameRequest takes a player slot and an optional display symbol.
+type joinGameRequest struct {
+	Player models.Player `json:"player"`
+	Symbol string        `json:"symbol"`
+}
+
+func (h *Handler) handleJoinGame(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	var req joinGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	g, err := h.gameService.JoinGame(r.Context(), gameID, req.Player, game.JoinOptions{Symbol: req.Symbol})
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionJoin, Player: req.Player}, err)
+	if err != nil {
+		h.notifyError(r, gameID, err)
+	}
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	w.Header().Set(seat.TokenHeader, h.seats.Token(g.ID, string(req.Player)))
+	respondJSON(w, g)
+}
+
+// vacateSlotRequest names the slot to free. The request carries the
+// other player's seat token.
+type vacateSlotRequest struct {
+	Player models.Player `json:"player"`
+}
+
+func (h *Handler) handleVacateSlot(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	var req vacateSlotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Only the other player may free a slot, so they must hold theirs
+	err = game.ErrInvalidPlayer
+	switch req.Player {
+	case models.PlayerX:
+		err = h.checkSeat(r, gameID, models.PlayerO)
+	case models.PlayerO:
+		err = h.checkSeat(r, gameID, models.PlayerX)
+	}
+	var g *models.GameState
+	if err == nil {
+		g, err = h.gameService.VacateSlot(r.Context(), gameID, req.Player)
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionVacate, Player: req.Player}, err)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	respondJSON(w, g)
+}
+
+// actionRequest offers a draw, answers the opponent's offer, claims the
+// win or says whether the player is ready.
+type actionRequest struct {
+	Player models.Player `json:"player"`
+	Accept bool          `json:"accept"`
+}
+
+func (h *Handler) handleOfferDraw(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, activity.ActionDrawOffer, func(ctx context.Context, gameID string, req actionRequest) (*models.GameState, error) {
+		return h.gameService.OfferDraw(ctx, gameID, req.Player)
+	})
+}
+
+func (h *Handler) handleRespondDraw(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, activity.ActionDrawReply, func(ctx context.Context, gameID string, req actionRequest) (*models.GameState, error) {
+		return h.gameService.RespondDraw(ctx, gameID, req.Player, req.Accept)
+	})
+}
+
+func (h *Handler) handleClaimWin(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, activity.ActionClaim, func(ctx context.Context, gameID string, req actionRequest) (*models.GameState, error) {
+		return h.gameService.ClaimWin(ctx, gameID, req.Player)
+	})
+}
+
+func (h *Handler) handleReady(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, activity.ActionReady, func(ctx context.Context, gameID string, req actionRequest) (*models.GameState, error) {
+		return h.gameService.Ready(ctx, gameID, req.Player, true)
+	})
+}
+
+func (h *Handler) handleUnready(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, activity.ActionUnready, func(ctx context.Context, gameID string, req actionRequest) (*models.GameState, error) {
+		return h.gameService.Ready(ctx, gameID, req.Player, false)
+	})
+}
+
+// claimResponse says when the player asked about may claim the win, and
+// what time it is now by the server's clock, see TimeHandler.
+type claimResponse struct {
+	ClaimableAt time.Time `json:"claimableAt"`
+	ServerTime  time.Time `json:"serverTime"`
+}
+
+// handleClaimableAt answers when ?player= may claim the win from an
+// opponent who left, or why they can't.
+func (h *Handler) handleClaimableAt(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	at, err := h.gameService.ClaimableAt(r.Context(), gameID, models.Player(r.URL.Query().Get("player")))
+	if err != nil {
+		respondErr(w, r, err)
 		return
 	}
+	respondJSON(w, claimResponse{ClaimableAt: at, ServerTime: time.Now().UTC()})
+}
 
-	g, err := h.gameService.MakeMove(gameID, move)
+// handleAction decodes an actionRequest, applies it with do if the
+// request holds the player's seat, recording it in the game's activity
+// as action, and broadcasts the result.
+func (h *Handler) handleAction(w http.ResponseWriter, r *http.Request, action string, do func(context.Context, string, actionRequest) (*models.GameState, error)) {
+	gameID, err := httpx.PathGameID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpx.WriteError(w, r, err)
+		return
+	}
+	var req actionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	h.hub.Broadcast(gameID, g)
+	var g *models.GameState
+	if err = h.checkSeat(r, gameID, req.Player); err == nil {
+		g, err = do(r.Context(), gameID, req)
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: action, Player: req.Player}, err)
+	if err != nil {
+		h.notifyError(r, gameID, err)
+	}
+	var early *game.ClaimError
+	if errors.As(err, &early) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(early.Remaining.Seconds()))))
+	}
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
 	respondJSON(w, g)
 }
 
+// resetRequest is the optional body of a reset or undo request, naming
+// the player making it, who must show their seat token. Only the player
+// who reset a game can undo it.
+type resetRequest struct {
+	Player models.Player `json:"player"`
+}
+
 func (h *Handler) handleResetGame(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
-	g, err := h.gameService.ResetGame(gameID)
+	gameID, err := httpx.PathGameID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		httpx.WriteError(w, r, err)
+		return
+	}
+	var req resetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Player != models.Empty {
+		err = h.checkSeat(r, gameID, req.Player)
+	}
+	var g *models.GameState
+	if err == nil {
+		g, err = h.gameService.ResetGame(r.Context(), gameID, req.Player)
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionReset, Player: req.Player}, err)
+	if err != nil {
+		respondErr(w, r, err)
 		return
 	}
 
-	h.hub.Broadcast(gameID, g)
+	h.hub.Broadcast(r.Context(), g.ID, g)
 	respondJSON(w, g)
 }
 
+// handleUndoReset puts a game reset or cancelled within the undo window
+// back as it was for the player who did so, see game.Service.UndoReset.
+func (h *Handler) handleUndoReset(w http.ResponseWriter, r *http.Request) {
+	var req resetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// A cancelled game is gone, and only found by its full ID
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if g, err := h.gameService.FindGame(r.Context(), gameID); err == nil {
+		gameID = g.ID
+	}
+	if !h.seats.Holds(gameID, r.Header.Get(seat.TokenHeader), req.Player) {
+		err = seat.ErrNoSeat
+	}
+	var g *models.GameState
+	if err == nil {
+		g, err = h.gameService.UndoReset(r.Context(), gameID, req.Player)
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionUndo, Player: req.Player}, err)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	respondJSON(w, g)
+}
+
+// checkSeat returns seat.ErrNoSeat unless r carries a seat token holding
+// player's side of the game.
+func (h *Handler) checkSeat(r *http.Request, gameID string, player models.Player) error {
+	g, err := h.gameService.FindGame(r.Context(), gameID)
+	if err != nil {
+		return err
+	}
+	if !h.seats.Holds(g.ID, r.Header.Get(seat.TokenHeader), player) {
+		return seat.ErrNoSeat
+	}
+	return nil
+}
+
+// record adds entry, for an action the game service answered with err,
+// to the game's activity, if there is a recorder.
+func (h *Handler) record(ctx context.Context, gameID string, entry activity.Entry, err error) {
+	if h.activity == nil {
+		return
+	}
+	entry.Transport = activity.TransportAPI
+	entry.Result = activity.Result(err)
+	h.activity.Record(ctx, gameID, entry)
+}
+
+// notifyError sends a game-error event to the connection r names in
+// broadcast.ConnHeader, if any.
+func (h *Handler) notifyError(r *http.Request, gameID string, err error) {
+	connID := r.Header.Get(broadcast.ConnHeader)
+	if connID == "" {
+		return
+	}
+	g, findErr := h.gameService.FindGame(r.Context(), gameID)
+	if findErr != nil {
+		return
+	}
+	h.hub.NotifyError(r.Context(), g.ID, broadcast.ToConn(connID), errcode.FromError(err).Code, err)
+}
+
+// respondError answers with the error envelope of package respond,
+// whose JSON the htmx handlers answer with too when asked for it.
+func respondError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	respond.Error(w, r, status, message)
+}
+
+// respondErr reports err with the status and code it is told with, see
+// errcode.FromError.
+func respondErr(w http.ResponseWriter, r *http.Request, err error) {
+	respond.Err(w, r, err)
+}
+
+// respondGameError reports a game service error along with its code,
+// with another status than its own.
+func respondGameError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	respond.GameError(w, r, status, err)
+}
+
 func respondJSON(w http.ResponseWriter, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data)
+	respond.JSON(w, http.StatusOK, data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	respond.JSON(w, status, data)
 }
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/server"
+)
+
+// adminKey is the admin key of the servers serve starts.
+const adminKey = "admin"
+
+// serve starts the server's mux on its own game service, made with opts,
+// with activity and the admin routes, and returns its URL.
+func serve(t *testing.T, opts ...game.Option) string {
+	t.Helper()
+	games := game.NewService(opts...)
+	t.Cleanup(func() { games.Close() })
+	srv := httptest.NewServer(server.NewMux(server.Deps{
+		Games:    games,
+		Hub:      broadcast.NewHub(),
+		Activity: activity.NewLog(games),
+		AdminKey: adminKey,
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// call sends a request with body, if not empty, and headers, given as
+// name and value pairs, and decodes a JSON answer into out, if not nil.
+func call(t *testing.T, method, url, body string, out any, headers ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+	} else {
+		io.Copy(io.Discard, res.Body)
+	}
+	return res
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/audit"
+)
+
+// KeyHandler manages API keys through the admin API and requires one of
+// them on the REST API, see Middleware.
+type KeyHandler struct {
+	keys  *apikey.Keyring
+	admin *AdminHandler
+}
+
+// NewKeyHandler creates a key handler. Its routes are admin routes, so
+// admin must be the server's admin handler, which it makes accept keys
+// with the admin scope as well as the admin key.
+func NewKeyHandler(keys *apikey.Keyring, admin *AdminHandler) *KeyHandler {
+	admin.keys = keys
+	return &KeyHandler{keys: keys, admin: admin}
+}
+
+// RegisterRoutes sets up the key management routes.
+func (h *KeyHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("POST /api/admin/keys", h.admin.RequireKey(h.handleCreate))
+	mux.Handle("GET /api/admin/keys", h.admin.RequireKey(h.handleList))
+	mux.Handle("DELETE /api/admin/keys/{id}", h.admin.RequireKey(h.handleRevoke))
+}
+
+// keyResponse describes a key without its hash.
+type keyResponse struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name,omitempty"`
+	Scopes    []apikey.Scope `json:"scopes"`
+	Rate      float64        `json:"rate"`
+	Burst     int            `json:"burst"`
+	CreatedAt time.Time      `json:"createdAt"`
+	// Token is only ever sent in answer to creating the key.
+	Token string `json:"token,omitempty"`
+}
+
+func newKeyResponse(key *apikey.Key) keyResponse {
+	return keyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		Rate:      key.Rate,
+		Burst:     key.Burst,
+		CreatedAt: key.CreatedAt,
+	}
+}
+
+// createKeyRequest describes the key to create. Rate and Burst default
+// to apikey.DefaultRate and apikey.DefaultBurst.
+type createKeyRequest struct {
+	Name   string         `json:"name"`
+	Scopes []apikey.Scope `json:"scopes"`
+	Rate   float64        `json:"rate"`
+	Burst  int            `json:"burst"`
+}
+
+func (h *KeyHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	key, token, err := h.keys.Create(r.Context(), strings.TrimSpace(req.Name), req.Scopes, req.Rate, req.Burst)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	h.admin.record(r.Context(), audit.ActionAdminKeyCreate, "")
+	slog.InfoContext(r.Context(), "API key created", "api_key", key.ID, "scopes", key.Scopes)
+	resp := newKeyResponse(key)
+	resp.Token = token
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (h *KeyHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	keys := h.keys.List()
+	resp := make([]keyResponse, len(keys))
+	for i := range keys {
+		resp[i] = newKeyResponse(&keys[i])
+	}
+	respondJSON(w, resp)
+}
+
+// handleRevoke deletes a key. Requests made with it are turned away from
+// then on.
+func (h *KeyHandler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := h.keys.Revoke(r.Context(), id)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	h.admin.record(r.Context(), audit.ActionAdminKeyRevoke, "")
+	slog.InfoContext(r.Context(), "API key revoked", "api_key", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Middleware requires a bearer API key with the scope a request under
+// /api/ needs, see scopeFor. Admin routes check keys themselves.
+func (h *KeyHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := scopeFor(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		key, err := h.keys.Check(token, scope)
+		if key.ID != "" {
+			annotate(r.Context(), "api_key", key.ID)
+		}
+		if err != nil {
+			respondKeyError(w, r, key, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), key.Actor())))
+	})
+}
+
+// scopeFor returns the scope a request to the REST API needs, and false
+// if it needs no key: it isn't to the REST API, it is to an admin route,
+// or it is to /api/version, /api/time or /api/oembed, which clients and
+// embedding sites read before they could have a key.
+func scopeFor(r *http.Request) (apikey.Scope, bool) {
+	path := r.URL.Path
+	switch {
+	case !strings.HasPrefix(path, "/api/"),
+		strings.HasPrefix(path, "/api/admin/"),
+		path == "/api/version", path == "/api/time", path == "/api/oembed":
+		return "", false
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return apikey.ScopeGamesRead, true
+	case r.Method == http.MethodPost && (path == "/api/game" || path == "/api/tournaments"):
+		return apikey.ScopeGamesCreate, true
+	}
+	return apikey.ScopeGamesPlay, true
+}
+
+// respondKeyError turns a request away for err from Keyring.Check, which
+// found key.
+func respondKeyError(w http.ResponseWriter, r *http.Request, key apikey.Key, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrRateLimited):
+		// By then the key has a request's worth of its limit back
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(1/key.Rate))))
+	case errors.Is(err, apikey.ErrUnknownKey):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	respondErr(w, r, err)
+}
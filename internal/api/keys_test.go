@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/server"
+)
+
+// serveKeys starts the mux requiring API keys on the REST API, and
+// returns its URL.
+func serveKeys(t *testing.T) string {
+	t.Helper()
+	games := game.NewService()
+	t.Cleanup(func() { games.Close() })
+	keys, err := apikey.NewKeyring(context.Background(), apikey.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.NewMux(server.Deps{
+		Games:    games,
+		Hub:      broadcast.NewHub(),
+		AdminKey: adminKey,
+		APIKeys:  keys,
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// createKey creates a key over the admin API from body, such as
+// `{"scopes":["games:read"]}`, and returns its ID and token.
+func createKey(t *testing.T, url, body string) (id, token string) {
+	t.Helper()
+	var key struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	if res := call(t, "POST", url+"/api/admin/keys", body, &key, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusCreated {
+		t.Fatalf("creating a key with %s: status = %d", body, res.StatusCode)
+	}
+	return key.ID, key.Token
+}
+
+// TestKeyScopes makes each kind of request with a key of each scope,
+// and checks only those the scope covers get through.
+func TestKeyScopes(t *testing.T) {
+	url := serveKeys(t)
+	_, owner := createKey(t, url, `{"scopes":["games:create","games:play","games:read"]}`)
+	bearer := func(token string) []string { return []string{"Authorization", "Bearer " + token} }
+
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g, bearer(owner)...)
+	requests := map[apikey.Scope]struct{ method, path string }{
+		apikey.ScopeGamesRead:   {"GET", "/api/game/" + g.ID},
+		apikey.ScopeGamesCreate: {"POST", "/api/game"},
+		apikey.ScopeGamesPlay:   {"PUT", "/api/game/" + g.ID},
+		apikey.ScopeAdmin:       {"GET", "/api/admin/hub"},
+	}
+
+	for _, scope := range apikey.Scopes {
+		_, token := createKey(t, url, `{"scopes":["`+string(scope)+`"]}`)
+		for needs, req := range requests {
+			var body respond.ErrorBody
+			res := call(t, req.method, url+req.path, "", &body, bearer(token)...)
+			switch {
+			case needs == scope && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated:
+				t.Errorf("%s key, %s %s: status = %d, want it allowed", scope, req.method, req.path, res.StatusCode)
+			case needs != scope && (res.StatusCode != http.StatusForbidden || body.Code != "key_scope"):
+				t.Errorf("%s key, %s %s: %d %q, want 403 key_scope", scope, req.method, req.path, res.StatusCode, body.Code)
+			}
+		}
+	}
+
+	// A read-only key can watch a game but not move in it
+	_, reader := createKey(t, url, `{"scopes":["games:read"]}`)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil, bearer(owner)...)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil, bearer(owner)...)
+	if res := call(t, "POST", url+"/api/game/"+g.ID, `{"player": "X", "position": 4}`, nil, bearer(reader)...); res.StatusCode != http.StatusForbidden {
+		t.Errorf("a move with a read-only key: status = %d", res.StatusCode)
+	}
+
+	// No key, a made-up one or the admin key, which isn't an API key,
+	// gets nowhere but the routes read before a client could have one
+	for _, headers := range [][]string{nil, bearer("not-a-key"), bearer(adminKey)} {
+		res := call(t, "GET", url+"/api/game/"+g.ID, "", nil, headers...)
+		if res.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(res.Header.Get("WWW-Authenticate"), "Bearer") {
+			t.Errorf("GET with %q: status = %d, WWW-Authenticate %q", headers, res.StatusCode, res.Header.Get("WWW-Authenticate"))
+		}
+		if res := call(t, "GET", url+"/api/version", "", nil, headers...); res.StatusCode != http.StatusOK {
+			t.Errorf("GET /api/version with %q: status = %d", headers, res.StatusCode)
+		}
+	}
+	// The admin key still opens the admin routes
+	if res := call(t, "GET", url+"/api/admin/hub", "", nil, bearer(adminKey)...); res.StatusCode != http.StatusOK {
+		t.Errorf("GET /api/admin/hub with the admin key: status = %d", res.StatusCode)
+	}
+}
+
+func TestKeyRevocation(t *testing.T) {
+	url := serveKeys(t)
+	id, token := createKey(t, url, `{"scopes":["games:create","games:read"]}`)
+	auth := []string{"Authorization", "Bearer " + token}
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g, auth...)
+	if res := call(t, "GET", url+"/api/game/"+g.ID, "", nil, auth...); res.StatusCode != http.StatusOK {
+		t.Fatalf("before revoking: status = %d", res.StatusCode)
+	}
+	if res := call(t, "DELETE", url+"/api/admin/keys/"+id, "", nil, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoking: status = %d", res.StatusCode)
+	}
+	if res := call(t, "GET", url+"/api/game/"+g.ID, "", nil, auth...); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("right after revoking: status = %d, want 401", res.StatusCode)
+	}
+	if res := call(t, "DELETE", url+"/api/admin/keys/"+id, "", nil, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusNotFound {
+		t.Errorf("revoking again: status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestKeyRateLimit(t *testing.T) {
+	url := serveKeys(t)
+	_, token := createKey(t, url, `{"scopes":["games:create","games:read"],"rate":0.5,"burst":3}`)
+	auth := []string{"Authorization", "Bearer " + token}
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g, auth...)
+	for i := range 2 {
+		if res := call(t, "GET", url+"/api/game/"+g.ID, "", nil, auth...); res.StatusCode != http.StatusOK {
+			t.Fatalf("request %d within the burst: status = %d", i, res.StatusCode)
+		}
+	}
+	res := call(t, "GET", url+"/api/game/"+g.ID, "", nil, auth...)
+	if res.StatusCode != http.StatusTooManyRequests || res.Header.Get("Retry-After") != "2" {
+		t.Errorf("past the burst: status = %d, Retry-After %q, want 429 after 2", res.StatusCode, res.Header.Get("Retry-After"))
+	}
+	// Each key has a limit of its own
+	_, other := createKey(t, url, `{"scopes":["games:read"]}`)
+	if res := call(t, "GET", url+"/api/game/"+g.ID, "", nil, "Authorization", "Bearer "+other); res.StatusCode != http.StatusOK {
+		t.Errorf("another key: status = %d", res.StatusCode)
+	}
+}
+
+// TestKeysOff checks a server not requiring keys behaves as if there
+// were no such thing.
+func TestKeysOff(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	if res := call(t, "POST", url+"/api/game", "", &g, "Authorization", "Bearer not-a-key"); res.StatusCode != http.StatusOK {
+		t.Errorf("creating a game with a bearer token: status = %d", res.StatusCode)
+	}
+	if res := call(t, "GET", url+"/api/game/"+g.ID, "", nil); res.StatusCode != http.StatusOK {
+		t.Errorf("reading a game with no key: status = %d", res.StatusCode)
+	}
+	for _, method := range []string{"GET", "POST"} {
+		if res := call(t, method, url+"/api/admin/keys", `{"scopes":["games:read"]}`, nil, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusNotFound {
+			t.Errorf("%s /api/admin/keys: status = %d, want 404", method, res.StatusCode)
+		}
+	}
+}
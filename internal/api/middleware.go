@@ -1,13 +1,35 @@
 package api
 
-import "net/http"
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/clientip"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/seat"
+)
+
+// RequestIDHeader is the header used to propagate request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds the length of a client supplied request ID
+const maxRequestIDLen = 128
 
 // CORSMiddleware adds CORS headers to responses
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader+", "+seat.TokenHeader+", "+broadcast.ConnHeader)
+		w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader+", "+VersionHeader+", "+seat.TokenHeader)
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -17,3 +39,158 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequestIDMiddleware assigns every request an ID, or keeps a sane
+// X-Request-ID, echoes it in the response and logs the request.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewID()
+		}
+		ctx := logging.WithRequestID(r.Context(), id)
+		notes := &requestNotes{}
+		ctx = context.WithValue(ctx, requestNotesKey{}, notes)
+		w.Header().Set(RequestIDHeader, id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+		}
+		if ip := clientip.From(ctx); ip.IsValid() {
+			attrs = append(attrs, "client_ip", ip.String())
+		}
+		notes.mu.Lock()
+		attrs = append(attrs, notes.attrs...)
+		notes.mu.Unlock()
+		slog.InfoContext(ctx, "request", attrs...)
+	})
+}
+
+type requestNotesKey struct{}
+
+// requestNotes are attributes for the log line of a request, added by
+// the handlers serving it with annotate.
+type requestNotes struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+// annotate adds key and value to the line RequestIDMiddleware logs for
+// the request of ctx, such as who it was made by.
+func annotate(ctx context.Context, key string, value any) {
+	if notes, ok := ctx.Value(requestNotesKey{}).(*requestNotes); ok {
+		notes.mu.Lock()
+		notes.attrs = append(notes.attrs, key, value)
+		notes.mu.Unlock()
+	}
+}
+
+// validRequestID reports whether a client supplied ID is safe to reuse.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// MethodNotAllowedMiddleware answers requests to an API route that
+// doesn't take their method in the JSON error envelope. The mux already
+// responds 405 with the Allow header listing the methods the route does
+// take; this replaces its plain text body. Other paths are left alone.
+func MethodNotAllowedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&methodNotAllowedWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+// methodNotAllowedWriter turns a 405 into a JSON error, discarding the
+// body written after it.
+type methodNotAllowedWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	replaced bool
+}
+
+func (w *methodNotAllowedWriter) WriteHeader(status int) {
+	if status != http.StatusMethodNotAllowed || w.replaced {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.replaced = true
+	respondError(w.ResponseWriter, w.r, status, "Method not allowed")
+}
+
+func (w *methodNotAllowedWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *methodNotAllowedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *methodNotAllowedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusRecorder captures the response status while passing through the
+// optional interfaces SSE and WebSocket handlers rely on.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
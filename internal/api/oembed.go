@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/urls"
+)
+
+// The size an embedded board is framed at unless the consumer asks for
+// less, enough for the board, its status line and the link under it.
+const (
+	embedWidth  = 260
+	embedHeight = 330
+)
+
+// oEmbedResponse is the oEmbed description of a game, see
+// https://oembed.com: a "rich" type whose HTML frames the game's embed
+// page.
+type oEmbedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	Title        string `json:"title"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// handleOEmbed describes the game a share link (/?game=<id>) or embed
+// page (/embed/<id>) on this server shows, so sites that know oEmbed can
+// frame it from the link alone. Only JSON is offered.
+func (h *Handler) handleOEmbed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if format := q.Get("format"); format != "" && format != "json" {
+		respondError(w, r, http.StatusNotImplemented, "Only the json format is supported")
+		return
+	}
+	width, height, err := embedSize(q)
+	if err != nil {
+		respondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	base := baseURL(r)
+	gameID, ok := embeddedGame(r, q.Get("url"))
+	if !ok {
+		respondError(w, r, http.StatusNotFound, "URL is not a game on this server")
+		return
+	}
+	g, err := h.gameService.FindGame(r.Context(), gameID)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	name := g.Slug
+	if name == "" {
+		name = g.ID
+	}
+	// The title is shown to whoever the link is shared with, so it is in
+	// the game's language rather than that of whoever asked
+	title := i18n.T(i18n.WithLang(r.Context(), g.Locale), "embed.title", name)
+	src := base + urls.Pathf(r.Context(), "/embed/%s", g.ID)
+	respondJSON(w, oEmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Tic Tac Toe",
+		ProviderURL:  base + urls.Path(r.Context(), "/"),
+		Title:        title,
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" frameborder="0" title="%s"></iframe>`,
+			html.EscapeString(src), width, height, html.EscapeString(title)),
+		Width:  width,
+		Height: height,
+	})
+}
+
+// embedSize returns the size to frame a board at, no larger than the
+// maxwidth and maxheight asked for.
+func embedSize(q url.Values) (width, height int, err error) {
+	width, height = embedWidth, embedHeight
+	for _, limit := range []struct {
+		name string
+		size *int
+	}{{"maxwidth", &width}, {"maxheight", &height}} {
+		v := q.Get(limit.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("%s must be a positive number", limit.name)
+		}
+		*limit.size = min(*limit.size, n)
+	}
+	return width, height, nil
+}
+
+// embeddedGame returns the ID of the game link shows, if it is a share
+// link or embed page of this server.
+func embeddedGame(r *http.Request, link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || link == "" || (u.Host != "" && !strings.EqualFold(u.Host, r.Host)) {
+		return "", false
+	}
+	path, ok := strings.CutPrefix(u.Path, urls.Prefix(r.Context()))
+	if !ok {
+		return "", false
+	}
+	if id, ok := strings.CutPrefix(path, "/embed/"); ok && id != "" && !strings.Contains(id, "/") {
+		return id, true
+	}
+	if id := u.Query().Get("game"); id != "" && (path == "/" || path == "") {
+		return id, true
+	}
+	return "", false
+}
+
+// baseURL returns the scheme and host the request was made to.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestOEmbed(t *testing.T) {
+	base := serve(t)
+	var g models.GameState
+	call(t, "POST", base+"/api/game", "", &g)
+	oembed := func(link, extra string) string {
+		return base + "/api/oembed?url=" + url.QueryEscape(link) + extra
+	}
+
+	for _, link := range []string{
+		base + "/?game=" + g.ID,
+		base + "/embed/" + g.ID,
+		"/embed/" + g.ID,
+	} {
+		var got map[string]any
+		if res := call(t, "GET", oembed(link, ""), "", &got); res.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d", link, res.StatusCode)
+			continue
+		}
+		want := map[string]any{
+			"version":       "1.0",
+			"type":          "rich",
+			"provider_name": "Tic Tac Toe",
+			"provider_url":  base + "/",
+			"width":         float64(260),
+			"height":        float64(330),
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %v, want %v", link, k, got[k], v)
+			}
+		}
+		title, _ := got["title"].(string)
+		html, _ := got["html"].(string)
+		if !strings.Contains(title, g.ID) {
+			t.Errorf("%s: title %q doesn't name the game", link, title)
+		}
+		iframe := `<iframe src="` + base + `/embed/` + g.ID + `" width="260" height="330" frameborder="0" title="`
+		if !strings.HasPrefix(html, iframe) || !strings.HasSuffix(html, `"></iframe>`) {
+			t.Errorf("%s: html %s", link, html)
+		}
+		if len(got) != 8 {
+			t.Errorf("%s: %d fields in %v, want 8", link, len(got), got)
+		}
+	}
+
+	var sized struct {
+		HTML   string `json:"html"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	}
+	call(t, "GET", oembed("/embed/"+g.ID, "&maxwidth=200&maxheight=1000&format=json"), "", &sized)
+	if sized.Width != 200 || sized.Height != 330 || !strings.Contains(sized.HTML, `width="200" height="330"`) {
+		t.Errorf("with maxwidth 200 and maxheight 1000: %+v, want 200x330", sized)
+	}
+
+	for _, tt := range []struct {
+		url    string
+		status int
+	}{
+		{oembed("/embed/"+g.ID, "&format=xml"), http.StatusNotImplemented},
+		{oembed("/embed/"+g.ID, "&maxwidth=wide"), http.StatusBadRequest},
+		{oembed("/embed/"+g.ID, "&maxheight=0"), http.StatusBadRequest},
+		{oembed("/embed/nosuchgame", ""), http.StatusNotFound},
+		{oembed("https://elsewhere.example/embed/"+g.ID, ""), http.StatusNotFound},
+		{oembed("/puzzles", ""), http.StatusNotFound},
+		{base + "/api/oembed", http.StatusNotFound},
+	} {
+		if res := call(t, "GET", tt.url, "", nil); res.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.url, res.StatusCode, tt.status)
+		}
+	}
+}
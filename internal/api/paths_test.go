@@ -0,0 +1,28 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestMalformedGameIDsAreNotFound(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+
+	for _, id := range []string{g.ID[:8] + "%3F", g.ID + "%3Fplayer=X", g.ID[:4] + "%2F" + g.ID[5:], "%C3%A9" + g.ID} {
+		var body struct{ Code string }
+		res := call(t, "GET", url+"/api/game/"+id, "", &body)
+		if res.StatusCode != http.StatusNotFound || body.Code != "game_not_found" {
+			t.Errorf("GET %s: status %d, code %q, want 404 game_not_found", id, res.StatusCode, body.Code)
+		}
+	}
+
+	// Still found however it is cased or padded
+	var found models.GameState
+	if res := call(t, "GET", url+"/api/game/%20"+g.ID[:8]+"%20", "", &found); res.StatusCode != http.StatusOK || found.ID != g.ID {
+		t.Errorf("GET by a padded prefix: status %d, game %q", res.StatusCode, found.ID)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/puzzle"
+)
+
+// PuzzleHandler serves the daily puzzle over the REST API.
+type PuzzleHandler struct {
+	puzzles *puzzle.Service
+}
+
+// NewPuzzleHandler creates a new daily puzzle handler.
+func NewPuzzleHandler(puzzles *puzzle.Service) *PuzzleHandler {
+	return &PuzzleHandler{puzzles: puzzles}
+}
+
+// RegisterRoutes sets up the puzzle routes.
+func (h *PuzzleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/puzzle/today", h.handleToday)
+	mux.HandleFunc("POST /api/puzzle/today/attempt", h.handleAttempt)
+}
+
+// puzzleResponse is today's puzzle along with how others are doing.
+type puzzleResponse struct {
+	puzzle.Puzzle
+	BoardCompact string       `json:"boardCompact"`
+	MaxAttempts  int          `json:"maxAttempts"`
+	Stats        puzzle.Stats `json:"stats"`
+}
+
+func (h *PuzzleHandler) handleToday(w http.ResponseWriter, r *http.Request) {
+	p := h.puzzles.Today()
+	respondJSON(w, puzzleResponse{
+		Puzzle:       p,
+		BoardCompact: p.Board.String(),
+		MaxAttempts:  puzzle.MaxAttempts,
+		Stats:        h.puzzles.Stats(),
+	})
+}
+
+// attemptRequest names the cell either by index or by coordinate, like
+// a move.
+type attemptRequest struct {
+	Position int    `json:"position"`
+	Cell     string `json:"cell,omitempty"`
+}
+
+func (h *PuzzleHandler) handleAttempt(w http.ResponseWriter, r *http.Request) {
+	var req attemptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Cell != "" {
+		position, err := models.ParseCell(req.Cell, models.BoardSize)
+		if err != nil {
+			respondErr(w, r, err)
+			return
+		}
+		req.Position = position
+	}
+
+	result, err := h.puzzles.Attempt(puzzle.Session(w, r), req.Position)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	respondJSON(w, result)
+}
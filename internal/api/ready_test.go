@@ -0,0 +1,42 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestReadyNeedsTheSeat(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", `{"readyCheck": true}`, &g)
+	x := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+	o := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil).Header.Get(seat.TokenHeader)
+	ready, unready := url+"/api/game/"+g.ID+"/ready", url+"/api/game/"+g.ID+"/unready"
+
+	var body struct{ Code string }
+	for _, tt := range []struct {
+		name  string
+		url   string
+		token string
+	}{
+		{"readying X without a token", ready, ""},
+		{"readying X with O's token", ready, o},
+		{"unreadying X with O's token", unready, o},
+	} {
+		if res := call(t, "POST", tt.url, `{"player": "X"}`, &body, seat.TokenHeader, tt.token); res.StatusCode != http.StatusForbidden || body.Code != "seat_token" {
+			t.Errorf("%s: %d %s, want 403 seat_token", tt.name, res.StatusCode, body.Code)
+		}
+	}
+
+	var got models.GameState
+	if res := call(t, "POST", ready, `{"player": "X"}`, &got, seat.TokenHeader, x); res.StatusCode != http.StatusOK || !got.PlayerXReady {
+		t.Fatalf("readying X with X's token: %d, ready %v", res.StatusCode, got.PlayerXReady)
+	}
+	var again models.GameState
+	if res := call(t, "POST", unready, `{"player": "X"}`, &again, seat.TokenHeader, x); res.StatusCode != http.StatusOK || again.PlayerXReady {
+		t.Errorf("unreadying X with X's token: %d, ready %v", res.StatusCode, again.PlayerXReady)
+	}
+}
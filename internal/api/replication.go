@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/errcode"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/replica"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/sse"
+)
+
+// ReplicationHandler serves a server's games to standbys following it,
+// and promotes a standby to take over from its primary, see package
+// replica. Every route requires the admin key.
+type ReplicationHandler struct {
+	feed *replica.Feed
+	// follower is set on a standby, nil otherwise
+	follower *replica.Follower
+	hub      *broadcast.Hub
+}
+
+// NewReplicationHandler creates a replication handler streaming feed.
+// follower is the standby's, or nil for a server that follows nobody.
+func NewReplicationHandler(feed *replica.Feed, follower *replica.Follower, hub *broadcast.Hub) *ReplicationHandler {
+	return &ReplicationHandler{feed: feed, follower: follower, hub: hub}
+}
+
+// RegisterRoutes sets up the replication routes behind admin's key.
+func (h *ReplicationHandler) RegisterRoutes(mux *http.ServeMux, admin *AdminHandler) {
+	mux.Handle("GET /api/admin/replication", admin.RequireKey(h.handleStream(admin)))
+	mux.Handle("GET /api/admin/replication/{gameID}", admin.RequireKey(h.handleGame))
+	mux.Handle("POST /api/admin/promote", admin.RequireKey(h.handlePromote(admin)))
+}
+
+// handleStream streams the feed as server-sent events: a sync event with
+// the latest entry of every game, then synced, then a change event for
+// each change as it happens. A follower that can't keep up is sent a
+// final disconnected event and cut off; it should reconnect.
+func (h *ReplicationHandler) handleStream(admin *AdminHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, changes, cancel := h.feed.Subscribe()
+		defer cancel()
+
+		sw, err := sse.NewWriter(w)
+		if err != nil {
+			respondErr(w, r, err)
+			return
+		}
+		defer sw.Close()
+		admin.record(r.Context(), audit.ActionAdminReplicate, "")
+		sw.Heartbeat(sse.DefaultHeartbeat)
+		send := func(name string, e replica.Entry) error {
+			data, err := json.Marshal(e)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "encoding replication entry failed", "game_id", e.GameID, "error", err)
+				return nil
+			}
+			return sw.Send(sse.Event{Name: name, Data: data})
+		}
+		for _, e := range entries {
+			if err := send(replica.EventSync, e); err != nil {
+				return
+			}
+		}
+		if err := sw.Send(sse.Event{Name: replica.EventSynced, Data: []byte("{}")}); err != nil {
+			return
+		}
+		for {
+			select {
+			case e, ok := <-changes:
+				if !ok {
+					sw.Send(sse.Event{Name: replica.EventCutOff, Data: []byte(`{"reason":"too slow"}`)})
+					return
+				}
+				if err := send(replica.EventChange, e); err != nil {
+					return
+				}
+			case <-h.hub.Draining():
+				sw.Send(broadcast.Restart())
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// handleGame returns the latest entry of one game, for a follower that
+// found it diverged.
+func (h *ReplicationHandler) handleGame(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	e, ok := h.feed.Latest(gameID)
+	if !ok {
+		respondErr(w, r, game.ErrGameNotFound)
+		return
+	}
+	respondJSON(w, e)
+}
+
+// handlePromote makes a standby stop following its primary and take its
+// place, answering with how far it had got, see replica.Status. Point
+// clients at it once it has.
+func (h *ReplicationHandler) handlePromote(admin *AdminHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.follower == nil {
+			respondErr(w, r, replica.ErrNotFollowing)
+			return
+		}
+		status, err := h.follower.Promote(r.Context())
+		if err != nil {
+			respondErr(w, r, err)
+			return
+		}
+		admin.record(r.Context(), audit.ActionAdminPromote, "")
+		respondJSON(w, status)
+	}
+}
+
+// ReadOnlyMiddleware turns away requests that would change something
+// while games is read-only, as on a standby, with a 503 and the code
+// read_only, rather than letting them get as far as the game service.
+// Promoting the standby is let through.
+func ReadOnlyMiddleware(games *game.Service, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !games.ReadOnly(),
+			r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions,
+			r.URL.Path == "/api/admin/promote":
+			next.ServeHTTP(w, r)
+		case strings.HasPrefix(r.URL.Path, "/api/"), respond.WantsJSON(r):
+			respondErr(w, r, game.ErrReadOnly)
+		default:
+			http.Error(w, game.ErrReadOnly.Error(), errcode.FromError(game.ErrReadOnly).Status)
+		}
+	})
+}
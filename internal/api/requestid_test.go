@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/api"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/respond"
+)
+
+// syncBuffer is a buffer the server's goroutines can log into while the
+// test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var logs syncBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewTextHandler(&logs, nil))))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	url := serve(t)
+
+	tests := []struct {
+		name string
+		sent string
+		kept bool
+	}{
+		{"sent by the client", "support-ticket-42", true},
+		{"not sent", "", false},
+		{"too long", strings.Repeat("a", 200), false},
+		{"with a space", "two words", false},
+	}
+	for _, tt := range tests {
+		var body respond.ErrorBody
+		var headers []string
+		if tt.sent != "" {
+			headers = []string{api.RequestIDHeader, tt.sent}
+		}
+		res := call(t, http.MethodGet, url+"/api/game/missing", "", &body, headers...)
+		id := res.Header.Get(api.RequestIDHeader)
+		if tt.kept && id != tt.sent {
+			t.Errorf("%s: answered with request ID %q, want %q", tt.name, id, tt.sent)
+		}
+		if !tt.kept && !logging.ValidID(id) {
+			t.Errorf("%s: answered with request ID %q, want a fresh one", tt.name, id)
+		}
+		if body.RequestID != id {
+			t.Errorf("%s: the error body has request ID %q, the header %q", tt.name, body.RequestID, id)
+		}
+		// The request is logged once answered, so may not be yet
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(logs.String(), "request_id="+id) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !strings.Contains(logs.String(), "request_id="+id) {
+			t.Errorf("%s: request %q wasn't logged with its ID", tt.name, id)
+		}
+	}
+}
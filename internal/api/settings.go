@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+)
+
+// settingsRequest changes a game's settings for Player, who must have
+// created it and show their seat token. Fields left out keep their value; a null handicap removes
+// it, as an empty start position does.
+type settingsRequest struct {
+	Player        models.Player   `json:"player"`
+	Mode          *models.Mode    `json:"mode"`
+	AnalysisLive  *bool           `json:"analysisLive"`
+	Handicap      json.RawMessage `json:"handicap"`
+	ReadyCheck    *bool           `json:"readyCheck"`
+	EarlyDraw     *bool           `json:"earlyDraw"`
+	StartPosition *string         `json:"startPosition"`
+	StartTurn     *models.Player  `json:"startTurn"`
+	Exhibition    *bool           `json:"exhibition"`
+	Locale        *string         `json:"locale"`
+}
+
+func (h *Handler) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	settings, err := h.gameService.Settings(r.Context(), gameID)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	respondJSON(w, settings)
+}
+
+// handleUpdateSettings applies a settingsRequest over the game's current
+// settings. Without a seat token for the player it fails with a 403 and
+// the code seat_token, and once the opponent has joined or anyone has
+// moved with a 409 and the code settings_locked.
+func (h *Handler) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	var req settingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	err = h.checkSeat(r, gameID, req.Player)
+	var settings models.GameSettings
+	if err == nil {
+		settings, err = h.gameService.Settings(r.Context(), gameID)
+	}
+	if err == nil {
+		err = mergeSettings(&settings, req)
+	}
+	var g *models.GameState
+	if err == nil {
+		g, err = h.gameService.UpdateSettings(r.Context(), gameID, req.Player, settings)
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionSettings, Player: req.Player}, err)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	respondJSON(w, g.GameSettings)
+}
+
+// mergeSettings applies the fields given in req to settings.
+func mergeSettings(settings *models.GameSettings, req settingsRequest) error {
+	if req.Mode != nil {
+		settings.Mode = *req.Mode
+	}
+	if req.AnalysisLive != nil {
+		settings.AnalysisLive = *req.AnalysisLive
+	}
+	if req.ReadyCheck != nil {
+		settings.ReadyCheck = *req.ReadyCheck
+	}
+	if req.EarlyDraw != nil {
+		settings.EarlyDraw = *req.EarlyDraw
+	}
+	if req.StartPosition != nil {
+		settings.StartPosition = *req.StartPosition
+		// A new position has its own side to move unless one is given
+		settings.StartTurn = models.Empty
+	}
+	if req.StartTurn != nil {
+		settings.StartTurn = *req.StartTurn
+	}
+	if req.Exhibition != nil {
+		settings.Exhibition = *req.Exhibition
+	}
+	if req.Locale != nil {
+		settings.Locale = *req.Locale
+	}
+	if req.Handicap != nil {
+		var handicap *handicapRequest
+		if err := json.Unmarshal(req.Handicap, &handicap); err != nil {
+			return game.ErrInvalidHandicap
+		}
+		settings.Handicap = handicap.handicap()
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestUpdateSettingsNeedsTheCreatorsSeatToken(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	token := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+	var other models.GameState
+	call(t, "POST", url+"/api/game", "", &other)
+	otherToken := call(t, "POST", url+"/api/game/"+other.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+
+	settings := url + "/api/game/" + g.ID + "/settings"
+	tests := []struct {
+		name  string
+		body  string
+		token string
+		want  int
+	}{
+		{"no token", `{"player": "X", "earlyDraw": true}`, "", http.StatusForbidden},
+		{"claiming the other side", `{"player": "O", "earlyDraw": true}`, token, http.StatusForbidden},
+		{"another game's token", `{"player": "X", "earlyDraw": true}`, otherToken, http.StatusForbidden},
+		{"creator", `{"player": "X", "earlyDraw": true}`, token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		var body struct{ Code string }
+		res := call(t, "PATCH", settings, tt.body, &body, seat.TokenHeader, tt.token)
+		if res.StatusCode != tt.want {
+			t.Errorf("%s: status = %d (%s), want %d", tt.name, res.StatusCode, body.Code, tt.want)
+		}
+		if tt.want == http.StatusForbidden && body.Code != "seat_token" {
+			t.Errorf("%s: code = %q, want seat_token", tt.name, body.Code)
+		}
+	}
+
+	var got models.GameSettings
+	call(t, "GET", settings, "", &got)
+	if !got.EarlyDraw {
+		t.Error("the creator's change wasn't kept")
+	}
+}
+
+func TestCreateFromStartPosition(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	if res := call(t, "POST", url+"/api/game", `{"mode": "hotseat", "startPosition": "X../.O./..X"}`, &g); res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", res.StatusCode)
+	}
+	if g.StartPosition != "X../.O./..X" || g.StartTurn != models.PlayerO || g.CurrentTurn != models.PlayerO || len(g.History) != 3 || !g.History[0].Setup {
+		t.Errorf("created %+v", g)
+	}
+
+	for _, body := range []string{
+		`{"startPosition": "XXX/OO./..."}`,
+		`{"startPosition": "X../.../...", "startTurn": "X"}`,
+		`{"startTurn": "O"}`,
+	} {
+		var res struct{ Code string }
+		if status := call(t, "POST", url+"/api/game", body, &res).StatusCode; status != http.StatusBadRequest || res.Code != "invalid_start" {
+			t.Errorf("%s: %d %q, want 400 invalid_start", body, status, res.Code)
+		}
+	}
+}
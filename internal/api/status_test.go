@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// statusz fetches /statusz as generic JSON, so the test sees the names
+// and types clients see rather than what status.Report decodes to.
+func statusz(t *testing.T, url string) map[string]any {
+	t.Helper()
+	var report map[string]any
+	if res := call(t, "GET", url+"/statusz", "", &report, "Authorization", "Bearer "+adminKey); res.StatusCode != http.StatusOK {
+		t.Fatalf("GET /statusz: status = %d", res.StatusCode)
+	}
+	return report
+}
+
+// field returns the value at the dotted path in report, failing the
+// test if it is missing or not of the type wanted.
+func field[T any](t *testing.T, report map[string]any, path string) T {
+	t.Helper()
+	var v any = report
+	for name := range strings.SplitSeq(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("%s: %T isn't an object", path, v)
+		}
+		if v, ok = obj[name]; !ok {
+			t.Fatalf("%s: no %q in %v", path, name, obj)
+		}
+	}
+	typed, ok := v.(T)
+	if !ok {
+		t.Fatalf("%s is %T %v, want %T", path, v, v, *new(T))
+	}
+	return typed
+}
+
+func TestStatusz(t *testing.T) {
+	url := serve(t, game.WithMaxGames(2))
+
+	before := statusz(t, url)
+	if started := field[string](t, before, "startedAt"); started == "" {
+		t.Error("no startedAt")
+	} else if _, err := time.Parse(time.RFC3339, started); err != nil {
+		t.Errorf("startedAt: %v", err)
+	}
+	if uptime := field[float64](t, before, "uptime"); uptime < 0 {
+		t.Errorf("uptime %v", uptime)
+	}
+	if v := field[string](t, before, "goVersion"); !strings.HasPrefix(v, "go") {
+		t.Errorf("goVersion %q", v)
+	}
+	field[map[string]any](t, before, "build")
+	if !field[bool](t, before, "games.storage.ok") {
+		t.Error("storage not ok")
+	}
+	field[float64](t, before, "games.storage.latencyNs")
+	field[map[string]any](t, before, "hub.subscribers")
+	counters := []string{
+		"games.games." + game.StatusWaiting,
+		"games.games." + game.StatusPlaying,
+		"games.games." + game.StatusFinished,
+		"games.expiry.expired",
+		"hub.broadcasts", "hub.events", "hub.dropped",
+		"hub.firehoseDisconnects", "hub.policyDisconnects", "hub.games",
+	}
+	for _, c := range counters {
+		if n := field[float64](t, before, c); n != 0 {
+			t.Errorf("%s = %v on a new server", c, n)
+		}
+	}
+
+	// A game played to the end, then two left waiting and a third that
+	// expires the oldest of them to make room
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil)
+	call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil)
+	for i, pos := range []int{0, 3, 1, 4, 2} {
+		move := fmt.Sprintf(`{"player": %q, "position": %d}`, []string{"X", "O"}[i%2], pos)
+		if res := call(t, "POST", url+"/api/game/"+g.ID, move, nil); res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status = %d", move, res.StatusCode)
+		}
+	}
+	for range 3 {
+		call(t, "POST", url+"/api/game", "", nil)
+	}
+
+	after := statusz(t, url)
+	want := map[string]float64{
+		"games.games." + game.StatusWaiting:  2,
+		"games.games." + game.StatusPlaying:  0,
+		"games.games." + game.StatusFinished: 1,
+		"games.expiry.expired":               1,
+	}
+	for c, n := range want {
+		if got := field[float64](t, after, c); got != n {
+			t.Errorf("%s = %v after playing, want %v", c, got, n)
+		}
+	}
+	for _, c := range []string{"hub.broadcasts", "hub.events"} {
+		if field[float64](t, after, c) == 0 {
+			t.Errorf("%s didn't move after playing", c)
+		}
+	}
+	field[string](t, after, "games.expiry.lastRun")
+
+	// The same numbers as a table for people
+	req, err := http.NewRequest("GET", url+"/htmx/statusz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+adminKey)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	page, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{"<th>games finished</th><td>1</td>", "<th>expired games</th><td>1</td>", "<th>storage</th><td>ok</td>"} {
+		if !strings.Contains(string(page), row) {
+			t.Errorf("/htmx/statusz has no %s:\n%s", row, page)
+		}
+	}
+
+	for _, path := range []string{"/statusz", "/htmx/statusz"} {
+		if res := call(t, "GET", url+path, "", nil); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s without the admin key: status = %d", path, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// TimeHandler tells clients the server's time, so they can work out how
+// far their own clock is off and read deadlines such as a game's
+// startsAt by the server's clock rather than theirs.
+type TimeHandler struct {
+	clock func() time.Time
+}
+
+// NewTimeHandler creates a new time handler.
+func NewTimeHandler() *TimeHandler {
+	return &TimeHandler{clock: time.Now}
+}
+
+// RegisterRoutes sets up the time route.
+func (h *TimeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/time", h.handleTime)
+}
+
+// timeResponse is the server's time when it answered.
+type timeResponse struct {
+	ServerTime time.Time `json:"serverTime"`
+}
+
+// handleTime answers with the time and nothing else, so that half the
+// round trip is a fair estimate of how old it is on arrival. Clients
+// syncing their clock take the reply with the shortest round trip of a
+// few.
+func (h *TimeHandler) handleTime(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondJSON(w, timeResponse{ServerTime: h.clock().UTC()})
+}
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	for name, url := range map[string]string{"open": serve(t), "keys required": serveKeys(t)} {
+		var got struct {
+			ServerTime time.Time `json:"serverTime"`
+		}
+		before := time.Now()
+		res := call(t, "GET", url+"/api/time", "", &got)
+		after := time.Now()
+		if res.StatusCode != http.StatusOK || res.Header.Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: status = %d, Cache-Control %q", name, res.StatusCode, res.Header.Get("Cache-Control"))
+		}
+		if got.ServerTime.Before(before) || got.ServerTime.After(after) || got.ServerTime.Location() != time.UTC {
+			t.Errorf("%s: serverTime %v, want UTC between %v and %v", name, got.ServerTime, before, after)
+		}
+	}
+}
+
+// TestTimeOffset works out a clock's offset from GET /api/time, as a
+// client syncing its clock would, against a server whose clock is ahead
+// by skew.
+func TestTimeOffset(t *testing.T) {
+	const skew = 90 * time.Second
+	url := serve(t)
+	skewed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got struct {
+			ServerTime time.Time `json:"serverTime"`
+		}
+		call(t, "GET", url+"/api/time", "", &got)
+		got.ServerTime = got.ServerTime.Add(skew)
+		json.NewEncoder(w).Encode(got)
+	}))
+	defer skewed.Close()
+
+	// Of a few tries, the one with the shortest round trip tells best
+	best, offset := time.Duration(1<<63-1), time.Duration(0)
+	for range 5 {
+		var got struct {
+			ServerTime time.Time `json:"serverTime"`
+		}
+		sent := time.Now()
+		call(t, "GET", skewed.URL, "", &got)
+		received := time.Now()
+		if rtt := received.Sub(sent); rtt < best {
+			best, offset = rtt, got.ServerTime.Add(rtt/2).Sub(received)
+		}
+	}
+	if d := (offset - skew).Abs(); d > 100*time.Millisecond {
+		t.Errorf("offset %v, want %v within 100ms", offset, skew)
+	}
+}
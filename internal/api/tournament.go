@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tiktaktoes/internal/tournament"
+)
+
+// TournamentHandler serves tournaments over the REST API.
+type TournamentHandler struct {
+	tournaments *tournament.Service
+}
+
+// NewTournamentHandler creates a new tournament handler.
+func NewTournamentHandler(tournaments *tournament.Service) *TournamentHandler {
+	return &TournamentHandler{tournaments: tournaments}
+}
+
+// RegisterRoutes sets up the tournament routes.
+func (h *TournamentHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/tournaments", h.handleCreate)
+	mux.HandleFunc("GET /api/tournaments/{id}", h.handleGet)
+}
+
+// createTournamentRequest lists the participants in seeding order. The
+// format defaults to single elimination.
+type createTournamentRequest struct {
+	Participants []string          `json:"participants"`
+	Format       tournament.Format `json:"format"`
+}
+
+func (h *TournamentHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	t, err := h.tournaments.Create(r.Context(), req.Participants, req.Format)
+	if err != nil {
+		respondErr(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *TournamentHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.tournaments.Get(r.PathValue("id"))
+	if !ok {
+		respondErr(w, r, tournament.ErrNotFound)
+		return
+	}
+	respondJSON(w, t)
+}
@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestUndoResetIsForWhoeverReset(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	x := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+	o := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil).Header.Get(seat.TokenHeader)
+	game := url + "/api/game/" + g.ID
+	call(t, "POST", game, `{"player": "X", "position": 4}`, nil)
+
+	if res := call(t, "PUT", game, `{"player": "X"}`, nil, seat.TokenHeader, o); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("resetting as X with O's token: status = %d, want 403", res.StatusCode)
+	}
+	if res := call(t, "PUT", game, `{"player": "X"}`, nil, seat.TokenHeader, x); res.StatusCode != http.StatusOK {
+		t.Fatalf("resetting: status = %d", res.StatusCode)
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		token string
+		code  string
+	}{
+		{"no player", "", x, "seat_token"},
+		{"no token", `{"player": "X"}`, "", "seat_token"},
+		{"claiming X with O's token", `{"player": "X"}`, o, "seat_token"},
+		{"the other player", `{"player": "O"}`, o, "not_resetter"},
+	}
+	for _, tt := range tests {
+		var body struct{ Code string }
+		res := call(t, "POST", game+"/undo-reset", tt.body, &body, seat.TokenHeader, tt.token)
+		if res.StatusCode != http.StatusForbidden || body.Code != tt.code {
+			t.Errorf("%s: %d %s, want 403 %s", tt.name, res.StatusCode, body.Code, tt.code)
+		}
+	}
+
+	var undone models.GameState
+	if res := call(t, "POST", game+"/undo-reset", `{"player": "X"}`, &undone, seat.TokenHeader, x); res.StatusCode != http.StatusOK {
+		t.Fatalf("undoing: status = %d", res.StatusCode)
+	}
+	if undone.Board[4] != models.PlayerX {
+		t.Error("undoing didn't put the board back")
+	}
+}
+
+func TestAnonymousResetCantBeUndone(t *testing.T) {
+	url := serve(t)
+	var g models.GameState
+	token := call(t, "POST", url+"/api/game", `{"mode": "hotseat"}`, &g).Header.Get(seat.TokenHeader)
+	game := url + "/api/game/" + g.ID
+	call(t, "POST", game, `{"player": "X", "position": 4}`, nil)
+	call(t, "PUT", game, "", nil)
+
+	var body struct{ Code string }
+	call(t, "POST", game+"/undo-reset", `{"player": "X"}`, &body, seat.TokenHeader, token)
+	if body.Code != "nothing_to_undo" {
+		t.Errorf("code = %q, want nothing_to_undo", body.Code)
+	}
+}
@@ -0,0 +1,53 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestVacateSlotNeedsTheOtherSeat(t *testing.T) {
+	clock := gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	url := serve(t, game.WithClock(clock))
+	var g models.GameState
+	call(t, "POST", url+"/api/game", "", &g)
+	x := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "X"}`, nil).Header.Get(seat.TokenHeader)
+	o := call(t, "POST", url+"/api/game/"+g.ID+"/join", `{"player": "O"}`, nil).Header.Get(seat.TokenHeader)
+	vacate := url + "/api/game/" + g.ID + "/vacate"
+
+	var body struct{ Code string }
+	if res := call(t, "POST", vacate, `{"player": "O"}`, &body, seat.TokenHeader, x); res.StatusCode != http.StatusConflict || body.Code != "vacate_too_early" {
+		t.Fatalf("vacating O straight away: %d %s, want 409 vacate_too_early", res.StatusCode, body.Code)
+	}
+	clock.Advance(game.DefaultVacateAfter)
+
+	tests := []struct {
+		name  string
+		body  string
+		token string
+		code  string
+	}{
+		{"no token", `{"player": "O"}`, "", "seat_token"},
+		{"O's own token", `{"player": "O"}`, o, "seat_token"},
+		{"a spectator", `{"player": ""}`, x, "invalid_player"},
+	}
+	for _, tt := range tests {
+		res := call(t, "POST", vacate, tt.body, &body, seat.TokenHeader, tt.token)
+		if res.StatusCode < 400 || body.Code != tt.code {
+			t.Errorf("%s: %d %s, want %s", tt.name, res.StatusCode, body.Code, tt.code)
+		}
+	}
+
+	var vacated models.GameState
+	if res := call(t, "POST", vacate, `{"player": "O"}`, &vacated, seat.TokenHeader, x); res.StatusCode != http.StatusOK {
+		t.Fatalf("vacating with X's token: status = %d", res.StatusCode)
+	}
+	if vacated.PlayerOJoined {
+		t.Error("O is still joined")
+	}
+}
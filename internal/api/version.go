@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/version"
+)
+
+// VersionHeader carries the server's version, see version.Info, on every
+// API response.
+const VersionHeader = "X-Server-Version"
+
+// VersionHandler tells clients which server they talk to and what it
+// supports, so they can turn optional behaviour on only where it exists.
+type VersionHandler struct {
+	features []string
+}
+
+// NewVersionHandler creates a new version handler advertising features,
+// the names of what this server offers beyond the core API.
+func NewVersionHandler(features []string) *VersionHandler {
+	return &VersionHandler{features: features}
+}
+
+// RegisterRoutes sets up the version route.
+func (h *VersionHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/version", h.handleVersion)
+}
+
+// versionResponse is the running build along with the API version and
+// features it offers.
+type versionResponse struct {
+	version.Info
+	APIVersion int      `json:"apiVersion"`
+	Features   []string `json:"features"`
+}
+
+func (h *VersionHandler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, versionResponse{
+		Info:       version.Get(),
+		APIVersion: version.APIVersion,
+		Features:   h.features,
+	})
+}
+
+// VersionMiddleware sets VersionHeader on responses to API requests.
+func VersionMiddleware(next http.Handler) http.Handler {
+	v := version.Get().Version
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			w.Header().Set(VersionHeader, v)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/api"
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/tournament"
+	"tiktaktoes/internal/version"
+)
+
+// TestVersionFeatures serves the mux with each optional part turned on
+// or off, and checks GET /api/version advertises just what is there.
+func TestVersionFeatures(t *testing.T) {
+	games := game.NewService()
+	defer games.Close()
+	hub := broadcast.NewHub()
+	keys, err := apikey.NewKeyring(context.Background(), apikey.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		deps server.Deps
+		on   []string
+		off  []string
+	}{
+		{
+			name: "bare",
+			off:  []string{"admin", "api-keys", "metrics", "compression", "activity", "tournaments", "puzzles", "featured", "commentary", "replication"},
+		},
+		{
+			name: "admin",
+			deps: server.Deps{AdminKey: adminKey},
+			on:   []string{"admin"},
+			off:  []string{"api-keys"},
+		},
+		{
+			name: "api keys without admin",
+			deps: server.Deps{APIKeys: keys},
+			off:  []string{"admin", "api-keys"},
+		},
+		{
+			name: "metrics and compression",
+			deps: server.Deps{Metrics: true, Compress: true},
+			on:   []string{"metrics", "compression"},
+			off:  []string{"admin"},
+		},
+		{
+			name: "activity and tournaments",
+			deps: server.Deps{Activity: activity.NewLog(games), Tournaments: tournament.NewService(games, hub)},
+			on:   []string{"activity", "tournaments"},
+			off:  []string{"metrics"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.deps.Games, tt.deps.Hub = games, hub
+			srv := httptest.NewServer(server.NewMux(tt.deps))
+			defer srv.Close()
+
+			var got struct {
+				Version    string   `json:"version"`
+				APIVersion int      `json:"apiVersion"`
+				Features   []string `json:"features"`
+			}
+			res := call(t, "GET", srv.URL+"/api/version", "", &got)
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d", res.StatusCode)
+			}
+			if got.Version != version.Get().Version || got.APIVersion != version.APIVersion {
+				t.Errorf("version %q, API %d, want %q, %d", got.Version, got.APIVersion, version.Get().Version, version.APIVersion)
+			}
+			if !slices.IsSorted(got.Features) {
+				t.Errorf("features %v aren't sorted", got.Features)
+			}
+			// The core features, and those the case turns on
+			for _, f := range append([]string{"ai", "hotseat", "delta", "embed", "events", "server-time"}, tt.on...) {
+				if !slices.Contains(got.Features, f) {
+					t.Errorf("%q not in %v", f, got.Features)
+				}
+			}
+			for _, f := range tt.off {
+				if slices.Contains(got.Features, f) {
+					t.Errorf("%q in %v", f, got.Features)
+				}
+			}
+			if h := res.Header.Get(api.VersionHeader); h != got.Version {
+				t.Errorf("%s: %q, want %q", api.VersionHeader, h, got.Version)
+			}
+		})
+	}
+}
+
+func TestVersionHeader(t *testing.T) {
+	url := serve(t)
+	want := version.Get().Version
+	for _, path := range []string{"/api/version", "/api/game/nosuchgame", "/api/nosuchroute"} {
+		if got := call(t, "GET", url+path, "", nil).Header.Get(api.VersionHeader); got != want {
+			t.Errorf("%s: %s %q, want %q", path, api.VersionHeader, got, want)
+		}
+	}
+	if got := call(t, "GET", url+"/", "", nil).Header.Get(api.VersionHeader); got != "" {
+		t.Errorf("the home page has %s %q", api.VersionHeader, got)
+	}
+}
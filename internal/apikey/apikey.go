@@ -0,0 +1,318 @@
+// Package apikey manages the scoped, rate-limited keys for the REST API.
+// Only a key's SHA-256 hash is kept; it goes by its fingerprint.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scope is something a key is allowed to do.
+type Scope string
+
+// The scopes a key can be granted.
+const (
+	// ScopeGamesCreate allows creating games and tournaments.
+	ScopeGamesCreate Scope = "games:create"
+	// ScopeGamesPlay allows everything else that changes a game, such as
+	// joining it and moving.
+	ScopeGamesPlay Scope = "games:play"
+	// ScopeGamesRead allows reading games, tournaments and puzzles.
+	ScopeGamesRead Scope = "games:read"
+	// ScopeAdmin allows the admin API, as the admin key does.
+	ScopeAdmin Scope = "admin:*"
+)
+
+// Scopes lists every scope.
+var Scopes = []Scope{ScopeGamesCreate, ScopeGamesPlay, ScopeGamesRead, ScopeAdmin}
+
+// Default rate limit of a key created without one: bursts of up to
+// DefaultBurst requests, refilled at DefaultRate per second.
+const (
+	DefaultRate  = 5
+	DefaultBurst = 20
+)
+
+// tokenPrefix starts every token, so a leaked one is easy to recognize.
+const tokenPrefix = "ttk_"
+
+var (
+	ErrUnknownKey   = errors.New("unknown API key")
+	ErrScope        = errors.New("API key isn't allowed to do that")
+	ErrRateLimited  = errors.New("API key rate limit exceeded")
+	ErrKeyNotFound  = errors.New("API key not found")
+	ErrInvalidScope = errors.New("invalid scope")
+	ErrInvalidRate  = errors.New("rate and burst can't be negative")
+)
+
+// Key is an API key as stored: everything but the token itself.
+type Key struct {
+	// ID is the key's fingerprint, see Fingerprint.
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Hash is the hex SHA-256 of the token.
+	Hash   string  `json:"hash"`
+	Scopes []Scope `json:"scopes"`
+	// Rate is how many requests a second the key may make on average,
+	// in bursts of up to Burst.
+	Rate      float64   `json:"rate"`
+	Burst     int       `json:"burst"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Has reports whether the key was granted scope.
+func (k *Key) Has(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Actor names the key's holder in the audit log, by its fingerprint.
+func (k *Key) Actor() string {
+	return "key:" + k.ID
+}
+
+// Fingerprint identifies a token without giving it away: the first four
+// bytes of its SHA-256 in hex, as audit.KeyActor names the admin key.
+func Fingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:4])
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store is where keys are kept, such as alongside the games, see
+// storage.Keys.
+type Store interface {
+	// PutKey inserts or replaces a key.
+	PutKey(ctx context.Context, key *Key) error
+	// DeleteKey removes a key. Deleting an unknown key is not an error.
+	DeleteKey(ctx context.Context, id string) error
+	// ListKeys returns every stored key.
+	ListKeys(ctx context.Context) ([]*Key, error)
+}
+
+// MemoryStore keeps keys in a map, lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*Key
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]*Key)}
+}
+
+// PutKey stores a copy of key.
+func (s *MemoryStore) PutKey(_ context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := *key
+	s.keys[k.ID] = &k
+	return nil
+}
+
+// DeleteKey removes the key with the given ID.
+func (s *MemoryStore) DeleteKey(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, id)
+	return nil
+}
+
+// ListKeys returns copies of every key.
+func (s *MemoryStore) ListKeys(context.Context) ([]*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		k := *key
+		keys = append(keys, &k)
+	}
+	return keys, nil
+}
+
+// entry is a key in use, with what is left of its rate limit.
+type entry struct {
+	key   *Key
+	limit *bucket
+}
+
+// Keyring checks tokens against the keys in a store. It reads the store
+// once, when it is created, and writes every change through to it, so
+// a revoked key is turned away from the next request on. It is safe for
+// concurrent use.
+type Keyring struct {
+	store Store
+
+	mu sync.Mutex
+	// byHash and byID hold the same entries
+	byHash map[string]*entry
+	byID   map[string]*entry
+}
+
+// NewKeyring creates a keyring of the keys in store.
+func NewKeyring(ctx context.Context, store Store) (*Keyring, error) {
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading API keys: %w", err)
+	}
+	k := &Keyring{
+		store:  store,
+		byHash: make(map[string]*entry, len(keys)),
+		byID:   make(map[string]*entry, len(keys)),
+	}
+	now := time.Now()
+	for _, key := range keys {
+		k.add(key, now)
+	}
+	return k, nil
+}
+
+// add puts key on the ring with a full rate limit. Must be called with
+// mu held, or before the ring is shared.
+func (k *Keyring) add(key *Key, now time.Time) {
+	e := &entry{key: key, limit: newBucket(key.Rate, float64(key.Burst), now)}
+	k.byHash[key.Hash] = e
+	k.byID[key.ID] = e
+}
+
+// Create makes a key with scopes, named name, limited to rate requests a
+// second in bursts of burst, zero for DefaultRate and DefaultBurst. It
+// returns the key and its token, which can't be had again.
+func (k *Keyring) Create(ctx context.Context, name string, scopes []Scope, rate float64, burst int) (*Key, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: none given", ErrInvalidScope)
+	}
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, s) {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+	}
+	if rate < 0 || burst < 0 {
+		return nil, "", ErrInvalidRate
+	}
+	if rate == 0 {
+		rate = DefaultRate
+	}
+	if burst == 0 {
+		burst = DefaultBurst
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var token string
+	for {
+		b := make([]byte, 32)
+		rand.Read(b)
+		token = tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+		// The fingerprint names the key, so it must be unique
+		if _, taken := k.byID[Fingerprint(token)]; !taken {
+			break
+		}
+	}
+	key := &Key{
+		ID:        Fingerprint(token),
+		Name:      name,
+		Hash:      hash(token),
+		Scopes:    slices.Compact(scopes),
+		Rate:      rate,
+		Burst:     burst,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := k.store.PutKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	k.add(key, time.Now())
+	return key, token, nil
+}
+
+// Revoke deletes the key with the given ID. It returns ErrKeyNotFound if
+// there is none.
+func (k *Keyring) Revoke(ctx context.Context, id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	e, ok := k.byID[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if err := k.store.DeleteKey(ctx, id); err != nil {
+		return err
+	}
+	delete(k.byID, id)
+	delete(k.byHash, e.key.Hash)
+	return nil
+}
+
+// List returns every key, oldest first.
+func (k *Keyring) List() []Key {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := make([]Key, 0, len(k.byID))
+	for _, e := range k.byID {
+		keys = append(keys, *e.key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// Check finds the key of token and takes a request from its rate limit
+// if it has scope. It returns the key, as long as there is one, along
+// with ErrUnknownKey, ErrScope or ErrRateLimited if the request isn't
+// allowed.
+func (k *Keyring) Check(token string, scope Scope) (Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	e, ok := k.byHash[hash(token)]
+	if !ok {
+		return Key{}, ErrUnknownKey
+	}
+	if !e.key.Has(scope) {
+		return *e.key, ErrScope
+	}
+	if !e.limit.allow(time.Now()) {
+		return *e.key, ErrRateLimited
+	}
+	return *e.key, nil
+}
+
+// bucket is a token bucket holding up to burst tokens, refilled at rate
+// per second.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64, now time.Time) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// allow takes a token if one is available at now.
+func (b *bucket) allow(now time.Time) bool {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
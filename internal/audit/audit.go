@@ -0,0 +1,354 @@
+// Package audit appends administrative and destructive actions to a
+// rotated JSONL file. Recording is best-effort: failures are only logged.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/clientip"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/models"
+)
+
+const (
+	defaultMaxSize  = 8 << 20
+	defaultMaxFiles = 5
+)
+
+// Actions recorded by the admin API. The game service records its own
+// actions under its journal event names, such as "cancel".
+const (
+	ActionAdminDenied = "admin.denied"
+	ActionAdminEvents = "admin.events"
+	ActionAdminExport = "admin.export"
+	// ActionAdminActivity records an admin reading a game's activity,
+	// see package activity.
+	ActionAdminActivity = "admin.activity"
+	// ActionAdminKeyCreate and ActionAdminKeyRevoke record API keys being
+	// created and revoked, see package apikey.
+	ActionAdminKeyCreate = "admin.key-create"
+	ActionAdminKeyRevoke = "admin.key-revoke"
+	// ActionAdminPin and ActionAdminUnpin record featured games being
+	// pinned and unpinned, see package featured.
+	ActionAdminPin   = "admin.pin"
+	ActionAdminUnpin = "admin.unpin"
+	// ActionAdminReplicate records a standby starting to follow the
+	// server, and ActionAdminPromote one taking over from its primary,
+	// see package replica.
+	ActionAdminReplicate = "admin.replicate"
+	ActionAdminPromote   = "admin.promote"
+)
+
+// Entry is one audited action. GameID and Player name what it was done
+// to, if anything.
+type Entry struct {
+	Seq       uint64        `json:"seq"`
+	Time      time.Time     `json:"time"`
+	Actor     string        `json:"actor"`
+	Action    string        `json:"action"`
+	GameID    string        `json:"gameId,omitempty"`
+	Player    models.Player `json:"player,omitempty"`
+	RequestID string        `json:"requestId,omitempty"`
+	ClientIP  string        `json:"clientIp,omitempty"`
+}
+
+// Options configures a Log.
+type Options struct {
+	// Path is the active log file. Its directory is created if missing.
+	Path string
+	// MaxSize is the size in bytes after which the file is rotated.
+	// Defaults to 8 MiB.
+	MaxSize int64
+	// MaxFiles is how many rotated files are kept. Defaults to 5.
+	MaxFiles int
+	// MaxAge is how long the active file takes entries before it is
+	// rotated, counted from its first entry. Zero rotates by size alone.
+	MaxAge time.Duration
+	// Clock timestamps entries. Defaults to the wall clock.
+	Clock Clock
+}
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock reads the wall clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Log appends entries to a rotated JSONL file. It is safe for
+// concurrent use.
+type Log struct {
+	opts Options
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	started time.Time // of the active file's first entry
+	lastSeq uint64
+}
+
+// Open opens the log at opts.Path, continuing after the last entry
+// already on disk.
+func Open(opts Options) (*Log, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSize
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	l := &Log{opts: opts}
+	err := l.scan(func(e Entry) bool {
+		l.lastSeq = e.Seq
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record appends an entry for action, filling in the actor, request ID
+// and client address from ctx. Errors are logged.
+func (l *Log) Record(ctx context.Context, action, gameID string, player models.Player) {
+	entry := Entry{
+		Time:      l.opts.Clock.Now(),
+		Actor:     actorFrom(ctx),
+		Action:    action,
+		GameID:    gameID,
+		Player:    player,
+		RequestID: logging.RequestID(ctx),
+	}
+	if ip := clientip.From(ctx); ip.IsValid() {
+		entry.ClientIP = ip.String()
+	}
+	if err := l.append(entry); err != nil {
+		slog.ErrorContext(ctx, "writing audit entry failed", "action", action, "game_id", gameID, "error", err)
+	}
+}
+
+func (l *Log) append(entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+	entry.Seq = l.lastSeq + 1
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if l.size > 0 && (l.size+int64(len(line)) > l.opts.MaxSize || l.expired(entry.Time)) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if l.started.IsZero() {
+		l.started = entry.Time
+	}
+	l.lastSeq = entry.Seq
+	return nil
+}
+
+// Page is a slice of the log, see List.
+type Page struct {
+	Entries []Entry `json:"entries"`
+	// Next is the cursor for the following page, or 0 on the last one.
+	Next uint64 `json:"next,omitempty"`
+}
+
+// List returns up to limit entries, oldest first, that come after the
+// cursor after (an entry's Seq, 0 for the start) and were recorded at or
+// after since. Entries rotated out of the kept files are gone.
+func (l *Log) List(since time.Time, after uint64, limit int) (Page, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	page := Page{Entries: []Entry{}}
+	err := l.scan(func(e Entry) bool {
+		if e.Seq <= after || e.Time.Before(since) {
+			return true
+		}
+		if len(page.Entries) == limit {
+			page.Next = page.Entries[limit-1].Seq
+			return false
+		}
+		page.Entries = append(page.Entries, e)
+		return true
+	})
+	return page, err
+}
+
+// Close closes the log. Later entries are dropped with a logged error.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := errors.Join(l.file.Sync(), l.file.Close())
+	l.file = nil
+	return err
+}
+
+// expired reports whether the active file has taken entries for MaxAge
+// by now. Must be called with the lock held.
+func (l *Log) expired(now time.Time) bool {
+	return l.opts.MaxAge > 0 && !l.started.IsZero() && now.Sub(l.started) >= l.opts.MaxAge
+}
+
+// openFile opens the active file for appending, noting when its first
+// entry was written. Must be called with the lock held, or before the
+// log is shared.
+func (l *Log) openFile() error {
+	file, err := os.OpenFile(l.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	var started time.Time
+	if _, err := scanFile(l.opts.Path, func(e Entry) bool {
+		started = e.Time
+		return false
+	}); err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	l.started = started
+	return nil
+}
+
+// rotate shifts the rotated files up by one, dropping the oldest, moves
+// the active file to "<path>.1" and starts a new one. Must be called with
+// the lock held.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	if err := os.Remove(l.rotated(l.opts.MaxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := l.opts.MaxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(l.rotated(i), l.rotated(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(l.opts.Path, l.rotated(1)); err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+func (l *Log) rotated(n int) string {
+	return fmt.Sprintf("%s.%d", l.opts.Path, n)
+}
+
+// scan calls fn for every entry on disk, oldest first, until it returns
+// false. Unreadable lines, such as one cut short by a crash, are skipped.
+func (l *Log) scan(fn func(Entry) bool) error {
+	paths := make([]string, 0, l.opts.MaxFiles+1)
+	for i := l.opts.MaxFiles; i >= 1; i-- {
+		paths = append(paths, l.rotated(i))
+	}
+	paths = append(paths, l.opts.Path)
+
+	for _, path := range paths {
+		more, err := scanFile(path, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+func scanFile(path string, fn func(Entry) bool) (bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn("skipping unreadable audit entry", "path", path, "error", err)
+			continue
+		}
+		if !fn(entry) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
+
+type actorKey struct{}
+
+// WithActor returns a context naming who is acting, such as an admin
+// key fingerprint from KeyActor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// KeyActor names the holder of an admin key by a fingerprint of it, so
+// the key itself never reaches the log.
+func KeyActor(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "admin:" + hex.EncodeToString(sum[:4])
+}
+
+// actorFrom returns the actor stored in ctx. Players have no accounts
+// or tokens to go by, so without one the actor is anonymous and only the
+// client address tells them apart.
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok {
+		return actor
+	}
+	return "anonymous"
+}
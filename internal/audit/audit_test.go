@@ -0,0 +1,202 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/game/gametest"
+)
+
+var start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// open opens a log in a temporary directory, closed when the test ends.
+func open(t *testing.T, opts audit.Options) *audit.Log {
+	t.Helper()
+	if opts.Path == "" {
+		opts.Path = filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	}
+	l, err := audit.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// lines returns how many entries each of the files at path, path.1,
+// path.2 and so on up to path.n holds, -1 for a missing one.
+func lines(t *testing.T, path string, n int) []int {
+	t.Helper()
+	counts := make([]int, n+1)
+	for i := range counts {
+		name := path
+		if i > 0 {
+			name = path + "." + strconv.Itoa(i)
+		}
+		b, err := os.ReadFile(name)
+		if os.IsNotExist(err) {
+			counts[i] = -1
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[i] = strings.Count(string(b), "\n")
+	}
+	return counts
+}
+
+// seqs returns the Seq of every entry still kept.
+func seqs(t *testing.T, l *audit.Log) []uint64 {
+	t.Helper()
+	page, err := l.List(time.Time{}, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for _, e := range page.Entries {
+		got = append(got, e.Seq)
+	}
+	return got
+}
+
+func TestRecord(t *testing.T) {
+	clock := gametest.NewFakeClock(start)
+	l := open(t, audit.Options{Clock: clock})
+	ctx := audit.WithActor(context.Background(), audit.KeyActor("secret"))
+	l.Record(ctx, audit.ActionAdminExport, "", "")
+	clock.Advance(time.Minute)
+	l.Record(context.Background(), "cancel", "abc", "X")
+
+	page, err := l.List(time.Time{}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entries) != 2 || page.Next != 0 {
+		t.Fatalf("listed %+v", page)
+	}
+	first, second := page.Entries[0], page.Entries[1]
+	if first.Seq != 1 || !first.Time.Equal(start) || first.Action != audit.ActionAdminExport || !strings.HasPrefix(first.Actor, "admin:") || strings.Contains(first.Actor, "secret") {
+		t.Errorf("first entry %+v", first)
+	}
+	if second.Seq != 2 || !second.Time.Equal(start.Add(time.Minute)) || second.Actor != "anonymous" || second.GameID != "abc" || second.Player != "X" {
+		t.Errorf("second entry %+v", second)
+	}
+
+	// Pages follow on from the cursor, and since leaves older entries out
+	if page, _ := l.List(time.Time{}, 0, 1); len(page.Entries) != 1 || page.Next != 1 {
+		t.Errorf("first page %+v", page)
+	}
+	if page, _ := l.List(time.Time{}, 1, 1); len(page.Entries) != 1 || page.Entries[0].Seq != 2 || page.Next != 0 {
+		t.Errorf("second page %+v", page)
+	}
+	if page, _ := l.List(start.Add(time.Second), 0, 10); len(page.Entries) != 1 || page.Entries[0].Seq != 2 {
+		t.Errorf("since a second in %+v", page)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	clock := gametest.NewFakeClock(start)
+	// Each entry is about 100 bytes, so the file takes two
+	l := open(t, audit.Options{Path: path, MaxSize: 250, MaxFiles: 2, Clock: clock})
+	for range 2 {
+		l.Record(context.Background(), "cancel", "abc", "X")
+	}
+	if got := lines(t, path, 2); got[0] != 2 || got[1] != -1 {
+		t.Fatalf("files hold %v entries before rotating", got)
+	}
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if got := lines(t, path, 2); got[0] != 1 || got[1] != 2 || got[2] != -1 {
+		t.Fatalf("files hold %v entries after rotating once", got)
+	}
+
+	// Past MaxFiles rotated files the oldest are dropped
+	for range 5 {
+		l.Record(context.Background(), "cancel", "abc", "X")
+	}
+	if got := lines(t, path, 3); got[0] != 2 || got[1] != 2 || got[2] != 2 || got[3] != -1 {
+		t.Errorf("files hold %v entries, want two each in three files", got)
+	}
+	if got := seqs(t, l); len(got) != 6 || got[0] != 3 || got[5] != 8 {
+		t.Errorf("kept entries %v, want 3 to 8", got)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	clock := gametest.NewFakeClock(start)
+	l := open(t, audit.Options{Path: path, MaxAge: time.Hour, MaxFiles: 3, Clock: clock})
+
+	// An empty file has no age, however long it waits
+	clock.Advance(2 * time.Hour)
+	l.Record(context.Background(), "cancel", "abc", "X")
+	clock.Advance(59 * time.Minute)
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if got := lines(t, path, 1); got[0] != 2 || got[1] != -1 {
+		t.Fatalf("files hold %v entries within the hour", got)
+	}
+
+	// The hour runs from the first entry, not the last
+	clock.Advance(time.Minute)
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if got := lines(t, path, 2); got[0] != 1 || got[1] != 2 || got[2] != -1 {
+		t.Fatalf("files hold %v entries an hour on", got)
+	}
+	clock.Advance(30 * time.Minute)
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if got := lines(t, path, 2); got[0] != 2 || got[1] != 2 {
+		t.Fatalf("files hold %v entries half an hour into the new file", got)
+	}
+	if got := seqs(t, l); len(got) != 4 || got[0] != 1 || got[3] != 4 {
+		t.Errorf("kept entries %v", got)
+	}
+}
+
+// TestRotateByAgeAfterReopening checks the age of the active file
+// survives a restart, so a server restarted more often than MaxAge
+// still rotates.
+func TestRotateByAgeAfterReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	clock := gametest.NewFakeClock(start)
+	opts := audit.Options{Path: path, MaxAge: time.Hour, Clock: clock}
+	l, err := audit.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Record(context.Background(), "cancel", "abc", "X")
+	l.Close()
+
+	clock.Advance(time.Hour)
+	l = open(t, opts)
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if got := lines(t, path, 1); got[0] != 1 || got[1] != 1 {
+		t.Errorf("files hold %v entries, want the first rotated out", got)
+	}
+	if got := seqs(t, l); len(got) != 2 || got[1] != 2 {
+		t.Errorf("kept entries %v, want the sequence carried on", got)
+	}
+}
+
+func TestRecordAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := open(t, audit.Options{Path: path})
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Dropped with a logged error, not a panic
+	l.Record(context.Background(), "cancel", "abc", "X")
+	if got := lines(t, path, 0); got[0] != 1 {
+		t.Errorf("file holds %d entries", got[0])
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// CloseReason is the close frame sent to a WebSocket client when the
+// server ends its connection, so clients can tell why they were dropped.
+type CloseReason struct {
+	Code int
+	Text string
+}
+
+// Close codes in the 4000 range are the application's own; the others
+// are the standard ones from RFC 6455.
+var (
+	ReasonShutdown    = CloseReason{websocket.CloseGoingAway, "server shutting down"}
+	ReasonPolicy      = CloseReason{websocket.ClosePolicyViolation, "too many messages"}
+	ReasonGameDeleted = CloseReason{4001, "game deleted"}
+	ReasonIdle        = CloseReason{4002, "idle timeout"}
+	ReasonGameExpired = CloseReason{4003, "game expired"}
+)
+
+// closeWait bounds writing a close frame and waiting for the client to
+// answer it.
+const closeWait = time.Second
+
+// Send starts the close handshake: it writes the close frame and gives
+// the client closeWait to answer, after which reads on conn fail. The
+// connection's read loop ends either way and closes it.
+func (r CloseReason) Send(conn *websocket.Conn) error {
+	deadline := time.Now().Add(closeWait)
+	err := conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(r.Code, r.Text), deadline)
+	conn.SetReadDeadline(deadline)
+	return err
+}
+
+// UnsubscribedFrameType is the type of the frame telling a WebSocket
+// client it no longer gets a game's updates, see Unsubscribed.
+const UnsubscribedFrameType = "unsubscribed"
+
+// Unsubscribed is the data of an unsubscribed frame. Reason is empty when
+// the client asked to unsubscribe, and otherwise that of the CloseReason
+// its other games' connection was spared.
+type Unsubscribed struct {
+	GameID string `json:"gameId"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Unsubscribe removes a WebSocket connection for a game, as UnregisterWS
+// does, and confirms it to the client with an unsubscribed frame.
+func (h *Hub) Unsubscribe(gameID string, conn *websocket.Conn) {
+	h.mu.Lock()
+	c, fns := h.unregisterWS(gameID, conn)
+	var send wsSend
+	if c != nil {
+		send = h.newSend(conn, c, unsubscribed(gameID, ""))
+	}
+	h.mu.Unlock()
+	if c != nil {
+		h.write([]wsSend{send})
+		notifyPresence(fns, gameID, c.sub.Player, false)
+	}
+}
+
+func unsubscribed(gameID, reason string) wsEvent {
+	return wsEvent{Type: UnsubscribedFrameType, GameID: gameID, Data: Unsubscribed{GameID: gameID, Reason: reason}}
+}
+
+// CloseGame closes the game's WebSocket clients, or unsubscribes those
+// subscribed to other games too, and drops its event log.
+func (h *Hub) CloseGame(gameID string, reason CloseReason) {
+	h.eventLogs.forget(gameID)
+	h.enqueue(gameID, job{closing: &reason, ctx: context.Background()})
+}
+
+// closeGame closes the game's WebSocket clients for CloseGame.
+func (h *Hub) closeGame(gameID string, reason CloseReason) {
+	type left struct {
+		player models.Player
+		fns    []PresenceFunc
+	}
+	var spared []left
+	var sends []wsSend
+	h.mu.Lock()
+	for conn := range h.wsClients[gameID] {
+		if len(h.wsGames[conn]) == 1 {
+			sends = append(sends, wsSend{conn: conn, closing: &reason})
+			continue
+		}
+		c, fns := h.unregisterWS(gameID, conn)
+		sends = append(sends, h.newSend(conn, c, unsubscribed(gameID, reason.Text)))
+		spared = append(spared, left{c.sub.Player, fns})
+	}
+	h.mu.Unlock()
+	// Each may wait out closeWait or WriteTimeout, so they go to the
+	// connections' outboxes, after what was sent to them before
+	h.post(sends)
+	for _, l := range spared {
+		notifyPresence(l.fns, gameID, l.player, false)
+	}
+}
+
+// CloseAll sends every WebSocket client a close frame, for instance when
+// the server shuts down.
+func (h *Hub) CloseAll(reason CloseReason) {
+	h.mu.RLock()
+	conns := make([]*websocket.Conn, 0, len(h.wsGames))
+	for conn := range h.wsGames {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+	for _, conn := range conns {
+		reason.Send(conn)
+	}
+}
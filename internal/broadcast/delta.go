@@ -0,0 +1,207 @@
+package broadcast
+
+import (
+	"sync"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// DeltaWindow is how many recent states of a game the hub keeps to diff
+// against. A delta client whose acked version has fallen out of the
+// window is sent the full state instead.
+const DeltaWindow = 16
+
+// Frame types sent to and received from WebSocket clients that asked for
+// deltas with ?delta=1.
+const (
+	DeltaFrameType = "delta"
+	StateFrameType = "state"
+	AckFrameType   = "ack"
+)
+
+// Game statuses reported in delta frames.
+const (
+	StatusWaiting = "waiting"
+	StatusPlaying = "playing"
+	StatusWon     = "won"
+	StatusDraw    = "draw"
+)
+
+// Change is a cell that differs between two versions of a game. Player
+// is empty for a cell that was cleared, as after a reset.
+type Change struct {
+	Pos    int           `json:"pos"`
+	Player models.Player `json:"player"`
+}
+
+// DeltaFrame takes a delta client from the state it acked, FromVersion,
+// to ToVersion. LegalMoves is sent whole.
+type DeltaFrame struct {
+	Type        string             `json:"type"`
+	GameID      string             `json:"gameId"`
+	Seq         uint64             `json:"seq,omitempty"`
+	FromVersion uint64             `json:"fromVersion"`
+	ToVersion   uint64             `json:"toVersion"`
+	Changes     []Change           `json:"changes"`
+	CurrentTurn models.Player      `json:"currentTurn"`
+	Status      string             `json:"status"`
+	Winner      models.Player      `json:"winner,omitempty"`
+	LegalMoves  []int              `json:"legalMoves"`
+	Thinking    bool               `json:"thinking,omitempty"`
+	LastMove    *models.MoveRecord `json:"lastMove,omitempty"`
+}
+
+// StateFrame carries a whole game to a delta client: when it connects,
+// when its acked version is too old to diff against, and when something
+// other than the board and outcome changed, such as a player joining or
+// offering a draw.
+type StateFrame struct {
+	Type    string            `json:"type"`
+	GameID  string            `json:"gameId"`
+	Seq     uint64            `json:"seq,omitempty"`
+	Version uint64            `json:"version"`
+	Game    *models.GameState `json:"game"`
+}
+
+// status summarizes where the game stands for delta frames. A game
+// waiting for its players to be ready is still waiting.
+func status(g *models.GameState) string {
+	switch {
+	case g.IsDraw:
+		return StatusDraw
+	case g.IsOver:
+		return StatusWon
+	case !g.PlayerXJoined || !g.PlayerOJoined, game.AwaitingReady(g):
+		return StatusWaiting
+	}
+	return StatusPlaying
+}
+
+// snapshot is the part of a game state deltas are computed from.
+type snapshot struct {
+	board            models.Board
+	currentTurn      models.Player
+	winner           models.Player
+	isOver, isDraw   bool
+	xJoined, oJoined bool
+	xSymbol, oSymbol string
+	drawOffer        models.Player
+	xReady, oReady   bool
+	startsAt         int64
+	moves            int
+}
+
+func snapshotOf(game *models.GameState) snapshot {
+	return snapshot{
+		board:       game.Board,
+		currentTurn: game.CurrentTurn,
+		winner:      game.Winner,
+		isOver:      game.IsOver,
+		isDraw:      game.IsDraw,
+		xJoined:     game.PlayerXJoined,
+		oJoined:     game.PlayerOJoined,
+		xSymbol:     game.XSymbol,
+		oSymbol:     game.OSymbol,
+		drawOffer:   game.DrawOffer,
+		xReady:      game.PlayerXReady,
+		oReady:      game.PlayerOReady,
+		startsAt:    game.StartsAt.UnixNano(),
+		moves:       len(game.History),
+	}
+}
+
+// diff returns the cells that changed from a to b. It reports false when
+// something a delta can't express changed too.
+func diff(a, b snapshot) ([]Change, bool) {
+	if a.xJoined != b.xJoined || a.oJoined != b.oJoined || a.xSymbol != b.xSymbol || a.oSymbol != b.oSymbol || a.drawOffer != b.drawOffer ||
+		a.xReady != b.xReady || a.oReady != b.oReady || a.startsAt != b.startsAt {
+		return nil, false
+	}
+	changes := []Change{}
+	for i := range a.board {
+		if a.board[i] != b.board[i] {
+			changes = append(changes, Change{Pos: i, Player: b.board[i]})
+		}
+	}
+	return changes, true
+}
+
+// versioned is a game state the hub numbered.
+type versioned struct {
+	version uint64
+	state   snapshot
+}
+
+// versions numbers the states broadcast for games with delta clients and
+// keeps the last DeltaWindow of them for each.
+type versions struct {
+	mu    sync.Mutex
+	games map[string][]versioned
+}
+
+// record returns the version of game, numbering it if it differs from
+// the latest state recorded for the game.
+func (v *versions) record(gameID string, game *models.GameState) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	state := snapshotOf(game)
+	window := v.games[gameID]
+	if n := len(window); n > 0 {
+		latest := window[n-1]
+		if latest.state == state {
+			return latest.version
+		}
+		window = append(window, versioned{version: latest.version + 1, state: state})
+	} else {
+		window = []versioned{{version: 1, state: state}}
+	}
+	if len(window) > DeltaWindow {
+		window = window[len(window)-DeltaWindow:]
+	}
+	if v.games == nil {
+		v.games = make(map[string][]versioned)
+	}
+	v.games[gameID] = window
+	return window[len(window)-1].version
+}
+
+// frame returns what to send a delta client that acked version acked
+// for game, recorded as version and broadcast as seq.
+func (v *versions) frame(gameID string, acked, version, seq uint64, game *models.GameState) any {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, old := range v.games[gameID] {
+		if old.version != acked {
+			continue
+		}
+		changes, ok := diff(old.state, snapshotOf(game))
+		if !ok {
+			break
+		}
+		return DeltaFrame{
+			Type:        DeltaFrameType,
+			GameID:      gameID,
+			Seq:         seq,
+			FromVersion: acked,
+			ToVersion:   version,
+			Changes:     changes,
+			CurrentTurn: game.CurrentTurn,
+			Status:      status(game),
+			Winner:      game.Winner,
+			LegalMoves:  game.LegalMoves,
+			Thinking:    game.Thinking,
+			LastMove:    game.LastMove,
+		}
+	}
+	return StateFrame{Type: StateFrameType, GameID: gameID, Seq: seq, Version: version, Game: game}
+}
+
+// forget drops the game's recorded states.
+func (v *versions) forget(gameID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.games, gameID)
+}
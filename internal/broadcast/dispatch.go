@@ -0,0 +1,414 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// QueueSize is how many sends a game's queue holds waiting for its
+// dispatcher. A queue that fills up coalesces its game states, see
+// enqueue, so it never holds up whoever sends.
+const QueueSize = 64
+
+// DispatchIdle is how long a game's dispatcher waits for more to send
+// before it stops. The next send starts another.
+const DispatchIdle = 30 * time.Second
+
+// WriteTimeout is how long a WebSocket client has to take a write. One
+// that doesn't is disconnected, since its connection can't be written to
+// again, and reconnects to catch up.
+const WriteTimeout = 2 * time.Second
+
+// job is one send waiting in a game's queue: a game state from
+// Broadcast, an event from SendTo, the close of CloseGame, or the answer
+// to a Pull for the connection pullTo.
+type job struct {
+	// n numbers the job among every game's, so clients registered after
+	// it was queued, which are sent the state themselves, are skipped
+	n       uint64
+	game    *models.GameState
+	event   *Event
+	target  Target
+	seq     uint64
+	created time.Time
+	closing *CloseReason
+	pull    *Pull
+	pullTo  *websocket.Conn
+	// ctx is that of the call that queued the job, without its
+	// cancellation, so the sends are traced under its span
+	ctx context.Context
+}
+
+// queue holds a game's jobs for its dispatcher, which runs while
+// running is set. Both are guarded by the hub's queueMu.
+type queue struct {
+	pending []job
+	running bool
+	// wake tells a dispatcher waiting for jobs that there are some
+	wake chan struct{}
+}
+
+// enqueue queues j for the game's dispatcher, starting one if there is
+// none. A full queue drops superseded states first, then its oldest event.
+func (h *Hub) enqueue(gameID string, j job) {
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	if h.closed {
+		return
+	}
+
+	q := h.queues[gameID]
+	if q == nil {
+		q = &queue{wake: make(chan struct{}, 1)}
+		h.queues[gameID] = q
+	}
+	j.n = h.queued.Add(1)
+	if len(q.pending) >= QueueSize {
+		q.pending = h.coalesce(q.pending, j.game != nil)
+	}
+	q.pending = append(q.pending, j)
+	if !q.running {
+		q.running = true
+		h.workers.Go(func() { h.dispatch(gameID, q) })
+		return
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// coalesce makes room in a full queue for another job, superseding is
+// set when that is a game state. Must be called with queueMu held.
+func (h *Hub) coalesce(pending []job, superseding bool) []job {
+	kept := pending[:0]
+	latest := -1
+	if !superseding {
+		for i, j := range pending {
+			if j.game != nil {
+				latest = i
+			}
+		}
+	}
+	for i, j := range pending {
+		if j.game != nil && i != latest {
+			h.coalesced.Add(1)
+			continue
+		}
+		kept = append(kept, j)
+	}
+	if len(kept) < QueueSize {
+		return kept
+	}
+	for i, j := range kept {
+		if j.closing == nil && j.pull == nil {
+			h.coalesced.Add(1)
+			return append(kept[:i], kept[i+1:]...)
+		}
+	}
+	return kept
+}
+
+// dispatch sends the game's queued jobs, in the order they were queued,
+// until it has had none for DispatchIdle or the hub is closed.
+func (h *Hub) dispatch(gameID string, q *queue) {
+	idle := time.NewTimer(DispatchIdle)
+	defer idle.Stop()
+	for {
+		h.queueMu.Lock()
+		jobs := q.pending
+		q.pending = nil
+		h.queueMu.Unlock()
+
+		for _, j := range jobs {
+			h.deliver(gameID, j)
+		}
+		if len(jobs) > 0 {
+			idle.Reset(DispatchIdle)
+			continue
+		}
+		select {
+		case <-q.wake:
+		case <-idle.C:
+			h.queueMu.Lock()
+			if len(q.pending) == 0 {
+				q.running = false
+				delete(h.queues, gameID)
+				h.queueMu.Unlock()
+				return
+			}
+			h.queueMu.Unlock()
+			idle.Reset(DispatchIdle)
+		case <-h.stopping:
+			// Nothing is queued once the hub is closed, so these are
+			// the last
+			h.queueMu.Lock()
+			jobs := q.pending
+			q.pending = nil
+			q.running = false
+			delete(h.queues, gameID)
+			h.queueMu.Unlock()
+			for _, j := range jobs {
+				h.deliver(gameID, j)
+			}
+			return
+		}
+	}
+}
+
+// Close stops the game's dispatchers once they have sent what was
+// queued, and waits for them. What is sent afterwards is dropped.
+func (h *Hub) Close() {
+	h.queueMu.Lock()
+	h.closed = true
+	h.queueMu.Unlock()
+	h.closeOnce.Do(func() { close(h.stopping) })
+	h.workers.Wait()
+}
+
+// OutboxSize is how many writes a WebSocket connection's outbox holds.
+// A connection that falls that far behind is closed, as one that doesn't
+// take a write within WriteTimeout is, and reconnects to catch up.
+const OutboxSize = QueueSize
+
+// wsSend is a write to one WebSocket client, made under mu once the
+// hub's lock is released. closing replaces frame with a close frame.
+type wsSend struct {
+	conn    *websocket.Conn
+	c       *client
+	frame   any
+	mu      *sync.Mutex
+	closing *CloseReason
+	created time.Time
+}
+
+// newSend returns the write of frame to conn, the connection of c. Must
+// be called with the lock held.
+func (h *Hub) newSend(conn *websocket.Conn, c *client, frame any) wsSend {
+	return wsSend{conn: conn, c: c, frame: frame, mu: h.writer(conn)}
+}
+
+// write makes the writes of sends, in order, recording each with its
+// client. Must be called without the lock held.
+func (h *Hub) write(sends []wsSend) {
+	for _, w := range sends {
+		w.c.delivered(writeJSON(w.mu, w.conn, w.frame))
+	}
+}
+
+// outbox queues a WebSocket connection's writes for a goroutine of its
+// own. Guarded by the hub's outboxMu.
+type outbox struct {
+	pending []wsSend
+	running bool
+	failed  bool
+}
+
+// post queues sends on their connections' outboxes, starting a writer
+// for each that has none. It never blocks on clients: a connection whose
+// outbox is full is closed instead, which ends its read loop and so
+// unregisters it.
+func (h *Hub) post(sends []wsSend) {
+	h.outboxMu.Lock()
+	defer h.outboxMu.Unlock()
+	for _, w := range sends {
+		o := h.outboxes[w.conn]
+		if o == nil {
+			o = &outbox{}
+			h.outboxes[w.conn] = o
+		}
+		if !o.failed && len(o.pending) >= OutboxSize {
+			o.failed = true
+			for _, dropped := range o.pending {
+				h.dropSend(dropped)
+			}
+			o.pending = nil
+			w.conn.Close()
+		}
+		if o.failed {
+			h.dropSend(w)
+			continue
+		}
+		o.pending = append(o.pending, w)
+		if !o.running {
+			o.running = true
+			// Posts come from dispatchers, which Close waits for, so
+			// the writer is added before it can be waited on
+			h.workers.Go(func() { h.drain(w.conn, o) })
+		}
+	}
+}
+
+// drain makes the writes queued on conn's outbox until there are none
+// left, then forgets the outbox.
+func (h *Hub) drain(conn *websocket.Conn, o *outbox) {
+	for {
+		h.outboxMu.Lock()
+		sends := o.pending
+		o.pending = nil
+		if len(sends) == 0 || o.failed {
+			for _, w := range sends {
+				h.dropSend(w)
+			}
+			o.running = false
+			delete(h.outboxes, conn)
+			h.outboxMu.Unlock()
+			return
+		}
+		h.outboxMu.Unlock()
+
+		for i, w := range sends {
+			if w.closing != nil {
+				w.closing.Send(conn)
+				continue
+			}
+			err := writeJSON(w.mu, conn, w.frame)
+			h.sent(w, err)
+			if err != nil {
+				// The write may have been cut off partway, so nothing
+				// more can be sent on the connection. Closing it ends its
+				// read loop, which unregisters it.
+				conn.Close()
+				h.outboxMu.Lock()
+				o.failed = true
+				h.outboxMu.Unlock()
+				for _, dropped := range sends[i+1:] {
+					h.dropSend(dropped)
+				}
+				break
+			}
+		}
+	}
+}
+
+// sent records the outcome of w with its client.
+func (h *Hub) sent(w wsSend, err error) {
+	if w.c == nil {
+		return
+	}
+	if w.created.IsZero() {
+		w.c.delivered(err)
+		return
+	}
+	h.deliveredWS(w.c, w.created, err)
+}
+
+// dropSend records that w was dropped without being written.
+func (h *Hub) dropSend(w wsSend) {
+	if w.closing == nil {
+		h.sent(w, errDropped)
+	}
+}
+
+// deliver sends j to the game's clients it targets. SSE clients are
+// queued for under the lock, which never blocks; WebSocket clients' writes
+// are posted to their outboxes after it is released, see post.
+func (h *Hub) deliver(gameID string, j job) {
+	if j.closing != nil {
+		h.closeGame(gameID, *j.closing)
+		return
+	}
+	if j.pull != nil {
+		h.deliverPull(gameID, j)
+		return
+	}
+	name := "hub.Broadcast"
+	if j.event != nil {
+		name = "hub.SendTo"
+	}
+	_, span := tracer.Start(j.ctx, name+".dispatch")
+	defer span.End()
+
+	h.mu.RLock()
+	var writes []wsSend
+	var version uint64
+	for conn, c := range h.wsClients[gameID] {
+		if c.joined >= j.n || !j.target.matches(c.sub) {
+			continue
+		}
+		var frame any
+		switch {
+		case j.event != nil:
+			frame = wsEvent{Type: j.event.Name, GameID: gameID, Seq: j.seq, Data: j.event.Data}
+		case !c.sub.Delta:
+			frame = c.sub.gameFrame(j.seq, j.game)
+		default:
+			if version == 0 {
+				version = h.versions.record(gameID, j.game)
+			}
+			frame = h.versions.frame(gameID, c.acked.Load(), version, j.seq, j.game)
+		}
+		send := h.newSend(conn, c, frame)
+		send.created = j.created
+		writes = append(writes, send)
+	}
+	msg := Message{Game: j.game, Event: j.event, Seq: j.seq, Created: j.created}
+	sseSent, dropped := 0, 0
+	for ch, c := range h.sseClients[gameID] {
+		if c.joined >= j.n || !j.target.matches(c.sub) {
+			continue
+		}
+		if h.sendSSE(ch, c, msg) {
+			sseSent++
+		} else {
+			dropped++
+		}
+	}
+	h.mu.RUnlock()
+
+	h.post(writes)
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("game.id", gameID),
+			attribute.Int("ws.queued", len(writes)),
+			attribute.Int("sse.sent", sseSent),
+			attribute.Int("sse.dropped", dropped),
+		)
+	}
+}
+
+// WriteJSON writes v to a WebSocket connection as JSON. Everything
+// written to a connection registered with the hub must go through it,
+// so that only one write is made at a time, and it gives up after
+// WriteTimeout.
+func (h *Hub) WriteJSON(conn *websocket.Conn, v any) error {
+	return writeJSON(h.writer(conn), conn, v)
+}
+
+// writer returns the mutex held while writing to conn, which it keeps
+// from its first registration until UnregisterConn, or nil outside that
+// time.
+func (h *Hub) writer(conn *websocket.Conn) *sync.Mutex {
+	if mu, ok := h.writers.Load(conn); ok {
+		return mu.(*sync.Mutex)
+	}
+	return nil
+}
+
+// writeJSON writes v to conn under mu, if it isn't nil, for WriteJSON.
+func writeJSON(mu *sync.Mutex, conn *websocket.Conn, v any) error {
+	if mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+// Queued returns how many sends are waiting for their games'
+// dispatchers.
+func (h *Hub) Queued() int {
+	h.queueMu.Lock()
+	defer h.queueMu.Unlock()
+	n := 0
+	for _, q := range h.queues {
+		n += len(q.pending)
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+package broadcast
+
+import (
+	"time"
+
+	"tiktaktoes/internal/sse"
+)
+
+// RestartEvent is the last event on an SSE stream the server ends because
+// it is shutting down, telling the client to reconnect rather than give up.
+const RestartEvent = "server-restart"
+
+// RestartRetry is how long SSE clients are asked to wait before
+// reconnecting after a RestartEvent, long enough for a new instance to
+// start listening.
+const RestartRetry = 3 * time.Second
+
+// Drain tells every SSE handler to send its client a RestartEvent and end
+// the stream. Handlers started afterwards do so straight away. It is
+// called on shutdown before waiting for requests to finish, which SSE
+// streams otherwise never do.
+func (h *Hub) Drain() {
+	h.drainOnce.Do(func() { close(h.draining) })
+}
+
+// Draining is closed once Drain has been called.
+func (h *Hub) Draining() <-chan struct{} {
+	return h.draining
+}
+
+// Restart returns a RestartEvent with its retry hint. EventSource waits
+// that long before reconnecting, sending the id of the last event it got
+// as Last-Event-ID.
+func Restart() sse.Event {
+	return sse.Event{Name: RestartEvent, Data: []byte("{}"), Retry: RestartRetry}
+}
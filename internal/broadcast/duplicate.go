@@ -0,0 +1,144 @@
+package broadcast
+
+import (
+	"fmt"
+
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// DuplicatePolicy decides what happens when a player's slot in a game
+// gets a connection while another already has it, as when a player
+// opens the game in a second tab. Either way one connection at a time
+// controls the slot; the others watch.
+type DuplicatePolicy int
+
+const (
+	// Supersede hands the slot to the newest connection. The one that
+	// had it is sent a superseded event and kept on as a spectator.
+	Supersede DuplicatePolicy = iota
+	// RejectDuplicate keeps the slot with the connection that has it,
+	// see Admit, unless the new one asks to take over, which then
+	// supersedes the older one.
+	RejectDuplicate
+)
+
+// ParseDuplicatePolicy parses "supersede" or "reject", as given on the
+// command line.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch s {
+	case "supersede":
+		return Supersede, nil
+	case "reject":
+		return RejectDuplicate, nil
+	}
+	return 0, fmt.Errorf("unknown duplicate connection policy %q, must be supersede or reject", s)
+}
+
+// SetDuplicatePolicy sets what happens to a second connection for a
+// player's slot, Supersede unless set. Set it before serving clients.
+func (h *Hub) SetDuplicatePolicy(p DuplicatePolicy) {
+	h.duplicates = p
+}
+
+// ReasonElsewhere closes a WebSocket connection turned away under
+// RejectDuplicate.
+var ReasonElsewhere = CloseReason{4004, "already connected elsewhere"}
+
+// SupersededEvent is sent to a connection that no longer controls its
+// player's slot, or never got to, and now only watches the game.
+const SupersededEvent = "superseded"
+
+// Superseded is the data of a superseded event. Rejected is set when the
+// connection was turned away because another already controls the slot,
+// and not set when a newer connection took the slot over from it.
+// Either way it may take over by reconnecting with ?takeover=1.
+type Superseded struct {
+	GameID   string        `json:"gameId"`
+	Player   models.Player `json:"player"`
+	Rejected bool          `json:"rejected,omitempty"`
+}
+
+// Admit reports whether a connection for sub may be registered as it
+// is. It is false only under RejectDuplicate, for a player whose slot in
+// the game another connection already controls, and takeover not set;
+// such a connection should be turned away or registered as a spectator.
+func (h *Hub) Admit(gameID string, sub Subscriber, takeover bool) bool {
+	if sub.Role != RolePlayer || takeover || h.duplicates != RejectDuplicate {
+		return true
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, c := range h.wsClients[gameID] {
+		if c.controls(sub.Player) {
+			return false
+		}
+	}
+	for _, c := range h.sseClients[gameID] {
+		if c.controls(sub.Player) {
+			return false
+		}
+	}
+	return true
+}
+
+// Superseded reports whether a WebSocket connection registered for the
+// game as a player has since lost the slot to another connection.
+func (h *Hub) Superseded(gameID string, conn *websocket.Conn) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	c, ok := h.wsClients[gameID][conn]
+	return ok && c.superseded
+}
+
+// Controls reports whether a WebSocket connection registered for the
+// game plays player's slot there, and hasn't lost it to another
+// connection.
+func (h *Hub) Controls(gameID string, conn *websocket.Conn, player models.Player) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	c, ok := h.wsClients[gameID][conn]
+	return ok && c.controls(player)
+}
+
+// controls reports whether the client plays player's slot. Must be
+// called with the hub's lock held.
+func (c *client) controls(player models.Player) bool {
+	return c.sub.Role == RolePlayer && c.sub.Player == player
+}
+
+// supersede demotes every other client controlling newest's slot to a
+// spectator and returns the WebSocket writes telling them so.
+// Must be called with the lock held.
+func (h *Hub) supersede(gameID string, newest *client) []wsSend {
+	if newest.sub.Role != RolePlayer {
+		return nil
+	}
+	var sends []wsSend
+	player := newest.sub.Player
+	ev := Event{Name: SupersededEvent, Data: Superseded{GameID: gameID, Player: player}}
+	for conn, c := range h.wsClients[gameID] {
+		if c != newest && c.controls(player) {
+			h.downgrade(gameID, c)
+			sends = append(sends, h.newSend(conn, c, wsEvent{Type: ev.Name, GameID: gameID, Data: ev.Data}))
+		}
+	}
+	for ch, c := range h.sseClients[gameID] {
+		if c != newest && c.controls(player) {
+			h.downgrade(gameID, c)
+			h.sendSSE(ch, c, Message{Event: &ev})
+		}
+	}
+	return sends
+}
+
+// downgrade turns a player's client into a spectator's. Must be called
+// with the lock held.
+func (h *Hub) downgrade(gameID string, c *client) {
+	// The slot's newest connection is counted, so this never takes the
+	// player offline and there are no callbacks to run
+	h.presence.connected(gameID, c.sub, -1)
+	c.sub.Role = RoleSpectator
+	c.superseded = true
+}
@@ -0,0 +1,119 @@
+package broadcast
+
+import (
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// EventLogSize is how many of a game's latest events the hub keeps for
+// clients filling a gap, see Hub.EventsSince.
+const EventLogSize = 100
+
+// GameUpdateEvent is the type of logged events that carry a game state
+// from Broadcast.
+const GameUpdateEvent = "game-update"
+
+// LoggedEvent is an event as it was sent to everyone watching a game.
+// Seq numbers the game's events from 1 up; it is not the game's version.
+type LoggedEvent struct {
+	Seq  uint64            `json:"seq"`
+	Type string            `json:"type"`
+	Time time.Time         `json:"time"`
+	Game *models.GameState `json:"game,omitempty"`
+	Data any               `json:"data,omitempty"`
+}
+
+// eventLogs numbers the events sent to all of a game's clients and keeps
+// the latest EventLogSize of each game.
+type eventLogs struct {
+	mu    sync.Mutex
+	games map[string]*eventLog
+}
+
+type eventLog struct {
+	// seq is the number of the last event
+	seq uint64
+	// ring holds the latest events, the oldest at start once full
+	ring  []LoggedEvent
+	start int
+}
+
+// record numbers the event and adds it to the game's log.
+func (l *eventLogs) record(gameID string, ev LoggedEvent) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.games == nil {
+		l.games = make(map[string]*eventLog)
+	}
+	log := l.games[gameID]
+	if log == nil {
+		log = &eventLog{}
+		l.games[gameID] = log
+	}
+	log.seq++
+	ev.Seq = log.seq
+	if len(log.ring) < EventLogSize {
+		log.ring = append(log.ring, ev)
+	} else {
+		log.ring[log.start] = ev
+		log.start = (log.start + 1) % EventLogSize
+	}
+	return ev.Seq
+}
+
+// since returns the game's logged events after fromSeq, oldest first,
+// and the number of its last event. complete is false when events after
+// fromSeq have already been dropped from the log, or fromSeq is from
+// before the game was numbered afresh.
+func (l *eventLogs) since(gameID string, fromSeq uint64) (events []LoggedEvent, seq uint64, complete bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	log := l.games[gameID]
+	if log == nil {
+		return []LoggedEvent{}, 0, fromSeq == 0
+	}
+	if fromSeq > log.seq {
+		return []LoggedEvent{}, log.seq, false
+	}
+	events = []LoggedEvent{}
+	for i := range log.ring {
+		ev := log.ring[(log.start+i)%len(log.ring)]
+		if ev.Seq > fromSeq {
+			events = append(events, ev)
+		}
+	}
+	oldest := log.seq - uint64(len(log.ring)) + 1
+	return events, log.seq, fromSeq+1 >= oldest
+}
+
+// seq returns the number of the game's last event, 0 if none is logged.
+func (l *eventLogs) seq(gameID string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if log := l.games[gameID]; log != nil {
+		return log.seq
+	}
+	return 0
+}
+
+func (l *eventLogs) forget(gameID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.games, gameID)
+}
+
+// EventsSince returns the events sent to everyone watching the game
+// after the one numbered fromSeq, for a client that missed some, and the
+// number of the last one. complete is false when some of them are no
+// longer kept, in which case the client should fetch the game instead.
+func (h *Hub) EventsSince(gameID string, fromSeq uint64) (events []LoggedEvent, seq uint64, complete bool) {
+	return h.eventLogs.since(gameID, fromSeq)
+}
+
+// Seq returns the number of the last event sent to everyone watching
+// the game, 0 if there is none yet.
+func (h *Hub) Seq(gameID string) uint64 {
+	return h.eventLogs.seq(gameID)
+}
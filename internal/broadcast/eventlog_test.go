@@ -0,0 +1,74 @@
+package broadcast_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+)
+
+// TestEventsSince has a subscriber miss an event, notice the gap in the
+// numbers and fill it from the log.
+func TestEventsSince(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	ch := subscribe(t, h, "game", broadcast.NewSubscriber("", "s"))
+
+	for i := range 3 {
+		h.Broadcast(ctx, "game", &models.GameState{ID: "game", Version: uint64(i + 1)})
+	}
+	// Events to some clients only aren't numbered, or the others would
+	// see a gap
+	h.SendTo(ctx, "game", broadcast.ToPlayer(models.PlayerX), broadcast.Event{Name: "ping"})
+	h.SendTo(ctx, "game", broadcast.ToAll(), broadcast.Event{Name: "countdown", Data: 3})
+
+	var seqs []uint64
+	for range 4 {
+		seqs = append(seqs, next(t, ch).Seq)
+	}
+	if want := []uint64{1, 2, 3, 4}; !slices.Equal(seqs, want) {
+		t.Fatalf("numbered %v, want %v", seqs, want)
+	}
+
+	// The client got 1, then missed 2 and noticed when 3 came
+	events, seq, complete := h.EventsSince("game", 1)
+	if seq != 4 || !complete || len(events) != 3 {
+		t.Fatalf("since 1: %d events up to %d, complete %v", len(events), seq, complete)
+	}
+	if ev := events[0]; ev.Seq != 2 || ev.Type != broadcast.GameUpdateEvent || ev.Game == nil || ev.Game.Version != 2 {
+		t.Errorf("the missed event %+v, want the update to version 2", ev)
+	}
+	if ev := events[2]; ev.Seq != 4 || ev.Type != "countdown" || ev.Data != 3 {
+		t.Errorf("the last event %+v, want the countdown", ev)
+	}
+	if events, _, complete := h.EventsSince("game", 4); len(events) != 0 || !complete {
+		t.Errorf("since the last: %d events, complete %v", len(events), complete)
+	}
+	if _, _, complete := h.EventsSince("game", 9); complete {
+		t.Error("since an event not sent yet: complete")
+	}
+}
+
+func TestEventsSinceDropped(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	for range broadcast.EventLogSize + 10 {
+		h.Broadcast(ctx, "game", &models.GameState{ID: "game"})
+	}
+	if events, seq, complete := h.EventsSince("game", 5); complete || seq != broadcast.EventLogSize+10 || len(events) != broadcast.EventLogSize {
+		t.Errorf("since 5: %d events up to %d, complete %v, want the last %d, incomplete", len(events), seq, complete, broadcast.EventLogSize)
+	}
+	if events, _, complete := h.EventsSince("game", 10); !complete || len(events) != broadcast.EventLogSize {
+		t.Errorf("since the event before the oldest kept: %d events, complete %v", len(events), complete)
+	}
+
+	// A game that is over starts afresh
+	h.Broadcast(ctx, "game", &models.GameState{ID: "game", IsOver: true})
+	if _, seq, _ := h.EventsSince("game", 0); seq != 0 {
+		t.Errorf("a finished game's log is at %d, want it dropped", seq)
+	}
+}
@@ -0,0 +1,102 @@
+package broadcast
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Types of firehose events for game state broadcasts. Targeted events
+// keep their own names, such as TurnNotificationEvent.
+const (
+	FirehoseGameUpdate = "game_update"
+	FirehoseGameOver   = "game_over"
+)
+
+// firehoseBuffer is how many events a firehose subscriber may fall
+// behind by before it is disconnected.
+const firehoseBuffer = 256
+
+// FirehoseEvent is one thing that happened on a game, as seen by
+// firehose subscribers.
+type FirehoseEvent struct {
+	GameID string            `json:"gameId"`
+	Type   string            `json:"type"`
+	Time   time.Time         `json:"time"`
+	Game   *models.GameState `json:"game,omitempty"`
+	Data   any               `json:"data,omitempty"`
+}
+
+// FirehoseFilter selects the events a firehose subscriber gets. Empty
+// fields match everything.
+type FirehoseFilter struct {
+	Types  []string
+	GameID string
+}
+
+func (f FirehoseFilter) matches(ev FirehoseEvent) bool {
+	if f.GameID != "" && ev.GameID != f.GameID {
+		return false
+	}
+	return len(f.Types) == 0 || slices.Contains(f.Types, ev.Type)
+}
+
+// firehose fans every broadcast and event of every game out to its
+// subscribers. It never blocks the sender: a subscriber that falls
+// behind by a full buffer is disconnected by closing its channel, and
+// can reconnect once it catches up.
+type firehose struct {
+	mu          sync.Mutex
+	subs        map[chan FirehoseEvent]FirehoseFilter
+	disconnects atomic.Uint64
+}
+
+// SubscribeAll subscribes to the events of every game that match
+// filter. The channel is closed when cancel is called or when the
+// subscriber falls too far behind; cancel must be called either way.
+func (h *Hub) SubscribeAll(filter FirehoseFilter) (events <-chan FirehoseEvent, cancel func()) {
+	ch := make(chan FirehoseEvent, firehoseBuffer)
+	h.firehose.mu.Lock()
+	if h.firehose.subs == nil {
+		h.firehose.subs = make(map[chan FirehoseEvent]FirehoseFilter)
+	}
+	h.firehose.subs[ch] = filter
+	h.firehose.mu.Unlock()
+
+	return ch, func() {
+		h.firehose.mu.Lock()
+		defer h.firehose.mu.Unlock()
+		if _, ok := h.firehose.subs[ch]; ok {
+			delete(h.firehose.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish hands ev to every matching subscriber without blocking.
+func (f *firehose) publish(ev FirehoseEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch, filter := range f.subs {
+		if !filter.matches(ev) {
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+			delete(f.subs, ch)
+			close(ch)
+			f.disconnects.Add(1)
+		}
+	}
+}
+
+// count returns the number of subscribers.
+func (f *firehose) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
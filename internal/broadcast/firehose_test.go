@@ -0,0 +1,111 @@
+package broadcast_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+)
+
+func TestFirehoseFilter(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	filters := []broadcast.FirehoseFilter{
+		{},
+		{GameID: "b"},
+		{Types: []string{broadcast.FirehoseGameOver, "ping"}},
+		{Types: []string{broadcast.FirehoseGameUpdate}, GameID: "a"},
+	}
+	var subs []<-chan broadcast.FirehoseEvent
+	for _, f := range filters {
+		events, cancel := h.SubscribeAll(f)
+		defer cancel()
+		subs = append(subs, events)
+	}
+
+	h.Broadcast(ctx, "a", &models.GameState{ID: "a"})
+	h.Broadcast(ctx, "b", &models.GameState{ID: "b"})
+	h.SendTo(ctx, "a", broadcast.ToAll(), broadcast.Event{Name: "ping"})
+	h.Broadcast(ctx, "b", &models.GameState{ID: "b", IsOver: true})
+
+	want := [][]string{
+		{"a game_update", "b game_update", "a ping", "b game_over"},
+		{"b game_update", "b game_over"},
+		{"a ping", "b game_over"},
+		{"a game_update"},
+	}
+	for i, events := range subs {
+		var got []string
+	read:
+		for {
+			select {
+			case ev := <-events:
+				got = append(got, ev.GameID+" "+ev.Type)
+			default:
+				break read
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want[i]) {
+			t.Errorf("filter %+v got %q, want %q", filters[i], got, want[i])
+		}
+	}
+}
+
+// TestSlowFirehoseSubscriber has a firehose subscriber stop reading,
+// and checks it is cut off without holding up the games or the
+// subscribers keeping up.
+func TestSlowFirehoseSubscriber(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	slow, cancelSlow := h.SubscribeAll(broadcast.FirehoseFilter{})
+	defer cancelSlow()
+	fast, cancelFast := h.SubscribeAll(broadcast.FirehoseFilter{})
+	defer cancelFast()
+	player := make(chan broadcast.Message, 1)
+	h.RegisterSSE("game", player, broadcast.NewSubscriber(models.PlayerX, "x"))
+	defer h.UnregisterSSE("game", player)
+
+	const n = 1000
+	got := make(chan int)
+	go func() {
+		count := 0
+		for range fast {
+			if count++; count == n {
+				break
+			}
+		}
+		got <- count
+	}()
+	start := time.Now()
+	for i := range n {
+		h.Broadcast(ctx, "game", &models.GameState{ID: "game", Version: uint64(i + 1)})
+		// The player reads as they come
+		if msg := next(t, player); msg.Game.Version != uint64(i+1) {
+			t.Fatalf("the player got version %d, want %d", msg.Game.Version, i+1)
+		}
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("%d broadcasts took %v with a subscriber not reading", n, took)
+	}
+	if count := <-got; count != n {
+		t.Errorf("the subscriber keeping up got %d events, want %d", count, n)
+	}
+
+	// The slow subscriber got as much as its buffer held, then its
+	// stream was closed
+	buffered := 0
+	for range slow {
+		buffered++
+	}
+	if buffered == 0 || buffered >= n {
+		t.Errorf("the slow subscriber got %d events before it was cut off", buffered)
+	}
+	if stats := h.Stats(); stats.FirehoseDisconnects != 1 || stats.Subscribers[broadcast.TransportFirehose] != 1 {
+		t.Errorf("%d disconnects, %d subscribers left, want 1 and 1", stats.FirehoseDisconnects, stats.Subscribers[broadcast.TransportFirehose])
+	}
+}
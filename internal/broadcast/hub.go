@@ -1,74 +1,386 @@
 package broadcast
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"tiktaktoes/internal/logging"
 	"tiktaktoes/internal/models"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var tracer = otel.Tracer("tiktaktoes/internal/broadcast")
+
 // Hub manages broadcasting game state updates to WebSocket and SSE clients.
+// Each connection is registered with a Subscriber describing it, so
+// events can also be sent to some of a game's clients with SendTo.
 type Hub struct {
-	wsClients  map[string]map[*websocket.Conn]bool
-	sseClients map[string]map[chan *models.GameState]bool
-	mu         sync.RWMutex
+	wsClients  map[string]map[*websocket.Conn]*client
+	sseClients map[string]map[chan Message]*client
+	// wsGames maps each WebSocket connection to the IDs of the games it
+	// is registered for, more than one once it subscribes to others
+	wsGames map[*websocket.Conn]map[string]bool
+	mu      sync.RWMutex
+
+	// Counters for Stats. Sends happen under the read lock, so they are
+	// updated atomically rather than under mu.
+	broadcasts atomic.Uint64
+	events     atomic.Uint64
+	dropped    atomic.Uint64
+	// policy counts WebSocket clients disconnected for abuse
+	policy atomic.Uint64
+	// lags times deliveries by transport
+	lags lags
+	// lastBroadcast maps a game ID to the time of its last broadcast
+	lastBroadcast sync.Map
+
+	firehose firehose
+	versions versions
+	presence presence
+	topics   topics
+	// eventLogs numbers what is sent to all of a game's clients
+	eventLogs eventLogs
+	// instanceID identifies this run of the server, see Welcome
+	instanceID string
+	// duplicates is what happens to a second connection for a player's
+	// slot, see SetDuplicatePolicy
+	duplicates DuplicatePolicy
+
+	// queues holds each game's sends waiting for its dispatcher, see
+	// enqueue. queued numbers them and coalesced counts those a full
+	// queue dropped.
+	queueMu   sync.Mutex
+	queues    map[string]*queue
+	queued    atomic.Uint64
+	coalesced atomic.Uint64
+	// writers holds a mutex for each registered WebSocket connection,
+	// held while writing to it, see WriteJSON. A connection keeps its
+	// own from its first registration until UnregisterConn, however
+	// many games it leaves and joins meanwhile.
+	writers sync.Map
+	// outboxes holds the writes waiting for each WebSocket connection
+	// that has any, see post
+	outboxMu sync.Mutex
+	outboxes map[*websocket.Conn]*outbox
+
+	// draining is closed by Drain
+	draining  chan struct{}
+	drainOnce sync.Once
+	// stopping is closed by Close, and closed set under queueMu, after
+	// which nothing more is queued. workers are the dispatchers and
+	// outboxes' writers, which Close waits for.
+	stopping  chan struct{}
+	closed    bool
+	closeOnce sync.Once
+	workers   sync.WaitGroup
 }
 
 // NewHub creates a new broadcast hub.
 func NewHub() *Hub {
 	return &Hub{
-		wsClients:  make(map[string]map[*websocket.Conn]bool),
-		sseClients: make(map[string]map[chan *models.GameState]bool),
+		wsClients:  make(map[string]map[*websocket.Conn]*client),
+		sseClients: make(map[string]map[chan Message]*client),
+		wsGames:    make(map[*websocket.Conn]map[string]bool),
+		queues:     make(map[string]*queue),
+		outboxes:   make(map[*websocket.Conn]*outbox),
+		draining:   make(chan struct{}),
+		stopping:   make(chan struct{}),
+		presence:   newPresence(),
+		topics:     newTopics(),
+		instanceID: logging.NewID(),
 	}
 }
 
-// RegisterWS adds a WebSocket connection for a game.
-func (h *Hub) RegisterWS(gameID string, conn *websocket.Conn) {
+// RegisterWS adds a WebSocket connection for a game. A connection may be
+// registered for several games, see UnregisterConn. A player's
+// connection takes the slot over from any other, see Superseded.
+func (h *Hub) RegisterWS(gameID string, conn *websocket.Conn, sub Subscriber) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.wsClients[gameID] == nil {
-		h.wsClients[gameID] = make(map[*websocket.Conn]bool)
+		h.wsClients[gameID] = make(map[*websocket.Conn]*client)
+	}
+	c := newClient(sub)
+	c.joined = h.queued.Load()
+	h.wsClients[gameID][conn] = c
+	if h.wsGames[conn] == nil {
+		h.wsGames[conn] = make(map[string]bool)
+		h.writers.LoadOrStore(conn, new(sync.Mutex))
+	}
+	h.wsGames[conn][gameID] = true
+	fns := h.presence.connected(gameID, sub, 1)
+	sends := h.supersede(gameID, c)
+	h.mu.Unlock()
+	h.write(sends)
+	notifyPresence(fns, gameID, sub.Player, true)
+}
+
+// SendState sends a newly registered WebSocket client the game's state,
+// if there is one, and a welcome frame.
+func (h *Hub) SendState(gameID string, conn *websocket.Conn, game *models.GameState) {
+	h.mu.RLock()
+	c, ok := h.wsClients[gameID][conn]
+	if !ok {
+		h.mu.RUnlock()
+		return
+	}
+	seq := h.eventLogs.seq(gameID)
+	var frames []any
+	switch {
+	case game == nil:
+	case !c.sub.Delta:
+		frames = append(frames, c.sub.gameFrame(seq, game))
+	default:
+		version := h.versions.record(gameID, game)
+		c.acked.Store(version)
+		frames = append(frames, StateFrame{Type: StateFrameType, GameID: gameID, Seq: seq, Version: version, Game: game})
+	}
+	frames = append(frames, h.welcome(gameID, c.sub.ConnID, game))
+	h.mu.RUnlock()
+
+	for _, frame := range frames {
+		c.delivered(h.WriteJSON(conn, frame))
+	}
+}
+
+// Ack records that a delta client has applied the given version, so
+// later deltas are computed from it. Acks older than one already
+// received are ignored.
+func (h *Hub) Ack(gameID string, conn *websocket.Conn, version uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	c, ok := h.wsClients[gameID][conn]
+	if !ok {
+		return
+	}
+	for {
+		acked := c.acked.Load()
+		if version <= acked || c.acked.CompareAndSwap(acked, version) {
+			return
+		}
 	}
-	h.wsClients[gameID][conn] = true
 }
 
 // UnregisterWS removes a WebSocket connection for a game.
 func (h *Hub) UnregisterWS(gameID string, conn *websocket.Conn) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	c, fns := h.unregisterWS(gameID, conn)
+	h.mu.Unlock()
+	if c != nil {
+		notifyPresence(fns, gameID, c.sub.Player, false)
+	}
+}
+
+// UnregisterConn removes a WebSocket connection for every game it is
+// registered for, as once it has closed, and forgets its writer. Writes
+// already collected for it are still made under that writer.
+func (h *Hub) UnregisterConn(conn *websocket.Conn) {
+	for _, gameID := range h.Subscriptions(conn) {
+		h.UnregisterWS(gameID, conn)
+	}
+	h.mu.Lock()
+	if h.wsGames[conn] == nil {
+		h.writers.Delete(conn)
+	}
+	h.mu.Unlock()
+}
+
+// Subscriptions returns the IDs of the games a WebSocket connection is
+// registered for, in no particular order.
+func (h *Hub) Subscriptions(conn *websocket.Conn) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]string, 0, len(h.wsGames[conn]))
+	for id := range h.wsGames[conn] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// unregisterWS removes a WebSocket connection for a game and returns its
+// client, nil if it wasn't registered, and the presence callbacks to run
+// once the lock is released. Must be called with the lock held.
+func (h *Hub) unregisterWS(gameID string, conn *websocket.Conn) (*client, []PresenceFunc) {
+	c, ok := h.wsClients[gameID][conn]
 	delete(h.wsClients[gameID], conn)
+	h.forgetIfEmpty(gameID)
+	delete(h.wsGames[conn], gameID)
+	if len(h.wsGames[conn]) == 0 {
+		delete(h.wsGames, conn)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return c, h.presence.connected(gameID, c.sub, -1)
 }
 
-// RegisterSSE adds an SSE channel for a game.
-func (h *Hub) RegisterSSE(gameID string, ch chan *models.GameState) {
+// RegisterSSE adds an SSE channel for a game. A player's channel takes
+// the slot over from any other connection, as RegisterWS does.
+func (h *Hub) RegisterSSE(gameID string, ch chan Message, sub Subscriber) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.sseClients[gameID] == nil {
-		h.sseClients[gameID] = make(map[chan *models.GameState]bool)
+		h.sseClients[gameID] = make(map[chan Message]*client)
 	}
-	h.sseClients[gameID][ch] = true
+	c := newClient(sub)
+	c.joined = h.queued.Load()
+	h.sseClients[gameID][ch] = c
+	fns := h.presence.connected(gameID, sub, 1)
+	sends := h.supersede(gameID, c)
+	h.mu.Unlock()
+	h.write(sends)
+	notifyPresence(fns, gameID, sub.Player, true)
 }
 
 // UnregisterSSE removes an SSE channel for a game.
-func (h *Hub) UnregisterSSE(gameID string, ch chan *models.GameState) {
+func (h *Hub) UnregisterSSE(gameID string, ch chan Message) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
+	c, ok := h.sseClients[gameID][ch]
 	delete(h.sseClients[gameID], ch)
+	h.forgetIfEmpty(gameID)
 	close(ch)
+	var fns []PresenceFunc
+	if ok {
+		fns = h.presence.connected(gameID, c.sub, -1)
+	}
+	h.mu.Unlock()
+	if ok {
+		notifyPresence(fns, gameID, c.sub.Player, false)
+	}
+}
+
+// forgetIfEmpty drops the game's entries once its last client of either
+// transport is gone. Must be called with the lock held.
+func (h *Hub) forgetIfEmpty(gameID string) {
+	if len(h.wsClients[gameID]) == 0 {
+		delete(h.wsClients, gameID)
+		h.versions.forget(gameID)
+	}
+	if len(h.sseClients[gameID]) == 0 {
+		delete(h.sseClients, gameID)
+	}
+	if h.wsClients[gameID] == nil && h.sseClients[gameID] == nil {
+		h.lastBroadcast.Delete(gameID)
+	}
+}
+
+// CountPolicyDisconnect records that a client was disconnected for
+// breaking the connection limits, such as flooding a WebSocket.
+func (h *Hub) CountPolicyDisconnect() {
+	h.policy.Add(1)
+}
+
+// NotifyTurn sends a turn-notification event to the player who moves
+// next. Nothing is sent once the game is over. moverConnID, if set, is
+// the connection the move came in on, which never needs telling.
+func (h *Hub) NotifyTurn(ctx context.Context, game *models.GameState, moverConnID string) {
+	if game.IsOver {
+		return
+	}
+	h.SendTo(ctx, game.ID, ToPlayer(game.CurrentTurn).Except(moverConnID), Event{
+		Name: TurnNotificationEvent,
+		Data: TurnNotification{GameID: game.ID, Player: game.CurrentTurn},
+	})
+}
+
+// NotifyError sends a game-error event to the connections selected by
+// target, typically the one a failed request came from, see ConnHeader.
+func (h *Hub) NotifyError(ctx context.Context, gameID string, target Target, code string, err error) {
+	h.SendTo(ctx, gameID, target, Event{
+		Name: ErrorEvent,
+		Data: ErrorNotice{GameID: gameID, Code: code, Message: err.Error()},
+	})
+}
+
+// wsEvent is how an Event is framed for WebSocket clients. GameID tells
+// the games of a connection subscribed to several apart. Seq is set for
+// events sent to all of the game's clients, see LoggedEvent.
+type wsEvent struct {
+	Type   string `json:"type"`
+	GameID string `json:"gameId,omitempty"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Data   any    `json:"data"`
+}
+
+// gameFrame is how a game state broadcast as seq is framed for a
+// WebSocket client that didn't ask for deltas: as is, or in a wsEvent of
+// type game-update if it asked for envelopes.
+func (sub Subscriber) gameFrame(seq uint64, game *models.GameState) any {
+	if !sub.Envelope {
+		return game
+	}
+	return wsEvent{Type: GameUpdateEvent, GameID: game.ID, Seq: seq, Data: game}
 }
 
-// Broadcast sends a game state update to all connected WebSocket and SSE clients.
-func (h *Hub) Broadcast(gameID string, game *models.GameState) {
+// SendTo sends an event to the game's subscribers selected by target.
+// It only queues the event for the game's dispatcher, see enqueue, so it
+// never waits on clients.
+func (h *Hub) SendTo(ctx context.Context, gameID string, target Target, ev Event) {
+	ctx, span := tracer.Start(ctx, "hub.SendTo")
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("game.id", gameID),
+			attribute.String("event", ev.Name),
+		)
+	}
+
+	h.events.Add(1)
+	now := time.Now()
+	h.firehose.publish(FirehoseEvent{GameID: gameID, Type: ev.Name, Time: now, Data: ev.Data})
+	var seq uint64
+	if target.everyone() {
+		seq = h.eventLogs.record(gameID, LoggedEvent{Type: ev.Name, Time: now, Data: ev.Data})
+	}
+	h.enqueue(gameID, job{event: &ev, target: target, seq: seq, created: now, ctx: context.WithoutCancel(ctx)})
+}
+
+// Broadcast queues a game state update for all connected WebSocket and
+// SSE clients.
+func (h *Hub) Broadcast(ctx context.Context, gameID string, game *models.GameState) {
+	ctx, span := tracer.Start(ctx, "hub.Broadcast")
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(attribute.String("game.id", gameID))
+	}
+
+	h.broadcasts.Add(1)
+	now := time.Now()
+	typ := FirehoseGameUpdate
+	if game.IsOver {
+		typ = FirehoseGameOver
+	}
+	h.firehose.publish(FirehoseEvent{GameID: gameID, Type: typ, Time: now, Game: game})
+	seq := h.eventLogs.record(gameID, LoggedEvent{Type: GameUpdateEvent, Time: now, Game: game})
+	if game.IsOver {
+		h.eventLogs.forget(gameID)
+	}
+
+	// Only games someone is watching are tracked, so the map is cleaned
+	// up along with the game's last client.
 	h.mu.RLock()
-	defer h.mu.RUnlock()
-	for conn := range h.wsClients[gameID] {
-		conn.WriteJSON(game)
+	if len(h.wsClients[gameID])+len(h.sseClients[gameID]) > 0 {
+		h.lastBroadcast.Store(gameID, now)
 	}
-	for ch := range h.sseClients[gameID] {
-		select {
-		case ch <- game:
-		default:
-		}
+	h.mu.RUnlock()
+
+	h.enqueue(gameID, job{game: game, target: ToAll(), seq: seq, created: now, ctx: context.WithoutCancel(ctx)})
+}
+
+// sendSSE queues msg for an SSE client without blocking. A client whose
+// buffer is full misses the message, which is counted as dropped.
+func (h *Hub) sendSSE(ch chan Message, c *client, msg Message) bool {
+	select {
+	case ch <- msg:
+		c.delivered(nil)
+		return true
+	default:
+		h.dropped.Add(1)
+		h.lags.sse.dropped.Add(1)
+		c.delivered(errDropped)
+		return false
 	}
 }
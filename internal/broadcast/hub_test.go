@@ -0,0 +1,170 @@
+package broadcast
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// dial returns the server's and the client's ends of a new WebSocket
+// connection. Both are closed when the test ends.
+func dial(t testing.TB) (server, client *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = <-conns
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client
+}
+
+// returnsWithin reports whether fn returns within d.
+func returnsWithin(d time.Duration, fn func()) bool {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func TestUnsubscribeWritesOutsideTheLock(t *testing.T) {
+	h := NewHub()
+	defer h.Close()
+	slow, _ := dial(t)
+	h.RegisterWS("slow", slow, NewSubscriber(models.PlayerX, "slow"))
+	h.RegisterWS("other", slow, NewSubscriber(models.PlayerX, "slow"))
+
+	// A dispatcher is stuck writing to the slow client
+	writer := h.writer(slow)
+	writer.Lock()
+	unsubscribed := make(chan struct{})
+	go func() {
+		h.Unsubscribe("slow", slow)
+		close(unsubscribed)
+	}()
+
+	// Meanwhile other games' clients come and go
+	other, _ := dial(t)
+	if !returnsWithin(time.Second, func() {
+		h.RegisterWS("fast", other, NewSubscriber(models.PlayerO, "fast"))
+		h.UnregisterConn(other)
+	}) {
+		t.Fatal("registration waited for the slow client's write")
+	}
+	writer.Unlock()
+	<-unsubscribed
+}
+
+func TestUnsubscribeKeepsTheConnectionsWriter(t *testing.T) {
+	h := NewHub()
+	defer h.Close()
+	conn, client := dial(t)
+	h.RegisterWS("game", conn, NewSubscriber(models.PlayerX, "conn"))
+	writer := h.writer(conn)
+
+	// Unsubscribing from its last game must not hand the confirmation a
+	// writer of its own while another write is under way
+	writer.Lock()
+	if returnsWithin(100*time.Millisecond, func() { h.Unsubscribe("game", conn) }) {
+		t.Fatal("the unsubscribed frame was written beside another write")
+	}
+	writer.Unlock()
+
+	var frame wsEvent
+	if err := client.ReadJSON(&frame); err != nil || frame.Type != UnsubscribedFrameType {
+		t.Fatalf("got %+v, %v, want an unsubscribed frame", frame, err)
+	}
+	if h.writer(conn) != writer {
+		t.Error("the connection's writer changed before it was torn down")
+	}
+	h.UnregisterConn(conn)
+	if h.writer(conn) != nil {
+		t.Error("the connection's writer outlived it")
+	}
+}
+
+func TestCloseGameWritesOutsideTheLock(t *testing.T) {
+	h := NewHub()
+	defer h.Close()
+	slow, _ := dial(t)
+	// Subscribed to another game too, so it is sent an unsubscribed frame
+	h.RegisterWS("closing", slow, NewSubscriber(models.PlayerX, "slow"))
+	h.RegisterWS("other", slow, NewSubscriber(models.PlayerX, "slow"))
+
+	writer := h.writer(slow)
+	writer.Lock()
+	closed := make(chan struct{})
+	go func() {
+		h.closeGame("closing", ReasonGameDeleted)
+		close(closed)
+	}()
+
+	other, _ := dial(t)
+	if !returnsWithin(time.Second, func() {
+		h.RegisterWS("fast", other, NewSubscriber(models.PlayerO, "fast"))
+	}) {
+		t.Fatal("registration waited for the closing game's writes")
+	}
+	writer.Unlock()
+	<-closed
+}
+
+func TestSupersedeWritesOutsideTheLock(t *testing.T) {
+	h := NewHub()
+	defer h.Close()
+	slow, client := dial(t)
+	h.RegisterWS("game", slow, NewSubscriber(models.PlayerX, "slow"))
+
+	writer := h.writer(slow)
+	writer.Lock()
+	newer, _ := dial(t)
+	registered := make(chan struct{})
+	go func() {
+		// Takes X over from the slow connection, which is told so
+		h.RegisterWS("game", newer, NewSubscriber(models.PlayerX, "newer"))
+		close(registered)
+	}()
+
+	other, _ := dial(t)
+	if !returnsWithin(time.Second, func() {
+		h.RegisterWS("other", other, NewSubscriber(models.PlayerO, "other"))
+	}) {
+		t.Fatal("registration waited for the superseded client's write")
+	}
+	writer.Unlock()
+	<-registered
+
+	var frame wsEvent
+	if err := client.ReadJSON(&frame); err != nil || frame.Type != SupersededEvent {
+		t.Fatalf("got %+v, %v, want a superseded event", frame, err)
+	}
+	if !h.Superseded("game", slow) {
+		t.Error("the slow connection still controls X")
+	}
+}
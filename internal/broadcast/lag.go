@@ -0,0 +1,102 @@
+package broadcast
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LagBuckets are the upper bounds, in seconds, of the delivery lag
+// histogram: the time from the hub taking an event to it being written
+// to a subscriber, see Hub.RecordDelivery.
+var LagBuckets = [...]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// lagHistogram counts one transport's deliveries by lag and the events
+// it failed to deliver. Sends happen under the hub's read lock, and SSE
+// writes in each stream's own goroutine, so it is updated atomically
+// rather than under any lock.
+type lagHistogram struct {
+	// buckets counts deliveries by the first of LagBuckets their lag is
+	// within, the last one those over all of them
+	buckets [len(LagBuckets) + 1]atomic.Uint64
+	count   atomic.Uint64
+	sumNs   atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func (l *lagHistogram) record(lag time.Duration) {
+	lag = max(lag, 0)
+	i := 0
+	for i < len(LagBuckets) && lag.Seconds() > LagBuckets[i] {
+		i++
+	}
+	l.buckets[i].Add(1)
+	l.count.Add(1)
+	l.sumNs.Add(uint64(lag))
+}
+
+// LagStats summarizes one transport's delivery lag.
+type LagStats struct {
+	// Count is how many events were delivered and Sum their lag added up.
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sumSeconds"`
+	// Buckets counts the deliveries within each of LagBuckets,
+	// cumulatively as in a Prometheus histogram.
+	Buckets []LagBucket `json:"buckets"`
+	// Dropped counts events the transport failed to deliver: those an
+	// SSE client's full buffer missed, or WebSocket writes that failed.
+	Dropped uint64 `json:"dropped"`
+}
+
+// LagBucket is one bucket of LagStats.
+type LagBucket struct {
+	// UpperBound is in seconds.
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+func (l *lagHistogram) stats() LagStats {
+	s := LagStats{
+		Count:   l.count.Load(),
+		Sum:     time.Duration(l.sumNs.Load()).Seconds(),
+		Buckets: make([]LagBucket, len(LagBuckets)),
+		Dropped: l.dropped.Load(),
+	}
+	var n uint64
+	for i, le := range LagBuckets {
+		n += l.buckets[i].Load()
+		s.Buckets[i] = LagBucket{UpperBound: le, Count: n}
+	}
+	return s
+}
+
+// lags holds the delivery lag of each game transport.
+type lags struct {
+	ws, sse lagHistogram
+}
+
+func (l *lags) of(transport Transport) *lagHistogram {
+	if transport == TransportWS {
+		return &l.ws
+	}
+	return &l.sse
+}
+
+// RecordDelivery records that an event the hub took at created has been
+// written to a subscriber over transport.
+func (h *Hub) RecordDelivery(transport Transport, created time.Time) {
+	if created.IsZero() {
+		return
+	}
+	h.lags.of(transport).record(time.Since(created))
+}
+
+// deliveredWS records the outcome of writing an event the hub took at
+// created to a WebSocket client.
+func (h *Hub) deliveredWS(c *client, created time.Time, err error) {
+	c.delivered(err)
+	if err != nil {
+		h.lags.ws.dropped.Add(1)
+		return
+	}
+	h.RecordDelivery(TransportWS, created)
+}
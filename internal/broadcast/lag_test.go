@@ -0,0 +1,61 @@
+package broadcast_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+)
+
+// within returns how many deliveries s counts within le seconds.
+func within(t *testing.T, s broadcast.LagStats, le float64) uint64 {
+	t.Helper()
+	for _, b := range s.Buckets {
+		if b.UpperBound == le {
+			return b.Count
+		}
+	}
+	t.Fatalf("no bucket of %vs in %+v", le, s.Buckets)
+	return 0
+}
+
+// TestDeliveryLag delivers an update to a WebSocket subscriber whose
+// writes stall, an SSE stream slow to write it and one that never
+// reads, and checks each lag lands in the bucket it should.
+func TestDeliveryLag(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	registerStalled(t, h, peer(t), "a")
+	slow := subscribe(t, h, "a", broadcast.NewSubscriber("", "slow"))
+	full := make(chan broadcast.Message)
+	h.RegisterSSE("a", full, broadcast.NewSubscriber("", "full"))
+	defer h.UnregisterSSE("a", full)
+
+	h.Broadcast(ctx, "a", &models.GameState{ID: "a"})
+	msg := next(t, slow)
+	time.Sleep(50 * time.Millisecond)
+	h.RecordDelivery(broadcast.TransportSSE, msg.Created)
+	// Messages the hub didn't time aren't counted
+	h.RecordDelivery(broadcast.TransportSSE, time.Time{})
+
+	deadline := time.Now().Add(2 * stall)
+	for h.Stats().Delivery[broadcast.TransportWS].Count == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	delivery := h.Stats().Delivery
+
+	ws := delivery[broadcast.TransportWS]
+	if ws.Count != 1 || within(t, ws, 0.5) != 0 || within(t, ws, 1) != 1 || ws.Sum < stall.Seconds() {
+		t.Errorf("WebSocket lag %+v, want one delivery after the %v stall", ws, stall)
+	}
+	sse := delivery[broadcast.TransportSSE]
+	if sse.Count != 1 || within(t, sse, 0.025) != 0 || within(t, sse, 0.5) != 1 {
+		t.Errorf("SSE lag %+v, want one delivery after 50ms", sse)
+	}
+	if sse.Dropped != 1 || ws.Dropped != 0 {
+		t.Errorf("dropped %d SSE and %d WebSocket, want the 1 the stream that never reads missed", sse.Dropped, ws.Dropped)
+	}
+}
@@ -0,0 +1,13 @@
+package broadcast_test
+
+import (
+	"testing"
+
+	"go.uber.org/goleak"
+)
+
+// TestMain fails the package if a test leaves a dispatcher, outbox
+// writer or connection running.
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
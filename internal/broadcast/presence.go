@@ -0,0 +1,138 @@
+package broadcast
+
+import (
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// PresenceFunc is called when a player's first connection to a game
+// opens or their last one closes.
+type PresenceFunc func(gameID string, player models.Player, online bool)
+
+// presence counts each player's connections to each game, remembering
+// since when a player without any has been away. Games are remembered
+// until Forget, since being away long enough is the point.
+type presence struct {
+	mu      sync.Mutex
+	clock   game.Clock
+	started time.Time
+	games   map[string]*[2]seen
+	fns     []PresenceFunc
+}
+
+// seen is one player's presence in a game.
+type seen struct {
+	conns int
+	since time.Time
+}
+
+func newPresence() presence {
+	return presence{clock: wallClock{}, started: time.Now(), games: make(map[string]*[2]seen)}
+}
+
+// wallClock reads the wall clock.
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+// SetClock sets the clock players' presence is timed by, which should
+// be the one the game service decides claims by, see game.WithClock.
+// The wall clock unless set. Set it before serving clients.
+func (h *Hub) SetClock(clock game.Clock) {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+	h.presence.clock = clock
+	h.presence.started = clock.Now()
+}
+
+// slot returns the index of player's seen in a game, or -1 for anyone
+// but X and O.
+func slot(player models.Player) int {
+	switch player {
+	case models.PlayerX:
+		return 0
+	case models.PlayerO:
+		return 1
+	}
+	return -1
+}
+
+// connected records a connection of sub opening (delta 1) or closing
+// (-1) and returns the callbacks to run if that changed whether its
+// player is online. They are run by the caller, outside the hub's lock.
+func (p *presence) connected(gameID string, sub Subscriber, delta int) []PresenceFunc {
+	i := slot(sub.Player)
+	if sub.Role != RolePlayer || i < 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	g := p.games[gameID]
+	if g == nil {
+		// A connection closing after Forget has nothing left to update
+		if delta < 0 {
+			return nil
+		}
+		g = &[2]seen{{since: p.started}, {since: p.started}}
+		p.games[gameID] = g
+	}
+	s := &g[i]
+	if s.conns+delta < 0 {
+		return nil
+	}
+	s.conns += delta
+	switch {
+	case delta > 0 && s.conns == 1:
+	case delta < 0 && s.conns == 0:
+		s.since = p.clock.Now()
+	default:
+		return nil
+	}
+	return p.fns
+}
+
+// OnPresence registers fn to be called whenever a player comes online
+// in a game or leaves it. Register callbacks before serving clients.
+func (h *Hub) OnPresence(fn PresenceFunc) {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+	h.presence.fns = append(h.presence.fns, fn)
+}
+
+// OfflineSince reports whether player has no connection to the game and,
+// if so, since when: when the last one closed, or when the hub started
+// for a player it has never seen connect.
+func (h *Hub) OfflineSince(gameID string, player models.Player) (since time.Time, offline bool) {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+	i := slot(player)
+	if i < 0 {
+		return time.Time{}, false
+	}
+	g := h.presence.games[gameID]
+	if g == nil {
+		return h.presence.started, true
+	}
+	return g[i].since, g[i].conns == 0
+}
+
+// Forget drops what the hub remembers about the game's players, once it
+// no longer matters whether they are around, such as when it is over.
+// A player who connects again is tracked afresh.
+func (h *Hub) Forget(gameID string) {
+	h.presence.mu.Lock()
+	defer h.presence.mu.Unlock()
+	delete(h.presence.games, gameID)
+}
+
+// notifyPresence runs the callbacks returned by presence.connected.
+func notifyPresence(fns []PresenceFunc, gameID string, player models.Player, online bool) {
+	for _, fn := range fns {
+		fn(gameID, player, online)
+	}
+}
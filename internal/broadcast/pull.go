@@ -0,0 +1,97 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// HistoryFrameType is the type of the frame answering a WebSocket
+// client's request for a game's moves, see Pull.
+const HistoryFrameType = "history"
+
+// HistoryFrame carries a game's moves from the one numbered From, from
+// zero, on. Total is how many moves the game has, so a client polling
+// for new ones asks from there next time. Seq is the number of the
+// game's last event, as in a state frame.
+type HistoryFrame struct {
+	Type   string              `json:"type"`
+	GameID string              `json:"gameId"`
+	Seq    uint64              `json:"seq,omitempty"`
+	From   int                 `json:"from"`
+	Total  int                 `json:"total"`
+	Moves  []models.MoveRecord `json:"moves"`
+}
+
+// Pull is a WebSocket client's request for a game as it is now, rather
+// than as it is next broadcast.
+type Pull struct {
+	// Current returns the game's state, or nil once it is gone.
+	Current func() *models.GameState
+	// History asks for the game's moves from From on instead of its
+	// state.
+	History bool
+	From    int
+}
+
+// Pull answers a WebSocket client's request for a game's state or its
+// history, queued behind what has been queued for the game already.
+func (h *Hub) Pull(ctx context.Context, gameID string, conn *websocket.Conn, p Pull) {
+	h.enqueue(gameID, job{
+		pull:    &p,
+		pullTo:  conn,
+		created: time.Now(),
+		ctx:     context.WithoutCancel(ctx),
+	})
+}
+
+// deliverPull answers the Pull of j, if its connection is still
+// registered for the game.
+func (h *Hub) deliverPull(gameID string, j job) {
+	_, span := tracer.Start(j.ctx, "hub.Pull.dispatch")
+	defer span.End()
+
+	// The event number is read before the state, so the state is at
+	// least as new as the event
+	seq := h.eventLogs.seq(gameID)
+	game := j.pull.Current()
+	if game == nil {
+		return
+	}
+
+	h.mu.RLock()
+	c, ok := h.wsClients[gameID][j.pullTo]
+	if !ok {
+		h.mu.RUnlock()
+		return
+	}
+	var frame any
+	switch {
+	case j.pull.History:
+		from := min(j.pull.From, len(game.History))
+		moves := game.History[from:]
+		if moves == nil {
+			moves = []models.MoveRecord{}
+		}
+		frame = HistoryFrame{
+			Type:   HistoryFrameType,
+			GameID: gameID,
+			Seq:    seq,
+			From:   from,
+			Total:  len(game.History),
+			Moves:  moves,
+		}
+	case !c.sub.Delta:
+		frame = c.sub.gameFrame(seq, game)
+	default:
+		version := h.versions.record(gameID, game)
+		c.acked.Store(version)
+		frame = StateFrame{Type: StateFrameType, GameID: gameID, Seq: seq, Version: version, Game: game}
+	}
+	send := h.newSend(j.pullTo, c, frame)
+	h.mu.RUnlock()
+	h.post([]wsSend{send})
+}
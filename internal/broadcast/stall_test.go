@@ -0,0 +1,297 @@
+package broadcast_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/goleak"
+)
+
+// stall is how long each write to a stalled subscriber takes.
+const stall = 500 * time.Millisecond
+
+// stallingConn is a connection every write to which takes stall once
+// stalling is set, as to a peer whose TCP window has closed.
+type stallingConn struct {
+	net.Conn
+	stalling atomic.Bool
+	closed   atomic.Bool
+}
+
+func (c *stallingConn) Write(p []byte) (int, error) {
+	if c.stalling.Load() {
+		time.Sleep(stall)
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *stallingConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+// peer starts a WebSocket server that reads everything sent to it, and
+// returns its URL.
+func peer(t testing.TB) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// registerStalled registers a connection to url with hub as a spectator
+// of the game, whose writes all stall once it is registered.
+func registerStalled(t testing.TB, hub *broadcast.Hub, url, gameID string) *stallingConn {
+	t.Helper()
+	var sc *stallingConn
+	dialer := websocket.Dialer{NetDial: func(network, addr string) (net.Conn, error) {
+		c, err := net.Dial(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		sc = &stallingConn{Conn: c}
+		return sc, nil
+	}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		hub.UnregisterConn(conn)
+		conn.Close()
+	})
+	sc.stalling.Store(true)
+	hub.RegisterWS(gameID, conn, broadcast.NewSubscriber("", "stalled"))
+	return sc
+}
+
+// watcher is a WebSocket client of a game, reading the versions of the
+// game it is sent.
+type watcher struct {
+	versions chan uint64
+}
+
+// watch connects a watcher to the game's WebSocket on the server at url.
+func watch(t testing.TB, url, gameID string) *watcher {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws/"+gameID+"?envelope=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	w := &watcher{versions: make(chan uint64, 1024)}
+	go func() {
+		for {
+			var frame struct {
+				Type string
+				Data json.RawMessage
+			}
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			var g models.GameState
+			if frame.Type == broadcast.GameUpdateEvent && json.Unmarshal(frame.Data, &g) == nil {
+				w.versions <- g.Version
+			}
+		}
+	}()
+	return w
+}
+
+// await waits for the watcher to be sent version, or a later one, and
+// reports whether it was within timeout.
+func (w *watcher) await(version uint64, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case v := <-w.versions:
+			if v >= version {
+				return true
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// TestStalledSubscriberHoldsUpNobody broadcasts to a game one of whose
+// subscribers takes stall over every write. The others must get each
+// update straight away, and Broadcast must return straight away.
+func TestStalledSubscriberHoldsUpNobody(t *testing.T) {
+	games := game.NewService()
+	t.Cleanup(func() { games.Close() })
+	hub := broadcast.NewHub()
+	t.Cleanup(hub.Close)
+	srv := httptest.NewServer(server.NewMux(server.Deps{Games: games, Hub: hub}))
+	t.Cleanup(srv.Close)
+
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerStalled(t, hub, peer(t), g.ID)
+	watchers := []*watcher{watch(t, srv.URL, g.ID), watch(t, srv.URL, g.ID)}
+	// Let the watchers' registration, and the state sent on it, go first
+	for _, w := range watchers {
+		w.await(g.Version, time.Second)
+	}
+
+	for i := range 3 {
+		g = g.Clone()
+		g.Version++
+		start := time.Now()
+		hub.Broadcast(t.Context(), g.ID, g)
+		if took := time.Since(start); took > stall/5 {
+			t.Errorf("broadcast %d took %v", i, took)
+		}
+		for j, w := range watchers {
+			if !w.await(g.Version, stall/2) {
+				t.Fatalf("watcher %d waited on the stalled subscriber for broadcast %d", j, i)
+			}
+		}
+	}
+}
+
+// BenchmarkMoveWithStalledSubscriber times moves made over the JSON API
+// in a game with 8 watchers, and how long after each move began they
+// all had it, with and without a ninth subscriber taking stall over
+// every write. It reports the 99th percentiles, which should be the same
+// either way:
+//
+//	go test ./internal/broadcast -run '^$' -bench Stalled -benchtime 200x
+func BenchmarkMoveWithStalledSubscriber(b *testing.B) {
+	for _, stalled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("stalled=%v", stalled), func(b *testing.B) {
+			benchmarkMoves(b, stalled)
+		})
+	}
+}
+
+func benchmarkMoves(b *testing.B, stalled bool) {
+	const watchers = 8
+	games := game.NewService()
+	b.Cleanup(func() { games.Close() })
+	hub := broadcast.NewHub()
+	srv := httptest.NewServer(server.NewMux(server.Deps{Games: games, Hub: hub}))
+	b.Cleanup(srv.Close)
+
+	g, err := games.CreateGame(b.Context(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var ws []*watcher
+	for range watchers {
+		w := watch(b, srv.URL, g.ID)
+		w.await(g.Version, time.Second)
+		ws = append(ws, w)
+	}
+	peerURL := peer(b)
+	var slow *stallingConn
+	if stalled {
+		slow = registerStalled(b, hub, peerURL, g.ID)
+	}
+
+	// A drawn game, played over and over, reset in between
+	draw := []int{0, 1, 2, 4, 3, 5, 7, 6, 8}
+	moves := make([]time.Duration, 0, b.N)
+	fanout := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := range b.N {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/game/"+g.ID, nil)
+		if n := i % (len(draw) + 1); n < len(draw) {
+			body := fmt.Sprintf(`{"player":%q,"position":%d}`, []models.Player{models.PlayerX, models.PlayerO}[n%2], draw[n])
+			req, _ = http.NewRequest(http.MethodPost, srv.URL+"/api/game/"+g.ID, strings.NewReader(body))
+		}
+		start := time.Now()
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var moved models.GameState
+		err = json.NewDecoder(res.Body).Decode(&moved)
+		res.Body.Close()
+		if err != nil || res.StatusCode != http.StatusOK {
+			b.Fatalf("request %d: %s, %v", i, res.Status, err)
+		}
+		moves = append(moves, time.Since(start))
+		for j, w := range ws {
+			if !w.await(moved.Version, 5*time.Second) {
+				b.Fatalf("watcher %d never got version %d", j, moved.Version)
+			}
+		}
+		fanout = append(fanout, time.Since(start))
+
+		// A subscriber that falls too far behind is disconnected, so
+		// stand up another to keep one stalled throughout
+		if stalled && slow.closed.Load() {
+			b.StopTimer()
+			slow = registerStalled(b, hub, peerURL, g.ID)
+			b.StartTimer()
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(p99(moves), "move-p99-ms")
+	b.ReportMetric(p99(fanout), "fanout-p99-ms")
+}
+
+// p99 returns the 99th percentile of ds in milliseconds.
+func p99(ds []time.Duration) float64 {
+	slices.Sort(ds)
+	return float64(ds[len(ds)*99/100]) / float64(time.Millisecond)
+}
+
+// TestCloseLeavesNoGoroutines closes a hub with dispatchers running and
+// a stalled subscriber's outbox still being written, as shutdown does,
+// and checks neither is left behind.
+func TestCloseLeavesNoGoroutines(t *testing.T) {
+	// Checked once the peers have closed too
+	running := goleak.IgnoreCurrent()
+	t.Cleanup(func() { goleak.VerifyNone(t, running) })
+	hub := broadcast.NewHub()
+	url := peer(t)
+	for i := range 3 {
+		id := fmt.Sprint("game-", i)
+		registerStalled(t, hub, url, id)
+		g := models.NewGameState(id)
+		for range 3 {
+			g = g.Clone()
+			g.Version++
+			hub.Broadcast(t.Context(), id, g)
+		}
+	}
+
+	// CloseAll writes to each in turn, waiting behind the write under
+	// way, so it is the writes left after it that Close must not wait on
+	hub.CloseAll(broadcast.ReasonShutdown)
+	start := time.Now()
+	hub.Close()
+	if took := time.Since(start); took > stall {
+		t.Errorf("Close took %v, waiting on the stalled subscribers' writes", took)
+	}
+	// Nothing is sent once the hub is closed
+	hub.Broadcast(t.Context(), "game-0", models.NewGameState("game-0"))
+}
@@ -0,0 +1,265 @@
+package broadcast
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Transport names the kind of connection a subscriber uses.
+type Transport string
+
+const (
+	TransportWS  Transport = "ws"
+	TransportSSE Transport = "sse"
+	// TransportFirehose counts subscribers to every game, see
+	// Hub.SubscribeAll. They don't appear under any one game.
+	TransportFirehose Transport = "firehose"
+)
+
+// errDropped is recorded for a message dropped for a client: an SSE
+// client whose buffer was full, or a WebSocket client whose outbox was,
+// see post, or whose connection had failed.
+var errDropped = errors.New("message dropped: client buffer full")
+
+// client is a registered connection along with its delivery diagnostics.
+type client struct {
+	sub         Subscriber
+	connectedAt time.Time
+	sent        atomic.Uint64
+	// acked is the last version a delta client acknowledged.
+	acked atomic.Uint64
+	// superseded is set, under the hub's lock, once another connection
+	// has taken the client's player slot over
+	superseded bool
+	// joined is the number of the last job queued when the client was
+	// registered, see job.n
+	joined uint64
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func newClient(sub Subscriber) *client {
+	return &client{sub: sub, connectedAt: time.Now()}
+}
+
+// delivered records the outcome of one send to the client.
+func (c *client) delivered(err error) {
+	if err != nil {
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
+		return
+	}
+	c.sent.Add(1)
+}
+
+func (c *client) stats(transport Transport) SubscriberStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := SubscriberStats{
+		ConnID:      c.sub.ConnID,
+		Transport:   transport,
+		Player:      c.sub.Player,
+		Spectator:   c.sub.Role == RoleSpectator,
+		Delta:       c.sub.Delta,
+		ConnectedAt: c.connectedAt,
+		Delivered:   c.sent.Load(),
+	}
+	if c.lastErr != nil {
+		s.LastError = c.lastErr.Error()
+	}
+	return s
+}
+
+// HubStats is a point-in-time view of the hub.
+type HubStats struct {
+	// Broadcasts counts game state updates sent with Broadcast.
+	Broadcasts uint64 `json:"broadcasts"`
+	// Events counts targeted events sent with SendTo.
+	Events uint64 `json:"events"`
+	// Dropped counts messages an SSE client missed because its buffer
+	// was full.
+	Dropped uint64 `json:"dropped"`
+	// FirehoseDisconnects counts firehose subscribers cut off for
+	// falling behind.
+	FirehoseDisconnects uint64 `json:"firehoseDisconnects"`
+	// PolicyDisconnects counts WebSocket clients disconnected for
+	// flooding or sending oversized messages.
+	PolicyDisconnects uint64 `json:"policyDisconnects"`
+	// Queued counts sends waiting for their games' dispatchers, and
+	// Coalesced those dropped from a full queue, see QueueSize.
+	Queued    int    `json:"queued"`
+	Coalesced uint64 `json:"coalesced"`
+	// Subscribers counts connected clients by transport.
+	Subscribers map[Transport]int `json:"subscribers"`
+	// Delivery is the delivery lag of game events by transport, see
+	// Hub.RecordDelivery.
+	Delivery map[Transport]LagStats `json:"delivery"`
+	// Games lists every game with at least one client, by ID.
+	Games []GameStats `json:"games"`
+	// Retained counts the games each per-game index holds an entry for.
+	Retained map[Index]int `json:"retained"`
+}
+
+// Index names one of the hub's per-game indexes in HubStats.Retained.
+type Index string
+
+const (
+	// IndexClients holds the games with connected clients, see
+	// Hub.GameCount.
+	IndexClients Index = "clients"
+	// IndexPresence holds whether each game's players are connected,
+	// see Hub.OfflineSince.
+	IndexPresence Index = "presence"
+	// IndexEventLogs holds each game's latest events, see
+	// Hub.EventsSince.
+	IndexEventLogs Index = "eventLogs"
+	// IndexVersions holds the states delta clients are sent diffs of.
+	IndexVersions Index = "versions"
+	// IndexQueues holds the sends waiting for each game's dispatcher.
+	IndexQueues Index = "queues"
+	// IndexLastBroadcast holds when each watched game was last
+	// broadcast.
+	IndexLastBroadcast Index = "lastBroadcast"
+)
+
+// GameStats describes the clients of one game.
+type GameStats struct {
+	GameID        string            `json:"gameId"`
+	Subscribers   map[Transport]int `json:"subscribers"`
+	LastBroadcast time.Time         `json:"lastBroadcast,omitzero"`
+}
+
+// SubscriberStats describes one connection to a game.
+type SubscriberStats struct {
+	ConnID      string        `json:"connId"`
+	Transport   Transport     `json:"transport"`
+	Player      models.Player `json:"player,omitempty"`
+	Spectator   bool          `json:"spectator"`
+	Delta       bool          `json:"delta,omitempty"`
+	ConnectedAt time.Time     `json:"connectedAt"`
+	// Delivered counts messages written to a WebSocket or queued for an
+	// SSE stream.
+	Delivered uint64 `json:"delivered"`
+	// LastError is the most recent failed or dropped send, if any.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Stats returns the hub's counters and a summary of every game with
+// connected clients.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := HubStats{
+		Broadcasts:  h.broadcasts.Load(),
+		Events:      h.events.Load(),
+		Dropped:     h.dropped.Load(),
+		Subscribers: map[Transport]int{TransportWS: 0, TransportSSE: 0, TransportFirehose: h.firehose.count()},
+		Delivery:    map[Transport]LagStats{TransportWS: h.lags.ws.stats(), TransportSSE: h.lags.sse.stats()},
+
+		FirehoseDisconnects: h.firehose.disconnects.Load(),
+		PolicyDisconnects:   h.policy.Load(),
+		Queued:              h.Queued(),
+		Coalesced:           h.coalesced.Load(),
+	}
+	games := make(map[string]*GameStats)
+	game := func(id string) *GameStats {
+		g, ok := games[id]
+		if !ok {
+			g = &GameStats{GameID: id, Subscribers: map[Transport]int{TransportWS: 0, TransportSSE: 0}}
+			if t, ok := h.lastBroadcast.Load(id); ok {
+				g.LastBroadcast = t.(time.Time)
+			}
+			games[id] = g
+		}
+		return g
+	}
+	for id, clients := range h.wsClients {
+		game(id).Subscribers[TransportWS] = len(clients)
+		stats.Subscribers[TransportWS] += len(clients)
+	}
+	for id, clients := range h.sseClients {
+		game(id).Subscribers[TransportSSE] = len(clients)
+		stats.Subscribers[TransportSSE] += len(clients)
+	}
+
+	stats.Retained = h.retained()
+	stats.Retained[IndexClients] = len(games)
+
+	stats.Games = make([]GameStats, 0, len(games))
+	for _, g := range games {
+		stats.Games = append(stats.Games, *g)
+	}
+	sort.Slice(stats.Games, func(i, j int) bool {
+		return stats.Games[i].GameID < stats.Games[j].GameID
+	})
+	return stats
+}
+
+// GameCount returns how many games have at least one connected client.
+func (h *Hub) GameCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := len(h.wsClients)
+	for id := range h.sseClients {
+		if _, ok := h.wsClients[id]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// retained counts the entries of the per-game indexes other than the
+// clients', for HubStats.Retained.
+func (h *Hub) retained() map[Index]int {
+	lastBroadcast := 0
+	h.lastBroadcast.Range(func(any, any) bool {
+		lastBroadcast++
+		return true
+	})
+	h.queueMu.Lock()
+	queues := len(h.queues)
+	h.queueMu.Unlock()
+	h.presence.mu.Lock()
+	presence := len(h.presence.games)
+	h.presence.mu.Unlock()
+	h.eventLogs.mu.Lock()
+	eventLogs := len(h.eventLogs.games)
+	h.eventLogs.mu.Unlock()
+	h.versions.mu.Lock()
+	versions := len(h.versions.games)
+	h.versions.mu.Unlock()
+	return map[Index]int{
+		IndexPresence:      presence,
+		IndexEventLogs:     eventLogs,
+		IndexVersions:      versions,
+		IndexQueues:        queues,
+		IndexLastBroadcast: lastBroadcast,
+	}
+}
+
+// Subscribers returns diagnostics for each of the game's connections,
+// oldest first.
+func (h *Hub) Subscribers(gameID string) []SubscriberStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	subs := make([]SubscriberStats, 0, len(h.wsClients[gameID])+len(h.sseClients[gameID]))
+	for _, c := range h.wsClients[gameID] {
+		subs = append(subs, c.stats(TransportWS))
+	}
+	for _, c := range h.sseClients[gameID] {
+		subs = append(subs, c.stats(TransportSSE))
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].ConnectedAt.Before(subs[j].ConnectedAt)
+	})
+	return subs
+}
@@ -0,0 +1,170 @@
+package broadcast_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	x := subscribe(t, h, "a", broadcast.NewSubscriber(models.PlayerX, "x"))
+	subscribe(t, h, "a", broadcast.NewSubscriber("", "s"))
+	// A stream that never reads, whose buffer of one fills at once
+	full := make(chan broadcast.Message, 1)
+	h.RegisterSSE("b", full, broadcast.NewSubscriber(models.PlayerO, "full"))
+	defer h.UnregisterSSE("b", full)
+
+	before := time.Now()
+	for range 3 {
+		h.Broadcast(ctx, "a", &models.GameState{ID: "a"})
+		h.Broadcast(ctx, "b", &models.GameState{ID: "b"})
+	}
+	h.SendTo(ctx, "a", broadcast.ToConn("x"), broadcast.Event{Name: "ping"})
+	for range 4 {
+		next(t, x)
+	}
+	// Deliveries are counted as the dispatchers make them
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if h.Stats().Dropped == 2 && byConn(h.Subscribers("a"))["s"].Delivered == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stats := h.Stats()
+	if stats.Broadcasts != 6 || stats.Events != 1 {
+		t.Errorf("%d broadcasts and %d events, want 6 and 1", stats.Broadcasts, stats.Events)
+	}
+	if stats.Dropped != 2 {
+		t.Errorf("%d dropped, want the 2 the full stream missed", stats.Dropped)
+	}
+	if stats.Subscribers[broadcast.TransportSSE] != 3 || stats.Subscribers[broadcast.TransportWS] != 0 {
+		t.Errorf("subscribers %v", stats.Subscribers)
+	}
+	if len(stats.Games) != 2 || stats.Games[0].GameID != "a" || stats.Games[0].Subscribers[broadcast.TransportSSE] != 2 {
+		t.Fatalf("games %+v", stats.Games)
+	}
+	if last := stats.Games[1].LastBroadcast; last.Before(before) {
+		t.Errorf("b last broadcast at %v, before the test started", last)
+	}
+
+	subs := byConn(h.Subscribers("a"))
+	if len(subs) != 2 {
+		t.Fatalf("a's subscribers %+v, want x and s", subs)
+	}
+	if x := subs["x"]; x.Delivered != 4 || x.Player != models.PlayerX || x.Spectator || x.Transport != broadcast.TransportSSE {
+		t.Errorf("x: %+v", x)
+	}
+	if s := subs["s"]; s.Delivered != 3 || s.Player != "" || !s.Spectator || s.ConnectedAt.IsZero() {
+		t.Errorf("s: %+v", s)
+	}
+	if subs := h.Subscribers("b"); len(subs) != 1 || subs[0].Delivered != 1 || subs[0].LastError == "" {
+		t.Errorf("b's subscribers %+v, want one delivery and an error", subs)
+	}
+}
+
+// TestRetainedAfterChurn subscribes to and leaves 10k games, over SSE
+// and now and then a delta WebSocket, and checks every per-game index
+// the hub keeps is empty again once the games are over.
+func TestRetainedAfterChurn(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	url := peer(t)
+
+	for i := range 10_000 {
+		id := fmt.Sprintf("g%d", i)
+		x := make(chan broadcast.Message, 4)
+		h.RegisterSSE(id, x, broadcast.NewSubscriber(models.PlayerX, "x"))
+		s := make(chan broadcast.Message, 4)
+		h.RegisterSSE(id, s, broadcast.NewSubscriber("", "s"))
+		var conn *websocket.Conn
+		if i%100 == 0 {
+			var err error
+			if conn, _, err = websocket.DefaultDialer.Dial(url, nil); err != nil {
+				t.Fatal(err)
+			}
+			sub := broadcast.NewSubscriber(models.PlayerO, "o")
+			sub.Delta = true
+			h.RegisterWS(id, conn, sub)
+		}
+
+		h.Broadcast(ctx, id, &models.GameState{ID: id, Version: 1})
+		h.SendTo(ctx, id, broadcast.ToSpectators(), broadcast.Event{Name: "ping"})
+		h.UnregisterSSE(id, x)
+		h.UnregisterSSE(id, s)
+		if conn != nil {
+			h.UnregisterConn(conn)
+			conn.Close()
+		}
+		// As the server does when a game ends, some deleted instead
+		if i%2 == 0 {
+			h.Broadcast(ctx, id, &models.GameState{ID: id, Version: 2, IsOver: true})
+		} else {
+			h.CloseGame(id, broadcast.ReasonGameDeleted)
+		}
+		h.Forget(id)
+	}
+
+	if n := h.GameCount(); n != 0 {
+		t.Errorf("GameCount = %d after every client left", n)
+	}
+	retained := h.Stats().Retained
+	for index, n := range retained {
+		// Queues go with their dispatchers, which idle out or stop
+		// with the hub
+		if index != broadcast.IndexQueues && n != 0 {
+			t.Errorf("%s holds %d games after every client left", index, n)
+		}
+	}
+	h.Close()
+	if n := h.Stats().Retained[broadcast.IndexQueues]; n != 0 {
+		t.Errorf("%s holds %d games once the hub is closed", broadcast.IndexQueues, n)
+	}
+}
+
+// byConn maps subs by connection ID.
+func byConn(subs []broadcast.SubscriberStats) map[string]broadcast.SubscriberStats {
+	m := make(map[string]broadcast.SubscriberStats)
+	for _, s := range subs {
+		m[s.ConnID] = s
+	}
+	return m
+}
+
+// BenchmarkBroadcastWithStats broadcasts to a watched game while the
+// hub's stats are read, as when a scrape lands mid-game, so the cost
+// the counters add to the broadcast path shows against
+// BenchmarkMoveWithStalledSubscriber.
+func BenchmarkBroadcastWithStats(b *testing.B) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	ch := make(chan broadcast.Message, broadcast.QueueSize)
+	h.RegisterSSE("game", ch, broadcast.NewSubscriber(models.PlayerX, "x"))
+	defer h.UnregisterSSE("game", ch)
+	go func() {
+		for range ch {
+		}
+	}()
+
+	b.RunParallel(func(pb *testing.PB) {
+		g := &models.GameState{ID: "game"}
+		for i := 0; pb.Next(); i++ {
+			if i%100 == 0 {
+				h.Stats()
+			}
+			h.Broadcast(ctx, "game", g)
+		}
+	})
+}
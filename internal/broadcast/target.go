@@ -0,0 +1,230 @@
+package broadcast
+
+import (
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Role is how a subscriber takes part in a game
+type Role int
+
+const (
+	// RoleSpectator watches a game without a player slot.
+	RoleSpectator Role = iota
+	// RolePlayer is connected as X or O.
+	RolePlayer
+)
+
+// Subscriber describes a connection registered with the hub.
+type Subscriber struct {
+	// Player is the slot the connection plays, empty for spectators.
+	Player models.Player
+	Role   Role
+	// ConnID identifies the connection, see logging.WithConnID.
+	ConnID string
+	// Delta is set for WebSocket clients that asked to be sent board
+	// diffs instead of whole states, see DeltaFrame.
+	Delta bool
+	// Envelope is set for WebSocket clients that asked to get game
+	// states as {"type": "game-update", "seq": N, "data": game} rather
+	// than bare, so they can see each broadcast's number.
+	Envelope bool
+	// NoAnalysis is set for connections that opted out of live
+	// analysis, typically players who don't want hints.
+	NoAnalysis bool
+	// Commentary is set for players who opted in to a game's
+	// commentary, which spectators always get, see ToCommentaryViewers.
+	Commentary bool
+}
+
+// NewSubscriber describes a connection for the given player slot. Any
+// value other than X or O makes it a spectator.
+func NewSubscriber(player models.Player, connID string) Subscriber {
+	if player != models.PlayerX && player != models.PlayerO {
+		return Subscriber{Role: RoleSpectator, ConnID: connID}
+	}
+	return Subscriber{Player: player, Role: RolePlayer, ConnID: connID}
+}
+
+// Target selects which of a game's subscribers an event goes to.
+type Target struct {
+	kind   targetKind
+	player models.Player
+	connID string
+	except string
+}
+
+type targetKind int
+
+const (
+	targetPlayer targetKind = iota + 1
+	targetSpectators
+	targetConn
+	targetAnalysis
+	targetCommentary
+	targetAll
+)
+
+// ToPlayer targets every connection of one player.
+func ToPlayer(p models.Player) Target {
+	return Target{kind: targetPlayer, player: p}
+}
+
+// ToSpectators targets every spectator of the game.
+func ToSpectators() Target {
+	return Target{kind: targetSpectators}
+}
+
+// ToConn targets a single connection.
+func ToConn(connID string) Target {
+	return Target{kind: targetConn, connID: connID}
+}
+
+// ToAnalysisViewers targets every connection that hasn't opted out of
+// live analysis.
+func ToAnalysisViewers() Target {
+	return Target{kind: targetAnalysis}
+}
+
+// ToCommentaryViewers targets every connection that gets the game's
+// commentary: spectators, and players who opted in.
+func ToCommentaryViewers() Target {
+	return Target{kind: targetCommentary}
+}
+
+// ToAll targets every connection to the game.
+func ToAll() Target {
+	return Target{kind: targetAll}
+}
+
+// Except leaves the given connection out of the target.
+func (t Target) Except(connID string) Target {
+	t.except = connID
+	return t
+}
+
+// everyone reports whether the target selects every connection to the
+// game, so that what is sent to it is numbered, see LoggedEvent.
+func (t Target) everyone() bool {
+	return t.kind == targetAll && t.except == ""
+}
+
+// matches reports whether sub is selected by the target.
+func (t Target) matches(sub Subscriber) bool {
+	if t.except != "" && sub.ConnID == t.except {
+		return false
+	}
+	switch t.kind {
+	case targetPlayer:
+		return sub.Role == RolePlayer && sub.Player == t.player
+	case targetSpectators:
+		return sub.Role == RoleSpectator
+	case targetConn:
+		return sub.ConnID == t.connID
+	case targetAnalysis:
+		return !sub.NoAnalysis
+	case targetCommentary:
+		return sub.Role == RoleSpectator || sub.Commentary
+	case targetAll:
+		return true
+	}
+	return false
+}
+
+// Event is a named message for some of a game's subscribers, as opposed
+// to the game state every subscriber receives from Broadcast. SSE
+// clients get Data rendered into an event of that Name; WebSocket clients
+// get {"type": Name, "data": Data}.
+type Event struct {
+	Name string
+	Data any
+}
+
+// Message is delivered to SSE subscribers: either a game state from
+// Broadcast or a targeted Event. Seq is 0 for an event sent to some only.
+type Message struct {
+	Game    *models.GameState
+	Event   *Event
+	Seq     uint64
+	Created time.Time
+}
+
+// TurnNotificationEvent is sent to the player whose turn it is after each
+// move, so clients can alert a player who isn't looking.
+const TurnNotificationEvent = "turn-notification"
+
+// TurnNotification is the data of a turn-notification event.
+type TurnNotification struct {
+	GameID string        `json:"gameId"`
+	Player models.Player `json:"player"`
+}
+
+// ErrorEvent is sent to the view a rejected request was made from, so
+// views driven by SSE or WebSocket updates can tell why. It isn't named
+// "error" because EventSource uses that for connection failures.
+const ErrorEvent = "game-error"
+
+// ConnHeader names the request header an HTTP request made from a live
+// view of a game carries the view's connection ID in, see Welcome, so
+// an ErrorEvent for it goes to that view alone.
+const ConnHeader = "X-Conn-ID"
+
+// ErrorNotice is the data of a game-error event. Code is stable, see
+// game.Code, and empty for unexpected errors.
+type ErrorNotice struct {
+	GameID  string `json:"gameId"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// AnalysisEvent carries the evaluation of a game created with live
+// analysis after each move. It is computed after the move has been
+// broadcast, so it trails the game-update it belongs to.
+const AnalysisEvent = "analysis-update"
+
+// AnalysisUpdate is the data of an analysis-update event. Moves is the
+// number of moves in the evaluated position, so clients can ignore an
+// evaluation for a position they have already moved past.
+type AnalysisUpdate struct {
+	GameID     string            `json:"gameId"`
+	Moves      int               `json:"moves"`
+	Evaluation models.Evaluation `json:"evaluation"`
+}
+
+// CommentaryEvent carries each line of a game's commentary as its
+// commentator writes it, see package commentary.
+const CommentaryEvent = "commentary"
+
+// CommentaryLine is the data of a commentary event.
+type CommentaryLine struct {
+	GameID string `json:"gameId"`
+	models.Commentary
+}
+
+// ClaimEvent tells a player whether their opponent has left and when
+// they may claim the win for it, see game.Service.ClaimWin. It is sent to
+// both players whenever either comes or goes.
+const ClaimEvent = "claim-update"
+
+// ClaimNotice is the data of a claim-update event. At is when Player may
+// claim the win, zero while there is none to claim.
+type ClaimNotice struct {
+	GameID     string        `json:"gameId"`
+	Player     models.Player `json:"player"`
+	At         time.Time     `json:"at,omitzero"`
+	ServerTime time.Time     `json:"serverTime,omitzero"`
+}
+
+// CountdownEvent counts down to the start of a game with a ready check
+// once both players are ready, see game.WithCountdown. It is sent to
+// everyone watching the game each second, ending with zero seconds.
+const CountdownEvent = "countdown"
+
+// Countdown is the data of a countdown event. ServerTime is when it was
+// sent, by the server's clock, which the game's startsAt is by too.
+type Countdown struct {
+	GameID     string    `json:"gameId"`
+	Seconds    int       `json:"seconds"`
+	ServerTime time.Time `json:"serverTime"`
+}
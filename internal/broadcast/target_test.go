@@ -0,0 +1,185 @@
+package broadcast_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+)
+
+// subscribe registers an SSE channel for sub on the game, unregistered
+// when the test ends.
+func subscribe(t *testing.T, h *broadcast.Hub, gameID string, sub broadcast.Subscriber) chan broadcast.Message {
+	t.Helper()
+	ch := make(chan broadcast.Message, 16)
+	h.RegisterSSE(gameID, ch, sub)
+	t.Cleanup(func() { h.UnregisterSSE(gameID, ch) })
+	return ch
+}
+
+// next returns the next message on ch, failing the test if none comes
+// within a second.
+func next(t *testing.T, ch chan broadcast.Message) broadcast.Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message within a second")
+		return broadcast.Message{}
+	}
+}
+
+func TestSendTo(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	x := broadcast.NewSubscriber(models.PlayerX, "x")
+	x.NoAnalysis = true
+	o := broadcast.NewSubscriber(models.PlayerO, "o")
+	o.Commentary = true
+	subs := map[string]chan broadcast.Message{
+		"x":  subscribe(t, h, "game", x),
+		"o":  subscribe(t, h, "game", o),
+		"s1": subscribe(t, h, "game", broadcast.NewSubscriber("", "s1")),
+		"s2": subscribe(t, h, "game", broadcast.NewSubscriber("spectator", "s2")),
+	}
+
+	tests := []struct {
+		name   string
+		target broadcast.Target
+		want   []string
+	}{
+		{"X", broadcast.ToPlayer(models.PlayerX), []string{"x"}},
+		{"O but their own connection", broadcast.ToPlayer(models.PlayerO).Except("o"), nil},
+		{"spectators", broadcast.ToSpectators(), []string{"s1", "s2"}},
+		{"one connection", broadcast.ToConn("s2"), []string{"s2"}},
+		{"analysis viewers", broadcast.ToAnalysisViewers(), []string{"o", "s1", "s2"}},
+		{"commentary viewers", broadcast.ToCommentaryViewers(), []string{"o", "s1", "s2"}},
+		{"all but one", broadcast.ToAll().Except("s1"), []string{"o", "s2", "x"}},
+	}
+	for _, tt := range tests {
+		h.SendTo(ctx, "game", tt.target, broadcast.Event{Name: "ping", Data: tt.name})
+		// A game's messages go out in order, so each subscriber's next is
+		// the event if it was sent it, and the update otherwise
+		h.Broadcast(ctx, "game", &models.GameState{ID: "game"})
+		var got []string
+		for name, ch := range subs {
+			msg := next(t, ch)
+			if msg.Event == nil {
+				continue
+			}
+			got = append(got, name)
+			if msg.Seq != 0 {
+				t.Errorf("%s: %s was sent seq %d for an event to some clients", tt.name, name, msg.Seq)
+			}
+			if msg := next(t, ch); msg.Game == nil {
+				t.Fatalf("%s: %s wasn't sent the update after the event", tt.name, name)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: sent to %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestSendToWhileSubscribing sends targeted events and updates while
+// spectators come and go, and checks the players that stay get every
+// update and just the events meant for them.
+func TestSendToWhileSubscribing(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	const rounds = 20
+	players := map[models.Player]chan broadcast.Message{}
+	for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+		ch := make(chan broadcast.Message, 4*rounds)
+		h.RegisterSSE("game", ch, broadcast.NewSubscriber(p, string(p)))
+		defer h.UnregisterSSE("game", ch)
+		players[p] = ch
+	}
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			for j := range rounds {
+				ch := make(chan broadcast.Message, 4*rounds)
+				h.RegisterSSE("game", ch, broadcast.NewSubscriber("", fmt.Sprintf("spectator-%d-%d", i, j)))
+				h.UnregisterSSE("game", ch)
+			}
+		})
+	}
+	wg.Go(func() {
+		for range rounds {
+			h.SendTo(ctx, "game", broadcast.ToPlayer(models.PlayerX), broadcast.Event{Name: "x-only"})
+			h.SendTo(ctx, "game", broadcast.ToSpectators(), broadcast.Event{Name: "spectators-only"})
+			h.Broadcast(ctx, "game", &models.GameState{ID: "game"})
+		}
+	})
+	wg.Wait()
+
+	for p, ch := range players {
+		var events, updates int
+		for updates < rounds {
+			msg := next(t, ch)
+			switch {
+			case msg.Game != nil:
+				updates++
+			case msg.Event.Name == "x-only" && p == models.PlayerX:
+				events++
+			default:
+				t.Errorf("%s was sent %+v", p, msg.Event)
+			}
+		}
+		if want := map[models.Player]int{models.PlayerX: rounds}[p]; events != want {
+			t.Errorf("%s was sent %d of its events, want %d", p, events, want)
+		}
+	}
+}
+
+func TestNotifyTurn(t *testing.T) {
+	ctx := context.Background()
+	h := broadcast.NewHub()
+	defer h.Close()
+	x := subscribe(t, h, "game", broadcast.NewSubscriber(models.PlayerX, "x"))
+	o := subscribe(t, h, "game", broadcast.NewSubscriber(models.PlayerO, "o"))
+	spectator := subscribe(t, h, "game", broadcast.NewSubscriber("", "s"))
+
+	tests := []struct {
+		name  string
+		game  models.GameState
+		mover string
+		want  chan broadcast.Message
+	}{
+		{"O to move", models.GameState{ID: "game", CurrentTurn: models.PlayerO}, "x", o},
+		{"X to move, from X's own connection", models.GameState{ID: "game", CurrentTurn: models.PlayerX}, "x", nil},
+		{"a finished game", models.GameState{ID: "game", CurrentTurn: models.PlayerO, IsOver: true}, "x", nil},
+	}
+	for _, tt := range tests {
+		h.NotifyTurn(ctx, &tt.game, tt.mover)
+		h.Broadcast(ctx, "game", &models.GameState{ID: "game"})
+		for _, ch := range []chan broadcast.Message{x, o, spectator} {
+			msg := next(t, ch)
+			if msg.Event == nil {
+				if ch == tt.want {
+					t.Errorf("%s: the player to move wasn't notified", tt.name)
+				}
+				continue
+			}
+			if ch != tt.want {
+				t.Errorf("%s: the wrong subscriber was sent %s", tt.name, msg.Event.Name)
+			}
+			want := broadcast.TurnNotification{GameID: "game", Player: tt.game.CurrentTurn}
+			if msg.Event.Name != broadcast.TurnNotificationEvent || msg.Event.Data != want {
+				t.Errorf("%s: sent %+v", tt.name, msg.Event)
+			}
+			next(t, ch)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+
+	"tiktaktoes/internal/models"
+)
+
+// Delivery is how a topic's events reach subscribers that haven't taken
+// the previous ones yet.
+type Delivery int
+
+const (
+	// DeliverLatest keeps only the newest event for a subscriber that is
+	// behind, for topics where each event supersedes the ones before. It
+	// is the default.
+	DeliverLatest Delivery = iota
+	// DeliverEvery queues every event, for topics where each one
+	// matters. A subscriber that falls a full buffer behind is
+	// disconnected by closing its channel, like a firehose subscriber.
+	DeliverEvery
+)
+
+// topicBuffer is how many events a DeliverEvery subscriber may fall
+// behind by before it is disconnected.
+const topicBuffer = 64
+
+// LobbyTopic carries LobbyEvents about games opening for an opponent or
+// no longer being open. Every event matters, so it uses DeliverEvery.
+const LobbyTopic = "lobby"
+
+// LobbyEvent is the name of events on LobbyTopic.
+const LobbyEvent = "lobby"
+
+// FeaturedEvent is the name of events on LobbyTopic that carry the
+// featured games feed, as a []featured.Entry, whenever it changes.
+const FeaturedEvent = "featured"
+
+// LobbyUpdate is the data of a lobby event. Open tells whether Game is
+// waiting for an opponent, see game.Waiting; a game that isn't, or has
+// been deleted, is to be taken off the list.
+type LobbyUpdate struct {
+	Game *models.GameState `json:"game"`
+	Open bool              `json:"open"`
+}
+
+// topics are named streams of events that aren't about one game, each
+// with its own subscribers and delivery, see Hub.BroadcastTopic.
+type topics struct {
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+type topic struct {
+	delivery Delivery
+	subs     map[chan Event]struct{}
+}
+
+func newTopics() topics {
+	return topics{topics: map[string]*topic{
+		LobbyTopic: {delivery: DeliverEvery},
+	}}
+}
+
+// get returns the named topic, creating it with the default delivery.
+// Must be called with the lock held.
+func (t *topics) get(name string) *topic {
+	tp := t.topics[name]
+	if tp == nil {
+		tp = &topic{}
+		t.topics[name] = tp
+	}
+	if tp.subs == nil {
+		tp.subs = make(map[chan Event]struct{})
+	}
+	return tp
+}
+
+// SetTopicDelivery sets how the topic's events are delivered. Set it
+// before anyone subscribes.
+func (h *Hub) SetTopicDelivery(name string, d Delivery) {
+	h.topics.mu.Lock()
+	defer h.topics.mu.Unlock()
+	h.topics.get(name).delivery = d
+}
+
+// SubscribeTopic subscribes to the named topic's events. The channel is
+// closed when cancel is called or, for DeliverEvery topics, when the
+// subscriber falls too far behind; cancel must be called either way.
+func (h *Hub) SubscribeTopic(name string) (events <-chan Event, cancel func()) {
+	h.topics.mu.Lock()
+	tp := h.topics.get(name)
+	size := 1
+	if tp.delivery == DeliverEvery {
+		size = topicBuffer
+	}
+	ch := make(chan Event, size)
+	tp.subs[ch] = struct{}{}
+	h.topics.mu.Unlock()
+
+	return ch, func() {
+		h.topics.mu.Lock()
+		defer h.topics.mu.Unlock()
+		if _, ok := tp.subs[ch]; ok {
+			delete(tp.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// BroadcastTopic sends ev to every subscriber of the named topic without
+// blocking, as its delivery says.
+func (h *Hub) BroadcastTopic(ctx context.Context, name string, ev Event) {
+	_, span := tracer.Start(ctx, "hub.BroadcastTopic")
+	defer span.End()
+
+	h.events.Add(1)
+	h.topics.mu.Lock()
+	defer h.topics.mu.Unlock()
+	tp := h.topics.get(name)
+	for ch := range tp.subs {
+		select {
+		case ch <- ev:
+			continue
+		default:
+		}
+		if tp.delivery == DeliverEvery {
+			delete(tp.subs, ch)
+			close(ch)
+			h.dropped.Add(1)
+			continue
+		}
+		// Replace the event the subscriber hasn't taken yet. Only
+		// senders hold the lock, so there is room once it is taken.
+		select {
+		case <-ch:
+			h.dropped.Add(1)
+		default:
+		}
+		ch <- ev
+	}
+}
@@ -0,0 +1,76 @@
+package broadcast
+
+import (
+	"time"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/version"
+)
+
+// WelcomeFrameType is the type of the frame a WebSocket client gets on
+// connecting, right after the game's state, see Welcome.
+const WelcomeFrameType = "welcome"
+
+// WelcomeEvents is how many of the game's latest events a Welcome sums up.
+const WelcomeEvents = 10
+
+// Welcome is the data of the frame a WebSocket client gets on connecting.
+// InstanceID changes whenever the server restarts.
+type Welcome struct {
+	InstanceID string            `json:"instanceId"`
+	ConnID     string            `json:"connId"`
+	Version    string            `json:"version"`
+	APIVersion int               `json:"apiVersion"`
+	ServerTime time.Time         `json:"serverTime"`
+	Seq        uint64            `json:"seq"`
+	Game       *models.GameState `json:"game,omitempty"`
+	Events     []EventSummary    `json:"events"`
+}
+
+// EventSummary describes a logged event without its data. LastMove is
+// the move that led to the state of a game-update event, if any.
+type EventSummary struct {
+	Seq      uint64             `json:"seq"`
+	Type     string             `json:"type"`
+	Time     time.Time          `json:"time"`
+	LastMove *models.MoveRecord `json:"lastMove,omitempty"`
+}
+
+// summaries returns the game's latest n logged events, oldest first.
+func (l *eventLogs) summaries(gameID string, n int) []EventSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	summaries := []EventSummary{}
+	log := l.games[gameID]
+	if log == nil {
+		return summaries
+	}
+	for i := max(len(log.ring)-n, 0); i < len(log.ring); i++ {
+		ev := log.ring[(log.start+i)%len(log.ring)]
+		summary := EventSummary{Seq: ev.Seq, Type: ev.Type, Time: ev.Time}
+		if ev.Game != nil {
+			summary.LastMove = ev.Game.LastMove
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// InstanceID identifies this run of the server, see Welcome.
+func (h *Hub) InstanceID() string {
+	return h.instanceID
+}
+
+// welcome returns the Welcome for a client of the game connecting now.
+func (h *Hub) welcome(gameID, connID string, game *models.GameState) wsEvent {
+	return wsEvent{Type: WelcomeFrameType, GameID: gameID, Data: Welcome{
+		InstanceID: h.instanceID,
+		ConnID:     connID,
+		Version:    version.Get().Version,
+		APIVersion: version.APIVersion,
+		ServerTime: time.Now().UTC(),
+		Seq:        h.eventLogs.seq(gameID),
+		Game:       game,
+		Events:     h.eventLogs.summaries(gameID, WelcomeEvents),
+	}}
+}
@@ -0,0 +1,175 @@
+// Package clientip resolves the address of the client behind trusted
+// reverse proxies and carries it through request contexts.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type contextKey struct{}
+
+// With returns a context carrying the client address.
+func With(ctx context.Context, ip netip.Addr) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// From returns the client address stored in ctx, or the zero Addr.
+func From(ctx context.Context) netip.Addr {
+	ip, _ := ctx.Value(contextKey{}).(netip.Addr)
+	return ip
+}
+
+// ParsePrefixes parses a comma-separated list of CIDRs or single
+// addresses, such as "10.0.0.0/8, ::1".
+func ParsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			p, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Header is the forwarding header trusted proxies record hops in.
+type Header int
+
+const (
+	// XForwardedFor is X-Forwarded-For, as nginx and most proxies set
+	// it.
+	XForwardedFor Header = iota
+	// Forwarded is the standard Forwarded header of RFC 7239.
+	Forwarded
+)
+
+// ParseHeader parses "xff" or "forwarded", as given on the command
+// line.
+func ParseHeader(s string) (Header, error) {
+	switch s {
+	case "xff":
+		return XForwardedFor, nil
+	case "forwarded":
+		return Forwarded, nil
+	}
+	return 0, fmt.Errorf("unknown forwarding header %q, must be xff or forwarded", s)
+}
+
+// Resolver finds the client address of requests.
+type Resolver struct {
+	trusted []netip.Prefix
+	header  Header
+}
+
+// NewResolver creates a resolver that trusts header from peers within
+// the given prefixes, and no other. With none, the peer is the client.
+func NewResolver(trusted []netip.Prefix, header Header) *Resolver {
+	return &Resolver{trusted: trusted, header: header}
+}
+
+// Middleware stores each request's client address in its context.
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), res.Resolve(r))))
+	})
+}
+
+// Resolve returns the client address of r. It is the zero Addr only if
+// the peer address itself can't be parsed.
+func (res *Resolver) Resolve(r *http.Request) netip.Addr {
+	peer := parseHost(r.RemoteAddr)
+	if !peer.IsValid() || !res.isTrusted(peer) {
+		return peer
+	}
+
+	hops := res.forwardedFor(r.Header)
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseHost(hops[i])
+		if !hop.IsValid() {
+			// Obfuscated or garbled: the last proxy that we trust is as
+			// close to the client as we can get
+			break
+		}
+		client = hop
+		if !res.isTrusted(hop) {
+			break
+		}
+	}
+	return client
+}
+
+func (res *Resolver) isTrusted(ip netip.Addr) bool {
+	for _, p := range res.trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the hops recorded by proxies in the resolver's
+// header, client first. The other header is never looked at.
+func (res *Resolver) forwardedFor(h http.Header) []string {
+	var hops []string
+	if res.header == Forwarded {
+		for _, v := range h.Values("Forwarded") {
+			for _, elem := range strings.Split(v, ",") {
+				hops = append(hops, forwardedElementFor(elem))
+			}
+		}
+		return hops
+	}
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedElementFor returns the for= parameter of one Forwarded
+// element, unquoted, or "" without one.
+func forwardedElementFor(elem string) string {
+	for _, pair := range strings.Split(elem, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(name, "for") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// parseHost parses an address with or without a port, IPv6 addresses
+// optionally in brackets. It returns the zero Addr for anything else,
+// including Forwarded's "unknown" and "_hidden" identifiers.
+func parseHost(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap().WithZone("")
+}
@@ -0,0 +1,91 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	trusted, err := ParsePrefixes("10.0.0.0/8, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		header Header
+		peer   string
+		xff    []string
+		fwd    []string
+		want   string
+	}{
+		{name: "untrusted peer without headers", peer: "203.0.113.9:5000", want: "203.0.113.9"},
+		{name: "untrusted peer spoofing X-Forwarded-For", peer: "203.0.113.9:5000", xff: []string{"1.2.3.4"}, want: "203.0.113.9"},
+		{name: "untrusted peer spoofing Forwarded", header: Forwarded, peer: "203.0.113.9:5000", fwd: []string{"for=1.2.3.4"}, want: "203.0.113.9"},
+		{name: "trusted proxy", peer: "10.0.0.1:5000", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted proxy over IPv6", peer: "[::1]:5000", xff: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted proxy without a header", peer: "10.0.0.1:5000", want: "10.0.0.1"},
+		{name: "spoofed leading X-Forwarded-For entries", peer: "10.0.0.1:5000", xff: []string{"1.2.3.4, 5.6.7.8, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "spoofed entry claiming to be a proxy", peer: "10.0.0.1:5000", xff: []string{"10.9.9.9, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "chained proxies", peer: "10.0.0.1:5000", xff: []string{"1.2.3.4, 198.51.100.7, 10.0.0.2", "10.0.0.3"}, want: "198.51.100.7"},
+		{name: "only proxies", peer: "10.0.0.1:5000", xff: []string{"10.0.0.2"}, want: "10.0.0.2"},
+		{name: "garbled hop stops at the last trusted one", peer: "10.0.0.1:5000", xff: []string{"198.51.100.7, nonsense, 10.0.0.2"}, want: "10.0.0.2"},
+		{name: "spoofed Forwarded behind an X-Forwarded-For proxy", peer: "10.0.0.1:5000", xff: []string{"198.51.100.7"}, fwd: []string{"for=1.2.3.4"}, want: "198.51.100.7"},
+		{name: "Forwarded alone behind an X-Forwarded-For proxy", peer: "10.0.0.1:5000", fwd: []string{"for=1.2.3.4"}, want: "10.0.0.1"},
+		{name: "Forwarded proxy", header: Forwarded, peer: "10.0.0.1:5000", fwd: []string{`for="[2001:db8::1]:4711";proto=https`}, want: "2001:db8::1"},
+		{name: "spoofed X-Forwarded-For behind a Forwarded proxy", header: Forwarded, peer: "10.0.0.1:5000", xff: []string{"1.2.3.4"}, fwd: []string{"for=198.51.100.7"}, want: "198.51.100.7"},
+		{name: "X-Forwarded-For alone behind a Forwarded proxy", header: Forwarded, peer: "10.0.0.1:5000", xff: []string{"1.2.3.4"}, want: "10.0.0.1"},
+		{name: "spoofed leading Forwarded elements", header: Forwarded, peer: "10.0.0.1:5000", fwd: []string{"for=1.2.3.4, for=198.51.100.7"}, want: "198.51.100.7"},
+		{name: "hidden Forwarded hop", header: Forwarded, peer: "10.0.0.1:5000", fwd: []string{"for=198.51.100.7, for=_hidden"}, want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.peer
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			for _, v := range tt.fwd {
+				r.Header.Add("Forwarded", v)
+			}
+			got := NewResolver(trusted, tt.header).Resolve(r)
+			if want := netip.MustParseAddr(tt.want); got != want {
+				t.Errorf("Resolve() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "127.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := NewResolver(nil, XForwardedFor).Resolve(r); got != netip.MustParseAddr("127.0.0.1") {
+		t.Errorf("Resolve() = %v, want the peer", got)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	for s, want := range map[string]Header{"xff": XForwardedFor, "forwarded": Forwarded} {
+		if got, err := ParseHeader(s); err != nil || got != want {
+			t.Errorf("ParseHeader(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseHeader("x-real-ip"); err == nil {
+		t.Error("ParseHeader accepted an unknown header")
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	got, err := ParsePrefixes(" 10.1.2.3/8 , ::ffff:192.0.2.1,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ParsePrefixes() = %v, want %v", got, want)
+	}
+	if _, err := ParsePrefixes("10.0.0.0/33"); err == nil {
+		t.Error("ParsePrefixes accepted a bad prefix")
+	}
+}
@@ -0,0 +1,197 @@
+// Package commentary lets a player appoint a commentator for their game,
+// whose lines are sent to its spectators.
+package commentary
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/text"
+)
+
+// MaxLines bounds how much commentary a game keeps. Once it has that
+// many lines, each new one drops the oldest.
+const MaxLines = 200
+
+var (
+	ErrNotJoined      = errors.New("only a player who has joined the game can appoint its commentator")
+	ErrNoCommentator  = errors.New("the game has no commentator")
+	ErrNotCommentator = errors.New("that isn't the game's commentator token")
+	ErrGameOver       = errors.New("the game is over and takes no more commentary")
+)
+
+// commentary is what is kept of a game's commentary.
+type commentary struct {
+	// token is the SHA-256 of the commentator's token, nil while there
+	// is no commentator.
+	token []byte
+	lines []models.Commentary
+}
+
+// Service keeps the commentators and commentary of games. Both are kept
+// in memory only, and dropped with the game. It is safe for concurrent
+// use.
+type Service struct {
+	games *game.Service
+	hub   *broadcast.Hub
+	clock func() time.Time
+
+	mu     sync.Mutex
+	byGame map[string]*commentary
+}
+
+// NewService creates a commentary service. A game's commentary is
+// dropped when the game expires or is cancelled.
+func NewService(games *game.Service, hub *broadcast.Hub) *Service {
+	s := &Service{
+		games:  games,
+		hub:    hub,
+		clock:  time.Now,
+		byGame: make(map[string]*commentary),
+	}
+	games.OnGameExpired(s.forget)
+	games.OnGameCancelled(s.forget)
+	return s
+}
+
+func (s *Service) forget(gs models.GameState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byGame, gs.ID)
+}
+
+// Appoint makes whoever holds the returned token the commentator of the
+// game with the given ID, which player must have joined. The previous
+// commentator, if any, is revoked. It returns the full ID of the game
+// along with the token, which can't be had again.
+func (s *Service) Appoint(ctx context.Context, gameID string, player models.Player) (string, string, error) {
+	g, err := s.games.FindGame(ctx, gameID)
+	if err != nil {
+		return "", "", err
+	}
+	if !joinedAs(g, player) {
+		return "", "", ErrNotJoined
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.byGame[g.ID]
+	if c == nil {
+		c = &commentary{}
+		s.byGame[g.ID] = c
+	}
+	c.token = hash(token)
+	return g.ID, token, nil
+}
+
+// Revoke takes the commentator of the game with the given ID, which
+// player must have joined, away. It returns ErrNoCommentator if it has
+// none. The commentary written so far is kept.
+func (s *Service) Revoke(ctx context.Context, gameID string, player models.Player) error {
+	g, err := s.games.FindGame(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if !joinedAs(g, player) {
+		return ErrNotJoined
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.byGame[g.ID]
+	if c == nil || c.token == nil {
+		return ErrNoCommentator
+	}
+	c.token = nil
+	return nil
+}
+
+// Comment adds a line of commentary from the holder of the game's
+// commentator token and sends it to the game's commentary viewers.
+func (s *Service) Comment(ctx context.Context, gameID, token, line string) (models.Commentary, error) {
+	g, err := s.games.FindGame(ctx, gameID)
+	if err != nil {
+		return models.Commentary{}, err
+	}
+	line, err = s.games.TextPolicy().Clean(text.FieldCommentary, line)
+	if err != nil {
+		return models.Commentary{}, err
+	}
+
+	s.mu.Lock()
+	c := s.byGame[g.ID]
+	if c == nil || c.token == nil || subtle.ConstantTimeCompare(c.token, hash(token)) != 1 {
+		s.mu.Unlock()
+		return models.Commentary{}, ErrNotCommentator
+	}
+	if g.IsOver {
+		s.mu.Unlock()
+		return models.Commentary{}, ErrGameOver
+	}
+	entry := models.Commentary{Move: len(g.History), Text: line, At: s.clock().UTC()}
+	if len(c.lines) >= MaxLines {
+		c.lines = slices.Delete(c.lines, 0, len(c.lines)-MaxLines+1)
+	}
+	c.lines = append(c.lines, entry)
+	s.mu.Unlock()
+
+	s.hub.SendTo(ctx, g.ID, broadcast.ToCommentaryViewers(), broadcast.Event{
+		Name: broadcast.CommentaryEvent,
+		Data: broadcast.CommentaryLine{GameID: g.ID, Commentary: entry},
+	})
+	return entry, nil
+}
+
+// Log is a game's commentary so far.
+type Log struct {
+	GameID string `json:"gameId"`
+	// Commentator is whether the game has one.
+	Commentator bool                `json:"commentator"`
+	Lines       []models.Commentary `json:"lines"`
+}
+
+// Get returns the commentary of the game with the given ID, oldest
+// first.
+func (s *Service) Get(ctx context.Context, gameID string) (Log, error) {
+	g, err := s.games.FindGame(ctx, gameID)
+	if err != nil {
+		return Log{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log := Log{GameID: g.ID, Lines: []models.Commentary{}}
+	if c := s.byGame[g.ID]; c != nil {
+		log.Commentator = c.token != nil
+		log.Lines = append(log.Lines, c.lines...)
+	}
+	return log, nil
+}
+
+func hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// joinedAs reports whether player's side of the game has been joined,
+// which is as near to having created it as the server knows.
+func joinedAs(g *models.GameState, player models.Player) bool {
+	switch player {
+	case models.PlayerX:
+		return g.PlayerXJoined
+	case models.PlayerO:
+		return g.PlayerOJoined
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package commentary_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// setup returns a commentary service over a new game service and hub,
+// and a game X has joined.
+func setup(t *testing.T) (*commentary.Service, *game.Service, *broadcast.Hub, *models.GameState) {
+	t.Helper()
+	games := game.NewService()
+	t.Cleanup(func() { games.Close() })
+	hub := broadcast.NewHub()
+	t.Cleanup(hub.Close)
+	g, err := games.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commentary.NewService(games, hub), games, hub, g
+}
+
+func TestAppointAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	s, _, _, g := setup(t)
+
+	if _, _, err := s.Appoint(ctx, g.ID, models.PlayerO); !errors.Is(err, commentary.ErrNotJoined) {
+		t.Errorf("appointing as O, who hasn't joined: %v", err)
+	}
+	if _, _, err := s.Appoint(ctx, "nosuchgame", models.PlayerX); !errors.Is(err, game.ErrGameNotFound) {
+		t.Errorf("appointing for a game that doesn't exist: %v", err)
+	}
+	if err := s.Revoke(ctx, g.ID, models.PlayerX); !errors.Is(err, commentary.ErrNoCommentator) {
+		t.Errorf("revoking before anyone was appointed: %v", err)
+	}
+
+	id, first, err := s.Appoint(ctx, g.ID, models.PlayerX)
+	if err != nil || id != g.ID || first == "" {
+		t.Fatalf("Appoint = %q, %q, %v", id, first, err)
+	}
+	if _, err := s.Comment(ctx, g.ID, first, "X takes the centre?"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Comment(ctx, g.ID, "not-the-token", "hello"); !errors.Is(err, commentary.ErrNotCommentator) {
+		t.Errorf("commenting with a made-up token: %v", err)
+	}
+
+	// Appointing again replaces the commentator
+	_, second, err := s.Appoint(ctx, g.ID, models.PlayerX)
+	if err != nil || second == first {
+		t.Fatalf("appointing again: %q, %v", second, err)
+	}
+	if _, err := s.Comment(ctx, g.ID, first, "still here"); !errors.Is(err, commentary.ErrNotCommentator) {
+		t.Errorf("commenting with the replaced token: %v", err)
+	}
+	if _, err := s.Comment(ctx, g.ID, second, "new voice"); err != nil {
+		t.Errorf("commenting with the new token: %v", err)
+	}
+
+	if err := s.Revoke(ctx, g.ID, models.PlayerO); !errors.Is(err, commentary.ErrNotJoined) {
+		t.Errorf("revoking as O: %v", err)
+	}
+	if err := s.Revoke(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Comment(ctx, g.ID, second, "one more"); !errors.Is(err, commentary.ErrNotCommentator) {
+		t.Errorf("commenting once revoked: %v", err)
+	}
+	if err := s.Revoke(ctx, g.ID, models.PlayerX); !errors.Is(err, commentary.ErrNoCommentator) {
+		t.Errorf("revoking twice: %v", err)
+	}
+
+	// The commentary stays when its commentator goes
+	log, err := s.Get(ctx, g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Commentator || len(log.Lines) != 2 || log.Lines[0].Text != "X takes the centre?" || log.Lines[1].Text != "new voice" {
+		t.Errorf("after revoking: %+v", log)
+	}
+}
+
+// TestRouting checks a line of commentary goes to spectators and players
+// who opted in, and not to other players.
+func TestRouting(t *testing.T) {
+	ctx := context.Background()
+	s, _, hub, g := setup(t)
+	_, token, err := s.Appoint(ctx, g.ID, models.PlayerX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	optedIn := broadcast.NewSubscriber(models.PlayerO, "o")
+	optedIn.Commentary = true
+	subs := map[string]broadcast.Subscriber{
+		"spectator":      broadcast.NewSubscriber("", "spectator"),
+		"X":              broadcast.NewSubscriber(models.PlayerX, "x"),
+		"O, opted in":    optedIn,
+		"X, another tab": broadcast.NewSubscriber(models.PlayerX, "x2"),
+	}
+	want := map[string]bool{"spectator": true, "O, opted in": true}
+	chans := make(map[string]chan broadcast.Message)
+	for name, sub := range subs {
+		ch := make(chan broadcast.Message, 4)
+		hub.RegisterSSE(g.ID, ch, sub)
+		defer hub.UnregisterSSE(g.ID, ch)
+		chans[name] = ch
+	}
+
+	line, err := s.Comment(ctx, g.ID, token, "here we go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A game update after it, to everyone, shows who the line skipped
+	hub.Broadcast(ctx, g.ID, g)
+	for name, ch := range chans {
+		var msg broadcast.Message
+		select {
+		case msg = <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: nothing within a second", name)
+		}
+		got := msg.Event != nil && msg.Event.Name == broadcast.CommentaryEvent
+		if got != want[name] {
+			t.Errorf("%s: first message %+v, want commentary %v", name, msg, want[name])
+			continue
+		}
+		if got && msg.Event.Data != (broadcast.CommentaryLine{GameID: g.ID, Commentary: line}) {
+			t.Errorf("%s: commentary %+v, want %+v", name, msg.Event.Data, line)
+		}
+	}
+}
+
+// TestReplayAlignment comments between moves and checks each line is
+// kept with the number of moves before it, so a replay can show it at
+// that position.
+func TestReplayAlignment(t *testing.T) {
+	ctx := context.Background()
+	s, games, _, g := setup(t)
+	if _, err := games.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	_, token, err := s.Appoint(ctx, g.ID, models.PlayerO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	say := func(text string) {
+		t.Helper()
+		if _, err := s.Comment(ctx, g.ID, token, text); err != nil {
+			t.Fatalf("commenting %q: %v", text, err)
+		}
+	}
+	move := func(p models.Player, pos int) {
+		t.Helper()
+		if _, err := games.MakeMove(ctx, g.ID, models.Move{Player: p, Position: pos}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	say("before a move")
+	move(models.PlayerX, 4)
+	say("the centre")
+	say("a strong start")
+	move(models.PlayerO, 0)
+	move(models.PlayerX, 8)
+	say("X sets up a fork")
+	move(models.PlayerO, 2)
+	move(models.PlayerX, 6)
+	move(models.PlayerO, 1)
+	if _, err := s.Comment(ctx, g.ID, token, "too late"); !errors.Is(err, commentary.ErrGameOver) {
+		t.Errorf("commenting on a finished game: %v", err)
+	}
+
+	log, err := s.Get(ctx, g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		move int
+		text string
+	}{{0, "before a move"}, {1, "the centre"}, {1, "a strong start"}, {3, "X sets up a fork"}}
+	if len(log.Lines) != len(want) {
+		t.Fatalf("%d lines, want %d: %+v", len(log.Lines), len(want), log.Lines)
+	}
+	for i, w := range want {
+		if got := log.Lines[i]; got.Move != w.move || got.Text != w.text || got.At.IsZero() {
+			t.Errorf("line %d: %+v, want %q after move %d", i, got, w.text, w.move)
+		}
+	}
+}
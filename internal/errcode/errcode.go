@@ -0,0 +1,136 @@
+// Package errcode maps the server's errors to the code, HTTP status and
+// retry hint every transport reports them with.
+package errcode
+
+import (
+	"errors"
+	"net/http"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/puzzle"
+	"tiktaktoes/internal/replica"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/sse"
+	"tiktaktoes/internal/text"
+	"tiktaktoes/internal/tournament"
+)
+
+// Info is how an error is told to clients.
+type Info struct {
+	// Code is the error's stable code, such as "not_your_turn", or ""
+	// for an error clients can't act on, as an unexpected one.
+	Code string
+	// Status is the HTTP status the error is answered with.
+	Status int
+	// Temporary is set for errors that may go away by themselves, so the
+	// same request is worth sending again later.
+	Temporary bool
+}
+
+// known maps each error to how it is told, by status and by code for
+// errors outside the game service. Errors wrapping another are listed
+// before it.
+var known = []struct {
+	err       error
+	status    int
+	code      string
+	temporary bool
+}{
+	{err: game.ErrInvalidState, status: http.StatusBadRequest},
+	{err: game.ErrGameNotFound, status: http.StatusNotFound},
+	{err: game.ErrAmbiguousID, status: http.StatusBadRequest},
+	{err: game.ErrInvalidMove, status: http.StatusBadRequest},
+	{err: game.ErrInvalidPlayer, status: http.StatusBadRequest},
+	{err: game.ErrInvalidSymbol, status: http.StatusBadRequest},
+	{err: game.ErrSymbolTaken, status: http.StatusBadRequest},
+	{err: game.ErrInvalidMode, status: http.StatusBadRequest},
+	{err: game.ErrModeFixed, status: http.StatusBadRequest},
+	{err: game.ErrInvalidHandicap, status: http.StatusBadRequest},
+	{err: game.ErrInvalidStart, status: http.StatusBadRequest},
+	{err: game.ErrReadyCheckMode, status: http.StatusBadRequest},
+	{err: game.ErrInvalidSlug, status: http.StatusBadRequest},
+	{err: game.ErrNotYourTurn, status: http.StatusConflict},
+	{err: game.ErrGameOver, status: http.StatusConflict},
+	{err: game.ErrPositionTaken, status: http.StatusConflict},
+	{err: game.ErrGameFull, status: http.StatusConflict},
+	{err: game.ErrSlotTaken, status: http.StatusConflict},
+	{err: game.ErrGameStarted, status: http.StatusConflict},
+	{err: game.ErrSlotEmpty, status: http.StatusConflict},
+	{err: game.ErrWaiting, status: http.StatusConflict},
+	{err: game.ErrNotStarted, status: http.StatusConflict},
+	{err: game.ErrDrawOffered, status: http.StatusConflict},
+	{err: game.ErrNoDrawOffer, status: http.StatusConflict},
+	{err: game.ErrNoClaim, status: http.StatusConflict},
+	{err: game.ErrOpponentPresent, status: http.StatusConflict},
+	{err: game.ErrClaimTooEarly, status: http.StatusConflict},
+	{err: game.ErrVacateTooEarly, status: http.StatusConflict},
+	{err: game.ErrGameExists, status: http.StatusConflict},
+	{err: game.ErrSettingsLocked, status: http.StatusConflict},
+	{err: game.ErrNotCreator, status: http.StatusConflict},
+	{err: game.ErrNoReadyCheck, status: http.StatusConflict},
+	{err: game.ErrNothingToUndo, status: http.StatusConflict},
+	{err: game.ErrUndoStale, status: http.StatusConflict},
+	{err: game.ErrNotResetter, status: http.StatusForbidden},
+	{err: game.ErrSlugTaken, status: http.StatusConflict},
+	{err: game.ErrComputerSide, status: http.StatusConflict},
+	{err: game.ErrComputerDraw, status: http.StatusConflict},
+	{err: game.ErrInvalidBatch, status: http.StatusBadRequest},
+	{err: game.ErrNoBatch, status: http.StatusConflict},
+	{err: game.ErrInvalidLocale, status: http.StatusBadRequest},
+	{err: game.ErrQuotaExceeded, status: http.StatusTooManyRequests, temporary: true},
+	{err: game.ErrServerFull, status: http.StatusServiceUnavailable, temporary: true},
+	{err: game.ErrJournal, status: http.StatusServiceUnavailable, temporary: true},
+	{err: game.ErrIDExhausted, status: http.StatusServiceUnavailable, temporary: true},
+	{err: game.ErrReadOnly, status: http.StatusServiceUnavailable, temporary: true},
+	{err: text.ErrRejected, status: http.StatusBadRequest},
+
+	{err: models.ErrInvalidBoard, status: http.StatusBadRequest, code: "invalid_board"},
+	{err: models.ErrInvalidCell, status: http.StatusBadRequest, code: "invalid_cell"},
+	{err: commentary.ErrNotJoined, status: http.StatusForbidden, code: "not_joined"},
+	{err: commentary.ErrNotCommentator, status: http.StatusForbidden, code: "not_commentator"},
+	{err: commentary.ErrNoCommentator, status: http.StatusNotFound, code: "no_commentator"},
+	{err: commentary.ErrGameOver, status: http.StatusConflict, code: "game_over"},
+	{err: activity.ErrNotJoined, status: http.StatusForbidden, code: "not_joined"},
+	{err: seat.ErrNoSeat, status: http.StatusForbidden, code: "seat_token"},
+	{err: tournament.ErrNotFound, status: http.StatusNotFound, code: "tournament_not_found"},
+	{err: tournament.ErrTooFewParticipants, status: http.StatusBadRequest, code: "too_few_participants"},
+	{err: tournament.ErrTooManyParticipants, status: http.StatusBadRequest, code: "too_many_participants"},
+	{err: tournament.ErrInvalidName, status: http.StatusBadRequest, code: "invalid_participant_name"},
+	{err: tournament.ErrDuplicateName, status: http.StatusBadRequest, code: "duplicate_participant_name"},
+	{err: tournament.ErrInvalidFormat, status: http.StatusBadRequest, code: "invalid_tournament_format"},
+	{err: puzzle.ErrIllegalMove, status: http.StatusBadRequest, code: "puzzle_cell_taken"},
+	{err: featured.ErrNotFeatured, status: http.StatusNotFound, code: "not_featured"},
+	{err: replica.ErrNotFollowing, status: http.StatusConflict, code: "not_following"},
+	{err: apikey.ErrUnknownKey, status: http.StatusUnauthorized, code: "unknown_key"},
+	{err: apikey.ErrScope, status: http.StatusForbidden, code: "key_scope"},
+	{err: apikey.ErrRateLimited, status: http.StatusTooManyRequests, code: "key_rate_limited", temporary: true},
+	{err: apikey.ErrKeyNotFound, status: http.StatusNotFound, code: "key_not_found"},
+	{err: apikey.ErrInvalidScope, status: http.StatusBadRequest, code: "invalid_scope"},
+	{err: apikey.ErrInvalidRate, status: http.StatusBadRequest, code: "invalid_rate"},
+	{err: sse.ErrUnsupported, status: http.StatusInternalServerError, code: "streaming_unsupported"},
+	{err: sse.ErrClosed, status: http.StatusServiceUnavailable, code: "stream_closed", temporary: true},
+	{err: sse.ErrInvalidField, status: http.StatusInternalServerError, code: "invalid_event"},
+}
+
+// FromError returns how err is told to clients. An error it doesn't know
+// is an internal one, answered with a 500 and no code.
+func FromError(err error) Info {
+	if err == nil {
+		return Info{Status: http.StatusOK}
+	}
+	for _, k := range known {
+		if errors.Is(err, k.err) {
+			info := Info{Code: k.code, Status: k.status, Temporary: k.temporary}
+			if info.Code == "" {
+				info.Code = game.Code(err)
+			}
+			return info
+		}
+	}
+	return Info{Status: http.StatusInternalServerError}
+}
@@ -0,0 +1,198 @@
+package errcode_test
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/errcode"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/puzzle"
+	"tiktaktoes/internal/replica"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/sse"
+	"tiktaktoes/internal/text"
+	"tiktaktoes/internal/tournament"
+)
+
+// errs holds every exported Err variable of the server's packages by
+// package and name. TestEveryErrorIsMapped fails for one missing here.
+var errs = map[string]error{
+	"activity.ErrNotJoined":             activity.ErrNotJoined,
+	"apikey.ErrUnknownKey":              apikey.ErrUnknownKey,
+	"apikey.ErrScope":                   apikey.ErrScope,
+	"apikey.ErrRateLimited":             apikey.ErrRateLimited,
+	"apikey.ErrKeyNotFound":             apikey.ErrKeyNotFound,
+	"apikey.ErrInvalidScope":            apikey.ErrInvalidScope,
+	"apikey.ErrInvalidRate":             apikey.ErrInvalidRate,
+	"commentary.ErrNotJoined":           commentary.ErrNotJoined,
+	"commentary.ErrNoCommentator":       commentary.ErrNoCommentator,
+	"commentary.ErrNotCommentator":      commentary.ErrNotCommentator,
+	"commentary.ErrGameOver":            commentary.ErrGameOver,
+	"featured.ErrNotFeatured":           featured.ErrNotFeatured,
+	"game.ErrGameNotFound":              game.ErrGameNotFound,
+	"game.ErrAmbiguousID":               game.ErrAmbiguousID,
+	"game.ErrInvalidMove":               game.ErrInvalidMove,
+	"game.ErrNotYourTurn":               game.ErrNotYourTurn,
+	"game.ErrGameOver":                  game.ErrGameOver,
+	"game.ErrPositionTaken":             game.ErrPositionTaken,
+	"game.ErrGameFull":                  game.ErrGameFull,
+	"game.ErrSlotTaken":                 game.ErrSlotTaken,
+	"game.ErrInvalidPlayer":             game.ErrInvalidPlayer,
+	"game.ErrIDExhausted":               game.ErrIDExhausted,
+	"game.ErrJournal":                   game.ErrJournal,
+	"game.ErrInvalidSymbol":             game.ErrInvalidSymbol,
+	"game.ErrSymbolTaken":               game.ErrSymbolTaken,
+	"game.ErrGameStarted":               game.ErrGameStarted,
+	"game.ErrSlotEmpty":                 game.ErrSlotEmpty,
+	"game.ErrInvalidMode":               game.ErrInvalidMode,
+	"game.ErrWaiting":                   game.ErrWaiting,
+	"game.ErrDrawOffered":               game.ErrDrawOffered,
+	"game.ErrNoDrawOffer":               game.ErrNoDrawOffer,
+	"game.ErrQuotaExceeded":             game.ErrQuotaExceeded,
+	"game.ErrServerFull":                game.ErrServerFull,
+	"game.ErrNoClaim":                   game.ErrNoClaim,
+	"game.ErrOpponentPresent":           game.ErrOpponentPresent,
+	"game.ErrClaimTooEarly":             game.ErrClaimTooEarly,
+	"game.ErrVacateTooEarly":            game.ErrVacateTooEarly,
+	"game.ErrGameExists":                game.ErrGameExists,
+	"game.ErrInvalidHandicap":           game.ErrInvalidHandicap,
+	"game.ErrInvalidStart":              game.ErrInvalidStart,
+	"game.ErrSettingsLocked":            game.ErrSettingsLocked,
+	"game.ErrNotCreator":                game.ErrNotCreator,
+	"game.ErrModeFixed":                 game.ErrModeFixed,
+	"game.ErrNotStarted":                game.ErrNotStarted,
+	"game.ErrNoReadyCheck":              game.ErrNoReadyCheck,
+	"game.ErrReadyCheckMode":            game.ErrReadyCheckMode,
+	"game.ErrNothingToUndo":             game.ErrNothingToUndo,
+	"game.ErrUndoStale":                 game.ErrUndoStale,
+	"game.ErrNotResetter":               game.ErrNotResetter,
+	"game.ErrInvalidSlug":               game.ErrInvalidSlug,
+	"game.ErrSlugTaken":                 game.ErrSlugTaken,
+	"game.ErrComputerSide":              game.ErrComputerSide,
+	"game.ErrComputerDraw":              game.ErrComputerDraw,
+	"game.ErrInvalidState":              game.ErrInvalidState,
+	"game.ErrInvalidBatch":              game.ErrInvalidBatch,
+	"game.ErrNoBatch":                   game.ErrNoBatch,
+	"game.ErrReadOnly":                  game.ErrReadOnly,
+	"game.ErrInvalidLocale":             game.ErrInvalidLocale,
+	"httpx.ErrBadGameID":                httpx.ErrBadGameID,
+	"httpx.ErrBadParam":                 httpx.ErrBadParam,
+	"models.ErrInvalidBoard":            models.ErrInvalidBoard,
+	"models.ErrInvalidCell":             models.ErrInvalidCell,
+	"puzzle.ErrIllegalMove":             puzzle.ErrIllegalMove,
+	"replica.ErrNotFollowing":           replica.ErrNotFollowing,
+	"seat.ErrNoSeat":                    seat.ErrNoSeat,
+	"sse.ErrUnsupported":                sse.ErrUnsupported,
+	"sse.ErrClosed":                     sse.ErrClosed,
+	"sse.ErrInvalidField":               sse.ErrInvalidField,
+	"text.ErrRejected":                  text.ErrRejected,
+	"tournament.ErrNotFound":            tournament.ErrNotFound,
+	"tournament.ErrTooFewParticipants":  tournament.ErrTooFewParticipants,
+	"tournament.ErrTooManyParticipants": tournament.ErrTooManyParticipants,
+	"tournament.ErrInvalidName":         tournament.ErrInvalidName,
+	"tournament.ErrDuplicateName":       tournament.ErrDuplicateName,
+	"tournament.ErrInvalidFormat":       tournament.ErrInvalidFormat,
+}
+
+// exported returns the exported Err variables declared in the package
+// in dir, as "pkg.ErrName".
+func exported(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := parser.ParseDir(token.NewFileSet(), dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for pkg, p := range files {
+		for _, f := range p.Files {
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						if strings.HasPrefix(name.Name, "Err") && name.IsExported() {
+							names = append(names, pkg+"."+name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// TestEveryErrorIsMapped checks that every error a handler can be
+// given, from any of the server's packages, is told to clients with a
+// code rather than as an internal error.
+func TestEveryErrorIsMapped(t *testing.T) {
+	dirs, err := filepath.Glob("../*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		for _, name := range exported(t, dir) {
+			if _, ok := errs[name]; !ok {
+				t.Errorf("%s is missing from errs: add it, and map it in errcode if it has no code", name)
+			}
+		}
+	}
+
+	for name, err := range errs {
+		if strings.HasPrefix(name, "httpx.") {
+			continue
+		}
+		if info := errcode.FromError(err); info.Code == "" {
+			t.Errorf("%s is told as %+v, with no code", name, info)
+		}
+	}
+	// The game service's errors are all mapped by status, not left to
+	// fall through to a 500 with the code they carry
+	for _, err := range game.Errors() {
+		if errcode.FromError(err).Status == http.StatusInternalServerError {
+			t.Errorf("no status for %q", game.Code(err))
+		}
+	}
+}
+
+// TestPathErrors checks the errors httpx reads path parameters with,
+// which errcode can't import, are answered as the client's mistake.
+func TestPathErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{httpx.ErrBadGameID, http.StatusNotFound},
+		{&httpx.ParamError{Name: "cell", Value: "x", Min: 0, Max: 8}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		httpx.WriteError(w, httptest.NewRequest(http.MethodPost, "/api/game/abc", nil), tt.err)
+		if w.Code != tt.status {
+			t.Errorf("%v answered with %d, want %d", tt.err, w.Code, tt.status)
+		}
+	}
+	if !errors.Is(httpx.ErrBadGameID, game.ErrGameNotFound) {
+		t.Error("ErrBadGameID isn't told as ErrGameNotFound")
+	}
+}
@@ -0,0 +1,289 @@
+// Package featured keeps a feed of finished games worth watching again.
+package featured
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// MaxEntries is how many unpinned games the feed keeps.
+const MaxEntries = 20
+
+// Reason is why a game was featured.
+type Reason string
+
+// The reasons a game is featured for, in the order Score prefers them.
+const (
+	// ReasonComeback is a win by the side that was lost at some point:
+	// the loser had a forced win and let it slip.
+	ReasonComeback Reason = "comeback"
+	// ReasonLongestWin is a win on the move that filled the board, as
+	// long as a game can go.
+	ReasonLongestWin Reason = "longest-win"
+	// ReasonFastestWin is a win with the fewest marks it takes, three.
+	ReasonFastestWin Reason = "fastest-win"
+)
+
+var ErrNotFeatured = errors.New("that game isn't featured")
+
+// Entry is a featured game.
+type Entry struct {
+	GameID string `json:"gameId"`
+	// Slug is the game's name, if it has one.
+	Slug   string        `json:"slug,omitempty"`
+	Reason Reason        `json:"reason"`
+	Winner models.Player `json:"winner"`
+	// Symbols are the marks the sides played with, X's then O's.
+	Symbols    [2]string    `json:"symbols"`
+	Moves      int          `json:"moves"`
+	Board      models.Board `json:"board"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Pinned     bool         `json:"pinned,omitempty"`
+}
+
+// Score returns why the finished game g is worth featuring, and false if
+// it isn't: draws, claimed wins and games from a start position never
+// are, and a win on the board only for one of the reasons.
+func Score(g *models.GameState) (Reason, bool) {
+	if !g.IsOver || g.Winner == models.Empty || g.WinClaimed || g.StartPosition != "" {
+		return "", false
+	}
+	switch {
+	case cameBack(g):
+		return ReasonComeback, true
+	case full(g.Board):
+		return ReasonLongestWin, true
+	case marks(g.Board, g.Winner) == 3:
+		return ReasonFastestWin, true
+	}
+	return "", false
+}
+
+// cameBack reports whether, at some point before its last move, the
+// loser of g had a forced win, replaying its history from the empty
+// board with the side that moved next to move.
+func cameBack(g *models.GameState) bool {
+	loser := models.PlayerX
+	if g.Winner == models.PlayerX {
+		loser = models.PlayerO
+	}
+	var board models.Board
+	for i, m := range g.History[:max(len(g.History)-1, 0)] {
+		board[m.Position] = m.Player
+		if game.Evaluate(board, g.History[i+1].Player).Winner == loser {
+			return true
+		}
+	}
+	return false
+}
+
+func full(board models.Board) bool {
+	return !slices.Contains(board[:], models.Empty)
+}
+
+func marks(board models.Board, p models.Player) int {
+	n := 0
+	for _, cell := range board {
+		if cell == p {
+			n++
+		}
+	}
+	return n
+}
+
+// Store is where the feed is kept, such as alongside the games, see
+// storage.Featured.
+type Store interface {
+	// PutFeatured inserts or replaces an entry.
+	PutFeatured(ctx context.Context, entry Entry) error
+	// DeleteFeatured removes an entry. Deleting an unknown one is not an
+	// error.
+	DeleteFeatured(ctx context.Context, gameID string) error
+	// ListFeatured returns every stored entry.
+	ListFeatured(ctx context.Context) ([]Entry, error)
+}
+
+// MemoryStore keeps the feed in a map, lost on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+// PutFeatured stores entry.
+func (s *MemoryStore) PutFeatured(_ context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.GameID] = entry
+	return nil
+}
+
+// DeleteFeatured removes the entry of the given game.
+func (s *MemoryStore) DeleteFeatured(_ context.Context, gameID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, gameID)
+	return nil
+}
+
+// ListFeatured returns every entry.
+func (s *MemoryStore) ListFeatured(context.Context) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Feed is the list of featured games. It reads its store once, when it
+// is created, and writes every change through to it. It is safe for
+// concurrent use.
+type Feed struct {
+	store Store
+
+	mu       sync.Mutex
+	entries  []Entry
+	onChange []func([]Entry)
+}
+
+// NewFeed creates a feed of the entries in store.
+func NewFeed(ctx context.Context, store Store) (*Feed, error) {
+	entries, err := store.ListFeatured(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading featured games: %w", err)
+	}
+	f := &Feed{store: store, entries: entries}
+	f.sort()
+	return f, nil
+}
+
+// OnChange registers fn to be called with the feed, as List returns it,
+// whenever it changes. It is called without the feed's lock held.
+func (f *Feed) OnChange(fn func([]Entry)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onChange = append(f.onChange, fn)
+}
+
+// List returns the feed: pinned entries, then the rest, each newest
+// first.
+func (f *Feed) List() []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.entries)
+}
+
+// Consider features the finished game g if Score finds it notable,
+// making room by dropping the oldest unpinned entries. It reports
+// whether g was featured.
+func (f *Feed) Consider(ctx context.Context, g *models.GameState) (bool, error) {
+	reason, ok := Score(g)
+	if !ok {
+		return false, nil
+	}
+	entry := Entry{
+		GameID:     g.ID,
+		Slug:       g.Slug,
+		Reason:     reason,
+		Winner:     g.Winner,
+		Symbols:    [2]string{g.Symbol(models.PlayerX), g.Symbol(models.PlayerO)},
+		Moves:      len(g.History),
+		Board:      g.Board,
+		FinishedAt: g.UpdatedAt,
+	}
+
+	f.mu.Lock()
+	if i := f.index(g.ID); i >= 0 {
+		// A game played again after a reset keeps its pin
+		entry.Pinned = f.entries[i].Pinned
+		f.entries = slices.Delete(f.entries, i, i+1)
+	}
+	if err := f.store.PutFeatured(ctx, entry); err != nil {
+		f.mu.Unlock()
+		return false, err
+	}
+	f.entries = append(f.entries, entry)
+	f.sort()
+	var err error
+	unpinned := 0
+	f.entries = slices.DeleteFunc(f.entries, func(e Entry) bool {
+		if e.Pinned {
+			return false
+		}
+		if unpinned++; unpinned <= MaxEntries {
+			return false
+		}
+		err = errors.Join(err, f.store.DeleteFeatured(ctx, e.GameID))
+		return true
+	})
+	f.changed()
+	return true, err
+}
+
+// Pin pins or unpins the featured game with the given ID. It returns
+// ErrNotFeatured if the game isn't in the feed.
+func (f *Feed) Pin(ctx context.Context, gameID string, pinned bool) error {
+	f.mu.Lock()
+	i := f.index(gameID)
+	if i < 0 {
+		f.mu.Unlock()
+		return ErrNotFeatured
+	}
+	entry := f.entries[i]
+	entry.Pinned = pinned
+	if err := f.store.PutFeatured(ctx, entry); err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	f.entries[i] = entry
+	f.sort()
+	f.changed()
+	return nil
+}
+
+// changed unlocks the feed and tells the OnChange callbacks about it.
+// Must be called with the lock held.
+func (f *Feed) changed() {
+	entries := slices.Clone(f.entries)
+	fns := f.onChange
+	f.mu.Unlock()
+	for _, fn := range fns {
+		fn(entries)
+	}
+}
+
+// index returns where the entry of the given game is, or -1. Must be
+// called with the lock held.
+func (f *Feed) index(gameID string) int {
+	return slices.IndexFunc(f.entries, func(e Entry) bool { return e.GameID == gameID })
+}
+
+// sort puts the entries in List's order. Must be called with the lock
+// held.
+func (f *Feed) sort() {
+	slices.SortStableFunc(f.entries, func(a, b Entry) int {
+		switch {
+		case a.Pinned != b.Pinned:
+			if a.Pinned {
+				return -1
+			}
+			return 1
+		case !a.FinishedAt.Equal(b.FinishedAt):
+			return b.FinishedAt.Compare(a.FinishedAt)
+		}
+		return 0
+	})
+}
@@ -0,0 +1,143 @@
+package featured_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// play plays a hotseat game through the given positions, X first, and
+// returns it as it stands after the last.
+func play(t *testing.T, s *game.Service, positions ...int) *models.GameState {
+	t.Helper()
+	ctx := context.Background()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := models.PlayerX
+	for _, pos := range positions {
+		if g, err = s.MakeMove(ctx, g.ID, models.Move{Position: pos, Player: player}); err != nil {
+			t.Fatalf("%v at %d: %v", player, pos, err)
+		}
+		player = g.CurrentTurn
+	}
+	return g
+}
+
+func TestScore(t *testing.T) {
+	s := game.NewService()
+	defer s.Close()
+	fastest := play(t, s, 0, 1, 3, 2, 6)
+	claimed, fromPosition := *fastest, *fastest
+	claimed.WinClaimed = true
+	fromPosition.StartPosition = "X........"
+
+	tests := []struct {
+		name string
+		g    *models.GameState
+		want featured.Reason
+		ok   bool
+	}{
+		// O had a forced win after X's third mark and let X through;
+		// a comeback counts ahead of the three marks it took
+		{"comeback", play(t, s, 0, 5, 1, 3, 2), featured.ReasonComeback, true},
+		{"longest win", play(t, s, 0, 1, 2, 3, 4, 5, 7, 6, 8), featured.ReasonLongestWin, true},
+		{"fastest win", fastest, featured.ReasonFastestWin, true},
+		{"four marks on a board with room", play(t, s, 0, 4, 8, 2, 6, 3, 7), "", false},
+		{"draw", play(t, s, 0, 4, 8, 1, 7, 6, 2, 5, 3), "", false},
+		{"not over", play(t, s, 0, 1), "", false},
+		{"claimed win", &claimed, "", false},
+		{"from a start position", &fromPosition, "", false},
+	}
+	for _, tt := range tests {
+		if reason, ok := featured.Score(tt.g); reason != tt.want || ok != tt.ok {
+			t.Errorf("%s: Score = %q, %v, want %q, %v", tt.name, reason, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestFeed fills the feed past its limit and pins and unpins an entry,
+// checking the order List gives, what the store keeps and what
+// OnChange hears.
+func TestFeed(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	store := featured.NewMemoryStore()
+	feed, err := featured.NewFeed(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var heard [][]featured.Entry
+	feed.OnChange(func(entries []featured.Entry) { heard = append(heard, entries) })
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var games []*models.GameState
+	for i := range featured.MaxEntries + 2 {
+		g := play(t, s, 0, 1, 3, 2, 6)
+		g.UpdatedAt = start.Add(time.Duration(i) * time.Minute)
+		if ok, err := feed.Consider(ctx, g); !ok || err != nil {
+			t.Fatalf("game %d: Consider = %v, %v", i, ok, err)
+		}
+		games = append(games, g)
+	}
+	if ok, err := feed.Consider(ctx, play(t, s, 0, 4, 8, 1, 7, 6, 2, 5, 3)); ok || err != nil {
+		t.Errorf("a draw: Consider = %v, %v", ok, err)
+	}
+
+	list := feed.List()
+	if len(list) != featured.MaxEntries || list[0].GameID != games[len(games)-1].ID || list[len(list)-1].GameID != games[2].ID {
+		t.Fatalf("after %d games, the feed holds %d, from %s to %s; want the newest %d", len(games), len(list), list[0].GameID, list[len(list)-1].GameID, featured.MaxEntries)
+	}
+	if e := list[0]; e.Reason != featured.ReasonFastestWin || e.Winner != models.PlayerX || e.Moves != 5 || !e.FinishedAt.Equal(games[len(games)-1].UpdatedAt) {
+		t.Errorf("newest entry %+v", e)
+	}
+	if len(heard) != len(games) {
+		t.Errorf("OnChange heard %d changes, want %d", len(heard), len(games))
+	}
+
+	// A pinned entry goes to the top and stays when newer games push
+	// the others out, and keeps its pin when its game is featured again
+	oldest := games[2]
+	if err := feed.Pin(ctx, oldest.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		g := play(t, s, 0, 1, 3, 2, 6)
+		g.UpdatedAt = start.Add(time.Hour + time.Duration(i)*time.Minute)
+		feed.Consider(ctx, g)
+	}
+	feed.Consider(ctx, oldest)
+	list = feed.List()
+	if len(list) != featured.MaxEntries+1 || list[0].GameID != oldest.ID || !list[0].Pinned || list[1].Pinned {
+		t.Fatalf("after pinning the oldest game: %d entries, first %+v", len(list), list[0])
+	}
+	if last := heard[len(heard)-1]; len(last) != len(list) || last[0].GameID != oldest.ID {
+		t.Errorf("OnChange last heard %d entries, want the %d List gives", len(last), len(list))
+	}
+
+	// The store has what the feed has, pins and all
+	reloaded, err := featured.NewFeed(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.List(); len(got) != len(list) || got[0] != list[0] || got[1].GameID != list[1].GameID {
+		t.Errorf("reloaded from the store: %d entries, first %+v", len(got), got[0])
+	}
+
+	if err := feed.Pin(ctx, oldest.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if list = feed.List(); list[0].GameID == oldest.ID || list[len(list)-1].GameID != oldest.ID || list[len(list)-1].Pinned {
+		t.Errorf("after unpinning, the oldest game wasn't put back in its place: first %s, last %+v", list[0].GameID, list[len(list)-1])
+	}
+	if err := feed.Pin(ctx, "nosuchgame", true); !errors.Is(err, featured.ErrNotFeatured) {
+		t.Errorf("pinning a game not featured: %v", err)
+	}
+}
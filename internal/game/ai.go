@@ -0,0 +1,126 @@
+package game
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// ComputerPlayer is the side the computer plays in a game against it,
+// see models.ModeAI.
+const ComputerPlayer = models.PlayerO
+
+// DefaultThinkTime is the least time the computer takes over a move,
+// unless set with WithThinkTime, so its reply doesn't land before anyone
+// has seen the move it answers.
+const DefaultThinkTime = 500 * time.Millisecond
+
+// WithThinkTime sets the least time the computer takes over a move. Its
+// search runs meanwhile, and a move that takes longer to find is played
+// as soon as it is found.
+func WithThinkTime(d time.Duration) Option {
+	return func(s *Service) {
+		s.thinkTime = d
+	}
+}
+
+func (s *Service) thinkTimeOrDefault() time.Duration {
+	if s.thinkTime <= 0 {
+		return DefaultThinkTime
+	}
+	return s.thinkTime
+}
+
+// ComputerToMove reports whether the game waits for the computer's move,
+// which is what GameState.Thinking says.
+func ComputerToMove(game *models.GameState) bool {
+	return game.Mode == models.ModeAI && !game.IsOver && game.CurrentTurn == ComputerPlayer
+}
+
+// BestMove returns the move the computer plays for turn: the one with
+// the best outcome under perfect play, winning as quickly or losing as
+// slowly as it can, the first in board order among equals. It returns -1
+// for a finished board.
+func BestMove(board models.Board, turn models.Player) int {
+	if checkWinner(board) != models.Empty {
+		return -1
+	}
+	move := -1
+	var best Evaluation
+	for i, cell := range board {
+		if cell != models.Empty {
+			continue
+		}
+		board[i] = turn
+		e := evaluate(board, opponent(turn))
+		board[i] = models.Empty
+		if move < 0 || better(e, best, turn) {
+			move, best = i, e
+		}
+	}
+	return move
+}
+
+// think has the computer play its move in the game from one of its
+// workers. Must be called with the lock held.
+func (s *Service) think(game *models.GameState) {
+	thought := game.Clone()
+	s.Go(game.ID, func(ctx context.Context) {
+		wait := time.NewTimer(s.thinkTimeOrDefault())
+		defer wait.Stop()
+		position := BestMove(thought.Board, thought.CurrentTurn)
+		select {
+		case <-wait.C:
+		case <-ctx.Done():
+			return
+		}
+		if err := s.playComputerMove(thought, position); err != nil {
+			slog.Warn("computer move failed", "game_id", thought.ID, "error", err)
+		}
+	})
+}
+
+// playComputerMove plays the computer's move worked out for thought,
+// unless the game has moved on from it.
+func (s *Service) playComputerMove(thought *models.GameState, position int) error {
+	ctx := context.Background()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists, err := s.games.Get(ctx, thought.ID)
+	if err != nil {
+		return err
+	}
+	if !exists || !ComputerToMove(game) || game.Version != thought.Version {
+		return nil
+	}
+	if err := CheckMove(game, position, ComputerPlayer); err != nil {
+		return err
+	}
+	game, err = s.play(ctx, game, models.Move{Position: position, Player: ComputerPlayer})
+	if err != nil {
+		return err
+	}
+	s.hooks.emit(hookComputerMoved, game)
+	return nil
+}
+
+// ResumeThinking has the computer answer the games waiting for it, such
+// as those recovered at startup, which nothing has asked it to yet.
+func (s *Service) ResumeThinking(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.games.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, game := range all {
+		if ComputerToMove(game) {
+			s.think(game)
+		}
+	}
+	return nil
+}
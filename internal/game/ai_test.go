@@ -0,0 +1,105 @@
+package game_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+func TestComputerDropsStaleMoveWithinOneTick(t *testing.T) {
+	ctx := context.Background()
+	// The clock never moves, so every change shares one UpdatedAt
+	clock := gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	s := game.NewService(game.WithClock(clock), game.WithThinkTime(time.Hour))
+	defer s.Close()
+
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeAI}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	current, err := s.MakeMove(ctx, g.ID, models.Move{Position: 8, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.UpdatedAt.Equal(stale.UpdatedAt) || current.Version <= stale.Version {
+		t.Fatalf("versions %d then %d at %v and %v, want the same time and a higher version", stale.Version, current.Version, stale.UpdatedAt, current.UpdatedAt)
+	}
+
+	// The answer to X in the centre, a corner, is legal on the new board
+	// but answers a position the game has left
+	if err := s.PlayComputerMove(stale, game.BestMove(stale.Board, stale.CurrentTurn)); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := s.GetGame(ctx, g.ID)
+	if after.Board[0] != models.Empty || after.CurrentTurn != game.ComputerPlayer {
+		t.Fatalf("stale move played: board %v", after.Board)
+	}
+
+	if err := s.PlayComputerMove(current, game.BestMove(current.Board, current.CurrentTurn)); err != nil {
+		t.Fatal(err)
+	}
+	after, _ = s.GetGame(ctx, g.ID)
+	if after.Board[4] != game.ComputerPlayer || after.Version != current.Version+1 {
+		t.Errorf("current move not played: board %v, version %d", after.Board, after.Version)
+	}
+}
+
+func TestVersionIncreasesWithEveryChange(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService(game.WithClock(gametest.NewFakeClock(time.Unix(0, 0))))
+	defer s.Close()
+
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []uint64{g.Version}
+	steps := []func() (*models.GameState, error){
+		func() (*models.GameState, error) { return s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}) },
+		func() (*models.GameState, error) {
+			return s.MakeMove(ctx, g.ID, models.Move{Position: 0, Player: models.PlayerX})
+		},
+		func() (*models.GameState, error) { return s.ResetGame(ctx, g.ID, models.PlayerX) },
+		// Putting the game back as it was still counts as a change
+		func() (*models.GameState, error) { return s.UndoReset(ctx, g.ID, models.PlayerX) },
+	}
+	for i, step := range steps {
+		next, err := step()
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if last := versions[len(versions)-1]; next.Version <= last {
+			t.Errorf("step %d: version %d after %d", i, next.Version, last)
+		}
+		versions = append(versions, next.Version)
+	}
+}
+
+func TestBestMoveConcurrently(t *testing.T) {
+	// Searches of several games share the cache; run under -race
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var board models.Board
+			board[i] = models.PlayerX
+			if move := game.BestMove(board, models.PlayerO); move < 0 || board[move] != models.Empty {
+				t.Errorf("BestMove after X on %d = %d", i, move)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"tiktaktoes/internal/models"
+)
+
+// MaxBatchMoves bounds the moves of a batch, see MakeMoves: no game has
+// room for more.
+const MaxBatchMoves = models.BoardSize * models.BoardSize
+
+// BatchError is returned by MakeMoves when one of the moves of a batch
+// can't be made. Index is that move's, counting from zero, and Err why.
+// It matches Err.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("move %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// MakeMoves makes moves in an Exhibition game all or none, committed as
+// one change. A move that can't be made is named by a BatchError.
+func (s *Service) MakeMoves(ctx context.Context, gameID string, moves []models.Move) (*models.GameState, error) {
+	ctx, span := tracer.Start(ctx, "game.MakeMoves")
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("game.id", gameID),
+			attribute.Int("batch.moves", len(moves)),
+		)
+	}
+
+	game, err := s.makeMoves(ctx, gameID, moves)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return game, err
+}
+
+func (s *Service) makeMoves(ctx context.Context, gameID string, moves []models.Move) (*models.GameState, error) {
+	if len(moves) == 0 || len(moves) > MaxBatchMoves {
+		return nil, ErrInvalidBatch
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if !game.Exhibition {
+		return nil, ErrNoBatch
+	}
+
+	// Each move is checked against the board the ones before it left,
+	// on a clone nobody else sees until it is committed
+	game = game.Clone()
+	batch := uuid.NewString()
+	now := s.clock.Now()
+	for i, move := range moves {
+		move, err := checkPlayable(game, move)
+		if err == nil {
+			err = place(game, move, batch, now)
+		}
+		if err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+	}
+
+	if err := s.commit(ctx, EventMoved, game); err != nil {
+		return nil, err
+	}
+	s.hooks.emit(hookMoved, game)
+	if game.IsOver {
+		s.hooks.emit(hookFinished, game)
+	}
+	return game, nil
+}
@@ -0,0 +1,111 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// exhibitionGame returns a new hot-seat game on s that takes batches.
+func exhibitionGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	g, err := s.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat, Exhibition: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestMakeMoves(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := exhibitionGame(t, s)
+
+	opening := []models.Move{{Player: models.PlayerX, Position: 0}, {Player: models.PlayerO, Position: 4}}
+	first, err := s.MakeMoves(ctx, g.ID, opening)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Board[0] != models.PlayerX || first.Board[4] != models.PlayerO || first.CurrentTurn != models.PlayerX || first.Version != g.Version+1 {
+		t.Errorf("after the opening: board %v, %s to move, version %d after %d", first.Board, first.CurrentTurn, first.Version, g.Version)
+	}
+	batch := first.History[0].Batch
+	if batch == "" || first.History[1].Batch != batch {
+		t.Errorf("the opening's moves have batches %q and %q, want one shared", batch, first.History[1].Batch)
+	}
+
+	// X wins on the third move, by cell name
+	won, err := s.MakeMoves(ctx, g.ID, []models.Move{{Player: models.PlayerX, Position: 1}, {Player: models.PlayerO, Position: 8}, {Player: models.PlayerX, Cell: "c1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if won.Winner != models.PlayerX || !won.IsOver || len(won.History) != 5 {
+		t.Errorf("winner %q, over %v after %d moves, want X after 5", won.Winner, won.IsOver, len(won.History))
+	}
+	if second := won.History[2].Batch; second == "" || second == batch || won.History[4].Batch != second {
+		t.Errorf("the second batch's moves have batches %q and %q, want one of their own", second, won.History[4].Batch)
+	}
+	var batchErr *game.BatchError
+	if _, err := s.MakeMoves(ctx, g.ID, []models.Move{{Player: models.PlayerO, Position: 5}}); !errors.As(err, &batchErr) || batchErr.Index != 0 || !errors.Is(err, game.ErrGameOver) {
+		t.Errorf("a batch in a finished game: %v", err)
+	}
+}
+
+// TestMakeMovesRollsBack checks a batch with a move that can't be made
+// leaves the game as it was and names that move.
+func TestMakeMovesRollsBack(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+
+	tests := []struct {
+		name  string
+		moves []models.Move
+		index int
+		want  error
+	}{
+		{"a taken cell", []models.Move{{Player: models.PlayerX, Position: 0}, {Player: models.PlayerO, Position: 4}, {Player: models.PlayerX, Position: 4}}, 2, game.ErrPositionTaken},
+		{"out of turn", []models.Move{{Player: models.PlayerX, Position: 0}, {Player: models.PlayerX, Position: 1}}, 1, game.ErrNotYourTurn},
+		{"off the board", []models.Move{{Player: models.PlayerX, Position: 9}}, 0, game.ErrInvalidMove},
+		{"past the win", []models.Move{{Player: models.PlayerX, Position: 0}, {Player: models.PlayerO, Position: 3}, {Player: models.PlayerX, Position: 1}, {Player: models.PlayerO, Position: 4}, {Player: models.PlayerX, Position: 2}, {Player: models.PlayerO, Position: 5}}, 5, game.ErrGameOver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := exhibitionGame(t, s)
+			_, err := s.MakeMoves(ctx, g.ID, tt.moves)
+			var batchErr *game.BatchError
+			if !errors.As(err, &batchErr) || batchErr.Index != tt.index || !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v at move %d", err, tt.want, tt.index)
+			}
+			after, _ := s.GetGame(ctx, g.ID)
+			if len(after.History) != 0 || after.Board != (models.Board{}) || after.Version != g.Version {
+				t.Errorf("the game changed: board %v, history %+v, version %d", after.Board, after.History, after.Version)
+			}
+		})
+	}
+}
+
+func TestMakeMovesRefused(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := exhibitionGame(t, s)
+
+	tooMany := make([]models.Move, game.MaxBatchMoves+1)
+	for _, moves := range [][]models.Move{nil, tooMany} {
+		if _, err := s.MakeMoves(ctx, g.ID, moves); !errors.Is(err, game.ErrInvalidBatch) {
+			t.Errorf("a batch of %d: %v", len(moves), err)
+		}
+	}
+	live := hotseatGame(t, s)
+	if _, err := s.MakeMoves(ctx, live.ID, []models.Move{{Player: models.PlayerX, Position: 0}}); !errors.Is(err, game.ErrNoBatch) {
+		t.Errorf("a batch in a game that isn't an exhibition: %v", err)
+	}
+	if _, err := s.MakeMoves(ctx, "nosuchgame", []models.Move{{Player: models.PlayerX, Position: 0}}); !errors.Is(err, game.ErrGameNotFound) {
+		t.Errorf("a batch in a game that doesn't exist: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// DefaultClaimAfter is how long an opponent must have been away before
+// the win can be claimed, unless set with WithClaimAfter.
+const DefaultClaimAfter = 2 * time.Minute
+
+// DefaultVacateAfter is how long a player must have been away before
+// their slot can be vacated, unless set with WithVacateAfter.
+const DefaultVacateAfter = 30 * time.Second
+
+// Presence reports whether a player is connected to a game. OfflineSince
+// tells whether player has no live view of the game open and, if so,
+// since when.
+type Presence interface {
+	OfflineSince(gameID string, player models.Player) (since time.Time, offline bool)
+}
+
+// WithPresence lets players claim the win from an opponent p has seen
+// leave, see ClaimWin. Without it, wins can't be claimed.
+func WithPresence(p Presence) Option {
+	return func(s *Service) {
+		s.presence = p
+	}
+}
+
+// WithClaimAfter sets how long an opponent must have been away before the
+// win can be claimed.
+func WithClaimAfter(d time.Duration) Option {
+	return func(s *Service) {
+		s.claimAfter = d
+	}
+}
+
+// WithVacateAfter sets how long a player must have been away before their
+// slot can be vacated, see VacateSlot.
+func WithVacateAfter(d time.Duration) Option {
+	return func(s *Service) {
+		s.vacateAfter = d
+	}
+}
+
+// ClaimError is returned by ClaimWin when the opponent hasn't been away
+// long enough yet. It matches ErrClaimTooEarly.
+type ClaimError struct {
+	// At is when the win can be claimed if the opponent stays away.
+	At        time.Time
+	Remaining time.Duration
+}
+
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("%v: you can claim the win in %s if they stay away", ErrClaimTooEarly, e.Remaining.Round(time.Second))
+}
+
+func (e *ClaimError) Unwrap() error {
+	return ErrClaimTooEarly
+}
+
+// ClaimableAt returns when player may claim the win because their
+// opponent left, see WithClaimAfter.
+func (s *Service) ClaimableAt(ctx context.Context, gameID string, player models.Player) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.claimableAt(game, player)
+}
+
+// claimableAt is ClaimableAt for a game already looked up. Must be called
+// with the lock held.
+func (s *Service) claimableAt(game *models.GameState, player models.Player) (time.Time, error) {
+	if player != models.PlayerX && player != models.PlayerO {
+		return time.Time{}, ErrInvalidPlayer
+	}
+	switch {
+	case game.IsOver:
+		return time.Time{}, ErrGameOver
+	case s.presence == nil, game.Mode != models.ModeOnline, !game.PlayerXJoined || !game.PlayerOJoined,
+		!Started(game):
+		return time.Time{}, ErrNoClaim
+	}
+	since, offline := s.presence.OfflineSince(game.ID, opponent(player))
+	if !offline {
+		return time.Time{}, ErrOpponentPresent
+	}
+	// An opponent who never had a live view open, say one playing over
+	// the API, has been away since their last move at the earliest
+	if active := lastActive(game, opponent(player)); active.After(since) {
+		since = active
+	}
+	after := s.claimAfter
+	if after <= 0 {
+		after = DefaultClaimAfter
+	}
+	return since.Add(after), nil
+}
+
+// ClaimWin ends the game with player as the winner because their opponent
+// left and hasn't come back, see ClaimableAt. A claim made too early
+// fails with a *ClaimError saying how long remains.
+func (s *Service) ClaimWin(ctx context.Context, gameID string, player models.Player) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	at, err := s.claimableAt(game, player)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if now.Before(at) {
+		return nil, &ClaimError{At: at, Remaining: at.Sub(now)}
+	}
+
+	game = game.Clone()
+	game.Winner = player
+	game.IsOver = true
+	game.WinClaimed = true
+	game.DrawOffer = models.Empty
+	game.UpdatedAt = now
+	if err := s.commit(ctx, EventClaimed, game); err != nil {
+		return nil, err
+	}
+	s.hooks.emit(hookFinished, game)
+	return game, nil
+}
+
+// lastActive returns when player last moved in the game, or when the
+// first move was made if they haven't yet. The game must have been
+// started, see Started.
+func lastActive(game *models.GameState, player models.Player) time.Time {
+	first := placed(game)
+	for i := len(game.History) - 1; i > first; i-- {
+		if game.History[i].Player == player {
+			return game.History[i].At
+		}
+	}
+	return game.History[first].At
+}
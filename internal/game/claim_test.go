@@ -0,0 +1,138 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// claimableGame returns a claims service timed by clock and a game on it
+// where X has made the first move, with both players connected.
+func claimableGame(t *testing.T, clock *gametest.FakeClock) (*game.Service, *gametest.Presence, *models.GameState) {
+	t.Helper()
+	presence := gametest.NewPresence(clock)
+	s := game.NewService(game.WithClock(clock), game.WithPresence(presence), game.WithClaimAfter(time.Minute))
+	t.Cleanup(func() { s.Close() })
+	g := joinedGame(t, s)
+	presence.Connect(g.ID, models.PlayerX)
+	presence.Connect(g.ID, models.PlayerO)
+	g, err := s.MakeMove(context.Background(), g.ID, models.Move{Position: 4, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, presence, g
+}
+
+func TestClaimWinTooEarly(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s, presence, g := claimableGame(t, clock)
+
+	if _, err := s.ClaimWin(ctx, g.ID, models.PlayerX); !errors.Is(err, game.ErrOpponentPresent) {
+		t.Fatalf("claiming while O is connected: err = %v, want ErrOpponentPresent", err)
+	}
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(40 * time.Second)
+	_, err := s.ClaimWin(ctx, g.ID, models.PlayerX)
+	var early *game.ClaimError
+	if !errors.As(err, &early) || !errors.Is(err, game.ErrClaimTooEarly) {
+		t.Fatalf("claiming 40s after O left: err = %v, want a ClaimError", err)
+	}
+	if early.Remaining != 20*time.Second || !early.At.Equal(start.Add(time.Minute)) {
+		t.Errorf("claimable in %s at %v", early.Remaining, early.At)
+	}
+	if got, _ := s.GetGame(ctx, g.ID); got.IsOver {
+		t.Error("an early claim ended the game")
+	}
+}
+
+func TestClaimWinAfterReconnecting(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s, presence, g := claimableGame(t, clock)
+
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(50 * time.Second)
+	// O comes back for a moment, which starts the wait over
+	presence.Connect(g.ID, models.PlayerO)
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(30 * time.Second)
+	at, err := s.ClaimableAt(ctx, g.ID, models.PlayerX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(50*time.Second + time.Minute); !at.Equal(want) {
+		t.Errorf("claimable at %v, want a minute after O left again, %v", at, want)
+	}
+	if _, err := s.ClaimWin(ctx, g.ID, models.PlayerX); !errors.Is(err, game.ErrClaimTooEarly) {
+		t.Errorf("claiming a minute and 20s after O first left: err = %v, want ErrClaimTooEarly", err)
+	}
+}
+
+func TestClaimWin(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s, presence, g := claimableGame(t, clock)
+	finished := make(chan models.GameState, 1)
+	s.OnGameFinished(func(gs models.GameState) { finished <- gs })
+
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(time.Minute)
+	won, err := s.ClaimWin(ctx, g.ID, models.PlayerX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !won.IsOver || won.Winner != models.PlayerX || !won.WinClaimed || !won.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("claimed game: over %v, winner %q, claimed %v, updated %v", won.IsOver, won.Winner, won.WinClaimed, won.UpdatedAt)
+	}
+	select {
+	case gs := <-finished:
+		if gs.ID != g.ID || gs.Winner != models.PlayerX {
+			t.Errorf("finished hook got %s won by %q", gs.ID, gs.Winner)
+		}
+	case <-time.After(time.Second):
+		t.Error("the finished hook wasn't called")
+	}
+	if _, err := s.ClaimWin(ctx, g.ID, models.PlayerX); !errors.Is(err, game.ErrGameOver) {
+		t.Errorf("claiming again: err = %v, want ErrGameOver", err)
+	}
+}
+
+func TestClaimWinRefusals(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	presence := gametest.NewPresence(clock)
+	s := game.NewService(game.WithClock(clock), game.WithPresence(presence))
+	defer s.Close()
+	unstarted := joinedGame(t, s)
+	hotseat, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MakeMove(ctx, hotseat.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+
+	tests := []struct {
+		name   string
+		gameID string
+		player models.Player
+		want   error
+	}{
+		{"before the first move", unstarted.ID, models.PlayerX, game.ErrNoClaim},
+		{"a hot-seat game", hotseat.ID, models.PlayerO, game.ErrNoClaim},
+		{"a spectator", unstarted.ID, models.Empty, game.ErrInvalidPlayer},
+		{"a missing game", "missing", models.PlayerX, game.ErrGameNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.ClaimWin(ctx, tt.gameID, tt.player); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package game
+
+import (
+	"errors"
+
+	"tiktaktoes/internal/text"
+)
+
+// errorCodes names the service's errors for clients. Codes are part of
+// the API: unlike the messages they never change, so clients can match
+// on them and translate them.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	// An invalid state is told as such whichever check it failed
+	{ErrInvalidState, "invalid_state"},
+	{ErrGameNotFound, "game_not_found"},
+	{ErrAmbiguousID, "ambiguous_id"},
+	{ErrInvalidMove, "invalid_move"},
+	{ErrNotYourTurn, "not_your_turn"},
+	{ErrGameOver, "game_over"},
+	{ErrPositionTaken, "position_taken"},
+	{ErrGameFull, "game_full"},
+	{ErrSlotTaken, "slot_taken"},
+	{ErrInvalidPlayer, "invalid_player"},
+	{ErrIDExhausted, "id_exhausted"},
+	{ErrJournal, "journal"},
+	{ErrInvalidSymbol, "invalid_symbol"},
+	{ErrSymbolTaken, "symbol_taken"},
+	{ErrGameStarted, "game_started"},
+	{ErrSlotEmpty, "slot_empty"},
+	{ErrInvalidMode, "invalid_mode"},
+	{ErrWaiting, "waiting_for_opponent"},
+	{ErrDrawOffered, "draw_already_offered"},
+	{ErrNoDrawOffer, "no_draw_offer"},
+	{ErrQuotaExceeded, "quota_exceeded"},
+	{ErrServerFull, "server_full"},
+	{ErrNoClaim, "no_claim"},
+	{ErrOpponentPresent, "opponent_present"},
+	{ErrClaimTooEarly, "claim_too_early"},
+	{ErrVacateTooEarly, "vacate_too_early"},
+	{ErrGameExists, "game_exists"},
+	{ErrInvalidHandicap, "invalid_handicap"},
+	{ErrInvalidStart, "invalid_start"},
+	{ErrSettingsLocked, "settings_locked"},
+	{ErrNotCreator, "not_creator"},
+	{ErrModeFixed, "mode_fixed"},
+	{ErrNotStarted, "not_started"},
+	{ErrNoReadyCheck, "no_ready_check"},
+	{ErrReadyCheckMode, "ready_check_mode"},
+	{ErrNothingToUndo, "nothing_to_undo"},
+	{ErrUndoStale, "undo_stale"},
+	{ErrNotResetter, "not_resetter"},
+	{ErrInvalidSlug, "invalid_slug"},
+	{ErrSlugTaken, "slug_taken"},
+	{ErrComputerSide, "computer_side"},
+	{ErrComputerDraw, "computer_draw"},
+	{ErrInvalidBatch, "invalid_batch"},
+	{ErrNoBatch, "no_batch"},
+	{ErrReadOnly, "read_only"},
+	{ErrInvalidLocale, "invalid_locale"},
+	{text.ErrRejected, "text_rejected"},
+}
+
+// Errors returns every error that has a code, see Code.
+func Errors() []error {
+	errs := make([]error, len(errorCodes))
+	for i, e := range errorCodes {
+		errs[i] = e.err
+	}
+	return errs
+}
+
+// Code returns the stable code of one of the service's errors, such as
+// "not_your_turn", or "" for any other error.
+func Code(err error) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return ""
+}
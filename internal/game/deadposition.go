@@ -0,0 +1,108 @@
+package game
+
+import (
+	"context"
+	"log/slog"
+
+	"tiktaktoes/internal/models"
+)
+
+// EventDeadPosition is the journal event of a game with EarlyDraw ended
+// as a draw because neither side can win anymore.
+const EventDeadPosition = "dead-position"
+
+// winnable memoizes Dead's search like solved does Evaluate's, and is
+// guarded by solvedMu too.
+var winnable = map[solverKey]bool{}
+
+// Dead reports whether no way of playing on from the position, with turn
+// to move and the sides alternating, gives either side a line, so the
+// game can only end in a draw. A won or full board isn't dead: it is
+// already over.
+func Dead(board models.Board, turn models.Player) bool {
+	if checkWinner(board) != models.Empty || isBoardFull(board) {
+		return false
+	}
+	solvedMu.Lock()
+	defer solvedMu.Unlock()
+	return !canWin(board, turn)
+}
+
+// canWin reports whether some way of playing on from the position ends
+// in a win for either side. Must be called with solvedMu held.
+func canWin(board models.Board, turn models.Player) bool {
+	if checkWinner(board) != models.Empty {
+		return true
+	}
+	if isBoardFull(board) {
+		return false
+	}
+	key := solverKey{board, turn}
+	if w, ok := winnable[key]; ok {
+		return w
+	}
+	w := false
+	for i, cell := range board {
+		if cell != models.Empty {
+			continue
+		}
+		board[i] = turn
+		w = canWin(board, opponent(turn))
+		board[i] = models.Empty
+		if w {
+			break
+		}
+	}
+	winnable[key] = w
+	return w
+}
+
+// checkDead has one of the game's workers find out whether the game,
+// just committed with EarlyDraw set, is dead and if so end it as a
+// draw. The search runs off the lock so it never holds up the move that
+// led to the position. Must be called with the lock held.
+func (s *Service) checkDead(game *models.GameState) {
+	// Before any handicap's double move has been played the board has a
+	// mark at most, which is never dead, so the sides alternate from any
+	// position that could be
+	if len(game.History) < 2 {
+		return
+	}
+	checked := game.Clone()
+	s.Go(game.ID, func(ctx context.Context) {
+		if !Dead(checked.Board, checked.CurrentTurn) || ctx.Err() != nil {
+			return
+		}
+		if err := s.endDead(checked); err != nil {
+			slog.Warn("ending dead position failed", "game_id", checked.ID, "error", err)
+		}
+	})
+}
+
+// endDead ends the game checked, found dead, as a draw, unless it has
+// moved on from that position meanwhile.
+func (s *Service) endDead(checked *models.GameState) error {
+	ctx := context.Background()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists, err := s.games.Get(ctx, checked.ID)
+	if err != nil {
+		return err
+	}
+	if !exists || game.IsOver || game.Version != checked.Version {
+		return nil
+	}
+	game = game.Clone()
+	game.IsDraw = true
+	game.IsOver = true
+	game.DrawReason = models.DrawDeadPosition
+	game.DrawOffer = models.Empty
+	game.UpdatedAt = s.clock.Now()
+	if err := s.commit(ctx, EventDeadPosition, game); err != nil {
+		return err
+	}
+	s.hooks.emit(hookDeadPosition, game)
+	s.hooks.emit(hookFinished, game)
+	return nil
+}
@@ -0,0 +1,49 @@
+package game_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+func TestEndDeadDropsStaleStateWithinOneTick(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	s := game.NewService(game.WithClock(clock))
+	defer s.Close()
+
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat, EarlyDraw: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 0, Player: models.PlayerX}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Found dead or not, the state checked is one the game has left
+	if err := s.EndDead(checked); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := s.GetGame(ctx, g.ID); after.IsOver {
+		t.Errorf("game in progress ended as %q from a stale state", after.DrawReason)
+	}
+
+	current, _ := s.GetGame(ctx, g.ID)
+	if err := s.EndDead(current); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := s.GetGame(ctx, g.ID); !after.IsOver || after.DrawReason != models.DrawDeadPosition {
+		t.Errorf("current state not ended: over %v, reason %q", after.IsOver, after.DrawReason)
+	}
+}
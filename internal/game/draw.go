@@ -0,0 +1,82 @@
+package game
+
+import (
+	"context"
+
+	"tiktaktoes/internal/models"
+)
+
+// OfferDraw offers the opponent a draw on the player's turn. The offer
+// stands until the opponent answers it with RespondDraw or the player
+// moves instead. A player may offer once a turn, and not before the
+// first move.
+func (s *Service) OfferDraw(ctx context.Context, gameID string, player models.Player) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if player != models.PlayerX && player != models.PlayerO {
+		return nil, ErrInvalidPlayer
+	}
+	switch {
+	case game.IsOver:
+		return nil, ErrGameOver
+	case Waiting(game):
+		return nil, ErrWaiting
+	case game.Mode == models.ModeAI:
+		return nil, ErrComputerDraw
+	case player != game.CurrentTurn:
+		return nil, ErrNotYourTurn
+	case !Started(game) || game.DrawOfferedAt == len(game.History):
+		return nil, ErrDrawOffered
+	}
+
+	game = game.Clone()
+	game.DrawOffer = player
+	game.DrawOfferedAt = len(game.History)
+	game.UpdatedAt = s.clock.Now()
+	if err := s.commit(ctx, EventDrawOffer, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// RespondDraw answers the opponent's offer of a draw. Accepting ends the
+// game as a draw; declining leaves it to be played on.
+func (s *Service) RespondDraw(ctx context.Context, gameID string, player models.Player, accept bool) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if player != models.PlayerX && player != models.PlayerO {
+		return nil, ErrInvalidPlayer
+	}
+	if game.IsOver {
+		return nil, ErrGameOver
+	}
+	if game.DrawOffer == models.Empty || game.DrawOffer == player {
+		return nil, ErrNoDrawOffer
+	}
+
+	game = game.Clone()
+	game.DrawOffer = models.Empty
+	if accept {
+		game.IsDraw = true
+		game.IsOver = true
+		game.DrawAgreed = true
+	}
+	game.UpdatedAt = s.clock.Now()
+	if err := s.commit(ctx, EventDrawReply, game); err != nil {
+		return nil, err
+	}
+	if accept {
+		s.hooks.emit(hookFinished, game)
+	}
+	return game, nil
+}
@@ -0,0 +1,129 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// openedGame returns a joined online game X has made the first move in.
+func openedGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	g, err := s.MakeMove(context.Background(), joinedGame(t, s).ID, models.Move{Position: 4, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDrawAccepted(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	finished := make(chan models.GameState, 1)
+	s.OnGameFinished(func(gs models.GameState) { finished <- gs })
+	g := openedGame(t, s)
+
+	offered, err := s.OfferDraw(ctx, g.ID, models.PlayerO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offered.DrawOffer != models.PlayerO {
+		t.Errorf("draw offer %q, want O", offered.DrawOffer)
+	}
+	if _, err := s.RespondDraw(ctx, g.ID, models.PlayerO, true); !errors.Is(err, game.ErrNoDrawOffer) {
+		t.Errorf("accepting your own offer: err = %v, want ErrNoDrawOffer", err)
+	}
+	drawn, err := s.RespondDraw(ctx, g.ID, models.PlayerX, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drawn.IsOver || !drawn.IsDraw || !drawn.DrawAgreed || drawn.DrawOffer != models.Empty {
+		t.Errorf("accepted draw: over %v, draw %v, agreed %v, offer %q", drawn.IsOver, drawn.IsDraw, drawn.DrawAgreed, drawn.DrawOffer)
+	}
+	select {
+	case gs := <-finished:
+		if !gs.DrawAgreed {
+			t.Error("the finished hook got a game not drawn by agreement")
+		}
+	case <-time.After(time.Second):
+		t.Error("the finished hook wasn't called")
+	}
+}
+
+func TestDrawDeclinedAndExpired(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := openedGame(t, s)
+
+	if _, err := s.OfferDraw(ctx, g.ID, models.PlayerO); err != nil {
+		t.Fatal(err)
+	}
+	declined, err := s.RespondDraw(ctx, g.ID, models.PlayerX, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if declined.IsOver || declined.DrawOffer != models.Empty {
+		t.Errorf("declined draw: over %v, offer %q", declined.IsOver, declined.DrawOffer)
+	}
+	// Once a turn
+	if _, err := s.OfferDraw(ctx, g.ID, models.PlayerO); !errors.Is(err, game.ErrDrawOffered) {
+		t.Errorf("offering twice in a turn: err = %v, want ErrDrawOffered", err)
+	}
+
+	// An offer still open when the player moves instead lapses
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 0, Player: models.PlayerO}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OfferDraw(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := s.MakeMove(ctx, g.ID, models.Move{Position: 1, Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.DrawOffer != models.Empty {
+		t.Errorf("offer %q still open after X moved", moved.DrawOffer)
+	}
+	if _, err := s.RespondDraw(ctx, g.ID, models.PlayerO, true); !errors.Is(err, game.ErrNoDrawOffer) {
+		t.Errorf("accepting a lapsed offer: err = %v, want ErrNoDrawOffer", err)
+	}
+}
+
+func TestDrawRefusals(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	waiting, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unstarted := joinedGame(t, s)
+	opened := openedGame(t, s)
+
+	tests := []struct {
+		name   string
+		gameID string
+		player models.Player
+		want   error
+	}{
+		{"waiting for an opponent", waiting.ID, models.PlayerX, game.ErrWaiting},
+		{"before the first move", unstarted.ID, models.PlayerX, game.ErrDrawOffered},
+		{"out of turn", opened.ID, models.PlayerX, game.ErrNotYourTurn},
+		{"a spectator", opened.ID, models.Empty, game.ErrInvalidPlayer},
+		{"a missing game", "missing", models.PlayerO, game.ErrGameNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.OfferDraw(ctx, tt.gameID, tt.player); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if _, err := s.RespondDraw(ctx, opened.ID, models.PlayerX, true); !errors.Is(err, game.ErrNoDrawOffer) {
+		t.Errorf("answering no offer: err = %v, want ErrNoDrawOffer", err)
+	}
+}
@@ -0,0 +1,35 @@
+package game
+
+import (
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// PlayComputerMove lets tests hand the computer a move worked out for a
+// state of their choosing, as its worker would.
+func (s *Service) PlayComputerMove(thought *models.GameState, position int) error {
+	return s.playComputerMove(thought, position)
+}
+
+// EndDead lets tests end a game as dead from a state of their choosing,
+// as its worker would once it found the position dead.
+func (s *Service) EndDead(checked *models.GameState) error {
+	return s.endDead(checked)
+}
+
+// Held returns how many resets and cancellations are held for undoing.
+func (s *Service) Held() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.held)
+}
+
+// Place lets tests make a move in game by its rules, as MakeMove does
+// once it has checked it, without a service.
+func Place(game *models.GameState, move models.Move) error {
+	return place(game, move, "", time.Time{})
+}
+
+// CheckWinner lets tests ask who has a line on board.
+var CheckWinner = checkWinner
@@ -0,0 +1,42 @@
+package game_test
+
+import (
+	"context"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// FuzzMakeMove sends moves in every form to a hot-seat game O is to move
+// in, and checks only those the rules allow change it.
+func FuzzMakeMove(f *testing.F) {
+	f.Add(0, "a1", 0, 0, true, true, "O")
+	ctx := context.Background()
+	s := game.NewService()
+	f.Cleanup(func() { s.Close() })
+
+	f.Fuzz(func(t *testing.T, position int, cell string, row, col int, hasRow, hasCol bool, player string) {
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.CancelGame(ctx, g.ID, models.Empty)
+		before, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		move := models.Move{Position: position, Cell: cell, Player: models.Player(player)}
+		if hasRow {
+			move.Row = &row
+		}
+		if hasCol {
+			move.Col = &col
+		}
+		_, err = s.MakeMove(ctx, g.ID, move)
+		after, _ := s.GetGame(ctx, g.ID)
+		gametest.WantMoveOutcome(t, before, after, move, err == nil)
+	})
+}
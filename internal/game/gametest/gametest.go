@@ -0,0 +1,85 @@
+// Package gametest provides deterministic fakes for testing code that
+// uses game.Service, and RunRepositoryTests, the tests every
+// game.Repository must pass.
+package gametest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SequentialIDs generates IDs of the form "<prefix>0001", "<prefix>0002", ...
+type SequentialIDs struct {
+	Prefix string
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewID returns the next ID in the sequence.
+func (g *SequentialIDs) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("%s%04d", g.Prefix, g.next)
+}
+
+// FixedIDs returns the given IDs in order, repeating the last one once
+// exhausted. Useful for forcing ID collisions.
+type FixedIDs struct {
+	mu  sync.Mutex
+	ids []string
+	pos int
+}
+
+// NewFixedIDs creates a generator returning ids in order.
+func NewFixedIDs(ids ...string) *FixedIDs {
+	return &FixedIDs{ids: ids}
+}
+
+// NewID returns the next ID.
+func (g *FixedIDs) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.ids) == 0 {
+		return ""
+	}
+	id := g.ids[g.pos]
+	if g.pos < len(g.ids)-1 {
+		g.pos++
+	}
+	return id
+}
+
+// FakeClock is a manually advanced clock.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a clock starting at t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
@@ -0,0 +1,67 @@
+package gametest
+
+import (
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+// WantMoveOutcome fails the test unless move took before to after, and
+// was accepted exactly when the rules allow it.
+func WantMoveOutcome(t testing.TB, before, after *models.GameState, move models.Move, accepted bool) {
+	t.Helper()
+	position, legal := legalPosition(before, move)
+	if accepted != legal {
+		t.Fatalf("%+v on %s: accepted = %v, want %v", move, before.Board, accepted, legal)
+	}
+	want := before.Board
+	if legal {
+		want[position] = move.Player
+	}
+	if after.Board != want {
+		t.Fatalf("%+v on %s: board %s, want %s", move, before.Board, after.Board, want)
+	}
+	switch moves := len(before.History); {
+	case legal && (len(after.History) != moves+1 || after.History[moves].Position != position):
+		t.Fatalf("%+v on %s: history %+v", move, before.Board, after.History)
+	case !legal && (len(after.History) != moves || after.Version != before.Version):
+		t.Fatalf("%+v on %s: the refused move changed the game to %+v", move, before.Board, after)
+	}
+}
+
+// legalPosition returns the cell move names, and whether the rules allow
+// it in game. A Position of 0 counts as unset when another form is
+// given, as the service reads it.
+func legalPosition(game *models.GameState, move models.Move) (int, bool) {
+	var named []int
+	if move.Cell != "" {
+		p, err := models.ParseCell(move.Cell, models.BoardSize)
+		if err != nil {
+			return 0, false
+		}
+		named = append(named, p)
+	}
+	if move.Row != nil || move.Col != nil {
+		if move.Row == nil || move.Col == nil {
+			return 0, false
+		}
+		p, err := models.CellIndex(*move.Row, *move.Col, models.BoardSize)
+		if err != nil {
+			return 0, false
+		}
+		named = append(named, p)
+	}
+	if move.Position != 0 || len(named) == 0 {
+		named = append(named, move.Position)
+	}
+	position := named[0]
+	for _, p := range named {
+		if p != position {
+			return 0, false
+		}
+	}
+	if position < 0 || position >= len(game.Board) || game.Board[position] != models.Empty {
+		return 0, false
+	}
+	return position, !game.IsOver && move.Player == game.CurrentTurn
+}
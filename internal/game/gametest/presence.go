@@ -0,0 +1,55 @@
+package gametest
+
+import (
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Presence is a game.Presence the test tells who is connected, timed by
+// a FakeClock. A player it hasn't been told about has always been away.
+type Presence struct {
+	clock *FakeClock
+
+	mu    sync.Mutex
+	seats map[presenceKey]presenceSeat
+}
+
+type presenceKey struct {
+	gameID string
+	player models.Player
+}
+
+type presenceSeat struct {
+	online bool
+	since  time.Time
+}
+
+// NewPresence creates a presence with nobody connected.
+func NewPresence(clock *FakeClock) *Presence {
+	return &Presence{clock: clock, seats: make(map[presenceKey]presenceSeat)}
+}
+
+// Connect marks player as connected to the game.
+func (p *Presence) Connect(gameID string, player models.Player) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seats[presenceKey{gameID, player}] = presenceSeat{online: true}
+}
+
+// Leave marks player as gone from the game from now on.
+func (p *Presence) Leave(gameID string, player models.Player) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seats[presenceKey{gameID, player}] = presenceSeat{since: p.clock.Now()}
+}
+
+// OfflineSince reports whether player is away from the game and since
+// when.
+func (p *Presence) OfflineSince(gameID string, player models.Player) (since time.Time, offline bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seat := p.seats[presenceKey{gameID, player}]
+	return seat.since, !seat.online
+}
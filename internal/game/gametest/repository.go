@@ -0,0 +1,316 @@
+package gametest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// RepositoryBackend describes a game.Repository implementation to
+// RunRepositoryTests.
+type RepositoryBackend struct {
+	// Open opens the repository kept in dir, which is empty for each
+	// test to begin with. The tests close what it returns.
+	Open func(t *testing.T, dir string) game.Repository
+	// Persistent is set for backends keeping their games in dir, which
+	// must then hold them when opened again after being closed.
+	Persistent bool
+}
+
+// RunRepositoryTests checks that the repositories backend opens behave
+// as the game service expects of every game.Repository, so a new
+// backend's tests can be a call to it.
+func RunRepositoryTests(t *testing.T, backend RepositoryBackend) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, RepositoryBackend)
+	}{
+		{"NotFound", testNotFound},
+		{"PutGet", testPutGet},
+		{"Replace", testReplace},
+		{"Finished", testFinished},
+		{"Delete", testDelete},
+		{"List", testList},
+		{"ConcurrentPuts", testConcurrentPuts},
+		{"Persistence", testPersistence},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, backend)
+		})
+	}
+}
+
+// open opens a new repository of backend's, closed when the test ends.
+func open(t *testing.T, backend RepositoryBackend) game.Repository {
+	t.Helper()
+	return openIn(t, backend, t.TempDir())
+}
+
+func openIn(t *testing.T, backend RepositoryBackend, dir string) game.Repository {
+	t.Helper()
+	repo := backend.Open(t, dir)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// sampleGame returns a game in progress with something set in most of
+// its fields, so a backend dropping any of them shows.
+func sampleGame(id string) *models.GameState {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	g := models.NewGameState(id)
+	g.Slug = "sample-" + id
+	g.PlayerXJoined, g.PlayerOJoined = true, true
+	g.XSymbol = "🦊"
+	g.GameSettings = models.GameSettings{
+		EarlyDraw: true,
+		Handicap:  &models.Handicap{Style: models.HandicapDoubleMove, Player: models.PlayerO},
+		Locale:    "es",
+	}
+	g.Board[4] = models.PlayerX
+	g.History = []models.MoveRecord{{Position: 4, Cell: "b2", Row: 1, Col: 1, Player: models.PlayerX, At: at, Think: 3 * time.Second}}
+	g.CurrentTurn = models.PlayerO
+	g.Version = 7
+	g.CreatedAt = at.Add(-time.Minute)
+	g.UpdatedAt = at
+	return g
+}
+
+// finish ends g as a win for X.
+func finish(g *models.GameState) *models.GameState {
+	g = g.Clone()
+	g.IsOver = true
+	g.Winner = models.PlayerX
+	g.Version++
+	return g
+}
+
+// mustPut stores g, failing the test if it can't.
+func mustPut(t *testing.T, repo game.Repository, g *models.GameState) {
+	t.Helper()
+	if err := repo.Put(context.Background(), g); err != nil {
+		t.Fatalf("Put(%s): %v", g.ID, err)
+	}
+}
+
+// wantGame fails the test unless repo holds want under its ID, field for
+// field as the JSON clients see.
+func wantGame(t *testing.T, repo game.Repository, want *models.GameState) {
+	t.Helper()
+	got, ok, err := repo.Get(context.Background(), want.ID)
+	if err != nil || !ok {
+		t.Fatalf("Get(%s) = %v, %v, want the game", want.ID, ok, err)
+	}
+	if g, w := asJSON(t, got), asJSON(t, want); !bytes.Equal(g, w) {
+		t.Errorf("Get(%s) =\n%s\nwant\n%s", want.ID, g, w)
+	}
+}
+
+// wantIDs fails the test unless repo lists exactly the games with ids.
+func wantIDs(t *testing.T, repo game.Repository, ids ...string) {
+	t.Helper()
+	games, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	got := make([]string, len(games))
+	for i, g := range games {
+		got[i] = g.ID
+	}
+	sort.Strings(got)
+	sort.Strings(ids)
+	if fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Errorf("List() = %v, want %v", got, ids)
+	}
+}
+
+func asJSON(t *testing.T, g *models.GameState) []byte {
+	t.Helper()
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func testNotFound(t *testing.T, backend RepositoryBackend) {
+	ctx := context.Background()
+	repo := open(t, backend)
+	if g, ok, err := repo.Get(ctx, "missing"); g != nil || ok || err != nil {
+		t.Errorf("Get(missing) = %v, %v, %v, want nil, false, nil", g, ok, err)
+	}
+	if err := repo.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete(missing) = %v, want nil", err)
+	}
+	wantIDs(t, repo)
+}
+
+func testPutGet(t *testing.T, backend RepositoryBackend) {
+	repo := open(t, backend)
+	g := sampleGame("g1")
+	mustPut(t, repo, g)
+	wantGame(t, repo, g)
+}
+
+func testReplace(t *testing.T, backend RepositoryBackend) {
+	repo := open(t, backend)
+	g := sampleGame("g1")
+	mustPut(t, repo, g)
+	g = g.Clone()
+	g.Board[0] = models.PlayerO
+	g.CurrentTurn = models.PlayerX
+	g.Version++
+	mustPut(t, repo, g)
+	wantGame(t, repo, g)
+	wantIDs(t, repo, "g1")
+}
+
+func testFinished(t *testing.T, backend RepositoryBackend) {
+	repo := open(t, backend)
+	g := sampleGame("g1")
+	mustPut(t, repo, g)
+
+	// Finished games are still found, once
+	over := finish(g)
+	mustPut(t, repo, over)
+	wantGame(t, repo, over)
+	wantIDs(t, repo, "g1")
+
+	// And come back into play when reset
+	reset := sampleGame("g1")
+	reset.Version = over.Version + 1
+	mustPut(t, repo, reset)
+	wantGame(t, repo, reset)
+	wantIDs(t, repo, "g1")
+}
+
+func testDelete(t *testing.T, backend RepositoryBackend) {
+	ctx := context.Background()
+	repo := open(t, backend)
+	live, over := sampleGame("live"), finish(sampleGame("over"))
+	mustPut(t, repo, live)
+	mustPut(t, repo, over)
+	mustPut(t, repo, sampleGame("kept"))
+
+	for _, id := range []string{"live", "over"} {
+		if err := repo.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete(%s): %v", id, err)
+		}
+		if _, ok, err := repo.Get(ctx, id); ok || err != nil {
+			t.Errorf("Get(%s) after Delete = %v, %v, want not found", id, ok, err)
+		}
+	}
+	wantIDs(t, repo, "kept")
+}
+
+func testList(t *testing.T, backend RepositoryBackend) {
+	repo := open(t, backend)
+	var ids []string
+	for i := range 10 {
+		g := sampleGame(fmt.Sprintf("g%d", i))
+		if i%3 == 0 {
+			g = finish(g)
+		}
+		mustPut(t, repo, g)
+		ids = append(ids, g.ID)
+	}
+	wantIDs(t, repo, ids...)
+
+	games, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range games {
+		want := sampleGame(g.ID)
+		if g.IsOver {
+			want = finish(want)
+		}
+		if !bytes.Equal(asJSON(t, g), asJSON(t, want)) {
+			t.Errorf("List() has %s as\n%s\nwant\n%s", g.ID, asJSON(t, g), asJSON(t, want))
+		}
+	}
+}
+
+func testConcurrentPuts(t *testing.T, backend RepositoryBackend) {
+	const writers = 20
+	repo := open(t, backend)
+	written := make([][]byte, writers)
+	var wg sync.WaitGroup
+	for i := range writers {
+		own := sampleGame(fmt.Sprintf("own%d", i))
+		shared := sampleGame("shared")
+		shared.Version = uint64(i)
+		shared.Board[i%9] = models.PlayerO
+		written[i] = asJSON(t, shared)
+		wg.Go(func() {
+			if err := repo.Put(context.Background(), own); err != nil {
+				t.Error(err)
+			}
+			if err := repo.Put(context.Background(), shared); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	games, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != writers+1 {
+		t.Errorf("List() has %d games, want %d", len(games), writers+1)
+	}
+	// The shared game is whole as one of its writers left it
+	got, _, err := repo.Get(context.Background(), "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range written {
+		if bytes.Equal(asJSON(t, got), w) {
+			return
+		}
+	}
+	t.Errorf("the shared game is none of the versions written: %s", asJSON(t, got))
+}
+
+func testPersistence(t *testing.T, backend RepositoryBackend) {
+	if !backend.Persistent {
+		t.Skip("the backend keeps nothing across restarts")
+	}
+	dir := t.TempDir()
+	repo := backend.Open(t, dir)
+	live, over, gone := sampleGame("live"), finish(sampleGame("over")), sampleGame("gone")
+	for _, g := range []*models.GameState{live, over, gone} {
+		mustPut(t, repo, g)
+	}
+	if err := repo.Delete(context.Background(), gone.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	repo = backend.Open(t, dir)
+	wantGame(t, repo, live)
+	wantGame(t, repo, over)
+	wantIDs(t, repo, "live", "over")
+
+	// Still writable after reopening, and kept again
+	moved := live.Clone()
+	moved.Board[0] = models.PlayerO
+	moved.Version++
+	mustPut(t, repo, moved)
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	repo = openIn(t, backend, dir)
+	wantGame(t, repo, moved)
+}
@@ -0,0 +1,84 @@
+package game
+
+import "tiktaktoes/internal/models"
+
+// RandomPosition as the Position of a models.HandicapMark places the mark
+// on a random cell.
+const RandomPosition = -1
+
+// checkHandicap checks that h is a handicap a game can have: a known
+// style for X or O, with the mark of a HandicapMark on the board and no
+// Position for any other.
+func checkHandicap(h *models.Handicap) error {
+	if h.Player != models.PlayerX && h.Player != models.PlayerO {
+		return ErrInvalidHandicap
+	}
+	switch h.Style {
+	case models.HandicapMark:
+		if h.Position < 0 || h.Position >= len(models.Board{}) {
+			return ErrInvalidHandicap
+		}
+	case models.HandicapDoubleMove:
+		if h.Position != 0 {
+			return ErrInvalidHandicap
+		}
+	default:
+		return ErrInvalidHandicap
+	}
+	return nil
+}
+
+// turnAfter returns whose turn it is once moves moves, counting the mark
+// of a HandicapMark and those of a start position, have been made in a
+// game with settings.
+func turnAfter(settings models.GameSettings, moves int) models.Player {
+	if settings.StartPosition != "" {
+		board, _ := models.ParseBoard(settings.StartPosition)
+		if (moves-count(board, models.PlayerX)-count(board, models.PlayerO))%2 == 0 {
+			return startTurn(settings, board)
+		}
+		return opponent(startTurn(settings, board))
+	}
+	if h := settings.Handicap; h != nil {
+		switch h.Style {
+		case models.HandicapMark:
+			if moves == 0 {
+				return h.Player
+			}
+			moves--
+		case models.HandicapDoubleMove:
+			if moves < 2 {
+				return h.Player
+			}
+			// After the double move the other side moves as if second
+			if moves%2 == 0 {
+				return opponent(h.Player)
+			}
+			return h.Player
+		}
+	}
+	if moves%2 == 0 {
+		return models.PlayerX
+	}
+	return models.PlayerO
+}
+
+// placed returns how many of game's moves its settings made rather than
+// a player: one for the mark of a HandicapMark, or the marks of its
+// start position.
+func placed(game *models.GameState) int {
+	if game.StartPosition != "" {
+		board, _ := models.ParseBoard(game.StartPosition)
+		return count(board, models.PlayerX) + count(board, models.PlayerO)
+	}
+	if game.Handicap != nil && game.Handicap.Style == models.HandicapMark {
+		return 1
+	}
+	return 0
+}
+
+// Started reports whether anyone has moved in the game yet. The marks a
+// handicap or start position places don't count.
+func Started(game *models.GameState) bool {
+	return len(game.History) > placed(game)
+}
@@ -0,0 +1,107 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// handicapped returns a new hot-seat game on s with handicap h.
+func handicapped(t *testing.T, s *game.Service, h models.Handicap) *models.GameState {
+	t.Helper()
+	g, err := s.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat, Handicap: &h}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestHandicapMark(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := handicapped(t, s, models.Handicap{Style: models.HandicapMark, Player: models.PlayerO, Position: 4})
+
+	if g.Board[4] != models.PlayerO || len(g.History) != 1 || g.History[0].Player != models.PlayerO || g.History[0].Position != 4 {
+		t.Fatalf("created with board %v, history %+v, want O's mark in the centre as move zero", g.Board, g.History)
+	}
+	if g.CurrentTurn != models.PlayerX || game.Started(g) || slices.Contains(g.LegalMoves, 4) || len(g.LegalMoves) != 8 {
+		t.Errorf("created with %s to move, started %v, legal moves %v", g.CurrentTurn, game.Started(g), g.LegalMoves)
+	}
+	// O's diagonal through the handicap mark wins
+	for _, m := range []models.Move{{Player: models.PlayerX, Position: 1}, {Player: models.PlayerO, Position: 0}, {Player: models.PlayerX, Position: 2}, {Player: models.PlayerO, Position: 8}} {
+		var err error
+		if g, err = s.MakeMove(ctx, g.ID, m); err != nil {
+			t.Fatalf("%+v: %v", m, err)
+		}
+	}
+	if g.Winner != models.PlayerO || !g.IsOver || len(g.History) != 5 {
+		t.Errorf("winner %q after %d moves, want O after 5", g.Winner, len(g.History))
+	}
+
+	random := handicapped(t, s, models.Handicap{Style: models.HandicapMark, Player: models.PlayerX, Position: game.RandomPosition})
+	x, o := 0, 0
+	for _, p := range random.Board {
+		switch p {
+		case models.PlayerX:
+			x++
+		case models.PlayerO:
+			o++
+		}
+	}
+	if x != 1 || o != 0 || random.Board[random.History[0].Position] != models.PlayerX {
+		t.Errorf("a random mark left board %v, history %+v", random.Board, random.History)
+	}
+}
+
+func TestHandicapDoubleMove(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	// A position means nothing to a double move
+	g := handicapped(t, s, models.Handicap{Style: models.HandicapDoubleMove, Player: models.PlayerO, Position: 3})
+	if g.CurrentTurn != models.PlayerO || len(g.History) != 0 || g.Board != (models.Board{}) || g.Handicap.Position != 0 {
+		t.Fatalf("created with %s to move, history %+v, handicap %+v, want O to open an empty board", g.CurrentTurn, g.History, g.Handicap)
+	}
+
+	turns := []models.Player{models.PlayerO, models.PlayerO, models.PlayerX, models.PlayerO, models.PlayerX}
+	for i, p := range turns {
+		if g.CurrentTurn != p {
+			t.Fatalf("move %d: %s to move, want %s", i, g.CurrentTurn, p)
+		}
+		var err error
+		if g, err = s.MakeMove(ctx, g.ID, models.Move{Player: p, Position: i}); err != nil {
+			t.Fatalf("move %d: %v", i, err)
+		}
+	}
+	// O took 0, 1 and 3, X 2 and 4
+	if g.IsOver || g.CurrentTurn != models.PlayerO {
+		t.Errorf("after five moves: over %v, %s to move", g.IsOver, g.CurrentTurn)
+	}
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Player: models.PlayerX, Position: 8}); !errors.Is(err, game.ErrNotYourTurn) {
+		t.Errorf("X moving twice after the opening: %v, want %v", err, game.ErrNotYourTurn)
+	}
+}
+
+func TestInvalidHandicap(t *testing.T) {
+	s := game.NewService()
+	defer s.Close()
+	for _, h := range []models.Handicap{
+		{Style: models.HandicapMark, Player: models.PlayerO, Position: 9},
+		{Style: models.HandicapMark, Player: models.PlayerO, Position: -2},
+		{Style: models.HandicapMark, Player: "Z", Position: 4},
+		{Style: "triple", Player: models.PlayerX},
+	} {
+		_, err := s.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Handicap: &h}})
+		if !errors.Is(err, game.ErrInvalidHandicap) {
+			t.Errorf("%+v: %v, want %v", h, err, game.ErrInvalidHandicap)
+		}
+	}
+	if n := total(s); n != 0 {
+		t.Errorf("%d games created", n)
+	}
+}
@@ -0,0 +1,219 @@
+package game
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"tiktaktoes/internal/models"
+)
+
+// Hook is called with the state of a game right after a transition
+type Hook func(gs models.GameState)
+
+// hookKind names the transitions hooks can be registered for
+type hookKind int
+
+const (
+	hookCreated hookKind = iota
+	hookJoined
+	hookMoved
+	hookFinished
+	hookExpired
+	hookReady
+	hookVacated
+	hookCancelled
+	hookRestored
+	hookComputerMoved
+	hookDeadPosition
+	numHookKinds
+)
+
+var hookNames = [numHookKinds]string{
+	hookCreated:       "created",
+	hookJoined:        "joined",
+	hookMoved:         "moved",
+	hookFinished:      "finished",
+	hookExpired:       "expired",
+	hookReady:         "ready",
+	hookVacated:       "vacated",
+	hookCancelled:     "cancelled",
+	hookRestored:      "restored",
+	hookComputerMoved: "computer-moved",
+	hookDeadPosition:  "dead-position",
+}
+
+// hookEvent is a transition waiting to be delivered
+type hookEvent struct {
+	kind  hookKind
+	state models.GameState
+}
+
+// hooks delivers transitions to registered callbacks. Events are queued
+// while the service lock is held and delivered in order by a separate
+// goroutine, so hooks never run under the lock and may call back into
+// the service.
+type hooks struct {
+	mu      sync.Mutex
+	fns     [numHookKinds][]Hook
+	queue   []hookEvent
+	wake    chan struct{}
+	done    chan struct{}
+	running bool
+	closed  bool
+}
+
+// OnGameCreated registers fn to be called for every new game.
+func (s *Service) OnGameCreated(fn Hook) {
+	s.hooks.register(hookCreated, fn)
+}
+
+// OnPlayerJoined registers fn to be called each time a player joins.
+func (s *Service) OnPlayerJoined(fn Hook) {
+	s.hooks.register(hookJoined, fn)
+}
+
+// OnMoveMade registers fn to be called after every move, including the
+// one that finishes a game.
+func (s *Service) OnMoveMade(fn Hook) {
+	s.hooks.register(hookMoved, fn)
+}
+
+// OnGameFinished registers fn to be called once when a game is won or
+// drawn.
+func (s *Service) OnGameFinished(fn Hook) {
+	s.hooks.register(hookFinished, fn)
+}
+
+// OnGameExpired registers fn to be called for each waiting game deleted
+// to make room for new ones, see WithMaxGames.
+func (s *Service) OnGameExpired(fn Hook) {
+	s.hooks.register(hookExpired, fn)
+}
+
+// OnReadyChanged registers fn to be called whenever the wait for the
+// players of a game with a ready check changes: a player says whether
+// they are ready, the countdown ends or the unready are vacated, see
+// Service.Ready and Service.AdvanceReady.
+func (s *Service) OnReadyChanged(fn Hook) {
+	s.hooks.register(hookReady, fn)
+}
+
+// OnSlotVacated registers fn to be called each time a player's slot is
+// freed for someone else to take, see Service.VacateSlot.
+func (s *Service) OnSlotVacated(fn Hook) {
+	s.hooks.register(hookVacated, fn)
+}
+
+// OnGameCancelled registers fn to be called for each waiting game its
+// creator cancels, see Service.CancelGame.
+func (s *Service) OnGameCancelled(fn Hook) {
+	s.hooks.register(hookCancelled, fn)
+}
+
+// OnGameRestored registers fn to be called for each game put back as it
+// was before a reset or cancellation, see Service.UndoReset.
+func (s *Service) OnGameRestored(fn Hook) {
+	s.hooks.register(hookRestored, fn)
+}
+
+// OnComputerMoved registers fn to be called after each move the
+// computer plays in a game against it, which no request is waiting on,
+// see ModeAI. OnMoveMade and OnGameFinished are called for it too.
+func (s *Service) OnComputerMoved(fn Hook) {
+	s.hooks.register(hookComputerMoved, fn)
+}
+
+// OnDeadPosition registers fn to be called for each game with EarlyDraw
+// ended as a draw once neither side could win, which happens shortly
+// after the move that led there and not in answer to it, see
+// models.DrawDeadPosition. OnGameFinished is called for it too.
+func (s *Service) OnDeadPosition(fn Hook) {
+	s.hooks.register(hookDeadPosition, fn)
+}
+
+func (h *hooks) register(kind hookKind, fn Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns[kind] = append(h.fns[kind], fn)
+	if !h.running && !h.closed {
+		h.running = true
+		h.wake = make(chan struct{}, 1)
+		h.done = make(chan struct{})
+		go h.run()
+	}
+}
+
+// emit queues a transition for delivery. It never blocks, so it is safe
+// to call with the service lock held.
+func (h *hooks) emit(kind hookKind, game *models.GameState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.fns[kind]) == 0 || h.closed {
+		return
+	}
+	h.queue = append(h.queue, hookEvent{kind: kind, state: *game.Clone()})
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events until the hooks are closed and drained.
+func (h *hooks) run() {
+	defer close(h.done)
+	for {
+		h.mu.Lock()
+		queue := h.queue
+		h.queue = nil
+		closed := h.closed
+		h.mu.Unlock()
+
+		for _, ev := range queue {
+			h.mu.Lock()
+			fns := h.fns[ev.kind]
+			h.mu.Unlock()
+			for _, fn := range fns {
+				callHook(ev, fn)
+			}
+		}
+		if len(queue) > 0 {
+			continue
+		}
+		if closed {
+			return
+		}
+		<-h.wake
+	}
+}
+
+// callHook runs one hook, containing any panic so one misbehaving hook
+// can't take down the others or the server.
+func callHook(ev hookEvent, fn Hook) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("game hook panicked", "hook", hookNames[ev.kind], "game_id", ev.state.ID, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	fn(ev.state)
+}
+
+// close delivers any queued events and stops the hook goroutine.
+func (h *hooks) close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	running := h.running
+	if running {
+		select {
+		case h.wake <- struct{}{}:
+		default:
+		}
+	}
+	h.mu.Unlock()
+	if running {
+		<-h.done
+	}
+}
@@ -0,0 +1,183 @@
+package game_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// recorder records the transitions a service's hooks are called for, in
+// the order they are called.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func record(s *game.Service) *recorder {
+	r := &recorder{}
+	add := func(kind string) game.Hook {
+		return func(gs models.GameState) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.events = append(r.events, kind)
+		}
+	}
+	s.OnGameCreated(add("created"))
+	s.OnPlayerJoined(add("joined"))
+	s.OnMoveMade(add("moved"))
+	s.OnGameFinished(add("finished"))
+	return r
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, " ")
+}
+
+// TestHooksFireOnce ends games each way they can end and checks the
+// hooks are called in order, with finished called once.
+func TestHooksFireOnce(t *testing.T) {
+	ctx := context.Background()
+	// X takes the top row while O plays the middle one
+	var line []models.Move
+	for i, p := range []int{0, 3, 1, 4, 2} {
+		line = append(line, models.Move{Position: p, Player: []models.Player{models.PlayerX, models.PlayerO}[i%2]})
+	}
+
+	tests := []struct {
+		name string
+		play func(t *testing.T, s *game.Service, presence *gametest.Presence, clock *gametest.FakeClock)
+		want string
+	}{
+		{
+			name: "a winning move",
+			play: func(t *testing.T, s *game.Service, _ *gametest.Presence, _ *gametest.FakeClock) {
+				g := joinedGame(t, s)
+				for _, m := range line {
+					if _, err := s.MakeMove(ctx, g.ID, m); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 5, Player: models.PlayerO}); err == nil {
+					t.Error("moved in a finished game")
+				}
+			},
+			want: "created joined moved moved moved moved moved finished",
+		},
+		{
+			name: "a batch of moves",
+			play: func(t *testing.T, s *game.Service, _ *gametest.Presence, _ *gametest.FakeClock) {
+				g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat, Exhibition: true}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := s.MakeMoves(ctx, g.ID, line); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "created moved finished",
+		},
+		{
+			name: "an agreed draw",
+			play: func(t *testing.T, s *game.Service, _ *gametest.Presence, _ *gametest.FakeClock) {
+				g := openedGame(t, s)
+				if _, err := s.OfferDraw(ctx, g.ID, models.PlayerO); err != nil {
+					t.Fatal(err)
+				}
+				for range 2 {
+					s.RespondDraw(ctx, g.ID, models.PlayerX, true)
+				}
+			},
+			want: "created joined moved finished",
+		},
+		{
+			name: "a claimed win",
+			play: func(t *testing.T, s *game.Service, presence *gametest.Presence, clock *gametest.FakeClock) {
+				g := openedGame(t, s)
+				presence.Connect(g.ID, models.PlayerO)
+				presence.Leave(g.ID, models.PlayerO)
+				clock.Advance(time.Hour)
+				for range 2 {
+					s.ClaimWin(ctx, g.ID, models.PlayerX)
+				}
+			},
+			want: "created joined moved finished",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := gametest.NewFakeClock(start)
+			presence := gametest.NewPresence(clock)
+			s := game.NewService(game.WithClock(clock), game.WithPresence(presence))
+			r := record(s)
+			tt.play(t, s, presence, clock)
+			// Close delivers every queued transition before returning
+			s.Close()
+			if got := r.String(); got != tt.want {
+				t.Errorf("hooks called for %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHookPanics checks a panicking hook neither stops the hooks after
+// it nor the transitions that follow from being delivered.
+func TestHookPanics(t *testing.T) {
+	s := game.NewService()
+	var mu sync.Mutex
+	var called []string
+	s.OnGameCreated(func(gs models.GameState) { panic("boom") })
+	s.OnGameCreated(func(gs models.GameState) {
+		mu.Lock()
+		defer mu.Unlock()
+		called = append(called, "created "+gs.ID)
+	})
+	s.OnPlayerJoined(func(gs models.GameState) {
+		mu.Lock()
+		defer mu.Unlock()
+		called = append(called, "joined "+gs.ID)
+	})
+	g := joinedGame(t, s)
+	s.Close()
+
+	if want := []string{"created " + g.ID, "joined " + g.ID}; !slices.Equal(called, want) {
+		t.Errorf("hooks called %q, want %q", called, want)
+	}
+}
+
+// TestHooksCallBack checks hooks run without the service's lock held, so
+// they can use the service they were registered on.
+func TestHooksCallBack(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	joined := make(chan *models.GameState, 1)
+	s.OnPlayerJoined(func(gs models.GameState) {
+		g, err := s.MakeMove(ctx, gs.ID, models.Move{Position: 4, Player: models.PlayerX})
+		if err != nil {
+			t.Error(err)
+		}
+		joined <- g
+	})
+	g := joinedGame(t, s)
+
+	select {
+	case moved := <-joined:
+		if moved != nil && moved.Board[4] != models.PlayerX {
+			t.Errorf("the hook's move left %q on the centre", moved.Board[4])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the joined hook didn't finish: it is waiting on the service")
+	}
+	if got, _ := s.GetGame(ctx, g.ID); len(got.History) != 1 {
+		t.Errorf("%d moves after the hook's", len(got.History))
+	}
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"context"
+	"strings"
+
+	"tiktaktoes/internal/models"
+)
+
+// MinPrefixLength is the shortest ID prefix that lookups resolve to a
+// game. Shorter input must match an ID exactly.
+const MinPrefixLength = 4
+
+// NormalizeID cleans up a game ID the way people type or read it out:
+// surrounding whitespace is dropped and letters are lowercased. Every
+// handler taking an ID from a request goes through it, as does the
+// service for IDs it is given or generates.
+func NormalizeID(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// FindGame returns the game id names by its ID, its slug or a unique
+// prefix. Callers must key anything by the returned game's ID.
+func (s *Service) FindGame(ctx context.Context, id string) (*models.GameState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.lookup(ctx, id)
+}
+
+// find returns the game id names after normalizing it, falling back to
+// a game in progress with that slug and then to a prefix match when
+// there's no exact one. Must be called with the lock held.
+func (s *Service) find(ctx context.Context, id string) (*models.GameState, error) {
+	id = NormalizeID(id)
+	game, exists, err := s.games.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return game, nil
+	}
+	if game, err := s.slugHolder(ctx, id); game != nil || err != nil {
+		return game, err
+	}
+	if len(id) < MinPrefixLength {
+		return nil, ErrGameNotFound
+	}
+
+	match, err := s.index.withPrefix(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if match == "" {
+		return nil, ErrGameNotFound
+	}
+	return s.get(ctx, match)
+}
+
+// get returns the stored game with exactly id, or ErrGameNotFound.
+func (s *Service) get(ctx context.Context, id string) (*models.GameState, error) {
+	game, exists, err := s.games.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrGameNotFound
+	}
+	return game, nil
+}
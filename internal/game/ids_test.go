@@ -0,0 +1,145 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// total counts the stored games.
+func total(s *game.Service) int {
+	n := 0
+	for _, count := range s.Stats(context.Background()).Games {
+		n += count
+	}
+	return n
+}
+
+func TestCreateGameRetriesTakenIDs(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	// The second and third IDs collide with the first, one only once
+	// normalized, and the fourth is blank
+	s := game.NewService(
+		game.WithClock(gametest.NewFakeClock(created)),
+		game.WithIDGenerator(gametest.NewFixedIDs("abc", "abc", " ABC ", "", "def")),
+	)
+	defer s.Close()
+
+	first, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != "abc" || second.ID != "def" {
+		t.Errorf("created %q and %q, want abc and def", first.ID, second.ID)
+	}
+	if !second.CreatedAt.Equal(created) {
+		t.Errorf("created at %v, want the clock's %v", second.CreatedAt, created)
+	}
+	if g, ok := s.GetGame(ctx, "abc"); !ok || g.CreatedAt != first.CreatedAt {
+		t.Errorf("the first game was replaced: %+v", g)
+	}
+}
+
+func TestCreateGameGivesUpOnCollisions(t *testing.T) {
+	ctx := context.Background()
+	// FixedIDs repeats its last ID forever
+	s := game.NewService(game.WithIDGenerator(gametest.NewFixedIDs("abc")))
+	defer s.Close()
+
+	if _, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{}); !errors.Is(err, game.ErrIDExhausted) {
+			t.Fatalf("CreateGame = %v, %v, want ErrIDExhausted", g, err)
+		}
+	}
+	if n := total(s); n != 1 {
+		t.Errorf("%d games stored, want the first alone", n)
+	}
+	if code := game.Code(game.ErrIDExhausted); code != "id_exhausted" {
+		t.Errorf("code %q", code)
+	}
+
+	// A generator with nothing to give never creates a game without an ID
+	empty := game.NewService(game.WithIDGenerator(gametest.NewFixedIDs()))
+	defer empty.Close()
+	if _, err := empty.CreateGame(ctx, models.PlayerX, game.CreateOptions{}); !errors.Is(err, game.ErrIDExhausted) {
+		t.Errorf("CreateGame with no IDs = %v, want ErrIDExhausted", err)
+	}
+}
+
+func TestFindGame(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService(game.WithIDGenerator(gametest.NewFixedIDs("abcd1234", "abcd5678", "abce0000")))
+	defer s.Close()
+	for range 3 {
+		if _, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		id   string
+		want string
+		err  error
+	}{
+		{"abcd1234", "abcd1234", nil},
+		{" ABCD1234\t", "abcd1234", nil},
+		{"abcd5", "abcd5678", nil},
+		{"ABCE", "abce0000", nil},
+		{"abcd", "", game.ErrAmbiguousID},
+		{"abc", "", game.ErrGameNotFound},
+		{"abcd9", "", game.ErrGameNotFound},
+		{"", "", game.ErrGameNotFound},
+	}
+	for _, tt := range tests {
+		g, err := s.FindGame(ctx, tt.id)
+		if !errors.Is(err, tt.err) || (err == nil && g.ID != tt.want) {
+			t.Errorf("FindGame(%q) = %v, %v, want %q, %v", tt.id, g, err, tt.want, tt.err)
+		}
+		if _, ok := s.GetGame(ctx, tt.id); ok != (tt.err == nil) {
+			t.Errorf("GetGame(%q) found = %v", tt.id, ok)
+		}
+	}
+
+	// Once one of them is gone, the prefix they shared names the other
+	if err := s.CancelGame(ctx, "abcd5678", models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := s.FindGame(ctx, "abcd"); err != nil || g.ID != "abcd1234" {
+		t.Errorf("FindGame(abcd) after cancelling abcd5678 = %v, %v", g, err)
+	}
+}
+
+// TestFindGameInStoredGames checks prefixes resolve to the games a
+// repository already held when the service was created.
+func TestFindGameInStoredGames(t *testing.T) {
+	ctx := context.Background()
+	repo := game.NewMemoryRepository()
+	for _, id := range []string{"beef0001", "beef0002", "cafe0001"} {
+		g := models.NewGameState(id)
+		if err := repo.Put(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := game.NewService(game.WithRepository(repo))
+	defer s.Close()
+
+	if g, err := s.FindGame(ctx, "cafe"); err != nil || g.ID != "cafe0001" {
+		t.Errorf("FindGame(cafe) = %v, %v", g, err)
+	}
+	if _, err := s.FindGame(ctx, "beef"); !errors.Is(err, game.ErrAmbiguousID) {
+		t.Errorf("FindGame(beef) err = %v, want ErrAmbiguousID", err)
+	}
+}
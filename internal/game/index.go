@@ -0,0 +1,153 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"tiktaktoes/internal/models"
+)
+
+// indexedRepository wraps the service's repository with an index of the
+// games it stores, kept up to date as they are put and deleted, so
+// looking a game up by slug or ID prefix doesn't list every game. It has
+// its own lock, and is loaded from the repository on first use.
+type indexedRepository struct {
+	Repository
+	key func(string) string
+
+	mu     sync.RWMutex
+	loaded bool
+	// ids holds every stored game's ID, sorted
+	ids []string
+	// slugs maps the text key of each game in progress's slug to its ID,
+	// and slugOf each game in slugs to that key
+	slugs  map[string]string
+	slugOf map[string]string
+}
+
+// newIndexedRepository indexes repo, keying slugs with key.
+func newIndexedRepository(repo Repository, key func(string) string) *indexedRepository {
+	return &indexedRepository{
+		Repository: repo,
+		key:        key,
+		slugs:      make(map[string]string),
+		slugOf:     make(map[string]string),
+	}
+}
+
+// Put stores game and indexes it.
+func (r *indexedRepository) Put(ctx context.Context, game *models.GameState) error {
+	if err := r.Repository.Put(ctx, game); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		r.add(game)
+	}
+	return nil
+}
+
+// Delete removes the game and its index entries.
+func (r *indexedRepository) Delete(ctx context.Context, id string) error {
+	if err := r.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		r.remove(id)
+	}
+	return nil
+}
+
+// load indexes the stored games the first time it is called.
+func (r *indexedRepository) load(ctx context.Context) error {
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return nil
+	}
+	all, err := r.Repository.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, game := range all {
+		r.add(game)
+	}
+	r.loaded = true
+	return nil
+}
+
+// add indexes game, replacing what was indexed for its ID. Must be
+// called with r.mu held.
+func (r *indexedRepository) add(game *models.GameState) {
+	if i, found := slices.BinarySearch(r.ids, game.ID); !found {
+		r.ids = slices.Insert(r.ids, i, game.ID)
+	}
+	r.dropSlug(game.ID)
+	if game.Slug != "" && !game.IsOver {
+		key := r.key(game.Slug)
+		r.slugs[key] = game.ID
+		r.slugOf[game.ID] = key
+	}
+}
+
+// remove drops id from the index. Must be called with r.mu held.
+func (r *indexedRepository) remove(id string) {
+	if i, found := slices.BinarySearch(r.ids, id); found {
+		r.ids = slices.Delete(r.ids, i, i+1)
+	}
+	r.dropSlug(id)
+}
+
+// dropSlug drops the slug indexed for id. Must be called with r.mu held.
+func (r *indexedRepository) dropSlug(id string) {
+	key, ok := r.slugOf[id]
+	if !ok {
+		return
+	}
+	delete(r.slugOf, id)
+	if r.slugs[key] == id {
+		delete(r.slugs, key)
+	}
+}
+
+// slugHolder returns the ID of the game in progress whose slug has the
+// same text key as slug, or "" if there is none.
+func (r *indexedRepository) slugHolder(ctx context.Context, slug string) (string, error) {
+	if err := r.load(ctx); err != nil {
+		return "", err
+	}
+	key := r.key(slug)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.slugs[key], nil
+}
+
+// withPrefix returns the ID of the one game whose ID starts with prefix,
+// "" if none does, or ErrAmbiguousID if more than one does.
+func (r *indexedRepository) withPrefix(ctx context.Context, prefix string) (string, error) {
+	if err := r.load(ctx); err != nil {
+		return "", err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i, _ := slices.BinarySearch(r.ids, prefix)
+	if i == len(r.ids) || !strings.HasPrefix(r.ids[i], prefix) {
+		return "", nil
+	}
+	if i+1 < len(r.ids) && strings.HasPrefix(r.ids[i+1], prefix) {
+		return "", ErrAmbiguousID
+	}
+	return r.ids[i], nil
+}
@@ -0,0 +1,45 @@
+package game
+
+import (
+	"tiktaktoes/internal/models"
+	"tiktaktoes/pkg/engine"
+)
+
+// LegalMoves returns the cells the player to move may take, in board
+// order. It is empty once the game is over, while it waits for its
+// second player and until both are ready, see AwaitingReady. The service keeps GameState.LegalMoves up to date with
+// it, so clients don't have to work it out themselves.
+func LegalMoves(game *models.GameState) []int {
+	if Waiting(game) || AwaitingReady(game) {
+		return []int{}
+	}
+	return rulesFor(game.GameSettings).LegalMoves(stateOf(game))
+}
+
+// LastMove returns a copy of the latest move in the game's history, or
+// nil before any, which the service keeps in GameState.LastMove so
+// clients can mark the cell that just changed. A start position's marks
+// aren't moves.
+func LastMove(game *models.GameState) *models.MoveRecord {
+	if len(game.History) == 0 || game.History[len(game.History)-1].Setup {
+		return nil
+	}
+	m := game.History[len(game.History)-1]
+	return &m
+}
+
+// CheckMove reports why player can't take position in game, or nil if
+// the move is legal. A legal move is one of LegalMoves made by the
+// player whose turn it is; the game's rules, see rulesFor, check the
+// move itself once the game is under way.
+func CheckMove(game *models.GameState, position int, player models.Player) error {
+	switch {
+	case game.IsOver:
+		return ErrGameOver
+	case Waiting(game):
+		return ErrWaiting
+	case AwaitingReady(game):
+		return ErrNotStarted
+	}
+	return rulesFor(game.GameSettings).Check(stateOf(game), engine.Move{Position: position, Player: engine.Player(player)})
+}
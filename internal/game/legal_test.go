@@ -0,0 +1,120 @@
+package game_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+func TestLegalMoves(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	play := func(g *models.GameState, positions ...int) *models.GameState {
+		t.Helper()
+		for _, pos := range positions {
+			var err error
+			if g, err = s.MakeMove(ctx, g.ID, models.Move{Position: pos, Player: g.CurrentTurn}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return g
+	}
+	create := func(settings models.GameSettings) *models.GameState {
+		t.Helper()
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: settings})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	tests := []struct {
+		name string
+		game *models.GameState
+		want []int
+	}{
+		{"a new game", hotseatGame(t, s), []int{0, 1, 2, 3, 4, 5, 6, 7, 8}},
+		{"under way", play(hotseatGame(t, s), 4, 0), []int{1, 2, 3, 5, 6, 7, 8}},
+		{"one cell left", create(models.GameSettings{Mode: models.ModeHotseat, StartPosition: "XOX/OXO/OX.", StartTurn: models.PlayerX}), []int{8}},
+		{"won", play(hotseatGame(t, s), 0, 3, 1, 4, 2), []int{}},
+		{"drawn", play(hotseatGame(t, s), 0, 1, 2, 4, 3, 5, 7, 6, 8), []int{}},
+		{"waiting for an opponent", create(models.GameSettings{}), []int{}},
+		{"waiting for the players to be ready", readyGame(t, s), []int{}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.game.LegalMoves, tt.want) {
+			t.Errorf("%s: legal moves %v, want %v", tt.name, tt.game.LegalMoves, tt.want)
+		}
+		if got := game.LegalMoves(tt.game); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: LegalMoves = %v, want %v", tt.name, got, tt.want)
+		}
+		// None is an empty list to clients, not null
+		data, err := json.Marshal(tt.game)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tt.want) == 0 && !strings.Contains(string(data), `"legalMoves":[]`) {
+			t.Errorf("%s: encoded as %s", tt.name, data)
+		}
+		for pos := range tt.game.Board {
+			err := game.CheckMove(tt.game, pos, tt.game.CurrentTurn)
+			if legal := slices.Contains(tt.want, pos); (err == nil) != legal {
+				t.Errorf("%s: CheckMove(%d) = %v, legal %v", tt.name, pos, err, legal)
+			}
+		}
+	}
+}
+
+// TestLastMove checks a game carries its latest move, in its JSON too,
+// none before the first or after a reset, and gets it back on a replay.
+func TestLastMove(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.LastMove != nil {
+		t.Errorf("a new game's last move %+v", g.LastMove)
+	}
+	for _, m := range []models.Move{{Player: models.PlayerX, Position: 4}, {Player: models.PlayerO, Position: 5}} {
+		if g, err = s.MakeMove(ctx, g.ID, m); err != nil {
+			t.Fatal(err)
+		}
+		if lm := g.LastMove; lm == nil || lm.Player != m.Player || lm.Position != m.Position || lm.Row != 1 || lm.Col != m.Position-3 {
+			t.Errorf("after %s at %d: last move %+v", m.Player, m.Position, lm)
+		}
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"lastMove":{"position":5,"cell":"c2","row":1,"col":2,"player":"O"`) {
+		t.Errorf("JSON without O's move as the last: %s", data)
+	}
+
+	saved := g.Clone()
+	saved.LastMove = nil
+	restored := game.NewService()
+	defer restored.Close()
+	if err := restored.Replay(ctx, game.EventMoved, saved); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := restored.GetGame(ctx, g.ID); got.LastMove == nil || *got.LastMove != *g.LastMove {
+		t.Errorf("replayed with last move %+v, want %+v", got.LastMove, g.LastMove)
+	}
+
+	if g, err = s.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(g); g.LastMove != nil || strings.Contains(string(data), "lastMove") {
+		t.Errorf("after a reset: last move %+v", g.LastMove)
+	}
+}
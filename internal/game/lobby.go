@@ -0,0 +1,30 @@
+package game
+
+import (
+	"context"
+	"slices"
+
+	"tiktaktoes/internal/models"
+)
+
+// OpenGames returns copies of the games waiting for an opponent, see
+// Waiting, oldest first.
+func (s *Service) OpenGames(ctx context.Context) ([]*models.GameState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all, err := s.games.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var open []*models.GameState
+	for _, game := range all {
+		if Waiting(game) {
+			open = append(open, game.Clone())
+		}
+	}
+	slices.SortFunc(open, func(a, b *models.GameState) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return open, nil
+}
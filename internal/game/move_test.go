@@ -0,0 +1,113 @@
+package game_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// hotseatGame returns a new hot-seat game on s.
+func hotseatGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	g, err := s.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestMoveByCell(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := hotseatGame(t, s)
+
+	tests := []struct {
+		name string
+		move models.Move
+		want error
+	}{
+		{"off the board", models.Move{Cell: "d1", Player: models.PlayerX}, models.ErrInvalidCell},
+		{"not a cell", models.Move{Cell: "2b", Player: models.PlayerX}, models.ErrInvalidCell},
+		{"another position", models.Move{Cell: "b2", Position: 3, Player: models.PlayerX}, game.ErrInvalidMove},
+	}
+	for _, tt := range tests {
+		if _, err := s.MakeMove(ctx, g.ID, tt.move); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	moved, err := s.MakeMove(ctx, g.ID, models.Move{Cell: "B2", Player: models.PlayerX})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Board[4] != models.PlayerX {
+		t.Errorf("b2 put X on %s", moved.Board)
+	}
+	// The same cell by index and by name
+	moved, err = s.MakeMove(ctx, g.ID, models.Move{Cell: "c3", Position: 8, Player: models.PlayerO})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.MoveRecord{{Position: 4, Cell: "b2", Row: 1, Col: 1}, {Position: 8, Cell: "c3", Row: 2, Col: 2}}
+	for i, rec := range moved.History {
+		if rec.Position != want[i].Position || rec.Cell != want[i].Cell || rec.Row != want[i].Row || rec.Col != want[i].Col {
+			t.Errorf("move %d recorded as %+v, want %+v", i, rec, want[i])
+		}
+	}
+}
+
+func TestMoveByRowCol(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := hotseatGame(t, s)
+
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"off the board", `{"row":3,"col":0,"player":"X"}`, models.ErrInvalidCell},
+		{"a negative column", `{"row":0,"col":-1,"player":"X"}`, models.ErrInvalidCell},
+		{"a row alone", `{"row":1,"player":"X"}`, game.ErrInvalidMove},
+		{"a column alone", `{"col":1,"player":"X"}`, game.ErrInvalidMove},
+		{"another position", `{"row":1,"col":1,"position":3,"player":"X"}`, game.ErrInvalidMove},
+		{"another cell", `{"row":1,"col":1,"cell":"a1","player":"X"}`, game.ErrInvalidMove},
+	}
+	for _, tt := range tests {
+		var move models.Move
+		if err := json.Unmarshal([]byte(tt.body), &move); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.MakeMove(ctx, g.ID, move); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	var moves []models.Move
+	if err := json.Unmarshal([]byte(`[
+		{"row":1,"col":2,"player":"X"},
+		{"row":0,"col":0,"position":0,"player":"O"},
+		{"row":2,"col":1,"cell":"b3","position":7,"player":"X"}
+	]`), &moves); err != nil {
+		t.Fatal(err)
+	}
+	var moved *models.GameState
+	for _, move := range moves {
+		var err error
+		if moved, err = s.MakeMove(ctx, g.ID, move); err != nil {
+			t.Fatalf("%+v: %v", move, err)
+		}
+	}
+	want := []models.MoveRecord{{Position: 5, Cell: "c2", Row: 1, Col: 2}, {Position: 0, Cell: "a1", Row: 0, Col: 0}, {Position: 7, Cell: "b3", Row: 2, Col: 1}}
+	for i, rec := range moved.History {
+		if rec.Position != want[i].Position || rec.Cell != want[i].Cell || rec.Row != want[i].Row || rec.Col != want[i].Col {
+			t.Errorf("move %d recorded as %+v, want %+v", i, rec, want[i])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/text"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator produces candidate IDs for new games.
+type IDGenerator interface {
+	NewID() string
+}
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Journal durably records every state change so it can be replayed after
+// a crash. Append is called with the service lock held, after validation
+// and before the change becomes visible.
+type Journal interface {
+	Append(event string, game *models.GameState) error
+	// LastSeq returns the sequence number of the last appended entry.
+	LastSeq() uint64
+}
+
+// Auditor keeps a trail of destructive actions. Record is called with
+// the service lock held once the action has been committed.
+type Auditor interface {
+	Record(ctx context.Context, action, gameID string, player models.Player)
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithIDGenerator sets the generator used for new game IDs.
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(s *Service) {
+		s.ids = gen
+	}
+}
+
+// WithClock sets the clock used for game timestamps.
+func WithClock(clock Clock) Option {
+	return func(s *Service) {
+		s.clock = clock
+	}
+}
+
+// WithJournal records every state change in j.
+func WithJournal(j Journal) Option {
+	return func(s *Service) {
+		s.journal = j
+	}
+}
+
+// WithAuditor records destructive actions with a.
+func WithAuditor(a Auditor) Option {
+	return func(s *Service) {
+		s.auditor = a
+	}
+}
+
+// WithTextPolicy checks what people type, such as game names, with p
+// rather than text.NewDefault(nil).
+func WithTextPolicy(p text.Policy) Option {
+	return func(s *Service) {
+		s.text = p
+	}
+}
+
+// TextPolicy returns the policy the service checks what people type
+// with, for services built on it to check theirs the same way.
+func (s *Service) TextPolicy() text.Policy {
+	return s.text
+}
+
+// WithRepository stores games in repo instead of in memory.
+func WithRepository(repo Repository) Option {
+	return func(s *Service) {
+		s.games = repo
+	}
+}
+
+// uuidGenerator generates short IDs from a random UUID.
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string {
+	return uuid.New().String()[:8]
+}
+
+// systemClock reads the wall clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
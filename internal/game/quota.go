@@ -0,0 +1,142 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"tiktaktoes/internal/models"
+)
+
+// QuotaError is returned by CreateGame when the creator already has as
+// many unfinished games as WithQuota allows. It matches ErrQuotaExceeded.
+type QuotaError struct {
+	Open  int
+	Limit int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: you have %d open games, finish or cancel one first", ErrQuotaExceeded, e.Open)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// WithQuota limits everyone creating games to n unfinished ones at a
+// time, counted by CreateOptions.Owner. Zero means no limit. Owners are
+// kept in memory only, so the count starts over after a restart.
+func WithQuota(n int) Option {
+	return func(s *Service) {
+		s.quota = n
+	}
+}
+
+// WithMaxGames caps the number of unfinished games at n. When a new game
+// would go over it, the unstarted games that have waited longest for
+// their players are expired to make room, see OnGameExpired; if that isn't enough,
+// CreateGame fails with ErrServerFull. Zero means no limit.
+func WithMaxGames(n int) Option {
+	return func(s *Service) {
+		s.maxGames = n
+	}
+}
+
+// checkQuota fails if owner already has the most unfinished games the
+// quota allows. Finished and deleted games are forgotten along the way.
+// Must be called with the lock held.
+func (s *Service) checkQuota(ctx context.Context, owner string) error {
+	if s.quota <= 0 || owner == "" {
+		return nil
+	}
+	open := 0
+	for id, o := range s.owners {
+		if o != owner {
+			continue
+		}
+		game, ok, err := s.games.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !ok || game.IsOver {
+			delete(s.owners, id)
+			continue
+		}
+		open++
+	}
+	if open >= s.quota {
+		return &QuotaError{Open: open, Limit: s.quota}
+	}
+	return nil
+}
+
+// unstarted reports whether nobody has moved in the game and at most one
+// side has joined, so expiring it interrupts no one mid-game.
+func unstarted(game *models.GameState) bool {
+	return !Started(game) && !(game.PlayerXJoined && game.PlayerOJoined)
+}
+
+// makeRoom expires unstarted games, longest idle first, until a new game
+// fits under the cap set by WithMaxGames. Nothing is expired if that
+// wouldn't make enough room. Must be called with the lock held.
+func (s *Service) makeRoom(ctx context.Context) error {
+	if s.maxGames <= 0 {
+		return nil
+	}
+	s.expiry.LastRun = s.clock.Now()
+	games, err := s.games.List(ctx)
+	if err != nil {
+		return err
+	}
+	var open, waiting []*models.GameState
+	for _, game := range games {
+		if game.IsOver {
+			continue
+		}
+		open = append(open, game)
+		if unstarted(game) {
+			waiting = append(waiting, game)
+		}
+	}
+	excess := len(open) - s.maxGames + 1
+	if excess <= 0 {
+		return nil
+	}
+	if excess > len(waiting) {
+		return ErrServerFull
+	}
+	slices.SortFunc(waiting, func(a, b *models.GameState) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	for _, game := range waiting[:excess] {
+		if err := s.expire(ctx, game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expire deletes an unstarted game to make room for new ones. Must be
+// called with the lock held.
+func (s *Service) expire(ctx context.Context, game *models.GameState) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if s.journal != nil {
+		if err := s.journal.Append(EventExpired, game); err != nil {
+			slog.Error("journal append failed", "game_id", game.ID, "event", EventExpired, "error", err)
+			return ErrJournal
+		}
+	}
+	if err := s.games.Delete(context.WithoutCancel(ctx), game.ID); err != nil {
+		return err
+	}
+	s.workers.stop(game.ID)
+	delete(s.owners, game.ID)
+	s.expiry.Expired++
+	slog.InfoContext(ctx, "game expired to make room", "game_id", game.ID, "idle_since", game.UpdatedAt)
+	s.audit(ctx, EventExpired, game.ID, "")
+	s.hooks.emit(hookExpired, game)
+	return nil
+}
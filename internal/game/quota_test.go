@@ -0,0 +1,61 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+func TestCreateGameValidatesBeforeMakingRoom(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	s := game.NewService(
+		game.WithMaxGames(1),
+		game.WithClock(clock),
+		game.WithIDGenerator(&gametest.SequentialIDs{Prefix: "g"}),
+	)
+	defer s.Close()
+
+	waiting, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waiting.ID != "g0001" {
+		t.Errorf("ID = %q, want g0001", waiting.ID)
+	}
+	clock.Advance(time.Minute)
+
+	invalid := []struct {
+		name string
+		opts game.CreateOptions
+		want error
+	}{
+		{"same symbols", game.CreateOptions{XSymbol: "O"}, game.ErrSymbolTaken},
+		{"unknown locale", game.CreateOptions{GameSettings: models.GameSettings{Locale: "tlh"}}, game.ErrInvalidLocale},
+	}
+	for _, tt := range invalid {
+		if _, err := s.CreateGame(ctx, models.PlayerX, tt.opts); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+		if _, ok := s.GetGame(ctx, waiting.ID); !ok {
+			t.Fatalf("%s: the waiting game was expired for a request that failed", tt.name)
+		}
+	}
+
+	// A valid one makes room as before
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetGame(ctx, waiting.ID); ok {
+		t.Error("the waiting game wasn't expired to make room")
+	}
+	if !g.CreatedAt.Equal(clock.Now()) {
+		t.Errorf("CreatedAt = %v, want %v", g.CreatedAt, clock.Now())
+	}
+}
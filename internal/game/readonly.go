@@ -0,0 +1,23 @@
+package game
+
+// SetReadOnly turns every change to the games away with ErrReadOnly, or
+// lets them through again, as on a standby that mirrors another
+// server's games until it takes over from it. Replay still applies
+// changes, which is how the standby keeps up.
+func (s *Service) SetReadOnly(readOnly bool) {
+	s.readOnly.Store(readOnly)
+}
+
+// ReadOnly reports whether changes are turned away, see SetReadOnly.
+func (s *Service) ReadOnly() bool {
+	return s.readOnly.Load()
+}
+
+// writable returns ErrReadOnly while changes are turned away. Every
+// change checks it before it is journaled.
+func (s *Service) writable() error {
+	if s.readOnly.Load() {
+		return ErrReadOnly
+	}
+	return nil
+}
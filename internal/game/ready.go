@@ -0,0 +1,149 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// DefaultReadyTimeout is how long the players of a game with a ready
+// check have to say they are ready, unless set with WithReadyTimeout.
+const DefaultReadyTimeout = time.Minute
+
+// WithReadyTimeout sets how long the players of a game with a ready check
+// have, once both have joined, to say they are ready before the side that
+// hasn't is vacated.
+func WithReadyTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.readyTimeout = d
+	}
+}
+
+// WithCountdown has a game with a ready check start d after both players
+// are ready rather than straight away, so neither is caught out by the
+// first move.
+func WithCountdown(d time.Duration) Option {
+	return func(s *Service) {
+		s.countdown = d
+	}
+}
+
+func (s *Service) readyTimeoutOrDefault() time.Duration {
+	if s.readyTimeout <= 0 {
+		return DefaultReadyTimeout
+	}
+	return s.readyTimeout
+}
+
+// AwaitingReady reports whether the game has both players but won't
+// start until both are ready and any countdown is over, see
+// Service.Ready. Moves fail with ErrNotStarted meanwhile.
+func AwaitingReady(game *models.GameState) bool {
+	return game.ReadyCheck && !game.IsOver && !Started(game) && game.PlayerXJoined && game.PlayerOJoined &&
+		(!game.PlayerXReady || !game.PlayerOReady || !game.StartsAt.IsZero())
+}
+
+// Ready records whether player is ready to start a game with a ready
+// check once both sides have joined. When both are, the game starts, or
+// counts down to starting if WithCountdown is set. Saying you aren't
+// ready during the countdown calls it off.
+func (s *Service) Ready(ctx context.Context, gameID string, player models.Player, ready bool) (*models.GameState, error) {
+	if player != models.PlayerX && player != models.PlayerO {
+		return nil, ErrInvalidPlayer
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case game.IsOver:
+		return nil, ErrGameOver
+	case !game.ReadyCheck:
+		return nil, ErrNoReadyCheck
+	case !game.PlayerXJoined || !game.PlayerOJoined:
+		return nil, ErrWaiting
+	case !AwaitingReady(game):
+		return nil, ErrGameStarted
+	}
+
+	game = game.Clone()
+	game.UpdatedAt = s.clock.Now()
+	if player == models.PlayerX {
+		game.PlayerXReady = ready
+	} else {
+		game.PlayerOReady = ready
+	}
+	game.StartsAt = time.Time{}
+	switch {
+	case game.PlayerXReady && game.PlayerOReady:
+		game.ReadyBy = time.Time{}
+		if s.countdown > 0 {
+			game.StartsAt = game.UpdatedAt.Add(s.countdown)
+		}
+	case game.ReadyBy.IsZero():
+		// Calling off a countdown starts the wait for both over
+		game.ReadyBy = game.UpdatedAt.Add(s.readyTimeoutOrDefault())
+	}
+	if err := s.commit(ctx, EventReady, game); err != nil {
+		return nil, err
+	}
+	s.hooks.emit(hookReady, game)
+	return game, nil
+}
+
+// AdvanceReady does what the clock calls for in a game with a ready
+// check: it starts a game whose countdown is over, or vacates the sides
+// that weren't ready by GameState.ReadyBy. It reports whether it changed
+// the game, and is meant to be called at those times.
+func (s *Service) AdvanceReady(ctx context.Context, gameID string) (*models.GameState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !AwaitingReady(game) {
+		return game, false, nil
+	}
+	now := s.clock.Now()
+	game = game.Clone()
+	event := EventReady
+	var vacated []models.Player
+	switch {
+	case !game.StartsAt.IsZero() && !now.Before(game.StartsAt):
+		game.StartsAt = time.Time{}
+	case !game.ReadyBy.IsZero() && !now.Before(game.ReadyBy):
+		for _, player := range []models.Player{models.PlayerX, models.PlayerO} {
+			if !readyAs(game, player) {
+				vacate(game, player)
+				vacated = append(vacated, player)
+			}
+		}
+		event = EventVacated
+	default:
+		return game, false, nil
+	}
+	game.UpdatedAt = now
+	if err := s.commit(ctx, event, game); err != nil {
+		return nil, false, err
+	}
+	for _, player := range vacated {
+		s.audit(ctx, EventVacated, game.ID, player)
+	}
+	s.hooks.emit(hookReady, game)
+	return game, true, nil
+}
+
+// readyAs reports whether player has said they are ready in the game.
+func readyAs(game *models.GameState, player models.Player) bool {
+	if player == models.PlayerX {
+		return game.PlayerXReady
+	}
+	return game.PlayerOReady
+}
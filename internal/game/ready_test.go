@@ -0,0 +1,151 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// readyGame returns a game with a ready check both players joined at
+// the clock's time.
+func readyGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	ctx := context.Background()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{ReadyCheck: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, err = s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestReadyBothStartsTheGame(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock), game.WithCountdown(3*time.Second))
+	defer s.Close()
+	g := readyGame(t, s)
+
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); !errors.Is(err, game.ErrNotStarted) {
+		t.Fatalf("moving before anyone is ready: err = %v, want ErrNotStarted", err)
+	}
+	if _, err := s.Ready(ctx, g.ID, models.PlayerX, true); err != nil {
+		t.Fatal(err)
+	}
+	both, err := s.Ready(ctx, g.ID, models.PlayerO, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !both.PlayerXReady || !both.PlayerOReady || !both.ReadyBy.IsZero() || !both.StartsAt.Equal(start.Add(3*time.Second)) {
+		t.Errorf("both ready: X %v, O %v, ready by %v, starts at %v", both.PlayerXReady, both.PlayerOReady, both.ReadyBy, both.StartsAt)
+	}
+	// Not until the countdown is over
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); !errors.Is(err, game.ErrNotStarted) {
+		t.Errorf("moving during the countdown: err = %v, want ErrNotStarted", err)
+	}
+	if _, changed, _ := s.AdvanceReady(ctx, g.ID); changed {
+		t.Error("started before the countdown was over")
+	}
+	clock.Advance(3 * time.Second)
+	if _, changed, err := s.AdvanceReady(ctx, g.ID); !changed || err != nil {
+		t.Fatalf("AdvanceReady after the countdown: changed %v, err %v", changed, err)
+	}
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+		t.Errorf("moving once started: %v", err)
+	}
+	if _, err := s.Ready(ctx, g.ID, models.PlayerX, false); !errors.Is(err, game.ErrGameStarted) {
+		t.Errorf("unready after the start: err = %v, want ErrGameStarted", err)
+	}
+}
+
+func TestReadyTimeoutVacatesTheSideNotReady(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock), game.WithReadyTimeout(time.Minute))
+	defer s.Close()
+	g := readyGame(t, s)
+	if !g.ReadyBy.Equal(start.Add(time.Minute)) {
+		t.Errorf("ready by %v, want a minute after O joined", g.ReadyBy)
+	}
+
+	if _, err := s.Ready(ctx, g.ID, models.PlayerX, true); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(59 * time.Second)
+	if _, changed, _ := s.AdvanceReady(ctx, g.ID); changed {
+		t.Error("vacated O within the minute")
+	}
+	clock.Advance(time.Second)
+	vacated, changed, err := s.AdvanceReady(ctx, g.ID)
+	if !changed || err != nil {
+		t.Fatalf("AdvanceReady a minute on: changed %v, err %v", changed, err)
+	}
+	if vacated.PlayerOJoined || !vacated.PlayerXJoined {
+		t.Errorf("after the timeout X joined %v and O joined %v, want O vacated", vacated.PlayerXJoined, vacated.PlayerOJoined)
+	}
+}
+
+func TestUnreadyBeforeStart(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock), game.WithCountdown(3*time.Second), game.WithReadyTimeout(time.Minute))
+	defer s.Close()
+	g := readyGame(t, s)
+
+	s.Ready(ctx, g.ID, models.PlayerX, true)
+	clock.Advance(30 * time.Second)
+	if _, err := s.Ready(ctx, g.ID, models.PlayerO, true); err != nil {
+		t.Fatal(err)
+	}
+	// Calling the countdown off gives both the full timeout again
+	clock.Advance(time.Second)
+	off, err := s.Ready(ctx, g.ID, models.PlayerO, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off.PlayerOReady || !off.PlayerXReady || !off.StartsAt.IsZero() || !off.ReadyBy.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("called off: X %v, O %v, starts at %v, ready by %v", off.PlayerXReady, off.PlayerOReady, off.StartsAt, off.ReadyBy)
+	}
+	clock.Advance(3 * time.Second)
+	if _, changed, _ := s.AdvanceReady(ctx, g.ID); changed {
+		t.Error("started after the countdown was called off")
+	}
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); !errors.Is(err, game.ErrNotStarted) {
+		t.Errorf("moving after the countdown was called off: err = %v, want ErrNotStarted", err)
+	}
+}
+
+func TestReadyRefusals(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	plain := joinedGame(t, s)
+	waiting, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{ReadyCheck: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		gameID string
+		player models.Player
+		want   error
+	}{
+		{"no ready check", plain.ID, models.PlayerX, game.ErrNoReadyCheck},
+		{"waiting for an opponent", waiting.ID, models.PlayerX, game.ErrWaiting},
+		{"a spectator", waiting.ID, models.Empty, game.ErrInvalidPlayer},
+		{"a missing game", "missing", models.PlayerX, game.ErrGameNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.Ready(ctx, tt.gameID, tt.player, true); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package game
+
+import (
+	"context"
+	"sync"
+
+	"tiktaktoes/internal/models"
+)
+
+// Repository stores game states. Implementations must be safe for
+// concurrent use. The service never modifies a state after handing it to
+// Put, so implementations may keep the pointer. Implementations backed by
+// I/O should give up once ctx is cancelled.
+type Repository interface {
+	// Get returns the game with the given ID and whether it exists.
+	Get(ctx context.Context, id string) (*models.GameState, bool, error)
+	// Put inserts or replaces a game.
+	Put(ctx context.Context, game *models.GameState) error
+	// Delete removes a game. Deleting an unknown game is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns every stored game.
+	List(ctx context.Context) ([]*models.GameState, error)
+	// Close releases the repository's resources.
+	Close() error
+}
+
+// MemoryRepository keeps games in a map. It is the default repository.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	games map[string]*models.GameState
+}
+
+// NewMemoryRepository creates an empty in-memory repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		games: make(map[string]*models.GameState),
+	}
+}
+
+// Get returns the game with the given ID.
+func (r *MemoryRepository) Get(_ context.Context, id string) (*models.GameState, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	game, exists := r.games[id]
+	return game, exists, nil
+}
+
+// Put inserts or replaces a game.
+func (r *MemoryRepository) Put(_ context.Context, game *models.GameState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.games[game.ID] = game
+	return nil
+}
+
+// Delete removes a game.
+func (r *MemoryRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.games, id)
+	return nil
+}
+
+// List returns every stored game.
+func (r *MemoryRepository) List(_ context.Context) ([]*models.GameState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	games := make([]*models.GameState, 0, len(r.games))
+	for _, game := range r.games {
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// Close does nothing.
+func (r *MemoryRepository) Close() error {
+	return nil
+}
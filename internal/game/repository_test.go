@@ -0,0 +1,14 @@
+package game_test
+
+import (
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	gametest.RunRepositoryTests(t, gametest.RepositoryBackend{
+		Open: func(*testing.T, string) game.Repository { return game.NewMemoryRepository() },
+	})
+}
@@ -0,0 +1,117 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// seatedGame returns an online game both players joined with their own
+// symbols, named and with settings of its own, with two moves made.
+func seatedGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	ctx := context.Background()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{
+		XSymbol:      "🐱",
+		Slug:         "best-of-three",
+		GameSettings: models.GameSettings{EarlyDraw: true, AnalysisLive: true, Locale: "es"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{Symbol: "🐶"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []models.Move{{Position: 4, Player: models.PlayerX}, {Position: 0, Player: models.PlayerO}} {
+		if g, err = s.MakeMove(ctx, g.ID, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestResetKeepsPlayers(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	before := seatedGame(t, s)
+
+	g, err := s.ResetGame(ctx, before.ID, models.PlayerO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.PlayerXJoined || !g.PlayerOJoined || g.XSymbol != "🐱" || g.OSymbol != "🐶" {
+		t.Errorf("after a reset: joined %v, %v with %q, %q", g.PlayerXJoined, g.PlayerOJoined, g.XSymbol, g.OSymbol)
+	}
+	if g.Slug != before.Slug || g.GameSettings != before.GameSettings || !g.CreatedAt.Equal(before.CreatedAt) {
+		t.Errorf("after a reset: %q with %+v, want %q with %+v", g.Slug, g.GameSettings, before.Slug, before.GameSettings)
+	}
+	if g.Board != (models.Board{}) || len(g.History) != 0 || g.IsOver || g.CurrentTurn != models.PlayerX {
+		t.Errorf("after a reset: %s with %d moves, over %v, %s to move", g.Board, len(g.History), g.IsOver, g.CurrentTurn)
+	}
+
+	// Both players go on playing, and nobody else can take a seat
+	for _, m := range []models.Move{{Position: 0, Player: models.PlayerX}, {Position: 4, Player: models.PlayerO}} {
+		if _, err := s.MakeMove(ctx, g.ID, m); err != nil {
+			t.Errorf("%s at %d after the reset: %v", m.Player, m.Position, err)
+		}
+	}
+	for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+		if _, err := s.JoinGame(ctx, g.ID, p, game.JoinOptions{}); !errors.Is(err, game.ErrSlotTaken) {
+			t.Errorf("joining as %s after the reset: %v, want ErrSlotTaken", p, err)
+		}
+	}
+}
+
+func TestResetRestartsReadyCheck(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := readyGame(t, s)
+	for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+		if _, err := s.Ready(ctx, g.ID, p, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := s.ResetGame(ctx, g.ID, models.PlayerX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.ReadyCheck || g.ReadyBy.IsZero() || g.PlayerXReady || g.PlayerOReady {
+		t.Errorf("after a reset: ready check %v by %v, ready %v, %v", g.ReadyCheck, g.ReadyBy, g.PlayerXReady, g.PlayerOReady)
+	}
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); !errors.Is(err, game.ErrNotStarted) {
+		t.Errorf("moving before both are ready again: %v, want ErrNotStarted", err)
+	}
+}
+
+func TestHardReset(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	before := seatedGame(t, s)
+
+	g, err := s.HardResetGame(ctx, before.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ID != before.ID || g.Slug != before.Slug || !g.CreatedAt.Equal(before.CreatedAt) {
+		t.Errorf("after a hard reset: %s %q created %v, want %s %q created %v", g.ID, g.Slug, g.CreatedAt, before.ID, before.Slug, before.CreatedAt)
+	}
+	if g.PlayerXJoined || g.PlayerOJoined || g.XSymbol != "" || g.OSymbol != "" || g.GameSettings != (models.GameSettings{}) {
+		t.Errorf("after a hard reset: joined %v, %v with %q, %q and %+v", g.PlayerXJoined, g.PlayerOJoined, g.XSymbol, g.OSymbol, g.GameSettings)
+	}
+	if g.Board != (models.Board{}) || len(g.History) != 0 {
+		t.Errorf("after a hard reset: %s with %d moves", g.Board, len(g.History))
+	}
+	// The seats are free for anyone
+	for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+		if _, err := s.JoinGame(ctx, g.ID, p, game.JoinOptions{}); err != nil {
+			t.Errorf("joining as %s after the hard reset: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package game
+
+import (
+	"tiktaktoes/internal/models"
+	"tiktaktoes/pkg/engine"
+)
+
+// The engines games are played by: on the 3x3 board with lines of
+// three, taking turns, or with a models.HandicapDoubleMove opening with
+// two moves, see rulesFor.
+var (
+	standardRules   = newRules(engine.Standard)
+	doubleMoveRules = newRules(engine.DoubleMove)
+)
+
+func newRules(variant engine.Variant) *engine.Engine {
+	e, err := engine.New(models.BoardSize, models.BoardSize, variant)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// rulesFor returns the engine a game with settings is played by. The
+// marks a handicap or start position places are on the board before
+// play, so they don't change the rules.
+func rulesFor(settings models.GameSettings) *engine.Engine {
+	if h := settings.Handicap; h != nil && h.Style == models.HandicapDoubleMove {
+		return doubleMoveRules
+	}
+	return standardRules
+}
+
+// stateOf returns game's position as the engine sees it, counting only
+// the moves the players made.
+func stateOf(game *models.GameState) engine.State {
+	return engine.State{
+		Board:  cells(game.Board),
+		Turn:   engine.Player(game.CurrentTurn),
+		Moves:  len(game.History) - placed(game),
+		Winner: engine.Player(game.Winner),
+		Over:   game.IsOver,
+	}
+}
+
+// cells returns board as the engine's.
+func cells(board models.Board) []engine.Player {
+	out := make([]engine.Player, len(board))
+	for i, cell := range board {
+		out[i] = engine.Player(cell)
+	}
+	return out
+}
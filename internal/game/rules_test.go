@@ -0,0 +1,161 @@
+package game_test
+
+import (
+	"errors"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// lineOwners returns the players with three marks in a row on board,
+// found by walking from every cell in every direction rather than from a
+// table of lines, to check the rules against.
+func lineOwners(board models.Board) map[models.Player]bool {
+	const n = models.BoardSize
+	owners := map[models.Player]bool{}
+	for row := range n {
+		for col := range n {
+			p := board[row*n+col]
+			if p == models.Empty {
+				continue
+			}
+			for _, dir := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+				run := 1
+				for r, c := row+dir[0], col+dir[1]; r >= 0 && r < n && c >= 0 && c < n && board[r*n+c] == p; r, c = r+dir[0], c+dir[1] {
+					run++
+				}
+				if run == n {
+					owners[p] = true
+				}
+			}
+		}
+	}
+	return owners
+}
+
+func TestCheckWinnerOnEveryBoard(t *testing.T) {
+	marks := []models.Player{models.Empty, models.PlayerX, models.PlayerO}
+	var board models.Board
+	for code := range 19683 { // 3^9
+		for i, c := 0, code; i < len(board); i, c = i+1, c/3 {
+			board[i] = marks[c%3]
+		}
+		owners := lineOwners(board)
+		got := game.CheckWinner(board)
+		// Boards where both sides have a line can't be played to, but
+		// either of them will do
+		if got == models.Empty && len(owners) > 0 || got != models.Empty && !owners[got] {
+			t.Fatalf("CheckWinner(%s) = %q, want one of %v", board, got, owners)
+		}
+	}
+}
+
+// TestEveryGame plays every game there is on the board, from an empty
+// one to its end, and checks the rules hold at each move.
+func TestEveryGame(t *testing.T) {
+	var games, xWins, oWins, draws int
+	var play func(g *models.GameState)
+	play = func(g *models.GameState) {
+		for pos := range g.Board {
+			if g.Board[pos] != models.Empty {
+				continue
+			}
+			mover := g.CurrentTurn
+			if err := game.CheckMove(g, pos, other(mover)); !errors.Is(err, game.ErrNotYourTurn) {
+				t.Fatalf("%s: %s moving at %d out of turn: err = %v", g.Board, other(mover), pos, err)
+			}
+			next := g.Clone()
+			if err := game.Place(next, models.Move{Position: pos, Player: mover}); err != nil {
+				t.Fatalf("%s: %s at %d: %v", g.Board, mover, pos, err)
+			}
+			if !next.IsOver {
+				if len(lineOwners(next.Board)) > 0 || full(next.Board) {
+					t.Fatalf("%s: the game went on", next.Board)
+				}
+				if next.CurrentTurn != other(mover) {
+					t.Fatalf("%s: %s to move after %s", next.Board, next.CurrentTurn, mover)
+				}
+				play(next)
+				continue
+			}
+
+			games++
+			checkEnd(t, next)
+			switch next.Winner {
+			case models.PlayerX:
+				xWins++
+			case models.PlayerO:
+				oWins++
+			default:
+				draws++
+			}
+		}
+	}
+	play(models.NewGameState("g"))
+
+	// The well-known counts of games of tic-tac-toe played to the end
+	if games != 255168 || xWins != 131184 || oWins != 77904 || draws != 46080 {
+		t.Errorf("%d games: %d won by X, %d by O and %d drawn, want 255168: 131184, 77904 and 46080",
+			games, xWins, oWins, draws)
+	}
+}
+
+// checkEnd checks the rules hold for g, a game just over.
+func checkEnd(t *testing.T, g *models.GameState) {
+	t.Helper()
+	owners := lineOwners(g.Board)
+	switch {
+	case g.IsDraw == (g.Winner != models.Empty):
+		t.Fatalf("%s: winner %q and draw %v", g.Board, g.Winner, g.IsDraw)
+	case g.IsDraw && (len(owners) > 0 || !full(g.Board)):
+		t.Fatalf("%s: drawn with a line or room left", g.Board)
+	case !g.IsDraw && (len(owners) != 1 || !owners[g.Winner]):
+		t.Fatalf("%s: won by %s, but the lines are %v", g.Board, g.Winner, owners)
+	case g.Winner != models.Empty && g.Winner != g.History[len(g.History)-1].Player:
+		t.Fatalf("%s: won by %s, who didn't move last", g.Board, g.Winner)
+	}
+
+	// The sides took turns, X first
+	for i, m := range g.History {
+		if want := []models.Player{models.PlayerX, models.PlayerO}[i%2]; m.Player != want {
+			t.Fatalf("%s: move %d by %s, want %s", g.Board, i, m.Player, want)
+		}
+	}
+
+	// Nothing more can be played
+	for pos := range g.Board {
+		for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+			if err := game.CheckMove(g, pos, p); !errors.Is(err, game.ErrGameOver) {
+				t.Fatalf("%s: %s at %d after the end: err = %v", g.Board, p, pos, err)
+			}
+		}
+	}
+
+	// And the history plays out to the same end
+	replay := models.NewGameState("g")
+	for _, m := range g.History {
+		if err := game.Place(replay, models.Move{Position: m.Position, Player: m.Player}); err != nil {
+			t.Fatalf("%s: replaying %s at %d: %v", g.Board, m.Player, m.Position, err)
+		}
+	}
+	if replay.Board != g.Board || replay.Winner != g.Winner || replay.IsDraw != g.IsDraw || !replay.IsOver {
+		t.Fatalf("the history of %s replays to %s, winner %q, draw %v", g.Board, replay.Board, replay.Winner, replay.IsDraw)
+	}
+}
+
+func full(board models.Board) bool {
+	for _, cell := range board {
+		if cell == models.Empty {
+			return false
+		}
+	}
+	return true
+}
+
+func other(p models.Player) models.Player {
+	if p == models.PlayerX {
+		return models.PlayerO
+	}
+	return models.PlayerX
+}
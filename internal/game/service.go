@@ -1,76 +1,308 @@
 package game
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/text"
+	"tiktaktoes/pkg/engine"
+	"time"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+var tracer = otel.Tracer("tiktaktoes/internal/game")
+
 var (
-	ErrInvalidMove   = errors.New("invalid move")
-	ErrNotYourTurn   = errors.New("not your turn")
-	ErrGameOver      = errors.New("game is over")
-	ErrPositionTaken = errors.New("position already taken")
-	ErrGameFull      = errors.New("game is full, already has two players")
-	ErrSlotTaken     = errors.New("that player slot is already taken")
-	ErrInvalidPlayer = errors.New("invalid player, must be X or O")
+	ErrGameNotFound    = errors.New("game not found")
+	ErrAmbiguousID     = errors.New("that code matches more than one game, enter more of it")
+	ErrInvalidMove     = engine.ErrInvalidMove
+	ErrNotYourTurn     = engine.ErrNotYourTurn
+	ErrGameOver        = engine.ErrGameOver
+	ErrPositionTaken   = engine.ErrPositionTaken
+	ErrGameFull        = errors.New("game is full, already has two players")
+	ErrSlotTaken       = errors.New("that player slot is already taken")
+	ErrInvalidPlayer   = errors.New("invalid player, must be X or O")
+	ErrIDExhausted     = errors.New("could not generate a unique game id")
+	ErrJournal         = errors.New("could not record the change, try again")
+	ErrInvalidSymbol   = errors.New("symbol must be a single printable character or emoji")
+	ErrSymbolTaken     = errors.New("both players can't use the same symbol")
+	ErrGameStarted     = errors.New("game has already started")
+	ErrSlotEmpty       = errors.New("nobody has joined that player slot")
+	ErrInvalidMode     = errors.New("unknown game mode, must be online, hotseat or ai")
+	ErrWaiting         = errors.New("waiting for an opponent to join")
+	ErrDrawOffered     = errors.New("a draw can be offered once a turn, after the first move")
+	ErrNoDrawOffer     = errors.New("there is no draw offer to answer")
+	ErrQuotaExceeded   = errors.New("too many open games")
+	ErrServerFull      = errors.New("the server has too many games in progress, try again later")
+	ErrNoClaim         = errors.New("a win can only be claimed in an online game under way")
+	ErrOpponentPresent = errors.New("your opponent is still connected")
+	ErrClaimTooEarly   = errors.New("your opponent hasn't been away long enough")
+	ErrVacateTooEarly  = errors.New("that player hasn't been away long enough to be replaced")
+	ErrGameExists      = errors.New("a game with that id already exists")
+	ErrInvalidHandicap = errors.New("invalid handicap")
+	ErrInvalidStart    = errors.New("invalid start position")
+	ErrSettingsLocked  = errors.New("settings can't change once the opponent has joined or play has begun")
+	ErrNotCreator      = errors.New("only the player who created the game can change its settings")
+	ErrModeFixed       = errors.New("a game's mode can't be changed")
+	ErrNotStarted      = errors.New("the game starts once both players are ready")
+	ErrNoReadyCheck    = errors.New("this game has no ready check")
+	ErrReadyCheckMode  = errors.New("only online games can have a ready check")
+	ErrNothingToUndo   = errors.New("there is no reset or cancellation to undo")
+	ErrUndoStale       = errors.New("the game has moved on since, so it can't be undone")
+	ErrNotResetter     = errors.New("only the player who reset or cancelled the game can undo it")
+	ErrInvalidSlug     = errors.New("a game's name must be 3 to 32 lowercase letters, digits or dashes")
+	ErrSlugTaken       = errors.New("another game in progress has that name")
+	ErrComputerSide    = errors.New("the computer plays that side")
+	ErrComputerDraw    = errors.New("the computer doesn't take draw offers")
+	ErrInvalidState    = errors.New("invalid game state")
+	ErrInvalidBatch    = errors.New("a batch must have 1 to 9 moves")
+	ErrNoBatch         = errors.New("only exhibition games take moves in batches")
+	ErrReadOnly        = errors.New("this server is a read-only standby, try the primary")
+	ErrInvalidLocale   = errors.New("unknown locale")
 )
 
-// winConditions defines all possible winning combinations
-var winConditions = [][]int{
-	{0, 1, 2}, // top row
-	{3, 4, 5}, // middle row
-	{6, 7, 8}, // bottom row
-	{0, 3, 6}, // left column
-	{1, 4, 7}, // middle column
-	{2, 5, 8}, // right column
-	{0, 4, 8}, // diagonal
-	{2, 4, 6}, // anti-diagonal
-}
+// Events recorded in the journal
+const (
+	EventCreated   = "create"
+	EventJoined    = "join"
+	EventMoved     = "move"
+	EventReset     = "reset"
+	EventHardReset = "hard-reset"
+	EventCancelled = "cancel"
+	EventVacated   = "vacate"
+	EventDrawOffer = "draw-offer"
+	EventDrawReply = "draw-reply"
+	EventExpired   = "expire"
+	EventClaimed   = "claim"
+	EventImported  = "import"
+	EventSettings  = "settings"
+	EventReady     = "ready"
+	EventUndone    = "undo"
+)
+
+// maxIDAttempts bounds how many IDs are tried before giving up on a collision
+const maxIDAttempts = 10
 
 // Service handles game logic
 type Service struct {
-	games map[string]*models.GameState
-	mu    sync.RWMutex
+	games Repository
+	// index is games, indexed by slug and ID prefix
+	index   *indexedRepository
+	mu      sync.RWMutex
+	ids     IDGenerator
+	clock   Clock
+	journal Journal
+	auditor Auditor
+	hooks   hooks
+
+	// quota and maxGames are set by WithQuota and WithMaxGames. owners
+	// maps the IDs of games created with an owner to it, for the quota.
+	// expiry counts what making room under maxGames did, for Stats.
+	quota    int
+	maxGames int
+	owners   map[string]string
+	expiry   ExpiryStats
+
+	// presence, claimAfter and vacateAfter are set by WithPresence,
+	// WithClaimAfter and WithVacateAfter
+	presence    Presence
+	claimAfter  time.Duration
+	vacateAfter time.Duration
+
+	// readyTimeout and countdown are set by WithReadyTimeout and
+	// WithCountdown
+	readyTimeout time.Duration
+	countdown    time.Duration
+
+	// undoWindow is set by WithUndoWindow. held maps the IDs of games
+	// recently reset or cancelled to the state they had, see UndoReset.
+	undoWindow time.Duration
+	held       map[string]heldGame
+
+	// thinkTime is set by WithThinkTime
+	thinkTime time.Duration
+	// workers owns the goroutines working on games, see Go
+	workers *workers
+
+	// text checks what people type, see WithTextPolicy
+	text text.Policy
+
+	// readOnly turns every change away, see SetReadOnly
+	readOnly atomic.Bool
 }
 
 // NewService creates a new game service
-func NewService() *Service {
-	return &Service{
-		games: make(map[string]*models.GameState),
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		games:   NewMemoryRepository(),
+		ids:     uuidGenerator{},
+		clock:   systemClock{},
+		workers: newWorkers(),
+		text:    text.NewDefault(nil),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.index = newIndexedRepository(s.games, s.text.Key)
+	s.games = s.index
+	return s
+}
+
+// Close stops the games' workers and waits for them, see Go, delivers
+// any pending hook calls and releases the service's repository.
+func (s *Service) Close() error {
+	s.workers.close()
+	s.hooks.close()
+	return s.games.Close()
+}
+
+// CreateOptions are optional settings for a new game.
+type CreateOptions struct {
+	// XSymbol and OSymbol replace the marks displayed for each player.
+	XSymbol string
+	OSymbol string
+	// GameSettings are the game's settings. The mark of a HandicapMark
+	// goes on a random cell if its Position is RandomPosition.
+	models.GameSettings
+	// Owner identifies who is creating the game, such as their address,
+	// for the quota set by WithQuota. Empty is exempt.
+	Owner string
+	// Slug names the game, such as "friday-lunch", so it can be looked
+	// up by that as well as its ID while it is in progress. Without one,
+	// FriendlyID makes one up.
+	Slug       string
+	FriendlyID bool
+}
+
+// JoinOptions are optional settings applied when a player joins.
+type JoinOptions struct {
+	// Symbol replaces the mark displayed for the joining player.
+	Symbol string
 }
 
 // CreateGame creates a new game and returns its state.
 // The creator automatically joins as the given player.
-func (s *Service) CreateGame(creator models.Player) *models.GameState {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Service) CreateGame(ctx context.Context, creator models.Player, opts CreateOptions) (*models.GameState, error) {
+	// Everything asked for is checked before the lock, and before
+	// making room could expire anyone's game for a request that fails
+	if err := validateSymbol(opts.XSymbol); err != nil {
+		return nil, err
+	}
+	if err := validateSymbol(opts.OSymbol); err != nil {
+		return nil, err
+	}
+	game := models.NewGameState("")
+	game.CreatedAt = s.clock.Now()
+	game.UpdatedAt = game.CreatedAt
+	game.XSymbol = opts.XSymbol
+	game.OSymbol = opts.OSymbol
+	if game.Symbol(models.PlayerX) == game.Symbol(models.PlayerO) {
+		return nil, ErrSymbolTaken
+	}
 
-	id := uuid.New().String()[:8]
-	game := models.NewGameState(id)
+	if err := applySettings(game, opts.GameSettings, game.CreatedAt); err != nil {
+		return nil, err
+	}
 
-	if creator == models.PlayerX {
+	if game.Mode == models.ModeHotseat || game.Mode == models.ModeAI {
+		game.PlayerXJoined, game.PlayerOJoined = true, true
+	} else if creator == models.PlayerX {
 		game.PlayerXJoined = true
 	} else if creator == models.PlayerO {
 		game.PlayerOJoined = true
 	}
 
-	s.games[id] = game
-	return game
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkQuota(ctx, opts.Owner); err != nil {
+		return nil, err
+	}
+	slug, err := s.claimSlug(ctx, NormalizeID(opts.Slug), opts.FriendlyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.makeRoom(ctx); err != nil {
+		return nil, err
+	}
+	id, err := s.newID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	game.ID = id
+	game.Slug = slug
+
+	if err := s.commit(ctx, EventCreated, game); err != nil {
+		return nil, err
+	}
+	if s.quota > 0 && opts.Owner != "" {
+		if s.owners == nil {
+			s.owners = make(map[string]string)
+		}
+		s.owners[game.ID] = opts.Owner
+	}
+	s.hooks.emit(hookCreated, game)
+	return game, nil
+}
+
+// newID generates an ID not used by any existing game.
+// Must be called with the lock held.
+func (s *Service) newID(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	for range maxIDAttempts {
+		id := NormalizeID(s.ids.NewID())
+		if id == "" {
+			continue
+		}
+		_, exists, err := s.games.Get(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return id, nil
+		}
+	}
+	return "", ErrIDExhausted
+}
+
+// audit records a destructive action if an Auditor is set.
+func (s *Service) audit(ctx context.Context, action, gameID string, player models.Player) {
+	if s.auditor != nil {
+		s.auditor.Record(ctx, action, gameID, player)
+	}
+}
+
+// lookup returns the stored game or an error if it doesn't exist. It
+// also gives up if ctx was cancelled while waiting for the lock.
+func (s *Service) lookup(ctx context.Context, id string) (*models.GameState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.find(ctx, id)
 }
 
 // JoinGame attempts to join a game as the given player.
 // Returns an error if the game is full or the slot is already taken.
-func (s *Service) JoinGame(gameID string, player models.Player) (*models.GameState, error) {
+func (s *Service) JoinGame(ctx context.Context, gameID string, player models.Player, opts JoinOptions) (*models.GameState, error) {
+	if err := validateSymbol(opts.Symbol); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	game, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
 	}
 
 	if player != models.PlayerX && player != models.PlayerO {
@@ -91,104 +323,442 @@ func (s *Service) JoinGame(gameID string, player models.Player) (*models.GameSta
 	}
 
 	// Join
+	game = game.Clone()
 	if player == models.PlayerX {
 		game.PlayerXJoined = true
+		if opts.Symbol != "" {
+			game.XSymbol = opts.Symbol
+		}
 	} else {
 		game.PlayerOJoined = true
+		if opts.Symbol != "" {
+			game.OSymbol = opts.Symbol
+		}
+	}
+	if game.Symbol(models.PlayerX) == game.Symbol(models.PlayerO) {
+		return nil, ErrSymbolTaken
+	}
+	game.UpdatedAt = s.clock.Now()
+	if game.ReadyCheck && game.PlayerXJoined && game.PlayerOJoined {
+		game.ReadyBy = game.UpdatedAt.Add(s.readyTimeoutOrDefault())
 	}
 
+	if err := s.commit(ctx, EventJoined, game); err != nil {
+		return nil, err
+	}
+	s.hooks.emit(hookJoined, game)
 	return game, nil
 }
 
-// GetGame retrieves a game by ID
-func (s *Service) GetGame(id string) (*models.GameState, bool) {
+// GetGame retrieves a game by ID or unique ID prefix, see FindGame.
+// An ambiguous prefix is reported as not found.
+func (s *Service) GetGame(ctx context.Context, id string) (*models.GameState, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	game, exists := s.games[id]
-	return game, exists
+	game, err := s.find(ctx, id)
+	if errors.Is(err, ErrGameNotFound) || errors.Is(err, ErrAmbiguousID) {
+		return nil, false
+	}
+	if err != nil {
+		slog.Error("loading game failed", "game_id", id, "error", err)
+		return nil, false
+	}
+	return game, true
 }
 
 // MakeMove processes a move and returns updated game state
-func (s *Service) MakeMove(gameID string, move models.Move) (*models.GameState, error) {
+func (s *Service) MakeMove(ctx context.Context, gameID string, move models.Move) (*models.GameState, error) {
+	ctx, span := tracer.Start(ctx, "game.MakeMove")
+	defer span.End()
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("game.id", gameID),
+			attribute.Int("move.position", move.Position),
+			attribute.String("move.player", string(move.Player)),
+		)
+	}
+
+	game, err := s.makeMove(ctx, gameID, move)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return game, err
+}
+
+func (s *Service) makeMove(ctx context.Context, gameID string, move models.Move) (*models.GameState, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	game, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	move, err = checkPlayable(game, move)
+	if err != nil {
+		return nil, err
 	}
+	return s.play(ctx, game, move)
+}
 
+// checkPlayable checks that a player may make move in game, returning it
+// with the cell it names resolved to its Position.
+func checkPlayable(game *models.GameState, move models.Move) (models.Move, error) {
 	if game.IsOver {
-		return nil, ErrGameOver
+		return move, ErrGameOver
 	}
 
-	if move.Position < 0 || move.Position > 8 {
-		return nil, ErrInvalidMove
+	if game.Mode == models.ModeAI && move.Player == ComputerPlayer {
+		return move, ErrComputerSide
 	}
 
-	if game.Board[move.Position] != models.Empty {
-		return nil, ErrPositionTaken
+	var err error
+	move.Position, err = resolvePosition(move)
+	if err != nil {
+		return move, err
+	}
+	return move, CheckMove(game, move.Position, move.Player)
+}
+
+// play makes a checked move in the game and commits it. Must be called
+// with the lock held.
+func (s *Service) play(ctx context.Context, game *models.GameState, move models.Move) (*models.GameState, error) {
+	game = game.Clone()
+	if err := place(game, move, "", s.clock.Now()); err != nil {
+		return nil, err
 	}
 
-	if move.Player != game.CurrentTurn {
-		return nil, ErrNotYourTurn
+	if err := s.commit(ctx, EventMoved, game); err != nil {
+		return nil, err
 	}
+	s.hooks.emit(hookMoved, game)
+	if game.IsOver {
+		s.hooks.emit(hookFinished, game)
+	}
+	return game, nil
+}
 
-	// Make the move
+// place makes a checked move in game, a clone not yet committed, and
+// records it in the History as made at now, in the batch with the given
+// ID if it is one of several, see MakeMoves. The game's rules, see
+// rulesFor, decide how it stands after the move.
+func place(game *models.GameState, move models.Move, batch string, now time.Time) error {
+	state, err := rulesFor(game.GameSettings).Apply(stateOf(game), engine.Move{Position: move.Position, Player: engine.Player(move.Player)})
+	if err != nil {
+		return err
+	}
 	game.Board[move.Position] = move.Player
+	game.History = append(game.History, models.MoveRecord{
+		Position: move.Position,
+		Cell:     models.CellName(move.Position, models.BoardSize),
+		Row:      move.Position / models.BoardSize,
+		Col:      move.Position % models.BoardSize,
+		Player:   move.Player,
+		At:       now,
+		Think:    thinkTime(game, now),
+		Batch:    batch,
+	})
+	game.UpdatedAt = now
+	// Moving instead of waiting for an answer withdraws an offer of a draw
+	if game.DrawOffer == move.Player {
+		game.DrawOffer = models.Empty
+	}
 
-	// Check for winner
-	if winner := checkWinner(game.Board); winner != models.Empty {
-		game.Winner = winner
-		game.IsOver = true
-	} else if isBoardFull(game.Board) {
-		game.IsDraw = true
+	if state.Over {
+		game.Winner = models.Player(state.Winner)
+		game.IsDraw = state.Draw()
 		game.IsOver = true
 	} else {
-		// Switch turns
-		if game.CurrentTurn == models.PlayerX {
-			game.CurrentTurn = models.PlayerO
-		} else {
-			game.CurrentTurn = models.PlayerX
+		game.CurrentTurn = models.Player(state.Turn)
+	}
+	return nil
+}
+
+// resolvePosition returns the board index a move names, whichever of
+// Position, Cell or Row/Col it was given as. A zero Position counts as
+// unset when another form is present, since it can't be told apart.
+func resolvePosition(move models.Move) (int, error) {
+	position := move.Position
+	given := ""
+	if move.Cell != "" {
+		p, err := models.ParseCell(move.Cell, models.BoardSize)
+		if err != nil {
+			return 0, err
+		}
+		if move.Position != 0 && move.Position != p {
+			return 0, fmt.Errorf("%w: cell %q and position %d disagree", ErrInvalidMove, move.Cell, move.Position)
+		}
+		position, given = p, fmt.Sprintf("cell %q", move.Cell)
+	}
+	if move.Row != nil || move.Col != nil {
+		if move.Row == nil || move.Col == nil {
+			return 0, fmt.Errorf("%w: row and col must be given together", ErrInvalidMove)
+		}
+		p, err := models.CellIndex(*move.Row, *move.Col, models.BoardSize)
+		if err != nil {
+			return 0, err
 		}
+		if given != "" && p != position {
+			return 0, fmt.Errorf("%w: %s and row %d, col %d disagree", ErrInvalidMove, given, *move.Row, *move.Col)
+		}
+		if given == "" && move.Position != 0 && move.Position != p {
+			return 0, fmt.Errorf("%w: position %d and row %d, col %d disagree", ErrInvalidMove, move.Position, *move.Row, *move.Col)
+		}
+		position = p
+	}
+	return position, nil
+}
+
+// ResetGame starts an existing game over, keeping its players and
+// settings. HardResetGame wipes everything instead.
+func (s *Service) ResetGame(ctx context.Context, gameID string, by models.Player) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	game := models.NewGameState(old.ID)
+	game.Slug = old.Slug
+	game.CreatedAt = old.CreatedAt
+	game.XSymbol = old.XSymbol
+	game.OSymbol = old.OSymbol
+	game.PlayerXJoined = old.PlayerXJoined
+	game.PlayerOJoined = old.PlayerOJoined
+	game.UpdatedAt = s.clock.Now()
+	if err := applySettings(game, old.GameSettings, game.UpdatedAt); err != nil {
+		return nil, err
 	}
+	if game.ReadyCheck && game.PlayerXJoined && game.PlayerOJoined {
+		game.ReadyBy = game.UpdatedAt.Add(s.readyTimeoutOrDefault())
+	}
+	return s.reset(ctx, old, game, EventReset, by)
+}
+
+// HardResetGame wipes an existing game back to how a new one starts,
+// keeping only its ID, name and creation time. It can't be undone.
+func (s *Service) HardResetGame(ctx context.Context, gameID string) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	game := models.NewGameState(old.ID)
+	game.Slug = old.Slug
+	game.CreatedAt = old.CreatedAt
+	game.UpdatedAt = s.clock.Now()
+	return s.reset(ctx, old, game, EventHardReset, models.Empty)
+}
 
+// reset replaces old with game, the fresh state it was reset to. A
+// player's reset, by someone other than models.Empty, holds old for them
+// to undo with UndoReset; any other drops what was held. Workers for the
+// game as it was are stopped. Must be called with the lock held.
+func (s *Service) reset(ctx context.Context, old, game *models.GameState, event string, by models.Player) (*models.GameState, error) {
+	if err := s.keepSlug(ctx, game); err != nil {
+		return nil, err
+	}
+	s.workers.stop(old.ID)
+	if err := s.commit(ctx, event, game); err != nil {
+		return nil, err
+	}
+	delete(s.held, old.ID)
+	if event == EventReset && (Started(old) || old.IsOver) {
+		s.hold(old, false, by)
+	}
+	s.audit(ctx, event, game.ID, by)
 	return game, nil
 }
 
-// ResetGame resets an existing game
-func (s *Service) ResetGame(gameID string) (*models.GameState, error) {
+// Waiting reports whether the game is waiting for its second player: one
+// side has joined and no move has been made yet.
+func Waiting(game *models.GameState) bool {
+	return game.PlayerXJoined != game.PlayerOJoined && !Started(game) && !game.IsOver
+}
+
+// CancelGame deletes a game that is still waiting for its opponent, on
+// behalf of by, who can undo it with UndoReset.
+func (s *Service) CancelGame(ctx context.Context, gameID string, by models.Player) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.games[gameID]
-	if !exists {
-		return nil, errors.New("game not found")
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if !Waiting(game) {
+		return ErrGameStarted
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if s.journal != nil {
+		if err := s.journal.Append(EventCancelled, game); err != nil {
+			slog.Error("journal append failed", "game_id", game.ID, "event", EventCancelled, "error", err)
+			return ErrJournal
+		}
+	}
+	if err := s.games.Delete(context.WithoutCancel(ctx), game.ID); err != nil {
+		return err
+	}
+	s.workers.stop(game.ID)
+	s.hold(game, true, by)
+	delete(s.owners, game.ID)
+	s.audit(ctx, EventCancelled, game.ID, by)
+	s.hooks.emit(hookCancelled, game)
+	return nil
+}
+
+// VacateSlot frees a joined player slot before the first move, once its
+// player has been away for the period set by WithVacateAfter.
+func (s *Service) VacateSlot(ctx context.Context, gameID string, player models.Player) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if player != models.PlayerX && player != models.PlayerO {
+		return nil, ErrInvalidPlayer
+	}
+	if game.IsOver || Started(game) {
+		return nil, ErrGameStarted
+	}
+	if game.Mode == models.ModeAI && player == ComputerPlayer {
+		return nil, ErrComputerSide
+	}
+
+	if (player == models.PlayerX && !game.PlayerXJoined) || (player == models.PlayerO && !game.PlayerOJoined) {
+		return nil, ErrSlotEmpty
+	}
+	now := s.clock.Now()
+	at, err := s.vacatableAt(game, player)
+	if err != nil {
+		return nil, err
+	}
+	if now.Before(at) {
+		return nil, fmt.Errorf("%w: try again in %s", ErrVacateTooEarly, at.Sub(now).Round(time.Second))
 	}
 
-	game := models.NewGameState(gameID)
-	s.games[gameID] = game
+	game = game.Clone()
+	vacate(game, player)
+	game.UpdatedAt = now
+
+	if err := s.commit(ctx, EventVacated, game); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, EventVacated, game.ID, player)
+	s.hooks.emit(hookVacated, game)
 	return game, nil
 }
 
-// checkWinner checks if there's a winner
-func checkWinner(board models.Board) models.Player {
-	for _, condition := range winConditions {
-		a, b, c := condition[0], condition[1], condition[2]
-		if board[a] != models.Empty && board[a] == board[b] && board[b] == board[c] {
-			return board[a]
+// vacatableAt returns when player's slot in the game can be vacated:
+// once they have been away for the period set by WithVacateAfter. Must be
+// called with the lock held.
+func (s *Service) vacatableAt(game *models.GameState, player models.Player) (time.Time, error) {
+	// A player who never had a live view open, say one playing over the
+	// API, or one nobody tracks, has been away since the game last
+	// changed at the earliest
+	since := game.UpdatedAt
+	if s.presence != nil {
+		left, offline := s.presence.OfflineSince(game.ID, player)
+		if !offline {
+			return time.Time{}, ErrOpponentPresent
+		}
+		if left.After(since) {
+			since = left
 		}
 	}
-	return models.Empty
+	after := s.vacateAfter
+	if after <= 0 {
+		after = DefaultVacateAfter
+	}
+	return since.Add(after), nil
 }
 
-// isBoardFull checks if the board is full
-func isBoardFull(board models.Board) bool {
-	for _, cell := range board {
-		if cell == models.Empty {
-			return false
+// vacate frees player's slot in the game, which calls off any wait for
+// both players to be ready.
+func vacate(game *models.GameState, player models.Player) {
+	if player == models.PlayerX {
+		game.PlayerXJoined = false
+		game.PlayerXReady = false
+		game.XSymbol = ""
+	} else {
+		game.PlayerOJoined = false
+		game.PlayerOReady = false
+		game.OSymbol = ""
+	}
+	game.ReadyBy = time.Time{}
+	game.StartsAt = time.Time{}
+}
+
+// commit journals a changed game and stores it, bumping its Version.
+// Must be called with the lock held.
+func (s *Service) commit(ctx context.Context, event string, game *models.GameState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.writable(); err != nil {
+		return err
+	}
+	ctx = context.WithoutCancel(ctx)
+	prev, exists, err := s.games.Get(ctx, game.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		game.Version = max(game.Version, prev.Version)
+	}
+	game.Version++
+	game.LegalMoves = LegalMoves(game)
+	game.Thinking = ComputerToMove(game)
+	game.LastMove = LastMove(game)
+	game.Timing = Timing(game)
+	if s.journal != nil {
+		if err := s.journal.Append(event, game); err != nil {
+			slog.Error("journal append failed", "game_id", game.ID, "event", event, "error", err)
+			return ErrJournal
 		}
 	}
-	return true
+	if err := s.games.Put(ctx, game); err != nil {
+		return err
+	}
+	if game.IsOver {
+		s.workers.stop(game.ID)
+	}
+	if game.Thinking {
+		s.think(game)
+	}
+	if game.EarlyDraw && !game.IsOver {
+		s.checkDead(game)
+	}
+	return nil
+}
+
+// checkWinner returns the player with a line on board, or Empty.
+func checkWinner(board models.Board) models.Player {
+	return models.Player(standardRules.Winner(engine.State{Board: cells(board)}))
+}
+
+// WinningLine returns the cells of the line that won the game, or nil if
+// nobody has won.
+func WinningLine(board models.Board) []int {
+	return standardRules.WinningLine(cells(board))
+}
+
+// isBoardFull checks if the board is full
+func isBoardFull(board models.Board) bool {
+	return engine.State{Board: cells(board)}.Full()
 }
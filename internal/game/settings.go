@@ -0,0 +1,137 @@
+package game
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/models"
+)
+
+// validateSettings checks a game's settings, including how they go
+// together, with any random handicap cell already picked. Every way of
+// setting them, creating a game, changing its settings or loading it,
+// comes through here.
+func validateSettings(settings models.GameSettings) error {
+	if settings.Mode != models.ModeOnline && settings.Mode != models.ModeHotseat && settings.Mode != models.ModeAI {
+		return ErrInvalidMode
+	}
+	// Both sides of a hot-seat game are at the same device, and the
+	// computer is always ready
+	if settings.ReadyCheck && settings.Mode != models.ModeOnline {
+		return ErrReadyCheckMode
+	}
+	if settings.Handicap != nil {
+		if err := checkHandicap(settings.Handicap); err != nil {
+			return err
+		}
+	}
+	if _, ok := i18n.Get(settings.Locale); settings.Locale != "" && !ok {
+		return ErrInvalidLocale
+	}
+	return checkStart(settings)
+}
+
+// applySettings gives a game that hasn't started settings, setting the
+// board up afresh with any handicap mark or start position.
+func applySettings(game *models.GameState, settings models.GameSettings, now time.Time) error {
+	if h := settings.Handicap; h != nil {
+		handicap := *h
+		switch {
+		case handicap.Style != models.HandicapMark:
+			handicap.Position = 0
+		case handicap.Position == RandomPosition:
+			handicap.Position = rand.IntN(len(game.Board))
+		}
+		settings.Handicap = &handicap
+	}
+	if err := validateSettings(settings); err != nil {
+		return err
+	}
+	var start models.Board
+	if settings.StartPosition != "" {
+		start, _ = models.ParseBoard(settings.StartPosition)
+		settings.StartPosition = start.String()
+		settings.StartTurn = startTurn(settings, start)
+	}
+
+	game.GameSettings = settings
+	game.Board = models.Board{}
+	game.History = []models.MoveRecord{}
+	if h := settings.Handicap; h != nil && h.Style == models.HandicapMark {
+		game.Board[h.Position] = h.Player
+		game.History = append(game.History, models.MoveRecord{
+			Position: h.Position,
+			Cell:     models.CellName(h.Position, models.BoardSize),
+			Row:      h.Position / models.BoardSize,
+			Col:      h.Position % models.BoardSize,
+			Player:   h.Player,
+			At:       now,
+		})
+	}
+	if settings.StartPosition != "" {
+		game.Board = start
+		game.History = setupRecords(start, now)
+	}
+	game.CurrentTurn = turnAfter(settings, len(game.History))
+	return nil
+}
+
+// Settings returns a game's settings.
+func (s *Service) Settings(ctx context.Context, gameID string) (models.GameSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return models.GameSettings{}, err
+	}
+	return game.Clone().GameSettings, nil
+}
+
+// UpdateSettings replaces a game's settings on behalf of its creator,
+// until the opponent joins or anyone moves.
+func (s *Service) UpdateSettings(ctx context.Context, gameID string, player models.Player, settings models.GameSettings) (*models.GameState, error) {
+	if player != models.PlayerX && player != models.PlayerO {
+		return nil, ErrInvalidPlayer
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, err := s.lookup(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game.IsOver || Started(game) || (game.PlayerXJoined && game.PlayerOJoined && game.Mode == models.ModeOnline) {
+		return nil, ErrSettingsLocked
+	}
+	if (game.PlayerXJoined || game.PlayerOJoined) && !joinedAs(game, player) {
+		return nil, ErrNotCreator
+	}
+	if settings.Mode != game.Mode {
+		return nil, ErrModeFixed
+	}
+
+	game = game.Clone()
+	game.UpdatedAt = s.clock.Now()
+	if err := applySettings(game, settings, game.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := s.commit(ctx, EventSettings, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// joinedAs reports whether player's side of the game has been joined.
+func joinedAs(game *models.GameState, player models.Player) bool {
+	switch player {
+	case models.PlayerX:
+		return game.PlayerXJoined
+	case models.PlayerO:
+		return game.PlayerOJoined
+	}
+	return false
+}
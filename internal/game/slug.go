@@ -0,0 +1,138 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/text"
+)
+
+// MaxSlugLength is the longest name a game can be given, see
+// CreateOptions.Slug.
+const MaxSlugLength = 32
+
+// slugPattern is what a game's name may look like.
+var slugPattern = regexp.MustCompile(`^[a-z0-9-]{3,32}$`)
+
+// SlugTakenError is returned by CreateGame when another game in progress
+// has the name asked for. It matches ErrSlugTaken.
+type SlugTakenError struct {
+	// Suggestion is a similar name that is free, such as the name with
+	// "-2" added.
+	Suggestion string
+}
+
+func (e *SlugTakenError) Error() string {
+	return fmt.Sprintf("%v, try %q", ErrSlugTaken, e.Suggestion)
+}
+
+func (e *SlugTakenError) Unwrap() error {
+	return ErrSlugTaken
+}
+
+// checkSlug checks that slug is a name a game can have: 3 to 32
+// lowercase letters, digits or dashes.
+func checkSlug(slug string) error {
+	if !slugPattern.MatchString(slug) {
+		return ErrInvalidSlug
+	}
+	return nil
+}
+
+// slugHolder returns the game in progress named slug, or one that only
+// looks like it to the text policy, or nil if there is none. Finished
+// games give up their names. Must be called with the lock held.
+func (s *Service) slugHolder(ctx context.Context, slug string) (*models.GameState, error) {
+	id, err := s.index.slugHolder(ctx, slug)
+	if err != nil || id == "" {
+		return nil, err
+	}
+	game, err := s.get(ctx, id)
+	if errors.Is(err, ErrGameNotFound) {
+		return nil, nil
+	}
+	return game, err
+}
+
+// claimSlug returns the name a new game gets: slug, or a made-up one if
+// friendly is set. Must be called with the lock held.
+func (s *Service) claimSlug(ctx context.Context, slug string, friendly bool) (string, error) {
+	if slug == "" {
+		if !friendly {
+			return "", nil
+		}
+		return s.freeSlug(ctx, friendlySlug())
+	}
+	slug, err := s.text.Clean(text.FieldSlug, slug)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSlug(slug); err != nil {
+		return "", err
+	}
+	holder, err := s.slugHolder(ctx, slug)
+	if err != nil || holder == nil {
+		return slug, err
+	}
+	suggestion, err := s.freeSlug(ctx, slug)
+	if err != nil {
+		return "", err
+	}
+	return "", &SlugTakenError{Suggestion: suggestion}
+}
+
+// freeSlug returns base if no game in progress has it, or else the first
+// free one of base-2, base-3 and so on, shortening base to fit. Must be
+// called with the lock held.
+func (s *Service) freeSlug(ctx context.Context, base string) (string, error) {
+	candidate := base
+	for n := 2; ; n++ {
+		holder, err := s.slugHolder(ctx, candidate)
+		if err != nil || holder == nil {
+			return candidate, err
+		}
+		suffix := "-" + strconv.Itoa(n)
+		candidate = base[:min(len(base), MaxSlugLength-len(suffix))] + suffix
+	}
+}
+
+// keepSlug drops the name of a game coming back into play, as after a
+// reset or an undone cancellation, if another game in progress has taken
+// it meanwhile. Must be called with the lock held.
+func (s *Service) keepSlug(ctx context.Context, game *models.GameState) error {
+	if game.Slug == "" {
+		return nil
+	}
+	holder, err := s.slugHolder(ctx, game.Slug)
+	if err != nil {
+		return err
+	}
+	if holder != nil && holder.ID != game.ID {
+		game.Slug = ""
+	}
+	return nil
+}
+
+var (
+	slugAdjectives = []string{
+		"brave", "calm", "clever", "cosy", "daring", "eager", "fancy", "gentle",
+		"happy", "jolly", "kind", "lucky", "merry", "nimble", "proud", "quick",
+		"quiet", "sunny", "swift", "witty",
+	}
+	slugNouns = []string{
+		"badger", "beaver", "falcon", "ferret", "gecko", "heron", "koala", "lemur",
+		"lynx", "marmot", "otter", "owl", "panda", "puffin", "quokka", "raven",
+		"seal", "tiger", "walrus", "wombat",
+	}
+)
+
+// friendlySlug makes up a name from an adjective and an animal, such as
+// "brave-otter".
+func friendlySlug() string {
+	return slugAdjectives[rand.IntN(len(slugAdjectives))] + "-" + slugNouns[rand.IntN(len(slugNouns))]
+}
@@ -0,0 +1,175 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// named creates a hot-seat game called slug.
+func named(t *testing.T, s *game.Service, slug string) *models.GameState {
+	t.Helper()
+	g, err := s.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{
+		GameSettings: models.GameSettings{Mode: models.ModeHotseat},
+		Slug:         slug,
+	})
+	if err != nil {
+		t.Fatalf("creating %q: %v", slug, err)
+	}
+	return g
+}
+
+func TestSlugLookup(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := named(t, s, "friday-lunch")
+
+	for _, id := range []string{"friday-lunch", " Friday-Lunch ", g.ID} {
+		if found, err := s.FindGame(ctx, id); err != nil || found.ID != g.ID {
+			t.Errorf("FindGame(%q) = %v, %v, want %s", id, found, err, g.ID)
+		}
+	}
+	// A slug is looked up whole, never as a prefix
+	if _, err := s.FindGame(ctx, "friday"); !errors.Is(err, game.ErrGameNotFound) {
+		t.Errorf("FindGame(friday) err = %v, want ErrGameNotFound", err)
+	}
+}
+
+func TestSlugCollisions(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	long := strings.Repeat("a", game.MaxSlugLength)
+	named(t, s, "friday-lunch")
+	named(t, s, "friday-lunch-2")
+	named(t, s, long)
+
+	tests := []struct {
+		slug       string
+		suggestion string
+	}{
+		{"friday-lunch", "friday-lunch-3"},
+		{"FRIDAY-LUNCH", "friday-lunch-3"},
+		// Invisible characters are dropped before the name is compared
+		{"friday-\u200dlunch", "friday-lunch-3"},
+		{"\u202efriday-lunch\u202c", "friday-lunch-3"},
+		{"friday-lunch-2", "friday-lunch-2-2"},
+		{long, long[:game.MaxSlugLength-2] + "-2"},
+	}
+	for _, tt := range tests {
+		_, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{Slug: tt.slug})
+		var taken *game.SlugTakenError
+		if !errors.As(err, &taken) || !errors.Is(err, game.ErrSlugTaken) {
+			t.Errorf("creating %q: err = %v, want a SlugTakenError", tt.slug, err)
+			continue
+		}
+		if taken.Suggestion != tt.suggestion {
+			t.Errorf("creating %q: suggested %q, want %q", tt.slug, taken.Suggestion, tt.suggestion)
+		}
+	}
+
+	for _, slug := range []string{"ab", "under_score", "with space"} {
+		if _, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{Slug: slug}); !errors.Is(err, game.ErrInvalidSlug) {
+			t.Errorf("creating %q: err = %v, want ErrInvalidSlug", slug, err)
+		}
+	}
+}
+
+// TestSlugRace has many players create games of the same name at once.
+// Only one of them gets it.
+func TestSlugRace(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Go(func() {
+			_, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{Slug: "the-final"})
+			switch {
+			case err == nil:
+				created.Add(1)
+			case !errors.Is(err, game.ErrSlugTaken):
+				t.Errorf("creating: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if created.Load() != 1 {
+		t.Errorf("%d games named the-final, want 1", created.Load())
+	}
+}
+
+func TestSlugReleased(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("when the game finishes", func(t *testing.T) {
+		s := game.NewService()
+		defer s.Close()
+		g := named(t, s, "rematch")
+		for i, pos := range []int{0, 3, 1, 4, 2} {
+			player := []models.Player{models.PlayerX, models.PlayerO}[i%2]
+			if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: pos, Player: player}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		next := named(t, s, "rematch")
+		if found, err := s.FindGame(ctx, "rematch"); err != nil || found.ID != next.ID {
+			t.Errorf("FindGame(rematch) = %v, %v, want the new game %s", found, err, next.ID)
+		}
+	})
+
+	t.Run("when the game expires", func(t *testing.T) {
+		s := game.NewService(game.WithMaxGames(1))
+		defer s.Close()
+		expired := make(chan string, 1)
+		s.OnGameExpired(func(g models.GameState) { expired <- g.ID })
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{Slug: "lobby"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if id := <-expired; id != g.ID {
+			t.Fatalf("expired %s, want %s", id, g.ID)
+		}
+		if _, err := s.FindGame(ctx, "lobby"); !errors.Is(err, game.ErrGameNotFound) {
+			t.Errorf("FindGame(lobby) after it expired: err = %v", err)
+		}
+		if _, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{Slug: "lobby"}); err != nil {
+			t.Errorf("naming a new game lobby: %v", err)
+		}
+	})
+}
+
+func TestFriendlySlug(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	friendly := regexp.MustCompile(`^[a-z]+-[a-z]+(-[0-9]+)?$`)
+
+	seen := make(map[string]bool)
+	for range 50 {
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{FriendlyID: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !friendly.MatchString(g.Slug) || seen[g.Slug] {
+			t.Errorf("made up %q", g.Slug)
+		}
+		seen[g.Slug] = true
+	}
+	if g := named(t, s, ""); g.Slug != "" {
+		t.Errorf("a game created without FriendlyID was named %q", g.Slug)
+	}
+}
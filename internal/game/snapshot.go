@@ -0,0 +1,229 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Snapshot returns copies of all games that are still in progress, along
+// with the journal sequence number they reflect (zero without a journal).
+func (s *Service) Snapshot(ctx context.Context) ([]*models.GameState, uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var seq uint64
+	if s.journal != nil {
+		seq = s.journal.LastSeq()
+	}
+
+	all, err := s.games.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	games := make([]*models.GameState, 0, len(all))
+	for _, game := range all {
+		if game.IsOver {
+			continue
+		}
+		games = append(games, game.Clone())
+	}
+	return games, seq, nil
+}
+
+// Restore loads previously snapshotted games into the service. Games that
+// fail validation or whose ID is already in use are skipped with a
+// warning. It returns the number of games restored.
+func (s *Service) Restore(ctx context.Context, games []*models.GameState) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	restored := 0
+	for _, game := range games {
+		if err := validateState(game); err != nil {
+			slog.Warn("skipping invalid game", "game_id", game.ID, "error", err)
+			continue
+		}
+		_, exists, err := s.games.Get(ctx, game.ID)
+		if err != nil {
+			slog.Warn("skipping game", "game_id", game.ID, "error", err)
+			continue
+		}
+		if exists {
+			slog.Warn("skipping game with duplicate id", "game_id", game.ID)
+			continue
+		}
+		if err := s.games.Put(ctx, withDerived(game)); err != nil {
+			slog.Warn("skipping game", "game_id", game.ID, "error", err)
+			continue
+		}
+		restored++
+	}
+	return restored
+}
+
+// Import adds a game exported from another instance, replacing one with
+// the same ID only if overwrite is set.
+func (s *Service) Import(ctx context.Context, game *models.GameState, overwrite bool) (*models.GameState, error) {
+	if game != nil && game.ID != NormalizeID(game.ID) {
+		return nil, fmt.Errorf("%w: invalid id %q", ErrInvalidState, game.ID)
+	}
+	if err := validateState(game); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists, err := s.games.Get(ctx, game.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists && !overwrite {
+		return nil, ErrGameExists
+	}
+	game = withDerived(game)
+	if err := s.keepSlug(ctx, game); err != nil {
+		return nil, err
+	}
+	if err := s.commit(ctx, EventImported, game); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, EventImported, game.ID, "")
+	return game, nil
+}
+
+// Replay applies a journaled event, replacing any existing game with the
+// same ID, or removing it for a cancelled or expired game. It is used during
+// recovery and does not write to the journal.
+func (s *Service) Replay(ctx context.Context, event string, game *models.GameState) error {
+	if event == EventCancelled || event == EventExpired {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.games.Delete(ctx, game.ID)
+	}
+	if err := validateState(game); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.games.Put(ctx, withDerived(game))
+}
+
+// withDerived returns a copy of game with the fields worked out from the
+// rest filled in: every history entry's row and column, the legal moves,
+// whether the computer is thinking, the last move and a finished game's
+// timing. Games saved before these were recorded don't have them.
+func withDerived(game *models.GameState) *models.GameState {
+	game = game.Clone()
+	for i, m := range game.History {
+		game.History[i].Row = m.Position / models.BoardSize
+		game.History[i].Col = m.Position % models.BoardSize
+	}
+	game.LegalMoves = LegalMoves(game)
+	game.Thinking = ComputerToMove(game)
+	game.LastMove = LastMove(game)
+	game.Timing = Timing(game)
+	return game
+}
+
+// validateState checks that a game state is internally consistent:
+// valid settings and marks, a history that reproduces the board in turn,
+// starting with any handicap's mark, and an outcome that matches the
+// board.
+func validateState(game *models.GameState) error {
+	if game == nil {
+		return errors.New("missing game")
+	}
+	if game.ID == "" {
+		return errors.New("missing id")
+	}
+	if game.CurrentTurn != models.PlayerX && game.CurrentTurn != models.PlayerO {
+		return fmt.Errorf("invalid current turn %q", game.CurrentTurn)
+	}
+	if game.Slug != "" {
+		if err := checkSlug(game.Slug); err != nil {
+			return err
+		}
+	}
+
+	if err := validateSettings(game.GameSettings); err != nil {
+		return err
+	}
+	if game.StartPosition != "" {
+		board, _ := models.ParseBoard(game.StartPosition)
+		setup := setupRecords(board, time.Time{})
+		if len(game.History) < len(setup) {
+			return errors.New("history does not start with the start position")
+		}
+		for i, rec := range setup {
+			if game.History[i].Position != rec.Position || game.History[i].Player != rec.Player {
+				return errors.New("history does not start with the start position")
+			}
+		}
+	} else if placed(game) > 0 && (len(game.History) == 0 || game.History[0].Position != game.Handicap.Position) {
+		return errors.New("history does not start with the handicap mark")
+	}
+
+	var replay models.Board
+	for i, rec := range game.History {
+		if rec.Player != models.PlayerX && rec.Player != models.PlayerO {
+			return fmt.Errorf("move %d: %w", i, ErrInvalidPlayer)
+		}
+		if rec.Position < 0 || rec.Position >= len(replay) {
+			return fmt.Errorf("move %d: %w", i, ErrInvalidMove)
+		}
+		if replay[rec.Position] != models.Empty {
+			return fmt.Errorf("move %d: %w", i, ErrPositionTaken)
+		}
+		if rec.Setup != (i < placed(game) && game.StartPosition != "") {
+			return fmt.Errorf("move %d: setup mark out of place", i)
+		}
+		if !rec.Setup && rec.Player != turnAfter(game.GameSettings, i) {
+			return fmt.Errorf("move %d: %w", i, ErrNotYourTurn)
+		}
+		replay[rec.Position] = rec.Player
+	}
+	if replay != game.Board {
+		return errors.New("history does not match board")
+	}
+
+	winner := checkWinner(game.Board)
+	if game.WinClaimed {
+		// The claimant won without a line, see Service.ClaimWin
+		if winner != models.Empty || (game.Winner != models.PlayerX && game.Winner != models.PlayerO) {
+			return fmt.Errorf("invalid claimed win by %q", game.Winner)
+		}
+		winner = game.Winner
+	}
+	if winner != game.Winner {
+		return fmt.Errorf("winner %q does not match board", game.Winner)
+	}
+	if game.DrawReason != "" && (game.DrawReason != models.DrawDeadPosition || !game.EarlyDraw || !Dead(game.Board, game.CurrentTurn)) {
+		return fmt.Errorf("draw reason %q does not match board", game.DrawReason)
+	}
+	if game.IsDraw != (winner == models.Empty && (isBoardFull(game.Board) || game.DrawAgreed || game.DrawReason != "")) {
+		return errors.New("draw flag does not match board")
+	}
+	if game.DrawOffer != models.Empty && (game.IsOver || game.DrawOffer != game.CurrentTurn) {
+		return fmt.Errorf("invalid draw offer by %q", game.DrawOffer)
+	}
+	if !game.ReadyCheck && (game.PlayerXReady || game.PlayerOReady || !game.ReadyBy.IsZero() || !game.StartsAt.IsZero()) {
+		return errors.New("ready flags without a ready check")
+	}
+	if game.ReadyCheck && Started(game) && (!game.PlayerXReady || !game.PlayerOReady || !game.StartsAt.IsZero()) {
+		return errors.New("moves made before both players were ready")
+	}
+	if game.IsOver != (winner != models.Empty || game.IsDraw) {
+		return errors.New("game over flag does not match board")
+	}
+	if !game.IsOver && game.CurrentTurn != turnAfter(game.GameSettings, len(game.History)) {
+		return errors.New("current turn does not follow history")
+	}
+	return nil
+}
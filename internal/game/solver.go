@@ -0,0 +1,134 @@
+package game
+
+import (
+	"sync"
+	"tiktaktoes/internal/models"
+)
+
+// Evaluation is the result of a position under perfect play
+type Evaluation struct {
+	// Winner is the player who wins with perfect play, or Empty for a draw.
+	Winner models.Player
+	// Plies is how many more moves are played before the game ends.
+	Plies int
+}
+
+type solverKey struct {
+	board models.Board
+	turn  models.Player
+}
+
+// solved memoizes evaluated positions. solvedMu guards the map alone.
+var (
+	solvedMu sync.RWMutex
+	solved   = map[solverKey]Evaluation{}
+)
+
+// Evaluate solves the position with turn to move using minimax. The
+// winning side prefers the quickest win and the losing side the longest
+// loss.
+func Evaluate(board models.Board, turn models.Player) Evaluation {
+	return evaluate(board, turn)
+}
+
+// Analyze evaluates the game's position for display. A finished game
+// is decided with nothing left to play.
+func Analyze(game *models.GameState) models.Evaluation {
+	if game.IsOver {
+		if game.Winner == models.Empty {
+			return models.Evaluation{Advantage: models.AdvantageEven}
+		}
+		return models.Evaluation{Advantage: string(game.Winner)}
+	}
+	e := Evaluate(game.Board, game.CurrentTurn)
+	if e.Winner == models.Empty {
+		return models.Evaluation{Advantage: models.AdvantageEven}
+	}
+	// Plies counts both sides' moves; the winner makes the last one
+	mateIn := (e.Plies + 1) / 2
+	return models.Evaluation{Advantage: string(e.Winner), MateIn: mateIn}
+}
+
+// WinningMoves returns every move that wins by force for turn, in board
+// order. It is empty when the position is drawn or lost.
+func WinningMoves(board models.Board, turn models.Player) []int {
+	if checkWinner(board) != models.Empty {
+		return nil
+	}
+	var moves []int
+	for i, cell := range board {
+		if cell != models.Empty {
+			continue
+		}
+		board[i] = turn
+		if evaluate(board, opponent(turn)).Winner == turn {
+			moves = append(moves, i)
+		}
+		board[i] = models.Empty
+	}
+	return moves
+}
+
+func evaluate(board models.Board, turn models.Player) Evaluation {
+	if winner := checkWinner(board); winner != models.Empty {
+		return Evaluation{Winner: winner}
+	}
+	if isBoardFull(board) {
+		return Evaluation{Winner: models.Empty}
+	}
+	key := solverKey{board, turn}
+	solvedMu.RLock()
+	e, ok := solved[key]
+	solvedMu.RUnlock()
+	if ok {
+		return e
+	}
+
+	var best Evaluation
+	first := true
+	for i, cell := range board {
+		if cell != models.Empty {
+			continue
+		}
+		board[i] = turn
+		e := evaluate(board, opponent(turn))
+		board[i] = models.Empty
+		e.Plies++
+		if first || better(e, best, turn) {
+			best = e
+			first = false
+		}
+	}
+	solvedMu.Lock()
+	solved[key] = best
+	solvedMu.Unlock()
+	return best
+}
+
+// better reports whether a is a better outcome than b for player.
+func better(a, b Evaluation, player models.Player) bool {
+	rank := func(e Evaluation) int {
+		switch e.Winner {
+		case player:
+			return 2
+		case models.Empty:
+			return 1
+		}
+		return 0
+	}
+	if rank(a) != rank(b) {
+		return rank(a) > rank(b)
+	}
+	if a.Winner == player {
+		return a.Plies < b.Plies
+	}
+	return a.Plies > b.Plies
+}
+
+// opponent returns the other player
+func opponent(p models.Player) models.Player {
+	if p == models.PlayerX {
+		return models.PlayerO
+	}
+	return models.PlayerX
+}
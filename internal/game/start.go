@@ -0,0 +1,89 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// checkStart checks the start position of settings, if any, could have
+// been reached by playing, with StartTurn to move.
+func checkStart(settings models.GameSettings) error {
+	if settings.StartPosition == "" {
+		if settings.StartTurn != models.Empty {
+			return fmt.Errorf("%w: a start turn needs a start position", ErrInvalidStart)
+		}
+		return nil
+	}
+	if settings.Handicap != nil {
+		return fmt.Errorf("%w: a game can't have both a start position and a handicap", ErrInvalidStart)
+	}
+	board, err := models.ParseBoard(settings.StartPosition)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidStart, err)
+	}
+	x, o := count(board, models.PlayerX), count(board, models.PlayerO)
+	switch {
+	case x > o+1 || o > x+1:
+		return fmt.Errorf("%w: X has %d marks and O %d, more than one apart", ErrInvalidStart, x, o)
+	case checkWinner(board) != models.Empty:
+		return fmt.Errorf("%w: %s already has a line", ErrInvalidStart, checkWinner(board))
+	case isBoardFull(board):
+		return fmt.Errorf("%w: the board is full", ErrInvalidStart)
+	}
+	turn := startTurn(settings, board)
+	switch {
+	case turn != models.PlayerX && turn != models.PlayerO:
+		return fmt.Errorf("%w: %w", ErrInvalidStart, ErrInvalidPlayer)
+	case (turn == models.PlayerX && x > o) || (turn == models.PlayerO && o > x):
+		return fmt.Errorf("%w: %s is ahead, so it's %s's turn", ErrInvalidStart, turn, opponent(turn))
+	case settings.EarlyDraw && Dead(board, turn):
+		return fmt.Errorf("%w: neither side can win from it", ErrInvalidStart)
+	}
+	return nil
+}
+
+// startTurn returns who moves first from board, the start position of
+// settings: StartTurn if set, otherwise whoever is behind, X when
+// neither is.
+func startTurn(settings models.GameSettings, board models.Board) models.Player {
+	switch {
+	case settings.StartTurn != models.Empty:
+		return settings.StartTurn
+	case count(board, models.PlayerX) > count(board, models.PlayerO):
+		return models.PlayerO
+	}
+	return models.PlayerX
+}
+
+// setupRecords returns the history a game starts with from board, its
+// start position: one Setup record for each mark, in cell order.
+func setupRecords(board models.Board, now time.Time) []models.MoveRecord {
+	var records []models.MoveRecord
+	for i, cell := range board {
+		if cell == models.Empty {
+			continue
+		}
+		records = append(records, models.MoveRecord{
+			Position: i,
+			Cell:     models.CellName(i, models.BoardSize),
+			Row:      i / models.BoardSize,
+			Col:      i % models.BoardSize,
+			Player:   cell,
+			At:       now,
+			Setup:    true,
+		})
+	}
+	return records
+}
+
+func count(board models.Board, p models.Player) int {
+	n := 0
+	for _, cell := range board {
+		if cell == p {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// fromPosition creates a hot-seat game on s from the start position in
+// settings.
+func fromPosition(s *game.Service, settings models.GameSettings) (*models.GameState, error) {
+	settings.Mode = models.ModeHotseat
+	return s.CreateGame(context.Background(), models.PlayerX, game.CreateOptions{GameSettings: settings})
+}
+
+func TestStartPositionValidation(t *testing.T) {
+	s := game.NewService()
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		settings models.GameSettings
+		wantErr  error
+		turn     models.Player
+	}{
+		{"X ahead, O to move", models.GameSettings{StartPosition: "X../.O./..X"}, nil, models.PlayerO},
+		{"even, X to move", models.GameSettings{StartPosition: "XO./.../..."}, nil, models.PlayerX},
+		{"even, O told to move", models.GameSettings{StartPosition: "XO./.../...", StartTurn: models.PlayerO}, nil, models.PlayerO},
+		{"O ahead", models.GameSettings{StartPosition: "O../.../..."}, nil, models.PlayerX},
+		{"separators left out, lower case", models.GameSettings{StartPosition: "x...o...."}, nil, models.PlayerX},
+		{"empty board with a turn", models.GameSettings{StartPosition: ".........", StartTurn: models.PlayerO}, nil, models.PlayerO},
+		{"too many X's", models.GameSettings{StartPosition: "XX./.X./..O"}, game.ErrInvalidStart, ""},
+		{"too many O's", models.GameSettings{StartPosition: "OO./O../..."}, game.ErrInvalidStart, ""},
+		{"X has a line", models.GameSettings{StartPosition: "XXX/OO./..."}, game.ErrInvalidStart, ""},
+		{"both have lines", models.GameSettings{StartPosition: "XXX/OOO/..."}, game.ErrInvalidStart, ""},
+		{"full board", models.GameSettings{StartPosition: "XOX/XOO/OXX"}, game.ErrInvalidStart, ""},
+		{"the side ahead to move", models.GameSettings{StartPosition: "X../.../...", StartTurn: models.PlayerX}, game.ErrInvalidStart, ""},
+		{"no such side", models.GameSettings{StartPosition: "X../.../...", StartTurn: "Z"}, game.ErrInvalidStart, ""},
+		{"not a board", models.GameSettings{StartPosition: "XO"}, game.ErrInvalidStart, ""},
+		{"unknown mark", models.GameSettings{StartPosition: "XQ./.../..."}, game.ErrInvalidStart, ""},
+		{"turn without a position", models.GameSettings{StartTurn: models.PlayerO}, game.ErrInvalidStart, ""},
+		{"with a handicap", models.GameSettings{StartPosition: "X../.../...", Handicap: &models.Handicap{Style: models.HandicapDoubleMove, Player: models.PlayerO}}, game.ErrInvalidStart, ""},
+		// The last cell gives X no line
+		{"dead with early draw", models.GameSettings{StartPosition: "XOX/XOO/OX.", EarlyDraw: true}, game.ErrInvalidStart, ""},
+		{"dead without early draw", models.GameSettings{StartPosition: "XOX/XOO/OX."}, nil, models.PlayerX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := fromPosition(s, tt.settings)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if g.CurrentTurn != tt.turn || g.StartTurn != tt.turn {
+				t.Errorf("%s to move, start turn %s, want %s", g.CurrentTurn, g.StartTurn, tt.turn)
+			}
+		})
+	}
+}
+
+// TestStartPositionPlay plays on from a start position, and checks its
+// marks open the history as setup, don't count as a start and come back
+// on a reset.
+func TestStartPositionPlay(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g, err := fromPosition(s, models.GameSettings{StartPosition: "x.o/.x./o.."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := g.Board
+
+	if g.StartPosition != "X.O/.X./O.." || len(g.History) != 4 || game.Started(g) || g.LastMove != nil {
+		t.Fatalf("created with start position %q, history %+v, started %v", g.StartPosition, g.History, game.Started(g))
+	}
+	for i, want := range []int{0, 2, 4, 6} {
+		if r := g.History[i]; !r.Setup || r.Position != want || r.Player != start[want] {
+			t.Errorf("history %d: %+v, want a setup mark at %d", i, r, want)
+		}
+	}
+	if len(g.LegalMoves) != 5 {
+		t.Errorf("legal moves %v, want the 5 empty cells", g.LegalMoves)
+	}
+
+	if _, err := s.MakeMove(ctx, g.ID, models.Move{Player: models.PlayerX, Position: 2}); !errors.Is(err, game.ErrPositionTaken) {
+		t.Errorf("X on a setup mark: %v", err)
+	}
+	if g, err = s.MakeMove(ctx, g.ID, models.Move{Player: models.PlayerX, Position: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if !g.IsOver || g.Winner != models.PlayerX || !game.Started(g) || g.History[4].Setup {
+		t.Errorf("after X completes the diagonal: over %v, winner %q, history %+v", g.IsOver, g.Winner, g.History)
+	}
+
+	if g, err = s.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if g.Board != start || len(g.History) != 4 || g.CurrentTurn != models.PlayerX || g.IsOver {
+		t.Errorf("after a reset: board %v, %d moves, %s to move, over %v; want the start position back", g.Board, len(g.History), g.CurrentTurn, g.IsOver)
+	}
+}
@@ -0,0 +1,82 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Statuses of a game, as counted by Stats.
+const (
+	// StatusWaiting is a game nobody has moved in yet, with a side
+	// still to be joined.
+	StatusWaiting = "waiting"
+	// StatusPlaying is a game under way.
+	StatusPlaying = "playing"
+	// StatusFinished is a game that is over.
+	StatusFinished = "finished"
+)
+
+// Status returns the game's status, one of StatusWaiting, StatusPlaying
+// and StatusFinished.
+func Status(game *models.GameState) string {
+	switch {
+	case game.IsOver:
+		return StatusFinished
+	case unstarted(game):
+		return StatusWaiting
+	}
+	return StatusPlaying
+}
+
+// Stats is a point-in-time view of the service.
+type Stats struct {
+	// Games counts the stored games by Status.
+	Games map[string]int `json:"games"`
+	// Expiry describes making room for new games, see WithMaxGames.
+	Expiry ExpiryStats `json:"expiry"`
+	// Storage says whether the repository could list the games.
+	Storage StorageStats `json:"storage"`
+}
+
+// ExpiryStats describes the expiry of unstarted games to make room for
+// new ones. LastRun is zero until a game is created under WithMaxGames.
+type ExpiryStats struct {
+	LastRun time.Time `json:"lastRun,omitzero"`
+	// Expired counts the games expired since the service started.
+	Expired uint64 `json:"expired"`
+}
+
+// StorageStats describes the repository's health. Latency is how long
+// listing the games took.
+type StorageStats struct {
+	OK      bool          `json:"ok"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latencyNs"`
+}
+
+// Stats counts the games by status and reports how the repository and
+// expiry are doing. Games are left uncounted if the repository fails,
+// which Storage tells.
+func (s *Service) Stats(ctx context.Context) Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := Stats{
+		Games:  map[string]int{StatusWaiting: 0, StatusPlaying: 0, StatusFinished: 0},
+		Expiry: s.expiry,
+	}
+	start := time.Now()
+	all, err := s.games.List(ctx)
+	stats.Storage.Latency = time.Since(start)
+	if err != nil {
+		stats.Storage.Error = err.Error()
+		return stats
+	}
+	stats.Storage.OK = true
+	for _, game := range all {
+		stats.Games[Status(game)]++
+	}
+	return stats
+}
@@ -0,0 +1,60 @@
+package game
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSymbolBytes bounds the encoded size of a display symbol. Long enough
+// for emoji ZWJ sequences, short enough to keep payloads small.
+const maxSymbolBytes = 32
+
+// validateSymbol checks that a display symbol is a single printable
+// grapheme. The empty string means "use the default".
+func validateSymbol(sym string) error {
+	if sym == "" {
+		return nil
+	}
+	if len(sym) > maxSymbolBytes || !utf8.ValidString(sym) {
+		return ErrInvalidSymbol
+	}
+
+	runes := []rune(sym)
+	if !isSymbolBase(runes[0]) {
+		return ErrInvalidSymbol
+	}
+	if len(runes) == 2 && isRegionalIndicator(runes[0]) && isRegionalIndicator(runes[1]) {
+		return nil
+	}
+
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.Is(unicode.M, r):
+		case r == 0xFE0E || r == 0xFE0F: // text/emoji variation selectors
+		case r >= 0x1F3FB && r <= 0x1F3FF: // skin tone modifiers
+		case r >= 0xE0020 && r <= 0xE007F: // tag sequences for subdivision flags
+		case r == 0x200D: // zero width joiner must join another base
+			if i+1 >= len(runes) || !isSymbolBase(runes[i+1]) {
+				return ErrInvalidSymbol
+			}
+			i++
+		default:
+			return ErrInvalidSymbol
+		}
+	}
+	return nil
+}
+
+// isSymbolBase reports whether r can start a grapheme: visible, not
+// whitespace, not a mark, and not a control or formatting character.
+func isSymbolBase(r rune) bool {
+	return unicode.IsGraphic(r) &&
+		!unicode.IsSpace(r) &&
+		!unicode.Is(unicode.M, r) &&
+		!unicode.Is(unicode.Cf, r)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
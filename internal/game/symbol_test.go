@@ -0,0 +1,92 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+func TestSymbols(t *testing.T) {
+	tests := []struct {
+		name   string
+		symbol string
+		ok     bool
+	}{
+		{"a letter", "Z", true},
+		{"an emoji", "🐱", true},
+		{"a combining accent", "e\u0301", true},
+		{"a skin tone", "👍🏽", true},
+		{"a joined family", "👨\u200d👩\u200d👧", true},
+		{"an emoji presentation", "☺\ufe0f", true},
+		{"a flag", "🇫🇷", true},
+		{"a markup character", "<", true},
+
+		{"two letters", "ab", false},
+		{"two emoji", "🐱🐶", false},
+		{"markup", "<b>", false},
+		{"a script tag", "<script>", false},
+		{"a space", " ", false},
+		{"a lone accent", "\u0301", false},
+		{"a zero-width space", "\u200b", false},
+		{"a dangling joiner", "👨\u200d", false},
+		{"a control character", "\x00", false},
+		{"invalid UTF-8", "\xff", false},
+		{"too long", "e" + strings.Repeat("\u0301", 20), false},
+	}
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	for _, tt := range tests {
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{XSymbol: tt.symbol})
+		if tt.ok && (err != nil || g.Symbol(models.PlayerX) != tt.symbol) {
+			t.Errorf("%s %q: %v", tt.name, tt.symbol, err)
+		}
+		if !tt.ok && !errors.Is(err, game.ErrInvalidSymbol) {
+			t.Errorf("%s %q: err = %v, want ErrInvalidSymbol", tt.name, tt.symbol, err)
+		}
+		if tt.ok {
+			// The same goes for a symbol picked when joining
+			if _, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{Symbol: "🐶"}); err != nil {
+				t.Errorf("%s: joining as 🐶: %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestSymbolTaken(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+
+	for _, opts := range []game.CreateOptions{
+		{XSymbol: "🐱", OSymbol: "🐱"},
+		{XSymbol: "O"},
+		{OSymbol: "X"},
+	} {
+		if _, err := s.CreateGame(ctx, models.PlayerX, opts); !errors.Is(err, game.ErrSymbolTaken) {
+			t.Errorf("creating with %q and %q: err = %v, want ErrSymbolTaken", opts.XSymbol, opts.OSymbol, err)
+		}
+	}
+
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{XSymbol: "🐱"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{Symbol: "🐱"}); !errors.Is(err, game.ErrSymbolTaken) {
+		t.Errorf("joining with X's symbol: err = %v, want ErrSymbolTaken", err)
+	}
+	if _, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{Symbol: "<b>"}); !errors.Is(err, game.ErrInvalidSymbol) {
+		t.Errorf("joining with markup: err = %v, want ErrInvalidSymbol", err)
+	}
+	joined, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{Symbol: "🐶"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined.Symbol(models.PlayerX) != "🐱" || joined.Symbol(models.PlayerO) != "🐶" {
+		t.Errorf("playing %s against %s", joined.Symbol(models.PlayerX), joined.Symbol(models.PlayerO))
+	}
+}
@@ -0,0 +1,56 @@
+package game
+
+import (
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// turnStarted returns when the turn of the player about to move in game
+// started: with the previous move, or for the game's first, once the
+// countdown of a ready check ended if it had one, and otherwise at the
+// game's last change, such as the second player joining.
+func turnStarted(game *models.GameState) time.Time {
+	if Started(game) {
+		return game.History[len(game.History)-1].At
+	}
+	if !game.StartsAt.IsZero() {
+		return game.StartsAt
+	}
+	return game.UpdatedAt
+}
+
+// thinkTime returns how long the player moving in game at now took over
+// it, see models.MoveRecord.Think.
+func thinkTime(game *models.GameState, now time.Time) time.Duration {
+	return max(now.Sub(turnStarted(game)), 0)
+}
+
+// Timing sums up how long each side of a finished game took over its
+// moves, X first, leaving out a side that made none. It is nil for a
+// game that isn't over.
+func Timing(game *models.GameState) []models.Timing {
+	if !game.IsOver {
+		return nil
+	}
+	var timings []models.Timing
+	for _, player := range []models.Player{models.PlayerX, models.PlayerO} {
+		t := models.Timing{Player: player}
+		for i, m := range game.History[placed(game):] {
+			if m.Player != player {
+				continue
+			}
+			t.Moves++
+			t.Total += m.Think
+			if t.LongestMove == 0 || m.Think > t.Longest {
+				t.Longest = m.Think
+				t.LongestMove = i + 1
+			}
+		}
+		if t.Moves > 0 {
+			t.Average = t.Total / time.Duration(t.Moves)
+			timings = append(timings, t)
+		}
+	}
+	return timings
+}
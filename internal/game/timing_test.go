@@ -0,0 +1,118 @@
+package game_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// TestTiming plays a game on a fake clock, and checks each move's think
+// time and the sums once it is over, including after a replay as from a
+// snapshot or the journal.
+func TestTiming(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock))
+	defer s.Close()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	if _, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// X wins on the top row, O taking longest over the fourth move
+	thinks := []time.Duration{2 * time.Second, 5 * time.Second, 1500 * time.Millisecond, 21 * time.Second, 3 * time.Second}
+	for i, m := range []models.Move{{Player: models.PlayerX, Position: 0}, {Player: models.PlayerO, Position: 3}, {Player: models.PlayerX, Position: 1}, {Player: models.PlayerO, Position: 4}, {Player: models.PlayerX, Position: 2}} {
+		clock.Advance(thinks[i])
+		if g, err = s.MakeMove(ctx, g.ID, m); err != nil {
+			t.Fatal(err)
+		}
+		if i < len(thinks)-1 && g.Timing != nil {
+			t.Errorf("move %d: timing %+v before the game is over", i, g.Timing)
+		}
+	}
+	for i, m := range g.History {
+		if m.Think != thinks[i] {
+			t.Errorf("move %d: think %v, want %v", i, m.Think, thinks[i])
+		}
+	}
+	want := []models.Timing{
+		{Player: models.PlayerX, Moves: 3, Total: 6500 * time.Millisecond, Average: 6500 * time.Millisecond / 3, Longest: 3 * time.Second, LongestMove: 5},
+		{Player: models.PlayerO, Moves: 2, Total: 26 * time.Second, Average: 13 * time.Second, Longest: 21 * time.Second, LongestMove: 4},
+	}
+	if !slices.Equal(g.Timing, want) {
+		t.Errorf("timing\n%+v, want\n%+v", g.Timing, want)
+	}
+
+	restored := game.NewService()
+	defer restored.Close()
+	saved := g.Clone()
+	saved.Timing = nil
+	if err := restored.Replay(ctx, game.EventMoved, saved); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := restored.GetGame(ctx, g.ID); !slices.Equal(got.Timing, want) {
+		t.Errorf("timing after a replay %+v", got.Timing)
+	}
+}
+
+// TestTimingFirstMove checks the first move's turn starts when the
+// countdown of a ready check ends.
+func TestTimingFirstMove(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock), game.WithCountdown(3*time.Second))
+	defer s.Close()
+	g := readyGame(t, s)
+	for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+		if _, err := s.Ready(ctx, g.ID, p, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(3 * time.Second)
+	if _, _, err := s.AdvanceReady(ctx, g.ID); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(4 * time.Second)
+	g, err := s.MakeMove(ctx, g.ID, models.Move{Player: models.PlayerX, Position: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if think := g.History[0].Think; think != 4*time.Second {
+		t.Errorf("first move think %v, want the 4s since the countdown ended", think)
+	}
+}
+
+// TestTimingLeavesOutSetup checks the marks of a start position aren't
+// counted as moves or numbered.
+func TestTimingLeavesOutSetup(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock))
+	defer s.Close()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat, StartPosition: "XO./.../..."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range []models.Move{{Player: models.PlayerX, Position: 4}, {Player: models.PlayerO, Position: 3}, {Player: models.PlayerX, Position: 8}} {
+		clock.Advance(time.Duration(i+1) * time.Second)
+		if g, err = s.MakeMove(ctx, g.ID, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []models.Timing{
+		{Player: models.PlayerX, Moves: 2, Total: 4 * time.Second, Average: 2 * time.Second, Longest: 3 * time.Second, LongestMove: 3},
+		{Player: models.PlayerO, Moves: 1, Total: 2 * time.Second, Average: 2 * time.Second, Longest: 2 * time.Second, LongestMove: 2},
+	}
+	if !slices.Equal(g.Timing, want) {
+		t.Errorf("timing\n%+v, want\n%+v", g.Timing, want)
+	}
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// DefaultUndoWindow is how long a reset or cancellation can be undone,
+// unless set with WithUndoWindow.
+const DefaultUndoWindow = time.Minute
+
+// WithUndoWindow sets how long a reset or cancellation can be undone, see
+// UndoReset.
+func WithUndoWindow(d time.Duration) Option {
+	return func(s *Service) {
+		s.undoWindow = d
+	}
+}
+
+// heldGame is the state a game had before it was reset or cancelled,
+// kept until it can no longer be undone.
+type heldGame struct {
+	game    *models.GameState
+	owner   string
+	deleted bool
+	// by is the player who reset or cancelled the game, the only one
+	// who may undo it
+	by    models.Player
+	until time.Time
+}
+
+// UndoWindow returns how long a reset or cancellation can be undone.
+func (s *Service) UndoWindow() time.Duration {
+	if s.undoWindow <= 0 {
+		return DefaultUndoWindow
+	}
+	return s.undoWindow
+}
+
+// hold keeps the state a game had before by reset it, or cancelled it if
+// deleted is set, for UndoReset. Must be called with the lock held.
+func (s *Service) hold(game *models.GameState, deleted bool, by models.Player) {
+	now := s.clock.Now()
+	s.pruneHeld(now)
+	if by != models.PlayerX && by != models.PlayerO {
+		return
+	}
+	if s.held == nil {
+		s.held = make(map[string]heldGame)
+	}
+	s.held[game.ID] = heldGame{game: game, owner: s.owners[game.ID], deleted: deleted, by: by, until: now.Add(s.UndoWindow())}
+}
+
+// PruneUndo drops the resets and cancellations that can no longer be
+// undone, which are otherwise only dropped when the next one is held.
+// The server calls it every UndoWindow.
+func (s *Service) PruneUndo() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneHeld(s.clock.Now())
+}
+
+// pruneHeld drops the held games that can no longer be undone. Must be
+// called with the lock held.
+func (s *Service) pruneHeld(now time.Time) {
+	for id, h := range s.held {
+		if !now.Before(h.until) {
+			delete(s.held, id)
+		}
+	}
+}
+
+// UndoableUntil returns until when player can undo the last reset or
+// cancellation of the game, and false if there is none they can undo.
+func (s *Service) UndoableUntil(ctx context.Context, gameID string, player models.Player) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, gameID, err := s.lookupHeld(ctx, gameID)
+	if err != nil {
+		return time.Time{}, false
+	}
+	s.pruneHeld(s.clock.Now())
+	h, ok := s.held[gameID]
+	if !ok || h.by != player {
+		return time.Time{}, false
+	}
+	return h.until, true
+}
+
+// lookupHeld looks up a game that may have been cancelled, returning it,
+// or nil if it doesn't exist, along with its full ID. Must be called with
+// the lock held.
+func (s *Service) lookupHeld(ctx context.Context, gameID string) (*models.GameState, string, error) {
+	current, err := s.lookup(ctx, gameID)
+	switch {
+	case err == nil:
+		return current, current.ID, nil
+	case errors.Is(err, ErrGameNotFound):
+		return nil, NormalizeID(gameID), nil
+	}
+	return nil, "", err
+}
+
+// UndoReset puts a game that player reset or cancelled in the last
+// WithUndoWindow back as it was.
+func (s *Service) UndoReset(ctx context.Context, gameID string, player models.Player) (*models.GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, gameID, err := s.lookupHeld(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	s.pruneHeld(now)
+	h, ok := s.held[gameID]
+	switch {
+	case !ok:
+		return nil, ErrNothingToUndo
+	case h.by != player:
+		return nil, ErrNotResetter
+	case h.deleted && current != nil:
+		return nil, ErrUndoStale
+	case !h.deleted && current == nil:
+		return nil, ErrGameNotFound
+	case !h.deleted && (Started(current) || current.IsOver):
+		return nil, ErrUndoStale
+	}
+
+	game := h.game.Clone()
+	if err := s.keepSlug(ctx, game); err != nil {
+		return nil, err
+	}
+	game.UpdatedAt = now
+	if err := s.commit(ctx, EventUndone, game); err != nil {
+		return nil, err
+	}
+	delete(s.held, gameID)
+	if h.owner != "" {
+		if s.owners == nil {
+			s.owners = make(map[string]string)
+		}
+		s.owners[gameID] = h.owner
+	}
+	s.audit(ctx, EventUndone, game.ID, player)
+	s.hooks.emit(hookRestored, game)
+	return game, nil
+}
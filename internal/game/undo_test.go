@@ -0,0 +1,140 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+// startedGame returns a hot-seat game with X in the centre.
+func startedGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	ctx := context.Background()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, err = s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestOnlyTheResetterCanUndo(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := startedGame(t, s)
+	if _, err := s.ResetGame(ctx, g.ID, models.PlayerO); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, player := range []models.Player{models.PlayerX, models.Empty} {
+		if _, ok := s.UndoableUntil(ctx, g.ID, player); ok {
+			t.Errorf("%q is offered to undo O's reset", player)
+		}
+		if _, err := s.UndoReset(ctx, g.ID, player); !errors.Is(err, game.ErrNotResetter) {
+			t.Errorf("%q undoing O's reset: err = %v, want ErrNotResetter", player, err)
+		}
+	}
+	if _, ok := s.UndoableUntil(ctx, g.ID, models.PlayerO); !ok {
+		t.Error("O isn't offered to undo their reset")
+	}
+	undone, err := s.UndoReset(ctx, g.ID, models.PlayerO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone.Board[4] != models.PlayerX {
+		t.Error("undoing didn't put the board back")
+	}
+}
+
+func TestOnlyTheCancellerCanUndo(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CancelGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UndoReset(ctx, g.ID, models.PlayerO); !errors.Is(err, game.ErrNotResetter) {
+		t.Errorf("O undoing X's cancellation: err = %v, want ErrNotResetter", err)
+	}
+	if _, err := s.UndoReset(ctx, g.ID, models.PlayerX); err != nil {
+		t.Errorf("X undoing their cancellation: %v", err)
+	}
+}
+
+func TestResetsByNobodyAreNotHeld(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+	g := startedGame(t, s)
+	if _, err := s.ResetGame(ctx, g.ID, models.Empty); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Held(); n != 0 {
+		t.Errorf("%d held, want none", n)
+	}
+	if _, err := s.UndoReset(ctx, g.ID, models.Empty); !errors.Is(err, game.ErrNothingToUndo) {
+		t.Errorf("err = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestHardResetLeavesNothingToUndo(t *testing.T) {
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+
+	// A hard reset can't be undone itself
+	g := startedGame(t, s)
+	if _, err := s.HardResetGame(ctx, g.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Held(); n != 0 {
+		t.Errorf("%d held after a hard reset, want none", n)
+	}
+
+	// And takes back the chance to undo a player's reset before it,
+	// which would bring back the seats and settings it wiped
+	g = startedGame(t, s)
+	if _, err := s.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.HardResetGame(ctx, g.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UndoReset(ctx, g.ID, models.PlayerX); !errors.Is(err, game.ErrNothingToUndo) {
+		t.Errorf("undoing after a hard reset: err = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestPruneUndo(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	s := game.NewService(game.WithClock(clock), game.WithUndoWindow(time.Minute))
+	defer s.Close()
+	g := startedGame(t, s)
+	if _, err := s.ResetGame(ctx, g.ID, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(59 * time.Second)
+	s.PruneUndo()
+	if n := s.Held(); n != 1 {
+		t.Fatalf("%d held within the window, want 1", n)
+	}
+	clock.Advance(time.Second)
+	s.PruneUndo()
+	if n := s.Held(); n != 0 {
+		t.Errorf("%d held once the window passed, want none", n)
+	}
+}
@@ -0,0 +1,176 @@
+package game_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+)
+
+var start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// joinedGame returns an online game X created and O joined at start.
+func joinedGame(t *testing.T, s *game.Service) *models.GameState {
+	t.Helper()
+	ctx := context.Background()
+	g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, err = s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestVacateSlotWaitsForThePlayerToLeave(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	presence := gametest.NewPresence(clock)
+	s := game.NewService(game.WithClock(clock), game.WithPresence(presence), game.WithVacateAfter(time.Minute))
+	defer s.Close()
+	g := joinedGame(t, s)
+
+	presence.Connect(g.ID, models.PlayerO)
+	clock.Advance(time.Hour)
+	if _, err := s.VacateSlot(ctx, g.ID, models.PlayerO); !errors.Is(err, game.ErrOpponentPresent) {
+		t.Fatalf("vacating a connected player: err = %v, want ErrOpponentPresent", err)
+	}
+
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(59 * time.Second)
+	_, err := s.VacateSlot(ctx, g.ID, models.PlayerO)
+	if !errors.Is(err, game.ErrVacateTooEarly) || game.Code(err) != "vacate_too_early" {
+		t.Fatalf("vacating within the window: err = %v, want ErrVacateTooEarly", err)
+	}
+	if want := "try again in 1s"; err.Error() != game.ErrVacateTooEarly.Error()+": "+want {
+		t.Errorf("err = %q, want it to say %q", err, want)
+	}
+
+	// Coming back and leaving again starts the wait over
+	presence.Connect(g.ID, models.PlayerO)
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(time.Second)
+	if _, err := s.VacateSlot(ctx, g.ID, models.PlayerO); !errors.Is(err, game.ErrVacateTooEarly) {
+		t.Fatalf("vacating a second after coming back: err = %v, want ErrVacateTooEarly", err)
+	}
+
+	clock.Advance(time.Minute)
+	vacated, err := s.VacateSlot(ctx, g.ID, models.PlayerO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vacated.PlayerOJoined || !vacated.PlayerXJoined {
+		t.Errorf("vacated O, left X joined %v and O joined %v", vacated.PlayerXJoined, vacated.PlayerOJoined)
+	}
+	if _, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Errorf("joining the vacated slot: %v", err)
+	}
+}
+
+func TestVacateSlotOfPlayerNeverSeen(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	// Without presence, and for a player presence never saw connect,
+	// the wait runs from the game's last change
+	for _, opts := range [][]game.Option{
+		{game.WithClock(clock)},
+		{game.WithClock(clock), game.WithPresence(gametest.NewPresence(clock))},
+	} {
+		s := game.NewService(opts...)
+		defer s.Close()
+		g := joinedGame(t, s)
+
+		clock.Advance(game.DefaultVacateAfter - time.Second)
+		if _, err := s.VacateSlot(ctx, g.ID, models.PlayerO); !errors.Is(err, game.ErrVacateTooEarly) {
+			t.Errorf("vacating straight after joining: err = %v, want ErrVacateTooEarly", err)
+		}
+		clock.Advance(time.Second)
+		if _, err := s.VacateSlot(ctx, g.ID, models.PlayerO); err != nil {
+			t.Errorf("vacating once the window passed: %v", err)
+		}
+	}
+}
+
+func TestVacateSlotRefusals(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock), game.WithPresence(gametest.NewPresence(clock)))
+	defer s.Close()
+	waiting, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := joinedGame(t, s)
+	if _, err := s.MakeMove(ctx, started.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+
+	tests := []struct {
+		name   string
+		gameID string
+		player models.Player
+		want   error
+	}{
+		{"an empty slot", waiting.ID, models.PlayerO, game.ErrSlotEmpty},
+		{"a spectator", waiting.ID, models.Empty, game.ErrInvalidPlayer},
+		{"a game under way", started.ID, models.PlayerO, game.ErrGameStarted},
+		{"a missing game", "missing", models.PlayerO, game.ErrGameNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.VacateSlot(ctx, tt.gameID, tt.player); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestVacateSlotRaces has players vacating and rejoining the same slot
+// at once. Each vacate or rejoin starts the wait over, so however they
+// interleave the slot is vacated once, and taken again at most once.
+func TestVacateSlotRaces(t *testing.T) {
+	ctx := context.Background()
+	clock := gametest.NewFakeClock(start)
+	s := game.NewService(game.WithClock(clock), game.WithPresence(gametest.NewPresence(clock)))
+	defer s.Close()
+	g := joinedGame(t, s)
+	clock.Advance(time.Hour)
+
+	var vacated, joined atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			_, err := s.VacateSlot(ctx, g.ID, models.PlayerO)
+			switch {
+			case err == nil:
+				vacated.Add(1)
+			case !errors.Is(err, game.ErrVacateTooEarly) && !errors.Is(err, game.ErrSlotEmpty):
+				t.Errorf("vacating: %v", err)
+			}
+		})
+		wg.Go(func() {
+			_, err := s.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{})
+			switch {
+			case err == nil:
+				joined.Add(1)
+			case !errors.Is(err, game.ErrSlotTaken):
+				t.Errorf("joining: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if vacated.Load() != 1 || joined.Load() > 1 {
+		t.Fatalf("vacated %d times and joined %d", vacated.Load(), joined.Load())
+	}
+	got, _ := s.GetGame(ctx, g.ID)
+	if got.PlayerOJoined != (joined.Load() == 1) {
+		t.Errorf("O joined = %v after %d rejoins", got.PlayerOJoined, joined.Load())
+	}
+}
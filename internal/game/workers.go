@@ -0,0 +1,94 @@
+package game
+
+import (
+	"context"
+	"sync"
+)
+
+// workers owns the goroutines working on games, such as the computer's
+// moves, and cancels a game's once it no longer needs them.
+type workers struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	games  map[string]*gameWorkers
+	all    sync.WaitGroup
+	closed bool
+}
+
+// gameWorkers are the running workers of one game.
+type gameWorkers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	n      int
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel, games: make(map[string]*gameWorkers)}
+}
+
+// Go runs fn for the game in a goroutine owned by the service. fn's
+// context is cancelled once the game has moved on, see workers, or the
+// service is closing, and fn should return soon after. Nothing runs once
+// the service is closed.
+func (s *Service) Go(gameID string, fn func(ctx context.Context)) {
+	s.workers.goGame(gameID, fn)
+}
+
+func (w *workers) goGame(gameID string, fn func(ctx context.Context)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	g := w.games[gameID]
+	if g == nil {
+		ctx, cancel := context.WithCancel(w.ctx)
+		g = &gameWorkers{ctx: ctx, cancel: cancel}
+		w.games[gameID] = g
+	}
+	g.n++
+	w.all.Add(1)
+	go func() {
+		defer w.all.Done()
+		defer w.done(gameID, g)
+		fn(g.ctx)
+	}()
+}
+
+// done records that one of g's workers returned, dropping g with its
+// last one.
+func (w *workers) done(gameID string, g *gameWorkers) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if g.n--; g.n > 0 {
+		return
+	}
+	g.cancel()
+	if w.games[gameID] == g {
+		delete(w.games, gameID)
+	}
+}
+
+// stop cancels the game's workers, as when it is over or gone. It
+// doesn't wait for them, so it is safe to call with the service lock
+// held; workers started afterwards get a fresh context.
+func (w *workers) stop(gameID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if g := w.games[gameID]; g != nil {
+		g.cancel()
+		delete(w.games, gameID)
+	}
+}
+
+// close cancels every worker and waits for them to return. Must not be
+// called with the service lock held, which workers may be waiting for.
+func (w *workers) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.cancel()
+	w.mu.Unlock()
+	w.all.Wait()
+}
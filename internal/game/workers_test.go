@@ -0,0 +1,110 @@
+package game_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+
+	"go.uber.org/goleak"
+)
+
+// TestCloseLeavesNoGoroutines plays games against the computer, which
+// thinks for an hour, and hooks and timers of a game's own, and checks
+// nothing is left running once the service is closed.
+func TestCloseLeavesNoGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	s := game.NewService(game.WithThinkTime(time.Hour))
+	s.OnMoveMade(func(models.GameState) {})
+
+	for range 5 {
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeAI}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The computer thinks over its answer
+		if _, err := s.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+			t.Fatal(err)
+		}
+		// And a timer waits for the game, as the ready clock's do
+		s.Go(g.ID, func(ctx context.Context) {
+			timer := time.NewTimer(time.Hour)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	done := make(chan error)
+	go func() { done <- s.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited on its workers")
+	}
+	// Nothing starts once it is closed
+	s.Go("late", func(context.Context) { t.Error("a worker ran after Close") })
+}
+
+// TestWorkersStopWithTheirGame checks a game's workers are cancelled
+// when it is won or reset, and those of other games are not.
+func TestWorkersStopWithTheirGame(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	s := game.NewService()
+	defer s.Close()
+
+	create := func() string {
+		g, err := s.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g.ID
+	}
+	work := func(gameID string) <-chan struct{} {
+		stopped := make(chan struct{})
+		s.Go(gameID, func(ctx context.Context) {
+			<-ctx.Done()
+			close(stopped)
+		})
+		return stopped
+	}
+	wantStopped := func(what string, stopped <-chan struct{}, want bool) {
+		t.Helper()
+		select {
+		case <-stopped:
+			if !want {
+				t.Errorf("%s: worker stopped", what)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if want {
+				t.Errorf("%s: worker still running", what)
+			}
+		}
+	}
+
+	won, reset, other := create(), create(), create()
+	wonWorker, resetWorker, otherWorker := work(won), work(reset), work(other)
+	for i, pos := range []int{0, 3, 1, 4, 2} {
+		if _, err := s.MakeMove(ctx, won, models.Move{Position: pos, Player: []models.Player{models.PlayerX, models.PlayerO}[i%2]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantStopped("won", wonWorker, true)
+	if _, err := s.ResetGame(ctx, reset, models.PlayerX); err != nil {
+		t.Fatal(err)
+	}
+	wantStopped("reset", resetWorker, true)
+	wantStopped("other game", otherWorker, false)
+
+	// A reset game's new workers get a fresh context
+	wantStopped("after the reset", work(reset), false)
+}
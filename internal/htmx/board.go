@@ -0,0 +1,64 @@
+package htmx
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/models"
+
+	"github.com/a-h/templ"
+)
+
+// boardStyle lays the board out as a grid as wide as it is, so cells
+// wrap into rows whatever the board size.
+func boardStyle() templ.SafeCSS {
+	return templ.SafeCSS(fmt.Sprintf("grid-template-columns: repeat(%d, 70px);", models.BoardSize))
+}
+
+// cellAttrs names a cell's coordinate, such as "b2", and its zero-based
+// row and column, matching the forms a move can be given in.
+func cellAttrs(index int) templ.Attributes {
+	return templ.Attributes{
+		"data-cell": models.CellName(index, models.BoardSize),
+		"data-row":  strconv.Itoa(index / models.BoardSize),
+		"data-col":  strconv.Itoa(index % models.BoardSize),
+	}
+}
+
+// onWinningLine reports whether the cell is part of the line that won g.
+func onWinningLine(g *models.GameState, index int) bool {
+	return g.Winner != models.Empty && slices.Contains(game.WinningLine(g.Board), index)
+}
+
+// isLastMove reports whether the cell was taken by the latest move of g,
+// so it can be picked out as the one that just changed.
+func isLastMove(g *models.GameState, index int) bool {
+	return g.LastMove != nil && g.LastMove.Position == index
+}
+
+// timingLine sums up how long the sides of the finished game g took
+// over their moves, such as "X avg 3.2s, O avg 7.8s, longest think: O
+// 21s on move 6".
+func timingLine(ctx context.Context, g *models.GameState) string {
+	parts := make([]string, 0, len(g.Timing)+1)
+	longest := g.Timing[0]
+	for _, t := range g.Timing {
+		parts = append(parts, i18n.T(ctx, "timing.average", g.Symbol(t.Player), seconds(t.Average)))
+		if t.Longest > longest.Longest {
+			longest = t
+		}
+	}
+	parts = append(parts, i18n.T(ctx, "timing.longest", g.Symbol(longest.Player), seconds(longest.Longest), longest.LongestMove))
+	return strings.Join(parts, ", ")
+}
+
+// seconds formats d in seconds to a tenth, such as "3.2s" or "21s".
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Round(100*time.Millisecond).Seconds(), 'f', -1, 64) + "s"
+}
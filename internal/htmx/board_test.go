@@ -0,0 +1,98 @@
+package htmx
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestBoardGolden renders boards and compares them with the golden files
+// in testdata, which go test -update rewrites.
+func TestBoardGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		board  string
+		player string
+	}{
+		{"new", ".../.../...", "X"},
+		{"under_way", "X.O/.X./...", "O"},
+		{"won", "OO./XXX/...", "O"},
+		{"spectated", "XO./.X./..O", ""},
+	}
+	for _, tt := range tests {
+		g := models.NewGameState("abcd1234")
+		g.PlayerXJoined, g.PlayerOJoined = true, true
+		board, err := models.ParseBoard(tt.board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Board = board
+		var x, o int
+		for i, p := range board {
+			switch p {
+			case models.PlayerX:
+				x++
+			case models.PlayerO:
+				o++
+			default:
+				continue
+			}
+			g.History = append(g.History, models.MoveRecord{Player: p, Position: i})
+		}
+		g.CurrentTurn = models.PlayerX
+		if x > o {
+			g.CurrentTurn = models.PlayerO
+		}
+		if line := game.WinningLine(g.Board); line != nil {
+			g.Winner, g.IsOver = g.Board[line[0]], true
+		}
+		g.LegalMoves, g.LastMove = game.LegalMoves(g), game.LastMove(g)
+
+		var out strings.Builder
+		if err := gameBoard(g, tt.player).Render(context.Background(), &out); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join("testdata", "board_"+tt.name+".golden")
+		if *update {
+			if err := os.WriteFile(path, []byte(out.String()), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != string(want) {
+			t.Errorf("%s: rendered\n%s\nwant\n%s", tt.name, out.String(), want)
+		}
+	}
+}
+
+// TestTimingLine checks the summary under a finished board, in English
+// and Spanish.
+func TestTimingLine(t *testing.T) {
+	g := models.NewGameState("abcd1234")
+	g.Timing = []models.Timing{
+		{Player: models.PlayerX, Moves: 3, Total: 9600 * time.Millisecond, Average: 3200 * time.Millisecond, Longest: 5 * time.Second, LongestMove: 3},
+		{Player: models.PlayerO, Moves: 2, Total: 15600 * time.Millisecond, Average: 7800 * time.Millisecond, Longest: 21 * time.Second, LongestMove: 6},
+	}
+	for lang, want := range map[string]string{
+		"en": "X avg 3.2s, O avg 7.8s, longest think: O 21s on move 6",
+		"es": "X media 3.2s, O media 7.8s, pensó más: O 21s en la jugada 6",
+	} {
+		if got := timingLine(i18n.WithLang(context.Background(), lang), g); got != want {
+			t.Errorf("in %s: %q, want %q", lang, got, want)
+		}
+	}
+}
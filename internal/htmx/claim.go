@@ -0,0 +1,59 @@
+package htmx
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+// handleClaimPrompt renders the player's claim prompt afresh, which a
+// pending one asks for once the wait is over.
+func (h *Handler) handleClaimPrompt(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	n := broadcast.ClaimNotice{GameID: gameID, Player: models.Player(player)}
+	if at, err := h.gameService.ClaimableAt(r.Context(), n.GameID, n.Player); err == nil {
+		n.At = at
+	}
+	w.Header().Set("Content-Type", "text/html")
+	ClaimPrompt(n).Render(r.Context(), w)
+}
+
+// handleClaimWin ends the game in the player's favour because their
+// opponent left, see game.Service.ClaimWin.
+func (h *Handler) handleClaimWin(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	err = seat.ErrNoSeat
+	var g *models.GameState
+	if h.heldSeat(r, gameID, player) != models.Empty {
+		g, err = h.gameService.ClaimWin(r.Context(), gameID, models.Player(player))
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionClaim, Player: models.Player(player)}, err)
+	h.renderAction(w, r, gameID, player, g, err)
+}
+
+// secondsUntil returns how many whole seconds remain until at, rounded up.
+func secondsUntil(at time.Time) int {
+	return int(math.Ceil(time.Until(at).Seconds()))
+}
@@ -0,0 +1,62 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestClaimWinNeedsTheSeat(t *testing.T) {
+	clock := gametest.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	presence := gametest.NewPresence(clock)
+	games := game.NewService(game.WithClock(clock), game.WithPresence(presence))
+	defer games.Close()
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	post := func(path, cookie string) (int, string) {
+		r := httptest.NewRequest("POST", path, nil)
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		var body struct{ Code string }
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Code
+	}
+
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.JoinGame(t.Context(), g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.MakeMove(t.Context(), g.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+		t.Fatal(err)
+	}
+	presence.Leave(g.ID, models.PlayerO)
+	clock.Advance(game.DefaultClaimAfter)
+	x := g.ID + "." + h.seats.Token(g.ID, "X")
+	o := g.ID + "." + h.seats.Token(g.ID, "O")
+
+	for _, cookie := range []string{"", g.ID + ".X", o} {
+		if status, code := post("/htmx/claim/"+g.ID+"?player=X", cookie); code != "seat_token" {
+			t.Errorf("claiming as X with cookie %q: %d %s, want seat_token", cookie, status, code)
+		}
+	}
+	if status, _ := post("/htmx/claim/"+g.ID+"?player=X", x); status != http.StatusOK {
+		t.Fatalf("claiming as X: status = %d, want 200", status)
+	}
+	if got, _ := games.GetGame(t.Context(), g.ID); got.Winner != models.PlayerX || !got.WinClaimed {
+		t.Errorf("claimed game won by %q, claimed %v", got.Winner, got.WinClaimed)
+	}
+}
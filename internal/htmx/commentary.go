@@ -0,0 +1,68 @@
+package htmx
+
+import (
+	"context"
+	"net/http"
+
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/errcode"
+	"tiktaktoes/internal/httpx"
+)
+
+// CommentaryHandler serves the commentary written on a game so far. The
+// game page loads it into its commentary panel, which the game's SSE
+// connection then adds each new line to.
+type CommentaryHandler struct {
+	commentary *commentary.Service
+}
+
+// NewCommentaryHandler creates a commentary handler.
+func NewCommentaryHandler(commentary *commentary.Service) *CommentaryHandler {
+	return &CommentaryHandler{commentary: commentary}
+}
+
+// RegisterRoutes sets up the commentary route.
+func (h *CommentaryHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /htmx/commentary/{gameID}", h.handleCommentary)
+}
+
+func (h *CommentaryHandler) handleCommentary(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	log, err := h.commentary.Get(r.Context(), gameID)
+	if err != nil {
+		http.Error(w, err.Error(), errcode.FromError(err).Status)
+		return
+	}
+	CommentaryLines(log.Lines).Render(r.Context(), w)
+}
+
+type commentaryKey struct{}
+
+// withCommentary marks a game being rendered for a player who opted in
+// to its commentary, which players' boards leave out otherwise, so the
+// page has a commentary panel and its SSE connection asks for the
+// commentary.
+func withCommentary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, commentaryKey{}, true)
+}
+
+// showCommentary reports whether the page of a game rendered for player
+// has a commentary panel: spectators' pages always do.
+func showCommentary(ctx context.Context, player string) bool {
+	optedIn, _ := ctx.Value(commentaryKey{}).(bool)
+	return optedIn || !isSeat(player)
+}
+
+// commentaryQuery returns the query parameter a game's SSE connection
+// adds when rendered withCommentary.
+func commentaryQuery(ctx context.Context) string {
+	if optedIn, _ := ctx.Value(commentaryKey{}).(bool); optedIn {
+		return "&commentary=1"
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/logging"
+)
+
+// connHeaders returns the hx-headers a rendered game sends with every
+// request it makes, naming its SSE connection so errors go back to it.
+func connHeaders(connID string) string {
+	b, _ := json.Marshal(map[string]string{broadcast.ConnHeader: connID})
+	return string(b)
+}
+
+// sseConnID returns the connection ID the page asked its SSE connection
+// to go by, or a new one.
+func sseConnID(r *http.Request) string {
+	if id := r.URL.Query().Get("conn"); logging.ValidID(id) {
+		return id
+	}
+	return logging.NewID()
+}
@@ -0,0 +1,62 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestDrawNeedsTheSeat(t *testing.T) {
+	games := game.NewService()
+	defer games.Close()
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	post := func(path, cookie string) (int, string) {
+		r := httptest.NewRequest("POST", path, nil)
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		var body struct{ Code string }
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Code
+	}
+
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.JoinGame(t.Context(), g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.MakeMove(t.Context(), g.ID, models.Move{Position: 4, Player: models.PlayerX}); err != nil {
+		t.Fatal(err)
+	}
+	x := g.ID + "." + h.seats.Token(g.ID, "X")
+	o := g.ID + "." + h.seats.Token(g.ID, "O")
+
+	for _, cookie := range []string{"", g.ID + ".O", x} {
+		if status, code := post("/htmx/draw/"+g.ID+"?player=O", cookie); code != "seat_token" {
+			t.Errorf("offering as O with cookie %q: %d %s, want seat_token", cookie, status, code)
+		}
+	}
+	if status, _ := post("/htmx/draw/"+g.ID+"?player=O", o); status != http.StatusOK {
+		t.Fatalf("offering as O: status = %d, want 200", status)
+	}
+	if status, code := post("/htmx/draw/"+g.ID+"/reply?player=X&accept=true", o); code != "seat_token" {
+		t.Errorf("accepting as X with O's cookie: %d %s, want seat_token", status, code)
+	}
+	if status, _ := post("/htmx/draw/"+g.ID+"/reply?player=X&accept=true", x); status != http.StatusOK {
+		t.Fatalf("accepting as X: status = %d, want 200", status)
+	}
+	if got, _ := games.GetGame(t.Context(), g.ID); !got.DrawAgreed {
+		t.Error("the game wasn't drawn")
+	}
+}
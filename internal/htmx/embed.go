@@ -0,0 +1,62 @@
+package htmx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/models"
+)
+
+// EmbedHandler serves the page other sites frame to show a game live,
+// see EmbedPage. Playing from it is left to the full page.
+type EmbedHandler struct {
+	gameService *game.Service
+	// frameAncestors are the sources allowed to frame the page, as in
+	// a CSP frame-ancestors directive
+	frameAncestors string
+}
+
+// NewEmbedHandler creates a new embed handler. frameAncestors lists the
+// sites allowed to frame the page, as CSP sources such as
+// "https://example.com" or "*" for any; none allows this server alone.
+func NewEmbedHandler(gameService *game.Service, frameAncestors []string) *EmbedHandler {
+	ancestors := strings.Join(frameAncestors, " ")
+	if ancestors == "" {
+		ancestors = "'self'"
+	}
+	return &EmbedHandler{gameService: gameService, frameAncestors: ancestors}
+}
+
+// RegisterRoutes sets up the embed routes.
+func (h *EmbedHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /embed/{gameID}", withBaseURL(h.handleEmbed))
+}
+
+// handleEmbed serves a game's embed page. It is always a spectator's
+// view, whatever side ?player= asks for or the browser has joined.
+func (h *EmbedHandler) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors "+h.frameAncestors)
+	var g *models.GameState
+	gameID, err := httpx.PathGameID(r)
+	if err == nil {
+		g, err = h.gameService.FindGame(r.Context(), gameID)
+	}
+	switch {
+	case errors.Is(err, game.ErrAmbiguousID):
+		httpx.WriteError(w, r, err)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusNotFound)
+		JoinNotFound().Render(r.Context(), w)
+		return
+	}
+	// The page is whoever the game is shared with's, so it is in the
+	// game's language if it has one
+	ctx := i18n.WithLang(r.Context(), g.Locale)
+	EmbedPage(g).Render(ctx, w)
+}
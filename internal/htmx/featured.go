@@ -0,0 +1,47 @@
+package htmx
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/models"
+)
+
+// FeaturedHandler serves the featured games feed on the home page. It
+// keeps itself current over the lobby stream, see handleLobbySSE.
+type FeaturedHandler struct {
+	feed *featured.Feed
+}
+
+// NewFeaturedHandler creates a featured games handler.
+func NewFeaturedHandler(feed *featured.Feed) *FeaturedHandler {
+	return &FeaturedHandler{feed: feed}
+}
+
+// RegisterRoutes sets up the featured games route.
+func (h *FeaturedHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /htmx/featured", h.handleFeatured)
+}
+
+func (h *FeaturedHandler) handleFeatured(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	Featured(h.feed.List()).Render(r.Context(), w)
+}
+
+// featuredName is how a featured game is named to people, like
+// displayID.
+func featuredName(e featured.Entry) string {
+	if e.Slug != "" {
+		return e.Slug
+	}
+	return e.GameID
+}
+
+// featuredWinner returns the mark the winner of a featured game played
+// with.
+func featuredWinner(e featured.Entry) string {
+	if e.Winner == models.PlayerO {
+		return e.Symbols[1]
+	}
+	return e.Symbols[0]
+}
@@ -0,0 +1,50 @@
+package htmx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+// FuzzHTMXMovePath plays O in a hot-seat game through the move route,
+// with anything at all as the position segment, and checks that only
+// the plain number of a free cell ever changes the game.
+func FuzzHTMXMovePath(f *testing.F) {
+	games := game.NewService()
+	f.Cleanup(func() { games.Close() })
+	mux := http.NewServeMux()
+	NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner(nil)).RegisterRoutes(mux)
+
+	f.Fuzz(func(t *testing.T, segment string) {
+		ctx := t.Context()
+		g, err := games.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer games.CancelGame(ctx, g.ID, models.Empty)
+		before, err := games.MakeMove(ctx, g.ID, models.Move{Position: 4, Player: models.PlayerX})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest("POST", "/htmx/move/"+g.ID+"/"+url.PathEscape(segment)+"?player=O", nil)
+		r.Header.Set("HX-Request", "true")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		// A segment that isn't a number's one spelling names no cell
+		move := models.Move{Position: -1, Player: models.PlayerO}
+		if n, err := strconv.Atoi(segment); err == nil && strconv.Itoa(n) == segment {
+			move.Position = n
+		}
+		after, _ := games.GetGame(ctx, g.ID)
+		gametest.WantMoveOutcome(t, before, after, move, after.Version != before.Version)
+	})
+}
@@ -3,152 +3,732 @@ package htmx
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
+	"tiktaktoes/internal/activity"
 	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/clientip"
+	"tiktaktoes/internal/errcode"
 	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/logging"
 	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/sse"
 
 	"github.com/a-h/templ"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var tracer = otel.Tracer("tiktaktoes/internal/htmx")
+
 // Handler handles HTMX requests with SSE for real-time updates.
 type Handler struct {
 	gameService *game.Service
 	hub         *broadcast.Hub
+	activity    activity.Recorder
+	seats       *seat.Signer
 }
 
-// NewHandler creates a new HTMX handler.
-func NewHandler(gameService *game.Service, hub *broadcast.Hub) *Handler {
+// NewHandler creates a new HTMX handler. With a recorder, the actions
+// players take are recorded in the games' activity. The seats cookie
+// is signed by seats.
+func NewHandler(gameService *game.Service, hub *broadcast.Hub, recorder activity.Recorder, seats *seat.Signer) *Handler {
 	return &Handler{
 		gameService: gameService,
 		hub:         hub,
+		activity:    recorder,
+		seats:       seats,
 	}
 }
 
 // RegisterRoutes sets up the HTMX routes.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /htmx/game/new", h.handleNewGame)
-	mux.HandleFunc("/htmx/game", h.handleGetGame)
-	mux.HandleFunc("POST /htmx/move/{gameID}/{position}", h.handleMakeMove)
-	mux.HandleFunc("POST /htmx/reset/{gameID}", h.handleResetGame)
-	mux.HandleFunc("/htmx/sse/{gameID}", h.handleSSE)
+	mux.HandleFunc("POST /htmx/game/new", withBaseURL(h.handleNewGame))
+	mux.HandleFunc("GET /htmx/game/{gameID}", withBaseURL(h.handleViewGame))
+	mux.HandleFunc("POST /htmx/join", withBaseURL(h.handleJoinGame))
+	mux.HandleFunc("POST /htmx/join/{gameID}", withBaseURL(h.handleJoinGame))
+	mux.HandleFunc("POST /htmx/move/{gameID}/{position}", withBaseURL(h.handleMakeMove))
+	mux.HandleFunc("POST /htmx/reset/{gameID}", withBaseURL(h.handleResetGame))
+	mux.HandleFunc("POST /htmx/cancel/{gameID}", h.handleCancelGame)
+	mux.HandleFunc("POST /htmx/vacate/{gameID}", withBaseURL(h.handleVacateSlot))
+	mux.HandleFunc("POST /htmx/draw/{gameID}", withBaseURL(h.handleOfferDraw))
+	mux.HandleFunc("POST /htmx/draw/{gameID}/reply", withBaseURL(h.handleRespondDraw))
+	mux.HandleFunc("POST /htmx/ready/{gameID}", withBaseURL(h.handleReady))
+	mux.HandleFunc("GET /htmx/undo/{gameID}", h.handleUndoNotice)
+	mux.HandleFunc("POST /htmx/undo/{gameID}", withBaseURL(h.handleUndo))
+	mux.HandleFunc("GET /htmx/claim/{gameID}", h.handleClaimPrompt)
+	mux.HandleFunc("POST /htmx/claim/{gameID}", withBaseURL(h.handleClaimWin))
+	mux.HandleFunc("PATCH /htmx/settings/{gameID}", withBaseURL(h.handleUpdateSettings))
+	mux.HandleFunc("GET /htmx/sse/{gameID}", withBaseURL(h.handleSSE))
+	mux.HandleFunc("GET /htmx/lobby", withBaseURL(h.handleLobby))
+	mux.HandleFunc("GET /htmx/sse/lobby", withBaseURL(h.handleLobbySSE))
 }
 
-func getPlayerFromRequest(r *http.Request) string {
+// seatFromRequest returns the side a request acts for, from the form or
+// the query string. ok is false unless it is X or O.
+func seatFromRequest(r *http.Request) (player string, ok bool) {
 	r.ParseForm()
-	player := r.FormValue("player")
+	player = r.FormValue("player")
 	if player == "" {
 		player = r.URL.Query().Get("player")
 	}
-	if player == "" {
-		player = "X"
+	return player, isSeat(player)
+}
+
+// requireSeat returns the side a move or other action is taken for. A
+// request without a valid one gets a 400 explaining so, rather than
+// acting for X.
+func requireSeat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	player, ok := seatFromRequest(r)
+	if !ok {
+		httpx.WriteError(w, r, game.ErrInvalidPlayer)
 	}
-	return player
+	return player, ok
 }
 
 func (h *Handler) handleNewGame(w http.ResponseWriter, r *http.Request) {
-	player := getPlayerFromRequest(r)
-	g := h.gameService.CreateGame(models.Player(player))
+	// The new-game form is the one place a side is picked for the user
+	player, ok := seatFromRequest(r)
+	if !ok {
+		player = string(models.PlayerX)
+	}
+	symbol := r.FormValue("symbol")
+	analysisLive, _ := strconv.ParseBool(r.FormValue("analysisLive"))
+	readyCheck, _ := strconv.ParseBool(r.FormValue("readyCheck"))
+	earlyDraw, _ := strconv.ParseBool(r.FormValue("earlyDraw"))
+	opts := game.CreateOptions{
+		GameSettings: models.GameSettings{
+			Mode:         models.Mode(r.FormValue("mode")),
+			AnalysisLive: analysisLive,
+			Handicap:     handicapFromRequest(r),
+			ReadyCheck:   readyCheck,
+			EarlyDraw:    earlyDraw,
+			Locale:       r.FormValue("locale"),
+		},
+		Slug: r.FormValue("slug"),
+	}
+	if opts.Mode == models.ModeAI {
+		player = string(opponentOf(string(game.ComputerPlayer)))
+	}
+	if player == string(models.PlayerO) {
+		opts.OSymbol = symbol
+	} else {
+		opts.XSymbol = symbol
+	}
+	if ip := clientip.From(r.Context()); ip.IsValid() {
+		opts.Owner = ip.String()
+	}
+	g, err := h.gameService.CreateGame(r.Context(), models.Player(player), opts)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	rememberSeat(h.seats, w, r, g, player)
+	if negotiated(w, r, g, nil) {
+		return
+	}
 	w.Header().Set("Content-Type", "text/html")
 	GameWrapper(g, player).Render(r.Context(), w)
 }
 
-func (h *Handler) handleGetGame(w http.ResponseWriter, r *http.Request) {
-	gameID := r.URL.Query().Get("gameId")
-	if gameID == "" {
-		gameID = r.FormValue("gameId")
+// handicapFromRequest returns the handicap the new-game form asks for as
+// "<style>:<player>", such as "double:O", or nil for none. A mark goes on
+// a random cell.
+func handicapFromRequest(r *http.Request) *models.Handicap {
+	style, player, _ := strings.Cut(r.FormValue("handicap"), ":")
+	if style == "" {
+		return nil
 	}
-	if gameID == "" {
-		http.Error(w, "Game ID required", http.StatusBadRequest)
+	return &models.Handicap{
+		Style:    models.HandicapStyle(style),
+		Player:   models.Player(player),
+		Position: game.RandomPosition,
+	}
+}
+
+// viewerFromRequest returns the side the request claims to be made for,
+// or "" for a spectator: player=spectator, or a missing or unknown side,
+// shows the game without acting for either. Pages are rendered for the
+// claimed side only if this browser joined it, see perspective.
+func viewerFromRequest(r *http.Request) string {
+	switch p := r.URL.Query().Get("player"); p {
+	case string(models.PlayerX), string(models.PlayerO):
+		return p
+	}
+	return ""
+}
+
+// handleViewGame renders a game without joining it, so reloading a link
+// never claims or fails on a slot. A side that hasn't joined yet is
+// offered a join button instead of the board.
+func (h *Handler) handleViewGame(w http.ResponseWriter, r *http.Request) {
+	// The same URL answers with HTML or JSON, see negotiated
+	w.Header().Add("Vary", "Accept")
+	var g *models.GameState
+	gameID, err := httpx.PathGameID(r)
+	if err == nil {
+		g, err = h.gameService.FindGame(r.Context(), gameID)
+	}
+	if negotiated(w, r, g, err) {
 		return
 	}
-	player := getPlayerFromRequest(r)
-	g, err := h.gameService.JoinGame(gameID, models.Player(player))
-	if err != nil {
+	switch {
+	case errors.Is(err, game.ErrGameNotFound):
 		w.Header().Set("Content-Type", "text/html")
-		ErrorStatus(err.Error()).Render(r.Context(), w)
+		w.WriteHeader(http.StatusNotFound)
+		JoinNotFound().Render(r.Context(), w)
+		return
+	case err != nil:
+		httpx.WriteError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "text/html")
+	ctx := r.Context()
+	if takeover, _ := strconv.ParseBool(r.URL.Query().Get("takeover")); takeover {
+		ctx = withTakeover(ctx)
+	}
+	if optedIn, _ := strconv.ParseBool(r.URL.Query().Get("commentary")); optedIn {
+		ctx = withCommentary(ctx)
+	}
+	GameWrapper(g, perspective(readSeats(h.seats, r), g, viewerFromRequest(r))).Render(ctx, w)
+}
+
+// handleJoinGame claims a side of a game, given either in the path or as
+// the gameId field of the join form. Each way it can fail gets its own
+// fragment, with a way forward where there is one.
+func (h *Handler) handleJoinGame(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if r.PathValue("gameID") == "" {
+		gameID, err = game.NormalizeID(r.FormValue("gameId")), nil
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	if gameID == "" && negotiated(w, r, nil, game.ErrGameNotFound) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if gameID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		httpx.ErrorStatus(i18n.T(r.Context(), "error.enter_code")).Render(r.Context(), w)
+		return
+	}
+
+	var g *models.GameState
+	if err == nil {
+		g, err = h.gameService.JoinGame(r.Context(), gameID, models.Player(player), game.JoinOptions{
+			Symbol: r.FormValue("symbol"),
+		})
+		h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionJoin, Player: models.Player(player)}, err)
+	}
+	if err != nil {
+		h.notifyError(r, gameID, err)
+	}
+	if err == nil {
+		// Let the creator's waiting room know the opponent arrived
+		h.hub.Broadcast(r.Context(), g.ID, g)
+		rememberSeat(h.seats, w, r, g, player)
+	}
+	if negotiated(w, r, g, err) {
+		return
+	}
+	if err != nil {
+		w.WriteHeader(errcode.FromError(err).Status)
+	}
+	switch {
+	case errors.Is(err, game.ErrGameNotFound):
+		JoinNotFound().Render(r.Context(), w)
+		return
+	case errors.Is(err, game.ErrSlotTaken), errors.Is(err, game.ErrGameFull):
+		current, exists := h.gameService.GetGame(r.Context(), gameID)
+		if exists && !joined(current, string(opponentOf(player))) {
+			JoinSlotTaken(current, string(opponentOf(player))).Render(r.Context(), w)
+		} else {
+			JoinFull(gameID).Render(r.Context(), w)
+		}
+		return
+	case err != nil:
+		httpx.ErrorStatus(httpx.ErrorText(r.Context(), err)).Render(r.Context(), w)
+		return
+	}
 	GameWrapper(g, player).Render(r.Context(), w)
 }
 
 func (h *Handler) handleMakeMove(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
-	var position int
-	fmt.Sscanf(r.PathValue("position"), "%d", &position)
-	player := getPlayerFromRequest(r)
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	position, err := pathPosition(r)
+	if err != nil {
+		h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionMove, Player: models.Player(player)}, err)
+		h.notifyError(r, gameID, err)
+		httpx.WriteError(w, r, err)
+		return
+	}
 	move := models.Move{
 		Position: position,
 		Player:   models.Player(player),
 	}
-	g, err := h.gameService.MakeMove(gameID, move)
+	g, err := h.gameService.MakeMove(r.Context(), gameID, move)
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionMove, Player: move.Player, Move: &move}, err)
 	if err != nil {
-		g, _ = h.gameService.GetGame(gameID)
+		h.notifyError(r, gameID, err)
+		if negotiated(w, r, nil, err) {
+			return
+		}
+		g, _ = h.gameService.GetGame(r.Context(), gameID)
 		if g != nil {
 			w.Header().Set("Content-Type", "text/html")
 			GameWrapper(g, player).Render(r.Context(), w)
 		}
 		return
 	}
-	h.hub.Broadcast(gameID, g)
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	h.hub.NotifyTurn(r.Context(), g, "")
+	if negotiated(w, r, g, nil) {
+		return
+	}
 	w.Header().Set("Content-Type", "text/html")
 	GameWrapper(g, player).Render(r.Context(), w)
 }
 
+// record adds entry, for an action the game service answered with err,
+// to the game's activity, if there is a recorder.
+func (h *Handler) record(ctx context.Context, gameID string, entry activity.Entry, err error) {
+	if h.activity == nil {
+		return
+	}
+	entry.Transport = activity.TransportHTMX
+	entry.Result = activity.Result(err)
+	h.activity.Record(ctx, gameID, entry)
+}
+
+// notifyError sends a game-error event to the live view r was made
+// from, named in broadcast.ConnHeader.
+func (h *Handler) notifyError(r *http.Request, gameID string, err error) {
+	connID := r.Header.Get(broadcast.ConnHeader)
+	if connID == "" {
+		return
+	}
+	g, findErr := h.gameService.FindGame(r.Context(), gameID)
+	if findErr != nil {
+		return
+	}
+	h.hub.NotifyError(r.Context(), g.ID, broadcast.ToConn(connID), errcode.FromError(err).Code, err)
+}
+
+// pathPosition returns the board index in r's position path parameter,
+// read strictly, see httpx.PathInt, so a mangled URL is a
+// game.ErrInvalidMove rather than a move somewhere else.
+func pathPosition(r *http.Request) (int, error) {
+	position, err := httpx.PathInt(r, "position", 0, len(models.Board{})-1)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", game.ErrInvalidMove, err)
+	}
+	return position, nil
+}
+
 func (h *Handler) handleResetGame(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
-	player := getPlayerFromRequest(r)
-	g, err := h.gameService.ResetGame(gameID)
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	g, err := h.gameService.ResetGame(r.Context(), gameID, h.heldSeat(r, gameID, player))
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionReset, Player: models.Player(player)}, err)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.notifyError(r, gameID, err)
+		if negotiated(w, r, nil, err) {
+			return
+		}
+		http.Error(w, err.Error(), errcode.FromError(err).Status)
+		return
+	}
+	h.hub.Broadcast(r.Context(), g.ID, g)
+	if negotiated(w, r, g, nil) {
 		return
 	}
-	h.hub.Broadcast(gameID, g)
 	w.Header().Set("Content-Type", "text/html")
 	GameWrapper(g, player).Render(r.Context(), w)
+	h.renderUndoNotice(w, r, g.ID, player)
 }
 
-func (h *Handler) handleSSE(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
-	player := r.URL.Query().Get("player")
-	if player == "" {
-		player = "X"
+func (h *Handler) handleCancelGame(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
 	}
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	ch := make(chan *models.GameState, 10)
-	h.hub.RegisterSSE(gameID, ch)
-	defer h.hub.UnregisterSSE(gameID, ch)
-	flusher, ok := w.(http.Flusher)
+	if g, err := h.gameService.FindGame(r.Context(), gameID); err == nil {
+		gameID = g.ID
+	}
+	player, _ := seatFromRequest(r)
+	if err := h.gameService.CancelGame(r.Context(), gameID, h.heldSeat(r, gameID, player)); err != nil {
+		// A cancelled game's activity goes with it, so only refusals are
+		// recorded
+		h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionCancel}, err)
+		httpx.WriteError(w, r, err)
+		return
+	}
+	h.hub.CloseGame(gameID, broadcast.ReasonGameDeleted)
+	if respond.WantsJSON(r) {
+		// There is no game left to answer with
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	Cancelled().Render(r.Context(), w)
+	if isSeat(player) {
+		h.renderUndoNotice(w, r, gameID, player)
+	}
+}
+
+// handleVacateSlot frees the opponent's slot on behalf of the requesting
+// player, who must hold their own seat, and shows them the waiting room
+// again.
+func (h *Handler) handleVacateSlot(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
 	if !ok {
-		http.Error(w, "SSE not supported", http.StatusInternalServerError)
 		return
 	}
-	// Send initial state
-	if g, exists := h.gameService.GetGame(gameID); exists {
-		html := renderToString(r.Context(), GameContent(g, player))
-		fmt.Fprintf(w, "event: game-update\ndata: %s\n\n", strings.ReplaceAll(html, "\n", ""))
-		flusher.Flush()
+	err = seat.ErrNoSeat
+	var g *models.GameState
+	if h.heldSeat(r, gameID, player) != models.Empty {
+		g, err = h.gameService.VacateSlot(r.Context(), gameID, opponentOf(player))
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionVacate, Player: models.Player(player)}, err)
+	if err == nil {
+		h.hub.Broadcast(r.Context(), g.ID, g)
+	}
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if negotiated(w, r, g, nil) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	GameWrapper(g, player).Render(r.Context(), w)
+}
+
+func (h *Handler) handleOfferDraw(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	err = seat.ErrNoSeat
+	var g *models.GameState
+	if h.heldSeat(r, gameID, player) != models.Empty {
+		g, err = h.gameService.OfferDraw(r.Context(), gameID, models.Player(player))
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionDrawOffer, Player: models.Player(player)}, err)
+	h.renderAction(w, r, gameID, player, g, err)
+}
+
+// handleRespondDraw answers the opponent's offer of a draw, accepting it
+// with ?accept=true.
+func (h *Handler) handleRespondDraw(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	accept, _ := strconv.ParseBool(r.URL.Query().Get("accept"))
+	err = seat.ErrNoSeat
+	var g *models.GameState
+	if h.heldSeat(r, gameID, player) != models.Empty {
+		g, err = h.gameService.RespondDraw(r.Context(), gameID, models.Player(player), accept)
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionDrawReply, Player: models.Player(player)}, err)
+	h.renderAction(w, r, gameID, player, g, err)
+}
+
+// renderAction broadcasts the game after a player's action, such as a
+// draw offer or saying they are ready, and shows it to the player, or on
+// failure tells them why and shows the game as it stands, like a
+// rejected move.
+func (h *Handler) renderAction(w http.ResponseWriter, r *http.Request, gameID, player string, g *models.GameState, err error) {
+	if err != nil {
+		h.notifyError(r, gameID, err)
+	} else {
+		h.hub.Broadcast(r.Context(), g.ID, g)
+	}
+	if negotiated(w, r, g, err) {
+		return
+	}
+	if err != nil {
+		if g, _ = h.gameService.GetGame(r.Context(), gameID); g == nil {
+			http.Error(w, err.Error(), errcode.FromError(err).Status)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/html")
+	GameWrapper(g, player).Render(r.Context(), w)
+}
+
+func (h *Handler) handleSSE(w http.ResponseWriter, r *http.Request) {
+	// Subscribe under the full ID, see the WebSocket handler
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if g, err := h.gameService.FindGame(r.Context(), gameID); err == nil {
+		gameID = g.ID
+	} else if errors.Is(err, game.ErrAmbiguousID) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Each update is rendered for the side asked for only if this browser
+	// joined it, so a link with someone else's ?player= shows the game as
+	// a spectator sees it. Targeted events go to joined sides alone.
+	seats := readSeats(h.seats, r)
+	claimed := viewerFromRequest(r)
+	player := seatOf(seats, gameID, claimed)
+	// An embedded board, see EmbedPage, is a spectator's whoever opens it
+	embed, _ := strconv.ParseBool(r.URL.Query().Get("embed"))
+	if embed {
+		seats, claimed, player = nil, "", ""
+	}
+	// Only one connection plays a side at a time, see
+	// broadcast.DuplicatePolicy. One turned away watches instead and is
+	// offered to take the side over, as is one superseded later on.
+	var superseded *broadcast.Superseded
+	takeover, _ := strconv.ParseBool(r.URL.Query().Get("takeover"))
+	if !h.hub.Admit(gameID, broadcast.NewSubscriber(models.Player(player), ""), takeover) {
+		superseded = &broadcast.Superseded{GameID: gameID, Player: models.Player(player), Rejected: true}
+		player, claimed = "", ""
+	}
+	if player != "" {
+		w.Header().Set(PerspectiveHeader, player)
+	} else {
+		w.Header().Set(PerspectiveHeader, "spectator")
+	}
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	defer sw.Close()
+	sw.Heartbeat(sse.DefaultHeartbeat)
+	ctx := logging.WithConnID(r.Context(), sseConnID(r))
+	slog.InfoContext(ctx, "sse opened", "game_id", gameID, "player", player, "claimed", claimed)
+	defer slog.InfoContext(ctx, "sse closed", "game_id", gameID)
+
+	ctx, span := tracer.Start(ctx, "sse.connection")
+	defer span.End()
+	sent := 0
+	defer func() {
+		if span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("game.id", gameID),
+				attribute.Int("events.sent", sent),
+			)
+		}
+	}()
+
+	ch := make(chan broadcast.Message, 10)
+	sub := broadcast.NewSubscriber(models.Player(player), logging.ConnID(ctx))
+	// Players' boards leave live analysis out so they aren't given hints,
+	// and commentary unless they asked for it, see withCommentary
+	sub.NoAnalysis = isSeat(player)
+	sub.Commentary, _ = strconv.ParseBool(r.URL.Query().Get("commentary"))
+	h.hub.RegisterSSE(gameID, ch, sub)
+	defer h.hub.UnregisterSSE(gameID, ch)
+	// Send initial state, unless the client reconnected having already
+	// seen it, as after a restart when nothing happened meanwhile
+	var state string
+	if g, exists := h.gameService.GetGame(ctx, gameID); exists {
+		state = stateID(g)
+		if _, seen := parseEventID(r.Header.Get("Last-Event-ID")); seen != state {
+			if err := sendGameUpdate(ctx, sw, g, perspective(seats, g, claimed), h.hub.Seq(gameID), embed); err != nil {
+				return
+			}
+			sent++
+		}
+	}
+	if superseded != nil {
+		if err := sendEvent(ctx, sw, broadcast.SupersededEvent, "", SupersededNotice(*superseded)); err != nil {
+			return
+		}
+		sent++
 	}
 	for {
 		select {
-		case g := <-ch:
-			html := renderToString(r.Context(), GameContent(g, player))
-			fmt.Fprintf(w, "event: game-update\ndata: %s\n\n", strings.ReplaceAll(html, "\n", ""))
-			flusher.Flush()
-		case <-r.Context().Done():
+		case msg := <-ch:
+			if msg.Event != nil && msg.Event.Name == broadcast.SupersededEvent {
+				// Show the board as spectators see it from now on, so
+				// it can't be played from here
+				claimed = ""
+				if g, exists := h.gameService.GetGame(ctx, gameID); exists {
+					if err := sendGameUpdate(ctx, sw, g, perspective(seats, g, claimed), h.hub.Seq(gameID), embed); err != nil {
+						return
+					}
+					sent++
+				}
+			}
+			if msg.Game != nil {
+				state = stateID(msg.Game)
+				err = sendGameUpdate(ctx, sw, msg.Game, perspective(seats, msg.Game, claimed), msg.Seq, embed)
+			} else {
+				var id string
+				if msg.Seq != 0 {
+					id = eventID(msg.Seq, state)
+				}
+				err = sendEvent(ctx, sw, msg.Event.Name, id, eventData(msg.Event.Data))
+			}
+			if err != nil {
+				return
+			}
+			h.hub.RecordDelivery(broadcast.TransportSSE, msg.Created)
+			sent++
+		case <-h.hub.Draining():
+			sw.Send(broadcast.Restart())
+			return
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func renderToString(ctx context.Context, component templ.Component) string {
-	var buf bytes.Buffer
-	component.Render(ctx, &buf)
-	return buf.String()
+// stateID identifies a game state as an SSE event id. UpdatedAt changes
+// with every change to a game and, unlike anything the hub counts, is
+// still the same after a restart.
+func stateID(g *models.GameState) string {
+	return strconv.FormatInt(g.UpdatedAt.UnixNano(), 10)
+}
+
+// eventID is the SSE event id of the event numbered seq, see
+// broadcast.LoggedEvent, sent when the client's latest game state was
+// state: "<seq>-<state>". Events sent to some clients only have no id,
+// so the last one stays that of an event everyone was sent.
+func eventID(seq uint64, state string) string {
+	return strconv.FormatUint(seq, 10) + "-" + state
+}
+
+// parseEventID splits a Last-Event-ID into the event's number and the
+// client's game state then. An id without a number, as sent before
+// events were numbered, is taken for the state alone.
+func parseEventID(id string) (seq uint64, state string) {
+	num, state, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, id
+	}
+	seq, _ = strconv.ParseUint(num, 10, 64)
+	return seq, state
+}
+
+// sendGameUpdate sends a game-update event numbered seq with the state's
+// id, which EventSource sends back as Last-Event-ID when it reconnects.
+// It carries the embedded board, see EmbedBoard, when embed is set, in
+// the game's language like the page it is on.
+func sendGameUpdate(ctx context.Context, sw *sse.Writer, g *models.GameState, player string, seq uint64, embed bool) error {
+	content := GameContent(g, player)
+	if embed {
+		ctx = i18n.WithLang(ctx, g.Locale)
+		content = EmbedBoard(g)
+	}
+	return sendEvent(ctx, sw, "game-update", eventID(seq, stateID(g)), content)
+}
+
+// bufferPool holds reusable buffers for rendering components.
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	// Don't keep unusually large buffers around
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// eventData turns a targeted event's data into a component: components
+// are used as is and anything else is sent as JSON.
+func eventData(data any) templ.Component {
+	switch d := data.(type) {
+	case templ.Component:
+		return d
+	case broadcast.TurnNotification:
+		return TurnNotice(d)
+	case broadcast.ErrorNotice:
+		return ErrorToast(d)
+	case broadcast.AnalysisUpdate:
+		return EvalBar(d.Evaluation)
+	case broadcast.CommentaryLine:
+		return CommentaryEntry(d.Commentary)
+	case broadcast.ClaimNotice:
+		return ClaimPrompt(d)
+	case broadcast.Countdown:
+		return CountdownNotice(d)
+	case broadcast.Superseded:
+		return SupersededNotice(d)
+	}
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		return json.NewEncoder(w).Encode(data)
+	})
+}
+
+// evaluation analyzes the game's position for the evaluation bar.
+// Templates name their game parameter game, which hides the package.
+func evaluation(g *models.GameState) models.Evaluation {
+	return game.Analyze(g)
+}
+
+// sendEvent renders a component into an SSE event. It is rendered into
+// a pooled buffer, which the writer copies from before the buffer is
+// returned, so nothing outlives the call.
+func sendEvent(ctx context.Context, sw *sse.Writer, event, id string, component templ.Component) error {
+	html := getBuffer()
+	defer putBuffer(html)
+	if err := component.Render(ctx, html); err != nil {
+		return err
+	}
+	return sw.Send(sse.Event{Name: event, ID: id, Data: html.Bytes()})
 }
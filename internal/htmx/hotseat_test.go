@@ -0,0 +1,66 @@
+package htmx
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+var movePattern = regexp.MustCompile(`hx-post="/htmx/move/[^/]+/(\d)\?player=(\w*)"`)
+
+// TestHotseatMoves renders a hot-seat game and an online one after each
+// move, and checks the hot-seat board posts moves for whoever's turn it
+// is while the online one posts them for the viewer's side.
+func TestHotseatMoves(t *testing.T) {
+	ctx := context.Background()
+	games := game.NewService()
+	defer games.Close()
+
+	for _, tt := range []struct {
+		mode models.Mode
+		pass bool
+	}{{models.ModeHotseat, true}, {models.ModeOnline, false}} {
+		g, err := games.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: tt.mode}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tt.pass {
+			if g, err = games.JoinGame(ctx, g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i, pos := range []int{4, 0, 8} {
+			var out strings.Builder
+			if err := GameContent(g, "X").Render(ctx, &out); err != nil {
+				t.Fatal(err)
+			}
+			page := out.String()
+			turn := g.CurrentTurn
+			want := "X"
+			if tt.pass {
+				want = string(turn)
+			}
+			// An online game only has X's cells to click on X's turn
+			cells := movePattern.FindAllStringSubmatch(page, -1)
+			if (tt.pass || turn == models.PlayerX) && len(cells) != 9-i {
+				t.Errorf("%s, move %d: %d cells to click, want %d", tt.mode, i, len(cells), 9-i)
+			}
+			for _, m := range cells {
+				if m[2] != want {
+					t.Errorf("%s, move %d: cell %s posts player=%s, want %s", tt.mode, i, m[1], m[2], want)
+				}
+			}
+			pass := "pass the device: " + string(turn) + "&#39;s turn"
+			if strings.Contains(page, pass) != tt.pass {
+				t.Errorf("%s, move %d: %q shown: %v, want %v\n%s", tt.mode, i, pass, !tt.pass, tt.pass, page)
+			}
+			if g, err = games.MakeMove(ctx, g.ID, models.Move{Position: pos, Player: turn}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package htmx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/models"
+
+	"github.com/a-h/templ"
+)
+
+// TestFragmentsAreTranslated renders fragments in English and Spanish,
+// and with no locale, which is English.
+func TestFragmentsAreTranslated(t *testing.T) {
+	g := models.NewGameState("abcd1234")
+	g.PlayerXJoined, g.PlayerOJoined = true, true
+	g.CurrentTurn = models.PlayerX
+
+	tests := []struct {
+		name      string
+		component templ.Component
+		en, es    string
+	}{
+		{"X's turn, to X", GameContent(g, "X"), "your_turn", "tu_turno"},
+		{"a missing game", JoinNotFound(), "game not found — check the code", "partida no encontrada — revisa el código"},
+		{"a full game", JoinFull(g.ID), "[watch]", "[mirar]"},
+	}
+	for _, tt := range tests {
+		for _, lang := range []string{"", "en", "es"} {
+			var out strings.Builder
+			if err := tt.component.Render(i18n.WithLang(context.Background(), lang), &out); err != nil {
+				t.Fatal(err)
+			}
+			want, other := tt.en, tt.es
+			if lang == "es" {
+				want, other = tt.es, tt.en
+			}
+			if !strings.Contains(out.String(), want) || strings.Contains(out.String(), other) {
+				t.Errorf("%s in %q: want %q, not %q, in\n%s", tt.name, lang, want, other, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package htmx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/urls"
+
+	"github.com/a-h/templ"
+	"rsc.io/qr"
+)
+
+type baseURLKey struct{}
+
+// withBaseURL records the URL the request was made to, so components
+// can render absolute invite links.
+func withBaseURL(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		ctx := context.WithValue(r.Context(), baseURLKey{}, scheme+"://"+r.Host)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// inviteURL returns the link the missing player opens to join the game.
+// It is relative when the request's base URL isn't known.
+func inviteURL(ctx context.Context, game *models.GameState) string {
+	base, _ := ctx.Value(baseURLKey{}).(string)
+	return base + urls.Pathf(ctx, "/?game=%s&player=%s", displayID(game), openSlot(game))
+}
+
+// watchURL returns the link that opens the game as a spectator. Like
+// inviteURL, it is relative when the request's base URL isn't known.
+func watchURL(ctx context.Context, game *models.GameState) string {
+	base, _ := ctx.Value(baseURLKey{}).(string)
+	return base + urls.Pathf(ctx, "/?game=%s", displayID(game))
+}
+
+// displayID is how a game is named to people: by its slug if it has one,
+// which is easier to say and type than its ID.
+func displayID(game *models.GameState) string {
+	if game.Slug != "" {
+		return game.Slug
+	}
+	return game.ID
+}
+
+// openSlot returns the side still waiting for a player.
+func openSlot(game *models.GameState) models.Player {
+	if game.PlayerXJoined {
+		return models.PlayerO
+	}
+	return models.PlayerX
+}
+
+// opponentOf returns the other side from player, who must be X or O.
+func opponentOf(player string) models.Player {
+	if player == string(models.PlayerO) {
+		return models.PlayerX
+	}
+	return models.PlayerO
+}
+
+// isSeat reports whether player is one of the two sides rather than a
+// spectator.
+func isSeat(player string) bool {
+	return player == string(models.PlayerX) || player == string(models.PlayerO)
+}
+
+// mover returns the side the player's clicks on the board move for:
+// their own, or in a hot-seat game whoever's turn it is, so one browser
+// plays both sides.
+func mover(game *models.GameState, player string) string {
+	if game.Mode == models.ModeHotseat {
+		return string(game.CurrentTurn)
+	}
+	return player
+}
+
+// canOfferDraw reports whether player may offer a draw in game: on their
+// turn in an online game, once a turn after the first move. Players of a
+// hot-seat game can simply agree, and the computer takes no offers.
+func canOfferDraw(game *models.GameState, player string) bool {
+	return game.Mode == models.ModeOnline && !game.IsOver && string(game.CurrentTurn) == player &&
+		game.DrawOffer == models.Empty && started(game) && game.DrawOfferedAt != len(game.History)
+}
+
+// handicapParam returns the game's handicap as the new-game form's
+// handicap field takes it, so a new game can be played with it too.
+func handicapParam(game *models.GameState) string {
+	if game.Handicap == nil {
+		return ""
+	}
+	return string(game.Handicap.Style) + ":" + string(game.Handicap.Player)
+}
+
+// started reports whether anyone has moved in g, see game.Started.
+func started(g *models.GameState) bool {
+	return game.Started(g)
+}
+
+// joined reports whether player's side of the game has been claimed.
+func joined(game *models.GameState, player string) bool {
+	switch models.Player(player) {
+	case models.PlayerX:
+		return game.PlayerXJoined
+	case models.PlayerO:
+		return game.PlayerOJoined
+	}
+	return false
+}
+
+// qrCode renders text as a QR code in an inline SVG. It renders nothing
+// if the text doesn't fit in a QR code.
+func qrCode(text string) templ.Component {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return templ.Raw("")
+	}
+	const quiet = 4
+	n := code.Size + 2*quiet
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg class="qr" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, n, n)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	// One rectangle per horizontal run of dark modules
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !code.Black(x, y) {
+				continue
+			}
+			run := 1
+			for x+run < code.Size && code.Black(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&sb, "M%d %dh%dv1h-%dz", x+quiet, y+quiet, run, run)
+			x += run - 1
+		}
+	}
+	sb.WriteString(`"/></svg>`)
+	return templ.Raw(sb.String())
+}
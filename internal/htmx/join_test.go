@@ -0,0 +1,88 @@
+package htmx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestJoinGame(t *testing.T) {
+	games := game.NewService(game.WithIDGenerator(gametest.NewFixedIDs("abcd1234", "beef5678", "cafe9abc", "d00d0000")))
+	defer games.Close()
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	create := func(opponent bool) *models.GameState {
+		t.Helper()
+		g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opponent {
+			if g, err = games.JoinGame(t.Context(), g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return g
+	}
+	waiting, full, joinable, viewed := create(false), create(true), create(false), create(false)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		form   url.Values
+		status int
+		want   []string
+	}{
+		{"by code", "POST", "/htmx/join", url.Values{"gameId": {"  " + strings.ToUpper(joinable.ID) + " "}, "player": {"O"}}, http.StatusOK, []string{`data-game-id="` + joinable.ID + `"`}},
+		{"without a code", "POST", "/htmx/join", url.Values{"gameId": {"  "}, "player": {"O"}}, http.StatusBadRequest, []string{"enter a game code to join"}},
+		{"a missing game", "POST", "/htmx/join", url.Values{"gameId": {"nope1234"}, "player": {"O"}}, http.StatusNotFound, []string{"game not found — check the code"}},
+		{"a missing game by path", "POST", "/htmx/join/nope1234", url.Values{"player": {"O"}}, http.StatusNotFound, []string{"game not found — check the code"}},
+		{"a taken side", "POST", "/htmx/join/" + waiting.ID, url.Values{"player": {"X"}}, http.StatusConflict, []string{
+			"that side is taken, join as O?",
+			`hx-post="/htmx/join/` + waiting.ID + `?player=O"`,
+		}},
+		{"a full game", "POST", "/htmx/join/" + full.ID, url.Values{"player": {"X"}}, http.StatusConflict, []string{
+			"game is full — watch instead?",
+			`hx-get="/htmx/game/` + full.ID + `"`,
+		}},
+		{"without a side", "POST", "/htmx/join/" + waiting.ID, nil, http.StatusBadRequest, nil},
+		{"viewing", "GET", "/htmx/game/" + viewed.ID, nil, http.StatusOK, []string{`data-game-id="` + viewed.ID + `"`}},
+		{"viewing a missing game", "GET", "/htmx/game/nope1234", nil, http.StatusNotFound, []string{"game not found — check the code"}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d\n%s", tt.name, w.Code, tt.status, w.Body)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("%s: no %q in\n%s", tt.name, want, w.Body)
+			}
+		}
+	}
+
+	// Joining by code took O's side; viewing, however often, takes none
+	for _, tt := range []struct {
+		g    *models.GameState
+		want bool
+	}{{joinable, true}, {viewed, false}, {waiting, false}} {
+		if got, _ := games.GetGame(t.Context(), tt.g.ID); got.PlayerOJoined != tt.want {
+			t.Errorf("%s: O joined = %v, want %v", tt.g.ID, got.PlayerOJoined, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package htmx
+
+import (
+	"log/slog"
+	"net/http"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/sse"
+
+	"github.com/a-h/templ"
+)
+
+// handleLobby renders the list of games waiting for an opponent, which
+// then keeps itself current over the lobby stream.
+func (h *Handler) handleLobby(w http.ResponseWriter, r *http.Request) {
+	games, err := h.gameService.OpenGames(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	Lobby(games).Render(r.Context(), w)
+}
+
+// handleLobbySSE streams the lobby's changes as entries to add to the
+// list or, out of band, to delete from it. Changes to the featured games
+// come as lobby events too, replacing their list out of band.
+func (h *Handler) handleLobbySSE(w http.ResponseWriter, r *http.Request) {
+	// Subscribed before the stream opens, so nothing published once the
+	// client sees it open is missed
+	events, cancel := h.hub.SubscribeTopic(broadcast.LobbyTopic)
+	defer cancel()
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	defer sw.Close()
+	sw.Heartbeat(sse.DefaultHeartbeat)
+	ctx := logging.WithConnID(r.Context(), logging.NewID())
+	slog.InfoContext(ctx, "sse opened", "topic", broadcast.LobbyTopic)
+	defer slog.InfoContext(ctx, "sse closed", "topic", broadcast.LobbyTopic)
+
+	for {
+		select {
+		case ev, ok := <-events:
+			// Closed for falling behind: the client reconnects and
+			// fetches the list afresh
+			if !ok {
+				return
+			}
+			var change templ.Component
+			switch data := ev.Data.(type) {
+			case broadcast.LobbyUpdate:
+				change = LobbyChange(data)
+			case []featured.Entry:
+				change = FeaturedChange(data)
+			default:
+				continue
+			}
+			if err := sendEvent(ctx, sw, broadcast.LobbyEvent, "", change); err != nil {
+				return
+			}
+		case <-h.hub.Draining():
+			sw.Send(broadcast.Restart())
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package htmx
+
+import (
+	"context"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/i18n"
+)
+
+// noticeText returns the message of an error event in the locale of ctx.
+func noticeText(ctx context.Context, n broadcast.ErrorNotice) string {
+	if n.Code == "" {
+		return n.Message
+	}
+	return i18n.T(ctx, "error."+n.Code)
+}
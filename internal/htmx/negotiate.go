@@ -0,0 +1,24 @@
+package htmx
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+)
+
+// negotiated answers a request that asks for JSON rather than a fragment,
+// see respond.WantsJSON, the way the REST API would: with the game g, or
+// the error envelope for err. It reports whether it did so, leaving the
+// HTML to the caller otherwise.
+func negotiated(w http.ResponseWriter, r *http.Request, g *models.GameState, err error) bool {
+	if !respond.WantsJSON(r) {
+		return false
+	}
+	if err != nil {
+		respond.Err(w, r, err)
+		return true
+	}
+	respond.JSON(w, http.StatusOK, g)
+	return true
+}
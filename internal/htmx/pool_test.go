@@ -0,0 +1,175 @@
+package htmx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/sse"
+
+	"github.com/a-h/templ"
+)
+
+// discardStream is an event stream's response that throws away what is
+// written to it, for benchmarks.
+type discardStream struct {
+	header http.Header
+}
+
+func (d *discardStream) Header() http.Header {
+	if d.header == nil {
+		d.header = http.Header{}
+	}
+	return d.header
+}
+
+func (d *discardStream) Write(p []byte) (int, error) { return len(p), nil }
+func (d *discardStream) WriteHeader(int)             {}
+func (d *discardStream) Flush()                      {}
+
+// benchGame returns a game part way through, to render.
+func benchGame() *models.GameState {
+	g := models.NewGameState("bench")
+	g.PlayerXJoined, g.PlayerOJoined = true, true
+	for i, pos := range []int{4, 0, 8, 2} {
+		g.Board[pos] = []models.Player{models.PlayerX, models.PlayerO}[i%2]
+		g.History = append(g.History, models.MoveRecord{Position: pos, Player: g.Board[pos]})
+	}
+	return g
+}
+
+// sendEventFresh is sendEvent as it was before buffers were pooled,
+// rendering into a buffer of its own and copying that into a string.
+func sendEventFresh(ctx context.Context, sw *sse.Writer, event, id string, component templ.Component) error {
+	var html bytes.Buffer
+	if err := component.Render(ctx, &html); err != nil {
+		return err
+	}
+	return sw.Send(sse.Event{Name: event, ID: id, Data: []byte(html.String())})
+}
+
+func BenchmarkSendEvent(b *testing.B) {
+	g := benchGame()
+	for _, bb := range []struct {
+		name string
+		send func(context.Context, *sse.Writer, string, string, templ.Component) error
+	}{
+		{"pooled", sendEvent},
+		{"fresh", sendEventFresh},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			sw, err := sse.NewWriter(&discardStream{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := bb.send(ctx, sw, "game-update", "1-1", GameContent(g, "X")); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkBroadcast times a move reaching the SSE clients watching the
+// game: the hub handing it to each, and each rendering its update, as
+// handleSSE does.
+func BenchmarkBroadcast(b *testing.B) {
+	const clients = 16
+	hub := broadcast.NewHub()
+	ctx := context.Background()
+	g := benchGame()
+	var rendered sync.WaitGroup
+	for i := range clients {
+		ch := make(chan broadcast.Message, 10)
+		hub.RegisterSSE(g.ID, ch, broadcast.NewSubscriber("", fmt.Sprint(i)))
+		sw, err := sse.NewWriter(&discardStream{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		go func() {
+			for msg := range ch {
+				if msg.Game != nil {
+					sendGameUpdate(ctx, sw, msg.Game, "", msg.Seq, false)
+					rendered.Done()
+				}
+			}
+		}()
+		b.Cleanup(func() { hub.UnregisterSSE(g.ID, ch) })
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		g = g.Clone()
+		g.Version++
+		rendered.Add(clients)
+		hub.Broadcast(ctx, g.ID, g)
+		rendered.Wait()
+	}
+}
+
+// TestSendEventDoesNotRetainBuffers renders many streams' events at once
+// from the one pool. Each stream must end up with exactly its own events
+// although the buffers they were rendered in were handed on to others
+// straight after. Run it with -race.
+func TestSendEventDoesNotRetainBuffers(t *testing.T) {
+	const streams, events = 16, 50
+	// Payloads of different sizes, so buffers grow and are reused by
+	// renders both bigger and smaller than the last
+	payload := func(stream, event int) string {
+		return strings.Repeat(fmt.Sprintf("<b>%d.%d</b>", stream, event), 1+(stream*event)%40)
+	}
+	recorders := make([]*httptest.ResponseRecorder, streams)
+	var wg sync.WaitGroup
+	for i := range streams {
+		recorders[i] = httptest.NewRecorder()
+		sw, err := sse.NewWriter(recorders[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Go(func() {
+			for j := range events {
+				if err := sendEvent(t.Context(), sw, "e", "", templ.Raw(payload(i, j))); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	for i, rec := range recorders {
+		var want strings.Builder
+		for j := range events {
+			fmt.Fprintf(&want, "event: e\ndata: %s\n\n", payload(i, j))
+		}
+		if got := rec.Body.String(); got != want.String() {
+			t.Errorf("stream %d got\n%.300s\nwant\n%.300s", i, got, want.String())
+		}
+	}
+}
+
+func TestPutBufferDropsLargeBuffers(t *testing.T) {
+	large := getBuffer()
+	large.Grow(128 << 10)
+	putBuffer(large)
+	for range 100 {
+		buf := getBuffer()
+		if buf == large {
+			t.Fatal("a buffer grown past 64KiB was pooled")
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("a pooled buffer came back holding %q", buf)
+		}
+		buf.WriteString("left over")
+		putBuffer(buf)
+	}
+}
@@ -0,0 +1,53 @@
+package htmx
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/puzzle"
+)
+
+// PuzzleHandler serves the daily puzzle as HTMX fragments.
+type PuzzleHandler struct {
+	puzzles *puzzle.Service
+}
+
+// NewPuzzleHandler creates a new daily puzzle handler.
+func NewPuzzleHandler(puzzles *puzzle.Service) *PuzzleHandler {
+	return &PuzzleHandler{puzzles: puzzles}
+}
+
+// RegisterRoutes sets up the puzzle routes.
+func (h *PuzzleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /htmx/puzzle", h.handlePuzzle)
+	mux.HandleFunc("POST /htmx/puzzle/attempt/{position}", h.handleAttempt)
+}
+
+func (h *PuzzleHandler) handlePuzzle(w http.ResponseWriter, r *http.Request) {
+	session := puzzle.Session(w, r)
+	result := h.puzzles.Progress(session)
+	w.Header().Set("Content-Type", "text/html")
+	PuzzleView(h.puzzles.Today(), result, h.puzzles.Stats(), "").Render(r.Context(), w)
+}
+
+func (h *PuzzleHandler) handleAttempt(w http.ResponseWriter, r *http.Request) {
+	session := puzzle.Session(w, r)
+	position, err := pathPosition(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	result, err := h.puzzles.Attempt(session, position)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+
+	message := ""
+	if !result.Correct && result.Solution == nil {
+		message = i18n.T(r.Context(), "puzzle.not_quite", puzzle.MaxAttempts-result.Attempts)
+	}
+	w.Header().Set("Content-Type", "text/html")
+	PuzzleView(h.puzzles.Today(), result, h.puzzles.Stats(), message).Render(r.Context(), w)
+}
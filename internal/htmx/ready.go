@@ -0,0 +1,59 @@
+package htmx
+
+import (
+	"net/http"
+	"strconv"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+// handleReady says the player is ready to start a game with a ready
+// check, or with ?ready=false that they no longer are, see
+// game.Service.Ready.
+func (h *Handler) handleReady(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	ready, err := strconv.ParseBool(r.URL.Query().Get("ready"))
+	if err != nil {
+		ready = true
+	}
+	err = seat.ErrNoSeat
+	var g *models.GameState
+	if h.heldSeat(r, gameID, player) != models.Empty {
+		g, err = h.gameService.Ready(r.Context(), gameID, models.Player(player), ready)
+	}
+	action := activity.ActionReady
+	if !ready {
+		action = activity.ActionUnready
+	}
+	h.record(r.Context(), gameID, activity.Entry{Action: action, Player: models.Player(player)}, err)
+	h.renderAction(w, r, gameID, player, g, err)
+}
+
+// awaitingReady reports whether g waits for its players to be ready, see
+// game.AwaitingReady.
+func awaitingReady(g *models.GameState) bool {
+	return game.AwaitingReady(g)
+}
+
+// isReady reports whether player has said they are ready in g.
+func isReady(g *models.GameState, player string) bool {
+	switch models.Player(player) {
+	case models.PlayerX:
+		return g.PlayerXReady
+	case models.PlayerO:
+		return g.PlayerOReady
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestReadyNeedsTheSeat(t *testing.T) {
+	games := game.NewService()
+	defer games.Close()
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	post := func(path, cookie string) (int, string) {
+		r := httptest.NewRequest("POST", path, nil)
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		var body struct{ Code string }
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Code
+	}
+
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{ReadyCheck: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.JoinGame(t.Context(), g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	x := g.ID + "." + h.seats.Token(g.ID, "X")
+	o := g.ID + "." + h.seats.Token(g.ID, "O")
+
+	for _, cookie := range []string{"", g.ID + ".X", o} {
+		if status, code := post("/htmx/ready/"+g.ID+"?player=X", cookie); code != "seat_token" {
+			t.Errorf("readying X with cookie %q: %d %s, want seat_token", cookie, status, code)
+		}
+	}
+	if status, _ := post("/htmx/ready/"+g.ID+"?player=X", x); status != http.StatusOK {
+		t.Fatalf("readying X: status = %d, want 200", status)
+	}
+	if status, code := post("/htmx/ready/"+g.ID+"?player=X&ready=false", o); code != "seat_token" {
+		t.Errorf("unreadying X with O's cookie: %d %s, want seat_token", status, code)
+	}
+	if got, _ := games.GetGame(t.Context(), g.ID); !got.PlayerXReady || got.PlayerOReady {
+		t.Errorf("X ready %v, O ready %v, want X alone", got.PlayerXReady, got.PlayerOReady)
+	}
+}
@@ -0,0 +1,91 @@
+package htmx
+
+import (
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+// seatsCookie names the cookie listing the sides this browser has joined,
+// see seat.CookieName. Players have no accounts, so it is all there is
+// to tell a player from someone who merely put ?player= in a link, and
+// entries whose token doesn't hold up are ignored.
+const seatsCookie = seat.CookieName
+
+// PerspectiveHeader names the response header of a game's event stream
+// saying whose view it renders once the sides have joined: "X", "O" or
+// "spectator".
+const PerspectiveHeader = "X-Perspective"
+
+// maxSeats bounds how many games the seats cookie remembers; the oldest
+// are forgotten first.
+const maxSeats = 32
+
+// readSeats returns the seats cookie's entries signed by signer, mapping
+// game IDs to the sides joined.
+func readSeats(signer *seat.Signer, r *http.Request) map[string]string {
+	return signer.Cookie(r)
+}
+
+// rememberSeat adds the sides joined in game to the seats cookie, signed
+// by signer: both of a hot-seat game's, otherwise player's.
+func rememberSeat(signer *seat.Signer, w http.ResponseWriter, r *http.Request, game *models.GameState, player string) {
+	sides := player
+	if game.Mode == models.ModeHotseat {
+		sides = string(models.PlayerX) + string(models.PlayerO)
+	}
+	var entries []string
+	if c, err := r.Cookie(seatsCookie); err == nil {
+		for _, entry := range strings.Split(c.Value, "-") {
+			id, token, ok := strings.Cut(entry, ".")
+			if !ok || id == "" {
+				continue
+			}
+			old, ok := signer.Sides(id, token)
+			if !ok {
+				continue
+			}
+			if id == game.ID {
+				if !strings.Contains(old, player) {
+					sides = old + sides
+				}
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, game.ID+"."+signer.Token(game.ID, sides))
+	if len(entries) > maxSeats {
+		entries = entries[len(entries)-maxSeats:]
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     seatsCookie,
+		Value:    strings.Join(entries, "-"),
+		Path:     "/",
+		MaxAge:   7 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// seatOf returns claimed if seats show it was joined in the game with ID
+// gameID, and "" otherwise.
+func seatOf(seats map[string]string, gameID, claimed string) string {
+	if isSeat(claimed) && strings.Contains(seats[gameID], claimed) {
+		return claimed
+	}
+	return ""
+}
+
+// perspective returns the side to render game for when claimed was
+// asked for with ?player=. A side nobody has joined yet is rendered as
+// asked, which offers to join it; a side somebody has joined only for
+// the browser that joined it, and as a spectator's view for anyone else.
+func perspective(seats map[string]string, game *models.GameState, claimed string) string {
+	if isSeat(claimed) && !joined(game, claimed) {
+		return claimed
+	}
+	return seatOf(seats, game.ID, claimed)
+}
@@ -0,0 +1,95 @@
+package htmx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+// withCookies returns a request carrying the cookies w set.
+func withCookies(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestSeatsCookieRoundTrip(t *testing.T) {
+	signer := seat.NewSigner([]byte("key"))
+	online := models.NewGameState("online")
+	hotseat := models.NewGameState("hotseat")
+	hotseat.Mode = models.ModeHotseat
+
+	w := httptest.NewRecorder()
+	rememberSeat(signer, w, httptest.NewRequest("GET", "/", nil), online, "X")
+	r := withCookies(w)
+	w = httptest.NewRecorder()
+	rememberSeat(signer, w, r, hotseat, "X")
+	r = withCookies(w)
+	w = httptest.NewRecorder()
+	rememberSeat(signer, w, r, online, "O")
+
+	seats := readSeats(signer, withCookies(w))
+	if seats["online"] != "XO" || seats["hotseat"] != "XO" || len(seats) != 2 {
+		t.Errorf("readSeats() = %v, want both sides of both games", seats)
+	}
+	if seats := readSeats(seat.NewSigner([]byte("other key")), withCookies(w)); len(seats) != 0 {
+		t.Errorf("another key's readSeats() = %v, want none", seats)
+	}
+}
+
+func TestSeatsCookieIgnoresForgedEntries(t *testing.T) {
+	signer := seat.NewSigner([]byte("key"))
+	signed := "game." + signer.Token("game", "X")
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"signed", signed, "X"},
+		{"unsigned", "game.X", ""},
+		{"sides rewritten", "game.XO" + signed[len("game.X"):], ""},
+		{"moved to another game", "other" + signed[len("game"):], ""},
+		{"forged beside a signed one", "other.O-" + signed, "X"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: tt.value})
+		seats := readSeats(signer, r)
+		if seats["game"] != tt.want || seats["other"] != "" {
+			t.Errorf("%s: readSeats() = %v, want game: %q", tt.name, seats, tt.want)
+		}
+	}
+}
+
+func TestForgedSeatsCookieCantChangeSettings(t *testing.T) {
+	games := game.NewService()
+	defer games.Close()
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for cookie, want := range map[string]int{
+		g.ID + ".X":                           http.StatusForbidden,
+		g.ID + "." + h.seats.Token(g.ID, "X"): http.StatusOK,
+	} {
+		r := httptest.NewRequest("PATCH", "/htmx/settings/"+g.ID+"?player=X", nil)
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("cookie %q: status = %d, want %d", cookie, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package htmx
+
+import (
+	"net/http"
+	"strconv"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/models"
+)
+
+// handicapOptions are the handicaps the waiting room offers, as the
+// new-game form's handicap field takes them.
+var handicapOptions = []models.Handicap{
+	{Style: models.HandicapMark, Player: models.PlayerX},
+	{Style: models.HandicapMark, Player: models.PlayerO},
+	{Style: models.HandicapDoubleMove, Player: models.PlayerX},
+	{Style: models.HandicapDoubleMove, Player: models.PlayerO},
+}
+
+// languageName returns the name of a locale in its own language, as the
+// waiting room offers it for the game's, see models.GameSettings.Locale.
+func languageName(lang string) string {
+	l, ok := i18n.Get(lang)
+	if !ok {
+		return lang
+	}
+	return l.T("language.name")
+}
+
+// handleUpdateSettings changes the settings of a game waiting for its
+// opponent from the waiting room's form.
+func (h *Handler) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	g, ok := h.gameService.GetGame(r.Context(), gameID)
+	if !ok {
+		if negotiated(w, r, nil, game.ErrGameNotFound) {
+			return
+		}
+		http.Error(w, game.ErrGameNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if seatOf(readSeats(h.seats, r), g.ID, player) == "" {
+		httpx.WriteErrorStatus(w, r, http.StatusForbidden, game.ErrNotCreator)
+		return
+	}
+
+	settings := g.GameSettings
+	settings.AnalysisLive, _ = strconv.ParseBool(r.FormValue("analysisLive"))
+	settings.ReadyCheck, _ = strconv.ParseBool(r.FormValue("readyCheck"))
+	settings.EarlyDraw, _ = strconv.ParseBool(r.FormValue("earlyDraw"))
+	settings.Locale = r.FormValue("locale")
+	if r.FormValue("handicap") != handicapParam(g) {
+		settings.Handicap = handicapFromRequest(r)
+	}
+	g, err = h.gameService.UpdateSettings(r.Context(), g.ID, models.Player(player), settings)
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionSettings, Player: models.Player(player)}, err)
+	if err == nil {
+		h.hub.Broadcast(r.Context(), g.ID, g)
+	}
+	if negotiated(w, r, g, err) {
+		return
+	}
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	GameWrapper(g, player).Render(r.Context(), w)
+}
@@ -0,0 +1,83 @@
+package htmx
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/status"
+)
+
+// StatusHandler renders status.Report as a table for operators. It
+// registers no routes of its own, as it must be served behind the admin
+// key, see api.AdminHandler.RequireKey.
+type StatusHandler struct {
+	status *status.Collector
+}
+
+// NewStatusHandler creates a new status page handler.
+func NewStatusHandler(collector *status.Collector) *StatusHandler {
+	return &StatusHandler{status: collector}
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	StatusTable(statusRows(h.status.Collect(r.Context()))).Render(r.Context(), w)
+}
+
+// statusRow is one line of the status table.
+type statusRow struct {
+	Name, Value string
+}
+
+// statusRows flattens a report into the rows of StatusTable.
+func statusRows(report status.Report) []statusRow {
+	rows := []statusRow{
+		{"started", report.StartedAt.Format(time.RFC3339)},
+		{"uptime", (time.Duration(report.Uptime) * time.Second).String()},
+		{"go version", report.GoVersion},
+		{"build path", report.Build.Path},
+		{"build version", report.Build.Version},
+		{"build revision", report.Build.Revision},
+	}
+	if !report.Build.Time.IsZero() {
+		rows = append(rows, statusRow{"build time", report.Build.Time.Format(time.RFC3339)})
+	}
+	if report.Build.Modified {
+		rows = append(rows, statusRow{"build modified", "yes"})
+	}
+	for _, s := range []string{game.StatusWaiting, game.StatusPlaying, game.StatusFinished} {
+		rows = append(rows, statusRow{"games " + s, fmt.Sprint(report.Games.Games[s])})
+	}
+	storage := "ok"
+	if !report.Games.Storage.OK {
+		storage = report.Games.Storage.Error
+	}
+	rows = append(rows,
+		statusRow{"storage", storage},
+		statusRow{"storage latency", report.Games.Storage.Latency.String()},
+		statusRow{"expired games", fmt.Sprint(report.Games.Expiry.Expired)},
+	)
+	if !report.Games.Expiry.LastRun.IsZero() {
+		rows = append(rows, statusRow{"expiry last run", report.Games.Expiry.LastRun.Format(time.RFC3339)})
+	}
+	transports := make([]broadcast.Transport, 0, len(report.Hub.Subscribers))
+	for t := range report.Hub.Subscribers {
+		transports = append(transports, t)
+	}
+	slices.Sort(transports)
+	for _, t := range transports {
+		rows = append(rows, statusRow{"subscribers " + string(t), fmt.Sprint(report.Hub.Subscribers[t])})
+	}
+	return append(rows,
+		statusRow{"games watched", fmt.Sprint(report.Hub.Games)},
+		statusRow{"broadcasts", fmt.Sprint(report.Hub.Broadcasts)},
+		statusRow{"events", fmt.Sprint(report.Hub.Events)},
+		statusRow{"dropped", fmt.Sprint(report.Hub.Dropped)},
+		statusRow{"firehose disconnects", fmt.Sprint(report.Hub.FirehoseDisconnects)},
+		statusRow{"policy disconnects", fmt.Sprint(report.Hub.PolicyDisconnects)},
+	)
+}
@@ -0,0 +1,34 @@
+package htmx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+// TestSymbolsAreEscaped renders a board played with markup characters
+// as symbols, which must come out as text.
+func TestSymbolsAreEscaped(t *testing.T) {
+	g := models.NewGameState("abcd1234")
+	g.Mode = models.ModeHotseat
+	g.PlayerXJoined, g.PlayerOJoined = true, true
+	g.XSymbol, g.OSymbol = "<", "&"
+	g.Board[0], g.Board[4] = models.PlayerX, models.PlayerO
+	g.CurrentTurn = models.PlayerX
+
+	var out strings.Builder
+	if err := GameContent(g, "X").Render(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, want := range []string{">&lt;</", ">&amp;</"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("the board has no %s in it:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "><</") || strings.Contains(html, ">&</") {
+		t.Errorf("a symbol was rendered unescaped:\n%s", html)
+	}
+}
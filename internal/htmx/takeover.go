@@ -0,0 +1,21 @@
+package htmx
+
+import "context"
+
+type takeoverKey struct{}
+
+// withTakeover marks a game being rendered for a browser taking its side
+// over from another tab, see broadcast.Admit, so its SSE connection asks
+// to.
+func withTakeover(ctx context.Context) context.Context {
+	return context.WithValue(ctx, takeoverKey{}, true)
+}
+
+// takeoverQuery returns the query parameter a game's SSE connection
+// adds when rendered withTakeover.
+func takeoverQuery(ctx context.Context) string {
+	if takeover, _ := ctx.Value(takeoverKey{}).(bool); takeover {
+		return "&takeover=1"
+	}
+	return ""
+}
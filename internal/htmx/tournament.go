@@ -0,0 +1,75 @@
+package htmx
+
+import (
+	"log/slog"
+	"net/http"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/sse"
+	"tiktaktoes/internal/tournament"
+)
+
+// TournamentHandler serves tournament brackets with SSE updates.
+type TournamentHandler struct {
+	tournaments *tournament.Service
+	hub         *broadcast.Hub
+}
+
+// NewTournamentHandler creates a new tournament handler.
+func NewTournamentHandler(tournaments *tournament.Service, hub *broadcast.Hub) *TournamentHandler {
+	return &TournamentHandler{tournaments: tournaments, hub: hub}
+}
+
+// RegisterRoutes sets up the tournament routes.
+func (h *TournamentHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /htmx/tournament/{id}", h.handleBracket)
+	mux.HandleFunc("GET /htmx/tournament/{id}/sse", h.handleSSE)
+}
+
+func (h *TournamentHandler) handleBracket(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.tournaments.Get(r.PathValue("id"))
+	if !ok {
+		httpx.WriteError(w, r, tournament.ErrNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	TournamentWrapper(t).Render(r.Context(), w)
+}
+
+func (h *TournamentHandler) handleSSE(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := h.tournaments.Get(id); !ok {
+		http.Error(w, tournament.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	defer sw.Close()
+	sw.Heartbeat(sse.DefaultHeartbeat)
+	ctx := logging.WithConnID(r.Context(), logging.NewID())
+	slog.InfoContext(ctx, "sse opened", "tournament_id", id)
+	defer slog.InfoContext(ctx, "sse closed", "tournament_id", id)
+
+	changed, stop := h.tournaments.Subscribe(id)
+	defer stop()
+	for {
+		select {
+		case <-changed:
+			if t, ok := h.tournaments.Get(id); ok {
+				if err := sendEvent(ctx, sw, "bracket-update", "", TournamentContent(t)); err != nil {
+					return
+				}
+			}
+		case <-h.hub.Draining():
+			sw.Send(broadcast.Restart())
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package htmx
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+)
+
+// handleUndoNotice renders the offer to undo the game's last reset or
+// cancellation afresh, which a shown one asks for once its window is
+// over, so it takes itself down.
+func (h *Handler) handleUndoNotice(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	h.renderUndoNotice(w, r, gameID, player)
+}
+
+// renderUndoNotice renders the offer to undo the game's last reset or
+// cancellation, or nothing once it can't be undone or if this browser
+// didn't do it as player.
+func (h *Handler) renderUndoNotice(w http.ResponseWriter, r *http.Request, gameID, player string) {
+	if until, ok := h.gameService.UndoableUntil(r.Context(), gameID, h.heldSeat(r, gameID, player)); ok {
+		UndoNotice(gameID, player, until).Render(r.Context(), w)
+	}
+}
+
+// heldSeat returns player if the seats cookie shows this browser joined
+// as them in the game gameID names, which may have been cancelled, and
+// models.Empty otherwise. Resets and cancellations are undone by the
+// side that made them, see game.Service.UndoReset.
+func (h *Handler) heldSeat(r *http.Request, gameID, player string) models.Player {
+	// A cancelled game is gone, and only found by its full ID
+	gameID = game.NormalizeID(gameID)
+	if g, err := h.gameService.FindGame(r.Context(), gameID); err == nil {
+		gameID = g.ID
+	}
+	return models.Player(seatOf(readSeats(h.seats, r), gameID, player))
+}
+
+// handleUndo puts the game back as it was before its last reset or
+// cancellation, see game.Service.UndoReset, and shows it. A failure is
+// told like a rejected move, or in place of the game if it is gone.
+func (h *Handler) handleUndo(w http.ResponseWriter, r *http.Request) {
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	player, ok := requireSeat(w, r)
+	if !ok {
+		return
+	}
+	g, err := h.gameService.UndoReset(r.Context(), gameID, h.heldSeat(r, gameID, player))
+	h.record(r.Context(), gameID, activity.Entry{Action: activity.ActionUndo, Player: models.Player(player)}, err)
+	if err != nil && !respond.WantsJSON(r) {
+		if _, findErr := h.gameService.FindGame(r.Context(), gameID); findErr != nil {
+			httpx.WriteError(w, r, err)
+			return
+		}
+	}
+	h.renderAction(w, r, gameID, player, g, err)
+}
@@ -0,0 +1,58 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestUndoNeedsTheResettersSeat(t *testing.T) {
+	games := game.NewService()
+	defer games.Close()
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	post := func(path, cookie string) (int, string) {
+		r := httptest.NewRequest("POST", path, nil)
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		var body struct{ Code string }
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Code
+	}
+
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	move := models.Move{Position: 4, Player: models.PlayerX}
+	signed := g.ID + "." + h.seats.Token(g.ID, "XO")
+	forged := g.ID + ".XO"
+
+	// A reset from a forged cookie is nobody's to undo
+	games.MakeMove(t.Context(), g.ID, move)
+	post("/htmx/reset/"+g.ID+"?player=X", forged)
+	if status, code := post("/htmx/undo/"+g.ID+"?player=X", signed); code != "nothing_to_undo" {
+		t.Errorf("undoing a reset from a forged cookie: %d %s, want nothing_to_undo", status, code)
+	}
+
+	games.MakeMove(t.Context(), g.ID, move)
+	post("/htmx/reset/"+g.ID+"?player=X", signed)
+	if status, code := post("/htmx/undo/"+g.ID+"?player=X", forged); code != "not_resetter" {
+		t.Errorf("undoing with a forged cookie: %d %s, want not_resetter", status, code)
+	}
+	if status, code := post("/htmx/undo/"+g.ID+"?player=O", signed); code != "not_resetter" {
+		t.Errorf("undoing as the other side: %d %s, want not_resetter", status, code)
+	}
+	if status, _ := post("/htmx/undo/"+g.ID+"?player=X", signed); status != http.StatusOK {
+		t.Errorf("undoing as the resetter: status = %d, want 200", status)
+	}
+}
@@ -0,0 +1,54 @@
+package htmx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+)
+
+func TestVacateNeedsTheOtherSeat(t *testing.T) {
+	// Any wait at all has passed by the time the request is made
+	games := game.NewService(game.WithVacateAfter(1))
+	defer games.Close()
+	h := NewHandler(games, broadcast.NewHub(), nil, seat.NewSigner([]byte("key")))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	post := func(path, cookie string) (int, string) {
+		r := httptest.NewRequest("POST", path, nil)
+		r.Header.Set("Accept", "application/json")
+		r.AddCookie(&http.Cookie{Name: seatsCookie, Value: cookie})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		var body struct{ Code string }
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Code
+	}
+
+	g, err := games.CreateGame(t.Context(), models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := games.JoinGame(t.Context(), g.ID, models.PlayerO, game.JoinOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	x := g.ID + "." + h.seats.Token(g.ID, "X")
+	o := g.ID + "." + h.seats.Token(g.ID, "O")
+
+	for _, cookie := range []string{"", g.ID + ".X", o} {
+		if status, code := post("/htmx/vacate/"+g.ID+"?player=X", cookie); code != "seat_token" {
+			t.Errorf("vacating O as X with cookie %q: %d %s, want seat_token", cookie, status, code)
+		}
+	}
+	if status, _ := post("/htmx/vacate/"+g.ID+"?player=X", x); status != http.StatusOK {
+		t.Fatalf("vacating O as X: status = %d, want 200", status)
+	}
+	if got, _ := games.GetGame(t.Context(), g.ID); got.PlayerOJoined {
+		t.Error("O is still joined")
+	}
+}
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/errcode"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/puzzle"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/tournament"
+)
+
+// WriteError writes err as the JSON error envelope for the API and the
+// WebSocket, or as the htmx status fragment otherwise.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errcode.FromError(err).Status
+	if status == http.StatusInternalServerError && errors.Is(err, ErrBadParam) {
+		status = http.StatusBadRequest
+	}
+	WriteErrorStatus(w, r, status, err)
+}
+
+// WriteErrorStatus is like WriteError with another status than err's
+// own, for a handler that has a reason to pick one.
+func WriteErrorStatus(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/ws/") || respond.WantsJSON(r) {
+		respond.GameError(w, r, status, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	ErrorStatus(ErrorText(r.Context(), err)).Render(r.Context(), w)
+}
+
+// errorKeys maps errors from outside the game service to their message
+// keys. Game errors are keyed by their code, see game.Code.
+var errorKeys = []struct {
+	err error
+	key string
+}{
+	{puzzle.ErrIllegalMove, "error.puzzle_cell_taken"},
+	{tournament.ErrNotFound, "error.tournament_not_found"},
+}
+
+// ErrorText returns err's message in the locale of ctx. Errors without a
+// translation, such as unexpected internal ones, are shown as is.
+func ErrorText(ctx context.Context, err error) string {
+	var quota *game.QuotaError
+	if errors.As(err, &quota) {
+		return i18n.T(ctx, "error.quota_exceeded_count", quota.Open)
+	}
+	var taken *game.SlugTakenError
+	if errors.As(err, &taken) {
+		return i18n.T(ctx, "error.slug_taken_try", taken.Suggestion)
+	}
+	if code := game.Code(err); code != "" {
+		return i18n.T(ctx, "error."+code)
+	}
+	for _, e := range errorKeys {
+		if errors.Is(err, e.err) {
+			return i18n.T(ctx, e.key)
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,80 @@
+// Package httpx reads strict path parameters and writes errors in the
+// form their route family answers in.
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"tiktaktoes/internal/game"
+)
+
+// MaxGameIDLength is the longest game ID, slug or prefix of either that
+// PathGameID accepts, well over the 36 characters of a generated ID.
+const MaxGameIDLength = 64
+
+// ErrBadGameID is returned for a game ID that can't name any game. It is
+// told to clients as game.ErrGameNotFound, as any other ID naming none.
+var ErrBadGameID = fmt.Errorf("%w: malformed game ID", game.ErrGameNotFound)
+
+// ErrBadParam is wrapped by the errors of malformed path parameters.
+var ErrBadParam = errors.New("malformed path parameter")
+
+// ParamError is returned for a path parameter that isn't what the route
+// takes. It wraps ErrBadParam.
+type ParamError struct {
+	Name     string
+	Value    string
+	Min, Max int
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s %q must be a whole number from %d to %d", e.Name, e.Value, e.Min, e.Max)
+}
+
+func (e *ParamError) Unwrap() error {
+	return ErrBadParam
+}
+
+// PathGameID returns the normalized game ID in r's gameID path parameter,
+// or ErrBadGameID if it couldn't name a game.
+func PathGameID(r *http.Request) (string, error) {
+	id := game.NormalizeID(r.PathValue("gameID"))
+	if id == "" || len(id) > MaxGameIDLength {
+		return "", ErrBadGameID
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return "", ErrBadGameID
+		}
+	}
+	return id, nil
+}
+
+// PathInt returns the plain decimal integer in r's path parameter name,
+// which must be from min to max.
+func PathInt(r *http.Request, name string, min, max int) (int, error) {
+	s := r.PathValue(name)
+	bad := &ParamError{Name: name, Value: s, Min: min, Max: max}
+	digits := s
+	if min < 0 && len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if digits == "" || digits[0] == '0' && len(digits) > 1 || s == "-0" {
+		return 0, bad
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, bad
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, bad
+	}
+	return n, nil
+}
@@ -0,0 +1,242 @@
+package httpx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/respond"
+)
+
+// routedID returns what PathGameID makes of the game ID in rawPath, as
+// the server's mux routes it, and false if the mux doesn't route it.
+func routedID(t *testing.T, rawPath string) (string, error, bool) {
+	t.Helper()
+	var id string
+	var err error
+	routed := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/game/{gameID}", func(w http.ResponseWriter, r *http.Request) {
+		routed = true
+		id, err = httpx.PathGameID(r)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, rawPath, nil))
+	return id, err, routed
+}
+
+func TestPathGameID(t *testing.T) {
+	uuid := "0b6c9f3e-7d1a-4c55-9a53-2f0e8c1d4b7a"
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/game/" + uuid, uuid},
+		{"/api/game/" + strings.ToUpper(uuid), uuid},
+		{"/api/game/0b6c", "0b6c"},
+		{"/api/game/friendly-fox-42", "friendly-fox-42"},
+		{"/api/game/g_1", "g_1"},
+		{"/api/game/%20abcd%09", "abcd"},
+		{"/api/game/%61bcd", "abcd"},
+		{"/api/game/abcd?player=X", "abcd"},
+		{"/api/game/" + strings.Repeat("a", httpx.MaxGameIDLength), strings.Repeat("a", httpx.MaxGameIDLength)},
+
+		{"/api/game/abcd%3F", ""},
+		{"/api/game/abcd%3Fplayer=X", ""},
+		{"/api/game/ab%2Fcd", ""},
+		{"/api/game/ab%23cd", ""},
+		{"/api/game/ab%00cd", ""},
+		{"/api/game/ab.cd", ""},
+		{"/api/game/ab%20cd", ""},
+		{"/api/game/%20", ""},
+		{"/api/game/caf%C3%A9", ""},
+		{"/api/game/%EF%BC%A1bcd", ""},
+		{"/api/game/%FF", ""},
+		{"/api/game/" + strings.Repeat("a", httpx.MaxGameIDLength+1), ""},
+	}
+	for _, tt := range tests {
+		got, err, routed := routedID(t, tt.path)
+		if !routed {
+			t.Errorf("%s: not routed", tt.path)
+			continue
+		}
+		if tt.want == "" {
+			if !errors.Is(err, httpx.ErrBadGameID) || !errors.Is(err, game.ErrGameNotFound) {
+				t.Errorf("%s: PathGameID() = %q, %v, want ErrBadGameID", tt.path, got, err)
+			}
+			continue
+		}
+		if got != tt.want || err != nil {
+			t.Errorf("%s: PathGameID() = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	// The mux itself turns away what has no ID or more than one segment
+	for _, path := range []string{"/api/game/", "/api/game/abcd/", "/api/game/ab/cd"} {
+		if _, _, routed := routedID(t, path); routed {
+			t.Errorf("%s: routed", path)
+		}
+	}
+}
+
+func TestPathGameIDEveryByte(t *testing.T) {
+	for b := range 256 {
+		c := byte(b)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetPathValue("gameID", "ab"+string([]byte{c})+"cd")
+		got, err := httpx.PathGameID(r)
+		lower := c
+		if c >= 'A' && c <= 'Z' {
+			lower = c + 'a' - 'A'
+		}
+		valid := lower >= 'a' && lower <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+		switch {
+		case valid && (err != nil || got != "ab"+string([]byte{lower})+"cd"):
+			t.Errorf("byte %#x: PathGameID() = %q, %v", c, got, err)
+		case !valid && err == nil:
+			t.Errorf("byte %#x: PathGameID() = %q, want an error", c, got)
+		}
+	}
+}
+
+// pathInt calls PathInt with s as the parameter.
+func pathInt(s string, min, max int) (int, error) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetPathValue("n", s)
+	return httpx.PathInt(r, "n", min, max)
+}
+
+func TestPathInt(t *testing.T) {
+	tests := []struct {
+		s        string
+		min, max int
+		want     int
+		ok       bool
+	}{
+		{"0", 0, 8, 0, true},
+		{"8", 0, 8, 8, true},
+		{"9", 0, 8, 0, false},
+		{"-1", 0, 8, 0, false},
+		{"-1", -5, 5, -1, true},
+		{"-5", -5, 5, -5, true},
+		{"-6", -5, 5, 0, false},
+		{"-0", -5, 5, 0, false},
+		{"+5", 0, 8, 0, false},
+		{"05", 0, 8, 0, false},
+		{"00", 0, 8, 0, false},
+		{"-05", -8, 8, 0, false},
+		{"", 0, 8, 0, false},
+		{"-", -8, 8, 0, false},
+		{" 5", 0, 8, 0, false},
+		{"5 ", 0, 8, 0, false},
+		{"5\n", 0, 8, 0, false},
+		{"1e1", 0, 100, 0, false},
+		{"0x1", 0, 100, 0, false},
+		{"1_0", 0, 100, 0, false},
+		{"5.0", 0, 8, 0, false},
+		{"banana", 0, 8, 0, false},
+		{"٥", 0, 8, 0, false}, // Arabic-Indic five
+		{"５", 0, 8, 0, false}, // fullwidth five
+		{"5́", 0, 8, 0, false},
+		{"99999999999999999999", 0, 8, 0, false},
+		{"-99999999999999999999", -8, 8, 0, false},
+		{"9223372036854775807", 0, 1 << 62, 0, false},
+		{"9223372036854775807", 0, 1<<63 - 1, 1<<63 - 1, true},
+	}
+	for _, tt := range tests {
+		got, err := pathInt(tt.s, tt.min, tt.max)
+		if tt.ok {
+			if got != tt.want || err != nil {
+				t.Errorf("PathInt(%q, %d, %d) = %d, %v, want %d", tt.s, tt.min, tt.max, got, err, tt.want)
+			}
+			continue
+		}
+		var bad *httpx.ParamError
+		if !errors.As(err, &bad) || !errors.Is(err, httpx.ErrBadParam) || bad.Name != "n" || bad.Value != tt.s {
+			t.Errorf("PathInt(%q, %d, %d) = %d, %v, want a ParamError", tt.s, tt.min, tt.max, got, err)
+		}
+	}
+}
+
+// TestPathIntShortStrings checks every string of up to two bytes: each is
+// read exactly when it is the plain decimal spelling of a number in
+// range.
+func TestPathIntShortStrings(t *testing.T) {
+	const min, max = -9, 42
+	check := func(s string) {
+		got, err := pathInt(s, min, max)
+		n, convErr := strconv.Atoi(s)
+		canonical := convErr == nil && strconv.Itoa(n) == s && n >= min && n <= max
+		switch {
+		case canonical && (err != nil || got != n):
+			t.Errorf("PathInt(%q) = %d, %v, want %d", s, got, err, n)
+		case !canonical && err == nil:
+			t.Errorf("PathInt(%q) = %d, want an error", s, got)
+		}
+	}
+	check("")
+	for a := range 256 {
+		check(string([]byte{byte(a)}))
+		for b := range 256 {
+			check(string([]byte{byte(a), byte(b)}))
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		err    error
+		status int
+		json   bool
+		code   string
+	}{
+		{"api", "/api/game/abcd", "", game.ErrNotYourTurn, http.StatusConflict, true, "not_your_turn"},
+		{"ws", "/ws/abcd", "", httpx.ErrBadGameID, http.StatusNotFound, true, "game_not_found"},
+		{"htmx", "/htmx/move/abcd/4", "", game.ErrNotYourTurn, http.StatusConflict, false, ""},
+		{"htmx asking for JSON", "/htmx/move/abcd/4", "application/json", game.ErrNotYourTurn, http.StatusConflict, true, "not_your_turn"},
+		{"bad parameter", "/api/thing/x", "", &httpx.ParamError{Name: "n", Value: "x", Max: 8}, http.StatusBadRequest, true, ""},
+		{"unexpected", "/htmx/game/abcd", "", errors.New("disk on fire"), http.StatusInternalServerError, false, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		httpx.WriteError(w, r, tt.err)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if !tt.json {
+			if ct := w.Header().Get("Content-Type"); ct != "text/html" || !strings.Contains(w.Body.String(), `id="status"`) {
+				t.Errorf("%s: got %s %q, want the status fragment", tt.name, ct, w.Body)
+			}
+			continue
+		}
+		var body respond.ErrorBody
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v in %q", tt.name, err, w.Body)
+		}
+		if body.Code != tt.code || body.Error != tt.err.Error() {
+			t.Errorf("%s: got %+v, want code %q", tt.name, body, tt.code)
+		}
+	}
+}
+
+func TestWriteErrorStatus(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPatch, "/htmx/settings/abcd", nil)
+	w := httptest.NewRecorder()
+	httpx.WriteErrorStatus(w, r, http.StatusForbidden, game.ErrNotCreator)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status %d, want 403", w.Code)
+	}
+}
@@ -0,0 +1,138 @@
+// Package i18n translates server-rendered text from embedded JSON
+// bundles, falling back to English for a missing key.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Default is the locale used when nothing better is negotiated, and the
+// one missing messages fall back to.
+const Default = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// bundles maps locale names to their messages.
+var bundles = loadBundles()
+
+// missing records which locale and key pairs have been logged.
+var missing sync.Map
+
+func loadBundles() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]map[string]string, len(entries))
+	for _, e := range entries {
+		data, err := localeFiles.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", e.Name(), err))
+		}
+		loaded[strings.TrimSuffix(e.Name(), path.Ext(e.Name()))] = messages
+	}
+	if _, ok := loaded[Default]; !ok {
+		panic("i18n: no bundle for the default locale")
+	}
+	return loaded
+}
+
+// Supported returns the names of the locales with a bundle, sorted.
+func Supported() []string {
+	names := make([]string, 0, len(bundles))
+	for name := range bundles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Localizer translates messages into one locale.
+type Localizer struct {
+	lang     string
+	messages map[string]string
+}
+
+// Get returns the localizer for lang, or false if there's no bundle
+// for it.
+func Get(lang string) (*Localizer, bool) {
+	messages, ok := bundles[lang]
+	if !ok {
+		return nil, false
+	}
+	return &Localizer{lang: lang, messages: messages}, true
+}
+
+var english, _ = Get(Default)
+
+// Lang returns the locale name, such as "en".
+func (l *Localizer) Lang() string {
+	return l.lang
+}
+
+// T returns the message for key formatted with args. A message missing
+// from the locale is taken from the default one; one missing from that
+// too comes out as the key itself.
+func (l *Localizer) T(key string, args ...any) string {
+	format, ok := l.messages[key]
+	if !ok {
+		l.reportMissing(key)
+		if format, ok = english.messages[key]; !ok {
+			english.reportMissing(key)
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// reportMissing logs a missing message the first time it's asked for.
+func (l *Localizer) reportMissing(key string) {
+	if _, seen := missing.LoadOrStore(l.lang+"\x00"+key, true); !seen {
+		slog.Warn("missing translation", "lang", l.lang, "key", key)
+	}
+}
+
+type contextKey struct{}
+
+// With returns a context carrying the localizer.
+func With(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// WithLang returns a context translating into lang, such as a game's own
+// locale, or ctx as it is when lang has no bundle.
+func WithLang(ctx context.Context, lang string) context.Context {
+	if l, ok := Get(lang); ok {
+		return With(ctx, l)
+	}
+	return ctx
+}
+
+// From returns the localizer stored in ctx, or the default locale's.
+func From(ctx context.Context) *Localizer {
+	if l, ok := ctx.Value(contextKey{}).(*Localizer); ok {
+		return l
+	}
+	return english
+}
+
+// T translates key into the locale of ctx, see Localizer.T.
+func T(ctx context.Context, key string, args ...any) string {
+	return From(ctx).T(key, args...)
+}
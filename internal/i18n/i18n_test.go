@@ -0,0 +1,140 @@
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// verbs finds the fmt verbs of a message.
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestBundlesMatch(t *testing.T) {
+	for lang, messages := range bundles {
+		for key, format := range bundles[Default] {
+			translated, ok := messages[key]
+			if !ok {
+				t.Errorf("%s has no %q", lang, key)
+				continue
+			}
+			if got, want := verbs.FindAllString(translated, -1), verbs.FindAllString(format, -1); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s %q formats %v, English %v", lang, key, got, want)
+			}
+		}
+		for key := range messages {
+			if _, ok := bundles[Default][key]; !ok {
+				t.Errorf("%s has %q, which English doesn't", lang, key)
+			}
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	// A locale with one message of its own
+	bundles["xx"] = map[string]string{"button.watch": "xx-watch"}
+	defer delete(bundles, "xx")
+	xx, _ := Get("xx")
+
+	tests := []struct {
+		key  string
+		args []any
+		want string
+	}{
+		{"button.watch", nil, "xx-watch"},
+		{"join.not_found", nil, bundles[Default]["join.not_found"]},
+		{"join.slot_taken", []any{"O"}, fmt.Sprintf(bundles[Default]["join.slot_taken"], "O")},
+		{"no.such.key", nil, "no.such.key"},
+	}
+	for range 2 {
+		for _, tt := range tests {
+			if got := xx.T(tt.key, tt.args...); got != tt.want {
+				t.Errorf("T(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		}
+	}
+	// Each miss is logged once however often it is asked for, and a key
+	// English lacks too is logged for English as well
+	for _, want := range []string{"lang=xx key=join.not_found", "lang=xx key=join.slot_taken", "lang=xx key=no.such.key", "lang=en key=no.such.key"} {
+		if n := strings.Count(logs.String(), want); n != 1 {
+			t.Errorf("%q logged %d times:\n%s", want, n, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "button.watch") {
+		t.Errorf("a translated message was logged as missing:\n%s", logs.String())
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9", "es"},
+		{"fr-FR, es;q=0.8, en;q=0.9", "en"},
+		{"fr, de", "en"},
+		{"EN-gb;q=0.5, ES;q=0.5", "en"},
+		{"es;q=bad, en;q=0.1", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, T(r.Context(), "button.watch"))
+	}))
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+		accept string
+		want   string
+		set    string
+	}{
+		{"by default", "", "", "", "watch", ""},
+		{"by header", "", "", "es-ES", "mirar", ""},
+		{"by cookie over the header", "", "en", "es", "watch", ""},
+		{"by parameter over both", "?lang=ES", "en", "en", "mirar", "es"},
+		{"unsupported parameter", "?lang=fr", "es", "", "mirar", ""},
+		{"unsupported cookie", "", "fr", "es", "mirar", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+		if tt.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: Cookie, Value: tt.cookie})
+		}
+		r.Header.Set("Accept-Language", tt.accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+		set := ""
+		for _, c := range w.Result().Cookies() {
+			if c.Name == Cookie {
+				set = c.Value
+			}
+		}
+		if set != tt.set {
+			t.Errorf("%s: set cookie %q, want %q", tt.name, set, tt.set)
+		}
+	}
+
+	if got := T(context.Background(), "button.watch"); got != "watch" {
+		t.Errorf("without a localizer: %q, want English", got)
+	}
+}
@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tiktaktoes/internal/urls"
+)
+
+// Cookie names the cookie remembering a locale picked with ?lang=.
+const Cookie = "lang"
+
+// cookieMaxAge keeps the choice for a year.
+const cookieMaxAge = 365 * 24 * 60 * 60
+
+// Middleware picks the locale of each request and stores its localizer
+// in the context. A supported ?lang= parameter wins and is remembered in
+// a cookie for later requests, then comes that cookie, then the
+// Accept-Language header.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := fromRequest(w, r)
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), l)))
+	})
+}
+
+func fromRequest(w http.ResponseWriter, r *http.Request) *Localizer {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		if l, ok := Get(strings.ToLower(lang)); ok {
+			http.SetCookie(w, &http.Cookie{
+				Name:     Cookie,
+				Value:    l.Lang(),
+				Path:     urls.Path(r.Context(), "/"),
+				MaxAge:   cookieMaxAge,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+			return l
+		}
+	}
+	if c, err := r.Cookie(Cookie); err == nil {
+		if l, ok := Get(c.Value); ok {
+			return l
+		}
+	}
+	l, _ := Get(Negotiate(r.Header.Get("Accept-Language")))
+	return l
+}
+
+// Negotiate returns the supported locale an Accept-Language header
+// prefers, or Default. Region subtags are ignored, so "es-MX" picks
+// "es".
+func Negotiate(header string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := bundles[lang]; !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// Earlier entries win ties
+		if q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
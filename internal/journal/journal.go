@@ -0,0 +1,374 @@
+// Package journal implements an append-only log of game state changes,
+// split into segment files named by the sequence number of their first entry.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// SyncPolicy controls when appended entries are fsynced.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs after every entry.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs periodically in the background.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	segmentPrefix = "journal-"
+	segmentSuffix = ".jsonl"
+
+	defaultMaxSegmentSize = 16 << 20
+	defaultSyncInterval   = time.Second
+)
+
+// Entry is one journaled state change.
+type Entry struct {
+	Seq   uint64            `json:"seq"`
+	At    time.Time         `json:"at"`
+	Event string            `json:"event"`
+	Game  *models.GameState `json:"game"`
+}
+
+// Options configures a Journal.
+type Options struct {
+	// Dir holds the segment files. It is created if missing.
+	Dir string
+	// Sync is the fsync policy. Defaults to SyncAlways.
+	Sync SyncPolicy
+	// SyncInterval is used with SyncInterval. Defaults to one second.
+	SyncInterval time.Duration
+	// MaxSegmentSize is the size in bytes after which a new segment is
+	// started. Defaults to 16 MiB.
+	MaxSegmentSize int64
+}
+
+// Journal appends entries to segment files. It is safe for concurrent use.
+type Journal struct {
+	opts Options
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	lastSeq uint64
+	dirty   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ParseSyncPolicy parses a sync policy name.
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncInterval, SyncNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown journal sync policy %q", s)
+}
+
+// Open opens the journal in opts.Dir, continuing after the last entry
+// already on disk.
+func Open(opts Options) (*Journal, error) {
+	if opts.Sync == "" {
+		opts.Sync = SyncAlways
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if opts.MaxSegmentSize <= 0 {
+		opts.MaxSegmentSize = defaultMaxSegmentSize
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	j := &Journal{opts: opts}
+	err := Replay(opts.Dir, 0, func(e Entry) error {
+		j.lastSeq = e.Seq
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := j.openSegment(); err != nil {
+		return nil, err
+	}
+
+	if opts.Sync == SyncInterval {
+		j.stop = make(chan struct{})
+		j.done = make(chan struct{})
+		go j.syncLoop()
+	}
+	return j, nil
+}
+
+// Append writes an entry for the given game state.
+func (j *Journal) Append(event string, game *models.GameState) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return errors.New("journal is closed")
+	}
+
+	entry := Entry{
+		Seq:   j.lastSeq + 1,
+		At:    time.Now(),
+		Event: event,
+		Game:  game,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if j.size > 0 && j.size+int64(len(line)) > j.opts.MaxSegmentSize {
+		if err := j.rotate(entry.Seq); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.file.Write(line)
+	j.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if j.opts.Sync == SyncAlways {
+		if err := j.file.Sync(); err != nil {
+			return err
+		}
+	} else {
+		j.dirty = true
+	}
+	j.lastSeq = entry.Seq
+	return nil
+}
+
+// LastSeq returns the sequence number of the last appended entry.
+func (j *Journal) LastSeq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.lastSeq
+}
+
+// Compact deletes segments whose entries are all at or below seq, e.g.
+// because a snapshot covering them has been written. The active segment
+// is never deleted.
+func (j *Journal) Compact(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	segments, err := listSegments(j.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(segments); i++ {
+		// A segment ends right before the next one starts
+		if segments[i+1].first-1 > seq {
+			break
+		}
+		if err := os.Remove(segments[i].path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close syncs and closes the journal.
+func (j *Journal) Close() error {
+	if j.stop != nil {
+		close(j.stop)
+		<-j.done
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	err := errors.Join(j.file.Sync(), j.file.Close())
+	j.file = nil
+	return err
+}
+
+func (j *Journal) syncLoop() {
+	defer close(j.done)
+	ticker := time.NewTicker(j.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			j.mu.Lock()
+			if j.dirty && j.file != nil {
+				if err := j.file.Sync(); err != nil {
+					slog.Error("journal sync failed", "error", err)
+				}
+				j.dirty = false
+			}
+			j.mu.Unlock()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// openSegment opens the newest segment for appending, creating the first
+// one if the directory is empty. Must be called with the lock held.
+func (j *Journal) openSegment() error {
+	segments, err := listSegments(j.opts.Dir)
+	if err != nil {
+		return err
+	}
+	path := segmentPath(j.opts.Dir, j.lastSeq+1)
+	if len(segments) > 0 {
+		path = segments[len(segments)-1].path
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	size := info.Size()
+
+	// Terminate a line left partial by a crash so the next entry starts
+	// on its own line; replay skips the partial one.
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, size-1); err == nil && last[0] != '\n' {
+			n, err := file.Write([]byte{'\n'})
+			size += int64(n)
+			if err != nil {
+				file.Close()
+				return err
+			}
+		}
+	}
+
+	j.file = file
+	j.size = size
+	return nil
+}
+
+// rotate closes the active segment and starts a new one whose first entry
+// is first. Must be called with the lock held.
+func (j *Journal) rotate(first uint64) error {
+	if err := errors.Join(j.file.Sync(), j.file.Close()); err != nil {
+		return err
+	}
+	j.file = nil
+
+	file, err := os.OpenFile(segmentPath(j.opts.Dir, first), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.file = file
+	j.size = 0
+	j.dirty = false
+	return nil
+}
+
+// Replay calls fn for every entry with a sequence number above after, in
+// order. A truncated final line, as left by a crash mid-write, is skipped.
+func Replay(dir string, after uint64, fn func(Entry) error) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+	for i, seg := range segments {
+		// Skip segments that end before the entries we want
+		if i+1 < len(segments) && segments[i+1].first-1 <= after {
+			continue
+		}
+		if err := replaySegment(seg.path, after, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replaySegment(path string, after uint64, fn func(Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn("skipping unreadable journal entry", "path", path, "line", line, "error", err)
+			continue
+		}
+		if entry.Seq <= after {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type segment struct {
+	path  string
+	first uint64
+}
+
+// listSegments returns the segment files in dir ordered by first entry.
+func listSegments(dir string) ([]segment, error) {
+	names, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, e := range names {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{path: filepath.Join(dir, name), first: first})
+	}
+	sort.Slice(segments, func(a, b int) bool {
+		return segments[a].first < segments[b].first
+	})
+	return segments, nil
+}
+
+func segmentPath(dir string, first uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", segmentPrefix, first, segmentSuffix))
+}
@@ -0,0 +1,137 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tiktaktoes/internal/journal"
+	"tiktaktoes/internal/models"
+)
+
+// open opens a journal in dir, closed when the test ends.
+func open(t *testing.T, opts journal.Options) *journal.Journal {
+	t.Helper()
+	j, err := journal.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+// appendN appends n entries for games "g1", "g2" and so on.
+func appendN(t *testing.T, j *journal.Journal, n int) {
+	t.Helper()
+	for i := range n {
+		g := models.NewGameState("g" + string(rune('1'+i)))
+		if err := j.Append("create", g); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// replayed returns the sequence numbers Replay gives after seq.
+func replayed(t *testing.T, dir string, after uint64) []uint64 {
+	t.Helper()
+	var seqs []uint64
+	err := journal.Replay(dir, after, func(e journal.Entry) error {
+		seqs = append(seqs, e.Seq)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seqs
+}
+
+func TestAppendReplay(t *testing.T) {
+	dir := t.TempDir()
+	j := open(t, journal.Options{Dir: dir})
+	appendN(t, j, 3)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening goes on after the last entry
+	j = open(t, journal.Options{Dir: dir})
+	if j.LastSeq() != 3 {
+		t.Fatalf("reopened at %d, want 3", j.LastSeq())
+	}
+	appendN(t, j, 1)
+	if got := replayed(t, dir, 1); len(got) != 3 || got[0] != 2 || got[2] != 4 {
+		t.Errorf("replayed %v after 1, want 2 to 4", got)
+	}
+	var games []string
+	journal.Replay(dir, 0, func(e journal.Entry) error {
+		games = append(games, e.Game.ID)
+		return nil
+	})
+	if len(games) != 4 || games[0] != "g1" || games[3] != "g1" {
+		t.Errorf("replayed games %v", games)
+	}
+}
+
+// TestReplaySkipsTruncatedLine leaves half an entry at the end of the
+// journal, as a crash mid-write does, and checks it is skipped and the
+// journal goes on on a line of its own.
+func TestReplaySkipsTruncatedLine(t *testing.T) {
+	dir := t.TempDir()
+	j := open(t, journal.Options{Dir: dir})
+	appendN(t, j, 2)
+	j.Close()
+	segments, _ := filepath.Glob(filepath.Join(dir, "journal-*.jsonl"))
+	f, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":3,"event":"cre`)
+	f.Close()
+
+	j = open(t, journal.Options{Dir: dir})
+	if j.LastSeq() != 2 {
+		t.Fatalf("reopened at %d, want 2", j.LastSeq())
+	}
+	appendN(t, j, 1)
+	if got := replayed(t, dir, 0); len(got) != 3 || got[2] != 3 {
+		t.Errorf("replayed %v, want 1 to 3", got)
+	}
+}
+
+func TestRotateAndCompact(t *testing.T) {
+	dir := t.TempDir()
+	// Every entry is bigger than a segment, so each gets its own
+	j := open(t, journal.Options{Dir: dir, MaxSegmentSize: 1, Sync: journal.SyncNever})
+	appendN(t, j, 4)
+	segments, _ := filepath.Glob(filepath.Join(dir, "journal-*.jsonl"))
+	if len(segments) != 4 {
+		t.Fatalf("%d segments, want 4", len(segments))
+	}
+
+	if err := j.Compact(2); err != nil {
+		t.Fatal(err)
+	}
+	segments, _ = filepath.Glob(filepath.Join(dir, "journal-*.jsonl"))
+	if len(segments) != 2 {
+		t.Errorf("%d segments after compacting up to 2, want 2", len(segments))
+	}
+	if got := replayed(t, dir, 2); len(got) != 2 || got[0] != 3 {
+		t.Errorf("replayed %v after compacting, want 3 and 4", got)
+	}
+	// The active segment stays, however far the compaction goes
+	j.Compact(10)
+	if got := replayed(t, dir, 0); len(got) != 1 || got[0] != 4 {
+		t.Errorf("replayed %v after compacting everything, want 4", got)
+	}
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for _, s := range []string{"always", "interval", "never"} {
+		if p, err := journal.ParseSyncPolicy(s); err != nil || string(p) != s {
+			t.Errorf("ParseSyncPolicy(%q) = %q, %v", s, p, err)
+		}
+	}
+	if _, err := journal.ParseSyncPolicy("sometimes"); err == nil {
+		t.Error("parsed sync policy sometimes")
+	}
+}
@@ -0,0 +1,83 @@
+// Package logging carries request and connection IDs through contexts
+// and adds them to every slog record logged with that context.
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	connIDKey
+)
+
+// NewID returns a fresh random ID suitable for requests and connections.
+func NewID() string {
+	return uuid.New().String()
+}
+
+// ValidID reports whether id is one NewID could have returned, for IDs
+// handed back by clients.
+func ValidID(id string) bool {
+	u, err := uuid.Parse(id)
+	return err == nil && u.String() == id
+}
+
+// WithRequestID returns a context carrying the given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithConnID returns a context carrying the given WS/SSE connection ID.
+func WithConnID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, connIDKey, id)
+}
+
+// ConnID returns the connection ID stored in ctx, or "".
+func ConnID(ctx context.Context) string {
+	id, _ := ctx.Value(connIDKey).(string)
+	return id
+}
+
+// Handler is a slog.Handler that adds the request and connection IDs
+// found in the record's context.
+type Handler struct {
+	slog.Handler
+}
+
+// NewHandler wraps h so that records include context IDs.
+func NewHandler(h slog.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+// Handle adds request_id and conn_id attributes before delegating.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := ConnID(ctx); id != "" {
+		r.AddAttrs(slog.String("conn_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a new Handler whose underlying handler has attrs.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a new Handler whose underlying handler has the group.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,106 @@
+// Package metrics exports server metrics in the Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+
+	"tiktaktoes/internal/broadcast"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tiktaktoes"
+
+// Handler serves the hub's counters alongside the standard Go runtime and
+// process metrics. Each call builds its own registry, so several servers
+// in one process don't collide.
+func Handler(hub *broadcast.Hub) http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newHubCollector(hub),
+	)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// hubCollector reads broadcast.Hub.Stats on every scrape. Per-game
+// figures are left out to keep label cardinality bounded.
+type hubCollector struct {
+	hub *broadcast.Hub
+
+	broadcasts  *prometheus.Desc
+	events      *prometheus.Desc
+	dropped     *prometheus.Desc
+	disconnects *prometheus.Desc
+	policy      *prometheus.Desc
+	subscribers *prometheus.Desc
+	games       *prometheus.Desc
+	lag         *prometheus.Desc
+	undelivered *prometheus.Desc
+	queued      *prometheus.Desc
+	coalesced   *prometheus.Desc
+	retained    *prometheus.Desc
+}
+
+func newHubCollector(hub *broadcast.Hub) *hubCollector {
+	name := func(n string) string { return prometheus.BuildFQName(namespace, "hub", n) }
+	return &hubCollector{
+		hub:         hub,
+		broadcasts:  prometheus.NewDesc(name("broadcasts_total"), "Game state updates broadcast to subscribers.", nil, nil),
+		events:      prometheus.NewDesc(name("events_total"), "Targeted events sent to some of a game's subscribers.", nil, nil),
+		dropped:     prometheus.NewDesc(name("dropped_total"), "Messages dropped because an SSE client's buffer was full.", nil, nil),
+		disconnects: prometheus.NewDesc(name("firehose_disconnects_total"), "Firehose subscribers disconnected for falling behind.", nil, nil),
+		policy:      prometheus.NewDesc(name("policy_disconnects_total"), "WebSocket clients disconnected for flooding or oversized messages.", nil, nil),
+		subscribers: prometheus.NewDesc(name("subscribers"), "Connected subscribers by transport.", []string{"transport"}, nil),
+		games:       prometheus.NewDesc(name("games"), "Games with at least one connected subscriber.", nil, nil),
+		lag:         prometheus.NewDesc(name("delivery_lag_seconds"), "Time from the hub taking a game event to writing it to a subscriber, by transport.", []string{"transport"}, nil),
+		undelivered: prometheus.NewDesc(name("delivery_dropped_total"), "Game events a subscriber missed, by transport: full SSE buffers and failed WebSocket writes.", []string{"transport"}, nil),
+		queued:      prometheus.NewDesc(name("queued"), "Sends waiting for their games' dispatchers.", nil, nil),
+		coalesced:   prometheus.NewDesc(name("coalesced_total"), "Sends dropped from a full game queue, mostly game states a newer one superseded.", nil, nil),
+		retained:    prometheus.NewDesc(name("retained_games"), "Games the hub holds an entry for, by per-game index.", []string{"index"}, nil),
+	}
+}
+
+func (c *hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.broadcasts
+	ch <- c.events
+	ch <- c.dropped
+	ch <- c.disconnects
+	ch <- c.policy
+	ch <- c.subscribers
+	ch <- c.games
+	ch <- c.lag
+	ch <- c.undelivered
+	ch <- c.queued
+	ch <- c.coalesced
+	ch <- c.retained
+}
+
+func (c *hubCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.hub.Stats()
+	ch <- prometheus.MustNewConstMetric(c.broadcasts, prometheus.CounterValue, float64(stats.Broadcasts))
+	ch <- prometheus.MustNewConstMetric(c.events, prometheus.CounterValue, float64(stats.Events))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.disconnects, prometheus.CounterValue, float64(stats.FirehoseDisconnects))
+	ch <- prometheus.MustNewConstMetric(c.policy, prometheus.CounterValue, float64(stats.PolicyDisconnects))
+	for transport, n := range stats.Subscribers {
+		ch <- prometheus.MustNewConstMetric(c.subscribers, prometheus.GaugeValue, float64(n), string(transport))
+	}
+	ch <- prometheus.MustNewConstMetric(c.games, prometheus.GaugeValue, float64(len(stats.Games)))
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(stats.Queued))
+	ch <- prometheus.MustNewConstMetric(c.coalesced, prometheus.CounterValue, float64(stats.Coalesced))
+	for index, n := range stats.Retained {
+		ch <- prometheus.MustNewConstMetric(c.retained, prometheus.GaugeValue, float64(n), string(index))
+	}
+	for transport, lag := range stats.Delivery {
+		buckets := make(map[float64]uint64, len(lag.Buckets))
+		for _, b := range lag.Buckets {
+			buckets[b.UpperBound] = b.Count
+		}
+		ch <- prometheus.MustNewConstHistogram(c.lag, lag.Count, lag.Sum, buckets, string(transport))
+		ch <- prometheus.MustNewConstMetric(c.undelivered, prometheus.CounterValue, float64(lag.Dropped), string(transport))
+	}
+}
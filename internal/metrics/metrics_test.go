@@ -0,0 +1,53 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/metrics"
+	"tiktaktoes/internal/models"
+)
+
+func TestHandler(t *testing.T) {
+	hub := broadcast.NewHub()
+	defer hub.Close()
+	ch := make(chan broadcast.Message, 4)
+	hub.RegisterSSE("game", ch, broadcast.NewSubscriber(models.PlayerX, "x"))
+	defer hub.UnregisterSSE("game", ch)
+	hub.Broadcast(context.Background(), "game", &models.GameState{ID: "game"})
+	hub.Broadcast(context.Background(), "game", &models.GameState{ID: "game"})
+	// Written as if 30ms after the hub took it
+	msg := <-ch
+	hub.RecordDelivery(broadcast.TransportSSE, msg.Created.Add(-30*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	metrics.Handler(hub).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	for _, want := range []string{
+		"tiktaktoes_hub_broadcasts_total 2\n",
+		"tiktaktoes_hub_events_total 0\n",
+		`tiktaktoes_hub_subscribers{transport="sse"} 1` + "\n",
+		`tiktaktoes_hub_subscribers{transport="ws"} 0` + "\n",
+		"tiktaktoes_hub_games 1\n",
+		`tiktaktoes_hub_delivery_lag_seconds_bucket{transport="sse",le="0.025"} 0` + "\n",
+		`tiktaktoes_hub_delivery_lag_seconds_bucket{transport="sse",le="0.05"} 1` + "\n",
+		`tiktaktoes_hub_delivery_lag_seconds_count{transport="sse"} 1` + "\n",
+		`tiktaktoes_hub_delivery_lag_seconds_count{transport="ws"} 0` + "\n",
+		`tiktaktoes_hub_delivery_dropped_total{transport="sse"} 0` + "\n",
+		`tiktaktoes_hub_retained_games{index="clients"} 1` + "\n",
+		"go_goroutines ",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("no %q in the metrics:\n%s", want, body)
+		}
+	}
+	// Per-game figures would give a series per game
+	if strings.Contains(string(body), `"game"`) {
+		t.Error("the metrics are labelled by game")
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidBoard is returned for compact boards that can't be parsed
+var ErrInvalidBoard = errors.New("invalid board")
+
+// emptyMark is the compact form of an empty cell
+const emptyMark = '.'
+
+// String returns the compact form of the board: one character per cell,
+// X, O or "." for empty, with rows separated by "/" like FEN. The empty
+// board is "..././...".
+func (b Board) String() string {
+	var sb strings.Builder
+	sb.Grow(len(b) + BoardSize - 1)
+	for i, cell := range b {
+		if i > 0 && i%BoardSize == 0 {
+			sb.WriteByte('/')
+		}
+		if cell == Empty {
+			sb.WriteByte(emptyMark)
+		} else {
+			sb.WriteString(string(cell))
+		}
+	}
+	return sb.String()
+}
+
+// ParseBoard parses the compact form produced by Board.String. The row
+// separators may be left out, so "X.O..X.O." is accepted as well.
+// Marks are case-insensitive.
+func ParseBoard(s string) (Board, error) {
+	var b Board
+
+	cells := s
+	if strings.Contains(s, "/") {
+		rows := strings.Split(s, "/")
+		if len(rows) != BoardSize {
+			return b, fmt.Errorf("%w %q: expected %d rows, got %d", ErrInvalidBoard, s, BoardSize, len(rows))
+		}
+		for i, row := range rows {
+			if n := utf8.RuneCountInString(row); n != BoardSize {
+				return b, fmt.Errorf("%w %q: row %d has %d cells, expected %d", ErrInvalidBoard, s, i+1, n, BoardSize)
+			}
+		}
+		cells = strings.Join(rows, "")
+	}
+	marks := []rune(cells)
+	if len(marks) != len(b) {
+		return b, fmt.Errorf("%w %q: expected %d cells, got %d", ErrInvalidBoard, s, len(b), len(marks))
+	}
+
+	for i, mark := range marks {
+		switch mark {
+		case 'X', 'x':
+			b[i] = PlayerX
+		case 'O', 'o':
+			b[i] = PlayerO
+		case emptyMark:
+			b[i] = Empty
+		default:
+			return b, fmt.Errorf("%w %q: unexpected %q at cell %s", ErrInvalidBoard, s, mark, CellName(i, BoardSize))
+		}
+	}
+	return b, nil
+}
+
+// gameStateJSON has GameState's fields without its JSON methods
+type gameStateJSON GameState
+
+// MarshalJSON adds the compact boardCompact field next to the board array
+func (g GameState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		gameStateJSON
+		BoardCompact string `json:"boardCompact"`
+	}{gameStateJSON(g), g.Board.String()})
+}
+
+// UnmarshalJSON accepts the board as either the board array or the
+// compact boardCompact string. When both are given they must agree.
+func (g *GameState) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*gameStateJSON
+		Board        *Board `json:"board"`
+		BoardCompact string `json:"boardCompact"`
+	}{gameStateJSON: (*gameStateJSON)(g)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	switch {
+	case aux.Board != nil:
+		g.Board = *aux.Board
+		if aux.BoardCompact != "" {
+			compact, err := ParseBoard(aux.BoardCompact)
+			if err != nil {
+				return err
+			}
+			if compact != g.Board {
+				return fmt.Errorf("%w: board and boardCompact disagree", ErrInvalidBoard)
+			}
+		}
+	case aux.BoardCompact != "":
+		board, err := ParseBoard(aux.BoardCompact)
+		if err != nil {
+			return err
+		}
+		g.Board = board
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestBoardStringRoundTrip(t *testing.T) {
+	var b models.Board
+	if s := b.String(); s != ".../.../..." {
+		t.Errorf("the empty board is %q", s)
+	}
+	b[0], b[2], b[4], b[8] = models.PlayerX, models.PlayerO, models.PlayerX, models.PlayerO
+	if s := b.String(); s != "X.O/.X./..O" {
+		t.Errorf("the board is %q", s)
+	}
+	for _, s := range []string{"X.O/.X./..O", "X.O.X...O", "x.o/.x./..o"} {
+		if got, err := models.ParseBoard(s); err != nil || got != b {
+			t.Errorf("ParseBoard(%q) = %s, %v, want %s", s, got, err, b)
+		}
+	}
+}
+
+func TestParseBoardRejects(t *testing.T) {
+	tests := []struct {
+		board string
+		why   string
+	}{
+		{"", "expected 9 cells, got 0"},
+		{"X.O.X...", "expected 9 cells, got 8"},
+		{"X.O.X...OX", "expected 9 cells, got 10"},
+		{"X.O/.X./..O/...", "expected 3 rows, got 4"},
+		{"X.O/.X/...O", "row 2 has 2 cells"},
+		{"X.O/.X./..Z", `unexpected 'Z' at cell c3`},
+		{"X-O.X...O", `unexpected '-' at cell b1`},
+		{"X O.X...O", `unexpected ' ' at cell b1`},
+		{"XéO.X..O", "expected 9 cells, got 8"},
+		{"XéO.X...O", `unexpected 'é' at cell b1`},
+		{"X.O/.é./..O", `unexpected 'é' at cell b2`},
+	}
+	for _, tt := range tests {
+		_, err := models.ParseBoard(tt.board)
+		if !errors.Is(err, models.ErrInvalidBoard) || !strings.Contains(err.Error(), tt.why) {
+			t.Errorf("ParseBoard(%q) err = %v, want ErrInvalidBoard saying %q", tt.board, err, tt.why)
+		}
+	}
+}
+
+func TestGameStateJSONBoard(t *testing.T) {
+	g := models.NewGameState("abcd1234")
+	g.Board[4] = models.PlayerX
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+	if fields["boardCompact"] != ".../.X./..." || fields["board"] == nil {
+		t.Errorf("marshalled the board as %v and %v", fields["board"], fields["boardCompact"])
+	}
+	var back models.GameState
+	if err := json.Unmarshal(data, &back); err != nil || back.Board != g.Board || back.ID != g.ID {
+		t.Errorf("unmarshalled %s, %v", back.Board, err)
+	}
+
+	// Either form alone will do, but given together they must agree
+	tests := []struct {
+		json string
+		want string
+		err  bool
+	}{
+		{`{"boardCompact":"X.O......"}`, "X.O/.../...", false},
+		{`{"board":["","","","","O","","","",""]}`, ".../.O./...", false},
+		{`{"board":["","","","","O","","","",""],"boardCompact":".../.O./..."}`, ".../.O./...", false},
+		{`{"board":["","","","","O","","","",""],"boardCompact":"X........"}`, "", true},
+		{`{"boardCompact":"X.O"}`, "", true},
+	}
+	for _, tt := range tests {
+		var g models.GameState
+		err := json.Unmarshal([]byte(tt.json), &g)
+		if tt.err != (err != nil) || (err == nil && g.Board.String() != tt.want) {
+			t.Errorf("unmarshalling %s: %s, %v", tt.json, g.Board, err)
+		}
+	}
+}
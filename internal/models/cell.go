@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// BoardSize is the number of rows and columns on the board
+const BoardSize = 3
+
+// ErrInvalidCell is returned for coordinates that don't name a cell
+var ErrInvalidCell = errors.New("invalid cell")
+
+// ParseCell converts a coordinate like "b2" into a board index. Columns
+// are letters starting at "a" on the left, rows are numbers starting at 1
+// on the top, so on a 3x3 board "a1" is index 0 and "c3" is index 8.
+// Letters are case-insensitive.
+func ParseCell(cell string, size int) (int, error) {
+	if len(cell) < 2 {
+		return 0, fmt.Errorf("%w %q: expected a column letter and a row number", ErrInvalidCell, cell)
+	}
+
+	letter := cell[0] | 0x20 // lowercase
+	if letter < 'a' || letter > 'z' {
+		return 0, fmt.Errorf("%w %q: expected a column letter and a row number", ErrInvalidCell, cell)
+	}
+	col := int(letter - 'a')
+
+	digits := cell[1:]
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, fmt.Errorf("%w %q: expected a column letter and a row number", ErrInvalidCell, cell)
+		}
+	}
+	row, err := strconv.Atoi(digits)
+	if err != nil || digits[0] == '0' {
+		return 0, fmt.Errorf("%w %q: bad row number", ErrInvalidCell, cell)
+	}
+	row--
+
+	if col >= size || row >= size {
+		return 0, fmt.Errorf("%w %q: outside the %dx%d board", ErrInvalidCell, cell, size, size)
+	}
+	return row*size + col, nil
+}
+
+// CellIndex converts a zero-based row and column into a board index
+func CellIndex(row, col, size int) (int, error) {
+	if row < 0 || col < 0 || row >= size || col >= size {
+		return 0, fmt.Errorf("%w: row %d, col %d is outside the %dx%d board", ErrInvalidCell, row, col, size, size)
+	}
+	return row*size + col, nil
+}
+
+// CellName converts a board index into a coordinate like "b2"
+func CellName(index, size int) string {
+	if index < 0 || index >= size*size {
+		return ""
+	}
+	return string(rune('a'+index%size)) + strconv.Itoa(index/size+1)
+}
@@ -0,0 +1,115 @@
+package models_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestParseCell(t *testing.T) {
+	tests := []struct {
+		cell string
+		size int
+		want int
+		ok   bool
+	}{
+		{"a1", 3, 0, true},
+		{"b2", 3, 4, true},
+		{"c3", 3, 8, true},
+		{"C1", 3, 2, true},
+		{"a3", 3, 6, true},
+		{"e5", 5, 24, true},
+		{"d2", 5, 8, true},
+		{"a5", 5, 20, true},
+
+		// Off the board
+		{"d1", 3, 0, false},
+		{"a4", 3, 0, false},
+		{"e5", 3, 0, false},
+		{"f1", 5, 0, false},
+		{"a6", 5, 0, false},
+		{"a10", 5, 0, false},
+
+		// Not a coordinate
+		{"", 3, 0, false},
+		{"b", 3, 0, false},
+		{"2b", 3, 0, false},
+		{"bb", 3, 0, false},
+		{"b0", 3, 0, false},
+		{"b02", 3, 0, false},
+		{"b-1", 3, 0, false},
+		{"b 2", 3, 0, false},
+		{" b2", 3, 0, false},
+		{"b2 ", 3, 0, false},
+		{"b99999999999999999999", 3, 0, false},
+		{"é1", 3, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := models.ParseCell(tt.cell, tt.size)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParseCell(%q, %d) = %d, %v, want %d", tt.cell, tt.size, got, err, tt.want)
+		}
+		if !tt.ok && (!errors.Is(err, models.ErrInvalidCell) || !strings.Contains(err.Error(), strconv.Quote(tt.cell))) {
+			t.Errorf("ParseCell(%q, %d) = %d, %v, want ErrInvalidCell naming the cell", tt.cell, tt.size, got, err)
+		}
+	}
+}
+
+func TestCellNameRoundTrip(t *testing.T) {
+	for _, size := range []int{3, 5} {
+		for i := range size * size {
+			name := models.CellName(i, size)
+			if got, err := models.ParseCell(name, size); err != nil || got != i {
+				t.Errorf("%dx%d: cell %d is named %q, which parses as %d, %v", size, size, i, name, got, err)
+			}
+		}
+		for _, i := range []int{-1, size * size} {
+			if name := models.CellName(i, size); name != "" {
+				t.Errorf("%dx%d: cell %d is named %q", size, size, i, name)
+			}
+		}
+	}
+}
+
+func TestCellIndex(t *testing.T) {
+	tests := []struct {
+		row, col, size int
+		want           int
+		ok             bool
+	}{
+		{0, 0, 3, 0, true},
+		{1, 2, 3, 5, true},
+		{2, 2, 3, 8, true},
+		{2, 0, 3, 6, true},
+		{3, 1, 4, 13, true},
+		{4, 4, 5, 24, true},
+		{1, 3, 5, 8, true},
+
+		{3, 0, 3, 0, false},
+		{0, 3, 3, 0, false},
+		{-1, 0, 3, 0, false},
+		{0, -1, 3, 0, false},
+		{4, 0, 4, 0, false},
+		{0, 5, 5, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := models.CellIndex(tt.row, tt.col, tt.size)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("CellIndex(%d, %d, %d) = %d, %v, want %d", tt.row, tt.col, tt.size, got, err, tt.want)
+		}
+		if !tt.ok && !errors.Is(err, models.ErrInvalidCell) {
+			t.Errorf("CellIndex(%d, %d, %d) = %d, %v, want ErrInvalidCell", tt.row, tt.col, tt.size, got, err)
+		}
+	}
+	// Each cell's row and column are those of its name
+	for _, size := range []int{3, 4, 5} {
+		for i := range size * size {
+			if got, err := models.CellIndex(i/size, i%size, size); err != nil || models.CellName(got, size) != models.CellName(i, size) {
+				t.Errorf("%dx%d: row %d, col %d is cell %d, %v, want %d", size, size, i/size, i%size, got, err, i)
+			}
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Player represents a player in the game
 type Player string
 
@@ -9,12 +11,68 @@ const (
 	Empty   Player = ""
 )
 
+// Mode is how a game is played.
+type Mode string
+
+const (
+	// ModeOnline games have a side for each of two browsers. It is the
+	// zero value, so games created before modes existed are online.
+	ModeOnline Mode = ""
+	// ModeHotseat games are played by two people sharing one device:
+	// both sides are joined from the start and moves simply alternate.
+	ModeHotseat Mode = "hotseat"
+	// ModeAI games are played against the computer, which takes O and
+	// replies to each move on its own, see game.ComputerPlayer. Both
+	// sides are joined from the start.
+	ModeAI Mode = "ai"
+)
+
+// HandicapStyle is how a Handicap gives its player an edge.
+type HandicapStyle string
+
+const (
+	// HandicapMark starts the game with one of the player's marks on the
+	// board, recorded as move zero, after which X moves first as usual.
+	HandicapMark HandicapStyle = "mark"
+	// HandicapDoubleMove has the player open the game with two moves in a
+	// row, after which the sides alternate.
+	HandicapDoubleMove HandicapStyle = "double"
+)
+
+// Handicap gives the weaker Player of a game an edge from the start.
+// Position is the cell of a HandicapMark's mark.
+type Handicap struct {
+	Style    HandicapStyle `json:"style"`
+	Player   Player        `json:"player"`
+	Position int           `json:"position"`
+}
+
 // Board represents the 3x3 game board
 type Board [9]Player
 
-// GameState represents the current state of a game
+// GameSettings are the options a game is created with. They can only
+// change before play begins, and resets keep them.
+type GameSettings struct {
+	Mode          Mode      `json:"mode,omitempty"`
+	AnalysisLive  bool      `json:"analysisLive,omitempty"`
+	Handicap      *Handicap `json:"handicap,omitempty"`
+	ReadyCheck    bool      `json:"readyCheck,omitempty"`
+	EarlyDraw     bool      `json:"earlyDraw,omitempty"`
+	StartPosition string    `json:"startPosition,omitempty"`
+	StartTurn     Player    `json:"startTurn,omitempty"`
+	Exhibition    bool      `json:"exhibition,omitempty"`
+	Locale        string    `json:"locale,omitempty"`
+}
+
+// DrawDeadPosition is the DrawReason of a game with EarlyDraw ended
+// because no way of playing on could give either side a line.
+const DrawDeadPosition = "dead_position"
+
+// GameState represents the current state of a game. LegalMoves, Thinking,
+// LastMove and Timing are derived from the rest by the service.
 type GameState struct {
 	ID            string `json:"id"`
+	Slug          string `json:"slug,omitempty"`
 	Board         Board  `json:"board"`
 	CurrentTurn   Player `json:"currentTurn"`
 	Winner        Player `json:"winner"`
@@ -22,14 +80,87 @@ type GameState struct {
 	IsDraw        bool   `json:"isDraw"`
 	PlayerXJoined bool   `json:"playerXJoined"`
 	PlayerOJoined bool   `json:"playerOJoined"`
+	XSymbol       string `json:"xSymbol"`
+	OSymbol       string `json:"oSymbol"`
+	GameSettings
+	DrawOffer     Player       `json:"drawOffer,omitempty"`
+	DrawOfferedAt int          `json:"drawOfferedAt,omitempty"`
+	DrawAgreed    bool         `json:"drawAgreed,omitempty"`
+	DrawReason    string       `json:"drawReason,omitempty"`
+	WinClaimed    bool         `json:"winClaimed,omitempty"`
+	PlayerXReady  bool         `json:"playerXReady,omitempty"`
+	PlayerOReady  bool         `json:"playerOReady,omitempty"`
+	ReadyBy       time.Time    `json:"readyBy,omitzero"`
+	StartsAt      time.Time    `json:"startsAt,omitzero"`
+	History       []MoveRecord `json:"history"`
+	LegalMoves    []int        `json:"legalMoves"`
+	Thinking      bool         `json:"thinking,omitempty"`
+	LastMove      *MoveRecord  `json:"lastMove,omitempty"`
+	Timing        []Timing     `json:"timing,omitempty"`
+	Version       uint64       `json:"version"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
 }
 
-// Move represents a player's move
+// Move represents a player's move. The cell may be given as a Position
+// index, as a coordinate like "b2" in Cell, or as a zero-based Row and
+// Col. Forms given together must name the same cell.
 type Move struct {
 	Position int    `json:"position"`
+	Cell     string `json:"cell,omitempty"`
+	Row      *int   `json:"row,omitempty"`
+	Col      *int   `json:"col,omitempty"`
 	Player   Player `json:"player"`
 }
 
+// MoveRecord is a move that has been applied to a game.
+type MoveRecord struct {
+	Position int           `json:"position"`
+	Cell     string        `json:"cell"`
+	Row      int           `json:"row"`
+	Col      int           `json:"col"`
+	Player   Player        `json:"player"`
+	At       time.Time     `json:"at"`
+	Think    time.Duration `json:"thinkNs,omitempty"`
+	Setup    bool          `json:"setup,omitempty"`
+	Batch    string        `json:"batch,omitempty"`
+}
+
+// Timing sums up how long a Player of a finished game took over their
+// Moves: in Total, on Average, and the Longest of them, which was the
+// game's move numbered LongestMove, counting from 1 and leaving out the
+// marks the game's settings placed.
+type Timing struct {
+	Player      Player        `json:"player"`
+	Moves       int           `json:"moves"`
+	Total       time.Duration `json:"totalNs"`
+	Average     time.Duration `json:"averageNs"`
+	Longest     time.Duration `json:"longestNs"`
+	LongestMove int           `json:"longestMove"`
+}
+
+// Evaluation is how a position stands under perfect play.
+type Evaluation struct {
+	// Advantage is the side that wins with perfect play, or "even" when
+	// the game is drawn.
+	Advantage string `json:"advantage"`
+	// MateIn is how many more moves the winning side needs, zero once
+	// the game is won and for even positions.
+	MateIn int `json:"mateIn"`
+}
+
+// AdvantageEven is the Evaluation.Advantage of a drawn position.
+const AdvantageEven = "even"
+
+// Commentary is a line a game's commentator wrote about it. Move is how
+// many moves had been played when it was written, so a replay can show
+// it alongside the position it was about.
+type Commentary struct {
+	Move int       `json:"move"`
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
 // NewGameState creates a new game state
 func NewGameState(id string) *GameState {
 	return &GameState{
@@ -39,5 +170,39 @@ func NewGameState(id string) *GameState {
 		Winner:      Empty,
 		IsOver:      false,
 		IsDraw:      false,
+		History:     []MoveRecord{},
+	}
+}
+
+// Symbol returns the mark displayed for a player. Players are X and O
+// internally but may choose any single character or emoji to show.
+func (g *GameState) Symbol(p Player) string {
+	switch p {
+	case PlayerX:
+		if g.XSymbol != "" {
+			return g.XSymbol
+		}
+	case PlayerO:
+		if g.OSymbol != "" {
+			return g.OSymbol
+		}
+	}
+	return string(p)
+}
+
+// Clone returns a deep copy of the game state
+func (g *GameState) Clone() *GameState {
+	clone := *g
+	clone.History = append([]MoveRecord{}, g.History...)
+	clone.LegalMoves = append([]int{}, g.LegalMoves...)
+	clone.Timing = append([]Timing(nil), g.Timing...)
+	if g.Handicap != nil {
+		h := *g.Handicap
+		clone.Handicap = &h
+	}
+	if g.LastMove != nil {
+		m := *g.LastMove
+		clone.LastMove = &m
 	}
+	return &clone
 }
@@ -0,0 +1,255 @@
+// Package puzzle serves a daily "find the winning move" position.
+package puzzle
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MaxAttempts is how many wrong answers a player gets before the
+// solution is revealed
+const MaxAttempts = 3
+
+// maxSessions bounds how many players' progress is tracked per day.
+// Players beyond it can still attempt the puzzle but aren't remembered.
+const maxSessions = 100_000
+
+// dateLayout is the format of Puzzle.Date
+const dateLayout = "2006-01-02"
+
+// ErrIllegalMove is returned for attempts on an occupied cell
+var ErrIllegalMove = errors.New("that cell is already taken")
+
+// Puzzle is a mid-game position with exactly one winning move
+type Puzzle struct {
+	Date     string        `json:"date"`
+	Board    models.Board  `json:"board"`
+	ToMove   models.Player `json:"toMove"`
+	solution int
+}
+
+// Generate returns the puzzle for the given day. It is deterministic, so
+// every server hands out the same puzzle for the same UTC date.
+func Generate(day time.Time) Puzzle {
+	day = day.UTC()
+	seed := uint64(day.Year())*10000 + uint64(day.Month())*100 + uint64(day.Day())
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for {
+		board, turn, ok := randomPosition(rng)
+		if !ok {
+			continue
+		}
+		wins := game.WinningMoves(board, turn)
+		if len(wins) != 1 {
+			continue
+		}
+		return Puzzle{
+			Date:     day.Format(dateLayout),
+			Board:    board,
+			ToMove:   turn,
+			solution: wins[0],
+		}
+	}
+}
+
+// randomPosition plays a few random moves from the empty board. It
+// reports false if the game ended along the way.
+func randomPosition(rng *rand.Rand) (models.Board, models.Player, bool) {
+	var board models.Board
+	turn := models.PlayerX
+	plies := 2 + rng.IntN(5)
+	for range plies {
+		var empty []int
+		for i, cell := range board {
+			if cell == models.Empty {
+				empty = append(empty, i)
+			}
+		}
+		board[empty[rng.IntN(len(empty))]] = turn
+		if turn == models.PlayerX {
+			turn = models.PlayerO
+		} else {
+			turn = models.PlayerX
+		}
+		// A finished game has nothing left to play
+		if game.Evaluate(board, turn).Plies == 0 {
+			return board, turn, false
+		}
+	}
+	return board, turn, true
+}
+
+// Solution returns the winning move
+func (p Puzzle) Solution() int {
+	return p.solution
+}
+
+// Result is the outcome of an attempt
+type Result struct {
+	Correct  bool `json:"correct"`
+	Attempts int  `json:"attempts"`
+	// Solution is set once the puzzle is solved or MaxAttempts wrong
+	// answers have been given.
+	Solution *int   `json:"solution,omitempty"`
+	Cell     string `json:"cell,omitempty"`
+}
+
+// Stats summarizes how today's puzzle is going
+type Stats struct {
+	Date      string  `json:"date"`
+	Players   int     `json:"players"`
+	Attempts  int     `json:"attempts"`
+	Solved    int     `json:"solved"`
+	SolveRate float64 `json:"solveRate"`
+}
+
+// progress is one player's attempts at today's puzzle
+type progress struct {
+	attempts int
+	solved   bool
+	revealed bool
+}
+
+// Service hands out the daily puzzle and checks attempts against it
+type Service struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	today    Puzzle
+	sessions map[string]*progress
+	stats    Stats
+}
+
+// NewService creates a puzzle service using the system clock
+func NewService() *Service {
+	return &Service{now: time.Now}
+}
+
+// Today returns today's puzzle
+func (s *Service) Today() Puzzle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current()
+}
+
+// Stats returns today's solve statistics
+func (s *Service) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current()
+	return s.stats
+}
+
+// Attempt checks a move by the player identified by session. Once a
+// player has solved the puzzle or had the solution revealed, further
+// attempts are answered but no longer counted.
+func (s *Service) Attempt(session string, position int) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.current()
+	if position < 0 || position >= len(p.Board) {
+		return Result{}, game.ErrInvalidMove
+	}
+	if p.Board[position] != models.Empty {
+		return Result{}, ErrIllegalMove
+	}
+
+	prog := s.sessions[session]
+	if prog == nil {
+		prog = &progress{}
+		if len(s.sessions) < maxSessions {
+			s.sessions[session] = prog
+		}
+	}
+
+	correct := position == p.solution
+	if !prog.solved && !prog.revealed {
+		if prog.attempts == 0 {
+			s.stats.Players++
+		}
+		prog.attempts++
+		s.stats.Attempts++
+		if correct {
+			prog.solved = true
+			s.stats.Solved++
+		} else if prog.attempts >= MaxAttempts {
+			prog.revealed = true
+		}
+		s.stats.SolveRate = float64(s.stats.Solved) / float64(s.stats.Players)
+	}
+
+	result := s.result(prog)
+	result.Correct = correct
+	return result, nil
+}
+
+// Progress returns where the player identified by session stands on
+// today's puzzle without making an attempt.
+func (s *Service) Progress(session string) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current()
+	prog := s.sessions[session]
+	if prog == nil {
+		return Result{}
+	}
+	result := s.result(prog)
+	result.Correct = prog.solved
+	return result
+}
+
+// result reports a player's progress, revealing the solution once they
+// are done. Must be called with the lock held.
+func (s *Service) result(prog *progress) Result {
+	result := Result{Attempts: prog.attempts}
+	if prog.solved || prog.revealed {
+		solution := s.today.solution
+		result.Solution = &solution
+		result.Cell = models.CellName(solution, models.BoardSize)
+	}
+	return result
+}
+
+// current returns today's puzzle, starting a new day's puzzle and
+// clearing progress when the date has changed. Must be called with the
+// lock held.
+func (s *Service) current() Puzzle {
+	now := s.now().UTC()
+	if s.today.Date != now.Format(dateLayout) {
+		s.today = Generate(now)
+		s.sessions = make(map[string]*progress)
+		s.stats = Stats{Date: s.today.Date}
+	}
+	return s.today
+}
+
+// SessionCookie names the cookie that identifies a player's attempts
+const SessionCookie = "puzzle_session"
+
+// Session returns the caller's puzzle session, issuing a new session
+// cookie if the request doesn't carry one.
+func Session(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(SessionCookie); err == nil && c.Value != "" && len(c.Value) <= 64 {
+		return c.Value
+	}
+	id := uuid.NewString()
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   2 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
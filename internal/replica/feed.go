@@ -0,0 +1,144 @@
+// Package replica streams a primary's game changes to a warm standby,
+// which follows them read-only until it is promoted.
+package replica
+
+import (
+	"errors"
+	"sync"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// ErrNotFollowing is returned by Follower.Promote once the standby has
+// been promoted.
+var ErrNotFollowing = errors.New("this server isn't following a primary")
+
+// Entry is a change to a game: its state once Event, one of the game
+// service's journal events such as game.EventMoved, changed it, and Seq
+// counting the changes to the game the feed has seen. Game is nil for
+// the events that remove a game.
+type Entry struct {
+	GameID string            `json:"gameId"`
+	Seq    uint64            `json:"seq"`
+	Event  string            `json:"event"`
+	Game   *models.GameState `json:"game,omitempty"`
+}
+
+// eventSeeded is the Event of the entries of games the feed was seeded
+// with, see Feed.Seed.
+const eventSeeded = "seed"
+
+// removes reports whether event removes the game it is about.
+func removes(event string) bool {
+	return event == game.EventCancelled || event == game.EventExpired
+}
+
+// subscriberBuffer is how far behind a subscriber may fall before it is
+// cut off, see Feed.Subscribe.
+const subscriberBuffer = 256
+
+// Feed is a game.Journal that streams the changes it is given to
+// subscribers, passing them on to another journal first if there is
+// one. It keeps the latest entry of every game, including a removed
+// one's, so a subscriber can start from them.
+type Feed struct {
+	journal game.Journal
+
+	mu     sync.Mutex
+	latest map[string]Entry
+	subs   map[chan Entry]struct{}
+}
+
+// NewFeed creates a feed that journals changes in j, which may be nil.
+func NewFeed(j game.Journal) *Feed {
+	return &Feed{
+		journal: j,
+		latest:  make(map[string]Entry),
+		subs:    make(map[chan Entry]struct{}),
+	}
+}
+
+// Append journals a change and streams it to subscribers. Like any
+// journal it is called with the service lock held, so it never blocks
+// on a subscriber.
+func (f *Feed) Append(event string, g *models.GameState) error {
+	if f.journal != nil {
+		if err := f.journal.Append(event, g); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := Entry{GameID: g.ID, Seq: f.latest[g.ID].Seq + 1, Event: event, Game: g}
+	if removes(event) {
+		e.Game = nil
+	}
+	f.latest[g.ID] = e
+	for ch := range f.subs {
+		select {
+		case ch <- e:
+		default:
+			// Cut off rather than hold the games up; it will come back
+			// and start over from the latest entries
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// LastSeq returns that of the journal changes are passed on to, or zero
+// without one.
+func (f *Feed) LastSeq() uint64 {
+	if f.journal == nil {
+		return 0
+	}
+	return f.journal.LastSeq()
+}
+
+// Seed adds games the feed hasn't seen change, such as those a server
+// restored on starting up or got from its primary, so that its own
+// subscribers get them too.
+func (f *Feed) Seed(games []*models.GameState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, g := range games {
+		if _, ok := f.latest[g.ID]; !ok {
+			f.latest[g.ID] = Entry{GameID: g.ID, Seq: 1, Event: eventSeeded, Game: g}
+		}
+	}
+}
+
+// Latest returns the latest entry of the game with the given ID, and
+// whether the feed knows it.
+func (f *Feed) Latest(gameID string) (Entry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.latest[gameID]
+	return e, ok
+}
+
+// Subscribe returns the latest entry of every game the feed knows, and a
+// channel of the entries that follow them. The channel is closed when
+// the subscriber falls too far behind, and by cancel.
+func (f *Feed) Subscribe() ([]Entry, <-chan Entry, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries := make([]Entry, 0, len(f.latest))
+	for _, e := range f.latest {
+		entries = append(entries, e)
+	}
+	ch := make(chan Entry, subscriberBuffer)
+	f.subs[ch] = struct{}{}
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+	return entries, ch, cancel
+}
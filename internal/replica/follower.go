@@ -0,0 +1,319 @@
+package replica
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// The events of the stream a feed is served as, see api.ReplicationHandler.
+const (
+	// EventSync carries the latest entry of a game, sent for every game
+	// the feed knows when a follower connects.
+	EventSync = "sync"
+	// EventSynced follows the last EventSync.
+	EventSynced = "synced"
+	// EventChange carries an entry as it happens.
+	EventChange = "change"
+	// EventCutOff ends the stream of a follower that fell too far
+	// behind. It should connect again.
+	EventCutOff = "disconnected"
+)
+
+// retryDelay is how long a Follower waits before connecting to the
+// primary again after losing it.
+const retryDelay = 2 * time.Second
+
+// Status is how a Follower is getting on.
+type Status struct {
+	// Primary is the URL of the server followed.
+	Primary string `json:"primary"`
+	// Connected is set while the follower is streaming from the primary,
+	// and Synced once it has caught up with it since connecting.
+	Connected bool `json:"connected"`
+	Synced    bool `json:"synced"`
+	// Applied counts the entries applied and Resynced the games fetched
+	// afresh after their sequence skipped a number.
+	Applied  uint64 `json:"applied"`
+	Resynced uint64 `json:"resynced"`
+}
+
+// Follower mirrors the games of a primary server into a game service,
+// which it keeps read-only while it follows, and tells their watchers
+// about each change. It authenticates with the primary's admin key.
+type Follower struct {
+	primary string
+	key     string
+	client  *http.Client
+	games   *game.Service
+	hub     *broadcast.Hub
+	feed    *Feed
+
+	mu     sync.Mutex
+	status Status
+	// seqs is the Seq of the last entry applied to each game since
+	// connecting
+	seqs map[string]uint64
+	// stop ends Run, which closes done once it has
+	stop     context.CancelFunc
+	done     chan struct{}
+	promoted bool
+}
+
+// NewFollower creates a follower of the server at primary, such as
+// "http://primary:8080", authenticating with key, and makes games
+// read-only until it is promoted. Then the games it got are seeded into
+// feed for the standby's own followers.
+func NewFollower(primary, key string, games *game.Service, hub *broadcast.Hub, feed *Feed) (*Follower, error) {
+	u, err := url.Parse(primary)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid primary URL %q", primary)
+	}
+	games.SetReadOnly(true)
+	return &Follower{
+		primary: strings.TrimSuffix(primary, "/"),
+		key:     key,
+		client:  &http.Client{},
+		games:   games,
+		hub:     hub,
+		feed:    feed,
+		status:  Status{Primary: primary},
+		seqs:    make(map[string]uint64),
+	}, nil
+}
+
+// Run follows the primary until ctx is done or the follower is
+// promoted, connecting again whenever the stream ends.
+func (f *Follower) Run(ctx context.Context) {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	f.mu.Lock()
+	if f.promoted {
+		f.mu.Unlock()
+		return
+	}
+	done := make(chan struct{})
+	defer close(done)
+	f.stop, f.done = stop, done
+	f.mu.Unlock()
+
+	for {
+		err := f.follow(ctx)
+		f.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("lost the primary, reconnecting", "primary", f.primary, "error", err, "retry_in", retryDelay)
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Promote stops following and makes the game service writable, so the
+// standby takes over from the primary with the games as it last had
+// them. It fails with ErrNotFollowing once promoted.
+func (f *Follower) Promote(ctx context.Context) (Status, error) {
+	f.mu.Lock()
+	if f.promoted {
+		f.mu.Unlock()
+		return Status{}, ErrNotFollowing
+	}
+	f.promoted = true
+	stop, done := f.stop, f.done
+	f.mu.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+
+	games, _, err := f.games.Snapshot(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	f.feed.Seed(games)
+	f.games.SetReadOnly(false)
+	status := f.Status()
+	slog.InfoContext(ctx, "promoted to primary", "primary", f.primary, "games", len(games), "applied", status.Applied)
+	return status, nil
+}
+
+// Status returns how the follower is getting on.
+func (f *Follower) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+func (f *Follower) setConnected(connected bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status.Connected = connected
+	f.status.Synced = false
+}
+
+// follow streams the primary's changes and applies them until the
+// stream ends.
+func (f *Follower) follow(ctx context.Context) error {
+	resp, err := f.get(ctx, "/api/admin/replication")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("primary answered %s", resp.Status)
+	}
+
+	// The sequences start over with the latest entries
+	f.mu.Lock()
+	clear(f.seqs)
+	f.mu.Unlock()
+	f.setConnected(true)
+	slog.InfoContext(ctx, "following primary", "primary", f.primary)
+
+	var name string
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := f.handle(ctx, name, data.Bytes()); err != nil {
+				return err
+			}
+			name = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("stream ended")
+}
+
+// handle acts on one event of the stream.
+func (f *Follower) handle(ctx context.Context, name string, data []byte) error {
+	switch name {
+	case EventSync, EventChange:
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("decoding entry: %w", err)
+		}
+		if name == EventSync {
+			return f.apply(ctx, e)
+		}
+		return f.change(ctx, e)
+	case EventSynced:
+		f.mu.Lock()
+		f.status.Synced = true
+		f.mu.Unlock()
+		slog.InfoContext(ctx, "caught up with primary", "primary", f.primary)
+	case EventCutOff:
+		return errors.New("primary cut the stream off")
+	case broadcast.RestartEvent:
+		return errors.New("primary is restarting")
+	}
+	return nil
+}
+
+// change applies an entry that follows the last one applied to its game.
+// One the follower has applied already is left alone, and one that
+// skips some is taken as a sign the game has diverged, so the game is
+// fetched afresh instead.
+func (f *Follower) change(ctx context.Context, e Entry) error {
+	f.mu.Lock()
+	last := f.seqs[e.GameID]
+	f.mu.Unlock()
+	switch {
+	case e.Seq <= last:
+		return nil
+	case e.Seq > last+1:
+		slog.WarnContext(ctx, "game diverged from primary, fetching it", "game_id", e.GameID, "seq", last, "got_seq", e.Seq)
+		return f.resync(ctx, e.GameID)
+	}
+	return f.apply(ctx, e)
+}
+
+// resync fetches the latest entry of a game from the primary and applies
+// it. A game the primary doesn't know is removed.
+func (f *Follower) resync(ctx context.Context, gameID string) error {
+	resp, err := f.get(ctx, "/api/admin/replication/"+url.PathEscape(gameID))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var e Entry
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			return fmt.Errorf("decoding entry of game %s: %w", gameID, err)
+		}
+	case http.StatusNotFound:
+		e = Entry{GameID: gameID, Event: game.EventCancelled}
+	default:
+		return fmt.Errorf("primary answered %s for game %s", resp.Status, gameID)
+	}
+	if err := f.apply(ctx, e); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.status.Resynced++
+	f.mu.Unlock()
+	return nil
+}
+
+// apply replays an entry into the game service and tells the game's
+// watchers. An entry the service finds invalid is skipped, as in
+// recovery, rather than stopping the follower.
+func (f *Follower) apply(ctx context.Context, e Entry) error {
+	g := e.Game
+	if g == nil {
+		g = &models.GameState{ID: e.GameID}
+	}
+	if err := f.games.Replay(ctx, e.Event, g); err != nil {
+		slog.WarnContext(ctx, "skipping invalid entry from primary", "game_id", e.GameID, "seq", e.Seq, "error", err)
+	} else if e.Game == nil {
+		f.hub.CloseGame(e.GameID, broadcast.ReasonGameDeleted)
+	} else {
+		f.hub.Broadcast(ctx, e.GameID, e.Game)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seqs[e.GameID] = e.Seq
+	f.status.Applied++
+	return nil
+}
+
+// get requests path from the primary with the admin key.
+func (f *Follower) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.primary+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+f.key)
+	return f.client.Do(req)
+}
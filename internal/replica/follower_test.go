@@ -0,0 +1,154 @@
+package replica_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/replica"
+)
+
+// event is one event of a replication stream.
+type event struct {
+	name  string
+	entry replica.Entry
+}
+
+// primary serves stream as a primary's replication stream, then holds it
+// open, and the latest entries of feed's games, as api.ReplicationHandler
+// does.
+func primary(t *testing.T, feed *replica.Feed, stream []event) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/replication", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			http.Error(w, "no", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range stream {
+			data, _ := json.Marshal(ev.entry)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, data)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("GET /api/admin/replication/{id}", func(w http.ResponseWriter, r *http.Request) {
+		e, ok := feed.Latest(r.PathValue("id"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(e)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+// TestFollowerResync streams a follower entries that skip one and come
+// out of order, and checks it fetches a game that skipped afresh, keeps
+// stale entries from winding it back and removes a game the primary no
+// longer has.
+func TestFollowerResync(t *testing.T) {
+	ctx := context.Background()
+	feed := replica.NewFeed(nil)
+	games := game.NewService(game.WithJournal(feed))
+	defer games.Close()
+	_, changes, cancel := feed.Subscribe()
+	defer cancel()
+
+	g, err := games.CreateGame(ctx, models.PlayerX, game.CreateOptions{GameSettings: models.GameSettings{Mode: models.ModeHotseat}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []replica.Entry{<-changes}
+	for i, pos := range []int{4, 0, 8, 1} {
+		p := models.PlayerX
+		if i%2 == 1 {
+			p = models.PlayerO
+		}
+		if _, err := games.MakeMove(ctx, g.ID, models.Move{Player: p, Position: pos}); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, <-changes)
+	}
+	// A game the primary's feed doesn't know, as if it were gone
+	other := game.NewService()
+	defer other.Close()
+	gone, err := other.CreateGame(ctx, models.PlayerX, game.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := primary(t, feed, []event{
+		{replica.EventSync, entries[0]},
+		{replica.EventSync, replica.Entry{GameID: gone.ID, Seq: 1, Event: game.EventCreated, Game: gone}},
+		{replica.EventSynced, replica.Entry{}},
+		{replica.EventChange, entries[1]},
+		// Seq 4 skips 3, so the game is fetched afresh, as of seq 5
+		{replica.EventChange, entries[3]},
+		{replica.EventChange, entries[2]},
+		{replica.EventChange, replica.Entry{GameID: gone.ID, Seq: 3, Event: game.EventMoved, Game: gone}},
+	})
+	standby := game.NewService()
+	defer standby.Close()
+	hub := broadcast.NewHub()
+	defer hub.Close()
+	f, err := replica.NewFollower(url, "admin", standby, hub, replica.NewFeed(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !standby.ReadOnly() {
+		t.Error("the standby's games aren't read-only while following")
+	}
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Run(runCtx)
+	}()
+	t.Cleanup(func() {
+		stop()
+		<-done
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for f.Status().Resynced < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("status %+v, want both games fetched afresh", f.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := f.Status(); !s.Connected || !s.Synced || s.Applied != 5 {
+		t.Errorf("status %+v, want connected and synced with 5 entries applied", s)
+	}
+	got, ok := standby.GetGame(ctx, g.ID)
+	if !ok {
+		t.Fatal("the standby doesn't have the game")
+	}
+	if want := entries[4].Game.Board; got.Board != want || len(got.History) != 4 {
+		t.Errorf("board %v with %d moves, want the latest %v", got.Board, len(got.History), want)
+	}
+	if _, ok := standby.GetGame(ctx, gone.ID); ok {
+		t.Error("the standby kept a game the primary doesn't have")
+	}
+
+	if _, err := f.Promote(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if standby.ReadOnly() {
+		t.Error("the standby's games are still read-only once promoted")
+	}
+	if _, err := f.Promote(ctx); !errors.Is(err, replica.ErrNotFollowing) {
+		t.Errorf("promoting twice: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Package respond writes JSON responses and the error envelope.
+package respond
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"tiktaktoes/internal/errcode"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/logging"
+	"tiktaktoes/internal/text"
+)
+
+// ErrorBody is the JSON envelope for failed requests. Temporary says the
+// same request may succeed later.
+type ErrorBody struct {
+	Error      string `json:"error"`
+	Code       string `json:"code,omitempty"`
+	Temporary  bool   `json:"temporary,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+	Field      string `json:"field,omitempty"`
+	MoveIndex  *int   `json:"moveIndex,omitempty"`
+	RequestID  string `json:"requestId,omitempty"`
+}
+
+// jsonBuffer pairs a buffer with an encoder writing into it so both
+// can be reused across responses.
+type jsonBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var jsonPool = sync.Pool{
+	New: func() any {
+		jb := &jsonBuffer{}
+		jb.enc = json.NewEncoder(&jb.buf)
+		return jb
+	},
+}
+
+// JSON writes data as JSON with the given status.
+func JSON(w http.ResponseWriter, status int, data any) {
+	jb := jsonPool.Get().(*jsonBuffer)
+	jb.buf.Reset()
+	defer func() {
+		// Don't keep unusually large buffers around
+		if jb.buf.Cap() <= 64<<10 {
+			jsonPool.Put(jb)
+		}
+	}()
+
+	if err := jb.enc.Encode(data); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jb.buf.Bytes())
+}
+
+// Error writes the error envelope with message.
+func Error(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed", "status", status, "error", message)
+	}
+	JSON(w, status, ErrorBody{
+		Error:     message,
+		RequestID: logging.RequestID(r.Context()),
+	})
+}
+
+// Err writes the error envelope for err with the status and code it is
+// told with, see errcode.FromError.
+func Err(w http.ResponseWriter, r *http.Request, err error) {
+	GameError(w, r, errcode.FromError(err).Status, err)
+}
+
+// GameError is like Err with another status than err's own, for a
+// handler that has a reason to pick one.
+func GameError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed", "status", status, "error", err)
+	}
+	info := errcode.FromError(err)
+	body := ErrorBody{
+		Error:     err.Error(),
+		Code:      info.Code,
+		Temporary: info.Temporary,
+		RequestID: logging.RequestID(r.Context()),
+	}
+	var taken *game.SlugTakenError
+	if errors.As(err, &taken) {
+		body.Suggestion = taken.Suggestion
+	}
+	var rejected *text.FieldError
+	if errors.As(err, &rejected) {
+		body.Field = string(rejected.Field)
+	}
+	var batch *game.BatchError
+	if errors.As(err, &batch) {
+		body.MoveIndex = &batch.Index
+	}
+	JSON(w, status, body)
+}
+
+// WantsJSON reports whether r, made to an endpoint that answers htmx
+// with HTML, asks for JSON instead: it accepts application/json and
+// doesn't come from htmx, which sends an HX-Request header and takes
+// fragments whatever it accepts.
+func WantsJSON(r *http.Request) bool {
+	if r.Header.Get("HX-Request") != "" {
+		return false
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package respond
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+// jsonFresh is JSON as it was before encoders were pooled.
+func jsonFresh(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func BenchmarkJSON(b *testing.B) {
+	g := models.NewGameState("bench")
+	g.Board[4] = models.PlayerX
+	g.History = []models.MoveRecord{{Position: 4, Player: models.PlayerX}}
+	for _, bb := range []struct {
+		name  string
+		write func(http.ResponseWriter, int, any)
+	}{
+		{"pooled", JSON},
+		{"fresh", jsonFresh},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			w := httptest.NewRecorder()
+			b.ReportAllocs()
+			for b.Loop() {
+				w.Body.Reset()
+				bb.write(w, http.StatusOK, g)
+			}
+		})
+	}
+}
+
+// TestJSONDoesNotRetainBuffers writes many responses at once from the one
+// pool. Each must be exactly its own although the buffer it was encoded
+// in went on to encode others. Run it with -race.
+func TestJSONDoesNotRetainBuffers(t *testing.T) {
+	const writers, responses = 16, 50
+	body := func(writer, response int) map[string]string {
+		return map[string]string{"v": strings.Repeat(fmt.Sprintf("%d.%d ", writer, response), 1+(writer*response)%64)}
+	}
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Go(func() {
+			for j := range responses {
+				w := httptest.NewRecorder()
+				JSON(w, http.StatusOK, body(i, j))
+				want, _ := json.Marshal(body(i, j))
+				if got := strings.TrimSuffix(w.Body.String(), "\n"); got != string(want) {
+					t.Errorf("response %d.%d = %.200s, want %.200s", i, j, got, want)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestJSONDropsLargeBuffers(t *testing.T) {
+	JSON(httptest.NewRecorder(), http.StatusOK, strings.Repeat("x", 128<<10))
+	for range 100 {
+		jb := jsonPool.Get().(*jsonBuffer)
+		if jb.buf.Cap() > 64<<10 {
+			t.Fatalf("a %d byte buffer was pooled", jb.buf.Cap())
+		}
+		jsonPool.Put(jb)
+	}
+}
+
+func TestJSONEncodeFailure(t *testing.T) {
+	w := httptest.NewRecorder()
+	JSON(w, http.StatusOK, func() {})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", w.Code)
+	}
+	// And the half-written buffer isn't sent with the next response
+	w = httptest.NewRecorder()
+	JSON(w, http.StatusOK, 1)
+	if w.Body.String() != "1\n" {
+		t.Errorf("body %q, want 1", w.Body)
+	}
+}
@@ -0,0 +1,130 @@
+// Package seat signs the tokens that vouch for the sides of a game a
+// client joined. Nothing is stored.
+package seat
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"tiktaktoes/internal/models"
+)
+
+// TokenHeader names the request header carrying a seat token on the
+// REST API, and the response header a join hands one out in.
+const TokenHeader = "X-Seat-Token"
+
+// TokenParam names the query parameter carrying a seat token where a
+// header can't be set, as on a browser's WebSocket handshake.
+const TokenParam = "seat"
+
+// CookieName names the cookie listing the sides a browser has joined, as
+// "<game id>.<seat token>" entries separated by "-", newest last. The
+// HTMX views keep it, see Cookie.
+const CookieName = "seats"
+
+// ErrNoSeat is returned when a request acting for a side doesn't carry
+// a seat token holding it.
+var ErrNoSeat = errors.New("a seat token for that side is required")
+
+// macSize is how many bytes of the HMAC a token keeps.
+const macSize = 16
+
+// Signer signs and checks seat tokens with its key.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner returns a signer with key, or with a random one if key is
+// empty, in which case the tokens it signs are only good until the
+// server restarts.
+func NewSigner(key []byte) *Signer {
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		rand.Read(key)
+	}
+	return &Signer{key: key}
+}
+
+// Token returns the token holding sides, one or both of "X" and "O", of
+// the game with ID gameID.
+func (s *Signer) Token(gameID, sides string) string {
+	return sides + "." + s.mac(gameID, sides)
+}
+
+// Sides returns the sides token holds of the game with ID gameID, and
+// false if the key didn't sign it for that game.
+func (s *Signer) Sides(gameID, token string) (string, bool) {
+	sides, mac, ok := strings.Cut(token, ".")
+	if !ok || !validSides(sides) {
+		return "", false
+	}
+	if !hmac.Equal([]byte(mac), []byte(s.mac(gameID, sides))) {
+		return "", false
+	}
+	return sides, true
+}
+
+// Holds reports whether token holds player's side of the game with ID
+// gameID.
+func (s *Signer) Holds(gameID, token string, player models.Player) bool {
+	if player != models.PlayerX && player != models.PlayerO {
+		return false
+	}
+	sides, ok := s.Sides(gameID, token)
+	return ok && strings.Contains(sides, string(player))
+}
+
+// Cookie returns the sides of each game r's seats cookie holds tokens
+// signed by s for, by game ID. Entries that don't hold up are left out.
+func (s *Signer) Cookie(r *http.Request) map[string]string {
+	seats := make(map[string]string)
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return seats
+	}
+	for _, entry := range strings.Split(c.Value, "-") {
+		id, token, ok := strings.Cut(entry, ".")
+		if !ok || id == "" {
+			continue
+		}
+		if sides, ok := s.Sides(id, token); ok {
+			seats[id] = sides
+		}
+	}
+	return seats
+}
+
+// Joined returns the sides of game that are joined, as a token holds
+// them, for handing out one when creating a game joins sides at once.
+func Joined(game *models.GameState) string {
+	var sides string
+	if game.PlayerXJoined {
+		sides += string(models.PlayerX)
+	}
+	if game.PlayerOJoined {
+		sides += string(models.PlayerO)
+	}
+	return sides
+}
+
+func (s *Signer) mac(gameID, sides string) string {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(gameID))
+	m.Write([]byte{0})
+	m.Write([]byte(sides))
+	return hex.EncodeToString(m.Sum(nil)[:macSize])
+}
+
+// validSides reports whether sides names one or both sides, each once.
+func validSides(sides string) bool {
+	switch sides {
+	case "X", "O", "XO", "OX":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package seat
+
+import (
+	"testing"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestHolds(t *testing.T) {
+	s := NewSigner([]byte("key"))
+	x := s.Token("game", "X")
+	both := s.Token("game", "XO")
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		gameID string
+		token  string
+		player models.Player
+		want   bool
+	}{
+		{"own side", s, "game", x, models.PlayerX, true},
+		{"other side", s, "game", x, models.PlayerO, false},
+		{"hot seat", s, "game", both, models.PlayerO, true},
+		{"other game", s, "other", x, models.PlayerX, false},
+		{"other key", NewSigner([]byte("other key")), "game", x, models.PlayerX, false},
+		{"random key", NewSigner(nil), "game", x, models.PlayerX, false},
+		{"no token", s, "game", "", models.PlayerX, false},
+		{"sides rewritten", s, "game", "XO" + x[1:], models.PlayerO, false},
+		{"mac alone", s, "game", x[2:], models.PlayerX, false},
+		{"no player", s, "game", both, models.Empty, false},
+	}
+	for _, tt := range tests {
+		if got := tt.signer.Holds(tt.gameID, tt.token, tt.player); got != tt.want {
+			t.Errorf("%s: Holds() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJoined(t *testing.T) {
+	g := models.NewGameState("game")
+	if got := Joined(g); got != "" {
+		t.Errorf("Joined() = %q before anyone joined", got)
+	}
+	g.PlayerOJoined = true
+	if got := Joined(g); got != "O" {
+		t.Errorf("Joined() = %q, want O", got)
+	}
+	g.PlayerXJoined = true
+	if got := Joined(g); got != "XO" {
+		t.Errorf("Joined() = %q, want XO", got)
+	}
+}
@@ -0,0 +1,115 @@
+package security_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/security"
+
+	"github.com/gorilla/websocket"
+)
+
+// serve starts a server upgrading every request with an upgrader made
+// from cfg, and returns its WebSocket URL and host.
+func serve(t *testing.T, cfg security.UpgraderConfig) (url, host string) {
+	t.Helper()
+	upgrader := security.NewUpgrader(cfg)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), strings.TrimPrefix(srv.URL, "http://")
+}
+
+// dial opens a WebSocket to url from origin, or without an Origin if it
+// is empty, and returns the handshake's status.
+func dial(t *testing.T, url, origin string) int {
+	t.Helper()
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	conn, res, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		conn.Close()
+	}
+	if res == nil {
+		t.Fatalf("dialing from %q: %v", origin, err)
+	}
+	return res.StatusCode
+}
+
+func TestUpgraderOrigins(t *testing.T) {
+	allowed := security.UpgraderConfig{AllowedOrigins: []string{"https://ttt.example.com", "HTTPS://Embed.Example.com/"}}
+	wildcard := security.UpgraderConfig{AllowedOrigins: []string{security.AnyOrigin}}
+	for _, tt := range []struct {
+		name   string
+		cfg    security.UpgraderConfig
+		origin string
+		ok     bool
+	}{
+		{"no Origin", allowed, "", true},
+		{"no Origin, none allowed", security.UpgraderConfig{}, "", true},
+		{"allowed", allowed, "https://ttt.example.com", true},
+		{"allowed, written differently", allowed, "https://embed.example.com", true},
+		{"denied", allowed, "https://evil.example.com", false},
+		{"denied over another scheme", allowed, "http://ttt.example.com", false},
+		{"denied, none allowed", security.UpgraderConfig{}, "https://ttt.example.com", false},
+		{"malformed", allowed, "://", false},
+		{"wildcard", wildcard, "https://anywhere.example.org", true},
+		{"wildcard, null origin", wildcard, "null", true},
+	} {
+		url, _ := serve(t, tt.cfg)
+		status := dial(t, url, tt.origin)
+		if ok := status == http.StatusSwitchingProtocols; ok != tt.ok {
+			t.Errorf("%s: %q answered %d", tt.name, tt.origin, status)
+		} else if !ok && status != http.StatusForbidden {
+			t.Errorf("%s: %q refused with %d, want 403", tt.name, tt.origin, status)
+		}
+	}
+}
+
+// TestUpgraderAllowsItsOwnHost checks a page served by the server
+// itself may connect without being listed.
+func TestUpgraderAllowsItsOwnHost(t *testing.T) {
+	url, host := serve(t, security.UpgraderConfig{})
+	if status := dial(t, url, "http://"+host); status != http.StatusSwitchingProtocols {
+		t.Errorf("the server's own origin answered %d", status)
+	}
+}
+
+func TestUpgraderLogsRejectedOrigin(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	url, _ := serve(t, security.UpgraderConfig{})
+	dial(t, url, "https://evil.example.com")
+	if !strings.Contains(logs.String(), "origin=https://evil.example.com") {
+		t.Errorf("logged %q, want the rejected origin", logs.String())
+	}
+}
+
+func TestUpgraderCompression(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		url, _ := serve(t, security.UpgraderConfig{EnableCompression: enabled})
+		dialer := websocket.Dialer{EnableCompression: true}
+		conn, res, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		negotiated := strings.Contains(res.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+		if negotiated != enabled {
+			t.Errorf("EnableCompression %v: negotiated %q", enabled, res.Header.Get("Sec-WebSocket-Extensions"))
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// watchComputer broadcasts the moves the computer plays in games against
+// it, which arrive on their own rather than in answer to a request, and
+// has it answer the games recovered while waiting for it.
+func watchComputer(games *game.Service, hub *broadcast.Hub) {
+	games.OnComputerMoved(func(gs models.GameState) {
+		ctx := context.Background()
+		hub.Broadcast(ctx, gs.ID, &gs)
+		hub.NotifyTurn(ctx, &gs, "")
+	})
+	if err := games.ResumeThinking(context.Background()); err != nil {
+		slog.Error("resuming games against the computer failed", "error", err)
+	}
+}
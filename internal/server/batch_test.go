@@ -0,0 +1,59 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestBatchMoves sends a batch of moves over the REST API with a
+// watcher connected, and checks the watcher gets one update for all of
+// them, and that a failing batch reports which move failed.
+func TestBatchMoves(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	id := srv.CreateGame(t, `{"mode":"hotseat","exhibition":true}`).ID
+	watcher := srv.DialWS(t, id, "")
+	watcher.Connected(t)
+
+	var apiErr *testutil.APIError
+	_, err := srv.Do(t, "POST", "/api/game/"+id+"/moves", `{"moves":[{"player":"X","position":0},{"player":"O","position":4},{"player":"X","position":4}]}`, nil)
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict || apiErr.Code != "position_taken" || apiErr.MoveIndex == nil || *apiErr.MoveIndex != 2 {
+		t.Fatalf("a batch failing at its third move: %v", err)
+	}
+
+	var g models.GameState
+	if _, err := srv.Do(t, "POST", "/api/game/"+id+"/moves", `{"moves":[{"player":"X","position":0},{"player":"O","position":4},{"player":"X","position":8}]}`, &g); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.History) != 3 || g.CurrentTurn != models.PlayerO {
+		t.Errorf("after the batch: %d moves, %s to move", len(g.History), g.CurrentTurn)
+	}
+	// The failed batch sent nothing and the good one a single update,
+	// so the one after it is the next move's
+	if got := watcher.NextOf(t, broadcast.GameUpdateEvent).Game(t); len(got.History) != 3 {
+		t.Errorf("the watcher's first update has %d moves, want the batch's 3", len(got.History))
+	}
+	srv.MustMove(t, id, models.PlayerO, 2)
+	if got := watcher.NextOf(t, broadcast.GameUpdateEvent).Game(t); len(got.History) != 4 {
+		t.Errorf("the watcher's next update has %d moves, want 4: the batch was sent more than once", len(got.History))
+	}
+
+	live := srv.CreateGame(t, `{"mode":"hotseat"}`).ID
+	for _, tt := range []struct {
+		id, body string
+		status   int
+		code     string
+	}{
+		{live, `{"moves":[{"player":"X","position":0}]}`, http.StatusConflict, "no_batch"},
+		{id, `{"moves":[]}`, http.StatusBadRequest, "invalid_batch"},
+	} {
+		if _, err := srv.Do(t, "POST", "/api/game/"+tt.id+"/moves", tt.body, nil); !errors.As(err, &apiErr) || apiErr.Status != tt.status || apiErr.Code != tt.code {
+			t.Errorf("%s: %v, want %d %s", tt.body, err, tt.status, tt.code)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestClaimWinWhenOpponentLeaves has O close their WebSocket and X
+// claim the win over the API, with the hub timing O's absence by the
+// same fake clock as the game service.
+func TestClaimWinWhenOpponentLeaves(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := gametest.NewFakeClock(start)
+	srv := testutil.Start(t, server.Config{
+		Clock:       clock,
+		GameOptions: []game.Option{game.WithClaimAfter(time.Minute)},
+	})
+	id := srv.CreateGame(t, "").ID
+	_, x := srv.JoinAs(t, id, models.PlayerX)
+	_, o := srv.JoinAs(t, id, models.PlayerO)
+	xConn := srv.DialWS(t, id, "player=X", seat.TokenHeader, x)
+	xConn.Connected(t)
+	oConn := srv.DialWS(t, id, "player=O", seat.TokenHeader, o)
+	oConn.Connected(t)
+	srv.MustMove(t, id, models.PlayerX, 4)
+
+	claim := func(token string) error {
+		_, err := srv.Do(t, http.MethodPost, "/api/game/"+id+"/claim", `{"player":"X"}`, nil, seat.TokenHeader, token)
+		return err
+	}
+	code := func(err error) string {
+		var apiErr *testutil.APIError
+		if !errors.As(err, &apiErr) {
+			return ""
+		}
+		return apiErr.Code
+	}
+	if err := claim(x); code(err) != "opponent_present" {
+		t.Fatalf("claiming while O is connected: %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	oConn.Conn.Close()
+	var notice broadcast.ClaimNotice
+	for notice.At.IsZero() {
+		if err := json.Unmarshal(xConn.NextOf(t, broadcast.ClaimEvent).Data, &notice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := start.Add(time.Hour + time.Minute); !notice.At.Equal(want) {
+		t.Errorf("X told they can claim at %v, want a minute after O left, %v", notice.At, want)
+	}
+
+	clock.Advance(59 * time.Second)
+	if err := claim(x); code(err) != "claim_too_early" {
+		t.Errorf("claiming within the minute: %v", err)
+	}
+	clock.Advance(time.Second)
+	for _, token := range []string{"", o} {
+		if err := claim(token); code(err) != "seat_token" {
+			t.Errorf("claiming X's win with token %q: %v, want seat_token", token, err)
+		}
+	}
+	var won models.GameState
+	if _, err := srv.Do(t, http.MethodPost, "/api/game/"+id+"/claim", `{"player":"X"}`, &won, seat.TokenHeader, x); err != nil {
+		t.Fatal(err)
+	}
+	if !won.IsOver || won.Winner != models.PlayerX || !won.WinClaimed {
+		t.Errorf("claimed game: over %v, winner %q, claimed %v", won.IsOver, won.Winner, won.WinClaimed)
+	}
+	if g := xConn.NextOf(t, broadcast.GameUpdateEvent).Game(t); g.Winner != models.PlayerX {
+		t.Errorf("X was sent a game won by %q", g.Winner)
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// watchClaims keeps players told whether they may claim the win from an
+// opponent who left: whenever a player of a game comes or goes, both are
+// sent a claim-update event with when they can, if at all.
+func watchClaims(games *game.Service, hub *broadcast.Hub) {
+	hub.OnPresence(func(gameID string, _ models.Player, _ bool) {
+		ctx := context.Background()
+		for _, player := range []models.Player{models.PlayerX, models.PlayerO} {
+			at, err := games.ClaimableAt(ctx, gameID, player)
+			if err != nil {
+				at = time.Time{}
+			}
+			hub.SendTo(ctx, gameID, broadcast.ToPlayer(player), broadcast.Event{
+				Name: broadcast.ClaimEvent,
+				Data: broadcast.ClaimNotice{GameID: gameID, Player: player, At: at, ServerTime: time.Now().UTC()},
+			})
+		}
+	})
+	// Whether the players of a finished or deleted game are around no
+	// longer matters
+	forget := func(gs models.GameState) { hub.Forget(gs.ID) }
+	games.OnGameFinished(forget)
+	games.OnGameExpired(forget)
+	games.OnGameCancelled(forget)
+}
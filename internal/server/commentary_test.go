@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestCommentary appoints a commentator over the API, and checks their
+// lines reach a spectator and a player who opted in but not the other
+// player, and stop once they are revoked.
+func TestCommentary(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	id := srv.CreateGame(t, "").ID
+	_, x := srv.JoinAs(t, id, models.PlayerX)
+	_, o := srv.JoinAs(t, id, models.PlayerO)
+	conns := map[string]*testutil.WS{
+		"spectator":   srv.DialWS(t, id, ""),
+		"X":           srv.DialWS(t, id, "player=X", seat.TokenHeader, x),
+		"O, opted in": srv.DialWS(t, id, "player=O&commentary=1", seat.TokenHeader, o),
+	}
+	for _, c := range conns {
+		c.Connected(t)
+	}
+
+	var appointed struct {
+		Token string `json:"token"`
+	}
+	if _, err := srv.Do(t, "POST", "/api/game/"+id+"/commentator", `{"player":"X"}`, &appointed); err != nil {
+		t.Fatal(err)
+	}
+	comment := func(text string) error {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"token": appointed.Token, "text": text})
+		_, err := srv.Do(t, "POST", "/api/game/"+id+"/commentary", string(body), nil)
+		return err
+	}
+	if err := comment("X to open"); err != nil {
+		t.Fatal(err)
+	}
+	srv.MustMove(t, id, models.PlayerX, 4)
+	if err := comment("the centre, naturally"); err != nil {
+		t.Fatal(err)
+	}
+	srv.MustMove(t, id, models.PlayerO, 0)
+
+	for name, c := range conns {
+		var got []models.Commentary
+		for updates := 0; updates < 2; {
+			f := c.Next(t)
+			switch f.Type {
+			case broadcast.GameUpdateEvent:
+				updates++
+			case broadcast.CommentaryEvent:
+				var line broadcast.CommentaryLine
+				if err := json.Unmarshal(f.Data, &line); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, line.Commentary)
+			}
+		}
+		if name == "X" {
+			if len(got) != 0 {
+				t.Errorf("X, who didn't opt in, got commentary %+v", got)
+			}
+			continue
+		}
+		if len(got) != 2 || got[0].Move != 0 || got[1].Move != 1 || got[1].Text != "the centre, naturally" {
+			t.Errorf("%s got commentary %+v, want both lines, after moves 0 and 1", name, got)
+		}
+	}
+
+	var log commentary.Log
+	srv.Do(t, "GET", "/api/game/"+id+"/commentary", "", &log)
+	if !log.Commentator || len(log.Lines) != 2 || log.Lines[1].Move != 1 {
+		t.Errorf("GET commentary: %+v", log)
+	}
+
+	var apiErr *testutil.APIError
+	if _, err := srv.Do(t, "DELETE", "/api/game/"+id+"/commentator", `{"player":"spectator"}`, nil); !errors.As(err, &apiErr) || apiErr.Code != "not_joined" {
+		t.Errorf("revoking as a spectator: %v", err)
+	}
+	if res, err := srv.Do(t, "DELETE", "/api/game/"+id+"/commentator", `{"player":"O"}`, nil); err != nil || res.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoking as O: %v", err)
+	}
+	if err := comment("one more thing"); !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden || apiErr.Code != "not_commentator" {
+		t.Errorf("commenting once revoked: %v", err)
+	}
+}
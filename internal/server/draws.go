@@ -0,0 +1,18 @@
+package server
+
+import (
+	"context"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// watchDeadPositions broadcasts the games with an early draw that end
+// once neither side can win, which happens shortly after the move that
+// led there rather than in answer to a request.
+func watchDeadPositions(games *game.Service, hub *broadcast.Hub) {
+	games.OnDeadPosition(func(gs models.GameState) {
+		hub.Broadcast(context.Background(), gs.ID, &gs)
+	})
+}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/htmx"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestEmbedPage opens a game's embed page and stream from the browser
+// of a player in it, and checks both show the board as spectators see
+// it, live, under the configured frame-ancestors.
+func TestEmbedPage(t *testing.T) {
+	for _, tt := range []struct {
+		ancestors []string
+		csp       string
+	}{
+		{nil, "frame-ancestors 'self'"},
+		{[]string{"https://example.com", "https://blog.example.org"}, "frame-ancestors https://example.com https://blog.example.org"},
+	} {
+		srv := testutil.Start(t, server.Config{EmbedAncestors: tt.ancestors})
+		xBrowser, oBrowser := browser(t), browser(t)
+		id := gameIDPattern.FindStringSubmatch(post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}}))[1]
+		post(t, oBrowser, srv, "/htmx/join/"+id, url.Values{"player": {"O"}})
+
+		for _, query := range []string{"", "?player=spectator", "?player=X"} {
+			res, err := xBrowser.Get(srv.URL + "/embed/" + id + query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK || res.Header.Get("Content-Security-Policy") != tt.csp {
+				t.Errorf("/embed/%s%s: %d with CSP %q, want %q", id, query, res.StatusCode, res.Header.Get("Content-Security-Policy"), tt.csp)
+			}
+			if movePattern.MatchString(string(page)) || strings.Contains(string(page), "lobby") {
+				t.Errorf("/embed/%s%s isn't a bare read-only board:\n%s", id, query, page)
+			}
+			if !strings.Contains(string(page), `sse-connect="/htmx/sse/`+id+`?embed=1"`) {
+				t.Errorf("/embed/%s%s doesn't follow the game:\n%s", id, query, page)
+			}
+		}
+
+		stream := srv.OpenSSE(t, xBrowser, "/htmx/sse/"+id+"?embed=1&player=X")
+		if got := stream.Response.Header.Get(htmx.PerspectiveHeader); got != "spectator" {
+			t.Errorf("embedded stream perspective %q, want spectator", got)
+		}
+		stream.NextOf(t, broadcast.GameUpdateEvent)
+		post(t, xBrowser, srv, "/htmx/move/"+id+"/4?player=X", nil)
+		update := stream.NextOf(t, broadcast.GameUpdateEvent).Data
+		if x, _ := marks(update); x != 1 || movePattern.MatchString(update) || !strings.Contains(update, `class="embed-link"`) {
+			t.Errorf("embedded update after X's move:\n%s", update)
+		}
+	}
+
+	srv := testutil.Start(t, server.Config{})
+	res, err := http.Get(srv.URL + "/embed/nosuchgame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("embedding a game that doesn't exist: %s", res.Status)
+	}
+}
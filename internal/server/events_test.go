@@ -0,0 +1,54 @@
+package server_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+)
+
+// events is the answer of the events endpoint.
+type events struct {
+	Seq      uint64                  `json:"seq"`
+	Complete bool                    `json:"complete"`
+	Events   []broadcast.LoggedEvent `json:"events"`
+}
+
+// TestEventsBackfill has a WebSocket client miss an update, notice the
+// gap in the numbers of the next, and fill it from the events endpoint.
+func TestEventsBackfill(t *testing.T) {
+	srv, g := startGame(t)
+	ws := srv.DialWS(t, g.ID, "")
+	ws.Connected(t)
+
+	var seqs []uint64
+	for i, pos := range xWins[:3] {
+		srv.MustMove(t, g.ID, mover(i), pos)
+		seqs = append(seqs, ws.NextOf(t, broadcast.GameUpdateEvent).Seq)
+	}
+	// The second update is taken as lost
+	got, missed := seqs[0], seqs[1]
+	if seqs[2] != got+2 {
+		t.Fatalf("updates numbered %v, want consecutive", seqs)
+	}
+
+	var backfill events
+	if _, err := srv.Do(t, http.MethodGet, "/api/game/"+g.ID+"/events?fromSeq="+strconv.FormatUint(got, 10), "", &backfill); err != nil {
+		t.Fatal(err)
+	}
+	if !backfill.Complete || backfill.Seq != seqs[2] || len(backfill.Events) != 2 {
+		t.Fatalf("since %d: %+v", got, backfill)
+	}
+	ev := backfill.Events[0]
+	if ev.Seq != missed || ev.Type != broadcast.GameUpdateEvent || ev.Game == nil || len(ev.Game.History) != 2 {
+		t.Errorf("the missed event %+v, want the update after the second move", ev)
+	}
+
+	for _, query := range []string{"?fromSeq=-1", "?fromSeq=x"} {
+		res, err := srv.Do(t, http.MethodGet, "/api/game/"+g.ID+"/events"+query, "", nil)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: %d %v, want 400", query, res.StatusCode, err)
+		}
+	}
+}
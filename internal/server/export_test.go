@@ -0,0 +1,160 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// importResponse is the answer to an import.
+type importResponse struct {
+	Imported int `json:"imported"`
+	Failed   int `json:"failed"`
+	Results  []struct {
+		Line  int    `json:"line"`
+		ID    string `json:"id"`
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	} `json:"results"`
+}
+
+// export downloads the server's games in progress.
+func export(t *testing.T, srv *testutil.Server, key string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/admin/export", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("export: %s, %v", res.Status, err)
+	}
+	return string(body)
+}
+
+// importGames uploads games, as export wrote them, with the query given.
+func importGames(t *testing.T, srv *testutil.Server, key, query, games string) importResponse {
+	t.Helper()
+	var resp importResponse
+	if _, err := srv.Do(t, http.MethodPost, "/api/admin/import"+query, games, &resp, "Authorization", "Bearer "+key); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	return resp
+}
+
+// TestExportImportRoundTrip moves the games in progress on one server
+// to a second, as when moving an instance to a new host, and finishes
+// one there.
+func TestExportImportRoundTrip(t *testing.T) {
+	a := testutil.Start(t, server.Config{AdminKey: "a-admin"})
+	b := testutil.Start(t, server.Config{AdminKey: "b-admin"})
+
+	online := a.CreateGame(t, "").ID
+	a.JoinAs(t, online, models.PlayerX)
+	a.JoinAs(t, online, models.PlayerO)
+	a.MustMove(t, online, models.PlayerX, 0)
+	moved := a.MustMove(t, online, models.PlayerO, 3)
+	hotseat := a.CreateGame(t, `{"mode":"hotseat"}`).ID
+	a.MustMove(t, hotseat, models.PlayerX, 4)
+	finished := a.CreateGame(t, `{"mode":"hotseat"}`).ID
+	for i, pos := range xWins {
+		a.MustMove(t, finished, []models.Player{models.PlayerX, models.PlayerO}[i%2], pos)
+	}
+
+	games := export(t, a, "a-admin")
+	var ids []string
+	for line := range strings.Lines(games) {
+		var g models.GameState
+		if err := json.Unmarshal([]byte(line), &g); err != nil {
+			t.Fatalf("export line %q: %v", line, err)
+		}
+		ids = append(ids, g.ID)
+	}
+	want := []string{online, hotseat}
+	slices.Sort(ids)
+	slices.Sort(want)
+	if !slices.Equal(ids, want) {
+		t.Fatalf("exported %v, want the games in progress %v", ids, want)
+	}
+
+	if resp := importGames(t, b, "b-admin", "", games); resp.Imported != 2 || resp.Failed != 0 {
+		t.Fatalf("import: %+v", resp)
+	}
+	// Imported games are as they were, down to their history
+	var got models.GameState
+	if _, err := b.Do(t, http.MethodGet, "/api/game/"+online, "", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Board != moved.Board || got.CurrentTurn != models.PlayerX || len(got.History) != 2 || !got.PlayerXJoined || !got.PlayerOJoined {
+		t.Errorf("imported %s as %s, turn %s, %d moves", online, got.Board, got.CurrentTurn, len(got.History))
+	}
+
+	// And go on from there, watched on the new server
+	watcher := b.DialWS(t, online, "")
+	watcher.Connected(t)
+	b.MustMove(t, online, models.PlayerX, 1)
+	b.MustMove(t, online, models.PlayerO, 4)
+	won := b.MustMove(t, online, models.PlayerX, 2)
+	if won.Winner != models.PlayerX || !won.IsOver {
+		t.Errorf("the game went on to %s, winner %q", won.Board, won.Winner)
+	}
+	for range 3 {
+		g := watcher.NextOf(t, broadcast.GameUpdateEvent).Game(t)
+		if g.ID != online {
+			t.Errorf("the watcher got %s", g.ID)
+		}
+	}
+
+	// A second import clashes with the games now there, unless
+	// overwriting them, which brings the finished game back
+	resp := importGames(t, b, "b-admin", "", games)
+	if resp.Imported != 0 || resp.Failed != 2 || resp.Results[0].Code != "game_exists" {
+		t.Errorf("importing again: %+v", resp)
+	}
+	if resp := importGames(t, b, "b-admin", "?overwrite=1", games); resp.Imported != 2 || resp.Failed != 0 {
+		t.Errorf("overwriting: %+v", resp)
+	}
+	if g := watcher.NextOf(t, broadcast.GameUpdateEvent).Game(t); g.IsOver || g.Board != moved.Board {
+		t.Errorf("the watcher saw the overwritten game as %s", g.Board)
+	}
+}
+
+// TestImportReportsEachLine checks a bad line fails alone, with its line
+// number, and the rest are imported.
+func TestImportReportsEachLine(t *testing.T) {
+	a := testutil.Start(t, server.Config{AdminKey: "a-admin"})
+	b := testutil.Start(t, server.Config{AdminKey: "b-admin"})
+	a.CreateGame(t, "")
+	good := export(t, a, "a-admin")
+
+	var bad models.GameState
+	json.Unmarshal([]byte(good), &bad)
+	bad.ID = "Not An ID"
+	badLine, _ := json.Marshal(bad)
+	resp := importGames(t, b, "b-admin", "", "not json\n\n"+string(badLine)+"\n"+good)
+	if resp.Imported != 1 || resp.Failed != 2 || len(resp.Results) != 3 {
+		t.Fatalf("import: %+v", resp)
+	}
+	for i, line := range []int{1, 3, 4} {
+		r := resp.Results[i]
+		if r.Line != line || (r.Error == "") != (line == 4) {
+			t.Errorf("result %d: %+v, want line %d", i, r, line)
+		}
+	}
+
+	// Without the admin key nothing is imported
+	if _, err := b.Do(t, http.MethodPost, "/api/admin/import", good, nil, "Authorization", "Bearer a-admin"); err == nil {
+		t.Error("imported with another server's admin key")
+	}
+}
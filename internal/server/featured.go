@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// watchFeatured offers every finished game to the featured feed, and
+// publishes the feed on the lobby topic whenever it changes, whether by
+// a game being featured or an admin pinning one.
+func watchFeatured(games *game.Service, hub *broadcast.Hub, feed *featured.Feed) {
+	feed.OnChange(func(entries []featured.Entry) {
+		hub.BroadcastTopic(context.Background(), broadcast.LobbyTopic, broadcast.Event{
+			Name: broadcast.FeaturedEvent,
+			Data: entries,
+		})
+	})
+	games.OnGameFinished(func(gs models.GameState) {
+		if _, err := feed.Consider(context.Background(), &gs); err != nil {
+			slog.Warn("featuring game failed", "game_id", gs.ID, "error", err)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package server_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestFeatured plays a game worth featuring with a lobby stream open,
+// and checks the feed shows it on the API, the stream and the home page
+// fragment, and that pinning it takes the admin key.
+func TestFeatured(t *testing.T) {
+	srv := testutil.Start(t, server.Config{AdminKey: "admin"})
+	lobby := srv.OpenSSE(t, nil, "/htmx/sse/lobby")
+	// The feed comes on the lobby event with the list of open games,
+	// swapped in out of band
+	nextFeed := func() string {
+		t.Helper()
+		for {
+			if ev := lobby.NextOf(t, broadcast.LobbyEvent); strings.Contains(ev.Data, `<ul class="featured" id="featured"`) {
+				return ev.Data
+			}
+		}
+	}
+
+	drawn := srv.CreateGame(t, `{"mode":"hotseat"}`).ID
+	for i, pos := range []int{0, 4, 8, 1, 7, 6, 2, 5, 3} {
+		srv.MustMove(t, drawn, mover(i), pos)
+	}
+	id := srv.CreateGame(t, `{"mode":"hotseat"}`).ID
+	for i, pos := range xWins {
+		srv.MustMove(t, id, mover(i), pos)
+	}
+
+	entry := `<li id="featured-` + id + `"`
+	if change := nextFeed(); !strings.Contains(change, entry) || strings.Contains(change, drawn) || !strings.Contains(change, `href="/?game=`+id+`"`) {
+		t.Errorf("the feed on the lobby stream doesn't link just the won game:\n%s", change)
+	}
+	var feed []featured.Entry
+	srv.Do(t, "GET", "/api/featured", "", &feed)
+	if len(feed) != 1 || feed[0].GameID != id || feed[0].Reason != featured.ReasonFastestWin || feed[0].Pinned {
+		t.Fatalf("GET /api/featured: %+v, want the won game as the fastest win", feed)
+	}
+
+	var apiErr *testutil.APIError
+	if _, err := srv.Do(t, "PUT", "/api/admin/featured/"+id+"/pin", "", nil); !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("pinning without the admin key: %v", err)
+	}
+	if _, err := srv.Do(t, "PUT", "/api/admin/featured/"+drawn+"/pin", "", nil, "Authorization", "Bearer admin"); !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("pinning a game not featured: %v", err)
+	}
+	srv.Do(t, "PUT", "/api/admin/featured/"+id+"/pin", "", &feed, "Authorization", "Bearer admin")
+	if len(feed) != 1 || !feed[0].Pinned {
+		t.Errorf("after pinning: %+v", feed)
+	}
+	if change := nextFeed(); !strings.Contains(change, entry+` class="pinned"`) {
+		t.Errorf("the lobby stream wasn't told of the pin:\n%s", change)
+	}
+
+	res, err := http.Get(srv.URL + "/htmx/featured")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), entry+` class="pinned"`) {
+		t.Errorf("/htmx/featured doesn't show the pinned game:\n%s", page)
+	}
+
+	feed = nil
+	srv.Do(t, "DELETE", "/api/admin/featured/"+id+"/pin", "", &feed, "Authorization", "Bearer admin")
+	if len(feed) != 1 || feed[0].Pinned {
+		t.Errorf("after unpinning: %+v", feed)
+	}
+}
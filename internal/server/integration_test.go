@@ -0,0 +1,550 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// startGame starts a server and a game on it with both sides joined.
+func startGame(t *testing.T) (*testutil.Server, *models.GameState) {
+	t.Helper()
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, "")
+	srv.JoinAs(t, g.ID, models.PlayerX)
+	g, _ = srv.JoinAs(t, g.ID, models.PlayerO)
+	return srv, g
+}
+
+// xWins is a game X wins along the top row, as X, O, X, O, X.
+var xWins = []int{0, 3, 1, 4, 2}
+
+// mover returns who makes the i-th move of a game, X first.
+func mover(i int) models.Player {
+	if i%2 == 0 {
+		return models.PlayerX
+	}
+	return models.PlayerO
+}
+
+func TestJSONGameWithWSObserver(t *testing.T) {
+	srv, g := startGame(t)
+	observer := srv.DialWS(t, g.ID, "")
+	first, welcome := observer.Connected(t)
+	if first.ID != g.ID || !first.PlayerXJoined || !first.PlayerOJoined {
+		t.Fatalf("connected to %+v, want %s with both sides joined", first, g.ID)
+	}
+
+	seq := welcome.Seq
+	for i, pos := range xWins {
+		moved := srv.MustMove(t, g.ID, mover(i), pos)
+		f := observer.NextOf(t, broadcast.GameUpdateEvent)
+		seen := f.Game(t)
+		if seen.Board != moved.Board || seen.Version != moved.Version {
+			t.Fatalf("move %d: the observer saw %s v%d, the mover %s v%d", i, seen.Board, seen.Version, moved.Board, moved.Version)
+		}
+		if f.Seq <= seq {
+			t.Errorf("move %d: seq %d after %d", i, f.Seq, seq)
+		}
+		seq = f.Seq
+	}
+
+	var final models.GameState
+	if _, err := srv.Do(t, http.MethodGet, "/api/game/"+g.ID, "", &final); err != nil {
+		t.Fatal(err)
+	}
+	if !final.IsOver || final.Winner != models.PlayerX || len(final.History) != len(xWins) {
+		t.Errorf("final game %+v, want won by X in %d moves", final, len(xWins))
+	}
+}
+
+// gameIDPattern finds the game's ID in a rendered board.
+var gameIDPattern = regexp.MustCompile(`data-game-id="([^"]+)"`)
+
+// browser is a client keeping cookies, as a browser keeps its seats.
+func browser(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+// post submits form to path as htmx does, with headers given as name
+// and value pairs, and returns the HTML answer.
+func post(t *testing.T, client *http.Client, srv *testutil.Server, path string, form url.Values, headers ...string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("POST %s: %s %s\n%s", path, res.Status, res.Header.Get("Content-Type"), body)
+	}
+	return string(body)
+}
+
+// TestSSETakeoverNeedsTheSeat checks an event stream claiming a side
+// without the seats cookie watches, and can't take the side over from
+// the browser that joined it, which can.
+func TestSSETakeoverNeedsTheSeat(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser := browser(t)
+	m := gameIDPattern.FindStringSubmatch(post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}}))
+	if m == nil {
+		t.Fatal("no game ID in the new game's page")
+	}
+	id := m[1]
+	srv.JoinAs(t, id, models.PlayerO)
+	path := "/htmx/sse/" + id + "?player=X"
+
+	first := srv.OpenSSE(t, xBrowser, path)
+	if got := first.Response.Header.Get("X-Perspective"); got != "X" {
+		t.Fatalf("X-Perspective %q for the browser that joined X", got)
+	}
+	first.NextOf(t, broadcast.GameUpdateEvent)
+	stranger := srv.OpenSSE(t, browser(t), path+"&takeover=1")
+	if got := stranger.Response.Header.Get("X-Perspective"); got != "spectator" {
+		t.Errorf("X-Perspective %q without the seats cookie, want spectator", got)
+	}
+	srv.MustMove(t, id, models.PlayerX, 4)
+	for ev := first.Next(t); ev.Name != broadcast.GameUpdateEvent; ev = first.Next(t) {
+		if ev.Name == broadcast.SupersededEvent {
+			t.Fatal("superseded by a stream without the seats cookie")
+		}
+	}
+
+	srv.OpenSSE(t, xBrowser, path+"&takeover=1")
+	first.NextOf(t, broadcast.SupersededEvent)
+}
+
+// connIDPattern finds the connection ID a rendered game's event stream
+// goes by.
+var connIDPattern = regexp.MustCompile(`conn=([0-9a-f-]{36})`)
+
+// TestSSEErrorsGoToTheRequester checks a rejected htmx request's
+// game-error event goes to the page that made it alone, not to every
+// stream of the side it claimed.
+func TestSSEErrorsGoToTheRequester(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser, oBrowser := browser(t), browser(t)
+	xPage := post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}})
+	id := gameIDPattern.FindStringSubmatch(xPage)[1]
+	oPage := post(t, oBrowser, srv, "/htmx/join/"+id, url.Values{"player": {"O"}})
+	xConn, oConn := connIDPattern.FindStringSubmatch(xPage), connIDPattern.FindStringSubmatch(oPage)
+	if xConn == nil || oConn == nil || xConn[1] == oConn[1] {
+		t.Fatalf("connection IDs %v and %v", xConn, oConn)
+	}
+
+	x := srv.OpenSSE(t, xBrowser, "/htmx/sse/"+id+"?player=X&conn="+xConn[1])
+	o := srv.OpenSSE(t, oBrowser, "/htmx/sse/"+id+"?player=O&conn="+oConn[1])
+	x.NextOf(t, broadcast.GameUpdateEvent)
+	o.NextOf(t, broadcast.GameUpdateEvent)
+
+	// O moves out of turn from its page
+	post(t, oBrowser, srv, "/htmx/move/"+id+"/4?player=O", nil, broadcast.ConnHeader, oConn[1])
+	if ev := o.NextOf(t, broadcast.ErrorEvent); !strings.Contains(ev.Data, "not_your_turn") {
+		t.Errorf("game-error %q, want not_your_turn", ev.Data)
+	}
+
+	// Without a connection to answer to, and claiming X's side over the
+	// API, nobody is sent the error. Events go out in order, so neither
+	// stream is sent one before the next move's update.
+	srv.MoveJSON(t, id, models.Move{Player: models.PlayerX, Position: 9})
+	post(t, oBrowser, srv, "/htmx/move/"+id+"/4?player=O", nil)
+	srv.MustMove(t, id, models.PlayerX, 4)
+	for name, stream := range map[string]*testutil.SSE{"X": x, "O": o} {
+		for ev := stream.Next(t); ev.Name != broadcast.GameUpdateEvent; ev = stream.Next(t) {
+			if ev.Name == broadcast.ErrorEvent {
+				t.Errorf("%s's stream got game-error %q", name, ev.Data)
+			}
+		}
+	}
+}
+
+// marks counts the cells of a rendered board marked by each side.
+func marks(html string) (x, o int) {
+	return strings.Count(html, `"cell disabled x`), strings.Count(html, `"cell disabled o`)
+}
+
+func TestHTMXGameWithSSEObserver(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser, oBrowser := browser(t), browser(t)
+
+	page := post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}})
+	m := gameIDPattern.FindStringSubmatch(page)
+	if m == nil {
+		t.Fatalf("no game ID in the new game's page:\n%s", page)
+	}
+	id := m[1]
+	post(t, oBrowser, srv, "/htmx/join/"+id, url.Values{"player": {"O"}})
+
+	observer := srv.OpenSSE(t, nil, "/htmx/sse/"+id)
+	if ct := observer.Response.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Errorf("Content-Type %q", ct)
+	}
+	if got := observer.Response.Header.Get("X-Perspective"); got != "spectator" {
+		t.Errorf("X-Perspective %q, want spectator", got)
+	}
+	initial := observer.NextOf(t, broadcast.GameUpdateEvent)
+	if x, o := marks(initial.Data); x != 0 || o != 0 {
+		t.Fatalf("the initial board has %d X and %d O marks", x, o)
+	}
+
+	lastSeq := uint64(0)
+	for i, pos := range xWins {
+		player, client := mover(i), xBrowser
+		if player == models.PlayerO {
+			client = oBrowser
+		}
+		post(t, client, srv, "/htmx/move/"+id+"/"+strconv.Itoa(pos)+"?player="+string(player), nil)
+
+		ev := observer.NextOf(t, broadcast.GameUpdateEvent)
+		x, o := marks(ev.Data)
+		if x != (i+2)/2 || o != (i+1)/2 {
+			t.Fatalf("move %d: the observer's board has %d X and %d O marks", i, x, o)
+		}
+		// Each update is numbered, for Last-Event-ID, see parseEventID
+		num, _, ok := strings.Cut(ev.ID, "-")
+		seq, err := strconv.ParseUint(num, 10, 64)
+		if !ok || err != nil || seq <= lastSeq {
+			t.Fatalf("move %d: event ID %q after seq %d", i, ev.ID, lastSeq)
+		}
+		lastSeq = seq
+	}
+
+	var final models.GameState
+	if _, err := srv.Do(t, http.MethodGet, "/api/game/"+id, "", &final); err != nil {
+		t.Fatal(err)
+	}
+	if final.Winner != models.PlayerX {
+		t.Errorf("the game was won by %q, want X", final.Winner)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, "")
+	srv.JoinAs(t, g.ID, models.PlayerX)
+	_, token := srv.JoinAs(t, g.ID, models.PlayerO)
+	srv.MustMove(t, g.ID, models.PlayerX, 4)
+	over := srv.CreateGame(t, "")
+	srv.JoinAs(t, over.ID, models.PlayerX)
+	srv.JoinAs(t, over.ID, models.PlayerO)
+	for i, pos := range xWins {
+		srv.MustMove(t, over.ID, mover(i), pos)
+	}
+	waiting := srv.CreateGame(t, "")
+	srv.JoinAs(t, waiting.ID, models.PlayerX)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"out of turn", "POST", "/api/game/" + g.ID, `{"player":"X","position":0}`, http.StatusConflict, "not_your_turn"},
+		{"taken", "POST", "/api/game/" + g.ID, `{"player":"O","position":4}`, http.StatusConflict, "position_taken"},
+		{"off the board", "POST", "/api/game/" + g.ID, `{"player":"O","position":9}`, http.StatusBadRequest, "invalid_move"},
+		{"bad cell", "POST", "/api/game/" + g.ID, `{"player":"O","cell":"z9"}`, http.StatusBadRequest, "invalid_cell"},
+		{"game over", "POST", "/api/game/" + over.ID, `{"player":"O","position":8}`, http.StatusConflict, "game_over"},
+		{"no opponent", "POST", "/api/game/" + waiting.ID, `{"player":"X","position":0}`, http.StatusConflict, "waiting_for_opponent"},
+		{"no such game", "POST", "/api/game/nosuchgame", `{"player":"X","position":0}`, http.StatusNotFound, "game_not_found"},
+		{"malformed ID", "GET", "/api/game/not.an.id", "", http.StatusNotFound, "game_not_found"},
+		{"slot taken", "POST", "/api/game/" + g.ID + "/join", `{"player":"X"}`, http.StatusConflict, "slot_taken"},
+		{"bad body", "POST", "/api/game/" + g.ID, `{"player":`, http.StatusBadRequest, ""},
+		{"htmx asking for JSON", "POST", "/htmx/move/" + g.ID + "/4?player=O", "", http.StatusConflict, "position_taken"},
+		{"htmx off the board", "POST", "/htmx/move/" + g.ID + "/9?player=O", "", http.StatusBadRequest, "invalid_move"},
+		{"htmx without a side", "POST", "/htmx/move/" + g.ID + "/0", "", http.StatusBadRequest, "invalid_player"},
+	}
+	for _, tt := range tests {
+		_, err := srv.Do(t, tt.method, tt.path, tt.body, nil, "Accept", "application/json")
+		var apiErr *testutil.APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: got %v, want an error", tt.name, err)
+			continue
+		}
+		if apiErr.Status != tt.status || apiErr.Code != tt.code || apiErr.ErrorBody.Error == "" {
+			t.Errorf("%s: got %d %q %q, want %d %q", tt.name, apiErr.Status, apiErr.Code, apiErr.ErrorBody.Error, tt.status, tt.code)
+		}
+	}
+
+	// The same errors reach WebSocket clients: requests made on a
+	// connection's behalf as game-error events, and their rejected
+	// messages as error frames
+	o := srv.DialWS(t, g.ID, "player=O", seat.TokenHeader, token)
+	_, welcome := o.Connected(t)
+	move := `{"player":"O","position":4}`
+	if _, err := srv.Do(t, http.MethodPost, "/api/game/"+g.ID, move, nil, broadcast.ConnHeader, welcome.ConnID); err == nil {
+		t.Fatal("O took a taken cell")
+	}
+	f := o.NextOf(t, broadcast.ErrorEvent)
+	if !strings.Contains(string(f.Data), `"code":"position_taken"`) {
+		t.Errorf("game-error %s, want position_taken", f.Data)
+	}
+	o.Send(t, map[string]any{"player": "X", "position": 0})
+	for f = o.Next(t); f.Type != ""; f = o.Next(t) {
+	}
+	if f.Code != "not_your_turn" || f.Error == "" {
+		t.Errorf("error frame %+v, want not_your_turn", f)
+	}
+}
+
+func TestWSReconnection(t *testing.T) {
+	srv, g := startGame(t)
+	first := srv.DialWS(t, g.ID, "")
+	_, before := first.Connected(t)
+	first.Conn.Close()
+
+	// Moves made while the client is away
+	srv.MustMove(t, g.ID, models.PlayerX, 0)
+	moved := srv.MustMove(t, g.ID, models.PlayerO, 4)
+
+	again := srv.DialWS(t, g.ID, "")
+	current, after := again.Connected(t)
+	if current.Board != moved.Board || current.Version != moved.Version {
+		t.Errorf("reconnected to %s v%d, want %s v%d", current.Board, current.Version, moved.Board, moved.Version)
+	}
+	if after.InstanceID != before.InstanceID {
+		t.Errorf("instance %s after %s on the same server", after.InstanceID, before.InstanceID)
+	}
+	if after.Seq != before.Seq+2 {
+		t.Errorf("seq %d after %d and two moves", after.Seq, before.Seq)
+	}
+
+	// And the events missed can be fetched to fill the gap
+	var missed struct {
+		Seq      uint64                  `json:"seq"`
+		Complete bool                    `json:"complete"`
+		Events   []broadcast.LoggedEvent `json:"events"`
+	}
+	path := "/api/game/" + g.ID + "/events?fromSeq=" + strconv.FormatUint(before.Seq, 10)
+	if _, err := srv.Do(t, http.MethodGet, path, "", &missed); err != nil {
+		t.Fatal(err)
+	}
+	if !missed.Complete || missed.Seq != after.Seq || len(missed.Events) != 2 {
+		t.Fatalf("missed %+v, want the two moves", missed)
+	}
+	for i, ev := range missed.Events {
+		if ev.Type != broadcast.GameUpdateEvent || ev.Seq != before.Seq+uint64(i)+1 || ev.Game == nil || len(ev.Game.History) != i+1 {
+			t.Errorf("missed event %d: %+v", i, ev)
+		}
+	}
+
+	// The new connection is live
+	srv.MustMove(t, g.ID, models.PlayerX, 8)
+	if f := again.NextOf(t, broadcast.GameUpdateEvent); f.Seq != after.Seq+1 || f.Game(t).Board[8] != models.PlayerX {
+		t.Errorf("after reconnecting got seq %d %s", f.Seq, f.Game(t).Board)
+	}
+}
+
+func TestSSEReconnection(t *testing.T) {
+	srv, g := startGame(t)
+	path := "/htmx/sse/" + g.ID
+	first := srv.OpenSSE(t, nil, path)
+	seen := first.NextOf(t, broadcast.GameUpdateEvent)
+	first.Response.Body.Close()
+
+	// A client coming back having seen the current state isn't sent it
+	// again, but gets what happens next
+	again := srv.OpenSSE(t, nil, path, "Last-Event-ID", seen.ID)
+	again.None(t, 200*time.Millisecond)
+	srv.MustMove(t, g.ID, models.PlayerX, 0)
+	update := again.NextOf(t, broadcast.GameUpdateEvent)
+	if x, _ := marks(update.Data); x != 1 {
+		t.Errorf("after reconnecting the board has %d X marks, want 1", x)
+	}
+
+	// One that missed a move is sent the state it missed straight away
+	stale := srv.OpenSSE(t, nil, path, "Last-Event-ID", seen.ID)
+	caughtUp := stale.NextOf(t, broadcast.GameUpdateEvent)
+	if caughtUp.ID != update.ID {
+		t.Errorf("caught up to event %q, want %q", caughtUp.ID, update.ID)
+	}
+	if x, _ := marks(caughtUp.Data); x != 1 {
+		t.Errorf("caught up to a board with %d X marks, want 1", x)
+	}
+}
+
+func TestShutdownAsksClientsToReconnect(t *testing.T) {
+	srv, g := startGame(t)
+	stream := srv.OpenSSE(t, nil, "/htmx/sse/"+g.ID)
+	stream.NextOf(t, broadcast.GameUpdateEvent)
+	conn := srv.DialWS(t, g.ID, "")
+	conn.Connected(t)
+
+	srv.Stop(t)
+	last := stream.Ended(t)
+	if len(last) != 1 || last[0].Name != broadcast.RestartEvent || last[0].Retry != broadcast.RestartRetry {
+		t.Errorf("the stream ended with %+v, want a %s event", last, broadcast.RestartEvent)
+	}
+	if code := conn.Closed(t); code != broadcast.ReasonShutdown.Code {
+		t.Errorf("the WebSocket closed with %d, want %d", code, broadcast.ReasonShutdown.Code)
+	}
+}
+
+// TestJoinIsBroadcast has O join a game X waits in, from a page and
+// over the API, and checks X's event stream leaves the waiting room
+// without a move being made.
+func TestJoinIsBroadcast(t *testing.T) {
+	joins := map[string]func(t *testing.T, srv *testutil.Server, id string){
+		"htmx": func(t *testing.T, srv *testutil.Server, id string) {
+			post(t, browser(t), srv, "/htmx/join/"+id, url.Values{"player": {"O"}})
+		},
+		"api": func(t *testing.T, srv *testutil.Server, id string) {
+			srv.JoinAs(t, id, models.PlayerO)
+		},
+	}
+	for name, join := range joins {
+		t.Run(name, func(t *testing.T) {
+			srv := testutil.Start(t, server.Config{})
+			xBrowser := browser(t)
+			m := gameIDPattern.FindStringSubmatch(post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}}))
+			if m == nil {
+				t.Fatal("no game ID in the new game's page")
+			}
+			id := m[1]
+			x := srv.OpenSSE(t, xBrowser, "/htmx/sse/"+id+"?player=X")
+			if ev := x.NextOf(t, broadcast.GameUpdateEvent); !strings.Contains(ev.Data, "waiting-room") {
+				t.Fatalf("X isn't in the waiting room before O joins:\n%s", ev.Data)
+			}
+
+			join(t, srv, id)
+			ev := x.NextOf(t, broadcast.GameUpdateEvent)
+			if strings.Contains(ev.Data, "waiting-room") {
+				t.Errorf("X is still in the waiting room after O joined:\n%s", ev.Data)
+			}
+			if x, o := marks(ev.Data); x != 0 || o != 0 {
+				t.Errorf("the board has %d X and %d O marks", x, o)
+			}
+		})
+	}
+}
+
+// TestTurnNotification checks each move over the API tells the player
+// to move next, and them alone, that it is their turn.
+func TestTurnNotification(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	id := srv.CreateGame(t, "").ID
+	_, xToken := srv.JoinAs(t, id, models.PlayerX)
+	_, oToken := srv.JoinAs(t, id, models.PlayerO)
+	x := srv.DialWS(t, id, "player=X", seat.TokenHeader, xToken)
+	x.Connected(t)
+	o := srv.DialWS(t, id, "player=O", seat.TokenHeader, oToken)
+	o.Connected(t)
+	// updateOrTurn returns the next game-update or turn-notification
+	// frame c gets
+	updateOrTurn := func(c *testutil.WS) testutil.Frame {
+		for {
+			if f := c.Next(t); f.Type == broadcast.GameUpdateEvent || f.Type == broadcast.TurnNotificationEvent {
+				return f
+			}
+		}
+	}
+
+	for i, pos := range xWins {
+		player, mover, waiter := mover(i), x, o
+		if player == models.PlayerO {
+			mover, waiter = o, x
+		}
+		srv.MustMove(t, id, player, pos)
+		if f := updateOrTurn(mover); f.Type != broadcast.GameUpdateEvent {
+			t.Fatalf("move %d: %s was sent %s after their own move", i, player, f.Type)
+		}
+		if f := updateOrTurn(waiter); f.Type != broadcast.GameUpdateEvent {
+			t.Fatalf("move %d: the opponent was sent %s before the update", i, f.Type)
+		}
+		if i == len(xWins)-1 {
+			break
+		}
+		f := updateOrTurn(waiter)
+		var notice broadcast.TurnNotification
+		if f.Type != broadcast.TurnNotificationEvent || json.Unmarshal(f.Data, &notice) != nil {
+			t.Fatalf("move %d: the opponent was sent %s %s, want their turn", i, f.Type, f.Data)
+		}
+		if notice.GameID != id || notice.Player == player {
+			t.Errorf("move %d: told %+v", i, notice)
+		}
+	}
+	// The game is over, so nobody is told it is their turn
+	for _, c := range []*testutil.WS{x, o} {
+		c.Conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		for {
+			var f testutil.Frame
+			if err := c.Conn.ReadJSON(&f); err != nil {
+				break
+			}
+			if f.Type == broadcast.TurnNotificationEvent {
+				t.Errorf("told %s after the game ended", f.Data)
+			}
+		}
+	}
+}
+
+// TestAdminEvents streams the ends of games to an admin while two games
+// are played, and checks only the one asked about is sent.
+func TestAdminEvents(t *testing.T) {
+	srv := testutil.Start(t, server.Config{AdminKey: "admin"})
+	watched := srv.CreateGame(t, `{"mode":"hotseat"}`).ID
+	other := srv.CreateGame(t, `{"mode":"hotseat"}`).ID
+	events := srv.OpenSSE(t, nil, "/api/admin/events?type=game_over,game_update&gameId="+watched, "Authorization", "Bearer admin")
+
+	for i, pos := range xWins {
+		srv.MustMove(t, other, mover(i), pos)
+		srv.MustMove(t, watched, mover(i), pos)
+	}
+	for i := range xWins {
+		ev := events.Next(t)
+		var got broadcast.FirehoseEvent
+		if err := json.Unmarshal([]byte(ev.Data), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := broadcast.FirehoseGameUpdate
+		if i == len(xWins)-1 {
+			want = broadcast.FirehoseGameOver
+		}
+		if ev.Name != want || got.Type != want || got.GameID != watched || got.Game == nil {
+			t.Errorf("event %d: %s %s for %s, want %s for %s", i, ev.Name, got.Type, got.GameID, want, watched)
+		}
+	}
+
+	res, err := http.Get(srv.URL + "/api/admin/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without the admin key: %s", res.Status)
+	}
+}
@@ -0,0 +1,42 @@
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"tiktaktoes/internal/journal"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestJournalRecoversAfterCrash starts a second server on the journal of
+// one that never shut down, as after it was killed, and checks every
+// move the first acknowledged is there, after the last snapshot too.
+func TestJournalRecoversAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	cfg := server.Config{
+		SnapshotPath: filepath.Join(dir, "games.json"),
+		Journal:      journal.Options{Dir: filepath.Join(dir, "journal")},
+	}
+	// A clean stop leaves a snapshot behind, so the crash below has one
+	// to start from as well as the journal
+	first := testutil.Start(t, cfg)
+	id := first.CreateGame(t, `{"mode":"hotseat"}`).ID
+	first.MustMove(t, id, models.PlayerX, 0)
+	first.Stop(t)
+
+	crashed := testutil.Start(t, cfg)
+	crashed.MustMove(t, id, models.PlayerO, 4)
+	acked := crashed.MustMove(t, id, models.PlayerX, 8)
+
+	recovered := testutil.Start(t, cfg)
+	var got models.GameState
+	if _, err := recovered.Do(t, http.MethodGet, "/api/game/"+id, "", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Board != acked.Board || len(got.History) != 3 || got.CurrentTurn != models.PlayerO {
+		t.Errorf("recovered %s with %d moves, want %s", got.Board, len(got.History), acked.Board)
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFDsStart is the first file descriptor systemd passes, see
+// sd_listen_fds(3).
+const listenFDsStart = 3
+
+// listen opens the listener the server accepts connections on: a socket
+// passed by systemd socket activation if there is one, else the unix
+// socket or TCP address in cfg.Listen, else cfg.Addr.
+func listen(cfg Config) (net.Listener, error) {
+	ln, err := systemdListener()
+	if ln != nil || err != nil {
+		return ln, err
+	}
+	if path, ok := strings.CutPrefix(cfg.Listen, "unix:"); ok {
+		return listenUnix(path, cfg.SocketMode)
+	}
+	addr := cfg.Listen
+	if addr == "" {
+		addr = cfg.Addr
+	}
+	return net.Listen("tcp", addr)
+}
+
+// systemdListener returns the first socket passed by systemd, or nil
+// when the process wasn't socket activated. The environment variables
+// are cleared so child processes don't mistake them for their own.
+func systemdListener() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, expected one", n)
+	}
+
+	f := os.NewFile(listenFDsStart, "systemd-socket")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket: %w", err)
+	}
+	return ln, nil
+}
+
+// listenUnix listens on a unix socket at path, removing a stale socket
+// file left behind by a process that didn't shut down cleanly. The
+// socket file is removed again when the listener is closed. A zero mode
+// leaves the permissions to the umask.
+func listenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes the socket file at path if nothing is
+// accepting connections on it. It refuses to touch anything that isn't
+// a socket or is still in use.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// socketDir returns a directory for unix sockets, whose paths must be
+// short, removed when the test ends.
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "ttt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestListenTCP(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"addr", Config{Addr: "127.0.0.1:0"}},
+		{"listen over addr", Config{Listen: "127.0.0.1:0", Addr: "256.0.0.1:1"}},
+	} {
+		ln, err := listen(tt.cfg)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if ln.Addr().Network() != "tcp" {
+			t.Errorf("%s: listening on %s", tt.name, ln.Addr().Network())
+		}
+		ln.Close()
+	}
+	if ln, err := listen(Config{Addr: "256.0.0.1:1"}); err == nil {
+		ln.Close()
+		t.Error("listened on a bad address")
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(socketDir(t), "ttt.sock")
+	ln, err := listen(Config{Listen: "unix:" + path, SocketMode: 0o660, Addr: "256.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Type() != fs.ModeSocket || info.Mode().Perm() != 0o660 {
+		t.Errorf("socket file has mode %v, want a socket with 0660", info.Mode())
+	}
+
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(conn)
+	conn.Close()
+	if string(got) != "ok" {
+		t.Errorf("read %q over the socket", got)
+	}
+
+	// A second server mustn't take the socket from the first
+	if ln2, err := listen(Config{Listen: "unix:" + path}); err == nil {
+		ln2.Close()
+		t.Error("listened on a socket in use")
+	}
+	ln.Close()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file left after closing: %v", err)
+	}
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	path := filepath.Join(socketDir(t), "ttt.sock")
+	// A socket file left behind, as by a server that was killed
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+	if _, err := os.Lstat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := listen(Config{Listen: "unix:" + path})
+	if err != nil {
+		t.Fatalf("listening over a stale socket: %v", err)
+	}
+	ln.Close()
+}
+
+func TestListenUnixRefusesOtherFiles(t *testing.T) {
+	path := filepath.Join(socketDir(t), "ttt.sock")
+	if err := os.WriteFile(path, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ln, err := listen(Config{Listen: "unix:" + path}); err == nil {
+		ln.Close()
+		t.Fatal("listened over a regular file")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "keep" {
+		t.Errorf("the file was replaced: %q", got)
+	}
+}
+
+func TestSystemdListenerEnvironment(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	for _, tt := range []struct {
+		name, pid, fds string
+		fails          bool
+	}{
+		{"not activated", "", "", false},
+		{"another process's", "1", "1", false},
+		{"bad count", pid, "x", true},
+		{"no sockets", pid, "0", true},
+		{"several sockets", pid, "2", true},
+	} {
+		t.Setenv("LISTEN_PID", tt.pid)
+		t.Setenv("LISTEN_FDS", tt.fds)
+		ln, err := systemdListener()
+		if ln != nil {
+			ln.Close()
+			t.Errorf("%s: got a listener", tt.name)
+		}
+		if (err != nil) != tt.fails {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.pid != "" && (os.Getenv("LISTEN_PID") != "" || os.Getenv("LISTEN_FDS") != "") {
+			t.Errorf("%s: the environment wasn't cleared", tt.name)
+		}
+	}
+}
+
+// TestSystemdActivation passes a listening socket to a child process as
+// systemd does, as its fd 3 with LISTEN_FDS set, and checks listen
+// accepts on it rather than the configured address. The child is this
+// test binary running TestSystemdActivationChild.
+func TestSystemdActivation(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSystemdActivationChild$")
+	cmd.Env = append(os.Environ(), "TTT_SYSTEMD_CHILD=1", "LISTEN_FDS=1")
+	cmd.ExtraFiles = []*os.File{f}
+	var out strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// The child accepts on the passed socket; this process never does
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(conn)
+	conn.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("child: %v\n%s", err, out.String())
+	}
+	if string(got) != "activated" {
+		t.Errorf("read %q from the child\n%s", got, out.String())
+	}
+}
+
+// TestSystemdActivationChild is the child of TestSystemdActivation.
+func TestSystemdActivationChild(t *testing.T) {
+	if os.Getenv("TTT_SYSTEMD_CHILD") == "" {
+		t.Skip("run by TestSystemdActivation")
+	}
+	// systemd sets it to the pid it starts, which the parent can't know
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	ln, err := listen(Config{Addr: "256.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("LISTEN_FDS left for child processes")
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("activated"))
+	conn.Close()
+}
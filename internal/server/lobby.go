@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// watchLobby publishes a lobby event whenever a game may have opened for
+// an opponent or stopped being open: as it is created, joined, vacated,
+// restored, cancelled or expired. Each event says whether the game is
+// open now, so subscribers need no memory of what they were told before.
+func watchLobby(games *game.Service, hub *broadcast.Hub) {
+	publish := func(gs models.GameState) {
+		hub.BroadcastTopic(context.Background(), broadcast.LobbyTopic, broadcast.Event{
+			Name: broadcast.LobbyEvent,
+			Data: broadcast.LobbyUpdate{Game: &gs, Open: game.Waiting(&gs)},
+		})
+	}
+	closed := func(gs models.GameState) {
+		hub.BroadcastTopic(context.Background(), broadcast.LobbyTopic, broadcast.Event{
+			Name: broadcast.LobbyEvent,
+			Data: broadcast.LobbyUpdate{Game: &gs},
+		})
+	}
+	games.OnGameCreated(publish)
+	games.OnPlayerJoined(publish)
+	games.OnSlotVacated(publish)
+	// A ready check that runs out vacates the unready
+	games.OnReadyChanged(publish)
+	games.OnGameRestored(publish)
+	games.OnGameCancelled(closed)
+	games.OnGameExpired(closed)
+}
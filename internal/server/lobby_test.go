@@ -0,0 +1,42 @@
+package server_test
+
+import (
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestLobby has two lobby streams watch a game open and then fill.
+func TestLobby(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	streams := []*testutil.SSE{
+		srv.OpenSSE(t, nil, "/htmx/sse/lobby"),
+		srv.OpenSSE(t, nil, "/htmx/sse/lobby"),
+	}
+
+	// A game nobody has joined isn't open yet
+	g := srv.CreateGame(t, "")
+	srv.JoinAs(t, g.ID, models.PlayerX)
+	entry := `<li id="lobby-` + g.ID + `">`
+	for i, s := range streams {
+		ev := s.NextOf(t, broadcast.LobbyEvent)
+		if !strings.Contains(ev.Data, `hx-swap-oob="delete"`) {
+			t.Errorf("stream %d: the new game was shown before anyone joined:\n%s", i, ev.Data)
+		}
+		if ev = s.NextOf(t, broadcast.LobbyEvent); !strings.Contains(ev.Data, entry) || !strings.Contains(ev.Data, "?player=O") {
+			t.Errorf("stream %d: no entry offering O's side for the game X waits in:\n%s", i, ev.Data)
+		}
+	}
+
+	srv.JoinAs(t, g.ID, models.PlayerO)
+	for i, s := range streams {
+		ev := s.NextOf(t, broadcast.LobbyEvent)
+		if !strings.Contains(ev.Data, `<li id="lobby-`+g.ID+`" hx-swap-oob="delete">`) {
+			t.Errorf("stream %d: the full game wasn't taken off the list:\n%s", i, ev.Data)
+		}
+	}
+}
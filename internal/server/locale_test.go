@@ -0,0 +1,90 @@
+package server_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestGameLocale plays a game in English between an English X and a
+// Spanish O, and checks each player's updates come in their own
+// language while what is shared comes in the game's, whoever asks.
+func TestGameLocale(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser, oBrowser := browser(t), browser(t)
+	english, spanish := []string{"Accept-Language", "en"}, []string{"Accept-Language", "es"}
+	id := gameIDPattern.FindStringSubmatch(post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}, "locale": {"en"}}, english...))[1]
+	post(t, oBrowser, srv, "/htmx/join/"+id, url.Values{"player": {"O"}}, spanish...)
+
+	x := srv.OpenSSE(t, xBrowser, "/htmx/sse/"+id+"?player=X", english...)
+	o := srv.OpenSSE(t, oBrowser, "/htmx/sse/"+id+"?player=O", spanish...)
+	embedded := srv.OpenSSE(t, nil, "/htmx/sse/"+id+"?embed=1", spanish...)
+	for _, s := range []*testutil.SSE{x, o, embedded} {
+		s.NextOf(t, broadcast.GameUpdateEvent)
+	}
+	post(t, xBrowser, srv, "/htmx/move/"+id+"/4?player=X", nil, english...)
+	for name, tt := range map[string]struct {
+		stream    *testutil.SSE
+		want, not string
+	}{
+		"X":        {x, "waiting: O", "esperando"},
+		"O":        {o, "tu_turno", "your_turn"},
+		"embedded": {embedded, "waiting: O", "esperando"},
+	} {
+		if update := tt.stream.NextOf(t, broadcast.GameUpdateEvent).Data; !strings.Contains(update, tt.want) || strings.Contains(update, tt.not) {
+			t.Errorf("%s's update after X's move, want %q:\n%s", name, tt.want, update)
+		}
+	}
+
+	get := func(path string) string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Language", "es")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %d %v", path, res.StatusCode, err)
+		}
+		return string(body)
+	}
+	if page := get("/embed/" + id); !strings.Contains(page, `<html lang="en"`) || !strings.Contains(page, "<title>Tic Tac Toe: ") {
+		t.Errorf("the embed page asked for in Spanish isn't in the game's English:\n%s", page)
+	}
+	if oembed := get("/api/oembed?url=" + url.QueryEscape("/?game="+id)); !strings.Contains(oembed, `"title":"Tic Tac Toe: `) {
+		t.Errorf("oEmbed asked for in Spanish: %s", oembed)
+	}
+}
+
+// TestGameLocaleSetting checks a game's locale is kept, and one with no
+// translations is turned away.
+func TestGameLocaleSetting(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	if g := srv.CreateGame(t, `{"locale":"es"}`); g.Locale != "es" {
+		t.Errorf("created with locale %q, want es", g.Locale)
+	}
+	var apiErr *testutil.APIError
+	if _, err := srv.Do(t, "POST", "/api/game", `{"locale":"tlh"}`, nil); !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || apiErr.Code != "invalid_locale" {
+		t.Errorf("creating with locale tlh: %v", err)
+	}
+	id := srv.CreateGame(t, "").ID
+	_, token := srv.JoinAs(t, id, models.PlayerX)
+	if _, err := srv.Do(t, "PATCH", "/api/game/"+id+"/settings", `{"player":"X","locale":"tlh"}`, nil, seat.TokenHeader, token); !errors.As(err, &apiErr) || apiErr.Code != "invalid_locale" {
+		t.Errorf("changing to locale tlh: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/api"
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/clientip"
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/htmx"
+	"tiktaktoes/internal/i18n"
+	"tiktaktoes/internal/metrics"
+	"tiktaktoes/internal/puzzle"
+	"tiktaktoes/internal/replica"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/security"
+	"tiktaktoes/internal/static"
+	"tiktaktoes/internal/status"
+	"tiktaktoes/internal/tournament"
+	"tiktaktoes/internal/urls"
+	"tiktaktoes/internal/ws"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Deps holds the shared services the HTTP handlers are built on.
+type Deps struct {
+	Games *game.Service
+	Hub   *broadcast.Hub
+	// Puzzles serves the daily puzzle. It is optional.
+	Puzzles *puzzle.Service
+	// Tournaments runs tournament brackets. It is optional.
+	Tournaments *tournament.Service
+	// Featured is the feed of featured games. It is optional.
+	Featured *featured.Feed
+	// Commentary keeps games' commentators and commentary. It is
+	// optional.
+	Commentary *commentary.Service
+	// Activity is where the handlers record the actions they receive
+	// for each game, which players and admins can read back. It is
+	// optional.
+	Activity *activity.Log
+	// AdminKey enables the admin endpoints under /api/admin, which
+	// require it as a bearer token. Empty leaves them unregistered.
+	AdminKey string
+	// SeatKey signs the seat tokens players are handed when they join,
+	// see package seat. Empty makes up a random one.
+	SeatKey []byte
+	// Audit is where admin actions are recorded and read back from. It
+	// is optional.
+	Audit *audit.Log
+	// Replication is the feed of changes to the games standbys follow,
+	// served under /api/admin with AdminKey. Follower is set on a
+	// standby, which turns changes away until it is promoted, see
+	// package replica. Both are optional.
+	Replication *replica.Feed
+	Follower    *replica.Follower
+	// APIKeys, when set, requires an API key on the REST API and serves
+	// their management under /api/admin/keys, see api.KeyHandler. It
+	// needs AdminKey, to create the first ones with.
+	APIKeys *apikey.Keyring
+	// Metrics serves Prometheus metrics at /metrics.
+	Metrics bool
+	// Compress enables gzip/zstd compression of responses, see
+	// api.CompressMiddleware.
+	Compress bool
+	// PathPrefix mounts every route under a path such as "/ttt", see
+	// package urls. Requests are expected to arrive with it intact.
+	PathPrefix string
+	// TrustedProxies are the peers whose forwarding headers are believed
+	// when resolving client addresses, see package clientip.
+	TrustedProxies []netip.Prefix
+	// TrustedHeader is the forwarding header those proxies set, the
+	// only one believed.
+	TrustedHeader clientip.Header
+	StaticDir     string
+	// Tracing wraps the handler with OpenTelemetry HTTP instrumentation.
+	Tracing bool
+	// WSIdleTimeout disconnects WebSocket clients that neither send
+	// anything nor answer pings for this long. Zero means
+	// ws.DefaultIdleTimeout.
+	WSIdleTimeout time.Duration
+	// WSUpgrader configures WebSocket handshakes. Its zero value only
+	// accepts pages served by this server.
+	WSUpgrader security.UpgraderConfig
+	// EmbedAncestors are the sites allowed to frame game embed pages, as
+	// CSP sources, see htmx.NewEmbedHandler. None allows this server
+	// alone.
+	EmbedAncestors []string
+}
+
+// NewMux wires every handler family onto a single mux and wraps it in
+// the middleware chain, exactly as the server binary serves it.
+func NewMux(deps Deps) http.Handler {
+	// A nil *activity.Log would make a non-nil Recorder
+	var recorder activity.Recorder
+	if deps.Activity != nil {
+		recorder = deps.Activity
+	}
+	seats := seat.NewSigner(deps.SeatKey)
+	apiHandler := api.NewHandler(deps.Games, deps.Hub, recorder, seats)
+	wsHandler := ws.NewHandler(deps.Games, deps.Hub, security.NewUpgrader(deps.WSUpgrader), deps.WSIdleTimeout, recorder, seats)
+	htmxHandler := htmx.NewHandler(deps.Games, deps.Hub, recorder, seats)
+
+	var keys *api.KeyHandler
+	mux := http.NewServeMux()
+	apiHandler.RegisterRoutes(mux)
+	wsHandler.RegisterRoutes(mux)
+	htmxHandler.RegisterRoutes(mux)
+	htmx.NewEmbedHandler(deps.Games, deps.EmbedAncestors).RegisterRoutes(mux)
+	api.NewVersionHandler(features(deps)).RegisterRoutes(mux)
+	api.NewTimeHandler().RegisterRoutes(mux)
+	if deps.Puzzles != nil {
+		api.NewPuzzleHandler(deps.Puzzles).RegisterRoutes(mux)
+		htmx.NewPuzzleHandler(deps.Puzzles).RegisterRoutes(mux)
+	}
+	if deps.Tournaments != nil {
+		api.NewTournamentHandler(deps.Tournaments).RegisterRoutes(mux)
+		htmx.NewTournamentHandler(deps.Tournaments, deps.Hub).RegisterRoutes(mux)
+	}
+	if deps.Commentary != nil {
+		api.NewCommentaryHandler(deps.Commentary).RegisterRoutes(mux)
+		htmx.NewCommentaryHandler(deps.Commentary).RegisterRoutes(mux)
+	}
+	var activityAPI *api.ActivityHandler
+	if deps.Activity != nil {
+		activityAPI = api.NewActivityHandler(deps.Activity, seats)
+		activityAPI.RegisterRoutes(mux)
+	}
+	var featuredAPI *api.FeaturedHandler
+	if deps.Featured != nil {
+		featuredAPI = api.NewFeaturedHandler(deps.Featured)
+		featuredAPI.RegisterRoutes(mux)
+		htmx.NewFeaturedHandler(deps.Featured).RegisterRoutes(mux)
+	}
+	if deps.AdminKey != "" {
+		admin := api.NewAdminHandler(deps.Games, deps.Hub, deps.AdminKey, deps.Audit)
+		admin.RegisterRoutes(mux)
+		if featuredAPI != nil {
+			featuredAPI.RegisterAdminRoutes(mux, admin)
+		}
+		if activityAPI != nil {
+			activityAPI.RegisterAdminRoutes(mux, admin)
+		}
+		if deps.Replication != nil {
+			api.NewReplicationHandler(deps.Replication, deps.Follower, deps.Hub).RegisterRoutes(mux, admin)
+		}
+		if deps.APIKeys != nil {
+			keys = api.NewKeyHandler(deps.APIKeys, admin)
+			keys.RegisterRoutes(mux)
+		}
+		statusPage := htmx.NewStatusHandler(status.NewCollector(deps.Games, deps.Hub))
+		mux.Handle("GET /htmx/statusz", admin.RequireKey(statusPage.ServeHTTP))
+	}
+	if deps.Metrics {
+		mux.Handle("GET /metrics", metrics.Handler(deps.Hub))
+	}
+
+	var handler http.Handler = mux
+	// Serve static files
+	if deps.StaticDir != "" {
+		handler = withStatic(mux, static.New(deps.StaticDir))
+	}
+	if keys != nil {
+		handler = keys.Middleware(handler)
+	}
+	handler = api.ReadOnlyMiddleware(deps.Games, handler)
+	handler = urls.Mount(urls.CleanPrefix(deps.PathPrefix), i18n.Middleware(api.VersionMiddleware(api.MethodNotAllowedMiddleware(handler))))
+	if deps.Compress {
+		handler = api.CompressMiddleware(handler)
+	}
+	handler = api.RequestIDMiddleware(api.CORSMiddleware(handler))
+	handler = clientip.NewResolver(deps.TrustedProxies, deps.TrustedHeader).Middleware(handler)
+	if deps.Tracing {
+		handler = otelhttp.NewHandler(handler, "http")
+	}
+	return handler
+}
+
+// withStatic serves files for the requests no route takes outside
+// /api/. Only the routes answer under /api/, so a method they don't take
+// gets their Allow header and an unknown path a 404.
+func withStatic(routes *http.ServeMux, files http.Handler) http.Handler {
+	fallback := http.NewServeMux()
+	fallback.Handle("GET /", files)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := routes.Handler(r); pattern != "" || strings.HasPrefix(r.URL.Path, "/api/") {
+			routes.ServeHTTP(w, r)
+			return
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// features lists what the server built from deps offers beyond the core
+// API, sorted, as advertised by GET /api/version.
+func features(deps Deps) []string {
+	features := []string{
+		"ai", "hotseat",
+		"delta", "envelope", "subscribe", "takeover", "pull",
+		"events", "embed", "server-time",
+		"early-draw", "handicap", "ready-check", "analysis", "start-position", "exhibition",
+	}
+	if deps.Puzzles != nil {
+		features = append(features, "puzzles")
+	}
+	if deps.Tournaments != nil {
+		features = append(features, "tournaments")
+	}
+	if deps.Featured != nil {
+		features = append(features, "featured")
+	}
+	if deps.Commentary != nil {
+		features = append(features, "commentary")
+	}
+	if deps.Activity != nil {
+		features = append(features, "activity")
+	}
+	if deps.AdminKey != "" {
+		features = append(features, "admin")
+		if deps.APIKeys != nil {
+			features = append(features, "api-keys")
+		}
+		if deps.Replication != nil {
+			features = append(features, "replication")
+		}
+	}
+	if deps.Metrics {
+		features = append(features, "metrics")
+	}
+	if deps.Compress {
+		features = append(features, "compression")
+	}
+	slices.Sort(features)
+	return features
+}
@@ -0,0 +1,213 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// answer is what an htmx endpoint answered.
+type answer struct {
+	status int
+	res    *http.Response
+	body   []byte
+}
+
+// html reports whether the answer is an HTML fragment.
+func (a answer) html() bool {
+	return strings.HasPrefix(a.res.Header.Get("Content-Type"), "text/html")
+}
+
+// game decodes the answer as a game, failing the test if it isn't JSON.
+func (a answer) game(t *testing.T) *models.GameState {
+	t.Helper()
+	var g models.GameState
+	a.decode(t, &g)
+	return &g
+}
+
+// code decodes the answer as the API's error envelope and returns its
+// code.
+func (a answer) code(t *testing.T) string {
+	t.Helper()
+	var body respond.ErrorBody
+	a.decode(t, &body)
+	return body.Code
+}
+
+func (a answer) decode(t *testing.T, v any) {
+	t.Helper()
+	if ct := a.res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("%s %s: %d %s, want JSON:\n%s", a.res.Request.Method, a.res.Request.URL.Path, a.status, ct, a.body)
+	}
+	if err := json.Unmarshal(a.body, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ask sends form to path with client, asking for JSON as fetch() would
+// when asJSON is set and as htmx does otherwise.
+func ask(t *testing.T, client *http.Client, srv *testutil.Server, method, path string, form url.Values, asJSON bool) answer {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !asJSON {
+		req.Header.Set("HX-Request", "true")
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return answer{status: res.StatusCode, res: res, body: body}
+}
+
+// TestNegotiation calls each htmx endpoint asking for JSON, and as htmx
+// does with the same Accept header, and checks only the first gets the
+// game or error envelope the REST API answers with.
+func TestNegotiation(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser, oBrowser, stranger := browser(t), browser(t), browser(t)
+
+	// A new game, to which X is sat
+	newGame := func(t *testing.T) string {
+		t.Helper()
+		return ask(t, xBrowser, srv, "POST", "/htmx/game/new?player=X", nil, true).game(t).ID
+	}
+	// A game both sides have joined
+	startedGame := func(t *testing.T) string {
+		t.Helper()
+		id := newGame(t)
+		if a := ask(t, oBrowser, srv, "POST", "/htmx/join/"+id+"?player=O", nil, true); a.status != http.StatusOK {
+			t.Fatalf("joining as O: %d %s", a.status, a.body)
+		}
+		return id
+	}
+
+	t.Run("new game", func(t *testing.T) {
+		if g := ask(t, xBrowser, srv, "POST", "/htmx/game/new?player=X", nil, true).game(t); !g.PlayerXJoined || g.PlayerOJoined {
+			t.Errorf("new game %+v, want X sat", g)
+		}
+		if a := ask(t, xBrowser, srv, "POST", "/htmx/game/new?player=X", nil, false); a.status != http.StatusOK || !a.html() {
+			t.Errorf("as htmx: %d, HTML %v", a.status, a.html())
+		}
+	})
+
+	t.Run("view", func(t *testing.T) {
+		id := newGame(t)
+		a := ask(t, stranger, srv, "GET", "/htmx/game/"+id, nil, true)
+		if g := a.game(t); g.ID != id || !strings.Contains(a.res.Header.Get("Vary"), "Accept") {
+			t.Errorf("game %s, Vary %q", g.ID, a.res.Header.Get("Vary"))
+		}
+		if a := ask(t, stranger, srv, "GET", "/htmx/game/"+id, nil, false); !a.html() {
+			t.Errorf("as htmx: %d, not HTML", a.status)
+		}
+		if a := ask(t, stranger, srv, "GET", "/htmx/game/nosuchgame", nil, true); a.status != http.StatusNotFound || a.code(t) != "game_not_found" {
+			t.Errorf("a game that doesn't exist: %d", a.status)
+		}
+	})
+
+	t.Run("join", func(t *testing.T) {
+		id := newGame(t)
+		if g := ask(t, oBrowser, srv, "POST", "/htmx/join/"+id+"?player=O", nil, true).game(t); g.ID != id || !g.PlayerOJoined {
+			t.Errorf("joined %+v", g)
+		}
+		taken := ask(t, stranger, srv, "POST", "/htmx/join/"+id+"?player=O", nil, true)
+		var body respond.ErrorBody
+		taken.decode(t, &body)
+		if taken.status != http.StatusConflict || body.RequestID == "" {
+			t.Errorf("joining a taken side: %d %+v", taken.status, body)
+		}
+		if a := ask(t, stranger, srv, "POST", "/htmx/join/"+id+"?player=O", nil, false); a.status != http.StatusConflict || !a.html() {
+			t.Errorf("as htmx: %d, HTML %v", a.status, a.html())
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		id := startedGame(t)
+		if g := ask(t, xBrowser, srv, "POST", "/htmx/move/"+id+"/4?player=X", nil, true).game(t); g.Board[4] != models.PlayerX {
+			t.Errorf("board after X's move %v", g.Board)
+		}
+		for _, tt := range []struct {
+			path   string
+			status int
+			code   string
+		}{
+			{"/htmx/move/" + id + "/0?player=X", http.StatusConflict, "not_your_turn"},
+			{"/htmx/move/" + id + "/x?player=O", http.StatusBadRequest, "invalid_move"},
+			{"/htmx/move/" + id + "/0", http.StatusBadRequest, "invalid_player"},
+		} {
+			if a := ask(t, oBrowser, srv, "POST", tt.path, nil, true); a.status != tt.status || a.code(t) != tt.code {
+				t.Errorf("%s: %d, want %d %s", tt.path, a.status, tt.status, tt.code)
+			}
+		}
+		if a := ask(t, oBrowser, srv, "POST", "/htmx/move/"+id+"/0", nil, false); a.status != http.StatusBadRequest || !a.html() {
+			t.Errorf("as htmx, without a side: %d, HTML %v", a.status, a.html())
+		}
+		if a := ask(t, oBrowser, srv, "POST", "/htmx/move/"+id+"/0?player=O", nil, false); a.status != http.StatusOK || !a.html() {
+			t.Errorf("as htmx: %d, HTML %v", a.status, a.html())
+		}
+	})
+
+	t.Run("draw, reset and undo", func(t *testing.T) {
+		id := startedGame(t)
+		ask(t, xBrowser, srv, "POST", "/htmx/move/"+id+"/4?player=X", nil, true)
+		if g := ask(t, oBrowser, srv, "POST", "/htmx/draw/"+id+"?player=O", nil, true).game(t); g.DrawOffer != models.PlayerO {
+			t.Errorf("draw offer %q, want O's", g.DrawOffer)
+		}
+		if a := ask(t, xBrowser, srv, "POST", "/htmx/draw/"+id+"/reply?player=X&accept=false", nil, true); a.status != http.StatusOK || a.game(t).DrawOffer != "" {
+			t.Errorf("declining the draw: %d %s", a.status, a.body)
+		}
+		if g := ask(t, xBrowser, srv, "POST", "/htmx/reset/"+id+"?player=X", nil, true).game(t); len(g.History) != 0 {
+			t.Errorf("history after a reset %+v", g.History)
+		}
+		if g := ask(t, xBrowser, srv, "POST", "/htmx/undo/"+id+"?player=X", nil, true).game(t); g.Board[4] != models.PlayerX {
+			t.Errorf("board after undoing the reset %v", g.Board)
+		}
+		if a := ask(t, xBrowser, srv, "POST", "/htmx/undo/"+id+"?player=X", nil, true); a.status != http.StatusConflict || a.code(t) != "nothing_to_undo" {
+			t.Errorf("undoing again: %d", a.status)
+		}
+		if a := ask(t, xBrowser, srv, "POST", "/htmx/reset/"+id+"?player=X", nil, false); a.status != http.StatusOK || !a.html() {
+			t.Errorf("resetting as htmx: %d, HTML %v", a.status, a.html())
+		}
+	})
+
+	t.Run("settings", func(t *testing.T) {
+		id := newGame(t)
+		path := "/htmx/settings/" + id + "?player=X"
+		if a := ask(t, xBrowser, srv, "PATCH", path, url.Values{"readyCheck": {"true"}}, true); !a.game(t).ReadyCheck {
+			t.Errorf("the creator's change wasn't made: %s", a.body)
+		}
+		if a := ask(t, stranger, srv, "PATCH", path, nil, true); a.status != http.StatusForbidden || a.code(t) != "not_creator" {
+			t.Errorf("a stranger: %d", a.status)
+		}
+		if a := ask(t, xBrowser, srv, "PATCH", path, url.Values{"readyCheck": {"false"}}, false); a.status != http.StatusOK || !a.html() {
+			t.Errorf("as htmx: %d, HTML %v", a.status, a.html())
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		if a := ask(t, xBrowser, srv, "POST", "/htmx/cancel/"+newGame(t)+"?player=X", nil, true); a.status != http.StatusNoContent {
+			t.Errorf("status = %d, want 204", a.status)
+		}
+		if a := ask(t, xBrowser, srv, "POST", "/htmx/cancel/"+newGame(t)+"?player=X", nil, false); a.status != http.StatusOK || !a.html() {
+			t.Errorf("as htmx: %d, HTML %v", a.status, a.html())
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/htmx"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// movePattern finds the sides a rendered board's cells post moves for.
+var movePattern = regexp.MustCompile(`hx-post="/htmx/move/[^/]+/\d+\?player=(\w*)"`)
+
+// send makes an htmx request with client and returns the status and
+// body of the answer.
+func send(t *testing.T, client *http.Client, srv *testutil.Server, method, path string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("HX-Request", "true")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(body)
+}
+
+// TestPlayerParam sends each handler taking ?player= each kind of value,
+// from the browser that joined X. Anything but a side is a 400 on the
+// paths that act, and a spectator's view on those that show the game.
+func TestPlayerParam(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser, oBrowser := browser(t), browser(t)
+	id := gameIDPattern.FindStringSubmatch(post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}}))[1]
+	post(t, oBrowser, srv, "/htmx/join/"+id, url.Values{"player": {"O"}})
+
+	values := []struct {
+		query string
+		side  bool
+	}{
+		{"", false},
+		{"?player=", false},
+		{"?player=banana", false},
+		{"?player=spectator", false},
+		{"?player=x", false},
+		{"?player=X", true},
+	}
+	for _, v := range values {
+		status, page := send(t, xBrowser, srv, http.MethodGet, "/htmx/game/"+id+v.query)
+		moves := movePattern.FindAllStringSubmatch(page, -1)
+		if status != http.StatusOK || (len(moves) > 0) != v.side {
+			t.Errorf("view%s: %d with %d cells to click", v.query, status, len(moves))
+		}
+		for _, m := range moves {
+			if m[1] != "X" {
+				t.Errorf("view%s: a cell posts player=%s", v.query, m[1])
+			}
+		}
+
+		stream := srv.OpenSSE(t, xBrowser, "/htmx/sse/"+id+v.query)
+		want := "spectator"
+		if v.side {
+			want = "X"
+		}
+		if got := stream.Response.Header.Get(htmx.PerspectiveHeader); got != want {
+			t.Errorf("stream%s: perspective %q, want %q", v.query, got, want)
+		}
+		update := stream.NextOf(t, broadcast.GameUpdateEvent).Data
+		if moves := movePattern.FindAllString(update, -1); (len(moves) > 0) != v.side {
+			t.Errorf("stream%s: %d cells to click", v.query, len(moves))
+		}
+
+		for _, path := range []string{"/htmx/reset/" + id, "/htmx/move/" + id + "/4"} {
+			if v.side {
+				continue
+			}
+			status, page := send(t, xBrowser, srv, http.MethodPost, path+v.query)
+			if status != http.StatusBadRequest || !strings.Contains(page, "invalid player") {
+				t.Errorf("POST %s%s: %d\n%s", path, v.query, status, page)
+			}
+		}
+	}
+
+	post(t, xBrowser, srv, "/htmx/move/"+id+"/4?player=X", nil)
+	post(t, xBrowser, srv, "/htmx/reset/"+id+"?player=X", nil)
+}
+
+// TestSSEPerspective joins a game as O and opens its event stream
+// claiming X, and checks none of the updates it is sent let it move for
+// X, nor for O, whose seat it didn't claim.
+func TestSSEPerspective(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	xBrowser, oBrowser := browser(t), browser(t)
+	id := gameIDPattern.FindStringSubmatch(post(t, xBrowser, srv, "/htmx/game/new", url.Values{"player": {"X"}}))[1]
+	post(t, oBrowser, srv, "/htmx/join/"+id, url.Values{"player": {"O"}})
+
+	stream := srv.OpenSSE(t, oBrowser, "/htmx/sse/"+id+"?player=X")
+	if got := stream.Response.Header.Get(htmx.PerspectiveHeader); got != "spectator" {
+		t.Errorf("perspective %q claiming the side O's browser didn't join, want spectator", got)
+	}
+	updates := []string{stream.NextOf(t, broadcast.GameUpdateEvent).Data}
+	for i, pos := range xWins {
+		client := xBrowser
+		if mover(i) == models.PlayerO {
+			client = oBrowser
+		}
+		post(t, client, srv, "/htmx/move/"+id+"/"+strconv.Itoa(pos)+"?player="+string(mover(i)), nil)
+		updates = append(updates, stream.NextOf(t, broadcast.GameUpdateEvent).Data)
+	}
+	for i, update := range updates {
+		if strings.Contains(update, "player=X") || movePattern.MatchString(update) {
+			t.Errorf("update %d acts for a side:\n%s", i, update)
+		}
+	}
+	if x, _ := marks(updates[len(updates)-1]); x != 3 {
+		t.Errorf("the last update has %d X marks, want 3", x)
+	}
+}
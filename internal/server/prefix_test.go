@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// linkPattern finds the paths a page links to, fetches or connects to.
+var linkPattern = regexp.MustCompile(`(?:href|src|action|hx-get|hx-post|hx-put|hx-patch|hx-delete|sse-connect|ws-connect)="(/[^"]*)"`)
+
+// TestPathPrefix plays an htmx game on a server mounted under /ttt, and
+// checks every path its pages use is under /ttt too.
+func TestPathPrefix(t *testing.T) {
+	srv := testutil.Start(t, server.Config{PathPrefix: "/ttt/"})
+	xBrowser, oBrowser := browser(t), browser(t)
+	links := 0
+	checkLinks := func(page string) {
+		t.Helper()
+		for _, m := range linkPattern.FindAllStringSubmatch(page, -1) {
+			links++
+			if !strings.HasPrefix(m[1], "/ttt/") {
+				t.Errorf("%s outside the prefix in:\n%s", m[0], page)
+			}
+		}
+	}
+
+	page := post(t, xBrowser, srv, "/ttt/htmx/game/new", url.Values{"player": {"X"}})
+	checkLinks(page)
+	m := gameIDPattern.FindStringSubmatch(page)
+	if m == nil {
+		t.Fatalf("no game ID in the new game's page:\n%s", page)
+	}
+	id := m[1]
+	// The way in takes the ID's first characters, as someone reads it out
+	checkLinks(post(t, oBrowser, srv, "/ttt/htmx/join/"+id[:game.MinPrefixLength], url.Values{"player": {"O"}}))
+	events := srv.OpenSSE(t, xBrowser, "/ttt/htmx/sse/"+id+"?player=X")
+	for i, pos := range xWins {
+		player, client := mover(i), xBrowser
+		if player == models.PlayerO {
+			client = oBrowser
+		}
+		checkLinks(post(t, client, srv, "/ttt/htmx/move/"+id+"/"+strconv.Itoa(pos)+"?player="+string(player), nil))
+		checkLinks(events.NextOf(t, broadcast.GameUpdateEvent).Data)
+	}
+	if links == 0 {
+		t.Fatal("the pages have no links to check")
+	}
+
+	var final models.GameState
+	if _, err := srv.Do(t, http.MethodGet, "/ttt/api/game/"+id, "", &final); err != nil {
+		t.Fatal(err)
+	}
+	if final.Winner != models.PlayerX {
+		t.Errorf("the game was won by %q, want X", final.Winner)
+	}
+	for _, path := range []string{"/api/game/" + id, "/tttx/api/game/" + id, "/ttt"} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if path == "/ttt" {
+			if res.Request.URL.Path != "/ttt/" {
+				t.Errorf("GET /ttt ended at %s, want a redirect to /ttt/", res.Request.URL.Path)
+			}
+		} else if res.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s outside the prefix: %s", path, res.Status)
+		}
+	}
+}
+
+// TestShortIDs looks games up over the API by the first characters of
+// their IDs.
+func TestShortIDs(t *testing.T) {
+	srv := testutil.Start(t, server.Config{
+		GameOptions: []game.Option{game.WithIDGenerator(gametest.NewFixedIDs("abcd1234", "abcd5678", "beef0000"))},
+	})
+	for range 3 {
+		srv.CreateGame(t, "")
+	}
+
+	tests := []struct {
+		id     string
+		status int
+		code   string
+		want   string
+	}{
+		{"beef0000", http.StatusOK, "", "beef0000"},
+		{"beef", http.StatusOK, "", "beef0000"},
+		{" BEEF0 ", http.StatusOK, "", "beef0000"},
+		{"abcd1", http.StatusOK, "", "abcd1234"},
+		{"abcd", http.StatusBadRequest, "ambiguous_id", ""},
+		{"bee", http.StatusNotFound, "game_not_found", ""},
+		{"beef1", http.StatusNotFound, "game_not_found", ""},
+	}
+	for _, tt := range tests {
+		var g models.GameState
+		res, err := srv.Do(t, http.MethodGet, "/api/game/"+url.PathEscape(tt.id), "", &g)
+		var apiErr *testutil.APIError
+		errors.As(err, &apiErr)
+		switch {
+		case res.StatusCode != tt.status:
+			t.Errorf("%q: status %d, want %d", tt.id, res.StatusCode, tt.status)
+		case tt.want != "" && g.ID != tt.want:
+			t.Errorf("%q found %s, want %s", tt.id, g.ID, tt.want)
+		case tt.code != "" && (apiErr == nil || apiErr.Code != tt.code):
+			t.Errorf("%q: %v, want %s", tt.id, err, tt.code)
+		}
+	}
+}
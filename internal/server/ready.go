@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+)
+
+// readyClock sends the countdown of games with a ready check and
+// vacates the sides that weren't ready in time.
+type readyClock struct {
+	games *game.Service
+	hub   *broadcast.Hub
+
+	mu     sync.Mutex
+	timers map[string]*readyTimer
+}
+
+// readyTimer is the pending timer of one game, run by one of its
+// workers, see game.Service.Go. Closing stop cancels it.
+type readyTimer struct {
+	stop chan struct{}
+}
+
+// watchReady starts a readyClock for the games of the service, including
+// those already waiting when it starts, such as recovered ones.
+func watchReady(games *game.Service, hub *broadcast.Hub) {
+	c := &readyClock{games: games, hub: hub, timers: make(map[string]*readyTimer)}
+	games.OnPlayerJoined(c.schedule)
+	games.OnReadyChanged(c.schedule)
+	pending, _, err := games.Snapshot(context.Background())
+	if err != nil {
+		slog.Error("listing games for ready checks failed", "error", err)
+		return
+	}
+	for _, gs := range pending {
+		c.schedule(*gs)
+	}
+}
+
+// schedule replaces the game's pending timer with one for what it waits
+// for now: the next second of its countdown, or its ready deadline.
+func (c *readyClock) schedule(gs models.GameState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t := c.timers[gs.ID]; t != nil {
+		close(t.stop)
+		delete(c.timers, gs.ID)
+	}
+	switch {
+	case !gs.StartsAt.IsZero():
+		c.tick(gs.ID, gs.StartsAt)
+	case !gs.ReadyBy.IsZero():
+		c.after(gs.ID, time.Until(gs.ReadyBy), c.advance)
+	}
+}
+
+// tick sends the countdown to startsAt in whole seconds, rounded up, and
+// schedules the next tick, or starts the game once it is over. Must be
+// called with the lock held.
+func (c *readyClock) tick(gameID string, startsAt time.Time) {
+	left := time.Until(startsAt)
+	seconds := int((left + time.Second - 1) / time.Second)
+	if left <= 0 {
+		seconds = 0
+	}
+	c.hub.SendTo(context.Background(), gameID, broadcast.ToAll(), broadcast.Event{
+		Name: broadcast.CountdownEvent,
+		Data: broadcast.Countdown{GameID: gameID, Seconds: seconds, ServerTime: time.Now().UTC()},
+	})
+	if seconds == 0 {
+		c.advance(gameID)
+		return
+	}
+	// Tick again as the count drops to the next whole second
+	c.after(gameID, left-time.Duration(seconds-1)*time.Second, func(gameID string) {
+		c.tick(gameID, startsAt)
+	})
+}
+
+// after runs fn for the game in d, with the lock held, unless the game
+// is scheduled anew or has moved on meanwhile, as when it is deleted.
+// Must be called with the lock held.
+func (c *readyClock) after(gameID string, d time.Duration, fn func(gameID string)) {
+	t := &readyTimer{stop: make(chan struct{})}
+	c.timers[gameID] = t
+	c.games.Go(gameID, func(ctx context.Context) {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-t.stop:
+			return
+		case <-ctx.Done():
+			c.mu.Lock()
+			if c.timers[gameID] == t {
+				delete(c.timers, gameID)
+			}
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.timers[gameID] != t {
+			return
+		}
+		delete(c.timers, gameID)
+		fn(gameID)
+	})
+}
+
+// advance has the service start the game or vacate the unready, and
+// broadcasts the game if that changed it. Must be called with the lock held.
+func (c *readyClock) advance(gameID string) {
+	ctx := context.Background()
+	gs, changed, err := c.games.AdvanceReady(ctx, gameID)
+	if err != nil {
+		slog.Warn("advancing ready check failed", "game_id", gameID, "error", err)
+		return
+	}
+	if changed {
+		c.hub.Broadcast(ctx, gs.ID, gs)
+	}
+}
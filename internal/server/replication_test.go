@@ -0,0 +1,97 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/replica"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// standby serves a server following primary with Serve, as cmd/server
+// does, since only it starts the follower, until the test ends.
+func standby(t *testing.T, primary *testutil.Server) string {
+	t.Helper()
+	srv, err := server.New(server.Config{AdminKey: "admin", Follow: primary.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return "http://" + ln.Addr().String()
+}
+
+// TestFollowAndPromote plays on a primary with a standby following it,
+// checks the standby mirrors the game but turns changes away, then
+// promotes it and plays on there.
+func TestFollowAndPromote(t *testing.T) {
+	primary := testutil.Start(t, server.Config{AdminKey: "admin"})
+	id := primary.CreateGame(t, "").ID
+	primary.JoinAs(t, id, models.PlayerX)
+	primary.JoinAs(t, id, models.PlayerO)
+	backup := &testutil.Server{URL: standby(t, primary)}
+	primary.MustMove(t, id, models.PlayerX, 4)
+
+	deadline := time.Now().Add(testutil.Timeout)
+	for {
+		var g models.GameState
+		_, err := backup.Do(t, "GET", "/api/game/"+id, "", &g)
+		if err == nil && g.Board[4] == models.PlayerX {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the standby didn't get X's move: %+v, %v", g.Board, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var apiErr *testutil.APIError
+	if _, err := backup.MoveJSON(t, id, models.Move{Player: models.PlayerO, Position: 0}); !errors.As(err, &apiErr) ||
+		apiErr.Status != http.StatusServiceUnavailable || apiErr.Code != "read_only" || !apiErr.Temporary {
+		t.Errorf("moving on the standby: %v", err)
+	}
+	if _, err := backup.Do(t, "POST", "/api/admin/promote", "", nil); !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("promoting without the admin key: %v", err)
+	}
+	if _, err := primary.Do(t, "POST", "/api/admin/promote", "", nil, "Authorization", "Bearer admin"); !errors.As(err, &apiErr) || apiErr.Code != "not_following" {
+		t.Errorf("promoting the primary: %v", err)
+	}
+
+	var status replica.Status
+	if _, err := backup.Do(t, "POST", "/api/admin/promote", "", &status, "Authorization", "Bearer admin"); err != nil {
+		t.Fatal(err)
+	}
+	if status.Primary != primary.URL || status.Applied == 0 {
+		t.Errorf("promoted with %+v, want the game applied", status)
+	}
+	g := backup.MustMove(t, id, models.PlayerO, 0)
+	if g.Board[0] != models.PlayerO || g.Board[4] != models.PlayerX {
+		t.Errorf("board on the promoted standby %v", g.Board)
+	}
+	// The primary's moves no longer reach it
+	primary.MustMove(t, id, models.PlayerO, 8)
+	if _, err := backup.Do(t, "POST", "/api/admin/promote", "", nil, "Authorization", "Bearer admin"); !errors.As(err, &apiErr) || apiErr.Code != "not_following" {
+		t.Errorf("promoting twice: %v", err)
+	}
+	backup.Do(t, "GET", "/api/game/"+id, "", g)
+	if g.Board[8] != "" {
+		t.Errorf("the promoted standby took a move from the primary: %v", g.Board)
+	}
+}
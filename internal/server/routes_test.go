@@ -0,0 +1,59 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestRoutes checks the status, and Allow header for a 405, each method
+// and path is answered with while static files are served at "/". The
+// API's answers come from its own routes, never the static files'.
+func TestRoutes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("// app"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := testutil.Start(t, server.Config{StaticDir: dir})
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodDelete, "/api/game/missing", http.StatusMethodNotAllowed, "GET, HEAD, POST, PUT"},
+		{http.MethodGet, "/api/game/missing/draw", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodGet, "/api/game/missing/ready", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodDelete, "/api/game/missing/claim", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodGet, "/api/nothing", http.StatusNotFound, ""},
+		{http.MethodPut, "/api/nothing", http.StatusNotFound, ""},
+		{http.MethodGet, "/app.js", http.StatusOK, ""},
+		{http.MethodDelete, "/app.js", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodGet, "/nothing.js", http.StatusNotFound, ""},
+		{http.MethodPost, "/ws/missing", http.StatusMethodNotAllowed, "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if res.StatusCode != tt.status || res.Header.Get("Allow") != tt.allow {
+			t.Errorf("%s %s: %s, Allow %q, want %d, Allow %q", tt.method, tt.path, res.Status, res.Header.Get("Allow"), tt.status, tt.allow)
+		}
+		if tt.status == http.StatusMethodNotAllowed && strings.HasPrefix(tt.path, "/api/") && body.Error != "Method not allowed" {
+			t.Errorf("%s %s: error %q, want the API's JSON error", tt.method, tt.path, body.Error)
+		}
+	}
+}
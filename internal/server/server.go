@@ -0,0 +1,474 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/activity"
+	"tiktaktoes/internal/analysis"
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/audit"
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/clientip"
+	"tiktaktoes/internal/commentary"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/journal"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/puzzle"
+	"tiktaktoes/internal/replica"
+	"tiktaktoes/internal/security"
+	"tiktaktoes/internal/snapshot"
+	"tiktaktoes/internal/tournament"
+)
+
+// Config holds the settings for a Server.
+type Config struct {
+	// Addr is the TCP address to listen on, e.g. ":8080".
+	Addr string
+	// Listen overrides Addr with "unix:/path/to.sock" or another TCP
+	// address. Either is ignored when systemd passes a socket, see listen.
+	Listen string
+	// SocketMode sets the permissions of a unix socket. Zero leaves
+	// them to the umask.
+	SocketMode fs.FileMode
+	// StaticDir is the directory served at "/". Empty disables static files.
+	StaticDir string
+	// ShutdownTimeout bounds how long Start waits for in-flight requests
+	// once its context is cancelled.
+	ShutdownTimeout time.Duration
+	// GameOptions are passed to game.NewService.
+	GameOptions []game.Option
+	// Clock times the games and players' presence in them, see
+	// game.WithClock. The wall clock if nil.
+	Clock game.Clock
+	// Tracing enables OpenTelemetry HTTP instrumentation. The tracer
+	// provider itself is configured globally, see package telemetry.
+	Tracing bool
+	// SnapshotPath is where in-progress games are saved on shutdown and
+	// restored from on startup. Empty disables snapshots.
+	SnapshotPath string
+	// SnapshotInterval is how often snapshots are written while running.
+	// Zero only writes on shutdown.
+	SnapshotInterval time.Duration
+	// Journal configures the write-ahead move journal. An empty Dir
+	// disables it. Recovery loads the snapshot, then replays newer entries.
+	Journal journal.Options
+	// Audit configures the audit log of destructive and admin actions.
+	// An empty Path disables it.
+	Audit audit.Options
+	// AdminKey enables the admin API, see Deps.AdminKey.
+	AdminKey string
+	// SeatKey signs players' seat tokens, see Deps.SeatKey.
+	SeatKey string
+	// RequireAPIKey requires an API key on the REST API, see
+	// Deps.APIKeys. It needs AdminKey.
+	RequireAPIKey bool
+	// APIKeys is where API keys are kept with RequireAPIKey, such as
+	// storage.Keys. Nil keeps them in memory.
+	APIKeys apikey.Store
+	// Featured is where the featured games feed is kept, such as
+	// storage.Featured. Nil keeps it in memory.
+	Featured featured.Store
+	// Metrics serves Prometheus metrics at /metrics.
+	Metrics bool
+	// Compress enables response compression, see Deps.Compress.
+	Compress bool
+	// TLS enables HTTPS on Addr. The zero value serves plain HTTP.
+	TLS TLSConfig
+	// PathPrefix mounts the server under a path, see Deps.PathPrefix.
+	PathPrefix string
+	// TrustedProxies lists reverse proxies whose forwarding headers are
+	// believed, see Deps.TrustedProxies.
+	TrustedProxies []netip.Prefix
+	// TrustedHeader is the forwarding header they set, see
+	// Deps.TrustedHeader.
+	TrustedHeader clientip.Header
+	// WSIdleTimeout disconnects silent WebSocket clients, see
+	// Deps.WSIdleTimeout.
+	WSIdleTimeout time.Duration
+	// WSUpgrader configures WebSocket handshakes, see Deps.WSUpgrader.
+	WSUpgrader security.UpgraderConfig
+	// DuplicateConnections is what happens when a player's side gets a
+	// second connection, as from another tab, see
+	// broadcast.DuplicatePolicy.
+	DuplicateConnections broadcast.DuplicatePolicy
+	// EmbedAncestors are the sites allowed to frame game embed pages,
+	// see Deps.EmbedAncestors.
+	EmbedAncestors []string
+	// Follow is the URL of a primary server to follow as its warm
+	// standby, such as "http://primary:8080", see package replica. The
+	// standby turns changes away until promoted. It needs AdminKey,
+	// which must be the primary's too.
+	Follow string
+}
+
+// Server is a self-contained game server. Each Server owns its own game
+// service and hub, so several can run in one process.
+type Server struct {
+	cfg     Config
+	games   *game.Service
+	hub     *broadcast.Hub
+	journal *journal.Journal
+	audit   *audit.Log
+	// feed streams the changes to the games to standbys, and follower
+	// keeps them in step with the primary on a standby, nil otherwise
+	feed     *replica.Feed
+	follower *replica.Follower
+	// analysis evaluates games with live analysis after each move
+	analysis *analysis.Live
+	handler  http.Handler
+	http     *http.Server
+	// redirect answers plain HTTP when TLS is enabled, nil otherwise
+	redirect *http.Server
+
+	// snapshotMu serializes snapshot writes from the loop and Shutdown
+	snapshotMu sync.Mutex
+}
+
+// New creates a server from cfg.
+func New(cfg Config) (*Server, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+	if err := cfg.TLS.validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.RequireAPIKey && cfg.AdminKey == "" {
+		return nil, errors.New("requiring API keys needs an admin key to create them with")
+	}
+	if cfg.Follow != "" && cfg.AdminKey == "" {
+		return nil, errors.New("following a primary needs its admin key")
+	}
+
+	s := &Server{
+		cfg: cfg,
+		hub: broadcast.NewHub(),
+	}
+	s.hub.SetDuplicatePolicy(cfg.DuplicateConnections)
+	opts := cfg.GameOptions
+	if cfg.Clock != nil {
+		s.hub.SetClock(cfg.Clock)
+		opts = append([]game.Option{game.WithClock(cfg.Clock)}, opts...)
+	}
+	// Changes go to the journal, if there is one, through the feed
+	var j game.Journal
+	if cfg.Journal.Dir != "" {
+		var err error
+		if s.journal, err = journal.Open(cfg.Journal); err != nil {
+			return nil, err
+		}
+		j = s.journal
+	}
+	s.feed = replica.NewFeed(j)
+	opts = append(opts[:len(opts):len(opts)], game.WithJournal(s.feed))
+	if cfg.Audit.Path != "" {
+		a, err := audit.Open(cfg.Audit)
+		if err != nil {
+			s.closeLogs()
+			return nil, err
+		}
+		s.audit = a
+		opts = append(opts[:len(opts):len(opts)], game.WithAuditor(a))
+	}
+	opts = append(opts[:len(opts):len(opts)], game.WithPresence(s.hub))
+	s.games = game.NewService(opts...)
+	s.games.OnGameExpired(func(gs models.GameState) {
+		s.hub.CloseGame(gs.ID, broadcast.ReasonGameExpired)
+	})
+	if err := s.recover(context.Background()); err != nil {
+		s.closeLogs()
+		return nil, err
+	}
+	if cfg.Follow != "" {
+		f, err := replica.NewFollower(cfg.Follow, cfg.AdminKey, s.games, s.hub, s.feed)
+		if err != nil {
+			s.closeLogs()
+			return nil, errors.Join(err, s.games.Close())
+		}
+		s.follower = f
+	} else {
+		// Standbys get the games restored along with those changed since
+		games, _, err := s.games.Snapshot(context.Background())
+		if err != nil {
+			s.closeLogs()
+			return nil, errors.Join(err, s.games.Close())
+		}
+		s.feed.Seed(games)
+	}
+	var keys *apikey.Keyring
+	if cfg.RequireAPIKey {
+		if cfg.APIKeys == nil {
+			cfg.APIKeys = apikey.NewMemoryStore()
+		}
+		k, err := apikey.NewKeyring(context.Background(), cfg.APIKeys)
+		if err != nil {
+			s.closeLogs()
+			return nil, errors.Join(err, s.games.Close())
+		}
+		keys = k
+	}
+	if cfg.Featured == nil {
+		cfg.Featured = featured.NewMemoryStore()
+	}
+	feed, err := featured.NewFeed(context.Background(), cfg.Featured)
+	if err != nil {
+		s.closeLogs()
+		return nil, errors.Join(err, s.games.Close())
+	}
+	s.analysis = analysis.NewLive(s.games, s.hub)
+	watchClaims(s.games, s.hub)
+	watchReady(s.games, s.hub)
+	watchLobby(s.games, s.hub)
+	watchComputer(s.games, s.hub)
+	watchDeadPositions(s.games, s.hub)
+	watchFeatured(s.games, s.hub, feed)
+	s.handler = NewMux(Deps{
+		Games:          s.games,
+		Hub:            s.hub,
+		Puzzles:        puzzle.NewService(),
+		Tournaments:    tournament.NewService(s.games, s.hub),
+		Featured:       feed,
+		Commentary:     commentary.NewService(s.games, s.hub),
+		Activity:       activity.NewLog(s.games),
+		AdminKey:       cfg.AdminKey,
+		SeatKey:        []byte(cfg.SeatKey),
+		Replication:    s.feed,
+		Follower:       s.follower,
+		Audit:          s.audit,
+		APIKeys:        keys,
+		Metrics:        cfg.Metrics,
+		Compress:       cfg.Compress,
+		PathPrefix:     cfg.PathPrefix,
+		TrustedProxies: cfg.TrustedProxies,
+		TrustedHeader:  cfg.TrustedHeader,
+		StaticDir:      cfg.StaticDir,
+		Tracing:        cfg.Tracing,
+		WSIdleTimeout:  cfg.WSIdleTimeout,
+		WSUpgrader:     cfg.WSUpgrader,
+		EmbedAncestors: cfg.EmbedAncestors,
+	})
+	// Long-lived streams (SSE, WebSocket) watch the request context, so
+	// cancel the base context when shutdown begins to let them finish.
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s.http = &http.Server{
+		Addr:        cfg.Addr,
+		Handler:     s.handler,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	s.http.RegisterOnShutdown(cancel)
+	if err := s.setupTLS(cfg.TLS); err != nil {
+		s.closeLogs()
+		return nil, errors.Join(err, s.games.Close())
+	}
+	return s, nil
+}
+
+// closeLogs closes the journal and audit log, whichever are open.
+func (s *Server) closeLogs() error {
+	var err error
+	if s.journal != nil {
+		err = s.journal.Close()
+	}
+	if s.audit != nil {
+		err = errors.Join(err, s.audit.Close())
+	}
+	return err
+}
+
+// recover restores games from the snapshot and then replays any journal
+// entries written after it.
+func (s *Server) recover(ctx context.Context) error {
+	var journalSeq uint64
+	if s.cfg.SnapshotPath != "" {
+		file, err := snapshot.Read(s.cfg.SnapshotPath)
+		if err != nil {
+			return err
+		}
+		restored := s.games.Restore(ctx, file.Games)
+		journalSeq = file.JournalSeq
+		slog.Info("restored games from snapshot", "path", s.cfg.SnapshotPath, "games", restored, "skipped", len(file.Games)-restored)
+	}
+
+	if s.journal != nil {
+		replayed := 0
+		err := journal.Replay(s.cfg.Journal.Dir, journalSeq, func(e journal.Entry) error {
+			if err := s.games.Replay(ctx, e.Event, e.Game); err != nil {
+				slog.Warn("skipping invalid journal entry", "seq", e.Seq, "error", err)
+				return nil
+			}
+			replayed++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("replayed journal", "dir", s.cfg.Journal.Dir, "after_seq", journalSeq, "entries", replayed)
+	}
+	return nil
+}
+
+// Handler returns the server's root HTTP handler.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Games returns the server's game service.
+func (s *Server) Games() *game.Service {
+	return s.games
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled or the server fails. On cancellation it shuts down gracefully.
+// A unix socket file is removed once the listener closes.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := listen(s.cfg)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like Start but uses an existing listener.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	errCh := make(chan error, 2)
+	go func() {
+		slog.Info("server starting", "addr", ln.Addr().String(), "tls", s.cfg.TLS.Enabled())
+		if s.cfg.TLS.Enabled() {
+			// Certificates come from TLSConfig, see setupTLS
+			errCh <- s.http.ServeTLS(ln, "", "")
+			return
+		}
+		errCh <- s.http.Serve(ln)
+	}()
+	if s.redirect != nil {
+		go func() {
+			slog.Info("https redirect starting", "addr", s.redirect.Addr)
+			if err := s.redirect.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+
+	if s.cfg.SnapshotPath != "" && s.cfg.SnapshotInterval > 0 {
+		snapshotCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go s.snapshotLoop(snapshotCtx)
+	}
+	undoCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.undoLoop(undoCtx)
+	if s.follower != nil {
+		followCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go s.follower.Run(followCtx)
+	}
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		// Don't leave the other listener running
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+		defer cancel()
+		return errors.Join(err, s.Shutdown(shutdownCtx))
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(shutdownCtx)
+}
+
+// Shutdown gracefully stops the server, waiting for in-flight requests
+// until ctx expires, then writes a final snapshot.
+func (s *Server) Shutdown(ctx context.Context) error {
+	// Event streams only end when the client goes away, so Shutdown
+	// would wait them out; ask their clients to come back instead
+	s.hub.Drain()
+	err := s.http.Shutdown(ctx)
+	// WebSockets are hijacked, so Shutdown leaves them be; tell their
+	// clients the server is going away
+	s.hub.CloseAll(broadcast.ReasonShutdown)
+	s.hub.Close()
+	if s.redirect != nil {
+		err = errors.Join(err, s.redirect.Shutdown(ctx))
+	}
+	if s.cfg.SnapshotPath != "" {
+		// The final snapshot is written even if ctx has run out
+		if snapErr := s.writeSnapshot(context.WithoutCancel(ctx)); snapErr != nil {
+			err = errors.Join(err, snapErr)
+		}
+	}
+	err = errors.Join(err, s.closeLogs())
+	err = errors.Join(err, s.games.Close())
+	s.analysis.Wait()
+	return err
+}
+
+// snapshotLoop writes a snapshot every SnapshotInterval until ctx is done.
+func (s *Server) snapshotLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.writeSnapshot(ctx); err != nil {
+				slog.Error("writing snapshot failed", "path", s.cfg.SnapshotPath, "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// undoLoop drops the resets and cancellations that can no longer be
+// undone every undo window until ctx is done, so games nobody touches
+// again aren't held in memory.
+func (s *Server) undoLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.games.UndoWindow())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.games.PruneUndo()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) writeSnapshot(ctx context.Context) error {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	games, journalSeq, err := s.games.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Write(s.cfg.SnapshotPath, games, journalSeq, time.Now()); err != nil {
+		return err
+	}
+	slog.Debug("wrote snapshot", "path", s.cfg.SnapshotPath, "games", len(games))
+
+	// Entries covered by the snapshot are no longer needed, including
+	// everything about finished games, which snapshots leave out.
+	if s.journal != nil {
+		if err := s.journal.Compact(journalSeq); err != nil {
+			slog.Error("compacting journal failed", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/security"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestTwoServers runs two servers side by side in one process, configured
+// apart, and checks neither sees the other's games, clients, keys or
+// origins. Their games get the same IDs, so anything kept per game ID
+// across servers would show.
+func TestTwoServers(t *testing.T) {
+	start := func(name string, origins ...string) *testutil.Server {
+		return testutil.Start(t, server.Config{
+			AdminKey:    name + "-admin",
+			SeatKey:     name + "-seats",
+			Metrics:     true,
+			WSUpgrader:  security.UpgraderConfig{AllowedOrigins: origins},
+			GameOptions: []game.Option{game.WithIDGenerator(&gametest.SequentialIDs{})},
+		})
+	}
+	a, b := start("a", "https://a.example"), start("b")
+
+	ga, gb := a.CreateGame(t, ""), b.CreateGame(t, "")
+	if ga.ID != gb.ID {
+		t.Fatalf("the games have IDs %s and %s, want the same", ga.ID, gb.ID)
+	}
+	id := ga.ID
+	_, aToken := a.JoinAs(t, id, models.PlayerX)
+	_, bToken := b.JoinAs(t, id, models.PlayerX)
+	a.JoinAs(t, id, models.PlayerO)
+	b.JoinAs(t, id, models.PlayerO)
+	if aToken == bToken {
+		t.Error("both servers handed out the same seat token")
+	}
+
+	// Only a's upgrader allows a's origin
+	aWatcher := a.DialWS(t, id, "", "Origin", "https://a.example")
+	aWatcher.Connected(t)
+	url := "ws" + strings.TrimPrefix(b.URL, "http") + "/ws/" + id
+	if conn, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://a.example"}}); err == nil {
+		conn.Close()
+		t.Error("b accepted a WebSocket from a's origin")
+	} else if res == nil || res.StatusCode != http.StatusForbidden {
+		t.Errorf("b answered a's origin with %v, want 403", err)
+	}
+	bWatcher := b.DialWS(t, id, "")
+	bWatcher.Connected(t)
+
+	// Each server's watcher sees its own game alone
+	a.MustMove(t, id, models.PlayerX, 0)
+	b.MustMove(t, id, models.PlayerX, 8)
+	if g := aWatcher.NextOf(t, broadcast.GameUpdateEvent).Game(t); g.Board[0] != models.PlayerX || g.Board[8] != models.Empty {
+		t.Errorf("a's watcher saw %s", g.Board)
+	}
+	if g := bWatcher.NextOf(t, broadcast.GameUpdateEvent).Game(t); g.Board[8] != models.PlayerX || g.Board[0] != models.Empty {
+		t.Errorf("b's watcher saw %s", g.Board)
+	}
+
+	// Seat tokens are good on the server that signed them alone
+	activity := "/api/game/" + id + "/activity?player=X"
+	for _, tt := range []struct {
+		srv   *testutil.Server
+		token string
+		ok    bool
+	}{{a, aToken, true}, {a, bToken, false}, {b, bToken, true}, {b, aToken, false}} {
+		if _, err := tt.srv.Do(t, http.MethodGet, activity, "", nil, "X-Seat-Token", tt.token); (err == nil) != tt.ok {
+			t.Errorf("%s with the token %s: %v", tt.srv.URL, tt.token, err)
+		}
+	}
+
+	// And so are admin keys, and each hub counts its own subscribers
+	for _, tt := range []struct {
+		srv      *testutil.Server
+		key, bad string
+	}{{a, "a-admin", "b-admin"}, {b, "b-admin", "a-admin"}} {
+		var stats struct {
+			Subscribers []broadcast.SubscriberStats `json:"subscribers"`
+		}
+		if _, err := tt.srv.Do(t, http.MethodGet, "/api/admin/hub/"+id, "", &stats, "Authorization", "Bearer "+tt.key); err != nil {
+			t.Fatalf("%s: %v", tt.srv.URL, err)
+		}
+		if len(stats.Subscribers) != 1 {
+			t.Errorf("%s has %d subscribers to %s, want 1", tt.srv.URL, len(stats.Subscribers), id)
+		}
+		if _, err := tt.srv.Do(t, http.MethodGet, "/api/admin/hub/"+id, "", nil, "Authorization", "Bearer "+tt.bad); err == nil {
+			t.Errorf("%s took the other server's admin key", tt.srv.URL)
+		}
+		// Metrics are registered per server, not on the default registry
+		if _, err := tt.srv.Do(t, http.MethodGet, "/metrics", "", nil); err != nil {
+			t.Errorf("%s/metrics: %v", tt.srv.URL, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package server_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestServerTimeStamps checks the welcome frame, countdown events and
+// the claim answer say the server's time along with their deadlines.
+func TestServerTimeStamps(t *testing.T) {
+	srv := testutil.Start(t, server.Config{GameOptions: []game.Option{game.WithCountdown(3 * time.Second)}})
+	// now checks stamp is the server's time at some point since before
+	now := func(what string, stamp, before time.Time) {
+		t.Helper()
+		if stamp.Before(before) || stamp.After(time.Now()) {
+			t.Errorf("%s serverTime %v, want between %v and now", what, stamp, before)
+		}
+	}
+
+	before := time.Now()
+	id := srv.CreateGame(t, `{"readyCheck":true}`).ID
+	_, x := srv.JoinAs(t, id, models.PlayerX)
+	_, o := srv.JoinAs(t, id, models.PlayerO)
+	conn := srv.DialWS(t, id, "")
+	_, welcome := conn.Connected(t)
+	now("welcome", welcome.ServerTime, before)
+
+	before = time.Now()
+	for p, token := range map[models.Player]string{models.PlayerX: x, models.PlayerO: o} {
+		if _, err := srv.Do(t, "POST", "/api/game/"+id+"/ready", `{"player":"`+string(p)+`"}`, nil, seat.TokenHeader, token); err != nil {
+			t.Fatalf("readying %s: %v", p, err)
+		}
+	}
+	var countdown broadcast.Countdown
+	if err := json.Unmarshal(conn.NextOf(t, broadcast.CountdownEvent).Data, &countdown); err != nil {
+		t.Fatal(err)
+	}
+	now("countdown", countdown.ServerTime, before)
+
+	id = srv.CreateGame(t, "").ID
+	srv.JoinAs(t, id, models.PlayerX)
+	srv.JoinAs(t, id, models.PlayerO)
+	srv.MustMove(t, id, models.PlayerX, 4)
+	var claim struct {
+		ClaimableAt time.Time `json:"claimableAt"`
+		ServerTime  time.Time `json:"serverTime"`
+	}
+	before = time.Now()
+	srv.Do(t, "GET", "/api/game/"+id+"/claim?player=X", "", &claim)
+	now("claim", claim.ServerTime, before)
+	if !claim.ClaimableAt.After(claim.ServerTime) {
+		t.Errorf("claimable at %v, not after the server's time %v", claim.ClaimableAt, claim.ServerTime)
+	}
+}
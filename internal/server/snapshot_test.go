@@ -0,0 +1,155 @@
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/snapshot"
+	"tiktaktoes/internal/testutil"
+)
+
+// TestSnapshotRestart stops a server with games mid-move and starts
+// another on its snapshot, where they go on from where they were.
+func TestSnapshotRestart(t *testing.T) {
+	cfg := server.Config{SnapshotPath: filepath.Join(t.TempDir(), "games.json")}
+	a := testutil.Start(t, cfg)
+	online := a.CreateGame(t, "").ID
+	a.JoinAs(t, online, models.PlayerX)
+	a.JoinAs(t, online, models.PlayerO)
+	a.MustMove(t, online, models.PlayerX, 0)
+	moved := a.MustMove(t, online, models.PlayerO, 4)
+	finished := a.CreateGame(t, `{"mode":"hotseat"}`).ID
+	for i, pos := range xWins {
+		a.MustMove(t, finished, []models.Player{models.PlayerX, models.PlayerO}[i%2], pos)
+	}
+	a.Stop(t)
+
+	b := testutil.Start(t, cfg)
+	var got models.GameState
+	if _, err := b.Do(t, http.MethodGet, "/api/game/"+online, "", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Board != moved.Board || got.CurrentTurn != models.PlayerX || len(got.History) != 2 {
+		t.Errorf("restored %s as %s, turn %s, %d moves", online, got.Board, got.CurrentTurn, len(got.History))
+	}
+	if _, err := b.Do(t, http.MethodGet, "/api/game/"+finished, "", nil); err == nil {
+		t.Error("the finished game was restored")
+	}
+	if g := b.MustMove(t, online, models.PlayerX, 8); len(g.History) != 3 {
+		t.Errorf("the restored game went on to %s", g.Board)
+	}
+}
+
+// TestSSEResumeAfterRestart restarts the server under a live event
+// stream, and checks the stream is told to come back, then picks up the
+// game on the new instance from the ID of the last event it had.
+func TestSSEResumeAfterRestart(t *testing.T) {
+	cfg := server.Config{SnapshotPath: filepath.Join(t.TempDir(), "games.json")}
+	a := testutil.Start(t, cfg)
+	g := a.CreateGame(t, "")
+	a.JoinAs(t, g.ID, models.PlayerX)
+	a.JoinAs(t, g.ID, models.PlayerO)
+	a.MustMove(t, g.ID, models.PlayerX, 4)
+	stream := a.OpenSSE(t, nil, "/htmx/sse/"+g.ID)
+	seen := stream.NextOf(t, broadcast.GameUpdateEvent)
+	a.Stop(t)
+	if last := stream.Ended(t); len(last) != 1 || last[0].Name != broadcast.RestartEvent || last[0].Retry != broadcast.RestartRetry {
+		t.Fatalf("the stream ended with %+v, want a %s event", last, broadcast.RestartEvent)
+	}
+
+	// Nothing happened meanwhile, so the resumed stream isn't sent the
+	// game again, while one that missed a move is
+	b := testutil.Start(t, cfg)
+	resumed := b.OpenSSE(t, nil, "/htmx/sse/"+g.ID, "Last-Event-ID", seen.ID)
+	behind := b.OpenSSE(t, nil, "/htmx/sse/"+g.ID, "Last-Event-ID", "1-1")
+	if x, o := marks(behind.NextOf(t, broadcast.GameUpdateEvent).Data); x != 1 || o != 0 {
+		t.Errorf("caught up on a board with %d X and %d O marks, want X's move", x, o)
+	}
+	b.MustMove(t, g.ID, models.PlayerO, 0)
+	if x, o := marks(resumed.NextOf(t, broadcast.GameUpdateEvent).Data); x != 1 || o != 1 {
+		t.Errorf("the resumed stream's first update has %d X and %d O marks, want O's move", x, o)
+	}
+}
+
+// TestWelcomeAfterRestart has a WebSocket client with moves pending
+// across a restart: one the first server applied before the client saw
+// it, and one the client had yet to send. On reconnecting, the new
+// instance ID tells the client to check its moves against the game it
+// is welcomed with, and it sends again only the one that is missing.
+func TestWelcomeAfterRestart(t *testing.T) {
+	cfg := server.Config{SnapshotPath: filepath.Join(t.TempDir(), "games.json"), SeatKey: "seats"}
+	a := testutil.Start(t, cfg)
+	g := a.CreateGame(t, "")
+	_, token := a.JoinAs(t, g.ID, models.PlayerX)
+	a.JoinAs(t, g.ID, models.PlayerO)
+
+	x := a.DialWS(t, g.ID, "player=X", seat.TokenHeader, token)
+	_, before := x.Connected(t)
+	pending := []models.Move{{Player: models.PlayerX, Position: 0}}
+	x.Send(t, pending[0])
+	x.NextOf(t, broadcast.GameUpdateEvent)
+	a.MustMove(t, g.ID, models.PlayerO, 4)
+	pending = append(pending, models.Move{Player: models.PlayerX, Position: 1})
+	a.Stop(t)
+
+	b := testutil.Start(t, cfg)
+	x = b.DialWS(t, g.ID, "player=X", seat.TokenHeader, token)
+	_, after := x.Connected(t)
+	if after.InstanceID == before.InstanceID {
+		t.Fatalf("instance %s on both sides of a restart", after.InstanceID)
+	}
+	if after.Seq != 0 || len(after.Events) != 0 {
+		t.Errorf("welcomed after the restart with seq %d and %d events, want numbering started afresh", after.Seq, len(after.Events))
+	}
+	if after.Game == nil || after.Game.Board[0] != models.PlayerX || after.Game.Board[4] != models.PlayerO {
+		t.Fatalf("welcomed after the restart with %+v, want the restored game", after.Game)
+	}
+
+	// Sent again blindly, the move already applied is refused
+	x.Send(t, pending[0])
+	f := x.Next(t)
+	for ; f.Type != ""; f = x.Next(t) {
+	}
+	if f.Code != "position_taken" {
+		t.Errorf("sending the applied move again: %+v, want position_taken", f)
+	}
+
+	pending = slices.DeleteFunc(pending, func(m models.Move) bool {
+		return after.Game.Board[m.Position] == m.Player
+	})
+	if len(pending) != 1 || pending[0].Position != 1 {
+		t.Fatalf("moves left to send %+v, want just X at 1", pending)
+	}
+	x.Send(t, pending[0])
+	got := x.NextOf(t, broadcast.GameUpdateEvent).Game(t)
+	if got.Board[1] != models.PlayerX || len(got.History) != 3 || got.CurrentTurn != models.PlayerO {
+		t.Errorf("after sending the missing move: %s, %d moves, %s to move", got.Board, len(got.History), got.CurrentTurn)
+	}
+}
+
+// TestSnapshotSkipsInvalidGames checks a server boots on a snapshot
+// with a game that doesn't hold together, restoring the rest.
+func TestSnapshotSkipsInvalidGames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	good := models.NewGameState("abcd1234")
+	bad := models.NewGameState("bad00000")
+	bad.Board[4] = models.PlayerX // with no move in the history
+	if err := snapshot.Write(path, []*models.GameState{bad, good}, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := testutil.Start(t, server.Config{SnapshotPath: path})
+	if _, err := srv.Do(t, http.MethodGet, "/api/game/"+good.ID, "", nil); err != nil {
+		t.Errorf("the valid game wasn't restored: %v", err)
+	}
+	if _, err := srv.Do(t, http.MethodGet, "/api/game/"+bad.ID, "", nil); err == nil {
+		t.Error("the invalid game was restored")
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSConfig selects how the server terminates HTTPS. At most one of a
+// certificate pair and ACME may be configured; with neither the server
+// speaks plain HTTP.
+type TLSConfig struct {
+	// CertFile and KeyFile are a PEM certificate chain and private key.
+	CertFile string
+	KeyFile  string
+	// ACMEDomains enables certificates from Let's Encrypt for these
+	// host names, obtained and renewed automatically.
+	ACMEDomains []string
+	// ACMECacheDir stores ACME account keys and certificates across
+	// restarts. Required with ACMEDomains.
+	ACMECacheDir string
+	// RedirectAddr is the plain HTTP address that redirects to HTTPS
+	// and, with ACME, answers its HTTP-01 challenges. It defaults to ":80"
+	// with ACME and is otherwise disabled when empty.
+	RedirectAddr string
+}
+
+// Enabled reports whether the server serves HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || len(c.ACMEDomains) > 0
+}
+
+// validate checks that the options make sense together.
+func (c TLSConfig) validate() error {
+	switch {
+	case c.CertFile != "" && c.KeyFile == "":
+		return errors.New("tls: certificate given without a key")
+	case c.KeyFile != "" && c.CertFile == "":
+		return errors.New("tls: key given without a certificate")
+	case c.CertFile != "" && len(c.ACMEDomains) > 0:
+		return errors.New("tls: use either a certificate or ACME, not both")
+	case len(c.ACMEDomains) > 0 && c.ACMECacheDir == "":
+		return errors.New("tls: ACME needs a cache directory")
+	case c.RedirectAddr != "" && !c.Enabled():
+		return errors.New("tls: HTTPS redirect needs TLS enabled")
+	}
+	for _, d := range c.ACMEDomains {
+		if d == "" || strings.ContainsAny(d, "/: ") {
+			return errors.New("tls: invalid ACME domain " + d)
+		}
+	}
+	return nil
+}
+
+// setupTLS configures s.http for HTTPS and builds the redirect server,
+// if any. It must be called after s.http is created.
+func (s *Server) setupTLS(cfg TLSConfig) error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	fallback := redirectHTTPS(httpsPort(s.cfg.Addr))
+	redirect := fallback
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		s.http.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	} else {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cfg.ACMECacheDir),
+			HostPolicy: autocert.HostWhitelist(cfg.ACMEDomains...),
+		}
+		s.http.TLSConfig = m.TLSConfig()
+		redirect = m.HTTPHandler(fallback)
+		if cfg.RedirectAddr == "" {
+			cfg.RedirectAddr = ":80"
+		}
+	}
+
+	if cfg.RedirectAddr != "" {
+		s.redirect = &http.Server{
+			Addr:    cfg.RedirectAddr,
+			Handler: redirect,
+		}
+	}
+	return nil
+}
+
+// httpsPort returns the port of the HTTPS listen address, or "" for the
+// default port.
+func httpsPort(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil || port == "443" {
+		return ""
+	}
+	return port
+}
+
+// redirectHTTPS sends plain HTTP requests to the same host and path over
+// HTTPS on the given port ("" for 443). Other methods than GET and HEAD
+// are refused rather than redirected: their body has already been sent
+// in the clear, and following the redirect would hide that.
+func redirectHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Use HTTPS", http.StatusBadRequest)
+			return
+		}
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "Use HTTPS", http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if port != "" {
+			host += ":" + port
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSConfigValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  TLSConfig
+		ok   bool
+	}{
+		{"plain HTTP", TLSConfig{}, true},
+		{"certificate", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}, true},
+		{"certificate with a redirect", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", RedirectAddr: ":80"}, true},
+		{"ACME", TLSConfig{ACMEDomains: []string{"ttt.example.com"}, ACMECacheDir: "certs"}, true},
+		{"certificate without a key", TLSConfig{CertFile: "cert.pem"}, false},
+		{"key without a certificate", TLSConfig{KeyFile: "key.pem"}, false},
+		{"certificate and ACME", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ACMEDomains: []string{"ttt.example.com"}, ACMECacheDir: "certs"}, false},
+		{"ACME without a cache", TLSConfig{ACMEDomains: []string{"ttt.example.com"}}, false},
+		{"redirect without TLS", TLSConfig{RedirectAddr: ":80"}, false},
+		{"empty ACME domain", TLSConfig{ACMEDomains: []string{""}, ACMECacheDir: "certs"}, false},
+		{"ACME domain with a port", TLSConfig{ACMEDomains: []string{"ttt.example.com:443"}, ACMECacheDir: "certs"}, false},
+		{"ACME domain as a URL", TLSConfig{ACMEDomains: []string{"https://ttt.example.com/"}, ACMECacheDir: "certs"}, false},
+	} {
+		if err := tt.cfg.validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: validate() = %v", tt.name, err)
+		}
+	}
+	// New refuses them too, before starting anything
+	if _, err := New(Config{TLS: TLSConfig{CertFile: "cert.pem"}}); err == nil {
+		t.Error("New took a certificate without a key")
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	for _, tt := range []struct {
+		name, method, host, target, port string
+		status                           int
+		location                         string
+	}{
+		{"default port", http.MethodGet, "ttt.example.com", "/game/abc?player=X", "", http.StatusMovedPermanently, "https://ttt.example.com/game/abc?player=X"},
+		{"plain port dropped", http.MethodGet, "ttt.example.com:80", "/", "", http.StatusMovedPermanently, "https://ttt.example.com/"},
+		{"other HTTPS port", http.MethodHead, "ttt.example.com", "/ws/abc", "8443", http.StatusMovedPermanently, "https://ttt.example.com:8443/ws/abc"},
+		{"IPv6", http.MethodGet, "[::1]:80", "/", "8443", http.StatusMovedPermanently, "https://[::1]:8443/"},
+		{"POST refused", http.MethodPost, "ttt.example.com", "/api/game", "", http.StatusBadRequest, ""},
+		{"no host", http.MethodGet, "", "/", "", http.StatusBadRequest, ""},
+	} {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		redirectHTTPS(tt.port).ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s: %d to %q, want %d to %q", tt.name, w.Code, w.Header().Get("Location"), tt.status, tt.location)
+		}
+	}
+}
+
+func TestHTTPSPort(t *testing.T) {
+	for addr, want := range map[string]string{":443": "", ":8443": "8443", "127.0.0.1:8443": "8443", "": ""} {
+		if got := httpsPort(addr); got != want {
+			t.Errorf("httpsPort(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+// selfSigned writes a certificate for 127.0.0.1 and its key to dir, and
+// returns their paths.
+func selfSigned(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for path, block := range map[string]*pem.Block{certFile: {Type: "CERTIFICATE", Bytes: der}, keyFile: {Type: "EC PRIVATE KEY", Bytes: keyDER}} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return certFile, keyFile
+}
+
+// freeAddr returns a loopback address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// TestServeTLS serves HTTPS with a certificate and the plain HTTP
+// redirect beside it, then shuts down and checks both listeners closed.
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile := selfSigned(t, t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirectAddr := freeAddr(t)
+	srv, err := New(Config{
+		Addr: ln.Addr().String(),
+		TLS:  TLSConfig{CertFile: certFile, KeyFile: keyFile, RedirectAddr: redirectAddr},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	served := make(chan error)
+	go func() { served <- srv.Serve(ctx, ln) }()
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: 5 * time.Second,
+	}
+	defer client.CloseIdleConnections()
+	res, err := client.Get("https://" + ln.Addr().String() + "/api/featured")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.TLS == nil || res.StatusCode != http.StatusOK {
+		t.Errorf("HTTPS answered %s, TLS %v", res.Status, res.TLS != nil)
+	}
+
+	// The redirect server starts alongside, so it may take a moment
+	var redirected *http.Response
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if redirected, err = client.Get("http://" + redirectAddr + "/game/abc?player=X"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirected.Body.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	if want := "https://127.0.0.1:" + port + "/game/abc?player=X"; redirected.StatusCode != http.StatusMovedPermanently || redirected.Header.Get("Location") != want {
+		t.Errorf("redirect answered %s to %q, want 301 to %q", redirected.Status, redirected.Header.Get("Location"), want)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	for _, addr := range []string{ln.Addr().String(), redirectAddr} {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			t.Errorf("%s still listening after shutdown", addr)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spansOnce sync.Once
+	spans     *tracetest.SpanRecorder
+)
+
+// recordSpans installs a global tracer provider recording every span,
+// once, since the packages' tracers keep the first one installed, and
+// forgets the spans recorded so far.
+func recordSpans() *tracetest.SpanRecorder {
+	spansOnce.Do(func() {
+		spans = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	})
+	spans.Reset()
+	return spans
+}
+
+// TestMoveSpans makes one move over the API and checks the spans it
+// leaves: the HTTP request's, the service's and the hub's under it, and
+// the hub's fan-out under that.
+func TestMoveSpans(t *testing.T) {
+	srv := testutil.Start(t, server.Config{Tracing: true})
+	id := srv.CreateGame(t, `{"mode":"hotseat"}`).ID
+	recorder := recordSpans()
+	srv.MustMove(t, id, models.PlayerX, 4)
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		clear(byName)
+		for _, s := range recorder.Ended() {
+			byName[s.Name()] = s
+		}
+		if byName["hub.Broadcast.dispatch"] != nil && byName["http"] != nil {
+			break
+		}
+	}
+
+	parents := map[string]string{
+		"game.MakeMove":          "http",
+		"hub.Broadcast":          "http",
+		"hub.Broadcast.dispatch": "hub.Broadcast",
+	}
+	root := byName["http"]
+	if root == nil {
+		t.Fatalf("no span for the request, got %v", byName)
+	}
+	for name, parent := range parents {
+		s, p := byName[name], byName[parent]
+		switch {
+		case s == nil:
+			t.Errorf("no %s span", name)
+		case s.SpanContext().TraceID() != root.SpanContext().TraceID():
+			t.Errorf("%s is in another trace than the request", name)
+		case p != nil && s.Parent().SpanID() != p.SpanContext().SpanID():
+			t.Errorf("%s isn't a child of %s", name, parent)
+		}
+	}
+}
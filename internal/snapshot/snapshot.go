@@ -0,0 +1,100 @@
+// Package snapshot persists in-progress games to a file so they survive
+// restarts.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+// Version is the current snapshot format version.
+const Version = 1
+
+// File is the on-disk snapshot format.
+type File struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// JournalSeq is the last journal entry reflected in Games. Recovery
+	// replays journal entries after it.
+	JournalSeq uint64              `json:"journalSeq,omitempty"`
+	Games      []*models.GameState `json:"games"`
+}
+
+// migrations upgrade a raw snapshot from the keyed version to the next one.
+// Add an entry here whenever Version is bumped.
+var migrations = map[int]func(json.RawMessage) (json.RawMessage, error){}
+
+// Write atomically replaces the snapshot at path with the given games.
+func Write(path string, games []*models.GameState, journalSeq uint64, now time.Time) error {
+	data, err := json.Marshal(File{
+		Version:    Version,
+		CreatedAt:  now,
+		JournalSeq: journalSeq,
+		Games:      games,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Read loads the snapshot at path, migrating older formats. A missing
+// file is not an error and yields an empty snapshot.
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	if header.Version > Version {
+		return nil, fmt.Errorf("snapshot %s: unsupported version %d", path, header.Version)
+	}
+	for v := header.Version; v < Version; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("snapshot %s: no migration from version %d", path, v)
+		}
+		if data, err = migrate(data); err != nil {
+			return nil, fmt.Errorf("snapshot %s: migrating from version %d: %w", path, v, err)
+		}
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	return &file, nil
+}
@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/models"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	g := models.NewGameState("abcd1234")
+	g.Board[4] = models.PlayerX
+	g.History = []models.MoveRecord{{Position: 4, Player: models.PlayerX}}
+
+	if err := Write(path, []*models.GameState{g}, 42, now); err != nil {
+		t.Fatal(err)
+	}
+	file, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != Version || !file.CreatedAt.Equal(now) || file.JournalSeq != 42 {
+		t.Errorf("read version %d, created %v, journal seq %d", file.Version, file.CreatedAt, file.JournalSeq)
+	}
+	if len(file.Games) != 1 || file.Games[0].ID != g.ID || file.Games[0].Board != g.Board || len(file.Games[0].History) != 1 {
+		t.Errorf("read games %+v", file.Games)
+	}
+
+	// Writing again replaces the file whole, leaving no temporary files
+	if err := Write(path, nil, 43, now); err != nil {
+		t.Fatal(err)
+	}
+	if file, err := Read(path); err != nil || len(file.Games) != 0 || file.JournalSeq != 43 {
+		t.Errorf("read %+v, %v after rewriting", file, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("%d files left next to the snapshot", len(entries)-1)
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	file, err := Read(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil || file.Version != Version || len(file.Games) != 0 {
+		t.Errorf("Read of a missing file = %+v, %v, want an empty snapshot", file, err)
+	}
+}
+
+func TestReadVersions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	newer := write("newer.json", `{"version":99,"games":[]}`)
+	if _, err := Read(newer); err == nil || !strings.Contains(err.Error(), "unsupported version 99") {
+		t.Errorf("reading a newer version: %v", err)
+	}
+	old := write("old.json", `{"version":0,"created":"2026-01-02T03:04:05Z","list":[{"id":"abcd1234"}]}`)
+	if _, err := Read(old); err == nil || !strings.Contains(err.Error(), "no migration from version 0") {
+		t.Errorf("reading a version without a migration: %v", err)
+	}
+	if _, err := Read(write("bad.json", "not json")); err == nil {
+		t.Error("read a file that isn't JSON")
+	}
+
+	// A migration upgrades an older file as it is read
+	migrations[0] = func(data json.RawMessage) (json.RawMessage, error) {
+		var v0 struct {
+			Created time.Time           `json:"created"`
+			List    []*models.GameState `json:"list"`
+		}
+		if err := json.Unmarshal(data, &v0); err != nil {
+			return nil, err
+		}
+		return json.Marshal(File{Version: 1, CreatedAt: v0.Created, Games: v0.List})
+	}
+	t.Cleanup(func() { delete(migrations, 0) })
+	file, err := Read(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != Version || len(file.Games) != 1 || file.Games[0].ID != "abcd1234" || file.CreatedAt.IsZero() {
+		t.Errorf("migrated to %+v", file)
+	}
+}
@@ -0,0 +1,190 @@
+// Package sse writes server-sent event streams, with optional heartbeats.
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeat is a heartbeat interval comfortably below the idle
+// timeouts of common reverse proxies.
+const DefaultHeartbeat = 25 * time.Second
+
+var (
+	// ErrUnsupported is returned by NewWriter when the response can't be
+	// flushed, so events would sit in a buffer instead of being streamed.
+	ErrUnsupported = errors.New("streaming not supported")
+	// ErrClosed is returned by Send after Close.
+	ErrClosed = errors.New("sse: writer closed")
+	// ErrInvalidField is returned by Send for a name or id with a line
+	// break in it, which would end the field early and let the rest be
+	// read as fields of its own, or an id with a NUL, which EventSource
+	// ignores.
+	ErrInvalidField = errors.New("sse: invalid event name or id")
+)
+
+// Event is one server-sent event. Only Data is required.
+type Event struct {
+	// Name is the event type; EventSource calls "message" listeners for
+	// events without one.
+	Name string
+	// ID is remembered by EventSource and sent back as Last-Event-ID
+	// when it reconnects.
+	ID string
+	// Data is the payload. It may span several lines and may be empty.
+	Data []byte
+	// Retry, when set, tells EventSource how long to wait before
+	// reconnecting once the stream ends.
+	Retry time.Duration
+}
+
+// Writer streams events to one client. Its methods may be called from
+// several goroutines.
+type Writer struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewWriter starts an event stream on w, sending the response headers
+// straight away. It fails with ErrUnsupported, having written nothing,
+// when w can't be flushed.
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &Writer{w: w, flusher: flusher, stop: make(chan struct{})}, nil
+}
+
+// Send writes ev and flushes it to the client.
+func (sw *Writer) Send(ev Event) error {
+	if strings.ContainsAny(ev.Name, "\r\n") || strings.ContainsAny(ev.ID, "\r\n\x00") {
+		return ErrInvalidField
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.closed {
+		return ErrClosed
+	}
+
+	sw.buf.Reset()
+	if ev.Retry > 0 {
+		sw.buf.WriteString("retry: ")
+		sw.buf.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		sw.buf.WriteByte('\n')
+	}
+	if ev.ID != "" {
+		sw.buf.WriteString("id: ")
+		sw.buf.WriteString(ev.ID)
+		sw.buf.WriteByte('\n')
+	}
+	if ev.Name != "" {
+		sw.buf.WriteString("event: ")
+		sw.buf.WriteString(ev.Name)
+		sw.buf.WriteByte('\n')
+	}
+	writeData(&sw.buf, ev.Data)
+	sw.buf.WriteByte('\n')
+	err := sw.flush()
+	// Don't hold on to the buffer of an unusually large event
+	if sw.buf.Cap() > 64<<10 {
+		sw.buf = bytes.Buffer{}
+	}
+	return err
+}
+
+// writeData writes data as data lines, breaking it at "\r\n", "\r" and
+// "\n" alike since EventSource accepts all three as line endings.
+func writeData(buf *bytes.Buffer, data []byte) {
+	for {
+		i := bytes.IndexAny(data, "\r\n")
+		buf.WriteString("data: ")
+		if i < 0 {
+			buf.Write(data)
+			buf.WriteByte('\n')
+			return
+		}
+		buf.Write(data[:i])
+		buf.WriteByte('\n')
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		data = data[i+1:]
+	}
+}
+
+// Heartbeat sends a comment every interval until Close, so that proxies
+// don't take a quiet stream for a dead one. Calling it again adds
+// another heartbeat rather than replacing the first.
+func (sw *Writer) Heartbeat(interval time.Duration) {
+	sw.wg.Add(1)
+	go func() {
+		defer sw.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if sw.comment("heartbeat") != nil {
+					return
+				}
+			case <-sw.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (sw *Writer) comment(text string) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.closed {
+		return ErrClosed
+	}
+	sw.buf.Reset()
+	sw.buf.WriteString(": ")
+	sw.buf.WriteString(text)
+	sw.buf.WriteString("\n\n")
+	return sw.flush()
+}
+
+// flush writes the buffered frame. Must be called with the lock held.
+func (sw *Writer) flush() error {
+	if _, err := sw.w.Write(sw.buf.Bytes()); err != nil {
+		return err
+	}
+	sw.flusher.Flush()
+	return nil
+}
+
+// Close stops heartbeats and makes further sends fail, returning once
+// nothing writes to the response anymore. It doesn't end the stream,
+// which happens when the handler returns, and is safe to call twice.
+func (sw *Writer) Close() error {
+	sw.mu.Lock()
+	if sw.closed {
+		sw.mu.Unlock()
+		return nil
+	}
+	sw.closed = true
+	sw.mu.Unlock()
+	close(sw.stop)
+	sw.wg.Wait()
+	return nil
+}
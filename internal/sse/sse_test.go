@@ -0,0 +1,151 @@
+package sse_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/sse"
+	"tiktaktoes/internal/testutil"
+)
+
+// newWriter returns a Writer on a recorder, and the recorder.
+func newWriter(t *testing.T) (*sse.Writer, *httptest.ResponseRecorder) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	sw, err := sse.NewWriter(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sw, rec
+}
+
+func TestNewWriter(t *testing.T) {
+	_, rec := newWriter(t)
+	for name, want := range map[string]string{"Content-Type": "text/event-stream", "Cache-Control": "no-cache"} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s: %q, want %q", name, got, want)
+		}
+	}
+	if !rec.Flushed {
+		t.Error("the headers weren't flushed")
+	}
+
+	// A response that can't be flushed would hold the events back
+	var plain struct{ http.ResponseWriter }
+	plain.ResponseWriter = httptest.NewRecorder()
+	if _, err := sse.NewWriter(plain); !errors.Is(err, sse.ErrUnsupported) {
+		t.Errorf("unflushable response: %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSendFraming(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		ev   sse.Event
+		want string
+	}{
+		{"data alone", sse.Event{Data: []byte("x")}, "data: x\n\n"},
+		{"empty data", sse.Event{Name: "ping"}, "event: ping\ndata: \n\n"},
+		{"every field", sse.Event{Name: "game-update", ID: "3-17", Data: []byte("{}"), Retry: 3 * time.Second}, "retry: 3000\nid: 3-17\nevent: game-update\ndata: {}\n\n"},
+		{"sub-millisecond retry", sse.Event{Data: []byte("x"), Retry: time.Microsecond}, "retry: 0\ndata: x\n\n"},
+		{"lines", sse.Event{Data: []byte("<div>\n  <b>X</b>\n</div>")}, "data: <div>\ndata:   <b>X</b>\ndata: </div>\n\n"},
+		{"CRLF", sse.Event{Data: []byte("a\r\nb")}, "data: a\ndata: b\n\n"},
+		{"CR", sse.Event{Data: []byte("a\rb")}, "data: a\ndata: b\n\n"},
+		{"LF CR", sse.Event{Data: []byte("a\n\rb")}, "data: a\ndata: \ndata: b\n\n"},
+		{"blank line", sse.Event{Data: []byte("a\n\nb")}, "data: a\ndata: \ndata: b\n\n"},
+		{"trailing newline", sse.Event{Data: []byte("a\n")}, "data: a\ndata: \n\n"},
+		{"field-like data", sse.Event{Data: []byte("x\nevent: forged\nid: 9")}, "data: x\ndata: event: forged\ndata: id: 9\n\n"},
+	} {
+		sw, rec := newWriter(t)
+		if err := sw.Send(tt.ev); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestSendRoundTrip reads events back as EventSource would, which must
+// give each one's data with its line breaks as "\n".
+func TestSendRoundTrip(t *testing.T) {
+	huge := strings.Repeat(strings.Repeat("x", 1000)+"\n", 1024)
+	sent := []sse.Event{
+		{Name: "game-update", ID: "1-1", Data: []byte("<div>\r\n<b>X</b>\r\n</div>")},
+		{Data: nil},
+		{Name: "huge", Data: []byte(huge)},
+		{Name: "game-error", Data: []byte(`{"code":"not_your_turn"}`), Retry: time.Second},
+	}
+	sw, rec := newWriter(t)
+	for _, ev := range sent {
+		if err := sw.Send(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := testutil.NewSSEReader(rec.Body)
+	for i, want := range sent {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		wantData := strings.ReplaceAll(string(want.Data), "\r\n", "\n")
+		if got.Name != want.Name || got.ID != want.ID || got.Retry != want.Retry || got.Data != wantData {
+			t.Errorf("event %d: read %s %q %v with %d bytes, want %s %q %v with %d", i, got.Name, got.ID, got.Retry, len(got.Data), want.Name, want.ID, want.Retry, len(wantData))
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("after the events: %v, want EOF", err)
+	}
+}
+
+func TestSendRejectsInvalidFields(t *testing.T) {
+	for _, ev := range []sse.Event{
+		{Name: "game-update\ndata: forged"},
+		{Name: "a\rb"},
+		{ID: "1\n"},
+		{ID: "1\x002"},
+	} {
+		sw, rec := newWriter(t)
+		if err := sw.Send(ev); !errors.Is(err, sse.ErrInvalidField) {
+			t.Errorf("Send(%q, %q) = %v, want ErrInvalidField", ev.Name, ev.ID, err)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("Send(%q, %q) wrote %q", ev.Name, ev.ID, rec.Body)
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	sw, rec := newWriter(t)
+	sw.Heartbeat(5 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Close has waited for the heartbeat, so the body is safe to read
+	body := rec.Body.String()
+	if n := strings.Count(body, ": heartbeat\n\n"); n < 2 || n*len(": heartbeat\n\n") != len(body) {
+		t.Errorf("wrote %q, want heartbeat comments alone", body)
+	}
+	ev, err := testutil.NewSSEReader(strings.NewReader(body)).Next()
+	if err != nil || len(ev.Comments) != 1 || ev.Comments[0] != "heartbeat" || ev.Data != "" {
+		t.Errorf("read %+v, %v, want a heartbeat comment", ev, err)
+	}
+
+	if err := sw.Send(sse.Event{Data: []byte("late")}); !errors.Is(err, sse.ErrClosed) {
+		t.Errorf("Send after Close: %v, want ErrClosed", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if rec.Body.Len() != len(body) {
+		t.Errorf("wrote %q after Close", rec.Body.String()[len(body):])
+	}
+}
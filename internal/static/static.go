@@ -0,0 +1,171 @@
+// Package static serves the frontend, with fingerprinted asset names
+// served as immutable. Files are read once at startup.
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	indexName = "/index.html"
+	// hashLen is the number of hex digits of the content hash in a
+	// fingerprinted name
+	hashLen = 8
+
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+)
+
+type file struct {
+	data    []byte
+	modTime time.Time
+	// etag is a hash of data. Conditional requests go by it rather than
+	// modTime, which doesn't change for index.html when only an asset
+	// it references does.
+	etag string
+	// immutable is set for fingerprinted names
+	immutable bool
+}
+
+// Handler serves a directory of static files.
+type Handler struct {
+	// files maps URL paths, both the original and fingerprinted names,
+	// to their contents
+	files map[string]*file
+	// fingerprinted maps an asset's original path to its hashed one
+	fingerprinted map[string]string
+	index         *file
+}
+
+// New loads the files under dir. Problems are logged rather than
+// returned, so a broken frontend never keeps the API from starting;
+// whatever couldn't be loaded is not found.
+func New(dir string) *Handler {
+	h := &Handler{
+		files:         make(map[string]*file),
+		fingerprinted: make(map[string]string),
+	}
+	err := fs.WalkDir(os.DirFS(dir), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path.Join(dir, name))
+		if err != nil {
+			slog.Warn("skipping static file", "file", name, "error", err)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		urlPath := "/" + name
+		f := newFile(data, info.ModTime())
+		h.files[urlPath] = f
+		if path.Ext(name) != ".html" {
+			hashed := fingerprint(urlPath, f.etag)
+			h.fingerprinted[urlPath] = hashed
+			immutable := *f
+			immutable.immutable = true
+			h.files[hashed] = &immutable
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("loading static files failed", "dir", dir, "error", err)
+	}
+
+	if raw, ok := h.files[indexName]; ok {
+		index, err := h.render(raw.data)
+		if err != nil {
+			slog.Error("rendering index.html failed", "error", err)
+			delete(h.files, indexName)
+		} else {
+			h.index = newFile(index, raw.modTime)
+			h.files[indexName] = h.index
+		}
+	}
+	return h
+}
+
+func newFile(data []byte, modTime time.Time) *file {
+	sum := sha256.Sum256(data)
+	return &file{data: data, modTime: modTime, etag: hex.EncodeToString(sum[:])}
+}
+
+// fingerprint inserts the start of a content hash before the extension
+// of p.
+func fingerprint(p, hash string) string {
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + "." + hash[:hashLen] + ext
+}
+
+// render executes an HTML page as a template. The asset function maps a
+// path relative to the static root to its fingerprinted name, still
+// relative so the page works under a path prefix.
+func (h *Handler) render(page []byte) ([]byte, error) {
+	tmpl, err := template.New("page").Funcs(template.FuncMap{
+		"asset": func(name string) string {
+			if hashed, ok := h.fingerprinted["/"+strings.TrimPrefix(name, "/")]; ok {
+				return strings.TrimPrefix(hashed, "/")
+			}
+			slog.Warn("unknown static asset", "asset", name)
+			return name
+		},
+	}).Parse(string(page))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ServeHTTP serves the file at the request path. The root serves
+// index.html, and so do unknown extensionless paths requested by a
+// browser, so deep links can be handled client-side. Anything else that
+// doesn't exist, directories included, is not found.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p := path.Clean("/" + r.URL.Path)
+	if p == "/" {
+		p = indexName
+	}
+	f, ok := h.files[p]
+	if !ok && h.index != nil && path.Ext(p) == "" && acceptsHTML(r) {
+		f, ok, p = h.index, true, indexName
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if f.immutable {
+		w.Header().Set("Cache-Control", cacheImmutable)
+	} else {
+		w.Header().Set("Cache-Control", cacheRevalidate)
+	}
+	w.Header().Set("ETag", `"`+f.etag+`"`)
+	http.ServeContent(w, r, p, f.modTime, bytes.NewReader(f.data))
+}
+
+// acceptsHTML reports whether the request looks like a browser
+// navigation rather than a fetch of some resource.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,153 @@
+package static_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/static"
+)
+
+// site writes a frontend to a temporary directory and returns a handler
+// serving it.
+func site(t *testing.T) *static.Handler {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":   `<link rel="stylesheet" href="{{asset "app.css"}}"><script src="{{asset "/js/app.js"}}"></script>`,
+		"app.css":      "body { color: red }",
+		"js/app.js":    "console.log('hi')",
+		"about.html":   "<p>about</p>",
+		"robots.txt":   "User-agent: *",
+		"img/logo.svg": "<svg/>",
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return static.New(dir)
+}
+
+// get requests path from h, with an Accept header if accept is set.
+func get(h http.Handler, path, accept string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+var assetPattern = regexp.MustCompile(`(?:href|src)="([^"]+)"`)
+
+func TestIndexReferencesFingerprintedAssets(t *testing.T) {
+	h := site(t)
+	index := get(h, "/", "text/html").Body.String()
+	assets := assetPattern.FindAllStringSubmatch(index, -1)
+	if len(assets) != 2 {
+		t.Fatalf("index.html references %d assets:\n%s", len(assets), index)
+	}
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^app\.[0-9a-f]{8}\.css$`),
+		regexp.MustCompile(`^js/app\.[0-9a-f]{8}\.js$`),
+	}
+	bodies := []string{"body { color: red }", "console.log('hi')"}
+	for i, m := range assets {
+		if !want[i].MatchString(m[1]) {
+			t.Errorf("index.html references %q, want a relative fingerprinted name", m[1])
+			continue
+		}
+		w := get(h, "/"+m[1], "")
+		if w.Code != http.StatusOK || w.Body.String() != bodies[i] {
+			t.Errorf("GET /%s: %d %q", m[1], w.Code, w.Body)
+		}
+	}
+}
+
+func TestCacheHeaders(t *testing.T) {
+	h := site(t)
+	m := assetPattern.FindStringSubmatch(get(h, "/", "").Body.String())
+	tests := []struct {
+		path  string
+		cache string
+	}{
+		{"/", "no-cache"},
+		{"/index.html", "no-cache"},
+		{"/app.css", "no-cache"},
+		{"/" + m[1], "public, max-age=31536000, immutable"},
+		{"/robots.txt", "no-cache"},
+	}
+	for _, tt := range tests {
+		w := get(h, tt.path, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: %d", tt.path, w.Code)
+			continue
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cache {
+			t.Errorf("GET %s: Cache-Control %q, want %q", tt.path, got, tt.cache)
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Errorf("GET %s: no ETag", tt.path)
+			continue
+		}
+		if w := get(h, tt.path, "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+			t.Errorf("GET %s again with its ETag: %d, want 304", tt.path, w.Code)
+		}
+	}
+}
+
+func TestNotFoundAndFallback(t *testing.T) {
+	h := site(t)
+	index := get(h, "/", "").Body.String()
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+		index  bool
+	}{
+		{"a deep link", "/game/abcd1234", "text/html,application/xhtml+xml", http.StatusOK, true},
+		{"a deep link fetched", "/game/abcd1234", "application/json", http.StatusNotFound, false},
+		{"a missing asset", "/missing.js", "text/html", http.StatusNotFound, false},
+		{"a stale fingerprint", "/app.00000000.css", "", http.StatusNotFound, false},
+		{"a directory", "/js/", "", http.StatusNotFound, false},
+		{"a directory without its slash", "/img", "", http.StatusNotFound, false},
+		{"another page", "/about.html", "text/html", http.StatusOK, false},
+		{"a path escaping the root", "/../static.go", "", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		w := get(h, tt.path, tt.accept)
+		if w.Code != tt.status {
+			t.Errorf("%s: GET %s: %d, want %d", tt.name, tt.path, w.Code, tt.status)
+		}
+		if got := w.Body.String() == index; got != tt.index {
+			t.Errorf("%s: GET %s served index.html: %v", tt.name, tt.path, got)
+		}
+		if strings.Contains(w.Body.String(), "app.js") && !tt.index {
+			t.Errorf("%s: GET %s listed a directory:\n%s", tt.name, tt.path, w.Body)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	body, _ := io.ReadAll(w.Body)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST /: %d, Allow %q: %s", w.Code, w.Header().Get("Allow"), body)
+	}
+}
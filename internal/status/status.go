@@ -0,0 +1,93 @@
+// Package status gathers what operators look at to see how the server is
+// doing, served as JSON at /statusz and as a table at /htmx/statusz.
+package status
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/version"
+)
+
+// started is about when the process started, for Report.Uptime.
+var started = time.Now()
+
+// Report is a point-in-time view of the server.
+type Report struct {
+	StartedAt time.Time `json:"startedAt"`
+	// Uptime is in seconds.
+	Uptime    float64    `json:"uptime"`
+	GoVersion string     `json:"goVersion"`
+	Build     Build      `json:"build"`
+	Games     game.Stats `json:"games"`
+	Hub       Hub        `json:"hub"`
+}
+
+// Build describes the binary, see version.Info. Revision is the commit
+// it was built from and Time when it was built, or if that wasn't
+// stamped, the commit's time.
+type Build struct {
+	Path     string    `json:"path,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Revision string    `json:"revision,omitempty"`
+	Time     time.Time `json:"time,omitzero"`
+	Modified bool      `json:"modified,omitempty"`
+}
+
+// Hub sums up broadcast.HubStats without the per-game figures.
+type Hub struct {
+	Broadcasts          uint64                      `json:"broadcasts"`
+	Events              uint64                      `json:"events"`
+	Dropped             uint64                      `json:"dropped"`
+	FirehoseDisconnects uint64                      `json:"firehoseDisconnects"`
+	PolicyDisconnects   uint64                      `json:"policyDisconnects"`
+	Subscribers         map[broadcast.Transport]int `json:"subscribers"`
+	// Games counts the games with at least one client.
+	Games int `json:"games"`
+}
+
+// Collector gathers Reports from the services' own Stats.
+type Collector struct {
+	games *game.Service
+	hub   *broadcast.Hub
+}
+
+// NewCollector creates a collector reporting on games and hub.
+func NewCollector(games *game.Service, hub *broadcast.Hub) *Collector {
+	return &Collector{games: games, hub: hub}
+}
+
+// Collect returns the server's current Report.
+func (c *Collector) Collect(ctx context.Context) Report {
+	hub := c.hub.Stats()
+	return Report{
+		StartedAt: started,
+		Uptime:    time.Since(started).Seconds(),
+		GoVersion: runtime.Version(),
+		Build:     readBuild(),
+		Games:     c.games.Stats(ctx),
+		Hub: Hub{
+			Broadcasts:          hub.Broadcasts,
+			Events:              hub.Events,
+			Dropped:             hub.Dropped,
+			FirehoseDisconnects: hub.FirehoseDisconnects,
+			PolicyDisconnects:   hub.PolicyDisconnects,
+			Subscribers:         hub.Subscribers,
+			Games:               len(hub.Games),
+		},
+	}
+}
+
+func readBuild() Build {
+	info := version.Get()
+	return Build{
+		Path:     info.Path,
+		Version:  info.Version,
+		Revision: info.Commit,
+		Time:     info.BuiltAt,
+		Modified: info.Modified,
+	}
+}
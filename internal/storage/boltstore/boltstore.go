@@ -0,0 +1,226 @@
+// Package boltstore implements game.Repository on an embedded bbolt
+// database, finished games moving to the "archive" bucket.
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	gamesBucket    = []byte("games")
+	archiveBucket  = []byte("archive")
+	keysBucket     = []byte("keys")
+	featuredBucket = []byte("featured")
+)
+
+// Store is a bbolt-backed game repository.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{gamesBucket, archiveBucket, keysBucket, featuredBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Get returns the game with the given ID from either bucket.
+func (s *Store) Get(ctx context.Context, id string) (*models.GameState, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	var game *models.GameState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gamesBucket).Get([]byte(id))
+		if data == nil {
+			data = tx.Bucket(archiveBucket).Get([]byte(id))
+		}
+		if data == nil {
+			return nil
+		}
+		game = &models.GameState{}
+		return json.Unmarshal(data, game)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return game, game != nil, nil
+}
+
+// Put stores a game, moving it to the archive once it is over.
+func (s *Store) Put(ctx context.Context, game *models.GameState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	key := []byte(game.ID)
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		live, archive := tx.Bucket(gamesBucket), tx.Bucket(archiveBucket)
+		if game.IsOver {
+			if err := live.Delete(key); err != nil {
+				return err
+			}
+			return archive.Put(key, data)
+		}
+		if err := archive.Delete(key); err != nil {
+			return err
+		}
+		return live.Put(key, data)
+	})
+}
+
+// Delete removes a game from both buckets.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	key := []byte(id)
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(gamesBucket).Delete(key); err != nil {
+			return err
+		}
+		return tx.Bucket(archiveBucket).Delete(key)
+	})
+}
+
+// List returns every stored game, live and archived.
+func (s *Store) List(ctx context.Context) ([]*models.GameState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var games []*models.GameState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{gamesBucket, archiveBucket} {
+			err := tx.Bucket(name).ForEach(func(_, data []byte) error {
+				game := &models.GameState{}
+				if err := json.Unmarshal(data, game); err != nil {
+					return err
+				}
+				games = append(games, game)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return games, err
+}
+
+// PutKey stores an API key.
+func (s *Store) PutKey(ctx context.Context, key *apikey.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).Put([]byte(key.ID), data)
+	})
+}
+
+// DeleteKey removes an API key.
+func (s *Store) DeleteKey(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).Delete([]byte(id))
+	})
+}
+
+// ListKeys returns every stored API key.
+func (s *Store) ListKeys(ctx context.Context) ([]*apikey.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var keys []*apikey.Key
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).ForEach(func(_, data []byte) error {
+			key := &apikey.Key{}
+			if err := json.Unmarshal(data, key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// PutFeatured stores a featured game entry.
+func (s *Store) PutFeatured(ctx context.Context, entry featured.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(featuredBucket).Put([]byte(entry.GameID), data)
+	})
+}
+
+// DeleteFeatured removes a featured game entry.
+func (s *Store) DeleteFeatured(ctx context.Context, gameID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(featuredBucket).Delete([]byte(gameID))
+	})
+}
+
+// ListFeatured returns every stored featured game entry.
+func (s *Store) ListFeatured(ctx context.Context) ([]featured.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var entries []featured.Entry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(featuredBucket).ForEach(func(_, data []byte) error {
+			var entry featured.Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
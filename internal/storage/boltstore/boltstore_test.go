@@ -0,0 +1,23 @@
+package boltstore_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/storage/boltstore"
+)
+
+func TestStore(t *testing.T) {
+	gametest.RunRepositoryTests(t, gametest.RepositoryBackend{
+		Open: func(t *testing.T, dir string) game.Repository {
+			store, err := boltstore.Open(filepath.Join(dir, "games.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+		Persistent: true,
+	})
+}
@@ -0,0 +1,48 @@
+// Package storage opens game repositories from a DSN string.
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"tiktaktoes/internal/apikey"
+	"tiktaktoes/internal/featured"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/storage/boltstore"
+)
+
+// Open creates the game repository described by dsn, "memory" (the
+// default) or "bolt:path/to/db".
+func Open(dsn string) (game.Repository, error) {
+	scheme, rest, _ := strings.Cut(dsn, ":")
+	switch scheme {
+	case "", "memory":
+		return game.NewMemoryRepository(), nil
+	case "bolt":
+		if rest == "" {
+			return nil, fmt.Errorf("store %q: missing database path", dsn)
+		}
+		return boltstore.Open(rest)
+	case "sqlite", "redis":
+		return nil, fmt.Errorf("store %q: %s backend is not available", dsn, scheme)
+	}
+	return nil, fmt.Errorf("store %q: unknown scheme %q", dsn, scheme)
+}
+
+// Keys returns where API keys are kept alongside the games in repo: in
+// the same database if its backend can hold them, in memory otherwise.
+func Keys(repo game.Repository) apikey.Store {
+	if store, ok := repo.(apikey.Store); ok {
+		return store
+	}
+	return apikey.NewMemoryStore()
+}
+
+// Featured returns where the featured games feed is kept alongside the
+// games in repo, like Keys.
+func Featured(repo game.Repository) featured.Store {
+	if store, ok := repo.(featured.Store); ok {
+		return store
+	}
+	return featured.NewMemoryStore()
+}
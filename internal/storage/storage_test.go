@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/game/gametest"
+	"tiktaktoes/internal/storage"
+)
+
+// backend opens repositories from the DSN dsn makes of their directory.
+func backend(dsn func(dir string) string, persistent bool) gametest.RepositoryBackend {
+	return gametest.RepositoryBackend{
+		Open: func(t *testing.T, dir string) game.Repository {
+			repo, err := storage.Open(dsn(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		},
+		Persistent: persistent,
+	}
+}
+
+func TestOpenMemory(t *testing.T) {
+	for _, dsn := range []string{"", "memory"} {
+		t.Run(dsn, func(t *testing.T) {
+			gametest.RunRepositoryTests(t, backend(func(string) string { return dsn }, false))
+		})
+	}
+}
+
+func TestOpenBolt(t *testing.T) {
+	gametest.RunRepositoryTests(t, backend(func(dir string) string {
+		return "bolt:" + filepath.Join(dir, "games.db")
+	}, true))
+}
+
+func TestOpenErrors(t *testing.T) {
+	tests := map[string]string{
+		"bolt:":                      "missing database path",
+		"bolt":                       "missing database path",
+		"sqlite:games.db":            "not available",
+		"redis://localhost:6379":     "not available",
+		"postgres://localhost/games": "unknown scheme",
+		"bolt:" + filepath.Join("no", "such", "dir", "games.db"): "no such file",
+	}
+	for dsn, want := range tests {
+		repo, err := storage.Open(dsn)
+		if err == nil {
+			repo.Close()
+			t.Errorf("Open(%q) succeeded", dsn)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Open(%q) = %v, want an error saying %q", dsn, err, want)
+		}
+	}
+}
+
+func TestOpenBoltTwice(t *testing.T) {
+	dsn := "bolt:" + filepath.Join(t.TempDir(), "games.db")
+	repo, err := storage.Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	// bbolt locks the file, so a second server on it gives up rather
+	// than share it
+	if other, err := storage.Open(dsn); err == nil {
+		other.Close()
+		t.Error("the database was opened twice at once")
+	}
+}
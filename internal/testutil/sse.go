@@ -0,0 +1,211 @@
+package testutil
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Event is a server-sent event as an EventSource sees it. Comments, such
+// as heartbeats, are kept too, though EventSource ignores them.
+type Event struct {
+	Name  string
+	ID    string
+	Data  string
+	Retry time.Duration
+	// Comments are the comment lines before the event, without the
+	// leading colon and space.
+	Comments []string
+}
+
+// SSEReader parses a stream of server-sent events.
+type SSEReader struct {
+	r *bufio.Reader
+}
+
+// NewSSEReader returns a reader of the events in r.
+func NewSSEReader(r io.Reader) *SSEReader {
+	return &SSEReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event. A run of comments ended by a blank line
+// is returned as an event with Comments alone, as heartbeats come. Data
+// lines are joined by newlines, as EventSource joins them.
+func (s *SSEReader) Next() (Event, error) {
+	var ev Event
+	var data []string
+	seen := false
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				err = io.ErrUnexpectedEOF
+			}
+			return ev, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			if !seen {
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			return ev, nil
+		}
+		seen = true
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			ev.Comments = append(ev.Comments, value)
+		case "event":
+			ev.Name = value
+		case "id":
+			ev.ID = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				ev.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// SSE is an open event stream.
+type SSE struct {
+	// Response is the stream's response, whose body the events are read
+	// from.
+	Response *http.Response
+	events   chan sseResult
+}
+
+type sseResult struct {
+	ev  Event
+	err error
+}
+
+// OpenSSE opens the event stream at path, such as "/htmx/sse/<id>",
+// with client, http.DefaultClient if nil, and the headers given as name
+// and value pairs. It is closed when the test ends.
+func (s *Server) OpenSSE(t testing.TB, client *http.Client, path string, headers ...string) *SSE {
+	t.Helper()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		t.Fatalf("GET %s: %s", path, res.Status)
+	}
+	stream := &SSE{Response: res, events: make(chan sseResult)}
+	done := make(chan struct{})
+	go func() {
+		defer close(stream.events)
+		r := NewSSEReader(res.Body)
+		for {
+			ev, err := r.Next()
+			select {
+			case stream.events <- sseResult{ev, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(done)
+		res.Body.Close()
+	})
+	return stream
+}
+
+// Next returns the next event with a name, skipping heartbeats and other
+// comments, failing the test if none comes within Timeout or the stream
+// ends.
+func (s *SSE) Next(t testing.TB) Event {
+	t.Helper()
+	timeout := time.After(Timeout)
+	for {
+		select {
+		case r, ok := <-s.events:
+			if !ok {
+				t.Fatal("the stream ended")
+			}
+			if r.err != nil {
+				t.Fatalf("reading an event: %v", r.err)
+			}
+			if r.ev.Name != "" || r.ev.Data != "" {
+				return r.ev
+			}
+		case <-timeout:
+			t.Fatalf("no event within %v", Timeout)
+		}
+	}
+}
+
+// NextOf returns the next event named name, skipping others.
+func (s *SSE) NextOf(t testing.TB, name string) Event {
+	t.Helper()
+	for {
+		if ev := s.Next(t); ev.Name == name {
+			return ev
+		}
+	}
+}
+
+// None fails the test if an event with a name comes within d.
+func (s *SSE) None(t testing.TB, d time.Duration) {
+	t.Helper()
+	timeout := time.After(d)
+	for {
+		select {
+		case r, ok := <-s.events:
+			if !ok || r.err != nil {
+				return
+			}
+			if r.ev.Name != "" || r.ev.Data != "" {
+				t.Fatalf("got %s event %q, want none", r.ev.Name, r.ev.ID)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// Ended waits for the server to end the stream, failing the test if it
+// is still open after Timeout, and returns the events it sent before
+// ending it, such as a restart.
+func (s *SSE) Ended(t testing.TB) []Event {
+	t.Helper()
+	var last []Event
+	timeout := time.After(Timeout)
+	for {
+		select {
+		case r, ok := <-s.events:
+			if !ok || r.err != nil {
+				return last
+			}
+			if r.ev.Name != "" || r.ev.Data != "" {
+				last = append(last, r.ev)
+			}
+		case <-timeout:
+			t.Fatalf("the stream is still open after %v", Timeout)
+		}
+	}
+}
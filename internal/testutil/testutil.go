@@ -0,0 +1,168 @@
+// Package testutil starts the whole game server for end-to-end tests,
+// wired as cmd/server wires it, and speaks each of its protocols: the
+// JSON API, WebSockets and the server-sent events of the htmx pages.
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/respond"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+)
+
+// Timeout bounds how long a helper waits for the server, so a test
+// expecting a frame or event that never comes fails rather than hangs.
+const Timeout = 5 * time.Second
+
+// Server is a game server started for a test.
+type Server struct {
+	*server.Server
+	// URL is where it is served, such as "http://127.0.0.1:41234".
+	URL string
+
+	ts      *httptest.Server
+	stopped sync.Once
+}
+
+// Start creates a server from cfg with server.New, as cmd/server does,
+// and serves its handler on httptest until the test ends, see Stop.
+func Start(t testing.TB, cfg server.Config) *Server {
+	t.Helper()
+	srv, err := server.New(cfg)
+	if err != nil {
+		t.Fatalf("server.New: %v", err)
+	}
+	s := &Server{Server: srv, ts: httptest.NewServer(srv.Handler())}
+	s.URL = s.ts.URL
+	t.Cleanup(func() { s.Stop(t) })
+	return s
+}
+
+// Stop shuts the server down as on SIGTERM, once, failing the test if it
+// can't. A test may call it to see what clients are told; otherwise it
+// is called when the test ends.
+func (s *Server) Stop(t testing.TB) {
+	t.Helper()
+	s.stopped.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+		defer cancel()
+		// Shutdown ends the event streams and WebSockets still open,
+		// which Close would otherwise wait on
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+		s.ts.Close()
+	})
+}
+
+// APIError is an error answer from the JSON API.
+type APIError struct {
+	Status int
+	respond.ErrorBody
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.ErrorBody.Error)
+}
+
+// Do sends a request to path with body, if not empty, as JSON and the
+// headers given as name and value pairs. A successful answer is decoded
+// into out, if not nil; any other is returned as an *APIError.
+func (s *Server) Do(t testing.TB, method, path, body string, out any, headers ...string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		apiErr := &APIError{Status: res.StatusCode}
+		if err := json.NewDecoder(res.Body).Decode(&apiErr.ErrorBody); err != nil {
+			t.Fatalf("%s %s: %d without an error body: %v", method, path, res.StatusCode, err)
+		}
+		return res, apiErr
+	}
+	if out == nil {
+		io.Copy(io.Discard, res.Body)
+		return res, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return res, nil
+}
+
+// CreateGame creates a game over the JSON API, with the settings in
+// body, a createGameRequest such as `{"mode":"hotseat"}`, or the defaults
+// if it is empty. It fails the test if the game isn't created.
+func (s *Server) CreateGame(t testing.TB, body string) *models.GameState {
+	t.Helper()
+	var g models.GameState
+	if _, err := s.Do(t, http.MethodPost, "/api/game", body, &g); err != nil {
+		t.Fatalf("creating a game: %v", err)
+	}
+	return &g
+}
+
+// JoinAs joins the game as player over the JSON API and returns it and
+// the seat token handed out. It fails the test if player can't join.
+func (s *Server) JoinAs(t testing.TB, gameID string, player models.Player) (*models.GameState, string) {
+	t.Helper()
+	var g models.GameState
+	body := fmt.Sprintf(`{"player":%q}`, player)
+	res, err := s.Do(t, http.MethodPost, "/api/game/"+gameID+"/join", body, &g)
+	if err != nil {
+		t.Fatalf("joining %s as %s: %v", gameID, player, err)
+	}
+	token := res.Header.Get(seat.TokenHeader)
+	if token == "" {
+		t.Fatalf("joining %s as %s: no seat token", gameID, player)
+	}
+	return &g, token
+}
+
+// MoveJSON sends move to the game over the JSON API and returns the game
+// after it, or the *APIError it was refused with.
+func (s *Server) MoveJSON(t testing.TB, gameID string, move models.Move) (*models.GameState, error) {
+	t.Helper()
+	body, err := json.Marshal(move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g models.GameState
+	if _, err := s.Do(t, http.MethodPost, "/api/game/"+gameID, string(body), &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// MustMove is MoveJSON failing the test if the move is refused.
+func (s *Server) MustMove(t testing.TB, gameID string, player models.Player, position int) *models.GameState {
+	t.Helper()
+	g, err := s.MoveJSON(t, gameID, models.Move{Player: player, Position: position})
+	if err != nil {
+		t.Fatalf("%s at %d in %s: %v", player, position, gameID, err)
+	}
+	return g
+}
@@ -0,0 +1,140 @@
+package testutil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// Frame is a frame a WebSocket client got, in the envelope every frame
+// but an error comes in when the client asks for envelopes. An error
+// frame, rejecting a message the client sent, has no Type and sets
+// Error and Code instead.
+type Frame struct {
+	Type   string          `json:"type"`
+	GameID string          `json:"gameId"`
+	Seq    uint64          `json:"seq"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+	Code   string          `json:"code"`
+}
+
+// Game decodes the game in a game-update frame, failing the test if it
+// is some other frame.
+func (f Frame) Game(t testing.TB) *models.GameState {
+	t.Helper()
+	if f.Type != broadcast.GameUpdateEvent {
+		t.Fatalf("got a %s frame, want %s", f.Type, broadcast.GameUpdateEvent)
+	}
+	var g models.GameState
+	if err := json.Unmarshal(f.Data, &g); err != nil {
+		t.Fatalf("decoding a %s frame: %v", f.Type, err)
+	}
+	return &g
+}
+
+// WS is a WebSocket connection to a game.
+type WS struct {
+	Conn *websocket.Conn
+}
+
+// DialWS connects to the game's WebSocket with the query parameters in
+// query, such as "player=X", and envelope=true, sending the headers
+// given as name and value pairs. The connection is closed when the test
+// ends.
+func (s *Server) DialWS(t testing.TB, gameID, query string, headers ...string) *WS {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/ws/" + gameID + "?envelope=true"
+	if query != "" {
+		url += "&" + query
+	}
+	header := http.Header{}
+	for i := 0; i+1 < len(headers); i += 2 {
+		header.Set(headers[i], headers[i+1])
+	}
+	conn, res, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		status := 0
+		if res != nil {
+			status = res.StatusCode
+		}
+		t.Fatalf("dialing %s: %v (status %d)", url, err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &WS{Conn: conn}
+}
+
+// Next returns the next frame, failing the test if none comes within
+// Timeout or the connection is closed.
+func (c *WS) Next(t testing.TB) Frame {
+	t.Helper()
+	f, err := c.read()
+	if err != nil {
+		t.Fatalf("reading a frame: %v", err)
+	}
+	return f
+}
+
+// NextOf returns the next frame of the type given, skipping others.
+func (c *WS) NextOf(t testing.TB, typ string) Frame {
+	t.Helper()
+	for {
+		if f := c.Next(t); f.Type == typ {
+			return f
+		}
+	}
+}
+
+// Connected reads the frames a new connection gets, the game and the
+// welcome, and returns them.
+func (c *WS) Connected(t testing.TB) (*models.GameState, broadcast.Welcome) {
+	t.Helper()
+	g := c.Next(t).Game(t)
+	f := c.Next(t)
+	if f.Type != broadcast.WelcomeFrameType {
+		t.Fatalf("got a %s frame, want %s", f.Type, broadcast.WelcomeFrameType)
+	}
+	var welcome broadcast.Welcome
+	if err := json.Unmarshal(f.Data, &welcome); err != nil {
+		t.Fatal(err)
+	}
+	return g, welcome
+}
+
+// Send sends v as a JSON message.
+func (c *WS) Send(t testing.TB, v any) {
+	t.Helper()
+	if err := c.Conn.WriteJSON(v); err != nil {
+		t.Fatalf("sending %v: %v", v, err)
+	}
+}
+
+// Closed waits for the server to close the connection and returns the
+// close code it gave, failing the test if it sends anything else first.
+func (c *WS) Closed(t testing.TB) int {
+	t.Helper()
+	f, err := c.read()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("got %+v, %v, want the connection closed", f, err)
+	}
+	return closeErr.Code
+}
+
+func (c *WS) read() (Frame, error) {
+	var f Frame
+	c.Conn.SetReadDeadline(time.Now().Add(Timeout))
+	_, data, err := c.Conn.ReadMessage()
+	if err != nil {
+		return f, err
+	}
+	return f, json.Unmarshal(data, &f)
+}
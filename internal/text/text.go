@@ -0,0 +1,217 @@
+// Package text checks what people type before it becomes part of a game
+// or tournament, such as game names and participants' names, so lists
+// others see show nothing invisible, misleading or offensive.
+package text
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Field is a kind of text people type, named in errors about it.
+type Field string
+
+const (
+	// FieldSlug is a game's name, see game.CreateOptions.Slug.
+	FieldSlug Field = "slug"
+	// FieldName is a player's name, such as a tournament participant's.
+	FieldName Field = "name"
+	// FieldCommentary is a line of commentary on a game, which may run to
+	// CommentaryMaxLength characters.
+	FieldCommentary Field = "commentary"
+)
+
+// ErrRejected is matched by every FieldError.
+var ErrRejected = errors.New("text rejected")
+
+// Reasons a FieldError gives.
+const (
+	ReasonInvalid = "isn't valid text"
+	ReasonEmpty   = "is empty"
+	ReasonTooLong = "is too long"
+	ReasonBlocked = "contains a word that isn't allowed"
+)
+
+// FieldError says why a Policy rejected the text typed for a field.
+type FieldError struct {
+	Field  Field
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return string(e.Field) + " " + e.Reason
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrRejected
+}
+
+// Policy decides what people may type. Clean returns the text as it is
+// to be kept for the field, or a *FieldError saying why it can't be.
+// Key returns the form of cleaned text that is compared when it must be
+// unique, so that names which merely look alike collide.
+type Policy interface {
+	Clean(field Field, s string) (string, error)
+	Key(s string) string
+}
+
+// DefaultMaxLength is the most characters Default allows in any field.
+const DefaultMaxLength = 32
+
+// CommentaryMaxLength is the most characters Default allows in a line of
+// commentary, which says more than a name.
+const CommentaryMaxLength = 280
+
+// maxMarks is how many combining marks Default keeps on one character,
+// which is plenty for real scripts but stops text spilling over its
+// neighbours.
+const maxMarks = 2
+
+// Default is the Policy used unless another is configured. Key folds
+// case, accents and Latin lookalikes.
+type Default struct {
+	// MaxLength is the most characters allowed, DefaultMaxLength if zero.
+	// It doesn't apply to commentary, see CommentaryMaxLength.
+	MaxLength int
+	blocked   map[string]bool
+}
+
+// NewDefault returns the default policy, rejecting text with any of the
+// blocked words in it as a whole word.
+func NewDefault(blocked []string) *Default {
+	p := &Default{blocked: make(map[string]bool, len(blocked))}
+	for _, w := range blocked {
+		if w = p.Key(strings.TrimSpace(w)); w != "" {
+			p.blocked[w] = true
+		}
+	}
+	return p
+}
+
+// LoadWords reads a list of blocked words, one per line. Blank lines and
+// lines starting with # are skipped.
+func LoadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, sc.Err()
+}
+
+// Clean implements Policy.
+func (p *Default) Clean(field Field, s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", &FieldError{field, ReasonInvalid}
+	}
+	var b strings.Builder
+	space, marks := false, 0
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co, unicode.Cs):
+			continue
+		case unicode.Is(unicode.M, r):
+			// Marks must sit on a character, and a few are enough
+			if marks++; marks > maxMarks || b.Len() == 0 || space {
+				continue
+			}
+		default:
+			marks = 0
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	clean := b.String()
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if field == FieldCommentary {
+		maxLength = CommentaryMaxLength
+	}
+	switch {
+	case clean == "":
+		return "", &FieldError{field, ReasonEmpty}
+	case utf8.RuneCountInString(clean) > maxLength:
+		return "", &FieldError{field, ReasonTooLong}
+	case p.isBlocked(clean):
+		return "", &FieldError{field, ReasonBlocked}
+	}
+	return clean, nil
+}
+
+// Key implements Policy.
+func (p *Default) Key(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.In(r, unicode.M, unicode.Cc, unicode.Cf) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if latin, ok := lookalikes[r]; ok {
+			r = latin
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isBlocked reports whether s has a blocked word in it.
+func (p *Default) isBlocked(s string) bool {
+	if len(p.blocked) == 0 {
+		return false
+	}
+	key := strings.Map(func(r rune) rune {
+		if letter, ok := standIns[r]; ok {
+			return letter
+		}
+		return r
+	}, p.Key(s))
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if p.blocked[w] {
+			return true
+		}
+	}
+	return p.blocked[strings.Join(words, "")]
+}
+
+// lookalikes maps Cyrillic and Greek lowercase letters to the Latin ones
+// they are easily mistaken for.
+var lookalikes = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i',
+	'ј': 'j', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'һ': 'h', 'ӏ': 'l',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+}
+
+// standIns maps digits and symbols often typed in place of letters to
+// them, for spotting blocked words spelled around the list.
+var standIns = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	'@': 'a', '$': 's', '!': 'i',
+}
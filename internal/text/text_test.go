@@ -0,0 +1,136 @@
+package text_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiktaktoes/internal/text"
+)
+
+func TestClean(t *testing.T) {
+	p := text.NewDefault(nil)
+	tests := []struct {
+		name   string
+		in     string
+		want   string
+		reason string
+	}{
+		{"plain", "Friday Lunch", "Friday Lunch", ""},
+		{"spaces", "  Friday \t\n Lunch  ", "Friday Lunch", ""},
+		{"right-to-left override", "\u202eevil\u202c.exe", "evil.exe", ""},
+		{"left-to-right and isolates", "\u200eab\u2066cd\u2069", "abcd", ""},
+		{"zero-width joiner", "an\u200dna", "anna", ""},
+		{"zero-width space", "an\u200bna", "anna", ""},
+		{"byte order mark", "\ufeffanna", "anna", ""},
+		{"control characters", "an\x00n\x1ba\x7f", "anna", ""},
+		{"private use", "an\ue000na", "anna", ""},
+		{"accents kept", "Zoë Chloé", "Zoë Chloé", ""},
+		{"marks piled up", "z\u0301\u0302\u0303\u0304oe", "z\u0301\u0302oe", ""},
+		{"marks with nothing under them", "\u0301\u0302zoe", "zoe", ""},
+		{"marks after a space", "zo \u0301e", "zo e", ""},
+		{"emoji joined", "👩\u200d👩", "👩👩", ""},
+		{"only invisible", "\u200b\u200d\u202e \u2066", "", text.ReasonEmpty},
+		{"empty", "", "", text.ReasonEmpty},
+		{"invalid utf-8", "an\xffna", "", text.ReasonInvalid},
+		{"at the limit", strings.Repeat("é", text.DefaultMaxLength), strings.Repeat("é", text.DefaultMaxLength), ""},
+		{"too long", strings.Repeat("a", text.DefaultMaxLength+1), "", text.ReasonTooLong},
+		// Stripped characters don't count towards the limit
+		{"padded with joiners", strings.Repeat("a\u200d", text.DefaultMaxLength), strings.Repeat("a", text.DefaultMaxLength), ""},
+	}
+	for _, tt := range tests {
+		got, err := p.Clean(text.FieldName, tt.in)
+		if tt.reason != "" {
+			var fe *text.FieldError
+			if !errors.As(err, &fe) || fe.Field != text.FieldName || fe.Reason != tt.reason || !errors.Is(err, text.ErrRejected) {
+				t.Errorf("%s: Clean(%q) = %q, %v, want %q rejected", tt.name, tt.in, got, err, tt.reason)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Clean(%q) = %q, %v, want %q", tt.name, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestCleanLength(t *testing.T) {
+	p := &text.Default{MaxLength: 5}
+	if _, err := p.Clean(text.FieldSlug, "abcdef"); err == nil || err.Error() != "slug is too long" {
+		t.Errorf("six characters with MaxLength 5: err = %v", err)
+	}
+	line := strings.Repeat("a", text.CommentaryMaxLength)
+	if got, err := p.Clean(text.FieldCommentary, line); err != nil || got != line {
+		t.Errorf("commentary of %d characters: err = %v", len(line), err)
+	}
+	if _, err := p.Clean(text.FieldCommentary, line+"a"); err == nil {
+		t.Error("commentary over CommentaryMaxLength was kept")
+	}
+}
+
+// TestKey checks names that only look alike, once cleaned, share a key,
+// and so can't both be taken where names must be unique.
+func TestKey(t *testing.T) {
+	p := text.NewDefault(nil)
+	same := []struct {
+		name string
+		a, b string
+	}{
+		{"case", "Anna", "ANNA"},
+		{"zero-width joiner", "anna", "an\u200dna"},
+		{"right-to-left override", "anna", "\u202eanna"},
+		{"cyrillic", "paco", "расо"},
+		{"greek", "kato", "κατο"},
+		{"fullwidth", "anna", "ａｎｎａ"},
+		{"accents", "zoe", "zoë"},
+		{"decomposed accents", "zoë", "zoe\u0308"},
+		{"ligature", "fine", "ﬁne"},
+	}
+	for _, tt := range same {
+		a, errA := p.Clean(text.FieldName, tt.a)
+		b, errB := p.Clean(text.FieldName, tt.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("%s: cleaning: %v, %v", tt.name, errA, errB)
+		}
+		if p.Key(a) != p.Key(b) {
+			t.Errorf("%s: %q and %q have keys %q and %q", tt.name, tt.a, tt.b, p.Key(a), p.Key(b))
+		}
+	}
+	for _, pair := range [][2]string{{"anna", "anne"}, {"ann a", "anna"}, {"名前", "名刺"}} {
+		if p.Key(pair[0]) == p.Key(pair[1]) {
+			t.Errorf("%q and %q share the key %q", pair[0], pair[1], p.Key(pair[0]))
+		}
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	p := text.NewDefault([]string{"badword", " Rude ", ""})
+	for _, s := range []string{
+		"badword", "BadWord", "b4dw0rd", "b@dword", "the rude one", "rude", "r u d e", "b-a-d-w-o-r-d",
+		"b\u0430dword", "ｂａｄｗｏｒｄ", "bad\u200dword", "\u202ebadword",
+	} {
+		if _, err := p.Clean(text.FieldName, s); err == nil || err.Error() != "name "+text.ReasonBlocked {
+			t.Errorf("Clean(%q) err = %v, want it blocked", s, err)
+		}
+	}
+	for _, s := range []string{"rudeness", "prudence", "badwords", "not bad word play"} {
+		if _, err := p.Clean(text.FieldName, s); err != nil {
+			t.Errorf("Clean(%q) err = %v, want it kept", s, err)
+		}
+	}
+}
+
+func TestLoadWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# blocked\nbadword\n\n  rude  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	words, err := text.LoadWords(path)
+	if err != nil || strings.Join(words, ",") != "badword,rude" {
+		t.Errorf("LoadWords = %q, %v", words, err)
+	}
+	if _, err := text.LoadWords(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadWords of a missing file didn't fail")
+	}
+}
@@ -0,0 +1,169 @@
+package tournament
+
+import "context"
+
+// The brackets of a double-elimination tournament, in the order of
+// Tournament.Brackets. A single-elimination tournament has the one
+// bracket, at winnersBracket.
+const (
+	winnersBracket = iota
+	losersBracket
+	finalBracket
+)
+
+// seed lays out the brackets of an elimination tournament and fills the
+// first round. Must be called with the lock held.
+func (s *Service) seed(ctx context.Context, t *Tournament) error {
+	names := t.Participants
+	size := 2
+	for size < len(names) {
+		size *= 2
+	}
+	if t.Format == SingleElimination {
+		t.Brackets = []*Bracket{{Name: BracketMain, Rounds: knockout(size / 2)}}
+	} else {
+		// The losers bracket takes the losers of each round of the
+		// winners bracket in turn: those of the first play each other,
+		// and those of every later round play the survivors, who then
+		// play each other again to halve their number
+		losers := &Bracket{Name: BracketLosers, Rounds: [][]*Match{}}
+		for n := size / 4; n >= 1; n /= 2 {
+			losers.Rounds = append(losers.Rounds, newRound(n), newRound(n))
+		}
+		t.Brackets = []*Bracket{
+			{Name: BracketWinners, Rounds: knockout(size / 2)},
+			losers,
+			{Name: BracketFinal, Rounds: [][]*Match{newRound(1)}},
+		}
+	}
+
+	// Seed i meets seed size-1-i; missing seeds are byes
+	for i := range size / 2 {
+		opponent := ""
+		if j := size - 1 - i; j < len(names) {
+			opponent = names[j]
+		}
+		if err := s.fill(ctx, t, spot{match: i}, names[i]); err != nil {
+			return err
+		}
+		if err := s.fill(ctx, t, spot{match: i, slot: 1}, opponent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// knockout returns the rounds of a bracket of n matches, halving to the
+// one deciding it.
+func knockout(n int) [][]*Match {
+	var rounds [][]*Match
+	for ; n >= 1; n /= 2 {
+		rounds = append(rounds, newRound(n))
+	}
+	return rounds
+}
+
+// newRound returns a round of n matches waiting on their players.
+func newRound(n int) []*Match {
+	round := make([]*Match, n)
+	for i := range round {
+		round[i] = &Match{}
+	}
+	return round
+}
+
+// fill settles a player slot with name, or with nobody when it is
+// empty. Once both of a match's slots are settled it is started, or goes
+// by as a bye if it has fewer than two players.
+// Must be called with the lock held.
+func (s *Service) fill(ctx context.Context, t *Tournament, at spot, name string) error {
+	m := t.match(at)
+	m.Players[at.slot] = name
+	m.filled[at.slot] = true
+	switch {
+	case !m.filled[0] || !m.filled[1]:
+		return nil
+	case m.Players[0] != "" && m.Players[1] != "":
+		return s.start(ctx, t, m)
+	}
+	m.Bye = true
+	m.Winner = m.Players[0]
+	if m.Winner == "" {
+		m.Winner = m.Players[1]
+	}
+	at.slot = 0
+	return s.advance(ctx, t, at, "")
+}
+
+// advance moves the winner of the match at the given spot on, or makes
+// them champion, and in a double-elimination tournament drops the loser
+// into the losers bracket. A loser of the losers bracket is out.
+// Must be called with the lock held.
+func (s *Service) advance(ctx context.Context, t *Tournament, at spot, loser string) error {
+	m := t.match(at)
+	if at.bracket == finalBracket && at.round == 0 && m.Winner == m.Players[1] {
+		// The winner of the winners bracket has lost for the first
+		// time, so the two play again to decide it
+		final := t.Brackets[finalBracket]
+		final.Rounds = append(final.Rounds, newRound(1))
+		reset := spot{bracket: finalBracket, round: 1}
+		if err := s.fill(ctx, t, reset, m.Players[0]); err != nil {
+			return err
+		}
+		reset.slot = 1
+		return s.fill(ctx, t, reset, m.Players[1])
+	}
+
+	to, ok := t.winnerTo(at)
+	if !ok {
+		t.Champion = m.Winner
+		return nil
+	}
+	if err := s.fill(ctx, t, to, m.Winner); err != nil {
+		return err
+	}
+	if to, ok := t.loserTo(at); ok {
+		return s.fill(ctx, t, to, loser)
+	}
+	return nil
+}
+
+// winnerTo returns the slot the winner of the match at the given spot
+// goes into, and false for a match deciding the tournament.
+func (t *Tournament) winnerTo(at spot) (spot, bool) {
+	rounds := t.Brackets[at.bracket].Rounds
+	switch {
+	case at.bracket == finalBracket:
+		return spot{}, false
+	case at.bracket == losersBracket && at.round+1 == len(rounds):
+		return spot{bracket: finalBracket, slot: 1}, true
+	case at.bracket == losersBracket && at.round%2 == 0:
+		// Meets a loser of the winners bracket next
+		return spot{bracket: losersBracket, round: at.round + 1, match: at.match}, true
+	case at.round+1 < len(rounds):
+		return spot{bracket: at.bracket, round: at.round + 1, match: at.match / 2, slot: at.match % 2}, true
+	case t.Format == DoubleElimination:
+		return spot{bracket: finalBracket}, true
+	}
+	return spot{}, false
+}
+
+// loserTo returns the slot the loser of the match at the given spot
+// drops into, and false when they are out.
+func (t *Tournament) loserTo(at spot) (spot, bool) {
+	if t.Format != DoubleElimination || at.bracket != winnersBracket {
+		return spot{}, false
+	}
+	losers := t.Brackets[losersBracket].Rounds
+	switch {
+	case len(losers) == 0:
+		// With two participants the loser goes straight to the final
+		return spot{bracket: finalBracket, slot: 1}, true
+	case at.round == 0:
+		return spot{bracket: losersBracket, match: at.match / 2, slot: at.match % 2}, true
+	}
+	// The later losers come in the other way up, so that they don't meet
+	// the survivors of the same half of the bracket straight away
+	r := 2*at.round - 1
+	return spot{bracket: losersBracket, round: r, match: len(losers[r]) - 1 - at.match, slot: 1}, true
+}
@@ -0,0 +1,160 @@
+package tournament
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// Points scored for a round-robin match
+const (
+	winPoints  = 2
+	drawPoints = 1
+)
+
+// Standing is a participant's record in a round robin.
+type Standing struct {
+	Participant string `json:"participant"`
+	Played      int    `json:"played"`
+	Won         int    `json:"won"`
+	Drawn       int    `json:"drawn"`
+	Lost        int    `json:"lost"`
+	Points      int    `json:"points"`
+	// WinMoves counts the moves the participant made in the games they
+	// won.
+	WinMoves int `json:"winMoves"`
+}
+
+// schedule pairs every participant with every other once, a round at a
+// time, by the circle method, with a bye for an odd number.
+func schedule(names []string) [][]*Match {
+	seeds := make([]int, len(names))
+	for i := range seeds {
+		seeds[i] = i
+	}
+	if len(seeds)%2 == 1 {
+		// Whoever meets -1 sits the round out
+		seeds = append([]int{-1}, seeds...)
+	}
+	n := len(seeds)
+	rounds := make([][]*Match, n-1)
+	for r := range rounds {
+		for i := range n / 2 {
+			x, o := seeds[i], seeds[n-1-i]
+			if x < 0 || o < 0 {
+				continue
+			}
+			if (i == 0 && r%2 == 1) || (i > 0 && i%2 == 1) {
+				x, o = o, x
+			}
+			rounds[r] = append(rounds[r], &Match{Players: [2]string{names[x], names[o]}, filled: [2]bool{true, true}})
+		}
+		seeds = append([]int{seeds[0], seeds[n-1]}, seeds[1:n-1]...)
+	}
+	return rounds
+}
+
+// startRound starts the games of a round-robin round.
+// Must be called with the lock held.
+func (s *Service) startRound(ctx context.Context, t *Tournament, r int) error {
+	for _, m := range t.Brackets[0].Rounds[r] {
+		if err := s.start(ctx, t, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordResult updates the standings of a round robin for a result in
+// round r, and once the round is over starts the next, or crowns the
+// leader after the last. Must be called with the lock held.
+func (s *Service) recordResult(ctx context.Context, t *Tournament, r int) {
+	t.Standings = standings(t)
+	rounds := t.Brackets[0].Rounds
+	for _, m := range rounds[r] {
+		if !m.decided() {
+			return
+		}
+	}
+	if r+1 == len(rounds) {
+		t.Champion = t.Standings[0].Participant
+		return
+	}
+	if err := s.startRound(ctx, t, r+1); err != nil {
+		slog.Error("starting tournament round failed", "tournament_id", t.ID, "round", r+1, "error", err)
+	}
+}
+
+// standings ranks a round robin's participants by points, then head to
+// head, then fewest moves to win, then seed.
+func standings(t *Tournament) []Standing {
+	seeds := make(map[string]int, len(t.Participants))
+	table := make([]Standing, len(t.Participants))
+	for i, name := range t.Participants {
+		seeds[name] = i
+		table[i].Participant = name
+	}
+
+	var played []*Match
+	for _, round := range t.Brackets[0].Rounds {
+		for _, m := range round {
+			if !m.decided() {
+				continue
+			}
+			played = append(played, m)
+			for slot, name := range m.Players {
+				st := &table[seeds[name]]
+				st.Played++
+				switch m.Winner {
+				case "":
+					st.Drawn++
+					st.Points += drawPoints
+				case name:
+					st.Won++
+					st.Points += winPoints
+					st.WinMoves += m.Moves[slot]
+				default:
+					st.Lost++
+				}
+			}
+		}
+	}
+
+	// Head to head: the points scored in matches between participants
+	// who are level on points
+	headToHead := make([]int, len(table))
+	for _, m := range played {
+		x, o := seeds[m.Players[0]], seeds[m.Players[1]]
+		if table[x].Points != table[o].Points {
+			continue
+		}
+		switch m.Winner {
+		case "":
+			headToHead[x] += drawPoints
+			headToHead[o] += drawPoints
+		case m.Players[0]:
+			headToHead[x] += winPoints
+		default:
+			headToHead[o] += winPoints
+		}
+	}
+
+	order := make([]int, len(table))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Or(
+			cmp.Compare(table[b].Points, table[a].Points),
+			cmp.Compare(headToHead[b], headToHead[a]),
+			cmp.Compare(table[a].WinMoves, table[b].WinMoves),
+			cmp.Compare(a, b),
+		)
+	})
+	ranked := make([]Standing, len(order))
+	for i, seed := range order {
+		ranked[i] = table[seed]
+	}
+	return ranked
+}
@@ -0,0 +1,120 @@
+package tournament
+
+import (
+	"slices"
+	"testing"
+)
+
+// played returns a round robin of participants in which matches have
+// been played, as one round.
+func played(participants []string, matches ...*Match) *Tournament {
+	return &Tournament{
+		Format:       RoundRobin,
+		Participants: participants,
+		Brackets:     []*Bracket{{Name: BracketSchedule, Rounds: [][]*Match{matches}}},
+	}
+}
+
+// won is a match x won against o, making the moves given.
+func won(x, o string, moves int) *Match {
+	return &Match{Players: [2]string{x, o}, Winner: x, Moves: [2]int{moves, moves - 1}}
+}
+
+// drawn is a drawn match between x and o.
+func drawn(x, o string) *Match {
+	return &Match{Players: [2]string{x, o}, Draw: true, Moves: [2]int{5, 4}}
+}
+
+func TestStandings(t *testing.T) {
+	abcd := []string{"A", "B", "C", "D"}
+	tests := []struct {
+		name string
+		t    *Tournament
+		want []string
+	}{
+		{"by points", played(abcd, won("C", "A", 3), won("C", "B", 3), won("B", "A", 3), drawn("D", "B")), []string{"C", "B", "D", "A"}},
+		// B won in fewer moves and is the higher seed, but lost to C
+		{"head to head", played(abcd, won("C", "B", 5), won("B", "A", 3), won("B", "D", 3), won("C", "D", 5), won("A", "C", 5)), []string{"C", "B", "A", "D"}},
+		// B, C and D beat each other in a circle, so are level head to
+		// head as well
+		{"head to head in a circle", played(abcd, won("B", "C", 4), won("C", "D", 3), won("D", "B", 5), won("A", "B", 3), won("A", "C", 3), won("A", "D", 3)), []string{"A", "C", "B", "D"}},
+		{"fewest moves", played([]string{"A", "B", "C"}, drawn("B", "C"), won("B", "A", 4), won("C", "A", 3)), []string{"C", "B", "A"}},
+		{"by seed", played(abcd, drawn("D", "C"), drawn("B", "A")), []string{"A", "B", "C", "D"}},
+		{"nothing played", played(abcd), []string{"A", "B", "C", "D"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := standings(tt.t)
+			var order []string
+			for _, st := range got {
+				order = append(order, st.Participant)
+			}
+			if !slices.Equal(order, tt.want) {
+				t.Errorf("ranked %v, want %v: %+v", order, tt.want, got)
+			}
+
+			// The same results in another order rank the same way
+			matches := tt.t.Brackets[0].Rounds[0]
+			slices.Reverse(matches)
+			if again := standings(tt.t); !slices.Equal(again, got) {
+				t.Errorf("with the matches the other way round: %+v", again)
+			}
+		})
+	}
+}
+
+func TestStandingsRecord(t *testing.T) {
+	got := standings(played([]string{"A", "B", "C"}, won("A", "B", 3), drawn("A", "C"), won("C", "B", 4)))
+	want := []Standing{
+		{Participant: "A", Played: 2, Won: 1, Drawn: 1, Points: 3, WinMoves: 3},
+		{Participant: "C", Played: 2, Won: 1, Drawn: 1, Points: 3, WinMoves: 4},
+		{Participant: "B", Played: 2, Lost: 2},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("standings\n%+v, want\n%+v", got, want)
+	}
+}
+
+// TestSchedule checks every participant meets every other exactly once,
+// at most once a round, and plays X about as often as O.
+func TestSchedule(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		names := make([]string, n)
+		for i := range names {
+			names[i] = string(rune('A' + i))
+		}
+		rounds := schedule(names)
+		met := make(map[[2]string]int)
+		asX := make(map[string]int)
+		for r, round := range rounds {
+			playing := make(map[string]bool)
+			for _, m := range round {
+				for _, p := range m.Players {
+					if playing[p] {
+						t.Errorf("%d participants: %s plays twice in round %d", n, p, r)
+					}
+					playing[p] = true
+				}
+				pair := m.Players
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				met[pair]++
+				asX[m.Players[0]]++
+			}
+		}
+		if len(met) != n*(n-1)/2 {
+			t.Errorf("%d participants: %d pairings, want %d", n, len(met), n*(n-1)/2)
+		}
+		for pair, times := range met {
+			if times != 1 {
+				t.Errorf("%d participants: %v meet %d times", n, pair, times)
+			}
+		}
+		for _, name := range names {
+			if x := asX[name]; x*2 < n-2 || x*2 > n {
+				t.Errorf("%d participants: %s plays X %d times of %d", n, name, x, n-1)
+			}
+		}
+	}
+}
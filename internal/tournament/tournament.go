@@ -0,0 +1,386 @@
+// Package tournament runs tournaments on top of the game service, in one
+// of three formats: single elimination, double elimination, in which a
+// participant is out after losing twice, and round robin, in which
+// everyone plays everyone.
+package tournament
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/text"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxParticipants bounds the size of a tournament
+	MaxParticipants = 64
+	// maxNameLength bounds a participant's name in bytes
+	maxNameLength = 32
+	// replayDelay is how long a drawn board stays up before the game is
+	// reset. It also lets the draw reach players before the reset does.
+	replayDelay = 2 * time.Second
+)
+
+var (
+	ErrNotFound            = errors.New("tournament not found")
+	ErrTooFewParticipants  = errors.New("a tournament needs at least two participants")
+	ErrTooManyParticipants = errors.New("too many participants")
+	ErrInvalidName         = errors.New("participant names must be 1-32 characters")
+	ErrDuplicateName       = errors.New("participant names must be unique")
+	ErrInvalidFormat       = errors.New("tournament format must be single-elimination, double-elimination or round-robin")
+)
+
+// Format is how a tournament pairs its participants.
+type Format string
+
+const (
+	// SingleElimination knocks the loser of every match out.
+	SingleElimination Format = "single-elimination"
+	// DoubleElimination knocks a participant out on their second loss:
+	// the losers of the winners bracket drop into a losers bracket, and
+	// the winners of the two meet in a grand final.
+	DoubleElimination Format = "double-elimination"
+	// RoundRobin has everyone play everyone once, and ranks them by the
+	// points they score, see Standing.
+	RoundRobin Format = "round-robin"
+)
+
+// Match is one pairing in the tournament. Players[0] plays X and
+// Players[1] plays O. An empty player slot is still waiting on an
+// earlier match.
+type Match struct {
+	Players [2]string `json:"players"`
+	GameID  string    `json:"gameId,omitempty"`
+	Winner  string    `json:"winner,omitempty"`
+	// Draw is set when a round-robin match was drawn. Elimination
+	// matches are played again until somebody wins.
+	Draw bool `json:"draw,omitempty"`
+	// Bye is set when the match has only one participant, who advances
+	// without playing, or none.
+	Bye bool `json:"bye,omitempty"`
+	// Replays counts drawn games that had to be played again.
+	Replays int `json:"replays,omitempty"`
+	// Moves counts the moves each player made in the match's game once
+	// it is over.
+	Moves [2]int `json:"moves,omitzero"`
+
+	// filled marks the player slots that are settled, with a player or
+	// with nobody, as when the match feeding one was a bye
+	filled [2]bool
+}
+
+// decided reports whether the match has been played, or needn't be.
+func (m *Match) decided() bool {
+	return m.Winner != "" || m.Draw || m.Bye
+}
+
+// Bracket is a part of a tournament: its matches in rounds, each round
+// playing on from the ones before it.
+type Bracket struct {
+	// Name is BracketMain for the one bracket of a single-elimination
+	// tournament and BracketSchedule for a round robin's rounds; a
+	// double-elimination tournament has BracketWinners, BracketLosers
+	// and BracketFinal.
+	Name   string     `json:"name"`
+	Rounds [][]*Match `json:"rounds"`
+}
+
+// The names of the brackets, see Bracket.
+const (
+	BracketMain     = "main"
+	BracketWinners  = "winners"
+	BracketLosers   = "losers"
+	BracketFinal    = "final"
+	BracketSchedule = "schedule"
+)
+
+// Tournament is a tournament in any format. Every format is laid out as
+// brackets of rounds of matches, and a round robin also has Standings,
+// so that clients can show any of them the same way.
+type Tournament struct {
+	ID           string     `json:"id"`
+	Format       Format     `json:"format"`
+	Participants []string   `json:"participants"`
+	Brackets     []*Bracket `json:"brackets"`
+	// Standings ranks the participants of a round robin, first to last,
+	// as the results so far have them.
+	Standings []Standing `json:"standings,omitempty"`
+	Champion  string     `json:"champion,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// clone returns a deep copy of the tournament
+func (t *Tournament) clone() *Tournament {
+	c := *t
+	c.Participants = append([]string{}, t.Participants...)
+	c.Standings = append([]Standing(nil), t.Standings...)
+	c.Brackets = make([]*Bracket, len(t.Brackets))
+	for b, bracket := range t.Brackets {
+		bc := &Bracket{Name: bracket.Name, Rounds: make([][]*Match, len(bracket.Rounds))}
+		for r, round := range bracket.Rounds {
+			bc.Rounds[r] = make([]*Match, len(round))
+			for i, m := range round {
+				mc := *m
+				bc.Rounds[r][i] = &mc
+			}
+		}
+		c.Brackets[b] = bc
+	}
+	return &c
+}
+
+// spot is a player slot of a match: the match's bracket, its round in
+// the bracket and its place in the round, and which of its players.
+type spot struct {
+	bracket, round, match, slot int
+}
+
+// match returns the match at s.
+func (t *Tournament) match(s spot) *Match {
+	return t.Brackets[s.bracket].Rounds[s.round][s.match]
+}
+
+// Service creates tournaments and advances them as their games finish.
+// Tournaments are kept in memory only.
+type Service struct {
+	games *game.Service
+	hub   *broadcast.Hub
+
+	mu          sync.Mutex
+	tournaments map[string]*Tournament
+	// matches maps a running match's game ID to its tournament
+	matches     map[string]string
+	subscribers map[string]map[chan struct{}]bool
+}
+
+// NewService creates a tournament service. Match results are picked up
+// through the game service's completion hook.
+func NewService(games *game.Service, hub *broadcast.Hub) *Service {
+	s := &Service{
+		games:       games,
+		hub:         hub,
+		tournaments: make(map[string]*Tournament),
+		matches:     make(map[string]string),
+		subscribers: make(map[string]map[chan struct{}]bool),
+	}
+	games.OnGameFinished(s.gameFinished)
+	return s
+}
+
+// Create lays out a tournament of the participants, in seeding order,
+// and starts its first matches. The format defaults to single
+// elimination. In an elimination bracket, when the number of
+// participants isn't a power of two, the top seeds get byes.
+func (s *Service) Create(ctx context.Context, participants []string, format Format) (*Tournament, error) {
+	if format == "" {
+		format = SingleElimination
+	}
+	if format != SingleElimination && format != DoubleElimination && format != RoundRobin {
+		return nil, ErrInvalidFormat
+	}
+	names, err := validateNames(s.games.TextPolicy(), participants)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tournament{
+		ID:           uuid.NewString()[:8],
+		Format:       format,
+		Participants: names,
+		CreatedAt:    time.Now(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tournaments[t.ID] = t
+
+	if format == RoundRobin {
+		t.Brackets = []*Bracket{{Name: BracketSchedule, Rounds: schedule(names)}}
+		t.Standings = standings(t)
+		err = s.startRound(ctx, t, 0)
+	} else {
+		err = s.seed(ctx, t)
+	}
+	if err != nil {
+		delete(s.tournaments, t.ID)
+		return nil, err
+	}
+	return t.clone(), nil
+}
+
+// validateNames cleans the participants' names with the text policy and
+// checks them. Names the policy takes for the same, such as ones that
+// differ only in case or in letters that look alike, are duplicates.
+func validateNames(policy text.Policy, participants []string) ([]string, error) {
+	if len(participants) < 2 {
+		return nil, ErrTooFewParticipants
+	}
+	if len(participants) > MaxParticipants {
+		return nil, ErrTooManyParticipants
+	}
+	names := make([]string, len(participants))
+	seen := make(map[string]bool, len(participants))
+	for i, p := range participants {
+		name, err := policy.Clean(text.FieldName, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(name) > maxNameLength {
+			return nil, ErrInvalidName
+		}
+		key := policy.Key(name)
+		if seen[key] {
+			return nil, ErrDuplicateName
+		}
+		seen[key] = true
+		names[i] = name
+	}
+	return names, nil
+}
+
+// Get returns a copy of the tournament
+func (s *Service) Get(id string) (*Tournament, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tournaments[id]
+	if !ok {
+		return nil, false
+	}
+	return t.clone(), true
+}
+
+// Subscribe returns a channel that receives a value whenever the
+// tournament changes, and a function to stop the subscription.
+func (s *Service) Subscribe(id string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers[id] == nil {
+		s.subscribers[id] = make(map[chan struct{}]bool)
+	}
+	s.subscribers[id][ch] = true
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers[id], ch)
+		if len(s.subscribers[id]) == 0 {
+			delete(s.subscribers, id)
+		}
+	}
+}
+
+// notify wakes the tournament's subscribers. A subscriber that hasn't
+// caught up with the previous change already has one pending, so
+// nothing is lost by skipping it. Must be called with the lock held.
+func (s *Service) notify(id string) {
+	for ch := range s.subscribers[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// start creates the match's game. Must be called with the lock held.
+func (s *Service) start(ctx context.Context, t *Tournament, m *Match) error {
+	g, err := s.games.CreateGame(ctx, models.Empty, game.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	m.GameID = g.ID
+	s.matches[g.ID] = t.ID
+	return nil
+}
+
+// gameFinished records the result of a match's game. A drawn
+// elimination match is reset to be played again; otherwise the result
+// stands and the tournament moves on.
+func (s *Service) gameFinished(g models.GameState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.matches[g.ID]
+	if !ok {
+		return
+	}
+	t := s.tournaments[id]
+	at, ok := t.find(g.ID)
+	if !ok {
+		return
+	}
+	m := t.match(at)
+	if g.IsDraw && t.Format != RoundRobin {
+		s.games.Go(g.ID, func(ctx context.Context) {
+			select {
+			case <-time.After(replayDelay):
+				s.replay(id, m)
+			case <-ctx.Done():
+			}
+		})
+		return
+	}
+
+	delete(s.matches, g.ID)
+	for _, move := range g.History {
+		switch {
+		case move.Setup:
+		case move.Player == models.PlayerX:
+			m.Moves[0]++
+		default:
+			m.Moves[1]++
+		}
+	}
+	winner := 1
+	switch {
+	case g.IsDraw:
+		m.Draw = true
+	case g.Winner == models.PlayerX:
+		winner = 0
+		m.Winner = m.Players[0]
+	default:
+		m.Winner = m.Players[1]
+	}
+	if t.Format == RoundRobin {
+		s.recordResult(context.Background(), t, at.round)
+	} else if err := s.advance(context.Background(), t, at, m.Players[1-winner]); err != nil {
+		slog.Error("starting tournament match failed", "tournament_id", t.ID, "error", err)
+	}
+	s.notify(id)
+}
+
+// find returns where the match playing the game is.
+func (t *Tournament) find(gameID string) (spot, bool) {
+	for b, bracket := range t.Brackets {
+		for r, round := range bracket.Rounds {
+			for i, m := range round {
+				if m.GameID == gameID {
+					return spot{bracket: b, round: r, match: i}, true
+				}
+			}
+		}
+	}
+	return spot{}, false
+}
+
+// replay resets a drawn match's game so it can be played again.
+func (s *Service) replay(id string, m *Match) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Nobody reset it, so there is no undoing the replay
+	reset, err := s.games.ResetGame(context.Background(), m.GameID, models.Empty)
+	if err != nil {
+		slog.Error("replaying drawn tournament game failed", "tournament_id", id, "game_id", m.GameID, "error", err)
+		return
+	}
+	m.Replays++
+	s.notify(id)
+	s.hub.Broadcast(context.Background(), m.GameID, reset)
+}
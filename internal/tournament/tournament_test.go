@@ -0,0 +1,216 @@
+package tournament_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/text"
+	"tiktaktoes/internal/tournament"
+)
+
+// TestParticipantNames creates tournaments whose participants' names
+// differ only in ways the text policy folds away, and checks they are
+// taken for the same name.
+func TestParticipantNames(t *testing.T) {
+	games := game.NewService(game.WithTextPolicy(text.NewDefault([]string{"rude"})))
+	defer games.Close()
+	s := tournament.NewService(games, broadcast.NewHub())
+
+	tests := []struct {
+		names []string
+		want  []string
+		err   error
+	}{
+		{[]string{" Ann \u200d", "\u202eBob"}, []string{"Ann", "Bob"}, nil},
+		{[]string{"Zoë", "Zoe\u0308"}, nil, tournament.ErrDuplicateName},
+		{[]string{"anna", "an\u200dna"}, nil, tournament.ErrDuplicateName},
+		{[]string{"paco", "расо"}, nil, tournament.ErrDuplicateName},
+		{[]string{"Bob", "ＢＯＢ"}, nil, tournament.ErrDuplicateName},
+		{[]string{"Ann", "\u200b\u200d"}, nil, text.ErrRejected},
+		{[]string{"Ann", "r\u200dude"}, nil, text.ErrRejected},
+	}
+	for _, tt := range tests {
+		tr, err := s.Create(context.Background(), tt.names, tournament.RoundRobin)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Create(%q) err = %v, want %v", tt.names, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(tr.Participants, tt.want) {
+			t.Errorf("Create(%q) = %v, %v, want participants %q", tt.names, tr, err, tt.want)
+		}
+	}
+}
+
+// Moves that finish a game, X first
+var (
+	xWins = []int{0, 3, 1, 4, 2}
+	oWins = []int{0, 3, 1, 4, 8, 5}
+	draw  = []int{0, 4, 8, 1, 7, 6, 2, 5, 3}
+)
+
+// play seats both sides of a match's game and makes the moves given.
+func play(t *testing.T, games *game.Service, gameID string, moves []int) {
+	t.Helper()
+	ctx := context.Background()
+	for _, p := range []models.Player{models.PlayerX, models.PlayerO} {
+		if _, err := games.JoinGame(ctx, gameID, p, game.JoinOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, pos := range moves {
+		p := models.PlayerX
+		if i%2 == 1 {
+			p = models.PlayerO
+		}
+		if _, err := games.MakeMove(ctx, gameID, models.Move{Player: p, Position: pos}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// next waits for the tournament to have a match ready to play, and
+// returns it, or nil once the tournament has a champion.
+func next(t *testing.T, s *tournament.Service, id string, done map[string]bool) *tournament.Match {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		tr, _ := s.Get(id)
+		if tr.Champion != "" {
+			return nil
+		}
+		for _, b := range tr.Brackets {
+			for _, round := range b.Rounds {
+				for _, m := range round {
+					if m.GameID != "" && !m.Bye && !done[m.GameID] {
+						done[m.GameID] = true
+						return m
+					}
+				}
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no match started within a second")
+	return nil
+}
+
+// run plays a tournament of participants in format through, deciding
+// each match's game with result, and returns it once it has a champion.
+func run(t *testing.T, participants []string, format tournament.Format, result func(m *tournament.Match) []int) *tournament.Tournament {
+	t.Helper()
+	games := game.NewService()
+	t.Cleanup(func() { games.Close() })
+	hub := broadcast.NewHub()
+	t.Cleanup(hub.Close)
+	s := tournament.NewService(games, hub)
+	tr, err := s.Create(context.Background(), participants, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(map[string]bool)
+	for m := next(t, s, tr.ID, done); m != nil; m = next(t, s, tr.ID, done) {
+		play(t, games, m.GameID, result(m))
+	}
+	tr, _ = s.Get(tr.ID)
+	return tr
+}
+
+// TestRoundRobin plays a round robin with a draw, and checks it scores
+// a point each and the leader is crowned once everyone has played.
+func TestRoundRobin(t *testing.T) {
+	// a draws with b and beats c, who beats b
+	winners := map[[2]string]string{{"a", "b"}: "", {"a", "c"}: "a", {"b", "c"}: "c"}
+	tr := run(t, []string{"a", "b", "c"}, tournament.RoundRobin, func(m *tournament.Match) []int {
+		pair := m.Players
+		slices.Sort(pair[:])
+		switch winners[pair] {
+		case "":
+			return draw
+		case m.Players[0]:
+			return xWins
+		}
+		return oWins
+	})
+	var order []string
+	var points []int
+	for _, st := range tr.Standings {
+		order, points = append(order, st.Participant), append(points, st.Points)
+	}
+	if !slices.Equal(order, []string{"a", "c", "b"}) || !slices.Equal(points, []int{3, 2, 1}) || tr.Champion != "a" {
+		t.Errorf("standings %v on %v points, champion %q", order, points, tr.Champion)
+	}
+	if rounds := tr.Brackets[0].Rounds; tr.Brackets[0].Name != tournament.BracketSchedule || len(rounds) != 3 {
+		t.Errorf("brackets %+v, want a schedule of 3 rounds", tr.Brackets[0])
+	}
+}
+
+// TestGrandFinalReset has the losers bracket's player win the grand
+// final, and checks it is played again.
+func TestGrandFinalReset(t *testing.T) {
+	matches := 0
+	tr := run(t, []string{"a", "b"}, tournament.DoubleElimination, func(m *tournament.Match) []int {
+		matches++
+		// b wins the winners bracket, then a wins the final twice
+		winner := "a"
+		if matches == 1 {
+			winner = "b"
+		}
+		if m.Players[0] == winner {
+			return xWins
+		}
+		return oWins
+	})
+	final := tr.Brackets[2]
+	if final.Name != tournament.BracketFinal || len(final.Rounds) != 2 {
+		t.Fatalf("final %+v, want two rounds", final)
+	}
+	for r, round := range final.Rounds {
+		if m := round[0]; m.Players != [2]string{"b", "a"} || m.Winner != "a" {
+			t.Errorf("final round %d: %+v, want a to beat b, who plays X", r, m)
+		}
+	}
+	if tr.Champion != "a" || matches != 3 {
+		t.Errorf("champion %q after %d matches", tr.Champion, matches)
+	}
+}
+
+// TestDoubleEliminationSeeding has the better seed win every match, and
+// checks who drops into the losers bracket and who meets in the final.
+func TestDoubleEliminationSeeding(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	tr := run(t, names, tournament.DoubleElimination, func(m *tournament.Match) []int {
+		if m.Players[0] < m.Players[1] {
+			return xWins
+		}
+		return oWins
+	})
+	var brackets []string
+	for _, b := range tr.Brackets {
+		brackets = append(brackets, b.Name)
+	}
+	if !slices.Equal(brackets, []string{tournament.BracketWinners, tournament.BracketLosers, tournament.BracketFinal}) {
+		t.Fatalf("brackets %v", brackets)
+	}
+	losers := tr.Brackets[1].Rounds
+	var sizes []int
+	for _, round := range losers {
+		sizes = append(sizes, len(round))
+	}
+	if !slices.Equal(sizes, []int{2, 2, 1, 1}) {
+		t.Errorf("losers bracket rounds of %v matches", sizes)
+	}
+	if first := losers[0]; first[0].Players != [2]string{"h", "g"} || first[1].Players != [2]string{"f", "e"} {
+		t.Errorf("losers bracket first round %v and %v", first[0].Players, first[1].Players)
+	}
+	if final := tr.Brackets[2].Rounds; len(final) != 1 || final[0][0].Players != [2]string{"a", "b"} || tr.Champion != "a" {
+		t.Errorf("final %+v, champion %q", final[0][0], tr.Champion)
+	}
+}
@@ -0,0 +1,68 @@
+// Package urls builds the links the server hands out, so they keep
+// working when it is mounted under a path prefix such as "/ttt".
+package urls
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type prefixKey struct{}
+
+// CleanPrefix normalizes a configured prefix to "" or "/a/b" form.
+func CleanPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// Mount serves next under prefix, which must be clean. Requests for the
+// bare prefix are redirected to prefix + "/" so relative links in the
+// page resolve under it, and anything outside the prefix is not found.
+// The prefix is recorded in the request context for Path.
+func Mount(prefix string, next http.Handler) http.Handler {
+	if prefix == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == prefix {
+			target := prefix + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+		if !ok || !strings.HasPrefix(rest, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := r.Clone(context.WithValue(r.Context(), prefixKey{}, prefix))
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// Prefix returns the path prefix the request was served under, or "".
+func Prefix(ctx context.Context) string {
+	prefix, _ := ctx.Value(prefixKey{}).(string)
+	return prefix
+}
+
+// Path returns the root-relative path p, which must start with "/", with
+// the request's prefix in front.
+func Path(ctx context.Context, p string) string {
+	return Prefix(ctx) + p
+}
+
+// Pathf is like Path with the path built by fmt.Sprintf.
+func Pathf(ctx context.Context, format string, args ...any) string {
+	return Path(ctx, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,61 @@
+// Package version identifies the running build, as stamped with -ldflags
+// -X or else recorded by the Go toolchain.
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// APIVersion is the version of the HTTP and WebSocket API. It goes up
+// only for changes existing clients can't cope with; additions are
+// advertised as features instead, see Features.
+const APIVersion = 1
+
+// Set with -ldflags -X, see the package comment.
+var version, commit, builtAt string
+
+// Info describes the running build.
+type Info struct {
+	// Version is the release, or "dev" for an unreleased build.
+	Version string `json:"version"`
+	// Path is the main module's path.
+	Path string `json:"path,omitempty"`
+	// Commit is the revision built from, if known, and Modified is set
+	// when the checkout had uncommitted changes.
+	Commit   string    `json:"commit,omitempty"`
+	Modified bool      `json:"modified,omitempty"`
+	BuiltAt  time.Time `json:"builtAt,omitzero"`
+}
+
+// Get returns the running build's Info.
+var Get = sync.OnceValue(func() Info {
+	info := Info{Version: version, Commit: commit}
+	info.BuiltAt, _ = time.Parse(time.RFC3339, builtAt)
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Path = build.Main.Path
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, s := range build.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				// The commit's time stands in for the build's
+				if info.BuiltAt.IsZero() {
+					info.BuiltAt, _ = time.Parse(time.RFC3339, s.Value)
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+})
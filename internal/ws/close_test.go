@@ -0,0 +1,118 @@
+package ws_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestCloseReasons ends a connection each way the server does, and
+// checks the client is told why with the close code and text.
+func TestCloseReasons(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  server.Config
+		// end ends c, watching g as it waits for O, and returns it, or
+		// returns another connection the server ends
+		end  func(t *testing.T, srv *testutil.Server, g *models.GameState, c *testutil.WS) *testutil.WS
+		want broadcast.CloseReason
+	}{
+		{
+			name: "shutdown",
+			end: func(t *testing.T, srv *testutil.Server, _ *models.GameState, c *testutil.WS) *testutil.WS {
+				srv.Stop(t)
+				return c
+			},
+			want: broadcast.ReasonShutdown,
+		},
+		{
+			name: "game deleted",
+			end: func(t *testing.T, srv *testutil.Server, g *models.GameState, c *testutil.WS) *testutil.WS {
+				res, err := http.Post(srv.URL+"/htmx/cancel/"+g.ID, "", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+				if res.StatusCode != http.StatusOK {
+					t.Fatalf("cancelling the game: %s", res.Status)
+				}
+				return c
+			},
+			want: broadcast.ReasonGameDeleted,
+		},
+		{
+			name: "game expired",
+			cfg:  server.Config{GameOptions: []game.Option{game.WithMaxGames(1)}},
+			end: func(t *testing.T, srv *testutil.Server, _ *models.GameState, c *testutil.WS) *testutil.WS {
+				// Making room for a new game expires the waiting one
+				srv.CreateGame(t, "")
+				return c
+			},
+			want: broadcast.ReasonGameExpired,
+		},
+		{
+			name: "idle",
+			cfg:  server.Config{WSIdleTimeout: 100 * time.Millisecond},
+			end: func(_ *testing.T, _ *testutil.Server, _ *models.GameState, c *testutil.WS) *testutil.WS {
+				// A client that doesn't answer the server's pings
+				c.Conn.SetPingHandler(func(string) error { return nil })
+				return c
+			},
+			want: broadcast.ReasonIdle,
+		},
+		{
+			name: "flooding",
+			end: func(t *testing.T, _ *testutil.Server, _ *models.GameState, c *testutil.WS) *testutil.WS {
+				go func() {
+					for c.Conn.WriteJSON(map[string]any{"type": "bogus"}) == nil {
+					}
+				}()
+				return c
+			},
+			want: broadcast.ReasonPolicy,
+		},
+		{
+			name: "connected elsewhere",
+			cfg:  server.Config{DuplicateConnections: broadcast.RejectDuplicate},
+			end: func(t *testing.T, srv *testutil.Server, g *models.GameState, _ *testutil.WS) *testutil.WS {
+				_, token := srv.JoinAs(t, g.ID, models.PlayerO)
+				srv.DialWS(t, g.ID, "player=O", seat.TokenHeader, token).Connected(t)
+				return srv.DialWS(t, g.ID, "player=O", seat.TokenHeader, token)
+			},
+			want: broadcast.ReasonElsewhere,
+		},
+		{
+			name: "the client leaving",
+			end: func(t *testing.T, _ *testutil.Server, _ *models.GameState, c *testutil.WS) *testutil.WS {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				if err := c.Conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+					t.Fatal(err)
+				}
+				return c
+			},
+			want: broadcast.CloseReason{Code: websocket.CloseNormalClosure},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testutil.Start(t, tt.cfg)
+			g, _ := srv.JoinAs(t, srv.CreateGame(t, "").ID, models.PlayerX)
+			c := srv.DialWS(t, g.ID, "")
+			c.Connected(t)
+			c = tt.end(t, srv, g, c)
+			closeErr := closedWith(t, c.Conn, testutil.Timeout)
+			if closeErr.Code != tt.want.Code || closeErr.Text != tt.want.Text {
+				t.Errorf("closed with %d %q, want %d %q", closeErr.Code, closeErr.Text, tt.want.Code, tt.want.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package ws_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// deltaFrame is a frame a delta client gets: a delta or a whole state.
+type deltaFrame struct {
+	Type        string             `json:"type"`
+	Version     uint64             `json:"version"`
+	FromVersion uint64             `json:"fromVersion"`
+	ToVersion   uint64             `json:"toVersion"`
+	Changes     []broadcast.Change `json:"changes"`
+	CurrentTurn models.Player      `json:"currentTurn"`
+	Status      string             `json:"status"`
+	Winner      models.Player      `json:"winner"`
+	LastMove    *models.MoveRecord `json:"lastMove"`
+	Game        *models.GameState  `json:"game"`
+}
+
+// nextDelta returns the next delta or state frame c gets, skipping the
+// welcome and any events.
+func nextDelta(t *testing.T, c *testutil.WS) deltaFrame {
+	t.Helper()
+	for {
+		c.Conn.SetReadDeadline(time.Now().Add(testutil.Timeout))
+		_, data, err := c.Conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading a frame: %v", err)
+		}
+		var f deltaFrame
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Type == broadcast.DeltaFrameType || f.Type == broadcast.StateFrameType {
+			return f
+		}
+	}
+}
+
+// ack acknowledges version and waits until the server has taken it, by
+// asking for the game after it.
+func ack(t *testing.T, c *testutil.WS, version uint64) {
+	t.Helper()
+	c.Send(t, map[string]any{"type": broadcast.AckFrameType, "version": version})
+	c.Send(t, map[string]any{"type": "get"})
+	if f := nextDelta(t, c); f.Type != broadcast.StateFrameType || f.Version != version {
+		t.Fatalf("asked for the game after acking %d, got %+v", version, f)
+	}
+}
+
+// plays are the moves of a game X wins on the top row.
+var plays = []struct {
+	player models.Player
+	pos    int
+}{{models.PlayerX, 0}, {models.PlayerO, 3}, {models.PlayerX, 1}, {models.PlayerO, 4}, {models.PlayerX, 2}}
+
+// TestDeltas plays a game to a delta client and a full one, and checks
+// the board the delta client builds from its deltas matches the one the
+// full client is sent after every move.
+func TestDeltas(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	delta := srv.DialWS(t, g.ID, "delta=1")
+	full := srv.DialWS(t, g.ID, "")
+	full.Connected(t)
+
+	first := nextDelta(t, delta)
+	if first.Type != broadcast.StateFrameType || first.Game == nil || first.Game.ID != g.ID {
+		t.Fatalf("a delta client connected to %+v, want the game's state", first)
+	}
+	board, acked := first.Game.Board, first.Version
+	var f deltaFrame
+	for i, m := range plays {
+		srv.MustMove(t, g.ID, m.player, m.pos)
+		want := full.NextOf(t, broadcast.GameUpdateEvent).Game(t)
+
+		f = nextDelta(t, delta)
+		if f.Type != broadcast.DeltaFrameType {
+			t.Fatalf("move %d: got a %s frame, want a delta", i, f.Type)
+		}
+		if f.FromVersion != acked || f.ToVersion != acked+1 {
+			t.Errorf("move %d: delta from %d to %d, want %d to %d", i, f.FromVersion, f.ToVersion, acked, acked+1)
+		}
+		if len(f.Changes) != 1 || f.Changes[0] != (broadcast.Change{Pos: m.pos, Player: m.player}) {
+			t.Errorf("move %d: changes %+v, want just %s at %d", i, f.Changes, m.player, m.pos)
+		}
+		if f.LastMove == nil || f.LastMove.Position != m.pos || f.LastMove.Player != m.player {
+			t.Errorf("move %d: last move %+v, want %s at %d", i, f.LastMove, m.player, m.pos)
+		}
+		for _, c := range f.Changes {
+			board[c.Pos] = c.Player
+		}
+		if board != want.Board || f.CurrentTurn != want.CurrentTurn {
+			t.Fatalf("move %d: the delta client has %v, %s to move; the full client %v, %s to move",
+				i, board, f.CurrentTurn, want.Board, want.CurrentTurn)
+		}
+		acked = f.ToVersion
+		ack(t, delta, acked)
+	}
+	if f.Status != broadcast.StatusWon || f.Winner != models.PlayerX {
+		t.Errorf("the last delta has status %q, winner %q, want X to have won", f.Status, f.Winner)
+	}
+}
+
+// TestDeltaFallback stops acking for longer than the hub keeps states
+// for, and checks the client is then sent the whole game, and deltas
+// again once it acks that.
+func TestDeltaFallback(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	c := srv.DialWS(t, g.ID, "delta=1")
+	acked := nextDelta(t, c).Version
+	reset := func() {
+		t.Helper()
+		if _, err := srv.Do(t, http.MethodPut, "/api/game/"+g.ID, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Games X wins, each followed by a reset, until the acked version
+	// has just gone from the window
+	var f deltaFrame
+	for n := 1; n <= broadcast.DeltaWindow; n++ {
+		if i := (n - 1) % (len(plays) + 1); i < len(plays) {
+			srv.MustMove(t, g.ID, plays[i].player, plays[i].pos)
+		} else {
+			reset()
+		}
+		f = nextDelta(t, c)
+		if n < broadcast.DeltaWindow && (f.Type != broadcast.DeltaFrameType || f.FromVersion != acked || f.ToVersion != acked+uint64(n)) {
+			t.Fatalf("%d versions on: got %+v, want a delta from %d", n, f, acked)
+		}
+	}
+	var want models.GameState
+	if _, err := srv.Do(t, http.MethodGet, "/api/game/"+g.ID, "", &want); err != nil {
+		t.Fatal(err)
+	}
+	if f.Type != broadcast.StateFrameType || f.Game == nil || f.Game.Board != want.Board || f.Version != acked+broadcast.DeltaWindow {
+		t.Fatalf("got %+v once version %d had gone, want the whole game %v", f, acked, want.Board)
+	}
+
+	acked = f.Version
+	ack(t, c, acked)
+	reset()
+	if f := nextDelta(t, c); f.Type != broadcast.DeltaFrameType || f.FromVersion != acked {
+		t.Errorf("after acking the whole game: %+v, want a delta from %d", f, acked)
+	}
+}
@@ -0,0 +1,87 @@
+package ws_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+
+	"github.com/gorilla/websocket"
+)
+
+// policyDisconnects waits for the server to count want clients
+// disconnected for breaking the connection limits, which it may do just
+// after the close frame is sent, and returns the count.
+func policyDisconnects(t *testing.T, srv *testutil.Server, want uint64) uint64 {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		var stats broadcast.HubStats
+		if _, err := srv.Do(t, http.MethodGet, "/api/admin/hub", "", &stats, "Authorization", "Bearer admin"); err != nil {
+			t.Fatal(err)
+		}
+		if stats.PolicyDisconnects >= want || time.Now().After(deadline) {
+			return stats.PolicyDisconnects
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestFloodDisconnects floods a game's WebSocket with moves, and checks
+// the flooder is disconnected while a polite client on the same game
+// keeps playing.
+func TestFloodDisconnects(t *testing.T) {
+	srv := testutil.Start(t, server.Config{AdminKey: "admin"})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	polite := srv.DialWS(t, g.ID, "")
+	polite.Connected(t)
+	flooder := srv.DialWS(t, g.ID, "")
+	flooder.Connected(t)
+
+	// Moves off the board, each answered with an error while the flooder
+	// is let through
+	go func() {
+		for range 200 {
+			if flooder.Conn.WriteJSON(move(models.PlayerX, 9)) != nil {
+				return
+			}
+		}
+	}()
+	closeErr := closedWith(t, flooder.Conn, testutil.Timeout)
+	if closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != broadcast.ReasonPolicy.Text {
+		t.Errorf("the flooder was closed with %v, want 1008 %s", closeErr, broadcast.ReasonPolicy.Text)
+	}
+	if n := policyDisconnects(t, srv, 1); n != 1 {
+		t.Errorf("%d policy disconnects, want 1", n)
+	}
+
+	for i, pos := range []int{4, 0} {
+		polite.Send(t, move(g.CurrentTurn, pos))
+		update := polite.NextOf(t, broadcast.GameUpdateEvent).Game(t)
+		if update.Board[pos] == models.Empty {
+			t.Fatalf("move %d: the polite client's move at %d wasn't made", i, pos)
+		}
+		g = update
+	}
+}
+
+// TestOversizedMessageDisconnects sends one message over the size cap.
+func TestOversizedMessageDisconnects(t *testing.T) {
+	srv := testutil.Start(t, server.Config{AdminKey: "admin"})
+	g := srv.CreateGame(t, "")
+	c := srv.DialWS(t, g.ID, "")
+	c.Connected(t)
+
+	c.Send(t, map[string]any{"type": "get", "padding": strings.Repeat("x", 8192)})
+	if closeErr := closedWith(t, c.Conn, testutil.Timeout); closeErr.Code != websocket.CloseMessageTooBig {
+		t.Errorf("closed with %v, want 1009", closeErr)
+	}
+	if n := policyDisconnects(t, srv, 1); n != 1 {
+		t.Errorf("%d policy disconnects, want 1", n)
+	}
+}
@@ -1,67 +1,537 @@
 package ws
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"tiktaktoes/internal/activity"
 	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/errcode"
 	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/httpx"
+	"tiktaktoes/internal/logging"
 	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/security"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
+var tracer = otel.Tracer("tiktaktoes/internal/ws")
+
+// DefaultIdleTimeout is how long a client may go without sending
+// anything or answering a ping before it is disconnected.
+const DefaultIdleTimeout = 75 * time.Second
+
+// writeWait bounds writing a ping.
+const writeWait = time.Second
 
 // Handler handles WebSocket connections for real-time game updates.
 type Handler struct {
 	gameService *game.Service
 	hub         *broadcast.Hub
+	upgrader    *websocket.Upgrader
+	idleTimeout time.Duration
+	activity    activity.Recorder
+	seats       *seat.Signer
 }
 
-// NewHandler creates a new WebSocket handler.
-func NewHandler(gameService *game.Service, hub *broadcast.Hub) *Handler {
+// NewHandler creates a new WebSocket handler. A nil upgrader accepts
+// same-origin handshakes only, and a zero idleTimeout means DefaultIdleTimeout.
+func NewHandler(gameService *game.Service, hub *broadcast.Hub, upgrader *websocket.Upgrader, idleTimeout time.Duration, recorder activity.Recorder, seats *seat.Signer) *Handler {
+	if upgrader == nil {
+		upgrader = security.NewUpgrader(security.UpgraderConfig{})
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
 	return &Handler{
 		gameService: gameService,
 		hub:         hub,
+		upgrader:    upgrader,
+		idleTimeout: idleTimeout,
+		activity:    recorder,
+		seats:       seats,
 	}
 }
 
 // RegisterRoutes sets up the WebSocket routes.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/ws/{gameID}", h.handleWebSocket)
+	mux.HandleFunc("GET /ws/{gameID}", h.handleWebSocket)
 }
 
+// maxSubscriptions caps how many games one connection may get updates
+// for, counting the one in its URL.
+const maxSubscriptions = 32
+
+// Errors answering subscription messages and messages for games the
+// connection isn't subscribed to.
+var (
+	errTooManySubscriptions = fmt.Errorf("subscribed to the most games allowed, %d", maxSubscriptions)
+	errNotSubscribed        = errors.New("not subscribed to that game")
+	errElsewhere            = errors.New("already connected elsewhere as that player, subscribe with takeover to take over")
+	errSuperseded           = errors.New("another connection has taken over as that player, this one only watches")
+	errPullLimited          = fmt.Errorf("asking for the game too often, at most %d a second", pullRate)
+	errInvalidFrom          = errors.New("from must be a move number, 0 or more")
+	errTimedOut             = fmt.Errorf("took longer than %v to handle, try again", handleTimeout)
+)
+
 func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	gameID := r.PathValue("gameID")
+	// Subscribe under the full ID, whatever prefix or case was used, so
+	// broadcasts for the game reach this connection
+	gameID, err := httpx.PathGameID(r)
+	if err != nil {
+		httpx.WriteError(w, r, err)
+		return
+	}
+	if g, err := h.gameService.FindGame(r.Context(), gameID); err == nil {
+		gameID = g.ID
+	} else if errors.Is(err, game.ErrAmbiguousID) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 	defer conn.Close()
 
-	h.hub.RegisterWS(gameID, conn)
-	defer h.hub.UnregisterWS(gameID, conn)
+	connID := logging.NewID()
+	ctx := logging.WithConnID(r.Context(), connID)
+	slog.InfoContext(ctx, "websocket opened", "game_id", gameID)
+	defer slog.InfoContext(ctx, "websocket closed", "game_id", gameID)
+
+	ctx, span := tracer.Start(ctx, "ws.connection")
+	defer span.End()
+	received, rejected := 0, 0
+	defer func() {
+		if span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("game.id", gameID),
+				attribute.Int("messages.received", received),
+				attribute.Int("messages.rejected", rejected),
+			)
+		}
+	}()
+
+	// A hijacked connection outlives the request context, so derive one
+	// that's cancelled when the read loop below ends with the connection.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Send current game state
-	if game, exists := h.gameService.GetGame(gameID); exists {
-		conn.WriteJSON(game)
+	// A side is played only by a connection holding its seat, see
+	// package seat, and one claiming it without watches instead
+	held := h.seats.Cookie(r)
+	for _, token := range []string{r.Header.Get(seat.TokenHeader), r.URL.Query().Get(seat.TokenParam)} {
+		if sides, ok := h.seats.Sides(gameID, token); ok {
+			held[gameID] += sides
+		}
+	}
+	player := models.Player(r.URL.Query().Get("player"))
+	if !holds(held[gameID], player) {
+		player = models.Empty
 	}
+	sub := broadcast.NewSubscriber(player, connID)
+	sub.Delta, _ = strconv.ParseBool(r.URL.Query().Get("delta"))
+	sub.Envelope, _ = strconv.ParseBool(r.URL.Query().Get("envelope"))
+	if analysis, err := strconv.ParseBool(r.URL.Query().Get("analysis")); err == nil {
+		sub.NoAnalysis = !analysis
+	}
+	sub.Commentary, _ = strconv.ParseBool(r.URL.Query().Get("commentary"))
+	// Under the reject policy a second connection for a player's slot is
+	// turned away unless it asks to take the slot over
+	takeover, _ := strconv.ParseBool(r.URL.Query().Get("takeover"))
+	if !h.hub.Admit(gameID, sub, takeover) {
+		slog.InfoContext(ctx, "websocket rejected, player connected elsewhere", "game_id", gameID, "player", player)
+		broadcast.ReasonElsewhere.Send(conn)
+		awaitClose(conn)
+		return
+	}
+	h.hub.RegisterWS(gameID, conn, sub)
+	defer h.hub.UnregisterConn(conn)
 
-	// Keep connection alive and listen for messages
+	// Send current game state and the welcome frame
+	g, _ := h.gameService.GetGame(ctx, gameID)
+	h.hub.SendState(gameID, conn, g)
+
+	// Keep connection alive and listen for messages. Pings keep clients
+	// that only listen active; one that answers neither pings nor
+	// anything else for the idle timeout is dropped.
+	conn.SetReadLimit(maxMessageSize)
+	lastActive := time.Now()
+	// reading is set while a message is half read, since began, which
+	// must be read within its own deadline rather than the idle one
+	reading, began := false, time.Time{}
+	active := func() {
+		lastActive = time.Now()
+		if !reading {
+			conn.SetReadDeadline(lastActive.Add(h.idleTimeout))
+		}
+	}
+	active()
+	conn.SetPongHandler(func(string) error {
+		active()
+		return nil
+	})
+	go h.ping(ctx, conn)
+	limit := newLimiter(time.Now())
+	queue := make(chan inbound, inboundQueue)
+	processed := make(chan int)
+	go func() {
+		processed <- h.process(ctx, conn, gameID, sub, held, limit, queue)
+	}()
+	// The messages queued are handled before the connection is
+	// unregistered and closed
+	defer func() {
+		close(queue)
+		rejected += <-processed
+	}()
 	for {
-		var move models.Move
-		if err := conn.ReadJSON(&move); err != nil {
-			break
+		var msg inbound
+		_, r, err := conn.NextReader()
+		if err == nil {
+			reading, began = true, time.Now()
+			conn.SetReadDeadline(began.Add(messageReadTimeout))
+			err = decode(r, &msg)
 		}
-		if game, err := h.gameService.MakeMove(gameID, move); err == nil {
-			h.hub.Broadcast(gameID, game)
-		} else {
-			conn.WriteJSON(map[string]string{"error": err.Error()})
+		var netErr net.Error
+		switch {
+		case errors.Is(err, websocket.ErrReadLimit):
+			// The library has already sent the close frame
+			slog.WarnContext(ctx, "websocket message too large", "game_id", gameID)
+			h.hub.CountPolicyDisconnect()
+			return
+		case errors.As(err, &netErr) && netErr.Timeout() && reading && time.Since(began) >= messageReadTimeout:
+			slog.WarnContext(ctx, "websocket message stalled, disconnecting", "game_id", gameID)
+			h.hub.CountPolicyDisconnect()
+			reasonStalled.Send(conn)
+			return
+		case errors.As(err, &netErr) && netErr.Timeout() && time.Since(lastActive) >= h.idleTimeout:
+			// Other timeouts are the deadline of a close frame the hub sent
+			slog.InfoContext(ctx, "websocket idle, disconnecting", "game_id", gameID)
+			broadcast.ReasonIdle.Send(conn)
+			return
+		case err != nil:
+			return
 		}
+		reading = false
+		active()
+		received++
+		switch limit.check(time.Now()) {
+		case drop:
+			rejected++
+			continue
+		case disconnect:
+			rejected++
+			slog.WarnContext(ctx, "websocket flooding, disconnecting", "game_id", gameID, "received", received)
+			h.hub.CountPolicyDisconnect()
+			broadcast.ReasonPolicy.Send(conn)
+			awaitClose(conn)
+			return
+		}
+		select {
+		case queue <- msg:
+		default:
+			// Those already queued are dropped with this one
+			rejected += 1 + len(queue)
+			discard(queue)
+			slog.WarnContext(ctx, "websocket messages backed up, disconnecting", "game_id", gameID, "queued", inboundQueue)
+			h.hub.CountPolicyDisconnect()
+			reasonBacklog.Send(conn)
+			awaitClose(conn)
+			return
+		}
+	}
+}
+
+// handle handles one message from a connection whose first game has the
+// given ID, and returns the error it was rejected with, if any. held maps
+// the IDs of games to the sides the connection's handshake holds seats
+// for.
+func (h *Handler) handle(ctx context.Context, conn *websocket.Conn, gameID string, sub broadcast.Subscriber, held map[string]string, msg inbound, limit *limiter) error {
+	if msg.Type == subscribeType || msg.Type == unsubscribeType {
+		return timedOut(ctx, h.subscribe(ctx, conn, sub, held, msg))
+	}
+	target, err := h.subscribed(ctx, conn, gameID, msg.GameID)
+	if err == nil && msg.Type == broadcast.AckFrameType {
+		h.hub.Ack(target, conn, msg.Version)
+		return nil
+	}
+	if msg.Type == getType || msg.Type == historyType {
+		if err == nil {
+			err = h.pull(ctx, conn, target, msg, limit)
+		}
+		return timedOut(ctx, err)
+	}
+	var g *models.GameState
+	moved := false
+	switch {
+	case err != nil:
+		// Not subscribed to the game, so not recorded in its activity
+	case h.hub.Superseded(target, conn):
+		// Its player plays from the connection that took over, so
+		// this one can't submit twice
+		err = errSuperseded
+	case slices.Contains(seatedTypes, msg.Type) && !h.hub.Controls(target, conn, msg.Player):
+		err = seat.ErrNoSeat
+	case msg.Type == offerDrawType:
+		g, err = h.gameService.OfferDraw(ctx, target, msg.Player)
+	case msg.Type == replyDrawType:
+		g, err = h.gameService.RespondDraw(ctx, target, msg.Player, msg.Accept)
+	case msg.Type == claimWinType:
+		g, err = h.gameService.ClaimWin(ctx, target, msg.Player)
+	case msg.Type == readyType, msg.Type == unreadyType:
+		g, err = h.gameService.Ready(ctx, target, msg.Player, msg.Type == readyType)
+	default:
+		g, err = h.gameService.MakeMove(ctx, target, msg.Move)
+		moved = true
+	}
+	err = timedOut(ctx, err)
+	if target != "" {
+		// Recorded even if the message ran out of time
+		h.record(context.WithoutCancel(ctx), target, entryFor(msg), err)
+	}
+	if err != nil {
+		return err
+	}
+	h.hub.Broadcast(ctx, target, g)
+	if moved {
+		h.hub.NotifyTurn(ctx, g, sub.ConnID)
+	}
+	return nil
+}
+
+// subscribe handles a subscribe or unsubscribe message.
+func (h *Handler) subscribe(ctx context.Context, conn *websocket.Conn, sub broadcast.Subscriber, held map[string]string, msg inbound) error {
+	g, err := h.gameService.FindGame(ctx, msg.GameID)
+	if err != nil {
+		return err
+	}
+	subscriptions := h.hub.Subscriptions(conn)
+	if msg.Type == unsubscribeType {
+		if !slices.Contains(subscriptions, g.ID) {
+			return errNotSubscribed
+		}
+		h.hub.Unsubscribe(g.ID, conn)
+		return nil
+	}
+	if !slices.Contains(subscriptions, g.ID) && len(subscriptions) >= maxSubscriptions {
+		return errTooManySubscriptions
+	}
+	claimed := msg.Player
+	if !holds(held[g.ID], claimed) && !h.seats.Holds(g.ID, msg.Seat, claimed) {
+		claimed = models.Empty
+	}
+	player := broadcast.NewSubscriber(claimed, sub.ConnID)
+	sub.Player, sub.Role = player.Player, player.Role
+	if !h.hub.Admit(g.ID, sub, msg.Takeover) {
+		return errElsewhere
+	}
+	h.hub.RegisterWS(g.ID, conn, sub)
+	h.hub.SendState(g.ID, conn, g)
+	return nil
+}
+
+// holds reports whether sides, as a seat token holds them, include
+// player's.
+func holds(sides string, player models.Player) bool {
+	return (player == models.PlayerX || player == models.PlayerO) && strings.Contains(sides, string(player))
+}
+
+// pull answers a get or history message, which only reads the game, so
+// spectators and superseded connections may send them too. The answer
+// is queued behind the game's broadcasts, see broadcast.Pull, and
+// written like them.
+func (h *Handler) pull(ctx context.Context, conn *websocket.Conn, gameID string, msg inbound, limit *limiter) error {
+	if msg.Type == historyType && msg.From < 0 {
+		return errInvalidFrom
+	}
+	if !limit.pull(time.Now()) {
+		return errPullLimited
+	}
+	if _, ok := h.gameService.GetGame(ctx, gameID); !ok {
+		return game.ErrGameNotFound
+	}
+	// The state is read once the answer's turn comes, which may be after
+	// the message's time is up
+	current := context.WithoutCancel(ctx)
+	h.hub.Pull(ctx, gameID, conn, broadcast.Pull{
+		Current: func() *models.GameState {
+			g, _ := h.gameService.GetGame(current, gameID)
+			return g
+		},
+		History: msg.Type == historyType,
+		From:    msg.From,
+	})
+	return nil
+}
+
+// subscribed returns the full ID of the game a message is for: the
+// connection's first game, or the one named by its gameId, which must
+// be one the connection is subscribed to.
+func (h *Handler) subscribed(ctx context.Context, conn *websocket.Conn, first, gameID string) (string, error) {
+	if gameID == "" {
+		return first, nil
+	}
+	subscriptions := h.hub.Subscriptions(conn)
+	if slices.Contains(subscriptions, gameID) {
+		return gameID, nil
+	}
+	g, err := h.gameService.FindGame(ctx, gameID)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(subscriptions, g.ID) {
+		return "", errNotSubscribed
+	}
+	return g.ID, nil
+}
+
+// code returns the stable code of an error sent in an errorFrame. The
+// connection's own errors are known here, the rest by errcode.
+func code(err error) string {
+	switch err {
+	case errTooManySubscriptions:
+		return "too_many_subscriptions"
+	case errNotSubscribed:
+		return "not_subscribed"
+	case errElsewhere:
+		return "connected_elsewhere"
+	case errSuperseded:
+		return "superseded"
+	case errPullLimited:
+		return "pull_limited"
+	case errInvalidFrom:
+		return "invalid_from"
+	case errTimedOut:
+		return "timed_out"
+	}
+	return errcode.FromError(err).Code
+}
+
+// ping sends the client a ping every few seconds until ctx is done. The
+// interval leaves room for two to go unanswered before the idle timeout.
+func (h *Handler) ping(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(h.idleTimeout * 2 / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// awaitClose discards messages until the client answers a close frame
+// or the deadline set when sending it passes.
+func awaitClose(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// Types of inbound messages other than moves, which have none.
+const (
+	offerDrawType = "offer-draw"
+	replyDrawType = "reply-draw"
+	claimWinType  = "claim-win"
+	readyType     = "ready"
+	unreadyType   = "unready"
+
+	subscribeType   = "subscribe"
+	unsubscribeType = "unsubscribe"
+
+	getType     = "get"
+	historyType = broadcast.HistoryFrameType
+)
+
+// seatedTypes are the messages taken for a side only by the connection
+// playing it, see broadcast.Hub.Controls.
+var seatedTypes = []string{offerDrawType, replyDrawType, claimWinType, readyType, unreadyType}
+
+// inbound is a message from a client: a move, or one of the types
+// handled in process. GameID defaults to the game in the URL.
+type inbound struct {
+	Type     string `json:"type"`
+	GameID   string `json:"gameId"`
+	Version  uint64 `json:"version"`
+	From     int    `json:"from"`
+	Accept   bool   `json:"accept"`
+	Takeover bool   `json:"takeover"`
+	Seat     string `json:"seat"`
+	models.Move
+}
+
+// entryFor returns the activity entry of msg, a move or another action.
+func entryFor(msg inbound) activity.Entry {
+	entry := activity.Entry{Player: msg.Player}
+	switch msg.Type {
+	case offerDrawType:
+		entry.Action = activity.ActionDrawOffer
+	case replyDrawType:
+		entry.Action = activity.ActionDrawReply
+	case claimWinType:
+		entry.Action = activity.ActionClaim
+	case readyType:
+		entry.Action = activity.ActionReady
+	case unreadyType:
+		entry.Action = activity.ActionUnready
+	default:
+		move := msg.Move
+		entry.Action = activity.ActionMove
+		entry.Move = &move
+	}
+	return entry
+}
+
+// record adds entry, for an action answered with err, to the game's
+// activity, if there is a recorder.
+func (h *Handler) record(ctx context.Context, gameID string, entry activity.Entry, err error) {
+	if h.activity == nil {
+		return
+	}
+	entry.Transport = activity.TransportWS
+	entry.Result = activity.Result(err)
+	if c := code(err); err != nil && c != "" {
+		entry.Result = c
+	}
+	h.activity.Record(ctx, gameID, entry)
+}
+
+// errorFrame is sent to a client whose message was rejected.
+type errorFrame struct {
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"`
+	Temporary bool   `json:"temporary,omitempty"`
+	GameID    string `json:"gameId,omitempty"`
+	ConnID    string `json:"connId"`
+}
+
+// newErrorFrame returns the errorFrame rejecting a message about the
+// game with the given ID with err.
+func newErrorFrame(err error, gameID, connID string) errorFrame {
+	return errorFrame{
+		Error:     err.Error(),
+		Code:      code(err),
+		Temporary: err == errPullLimited || err == errTimedOut || errcode.FromError(err).Temporary,
+		GameID:    gameID,
+		ConnID:    connID,
 	}
 }
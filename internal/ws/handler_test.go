@@ -0,0 +1,304 @@
+package ws_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/game"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/seat"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/goleak"
+)
+
+// verifyNoLeaks checks, once the test has ended and everything it set
+// up has been torn down, that the goroutines it started are gone.
+func verifyNoLeaks(t *testing.T) {
+	running := goleak.IgnoreCurrent()
+	t.Cleanup(func() {
+		http.DefaultClient.CloseIdleConnections()
+		goleak.VerifyNone(t, running)
+	})
+}
+
+// move is a move message, as a client sends it.
+func move(player models.Player, position int) map[string]any {
+	return map[string]any{"player": player, "position": position}
+}
+
+// TestConnectionCloseLeavesNoGoroutines plays over several connections
+// that then go away, and checks each one's reader, message handler and
+// pinger are gone with it.
+func TestConnectionCloseLeavesNoGoroutines(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	// The game's dispatcher is the hub's, running until it idles or the
+	// hub closes, so it is started before the check begins
+	srv.MustMove(t, g.ID, models.PlayerX, 0)
+
+	running := goleak.IgnoreCurrent()
+	var conns []*testutil.WS
+	for range 3 {
+		c := srv.DialWS(t, g.ID, "")
+		c.Connected(t)
+		conns = append(conns, c)
+	}
+	conns[0].Send(t, move(models.PlayerO, 4))
+	for _, c := range conns {
+		c.NextOf(t, broadcast.GameUpdateEvent)
+	}
+	// One client leaves a message unanswered as it goes
+	conns[1].Send(t, move(models.PlayerX, 4))
+	for _, c := range conns {
+		c.Conn.Close()
+	}
+	goleak.VerifyNone(t, running)
+}
+
+// TestShutdownLeavesNoGoroutines shuts a server down with clients still
+// connected, a computer thinking and a ready check's clock running, and
+// checks nothing is left behind: not the connections' goroutines, the
+// hub's dispatchers and writers, nor the games' workers.
+func TestShutdownLeavesNoGoroutines(t *testing.T) {
+	verifyNoLeaks(t)
+	srv := testutil.Start(t, server.Config{GameOptions: []game.Option{game.WithThinkTime(time.Hour)}})
+
+	ai := srv.CreateGame(t, `{"mode":"ai"}`)
+	srv.MustMove(t, ai.ID, models.PlayerX, 4)
+	ready := srv.CreateGame(t, `{"readyCheck":true}`)
+	srv.JoinAs(t, ready.ID, models.PlayerX)
+	srv.JoinAs(t, ready.ID, models.PlayerO)
+	hotseat := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	for _, id := range []string{ai.ID, ready.ID, hotseat.ID} {
+		for range 2 {
+			srv.DialWS(t, id, "").Connected(t)
+		}
+	}
+	srv.MustMove(t, hotseat.ID, models.PlayerX, 0)
+
+	srv.Stop(t)
+}
+
+// stallDialer dials WebSockets whose raw connection it keeps, so a test
+// can write half a frame to it.
+type stallDialer struct {
+	raw net.Conn
+}
+
+func (d *stallDialer) dial(t *testing.T, srv *testutil.Server, gameID string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{NetDial: func(network, addr string) (net.Conn, error) {
+		c, err := net.Dial(network, addr)
+		d.raw = c
+		return c, err
+	}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/"+gameID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// closedWith reads from conn, past any frames, until it is closed, and
+// returns the close error, failing the test if it isn't closed within
+// timeout.
+func closedWith(t *testing.T, conn *websocket.Conn, timeout time.Duration) *websocket.CloseError {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("got %v, want the connection closed", err)
+		}
+		return closeErr
+	}
+}
+
+// TestStalledMessageDisconnects sends the start of a message and no
+// more. The client is disconnected once it has had the message read
+// timeout to finish it, long before the idle timeout.
+func TestStalledMessageDisconnects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits out the message read timeout")
+	}
+	verifyNoLeaks(t)
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, "")
+	var d stallDialer
+	conn := d.dial(t, srv, g.ID)
+
+	// A text frame of ten bytes, of which `{"t` is sent, masked with
+	// zeroes so it stays as it is
+	start := time.Now()
+	if _, err := d.raw.Write([]byte{0x81, 0x80 | 10, 0, 0, 0, 0, '{', '"', 't'}); err != nil {
+		t.Fatal(err)
+	}
+	closeErr := closedWith(t, conn, 10*time.Second)
+	if closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != "message stalled" {
+		t.Errorf("closed with %v, want 1008 message stalled", closeErr)
+	}
+	if took := time.Since(start); took < 4*time.Second {
+		t.Errorf("disconnected after %v, before the message read timeout", took)
+	}
+}
+
+// blockingRepository is a repository whose writes wait, once blocking is
+// set, until release is closed or they are cancelled.
+type blockingRepository struct {
+	game.Repository
+	blocking atomic.Bool
+	release  chan struct{}
+}
+
+func (r *blockingRepository) Put(ctx context.Context, g *models.GameState) error {
+	if r.blocking.Load() {
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return r.Repository.Put(ctx, g)
+}
+
+// TestBacklogDisconnects holds up the handling of a client's moves and
+// has it go on sending them as fast as the rate limit lets it. It is
+// disconnected once its queue is full, and the goroutine handling its
+// messages is gone once the game lets the one under way finish.
+func TestBacklogDisconnects(t *testing.T) {
+	verifyNoLeaks(t)
+	repo := &blockingRepository{Repository: game.NewMemoryRepository(), release: make(chan struct{})}
+	srv := testutil.Start(t, server.Config{GameOptions: []game.Option{game.WithRepository(repo)}})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	c := srv.DialWS(t, g.ID, "")
+	c.Connected(t)
+
+	repo.blocking.Store(true)
+	// A burst the limiter allows, then more once it has refilled: one
+	// message is handled, the rest fill the queue and overflow it
+	for i := range 10 {
+		c.Send(t, move(models.PlayerX, i%9))
+	}
+	time.Sleep(500 * time.Millisecond)
+	c.Send(t, move(models.PlayerX, 0))
+	c.Send(t, move(models.PlayerX, 0))
+	closeErr := closedWith(t, c.Conn, testutil.Timeout)
+	if closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != "too many messages in flight" {
+		t.Errorf("closed with %v, want 1008 too many messages in flight", closeErr)
+	}
+	close(repo.release)
+}
+
+// until returns the next of c's frames of one of the types given.
+func until(t *testing.T, c *testutil.WS, types ...string) testutil.Frame {
+	t.Helper()
+	for {
+		if f := c.Next(t); slices.Contains(types, f.Type) {
+			return f
+		}
+	}
+}
+
+// TestTakeoverNeedsTheSeat checks a connection claiming a side without a
+// seat token holding it watches instead, under either duplicate policy,
+// and can't take the side from the connection playing it.
+func TestTakeoverNeedsTheSeat(t *testing.T) {
+	for _, policy := range []broadcast.DuplicatePolicy{broadcast.Supersede, broadcast.RejectDuplicate} {
+		srv := testutil.Start(t, server.Config{DuplicateConnections: policy})
+		g := srv.CreateGame(t, "")
+		_, x := srv.JoinAs(t, g.ID, models.PlayerX)
+		_, o := srv.JoinAs(t, g.ID, models.PlayerO)
+		other := srv.CreateGame(t, "")
+
+		// The token in the query, as a browser's WebSocket sends it
+		first := srv.DialWS(t, g.ID, "player=X&seat="+url.QueryEscape(x))
+		first.Connected(t)
+
+		for _, query := range []string{"player=X", "player=X&takeover=1", "player=X&takeover=1&seat=" + url.QueryEscape(o)} {
+			srv.DialWS(t, g.ID, query).Connected(t)
+		}
+		srv.DialWS(t, g.ID, "player=X&takeover=1", seat.TokenHeader, o).Connected(t)
+		subscriber := srv.DialWS(t, other.ID, "")
+		subscriber.Connected(t)
+		subscriber.Send(t, map[string]any{"type": "subscribe", "gameId": g.ID, "player": "X", "takeover": true})
+		subscriber.Connected(t)
+
+		srv.MustMove(t, g.ID, models.PlayerX, 4)
+		if f := until(t, first, broadcast.GameUpdateEvent, broadcast.SupersededEvent); f.Type != broadcast.GameUpdateEvent {
+			t.Fatalf("policy %d: the first connection was superseded without a seat token", policy)
+		}
+
+		// With the seat's token the takeover goes ahead
+		subscriber.Send(t, map[string]any{"type": "subscribe", "gameId": g.ID, "player": "X", "takeover": true, "seat": x})
+		if f := until(t, first, broadcast.GameUpdateEvent, broadcast.SupersededEvent); f.Type != broadcast.SupersededEvent {
+			t.Errorf("policy %d: got %s, want superseded by the connection holding the seat", policy, f.Type)
+		}
+	}
+}
+
+// TestActionsNeedTheSeat checks messages acting for a side other than
+// moves are refused from connections not playing it, whether they
+// claimed it without its seat token or only watch.
+func TestActionsNeedTheSeat(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, "")
+	_, x := srv.JoinAs(t, g.ID, models.PlayerX)
+	_, o := srv.JoinAs(t, g.ID, models.PlayerO)
+	srv.MustMove(t, g.ID, models.PlayerX, 4)
+	other := srv.CreateGame(t, "")
+
+	player := srv.DialWS(t, g.ID, "player=X", seat.TokenHeader, x)
+	player.Connected(t)
+	intruders := map[string]*testutil.WS{
+		"a spectator":    srv.DialWS(t, g.ID, ""),
+		"no seat token":  srv.DialWS(t, g.ID, "player=X"),
+		"O's seat token": srv.DialWS(t, g.ID, "player=X", seat.TokenHeader, o),
+		"a subscription": srv.DialWS(t, other.ID, ""),
+		"O's connection": srv.DialWS(t, g.ID, "player=O", seat.TokenHeader, o),
+	}
+	for _, c := range intruders {
+		c.Connected(t)
+	}
+	sub := intruders["a subscription"]
+	sub.Send(t, map[string]any{"type": "subscribe", "gameId": g.ID, "player": "X"})
+	sub.Connected(t)
+
+	actions := []map[string]any{
+		{"type": "claim-win", "player": "X"},
+		{"type": "offer-draw", "player": "X"},
+		{"type": "reply-draw", "player": "X", "accept": true},
+		{"type": "ready", "player": "X"},
+		{"type": "unready", "player": "X"},
+	}
+	for _, action := range actions {
+		action["gameId"] = g.ID
+		for name, c := range intruders {
+			c.Send(t, action)
+			if f := until(t, c, ""); f.Code != "seat_token" {
+				t.Errorf("%s from %s: error %q %q, want seat_token", action["type"], name, f.Code, f.Error)
+			}
+		}
+		// The connection playing X gets past the seat check to the game
+		player.Send(t, action)
+		if f := until(t, player, "", broadcast.GameUpdateEvent); f.Code == "seat_token" {
+			t.Errorf("%s from X's connection: refused with seat_token", action["type"])
+		}
+	}
+}
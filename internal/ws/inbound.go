@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+
+	"github.com/gorilla/websocket"
+)
+
+// A connection's messages are queued by its read loop and handled one at
+// a time by Handler.process.
+const (
+	inboundQueue       = messageBurst
+	handleTimeout      = 5 * time.Second
+	messageReadTimeout = 5 * time.Second
+)
+
+// Close reasons of the read loop's own, both policy violations.
+var (
+	reasonBacklog = broadcast.CloseReason{Code: websocket.ClosePolicyViolation, Text: "too many messages in flight"}
+	reasonStalled = broadcast.CloseReason{Code: websocket.ClosePolicyViolation, Text: "message stalled"}
+)
+
+// decode reads one message's JSON from r, then the rest of the message,
+// as websocket.Conn.ReadJSON would but without leaving any of it for the
+// next read.
+func decode(r io.Reader, msg *inbound) error {
+	err := json.NewDecoder(r).Decode(msg)
+	if err == io.EOF {
+		// A message can't be empty
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// process handles the messages the read loop queues until it closes the
+// queue, and returns how many it rejected.
+func (h *Handler) process(ctx context.Context, conn *websocket.Conn, gameID string, sub broadcast.Subscriber, held map[string]string, limit *limiter, queue <-chan inbound) int {
+	rejected := 0
+	for msg := range queue {
+		msgCtx, cancel := context.WithTimeout(ctx, handleTimeout)
+		err := h.handle(msgCtx, conn, gameID, sub, held, msg, limit)
+		cancel()
+		if err != nil {
+			rejected++
+			h.hub.WriteJSON(conn, newErrorFrame(err, msg.GameID, sub.ConnID))
+		}
+	}
+	return rejected
+}
+
+// discard empties queue of the messages waiting in it.
+func discard(queue chan inbound) {
+	for {
+		select {
+		case <-queue:
+		default:
+			return
+		}
+	}
+}
+
+// timedOut returns errTimedOut for err if it is ctx's deadline passing,
+// and err as it is otherwise.
+func timedOut(ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.WarnContext(ctx, "websocket message timed out", "timeout", handleTimeout)
+		return errTimedOut
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package ws
+
+import "time"
+
+// Inbound message limits per connection. A client that keeps sending
+// past them is flooding and is disconnected.
+const (
+	messageRate  = 5
+	messageBurst = 10
+	floodBurst   = 20
+
+	// maxMessageSize caps one inbound message; a move is well under it.
+	maxMessageSize = 4096
+)
+
+// Limits on requests for a game's state or moves, which are answered
+// with pull_limited past them.
+const (
+	pullRate  = 1
+	pullBurst = 5
+)
+
+// bucket is a token bucket holding up to burst tokens, refilled at rate
+// per second.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64, now time.Time) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// allow takes a token if one is available at now.
+func (b *bucket) allow(now time.Time) bool {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// limiter decides what to do with each message a connection sends. Its
+// read loop calls check and the goroutine handling the messages pull, so
+// each bucket is only ever used by one of them.
+type limiter struct {
+	messages *bucket
+	drops    *bucket
+	pulls    *bucket
+}
+
+func newLimiter(now time.Time) *limiter {
+	return &limiter{
+		messages: newBucket(messageRate, messageBurst, now),
+		drops:    newBucket(messageRate, floodBurst, now),
+		pulls:    newBucket(pullRate, pullBurst, now),
+	}
+}
+
+// verdict is what becomes of one inbound message
+type verdict int
+
+const (
+	accept verdict = iota
+	drop
+	disconnect
+)
+
+func (l *limiter) check(now time.Time) verdict {
+	switch {
+	case l.messages.allow(now):
+		return accept
+	case l.drops.allow(now):
+		return drop
+	}
+	return disconnect
+}
+
+// pull reports whether a request for a game's state or moves at now,
+// which check has let through, may be answered.
+func (l *limiter) pull(now time.Time) bool {
+	return l.pulls.allow(now)
+}
@@ -0,0 +1,38 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := newLimiter(now)
+	check := func(n int, want verdict) {
+		t.Helper()
+		for i := range n {
+			if got := l.check(now); got != want {
+				t.Fatalf("message %d: verdict %d, want %d", i, got, want)
+			}
+		}
+	}
+	check(messageBurst, accept)
+	check(floodBurst/2, drop)
+	// A second refills messageRate of each
+	now = now.Add(time.Second)
+	check(messageRate, accept)
+	check(messageRate+floodBurst/2, drop)
+	check(1, disconnect)
+
+	for i := range pullBurst {
+		if !l.pull(now) {
+			t.Fatalf("pull %d refused within the burst", i)
+		}
+	}
+	if l.pull(now) {
+		t.Error("a pull beyond the burst was let through")
+	}
+	if !l.pull(now.Add(time.Second / pullRate)) {
+		t.Error("no pull let through once the bucket refilled")
+	}
+}
@@ -0,0 +1,13 @@
+package ws_test
+
+import (
+	"testing"
+
+	"go.uber.org/goleak"
+)
+
+// TestMain fails the package if a test leaves a connection's reader,
+// message handler or pinger running.
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
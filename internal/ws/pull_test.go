@@ -0,0 +1,136 @@
+package ws_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"tiktaktoes/internal/broadcast"
+	"tiktaktoes/internal/models"
+	"tiktaktoes/internal/server"
+	"tiktaktoes/internal/testutil"
+)
+
+// nextHistory returns the next history frame c gets, skipping others.
+func nextHistory(t *testing.T, c *testutil.WS) broadcast.HistoryFrame {
+	t.Helper()
+	for {
+		c.Conn.SetReadDeadline(time.Now().Add(testutil.Timeout))
+		_, data, err := c.Conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading a frame: %v", err)
+		}
+		var f broadcast.HistoryFrame
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Type == broadcast.HistoryFrameType {
+			return f
+		}
+	}
+}
+
+// errorFrame is the part of an error frame a test checks.
+type errorFrame struct {
+	Code      string `json:"code"`
+	Temporary bool   `json:"temporary"`
+}
+
+// nextError returns the next error frame c gets, skipping others.
+func nextError(t *testing.T, c *testutil.WS) errorFrame {
+	t.Helper()
+	for {
+		c.Conn.SetReadDeadline(time.Now().Add(testutil.Timeout))
+		_, data, err := c.Conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading a frame: %v", err)
+		}
+		var f errorFrame
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Code != "" {
+			return f
+		}
+	}
+}
+
+// TestPull asks for a game's moves and state as a spectator, and checks
+// the answers and that asking too often is refused.
+func TestPull(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	srv.MustMove(t, g.ID, models.PlayerX, 4)
+	srv.MustMove(t, g.ID, models.PlayerO, 0)
+	c := srv.DialWS(t, g.ID, "")
+	c.Connected(t)
+
+	c.Send(t, map[string]any{"type": "history", "from": 1})
+	if h := nextHistory(t, c); h.GameID != g.ID || h.From != 1 || h.Total != 2 || len(h.Moves) != 1 || h.Moves[0].Position != 0 || h.Seq == 0 {
+		t.Errorf("history from 1: %+v, want O's move of 2", h)
+	}
+	c.Send(t, map[string]any{"type": "history", "from": 5, "gameId": g.ID})
+	if h := nextHistory(t, c); h.From != 2 || h.Total != 2 || h.Moves == nil || len(h.Moves) != 0 {
+		t.Errorf("history from past the end: %+v, want no moves", h)
+	}
+	c.Send(t, map[string]any{"type": "get"})
+	if got := c.NextOf(t, broadcast.GameUpdateEvent).Game(t); got.Board[4] != models.PlayerX || got.Board[0] != models.PlayerO {
+		t.Errorf("get: board %v", got.Board)
+	}
+	c.Send(t, map[string]any{"type": "history", "from": -1})
+	if f := nextError(t, c); f.Code != "invalid_from" {
+		t.Errorf("history from -1: %+v", f)
+	}
+	c.Send(t, map[string]any{"type": "get", "gameId": "nosuchgame"})
+	if f := nextError(t, c); f.Code != "game_not_found" {
+		t.Errorf("get for a game that doesn't exist: %+v", f)
+	}
+
+	// Three of the five a burst allows are spent
+	for range 2 {
+		c.Send(t, map[string]any{"type": "get"})
+	}
+	c.Send(t, map[string]any{"type": "get"})
+	if f := nextError(t, c); f.Code != "pull_limited" || !f.Temporary {
+		t.Errorf("a get beyond the burst: %+v, want a temporary pull_limited", f)
+	}
+}
+
+// TestPullOrdering asks a delta client's game for its state while moves
+// are made, and checks no answer is older than a frame sent before it.
+func TestPullOrdering(t *testing.T) {
+	srv := testutil.Start(t, server.Config{})
+	g := srv.CreateGame(t, `{"mode":"hotseat"}`)
+	c := srv.DialWS(t, g.ID, "delta=1")
+	version := nextDelta(t, c).Version
+
+	for _, m := range plays[:4] {
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			if _, err := srv.MoveJSON(t, g.ID, models.Move{Player: m.player, Position: m.pos}); err != nil {
+				t.Error(err)
+			}
+		})
+		c.Send(t, map[string]any{"type": "get"})
+		wg.Wait()
+	}
+	c.Send(t, map[string]any{"type": "get"})
+
+	for states := 0; states < 5; {
+		f := nextDelta(t, c)
+		switch f.Type {
+		case broadcast.DeltaFrameType:
+			version = max(version, f.ToVersion)
+		case broadcast.StateFrameType:
+			if f.Version < version {
+				t.Errorf("state %d answered a get after version %d was sent", f.Version, version)
+			}
+			version = f.Version
+			states++
+		}
+		if states == 5 && len(f.Game.History) != 4 {
+			t.Errorf("the last get was answered with %d moves, want all 4", len(f.Game.History))
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package engine is the rules of tic-tac-toe on a board of any size, with
+// no games or locks. A State is a value: Apply returns a new one.
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Player is a side, or Empty for a cell nobody has taken.
+type Player string
+
+const (
+	X     Player = "X"
+	O     Player = "O"
+	Empty Player = ""
+)
+
+// Opponent returns the other side, or Empty for Empty.
+func (p Player) Opponent() Player {
+	switch p {
+	case X:
+		return O
+	case O:
+		return X
+	}
+	return Empty
+}
+
+// Variant is how the turn passes between the sides.
+type Variant int
+
+const (
+	// Standard has the sides take turns, one move each.
+	Standard Variant = iota
+	// DoubleMove has the side that moves first open with two moves in a
+	// row, after which the sides take turns.
+	DoubleMove
+)
+
+// MaxSize is the largest board an Engine plays on.
+const MaxSize = 19
+
+// Errors returned by Apply and Check.
+var (
+	ErrInvalidMove   = errors.New("invalid move")
+	ErrPositionTaken = errors.New("position already taken")
+	ErrNotYourTurn   = errors.New("not your turn")
+	ErrGameOver      = errors.New("game is over")
+)
+
+// State is a game's position. Once the game is Over, Winner is the side
+// with a line, or Empty for a draw.
+type State struct {
+	Board  []Player
+	Turn   Player
+	Moves  int
+	Winner Player
+	Over   bool
+}
+
+// Draw reports whether the game ended without a winner.
+func (s State) Draw() bool {
+	return s.Over && s.Winner == Empty
+}
+
+// Full reports whether every cell of the board has been taken.
+func (s State) Full() bool {
+	for _, cell := range s.Board {
+		if cell == Empty {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a copy of s that shares nothing with it.
+func (s State) Clone() State {
+	s.Board = append([]Player(nil), s.Board...)
+	return s
+}
+
+// Move is a side taking a cell, numbered row by row from 0.
+type Move struct {
+	Position int
+	Player   Player
+}
+
+// Engine plays by the rules of one size of board, length of line and
+// variant. It is safe to use from several goroutines.
+type Engine struct {
+	size      int
+	winLength int
+	variant   Variant
+	// lines are every run of winLength cells in a row, column or
+	// diagonal: the rows first, then the columns, the diagonals going
+	// down to the right and those going down to the left, each in board
+	// order of its first cell
+	lines [][]int
+	// through maps each cell to the lines it is on
+	through [][]int
+}
+
+// New returns an engine for a size by size board on which winLength
+// marks in a row win, playing variant.
+func New(size, winLength int, variant Variant) (*Engine, error) {
+	switch {
+	case size < 1 || size > MaxSize:
+		return nil, fmt.Errorf("board size %d out of range, must be 1 to %d", size, MaxSize)
+	case winLength < 1 || winLength > size:
+		return nil, fmt.Errorf("line length %d out of range, must be 1 to the board size, %d", winLength, size)
+	case variant != Standard && variant != DoubleMove:
+		return nil, fmt.Errorf("unknown variant %d", variant)
+	}
+	e := &Engine{size: size, winLength: winLength, variant: variant, through: make([][]int, size*size)}
+	// Each direction as a step in rows and columns
+	for _, dir := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		for row := range size {
+			for col := range size {
+				endRow, endCol := row+dir[0]*(winLength-1), col+dir[1]*(winLength-1)
+				if endRow >= size || endCol < 0 || endCol >= size {
+					continue
+				}
+				line := make([]int, winLength)
+				for i := range line {
+					line[i] = (row+dir[0]*i)*size + col + dir[1]*i
+				}
+				for _, cell := range line {
+					e.through[cell] = append(e.through[cell], len(e.lines))
+				}
+				e.lines = append(e.lines, line)
+			}
+		}
+		// A line of one is the same cell in every direction
+		if winLength == 1 {
+			break
+		}
+	}
+	return e, nil
+}
+
+// Size returns the number of rows and columns of the engine's board.
+func (e *Engine) Size() int { return e.size }
+
+// WinLength returns how many marks in a row win.
+func (e *Engine) WinLength() int { return e.winLength }
+
+// Variant returns how the turn passes between the sides.
+func (e *Engine) Variant() Variant { return e.variant }
+
+// Start returns the position games start from: an empty board with X to
+// move.
+func (e *Engine) Start() State {
+	return State{Board: make([]Player, e.size*e.size), Turn: X}
+}
+
+// Check reports why m can't be applied to s, or nil if it can: the game
+// must be under way, and m must be the turn of its player and take a
+// free cell on the board.
+func (e *Engine) Check(s State, m Move) error {
+	switch {
+	case s.Over:
+		return ErrGameOver
+	case m.Position < 0 || m.Position >= len(s.Board):
+		return ErrInvalidMove
+	case s.Board[m.Position] != Empty:
+		return ErrPositionTaken
+	case m.Player != s.Turn:
+		return ErrNotYourTurn
+	}
+	return nil
+}
+
+// Apply returns the position after m, which Check must allow. The game
+// is over once m completes a line or fills the board; otherwise the turn
+// passes as the variant has it.
+func (e *Engine) Apply(s State, m Move) (State, error) {
+	if err := e.Check(s, m); err != nil {
+		return s, err
+	}
+	s = s.Clone()
+	s.Board[m.Position] = m.Player
+	s.Moves++
+	switch {
+	case e.completes(s.Board, m.Position):
+		s.Winner = m.Player
+		s.Over = true
+	case s.Full():
+		s.Over = true
+	case e.variant == DoubleMove && s.Moves == 1:
+		// The opening side moves again
+	default:
+		s.Turn = s.Turn.Opponent()
+	}
+	return s, nil
+}
+
+// completes reports whether the mark on cell is part of a line.
+func (e *Engine) completes(board []Player, cell int) bool {
+	for _, l := range e.through[cell] {
+		if e.complete(board, e.lines[l]) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete reports whether one side holds every cell of line.
+func (e *Engine) complete(board []Player, line []int) bool {
+	first := board[line[0]]
+	if first == Empty {
+		return false
+	}
+	for _, cell := range line[1:] {
+		if board[cell] != first {
+			return false
+		}
+	}
+	return true
+}
+
+// LegalMoves returns the cells the side to move may take, in board
+// order, none once the game is over.
+func (e *Engine) LegalMoves(s State) []int {
+	moves := []int{}
+	if s.Over {
+		return moves
+	}
+	for i, cell := range s.Board {
+		if cell == Empty {
+			moves = append(moves, i)
+		}
+	}
+	return moves
+}
+
+// Winner returns the side with a line on the board of s, or Empty when
+// neither has one. It looks at the board alone, so it also tells who won
+// a position set up rather than played to.
+func (e *Engine) Winner(s State) Player {
+	if line := e.WinningLine(s.Board); line != nil {
+		return s.Board[line[0]]
+	}
+	return Empty
+}
+
+// WinningLine returns the cells of a line that one side holds all of, or
+// nil if there is none. Rows are looked at first, then columns, then
+// diagonals, so a move completing two lines gets the first of them. The
+// cells are the engine's own, not to be changed.
+func (e *Engine) WinningLine(board []Player) []int {
+	if len(board) != e.size*e.size {
+		return nil
+	}
+	for _, line := range e.lines {
+		if e.complete(board, line) {
+			return line
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package engine_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"tiktaktoes/pkg/engine"
+)
+
+// winConditions is the table of lines the game service checked before
+// the rules moved here, in its order.
+var winConditions = [][]int{
+	{0, 1, 2}, // top row
+	{3, 4, 5}, // middle row
+	{6, 7, 8}, // bottom row
+	{0, 3, 6}, // left column
+	{1, 4, 7}, // middle column
+	{2, 5, 8}, // right column
+	{0, 4, 8}, // diagonal
+	{2, 4, 6}, // anti-diagonal
+}
+
+// oldWinningLine is the service's WinningLine as it was.
+func oldWinningLine(board []engine.Player) []int {
+	for _, condition := range winConditions {
+		a, b, c := condition[0], condition[1], condition[2]
+		if board[a] != engine.Empty && board[a] == board[b] && board[b] == board[c] {
+			return condition
+		}
+	}
+	return nil
+}
+
+// mustNew is engine.New failing the test on an error.
+func mustNew(t testing.TB, size, winLength int, variant engine.Variant) *engine.Engine {
+	t.Helper()
+	e, err := engine.New(size, winLength, variant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// board decodes code as a board of n cells, a base 3 digit each.
+func board(n, code int) []engine.Player {
+	marks := []engine.Player{engine.Empty, engine.X, engine.O}
+	b := make([]engine.Player, n)
+	for i := range b {
+		b[i] = marks[code%3]
+		code /= 3
+	}
+	return b
+}
+
+// TestClassicMatchesWinConditions checks the 3x3 engine against the old
+// table on every one of the 3^9 boards: the same line, the same winner
+// and the same fullness, so the service behaves as it did.
+func TestClassicMatchesWinConditions(t *testing.T) {
+	e := mustNew(t, 3, 3, engine.Standard)
+	for code := range 19683 { // 3^9
+		b := board(9, code)
+		want := oldWinningLine(b)
+		if got := e.WinningLine(b); !slices.Equal(got, want) {
+			t.Fatalf("WinningLine(%v) = %v, want %v", b, got, want)
+		}
+		wantWinner := engine.Empty
+		if want != nil {
+			wantWinner = b[want[0]]
+		}
+		s := engine.State{Board: b}
+		if got := e.Winner(s); got != wantWinner {
+			t.Fatalf("Winner(%v) = %q, want %q", b, got, wantWinner)
+		}
+		if got := s.Full(); got != !slices.Contains(b, engine.Empty) {
+			t.Fatalf("Full(%v) = %v", b, got)
+		}
+	}
+}
+
+// lineOwners returns the sides with winLength marks in a row on a size
+// by size board, found by walking from every cell in every direction
+// rather than from the engine's lines, to check them against.
+func lineOwners(b []engine.Player, size, winLength int) map[engine.Player]bool {
+	owners := map[engine.Player]bool{}
+	for row := range size {
+		for col := range size {
+			p := b[row*size+col]
+			if p == engine.Empty {
+				continue
+			}
+			for _, dir := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+				run := 1
+				for r, c := row+dir[0], col+dir[1]; r >= 0 && r < size && c >= 0 && c < size && b[r*size+c] == p; r, c = r+dir[0], c+dir[1] {
+					run++
+				}
+				if run >= winLength {
+					owners[p] = true
+				}
+			}
+		}
+	}
+	return owners
+}
+
+// TestRandomGames plays random games on larger boards and checks at each
+// move that the game ends exactly when a line is made or the board fills,
+// that the turn passes, and that the state applied to is left alone.
+func TestRandomGames(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, tt := range []struct{ size, winLength int }{{4, 3}, {4, 4}, {5, 4}, {5, 5}} {
+		t.Run(fmt.Sprintf("%dx%d/%d", tt.size, tt.size, tt.winLength), func(t *testing.T) {
+			e := mustNew(t, tt.size, tt.winLength, engine.Standard)
+			for range 2000 {
+				s := e.Start()
+				for !s.Over {
+					legal := e.LegalMoves(s)
+					if len(legal) == 0 {
+						t.Fatalf("no legal moves in %v, which isn't over", s.Board)
+					}
+					m := engine.Move{Position: legal[rng.IntN(len(legal))], Player: s.Turn}
+					before := s.Clone()
+					next, err := e.Apply(s, m)
+					if err != nil {
+						t.Fatalf("%v on %v: %v", m, s.Board, err)
+					}
+					if !slices.Equal(s.Board, before.Board) {
+						t.Fatalf("Apply changed the board it was given")
+					}
+					owners := lineOwners(next.Board, tt.size, tt.winLength)
+					switch {
+					case owners[m.Player]:
+						if !next.Over || next.Winner != m.Player || e.Winner(next) != m.Player {
+							t.Fatalf("%v made a line on %v but got %+v", m, next.Board, next)
+						}
+					case len(owners) > 0:
+						t.Fatalf("%v on %v, a game that wasn't over, found lines for %v", m, next.Board, owners)
+					case next.Full():
+						if !next.Draw() {
+							t.Fatalf("full board %v isn't a draw: %+v", next.Board, next)
+						}
+					default:
+						if next.Over || next.Turn != m.Player.Opponent() {
+							t.Fatalf("after %v on %v got %+v", m, next.Board, next)
+						}
+					}
+					s = next
+				}
+				if legal := e.LegalMoves(s); len(legal) != 0 {
+					t.Fatalf("legal moves %v once over", legal)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	e := mustNew(t, 3, 3, engine.Standard)
+	s, _ := e.Apply(e.Start(), engine.Move{Position: 4, Player: engine.X})
+	over := s.Clone()
+	over.Over = true
+	for _, tt := range []struct {
+		name string
+		s    engine.State
+		m    engine.Move
+		want error
+	}{
+		{"off the board", s, engine.Move{Position: 9, Player: engine.O}, engine.ErrInvalidMove},
+		{"negative", s, engine.Move{Position: -1, Player: engine.O}, engine.ErrInvalidMove},
+		{"taken", s, engine.Move{Position: 4, Player: engine.O}, engine.ErrPositionTaken},
+		{"out of turn", s, engine.Move{Position: 0, Player: engine.X}, engine.ErrNotYourTurn},
+		{"over", over, engine.Move{Position: 0, Player: engine.O}, engine.ErrGameOver},
+	} {
+		if _, err := e.Apply(tt.s, tt.m); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDoubleMove(t *testing.T) {
+	e := mustNew(t, 3, 3, engine.DoubleMove)
+	s := e.Start()
+	for i, m := range []engine.Move{{0, engine.X}, {1, engine.X}, {4, engine.O}, {2, engine.X}} {
+		var err error
+		if s, err = e.Apply(s, m); err != nil {
+			t.Fatalf("move %d: %v", i, err)
+		}
+	}
+	if !s.Over || s.Winner != engine.X {
+		t.Errorf("X's opening pair and a third didn't win: %+v", s)
+	}
+}
+
+func TestNewRejects(t *testing.T) {
+	for _, tt := range []struct {
+		size, winLength int
+		variant         engine.Variant
+	}{{0, 1, engine.Standard}, {engine.MaxSize + 1, 3, engine.Standard}, {3, 4, engine.Standard}, {3, 0, engine.Standard}, {3, 3, 7}} {
+		if _, err := engine.New(tt.size, tt.winLength, tt.variant); err == nil {
+			t.Errorf("New(%d, %d, %d) made an engine", tt.size, tt.winLength, tt.variant)
+		}
+	}
+}
+
+// BenchmarkApply applies one move to a position part way through a game.
+func BenchmarkApply(b *testing.B) {
+	for _, bb := range []struct{ size, winLength int }{{3, 3}, {5, 4}, {19, 5}} {
+		b.Run(fmt.Sprintf("%dx%d", bb.size, bb.size), func(b *testing.B) {
+			e := mustNew(b, bb.size, bb.winLength, engine.Standard)
+			s := e.Start()
+			for _, pos := range []int{0, 1, 2} {
+				s, _ = e.Apply(s, engine.Move{Position: pos, Player: s.Turn})
+			}
+			m := engine.Move{Position: len(s.Board) - 1, Player: s.Turn}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := e.Apply(s, m); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWinnerDetection5x5 looks for a line on a full 5x5 board with
+// none of four, the most Winner has to look at.
+func BenchmarkWinnerDetection5x5(b *testing.B) {
+	e := mustNew(b, 5, 4, engine.Standard)
+	x, o := engine.X, engine.O
+	s := engine.State{Board: []engine.Player{
+		x, x, o, o, x,
+		o, o, x, x, o,
+		x, x, o, o, x,
+		o, o, x, x, o,
+		x, x, o, o, x,
+	}}
+	if w := e.Winner(s); w != engine.Empty {
+		b.Fatalf("the board has a line for %s", w)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		e.Winner(s)
+	}
+}
@@ -0,0 +1,108 @@
+// URLs in this page are relative so it works when the server is
+// mounted under a path prefix; fragments it loads carry the prefix.
+let myPlayer = 'X';
+
+function selectPlayer(player) {
+    myPlayer = player;
+    document.getElementById('selectX').classList.toggle('active', player === 'X');
+    document.getElementById('selectO').classList.toggle('active', player === 'O');
+}
+
+function getPlayer() {
+    return myPlayer;
+}
+
+function getSymbol() {
+    return document.getElementById('symbol').value.trim();
+}
+
+function getAnalysisLive() {
+    return document.getElementById('analysisLive').checked;
+}
+
+function getHandicap() {
+    return document.getElementById('handicap').value;
+}
+
+function getSlug() {
+    return document.getElementById('slug').value.trim().toLowerCase();
+}
+
+function getReadyCheck() {
+    return document.getElementById('readyCheck').checked;
+}
+
+function getEarlyDraw() {
+    return document.getElementById('earlyDraw').checked;
+}
+
+function copyShareLink(gameId) {
+    const shareURL = `${location.origin}${location.pathname}?game=${gameId}`;
+    navigator.clipboard.writeText(shareURL);
+    const el = document.getElementById('shareLink');
+    const label = el.textContent;
+    el.textContent = el.dataset.copied;
+    setTimeout(() => el.textContent = label, 1500);
+}
+
+// Check URL for game ID on load
+document.addEventListener('DOMContentLoaded', function() {
+    const urlParams = new URLSearchParams(window.location.search);
+    const player = urlParams.get('player');
+    if (player === 'X' || player === 'O') {
+        selectPlayer(player);
+    }
+    const tournamentId = urlParams.get('tournament');
+    if (tournamentId) {
+        htmx.ajax('GET', 'htmx/tournament/' + encodeURIComponent(tournamentId), '#game-container');
+        return;
+    }
+    const gameId = urlParams.get('game');
+    if (gameId) {
+        document.getElementById('joinId').value = gameId;
+        // Only look: joining is an explicit click, so reloading never
+        // claims a side. Without ?player= the game is watched.
+        // Players see the game's commentary only with ?commentary=1
+        const commentary = urlParams.get('commentary') === '1' ? '&commentary=1' : '';
+        htmx.ajax('GET', 'htmx/game/' + encodeURIComponent(gameId.trim()) + '?player=' + (player || '') + commentary, '#game-container');
+    }
+});
+
+// Join errors and the like come back as 4xx fragments explaining what
+// went wrong; show them instead of dropping them
+document.body.addEventListener('htmx:beforeSwap', function(evt) {
+    const xhr = evt.detail.xhr;
+    const type = xhr.getResponseHeader('Content-Type') || '';
+    if (xhr.status >= 400 && xhr.status < 500 && type.startsWith('text/html')) {
+        evt.detail.shouldSwap = true;
+        evt.detail.isError = false;
+    }
+});
+
+// Alert the player when it becomes their turn in a background tab
+document.body.addEventListener('htmx:sseMessage', function(evt) {
+    if (evt.detail.type !== 'turn-notification' || !document.hidden) return;
+    document.title = '(your turn) Tic Tac Toe';
+    try {
+        const audio = new AudioContext();
+        const beep = audio.createOscillator();
+        beep.connect(audio.destination);
+        beep.start();
+        beep.stop(audio.currentTime + 0.15);
+    } catch (e) {}
+});
+document.addEventListener('visibilitychange', function() {
+    if (!document.hidden) document.title = 'Tic Tac Toe';
+});
+
+// Update URL when game loads
+document.body.addEventListener('htmx:afterSwap', function(evt) {
+    const gameEl = document.querySelector('[data-game-id][data-player]');
+    if (gameEl) {
+        const { gameId, player } = gameEl.dataset;
+        // Keep the side in the URL so a reload shows the same view
+        const commentary = new URLSearchParams(location.search).get('commentary') === '1' ? '&commentary=1' : '';
+        history.pushState({}, '', (player ? `?game=${gameId}&player=${player}` : `?game=${gameId}`) + commentary);
+        if (player) selectPlayer(player);
+    }
+});